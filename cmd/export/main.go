@@ -0,0 +1,108 @@
+// Command export copies what changed in the ledger since the previous export
+// to files, once. It reads the same configuration as the service, writes the
+// manifest of the run to stdout and exits with status 0 once the run is
+// complete, 1 otherwise. Runs made here and through the admin endpoint share
+// their watermarks, which are kept in the database.
+//
+//	export -dir /data/exports -format parquet -tenants acme,globex
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/ashwin-m/transactions/config"
+	exportdao "github.com/ashwin-m/transactions/daos/export"
+	"github.com/ashwin-m/transactions/services/export"
+	"github.com/ashwin-m/transactions/utils/logging"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	exitExported = 0
+	exitFailed   = 1
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailed
+	}
+
+	dir := flag.String("dir", cfg.Export.Dir, "directory runs are written to")
+	format := flag.String("format", cfg.Export.Format, "format of the exported files, csv, ndjson or parquet")
+	tenants := flag.String("tenants", strings.Join(cfg.Export.Tenants, ","), "comma separated tenants to export")
+	flag.Parse()
+
+	// the manifest goes to stdout, keep the logs apart
+	slog.SetDefault(logging.New(os.Stderr, cfg.Logging.Level, cfg.Logging.Format))
+
+	options := export.Options{Dir: *dir, Format: *format}
+	for _, tenantId := range strings.Split(*tenants, ",") {
+		tenantId = strings.TrimSpace(tenantId)
+		if !tenant.Valid(tenantId) {
+			fmt.Fprintf(os.Stderr, "invalid tenant %q\n", tenantId)
+			return exitFailed
+		}
+		options.Tenants = append(options.Tenants, tenantId)
+	}
+	if !slices.Contains(export.Formats, *format) {
+		fmt.Fprintf(os.Stderr, "format must be one of %s, got %q\n", strings.Join(export.Formats, ", "), *format)
+		return exitFailed
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	poolConfig, err := cfg.Database.PoolConfig()
+	if err != nil {
+		slog.Error("invalid database configuration", slog.Any("error", err))
+		return exitFailed
+	}
+	tenant.ConfigurePool(poolConfig)
+
+	db, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		slog.Error("unable to connect to database", slog.Any("error", err))
+		return exitFailed
+	}
+	defer db.Close()
+
+	exporter := export.NewExporter(db, exportdao.NewDao(db), options)
+	manifest, err := exporter.Export(ctx)
+	if err != nil {
+		slog.Error("unable to export the ledger", slog.Any("error", err))
+		return exitFailed
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "    ")
+	err = encoder.Encode(manifest)
+	if err != nil {
+		slog.Error("unable to write the manifest", slog.Any("error", err))
+		return exitFailed
+	}
+
+	for _, file := range manifest.Files {
+		slog.Info("table exported",
+			slog.String("run", manifest.Id),
+			slog.String("table", file.Table),
+			slog.Int64("rows", file.Rows))
+	}
+
+	return exitExported
+}
@@ -0,0 +1,132 @@
+// Command import creates accounts in bulk from a CSV or NDJSON file, or from
+// stdin. It reads the same configuration as the service, writes the report
+// of the import to stdout and exits with status 0 when every row was
+// imported, 1 when some rows failed and 2 when the import couldn't finish.
+// Accounts that already exist are skipped, so an import that was stopped is
+// resumed by running it again.
+//
+//	import -tenant acme -format csv accounts.csv
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/ashwin-m/transactions/config"
+	importsdao "github.com/ashwin-m/transactions/daos/imports"
+	"github.com/ashwin-m/transactions/services/imports"
+	"github.com/ashwin-m/transactions/utils/logging"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	exitImported   = 0
+	exitRowsFailed = 1
+	exitFailed     = 2
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailed
+	}
+
+	tenantId := flag.String("tenant", tenant.Default, "tenant the accounts are created in")
+	format := flag.String("format", imports.FormatCSV, "format of the file, csv or ndjson")
+	batchSize := flag.Int("batch-size", cfg.Import.BatchSize, "accounts written per transaction")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [file]\n\nReads stdin when no file is given.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// the report goes to stdout, keep the logs apart
+	slog.SetDefault(logging.New(os.Stderr, cfg.Logging.Level, cfg.Logging.Format))
+
+	if !tenant.Valid(*tenantId) {
+		fmt.Fprintf(os.Stderr, "invalid tenant %q\n", *tenantId)
+		return exitFailed
+	}
+	if !slices.Contains(imports.Formats, *format) {
+		fmt.Fprintf(os.Stderr, "format must be one of %s, got %q\n", strings.Join(imports.Formats, ", "), *format)
+		return exitFailed
+	}
+	if *batchSize <= 0 {
+		fmt.Fprintln(os.Stderr, "batch-size must be positive")
+		return exitFailed
+	}
+	if flag.NArg() > 1 {
+		flag.Usage()
+		return exitFailed
+	}
+
+	var input io.Reader = os.Stdin
+	if flag.NArg() == 1 {
+		file, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailed
+		}
+		defer file.Close()
+		input = file
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	poolConfig, err := cfg.Database.PoolConfig()
+	if err != nil {
+		slog.Error("invalid database configuration", slog.Any("error", err))
+		return exitFailed
+	}
+	tenant.ConfigurePool(poolConfig)
+
+	db, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		slog.Error("unable to connect to database", slog.Any("error", err))
+		return exitFailed
+	}
+	defer db.Close()
+
+	service := imports.NewService(db, importsdao.NewDao(db), *batchSize)
+	report, importErr := service.Import(tenant.NewContext(ctx, *tenantId), *format, input)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "    ")
+	err = encoder.Encode(report)
+	if err != nil {
+		slog.Error("unable to write the report", slog.Any("error", err))
+		return exitFailed
+	}
+
+	if importErr != nil {
+		slog.Error("unable to import accounts, run again to resume", slog.Any("error", importErr))
+		return exitFailed
+	}
+
+	slog.Info("accounts imported",
+		slog.String("tenant", *tenantId),
+		slog.Int64("created", report.Created),
+		slog.Int64("existing", report.Existing),
+		slog.Int64("failed", report.Failed))
+	if report.Failed > 0 {
+		return exitRowsFailed
+	}
+
+	return exitImported
+}
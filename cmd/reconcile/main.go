@@ -0,0 +1,127 @@
+// Command reconcile checks every balance of the ledger against the transfers
+// behind it, and that transfers conserved money, once. It reads the same
+// configuration as the service, and exits with status 0 when the ledger is
+// consistent, 1 when it found discrepancies and 2 when it couldn't run,
+// another reconciliation running included.
+//
+//	reconcile -tenants acme,globex -report discrepancies.csv -format csv
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ashwin-m/transactions/config"
+	reconciliationdao "github.com/ashwin-m/transactions/daos/reconciliation"
+	"github.com/ashwin-m/transactions/services/reconciliation"
+	"github.com/ashwin-m/transactions/utils/logging"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	exitConsistent    = 0
+	exitDiscrepancies = 1
+	exitFailed        = 2
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailed
+	}
+
+	tenants := flag.String("tenants", strings.Join(cfg.Reconcile.Tenants, ","), "comma separated tenants to reconcile together")
+	tolerance := flag.Float64("tolerance", cfg.Reconcile.Tolerance, "largest difference that isn't a discrepancy")
+	reportPath := flag.String("report", "", "file the discrepancy report is written to, - for stdout, none when empty")
+	format := flag.String("format", reconciliation.FormatJSON, "format of the report, json or csv")
+	flag.Parse()
+
+	// the report may go to stdout, keep the logs apart
+	slog.SetDefault(logging.New(os.Stderr, cfg.Logging.Level, cfg.Logging.Format))
+
+	options := reconciliation.Options{Tolerance: *tolerance}
+	for _, tenantId := range strings.Split(*tenants, ",") {
+		tenantId = strings.TrimSpace(tenantId)
+		if !tenant.Valid(tenantId) {
+			fmt.Fprintf(os.Stderr, "invalid tenant %q\n", tenantId)
+			return exitFailed
+		}
+		options.Tenants = append(options.Tenants, tenantId)
+	}
+	if *reportPath != "" && *format != reconciliation.FormatJSON && *format != reconciliation.FormatCSV {
+		fmt.Fprintf(os.Stderr, "format must be one of %s, got %q\n", strings.Join(reconciliation.Formats, ", "), *format)
+		return exitFailed
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	poolConfig, err := cfg.Database.PoolConfig()
+	if err != nil {
+		slog.Error("invalid database configuration", slog.Any("error", err))
+		return exitFailed
+	}
+	tenant.ConfigurePool(poolConfig)
+
+	db, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		slog.Error("unable to connect to database", slog.Any("error", err))
+		return exitFailed
+	}
+	defer db.Close()
+
+	reconciler := reconciliation.NewReconciler(db, reconciliationdao.NewDao(db), nil, options)
+	report, err := reconciler.Reconcile(ctx)
+	if err != nil {
+		slog.Error("unable to reconcile the ledger", slog.Any("error", err))
+		return exitFailed
+	}
+
+	if *reportPath != "" {
+		err = writeReport(*reportPath, *format, report)
+		if err != nil {
+			slog.Error("unable to write the report", slog.String("report", *reportPath), slog.Any("error", err))
+			return exitFailed
+		}
+	}
+
+	slog.Info("ledger reconciled",
+		slog.Int64("accounts", report.Accounts),
+		slog.Int64("inferred", report.Inferred),
+		slog.Int("mismatches", len(report.Mismatches)),
+		slog.Bool("conservation_holds", report.Conservation.Holds),
+		slog.Float64("conservation_difference", report.Conservation.Difference))
+
+	if !report.Consistent() {
+		return exitDiscrepancies
+	}
+
+	return exitConsistent
+}
+
+func writeReport(path, format string, report reconciliation.Report) error {
+	if path == "-" {
+		return reconciliation.Write(os.Stdout, format, report)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = reconciliation.Write(file, format, report)
+	return errors.Join(err, file.Close())
+}
@@ -0,0 +1,141 @@
+// Command replay sends a JSONL log of requests to a running server, with as
+// many requests in flight and at the rate asked for, and reports latencies
+// and outcomes by route. Unless told otherwise it reads the balances of the
+// accounts the log touches before and after, to check transfers conserved
+// money. It exits with status 0 when every response had an expected status
+// and money was conserved, 1 when not and 2 when it couldn't run.
+//
+//	replay -url http://localhost:8080 -concurrency 32 -header "X-API-Key: $KEY" resources/replay/transfers.jsonl
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ashwin-m/transactions/utils/replay"
+)
+
+const (
+	exitPassed = 0
+	exitFailed = 1
+	exitError  = 2
+)
+
+// headers collects repeated -header flags.
+type headers http.Header
+
+func (h headers) String() string {
+	return ""
+}
+
+func (h headers) Set(value string) error {
+	name, content, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return errors.New(`must look like "Name: value"`)
+	}
+	http.Header(h).Add(strings.TrimSpace(name), strings.TrimSpace(content))
+	return nil
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	requestHeaders := headers{}
+	url := flag.String("url", "http://localhost:8080", "where the server listens")
+	concurrency := flag.Int("concurrency", 8, "requests in flight at most")
+	rate := flag.Float64("rate", 0, "requests sent per second at most, 0 for as fast as possible")
+	repeat := flag.Int("repeat", 1, "times the log is replayed")
+	conservation := flag.Bool("conservation", true, "check the balances the log touches conserve money, needs admin credentials")
+	tolerance := flag.Float64("tolerance", replay.DefaultTolerance, "largest difference in balances that conserves money")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout of each request")
+	asJSON := flag.Bool("json", false, "write the report as JSON")
+	flag.Var(requestHeaders, "header", `header sent with every request, like "X-API-Key: <key>", repeatable`)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [log]\n\nReads the log from stdin when none is given.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *concurrency <= 0 || *repeat <= 0 || *rate < 0 || *tolerance <= 0 || *timeout <= 0 {
+		fmt.Fprintln(os.Stderr, "concurrency, repeat, tolerance and timeout must be positive, rate must not be negative")
+		return exitError
+	}
+	if flag.NArg() > 1 {
+		flag.Usage()
+		return exitError
+	}
+
+	var input io.Reader = os.Stdin
+	if flag.NArg() == 1 {
+		file, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		defer file.Close()
+		input = file
+	}
+
+	entries, err := replay.Read(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid log: %v\n", err)
+		return exitError
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = *concurrency
+
+	report, err := replay.Run(ctx, entries, replay.Options{
+		BaseURL:      *url,
+		Headers:      http.Header(requestHeaders),
+		Concurrency:  *concurrency,
+		Rate:         *rate,
+		Repeat:       *repeat,
+		Conservation: *conservation,
+		Tolerance:    *tolerance,
+		Client:       &http.Client{Transport: transport, Timeout: *timeout},
+	})
+	// an interrupted replay still reports what it sent
+	interrupted := errors.Is(err, context.Canceled)
+	if err != nil && !interrupted {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "    ")
+		err = encoder.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	if interrupted {
+		fmt.Fprintln(os.Stderr, "interrupted before the whole log was replayed")
+		return exitError
+	}
+	if !report.OK() {
+		return exitFailed
+	}
+
+	return exitPassed
+}
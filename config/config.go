@@ -0,0 +1,605 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ashwin-m/transactions/utils/secretbox"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultEnvFile = ".env"
+
+	minAdminTokenLength = 32
+
+	// sampleAdminToken is the admin token .env.sample used to hold, it is
+	// public and refused.
+	sampleAdminToken = "change-me-to-a-long-random-admin-token"
+)
+
+var (
+	storageBackends = []string{"postgres", "memory"}
+	sslModes        = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	logFormats      = []string{"json", "text"}
+	exporters       = []string{"none", "stdout", "otlp"}
+	eventSinks      = []string{"none", "stdout", "file", "http"}
+	exportFormats   = []string{"csv", "ndjson", "parquet"}
+)
+
+type Config struct {
+	Server    ServerConfig
+	Storage   StorageConfig
+	Database  DatabaseConfig
+	Health    HealthConfig
+	Logging   LoggingConfig
+	Tracing   TracingConfig
+	Auth      AuthConfig
+	Audit     AuditConfig
+	Tenancy   TenancyConfig
+	RateLimit RateLimitConfig
+	API       APIConfig
+	Events    EventsConfig
+	Webhooks  WebhooksConfig
+	Reconcile ReconcileConfig
+	Export    ExportConfig
+	Import    ImportConfig
+}
+
+type ServerConfig struct {
+	Addr string
+	// GRPCAddr is where the gRPC Ledger API listens, without TLS. It is
+	// disabled when empty, the default.
+	GRPCAddr string
+}
+
+// StorageConfig picks where accounts, transfers, their events and API keys
+// are kept.
+type StorageConfig struct {
+	// Backend is postgres or memory. The memory backend keeps nothing
+	// across restarts, API keys included: they are issued with the admin
+	// token as usual and authenticate as bearer tokens, or HMAC signatures
+	// when AUTH_SIGNING_KEY is set, alongside JWTs. The routes of webhooks,
+	// the audit log, exports and imports are only served with postgres,
+	// and reconciliation only runs on it.
+	Backend string
+}
+
+type HealthConfig struct {
+	// Timeout bounds the dependency checks made by the readiness endpoint.
+	Timeout time.Duration
+}
+
+type LoggingConfig struct {
+	Level  slog.Level
+	Format string
+}
+
+type TracingConfig struct {
+	// Exporter is one of none, stdout or otlp.
+	Exporter     string
+	ServiceName  string
+	SampleRatio  float64
+	OTLPEndpoint string
+	OTLPInsecure bool
+}
+
+type AuthConfig struct {
+	// Enabled requires every /accounts and /transactions request to be
+	// authenticated. Only turn it off for local development.
+	Enabled bool
+	// AdminToken protects the /admin endpoints. They are disabled when empty.
+	AdminToken Secret
+	// SigningKey encrypts the HMAC signing secrets of API keys, 32 bytes
+	// base64 encoded. HMAC signed requests are only accepted when it is set.
+	SigningKey  Secret
+	HMACMaxSkew time.Duration
+	JWT         JWTConfig
+}
+
+type AuditConfig struct {
+	// Key keys the hash chain of the audit log, 32 bytes base64 encoded.
+	// Without it entries are chained by plain SHA-256, which anyone able to
+	// write to the database can recompute.
+	Key Secret
+}
+
+// JWTConfig configures validation of gateway issued bearer tokens. It is
+// enabled by setting one of JWKSFile or JWKSURL.
+type JWTConfig struct {
+	Issuer        string
+	Audience      string
+	JWKSFile      string
+	JWKSURL       string
+	JWKSRefresh   time.Duration
+	ClientIDClaim string
+	RolesClaim    string
+	TenantClaim   string
+	Leeway        time.Duration
+}
+
+// RateLimitConfig sets the token buckets applied to authenticated requests.
+// A rate of zero turns the corresponding limit off.
+type RateLimitConfig struct {
+	Enabled bool
+	// ClientRate and ClientBurst limit every request made by a client.
+	ClientRate  float64
+	ClientBurst int
+	// AccountRate and AccountBurst limit transfers out of a single account.
+	AccountRate  float64
+	AccountBurst int
+}
+
+// APIConfig controls the versions of the API that are served.
+type APIConfig struct {
+	// UnversionedRoutes keeps serving the v1 routes without their /v1
+	// prefix, as they were before the API was versioned. Their responses
+	// carry Deprecation headers.
+	UnversionedRoutes bool
+	// UnversionedSunset is announced in the Sunset header of unversioned
+	// routes. No date is announced when it is zero.
+	UnversionedSunset time.Time
+}
+
+// EventsConfig picks where the domain events recorded in the outbox are
+// published.
+type EventsConfig struct {
+	// Sink is one of none, stdout, file or http. With none the events stay
+	// in the outbox until a sink is configured.
+	Sink string
+	// File is appended to by the file sink.
+	File string
+	// HTTPURL receives a POST per event from the http sink.
+	HTTPURL       string
+	RelayInterval time.Duration
+	BatchSize     int
+	// RetryBase is the wait before retrying an event that failed to
+	// publish, doubling with each failed attempt.
+	RetryBase time.Duration
+	// MaxAttempts is the number of failed attempts after which an event is
+	// parked.
+	MaxAttempts int
+}
+
+// WebhooksConfig tunes the delivery of events to webhook subscriptions.
+type WebhooksConfig struct {
+	// MaxAttempts is the number of attempts after which a delivery is dead.
+	MaxAttempts int
+	// RetryBase is the wait after the first failed attempt, doubling with
+	// each one after it.
+	RetryBase time.Duration
+	// Timeout bounds every attempt.
+	Timeout time.Duration
+}
+
+// ReconcileConfig schedules the reconciliation of balances with transfers.
+type ReconcileConfig struct {
+	// Interval is the time between reconciliations, which are off when it
+	// is zero.
+	Interval time.Duration
+	// Tenants are reconciled together, so transfers between them must
+	// conserve money.
+	Tenants []string
+	// Tolerance is the largest difference that isn't a discrepancy.
+	Tolerance float64
+}
+
+// ExportConfig describes the exports of the ledger made by cmd/export and
+// the admin endpoint.
+type ExportConfig struct {
+	// Dir holds a directory per export run.
+	Dir    string
+	Format string
+	// Tenants are exported, each from where its previous export stopped.
+	Tenants []string
+}
+
+// ImportConfig describes the account imports made by cmd/import and the
+// admin endpoint.
+type ImportConfig struct {
+	// BatchSize is the number of accounts written per transaction.
+	BatchSize int
+}
+
+type TenancyConfig struct {
+	// AllowCrossTenantTransfers lets a transfer name a destination account
+	// in another tenant. Transfers stay within the caller's tenant otherwise.
+	AllowCrossTenantTransfers bool
+}
+
+func (j JWTConfig) Enabled() bool {
+	return j.JWKSFile != "" || j.JWKSURL != ""
+}
+
+type DatabaseConfig struct {
+	Host     string
+	Port     int
+	User     string
+	Password Secret
+	Name     string
+
+	SSLMode     string
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
+
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	ConnectTimeout    time.Duration
+}
+
+// Options controls where configuration is read from. Values are resolved with
+// the following precedence, highest first: process environment, the .env
+// file, the config file and finally the built-in defaults.
+type Options struct {
+	// EnvFile is the dotenv file to read. A missing file is not an error.
+	EnvFile string
+	// ConfigFile is an optional YAML or TOML file, picked by extension.
+	ConfigFile string
+	// LookupEnv reads the process environment, os.LookupEnv when nil.
+	LookupEnv func(string) (string, bool)
+}
+
+// Load reads configuration using the default sources. The config file path is
+// taken from the CONFIG_FILE environment variable.
+func Load() (Config, error) {
+	return LoadWith(Options{})
+}
+
+func LoadWith(opts Options) (Config, error) {
+	src, err := newSources(opts)
+	if err != nil {
+		return Config{}, err
+	}
+
+	l := &loader{src: src}
+
+	var cfg Config
+	cfg.Server.Addr = l.string("HTTP_ADDR", "server.addr", ":8080")
+	cfg.Server.GRPCAddr = l.string("GRPC_ADDR", "server.grpc_addr", "")
+
+	cfg.Storage.Backend = l.string("STORAGE_BACKEND", "storage.backend", "postgres")
+
+	cfg.Database.Host = l.string("DB_HOST", "database.host", "")
+	cfg.Database.Port = l.int("DB_PORT", "database.port", 5432)
+	cfg.Database.User = l.string("DB_USER", "database.user", "")
+	cfg.Database.Password = Secret(l.string("DB_PASSWORD", "database.password", ""))
+	cfg.Database.Name = l.string("DB_NAME", "database.name", "")
+
+	cfg.Database.SSLMode = l.string("DB_SSLMODE", "database.sslmode", "prefer")
+	cfg.Database.SSLRootCert = l.string("DB_SSLROOTCERT", "database.sslrootcert", "")
+	cfg.Database.SSLCert = l.string("DB_SSLCERT", "database.sslcert", "")
+	cfg.Database.SSLKey = l.string("DB_SSLKEY", "database.sslkey", "")
+
+	cfg.Database.MaxConns = int32(l.int("DB_MAX_CONNS", "database.max_conns", 10))
+	cfg.Database.MinConns = int32(l.int("DB_MIN_CONNS", "database.min_conns", 0))
+	cfg.Database.MaxConnLifetime = l.duration("DB_MAX_CONN_LIFETIME", "database.max_conn_lifetime", time.Hour)
+	cfg.Database.MaxConnIdleTime = l.duration("DB_MAX_CONN_IDLE_TIME", "database.max_conn_idle_time", 30*time.Minute)
+	cfg.Database.HealthCheckPeriod = l.duration("DB_HEALTH_CHECK_PERIOD", "database.health_check_period", time.Minute)
+	cfg.Database.ConnectTimeout = l.duration("DB_CONNECT_TIMEOUT", "database.connect_timeout", 5*time.Second)
+
+	cfg.Health.Timeout = l.duration("HEALTH_CHECK_TIMEOUT", "health.timeout", 2*time.Second)
+
+	cfg.Logging.Level = l.logLevel("LOG_LEVEL", "logging.level", slog.LevelInfo)
+	cfg.Logging.Format = l.string("LOG_FORMAT", "logging.format", "json")
+
+	cfg.Tracing.Exporter = l.string("TRACING_EXPORTER", "tracing.exporter", "none")
+	cfg.Tracing.ServiceName = l.string("TRACING_SERVICE_NAME", "tracing.service_name", "transactions")
+	cfg.Tracing.SampleRatio = l.float("TRACING_SAMPLE_RATIO", "tracing.sample_ratio", 1)
+	cfg.Tracing.OTLPEndpoint = l.string("TRACING_OTLP_ENDPOINT", "tracing.otlp_endpoint", "")
+	cfg.Tracing.OTLPInsecure = l.bool("TRACING_OTLP_INSECURE", "tracing.otlp_insecure", false)
+
+	cfg.Auth.Enabled = l.bool("AUTH_ENABLED", "auth.enabled", true)
+	cfg.Auth.AdminToken = Secret(l.string("AUTH_ADMIN_TOKEN", "auth.admin_token", ""))
+	cfg.Auth.SigningKey = Secret(l.string("AUTH_SIGNING_KEY", "auth.signing_key", ""))
+	cfg.Auth.HMACMaxSkew = l.duration("AUTH_HMAC_MAX_SKEW", "auth.hmac_max_skew", 5*time.Minute)
+	cfg.Auth.JWT.Issuer = l.string("AUTH_JWT_ISSUER", "auth.jwt.issuer", "")
+	cfg.Auth.JWT.Audience = l.string("AUTH_JWT_AUDIENCE", "auth.jwt.audience", "")
+	cfg.Auth.JWT.JWKSFile = l.string("AUTH_JWT_JWKS_FILE", "auth.jwt.jwks_file", "")
+	cfg.Auth.JWT.JWKSURL = l.string("AUTH_JWT_JWKS_URL", "auth.jwt.jwks_url", "")
+	cfg.Auth.JWT.JWKSRefresh = l.duration("AUTH_JWT_JWKS_REFRESH", "auth.jwt.jwks_refresh", 10*time.Minute)
+	cfg.Auth.JWT.ClientIDClaim = l.string("AUTH_JWT_CLIENT_ID_CLAIM", "auth.jwt.client_id_claim", "sub")
+	cfg.Auth.JWT.RolesClaim = l.string("AUTH_JWT_ROLES_CLAIM", "auth.jwt.roles_claim", "roles")
+	cfg.Auth.JWT.TenantClaim = l.string("AUTH_JWT_TENANT_CLAIM", "auth.jwt.tenant_claim", "tenant_id")
+	cfg.Auth.JWT.Leeway = l.duration("AUTH_JWT_LEEWAY", "auth.jwt.leeway", 30*time.Second)
+
+	cfg.Audit.Key = Secret(l.string("AUDIT_KEY", "audit.key", ""))
+
+	cfg.Tenancy.AllowCrossTenantTransfers = l.bool("TENANCY_ALLOW_CROSS_TENANT_TRANSFERS", "tenancy.allow_cross_tenant_transfers", false)
+
+	cfg.RateLimit.Enabled = l.bool("RATE_LIMIT_ENABLED", "rate_limit.enabled", true)
+	cfg.RateLimit.ClientRate = l.float("RATE_LIMIT_CLIENT_RATE", "rate_limit.client_rate", 50)
+	cfg.RateLimit.ClientBurst = l.int("RATE_LIMIT_CLIENT_BURST", "rate_limit.client_burst", 100)
+	cfg.RateLimit.AccountRate = l.float("RATE_LIMIT_ACCOUNT_RATE", "rate_limit.account_rate", 5)
+	cfg.RateLimit.AccountBurst = l.int("RATE_LIMIT_ACCOUNT_BURST", "rate_limit.account_burst", 10)
+
+	cfg.API.UnversionedRoutes = l.bool("API_UNVERSIONED_ROUTES", "api.unversioned_routes", true)
+	cfg.API.UnversionedSunset = l.date("API_UNVERSIONED_SUNSET", "api.unversioned_sunset")
+
+	cfg.Events.Sink = l.string("EVENTS_SINK", "events.sink", "none")
+	cfg.Events.File = l.string("EVENTS_FILE", "events.file", "")
+	cfg.Events.HTTPURL = l.string("EVENTS_HTTP_URL", "events.http_url", "")
+	cfg.Events.RelayInterval = l.duration("EVENTS_RELAY_INTERVAL", "events.relay_interval", time.Second)
+	cfg.Events.BatchSize = l.int("EVENTS_BATCH_SIZE", "events.batch_size", 100)
+	cfg.Events.RetryBase = l.duration("EVENTS_RETRY_BASE", "events.retry_base", time.Second)
+	cfg.Events.MaxAttempts = l.int("EVENTS_MAX_ATTEMPTS", "events.max_attempts", 20)
+
+	cfg.Webhooks.MaxAttempts = l.int("WEBHOOKS_MAX_ATTEMPTS", "webhooks.max_attempts", 8)
+	cfg.Webhooks.RetryBase = l.duration("WEBHOOKS_RETRY_BASE", "webhooks.retry_base", 30*time.Second)
+	cfg.Webhooks.Timeout = l.duration("WEBHOOKS_TIMEOUT", "webhooks.timeout", 10*time.Second)
+
+	cfg.Reconcile.Interval = l.duration("RECONCILE_INTERVAL", "reconcile.interval", 24*time.Hour)
+	for _, tenantId := range strings.Split(l.string("RECONCILE_TENANTS", "reconcile.tenants", tenant.Default), ",") {
+		cfg.Reconcile.Tenants = append(cfg.Reconcile.Tenants, strings.TrimSpace(tenantId))
+	}
+	cfg.Reconcile.Tolerance = l.float("RECONCILE_TOLERANCE", "reconcile.tolerance", 1e-6)
+
+	cfg.Export.Dir = l.string("EXPORT_DIR", "export.dir", "exports")
+	cfg.Export.Format = l.string("EXPORT_FORMAT", "export.format", "csv")
+	for _, tenantId := range strings.Split(l.string("EXPORT_TENANTS", "export.tenants", tenant.Default), ",") {
+		cfg.Export.Tenants = append(cfg.Export.Tenants, strings.TrimSpace(tenantId))
+	}
+
+	cfg.Import.BatchSize = l.int("IMPORT_BATCH_SIZE", "import.batch_size", 5000)
+
+	l.problems = append(l.problems, cfg.validate()...)
+	if len(l.problems) > 0 {
+		return Config{}, &ValidationError{Problems: l.problems}
+	}
+
+	return cfg, nil
+}
+
+func (c Config) validate() []string {
+	var problems []string
+
+	if c.Server.Addr == "" {
+		problems = append(problems, "HTTP_ADDR must not be empty")
+	}
+	if c.Server.GRPCAddr != "" && c.Server.GRPCAddr == c.Server.Addr {
+		problems = append(problems, "GRPC_ADDR must differ from HTTP_ADDR")
+	}
+
+	if !slices.Contains(storageBackends, c.Storage.Backend) {
+		problems = append(problems, fmt.Sprintf("STORAGE_BACKEND must be one of %s, got %q", strings.Join(storageBackends, ", "), c.Storage.Backend))
+	}
+	// the memory backend doesn't connect to a database
+	inMemory := c.Storage.Backend == "memory"
+	db := c.Database
+	if db.Host == "" && !inMemory {
+		problems = append(problems, "DB_HOST is required")
+	}
+	if db.Port < 1 || db.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("DB_PORT must be between 1 and 65535, got %d", db.Port))
+	}
+	if db.User == "" && !inMemory {
+		problems = append(problems, "DB_USER is required")
+	}
+	if db.Name == "" && !inMemory {
+		problems = append(problems, "DB_NAME is required")
+	}
+	if !slices.Contains(sslModes, db.SSLMode) {
+		problems = append(problems, fmt.Sprintf("DB_SSLMODE must be one of %s, got %q", strings.Join(sslModes, ", "), db.SSLMode))
+	}
+	if (db.SSLCert == "") != (db.SSLKey == "") {
+		problems = append(problems, "DB_SSLCERT and DB_SSLKEY must be set together")
+	}
+	if db.MaxConns < 1 {
+		problems = append(problems, fmt.Sprintf("DB_MAX_CONNS must be at least 1, got %d", db.MaxConns))
+	}
+	if db.MinConns < 0 {
+		problems = append(problems, fmt.Sprintf("DB_MIN_CONNS must not be negative, got %d", db.MinConns))
+	}
+	if db.MinConns > db.MaxConns {
+		problems = append(problems, fmt.Sprintf("DB_MIN_CONNS (%d) must not exceed DB_MAX_CONNS (%d)", db.MinConns, db.MaxConns))
+	}
+	if db.ConnectTimeout < 0 {
+		problems = append(problems, "DB_CONNECT_TIMEOUT must not be negative")
+	}
+
+	if c.Health.Timeout <= 0 {
+		problems = append(problems, "HEALTH_CHECK_TIMEOUT must be positive")
+	}
+
+	if !slices.Contains(logFormats, c.Logging.Format) {
+		problems = append(problems, fmt.Sprintf("LOG_FORMAT must be one of %s, got %q", strings.Join(logFormats, ", "), c.Logging.Format))
+	}
+
+	if !slices.Contains(exporters, c.Tracing.Exporter) {
+		problems = append(problems, fmt.Sprintf("TRACING_EXPORTER must be one of %s, got %q", strings.Join(exporters, ", "), c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, fmt.Sprintf("TRACING_SAMPLE_RATIO must be between 0 and 1, got %g", c.Tracing.SampleRatio))
+	}
+
+	if c.Auth.HMACMaxSkew <= 0 {
+		problems = append(problems, "AUTH_HMAC_MAX_SKEW must be positive")
+	}
+	if _, err := secretbox.ParseKey(c.Auth.SigningKey.Value()); len(c.Auth.SigningKey) > 0 && err != nil {
+		problems = append(problems, "AUTH_SIGNING_KEY must be 32 bytes, base64 encoded")
+	}
+	if _, err := secretbox.ParseKey(c.Audit.Key.Value()); len(c.Audit.Key) > 0 && err != nil {
+		problems = append(problems, "AUDIT_KEY must be 32 bytes, base64 encoded")
+	}
+	if len(c.Auth.AdminToken) > 0 && len(c.Auth.AdminToken) < minAdminTokenLength {
+		problems = append(problems, fmt.Sprintf("AUTH_ADMIN_TOKEN must be at least %d characters", minAdminTokenLength))
+	}
+	if c.Auth.AdminToken.Value() == sampleAdminToken {
+		problems = append(problems, "AUTH_ADMIN_TOKEN must not be the placeholder of .env.sample")
+	}
+
+	jwt := c.Auth.JWT
+	if jwt.JWKSFile != "" && jwt.JWKSURL != "" {
+		problems = append(problems, "AUTH_JWT_JWKS_FILE and AUTH_JWT_JWKS_URL must not both be set")
+	}
+	if jwt.Enabled() {
+		if jwt.Issuer == "" {
+			problems = append(problems, "AUTH_JWT_ISSUER is required when JWT authentication is enabled")
+		}
+		if jwt.Audience == "" {
+			problems = append(problems, "AUTH_JWT_AUDIENCE is required when JWT authentication is enabled")
+		}
+		if jwt.JWKSURL != "" {
+			u, err := url.Parse(jwt.JWKSURL)
+			if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				problems = append(problems, fmt.Sprintf("AUTH_JWT_JWKS_URL must be an http(s) url, got %q", jwt.JWKSURL))
+			}
+		}
+		if jwt.JWKSRefresh <= 0 {
+			problems = append(problems, "AUTH_JWT_JWKS_REFRESH must be positive")
+		}
+		if jwt.ClientIDClaim == "" {
+			problems = append(problems, "AUTH_JWT_CLIENT_ID_CLAIM must not be empty")
+		}
+		if jwt.Leeway < 0 {
+			problems = append(problems, "AUTH_JWT_LEEWAY must not be negative")
+		}
+	}
+
+	rl := c.RateLimit
+	if rl.ClientRate < 0 || rl.AccountRate < 0 {
+		problems = append(problems, "RATE_LIMIT_CLIENT_RATE and RATE_LIMIT_ACCOUNT_RATE must not be negative")
+	}
+	if rl.ClientRate > 0 && rl.ClientBurst < 1 {
+		problems = append(problems, fmt.Sprintf("RATE_LIMIT_CLIENT_BURST must be at least 1, got %d", rl.ClientBurst))
+	}
+	if rl.AccountRate > 0 && rl.AccountBurst < 1 {
+		problems = append(problems, fmt.Sprintf("RATE_LIMIT_ACCOUNT_BURST must be at least 1, got %d", rl.AccountBurst))
+	}
+
+	ev := c.Events
+	if !slices.Contains(eventSinks, ev.Sink) {
+		problems = append(problems, fmt.Sprintf("EVENTS_SINK must be one of %s, got %q", strings.Join(eventSinks, ", "), ev.Sink))
+	}
+	if ev.Sink == "file" && ev.File == "" {
+		problems = append(problems, "EVENTS_FILE is required with the file sink")
+	}
+	if ev.Sink == "http" {
+		u, err := url.Parse(ev.HTTPURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("EVENTS_HTTP_URL must be an http(s) url, got %q", ev.HTTPURL))
+		}
+	}
+	if ev.RelayInterval <= 0 {
+		problems = append(problems, "EVENTS_RELAY_INTERVAL must be positive")
+	}
+	if ev.BatchSize < 1 {
+		problems = append(problems, fmt.Sprintf("EVENTS_BATCH_SIZE must be at least 1, got %d", ev.BatchSize))
+	}
+	if ev.RetryBase <= 0 {
+		problems = append(problems, "EVENTS_RETRY_BASE must be positive")
+	}
+	if ev.MaxAttempts < 1 {
+		problems = append(problems, fmt.Sprintf("EVENTS_MAX_ATTEMPTS must be at least 1, got %d", ev.MaxAttempts))
+	}
+
+	wh := c.Webhooks
+	if wh.MaxAttempts < 1 {
+		problems = append(problems, fmt.Sprintf("WEBHOOKS_MAX_ATTEMPTS must be at least 1, got %d", wh.MaxAttempts))
+	}
+	if wh.RetryBase <= 0 {
+		problems = append(problems, "WEBHOOKS_RETRY_BASE must be positive")
+	}
+	if wh.Timeout <= 0 {
+		problems = append(problems, "WEBHOOKS_TIMEOUT must be positive")
+	}
+
+	rc := c.Reconcile
+	if rc.Interval < 0 {
+		problems = append(problems, "RECONCILE_INTERVAL must not be negative")
+	}
+	for _, tenantId := range rc.Tenants {
+		if !tenant.Valid(tenantId) {
+			problems = append(problems, fmt.Sprintf("RECONCILE_TENANTS must be a comma separated list of tenant ids, got %q", strings.Join(rc.Tenants, ",")))
+			break
+		}
+	}
+	if rc.Tolerance <= 0 {
+		problems = append(problems, "RECONCILE_TOLERANCE must be positive")
+	}
+
+	ex := c.Export
+	if ex.Dir == "" {
+		problems = append(problems, "EXPORT_DIR must not be empty")
+	}
+	if !slices.Contains(exportFormats, ex.Format) {
+		problems = append(problems, fmt.Sprintf("EXPORT_FORMAT must be one of %s, got %q", strings.Join(exportFormats, ", "), ex.Format))
+	}
+	for _, tenantId := range ex.Tenants {
+		if !tenant.Valid(tenantId) {
+			problems = append(problems, fmt.Sprintf("EXPORT_TENANTS must be a comma separated list of tenant ids, got %q", strings.Join(ex.Tenants, ",")))
+			break
+		}
+	}
+
+	if c.Import.BatchSize <= 0 {
+		problems = append(problems, "IMPORT_BATCH_SIZE must be positive")
+	}
+
+	return problems
+}
+
+// URL returns the connection string for the database. Credentials and
+// parameters are escaped so passwords may contain any character.
+func (d DatabaseConfig) URL() string {
+	query := url.Values{}
+	query.Set("sslmode", d.SSLMode)
+	if d.SSLRootCert != "" {
+		query.Set("sslrootcert", d.SSLRootCert)
+	}
+	if d.SSLCert != "" {
+		query.Set("sslcert", d.SSLCert)
+		query.Set("sslkey", d.SSLKey)
+	}
+	if d.ConnectTimeout > 0 {
+		// connect_timeout is in whole seconds and 0 waits forever, so
+		// shorter timeouts are rounded up
+		query.Set("connect_timeout", strconv.Itoa(int(math.Ceil(d.ConnectTimeout.Seconds()))))
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(d.User, d.Password.Value()),
+		Host:     net.JoinHostPort(d.Host, strconv.Itoa(d.Port)),
+		Path:     "/" + d.Name,
+		RawQuery: query.Encode(),
+	}
+
+	return u.String()
+}
+
+func (d DatabaseConfig) PoolConfig() (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(d.URL())
+	if err != nil {
+		return nil, err
+	}
+
+	poolConfig.MaxConns = d.MaxConns
+	poolConfig.MinConns = d.MinConns
+	poolConfig.MaxConnLifetime = d.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = d.MaxConnIdleTime
+	poolConfig.HealthCheckPeriod = d.HealthCheckPeriod
+
+	return poolConfig, nil
+}
+
+// ValidationError lists every problem found while loading configuration so
+// they can all be fixed in one go.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
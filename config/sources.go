@@ -0,0 +1,234 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// sources holds every place a setting can come from. Environment style keys
+// (DB_HOST) are used for the process environment and the .env file, dotted
+// keys (database.host) for the config file.
+type sources struct {
+	lookupEnv func(string) (string, bool)
+	dotEnv    map[string]string
+	file      map[string]string
+}
+
+func newSources(opts Options) (*sources, error) {
+	src := &sources{
+		lookupEnv: opts.LookupEnv,
+	}
+	if src.lookupEnv == nil {
+		src.lookupEnv = os.LookupEnv
+	}
+
+	envFile := opts.EnvFile
+	if envFile == "" {
+		envFile = defaultEnvFile
+	}
+
+	dotEnv, err := godotenv.Read(envFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading %s: %w", envFile, err)
+	}
+	src.dotEnv = dotEnv
+
+	configFile := opts.ConfigFile
+	if configFile == "" {
+		configFile, _ = src.lookup("CONFIG_FILE", "")
+	}
+
+	if configFile != "" {
+		file, err := readConfigFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+		src.file = file
+	}
+
+	return src, nil
+}
+
+func (s *sources) lookup(envKey, fileKey string) (string, bool) {
+	if value, ok := s.lookupEnv(envKey); ok {
+		return value, true
+	}
+
+	if value, ok := s.dotEnv[envKey]; ok {
+		return value, true
+	}
+
+	if value, ok := s.file[fileKey]; ok && fileKey != "" {
+		return value, true
+	}
+
+	return "", false
+}
+
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var tree map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported format %q, use .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	flatten("", tree, values)
+
+	return values, nil
+}
+
+func flatten(prefix string, tree map[string]any, values map[string]string) {
+	for key, value := range tree {
+		fullKey := strings.ToLower(key)
+		if prefix != "" {
+			fullKey = prefix + "." + fullKey
+		}
+
+		switch v := value.(type) {
+		case map[string]any:
+			flatten(fullKey, v, values)
+		case nil:
+		case time.Time:
+			values[fullKey] = v.Format(time.RFC3339)
+		default:
+			values[fullKey] = fmt.Sprint(v)
+		}
+	}
+}
+
+// loader converts raw values into typed settings, collecting every problem
+// instead of stopping at the first one.
+type loader struct {
+	src      *sources
+	problems []string
+}
+
+func (l *loader) string(envKey, fileKey, fallback string) string {
+	value, ok := l.src.lookup(envKey, fileKey)
+	if !ok {
+		return fallback
+	}
+
+	return strings.TrimSpace(value)
+}
+
+func (l *loader) int(envKey, fileKey string, fallback int) int {
+	value, ok := l.src.lookup(envKey, fileKey)
+	if !ok {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s must be an integer, got %q", envKey, value))
+		return fallback
+	}
+
+	return parsed
+}
+
+func (l *loader) float(envKey, fileKey string, fallback float64) float64 {
+	value, ok := l.src.lookup(envKey, fileKey)
+	if !ok {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s must be a number, got %q", envKey, value))
+		return fallback
+	}
+
+	return parsed
+}
+
+func (l *loader) bool(envKey, fileKey string, fallback bool) bool {
+	value, ok := l.src.lookup(envKey, fileKey)
+	if !ok {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s must be a boolean, got %q", envKey, value))
+		return fallback
+	}
+
+	return parsed
+}
+
+func (l *loader) duration(envKey, fileKey string, fallback time.Duration) time.Duration {
+	value, ok := l.src.lookup(envKey, fileKey)
+	if !ok {
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s must be a duration such as 30s or 5m, got %q", envKey, value))
+		return fallback
+	}
+
+	return parsed
+}
+
+// date reads a date such as 2025-06-30, or a RFC 3339 timestamp. A missing
+// or empty value is the zero time.
+func (l *loader) date(envKey, fileKey string) time.Time {
+	value, ok := l.src.lookup(envKey, fileKey)
+	if !ok || strings.TrimSpace(value) == "" {
+		return time.Time{}
+	}
+
+	value = strings.TrimSpace(value)
+	parsed, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		parsed, err = time.Parse(time.RFC3339, value)
+	}
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s must be a date such as 2025-06-30, got %q", envKey, value))
+		return time.Time{}
+	}
+
+	return parsed.UTC()
+}
+
+func (l *loader) logLevel(envKey, fileKey string, fallback slog.Level) slog.Level {
+	value, ok := l.src.lookup(envKey, fileKey)
+	if !ok {
+		return fallback
+	}
+
+	var level slog.Level
+	err := level.UnmarshalText([]byte(strings.TrimSpace(value)))
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s must be one of debug, info, warn, error, got %q", envKey, value))
+		return fallback
+	}
+
+	return level
+}
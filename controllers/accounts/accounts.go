@@ -4,42 +4,70 @@ import (
 	"net/http"
 	"strconv"
 
-	accounts_dao "github.com/ashwin-m/transactions/daos/accounts"
+	"github.com/ashwin-m/transactions/middlewares/audit"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	accountsservice "github.com/ashwin-m/transactions/services/accounts"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgerrcode"
-	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type createAccountsRequest struct {
-	Id      int64  `json:"account_id"`
-	Balance string `json:"initial_balance"`
+	Id      int64  `json:"account_id" binding:"required"`
+	Balance string `json:"initial_balance" binding:"required,numeric"`
+	// OwnerId may only be set by admins, accounts are otherwise owned by the
+	// client creating them.
+	OwnerId string `json:"owner_id"`
 }
 
 type accounts struct {
 	Id      int64   `json:"account_id"`
 	Balance float64 `json:"balance"`
+	OwnerId string  `json:"owner_id,omitempty"`
 }
 
 type handler struct {
-	dao accounts_dao.Dao
+	service accountsservice.Service
 }
 
 type Handler interface {
-	RouteGroup(*gin.Engine)
+	RouteGroup(gin.IRouter)
+	Describe(*openapi3.T)
 }
 
-func NewHandler(dao accounts_dao.Dao) Handler {
+func NewHandler(service accountsservice.Service) Handler {
 	return &handler{
-		dao: dao,
+		service: service,
 	}
 }
 
-func (h *handler) RouteGroup(r *gin.Engine) {
+func (h *handler) RouteGroup(r gin.IRouter) {
 	rg := r.Group("/accounts")
 
-	rg.POST("", h.create)
-	rg.GET("/:id", h.get)
+	rg.POST("", auth.RequireRole(auth.RoleTransfer), audit.Succeeds(http.StatusNoContent), h.create)
+	rg.GET("/:id", auth.RequireRole(auth.RoleRead), h.get)
+}
+
+// Describe documents the routes registered by RouteGroup.
+func (h *handler) Describe(doc *openapi3.T) {
+	create := openapi3.NewOperation()
+	create.OperationID = "createAccount"
+	create.Summary = "Create an account with an initial balance"
+	create.RequestBody = openapi.JSONBody(createAccountsRequest{})
+	create.AddResponse(http.StatusNoContent, openapi.EmptyResponse("The account was created"))
+	openapi.Problems(create, apperrors.CodeValidationFailed, apperrors.CodeUnauthorized, apperrors.CodeForbidden,
+		apperrors.CodeAccountAlreadyExists, apperrors.CodeRateLimited, apperrors.CodeInternal)
+	openapi.Operation(doc, http.MethodPost, "/accounts", create)
+
+	get := openapi3.NewOperation()
+	get.OperationID = "getAccount"
+	get.Summary = "Get an account by id"
+	get.AddParameter(openapi3.NewPathParameter("id").WithSchema(openapi3.NewInt64Schema()))
+	get.AddResponse(http.StatusOK, openapi.JSONResponse("The account", accounts{}))
+	openapi.Problems(get, apperrors.CodeValidationFailed, apperrors.CodeUnauthorized, apperrors.CodeForbidden,
+		apperrors.CodeAccountNotFound, apperrors.CodeRateLimited, apperrors.CodeInternal)
+	openapi.Operation(doc, http.MethodGet, "/accounts/:id", get)
 }
 
 func (h *handler) create(c *gin.Context) {
@@ -47,24 +75,17 @@ func (h *handler) create(c *gin.Context) {
 
 	err := c.ShouldBindJSON(&account)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	initialAccountBalance, err := strconv.ParseFloat(account.Balance, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperrors.Abort(c, apperrors.FromBinding(err))
 		return
 	}
 
-	_, err = h.dao.Create(account.Id, initialAccountBalance)
+	_, err = h.service.Create(c.Request.Context(), accountsservice.CreateRequest{
+		Id:             account.Id,
+		InitialBalance: account.Balance,
+		OwnerId:        account.OwnerId,
+	})
 	if err != nil {
-		if err, ok := err.(*pgconn.PgError); ok && pgerrcode.IsIntegrityConstraintViolation(err.Code) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperrors.Abort(c, err)
 		return
 	}
 
@@ -77,25 +98,20 @@ func (h *handler) get(c *gin.Context) {
 
 	id, err := strconv.ParseInt(idString, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apperrors.Abort(c, apperrors.Validation(apperrors.FieldError{Field: "id", Message: "must be an integer"}))
 		return
 	}
 
-	account, err := h.dao.GetById(id)
+	account, err := h.service.Get(c.Request.Context(), id)
 	if err != nil {
-		switch err {
-		case pgx.ErrNoRows:
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
+		apperrors.Abort(c, err)
+		return
 	}
 
 	accountResponse := accounts{
 		Id:      account.GetId(),
 		Balance: account.GetBalance(),
+		OwnerId: account.GetOwnerId(),
 	}
 
 	c.JSON(http.StatusOK, accountResponse)
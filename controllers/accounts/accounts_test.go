@@ -9,18 +9,31 @@ import (
 	"testing"
 
 	daoMocks "github.com/ashwin-m/transactions/daos/accounts/mocks"
+	outboxMocks "github.com/ashwin-m/transactions/daos/outbox/mocks"
+	"github.com/ashwin-m/transactions/middlewares/auth"
 	accounts_model "github.com/ashwin-m/transactions/models/accounts"
+	eventsmodel "github.com/ashwin-m/transactions/models/events"
+	accountsservice "github.com/ashwin-m/transactions/services/accounts"
+	"github.com/ashwin-m/transactions/utils/openapi"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	adminIdentity  = auth.Identity{ClientID: "admin", Roles: []string{auth.RoleAdmin}}
+	clientIdentity = auth.Identity{ClientID: "client-1", Roles: []string{auth.RoleRead, auth.RoleTransfer}}
 )
 
 func TestAccountsCreate_BalancePassedAsInt(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity))
 
 	mockDao := daoMocks.NewDao(t)
 
-	h := NewHandler(mockDao)
+	h := NewHandler(accountsservice.NewService(nil, mockDao, outboxMocks.NewDao(t)))
 	h.RouteGroup(router)
 
 	body := `{
@@ -34,15 +47,16 @@ func TestAccountsCreate_BalancePassedAsInt(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "{\"error\":\"json: cannot unmarshal number into Go struct field createAccountsRequest.initial_balance of type string\"}", w.Body.String())
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:validation-failed\",\"title\":\"The request is invalid\",\"status\":400,\"detail\":\"the request has invalid fields\",\"instance\":\"/accounts\",\"code\":\"VALIDATION_FAILED\",\"errors\":[{\"field\":\"initial_balance\",\"message\":\"must be a string\"}]}", w.Body.String())
 }
 
 func TestAccountsCreate_BadFloatPassed(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity))
 
 	mockDao := daoMocks.NewDao(t)
 
-	h := NewHandler(mockDao)
+	h := NewHandler(accountsservice.NewService(nil, mockDao, outboxMocks.NewDao(t)))
 	h.RouteGroup(router)
 
 	body := `{
@@ -56,16 +70,21 @@ func TestAccountsCreate_BadFloatPassed(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "{\"error\":\"strconv.ParseFloat: parsing \\\"abc\\\": invalid syntax\"}", w.Body.String())
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:validation-failed\",\"title\":\"The request is invalid\",\"status\":400,\"detail\":\"the request has invalid fields\",\"instance\":\"/accounts\",\"code\":\"VALIDATION_FAILED\",\"errors\":[{\"field\":\"initial_balance\",\"message\":\"must be a decimal number\"}]}", w.Body.String())
 }
 
 func TestAccountsCreate_DaoReturnError(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity))
 
 	mockDao := daoMocks.NewDao(t)
-	mockDao.EXPECT().Create(int64(123), 100.23344).Return(accounts_model.Accounts{}, errors.New("test"))
+	mockDao.EXPECT().Create(mock.Anything, mock.Anything, int64(123), 100.23344, "admin").Return(accounts_model.Accounts{}, errors.New("test"))
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectRollback()
 
-	h := NewHandler(mockDao)
+	h := NewHandler(accountsservice.NewService(mockDB, mockDao, outboxMocks.NewDao(t)))
 	h.RouteGroup(router)
 
 	body := `{
@@ -79,16 +98,23 @@ func TestAccountsCreate_DaoReturnError(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "{\"error\":\"test\"}", w.Body.String())
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:internal-error\",\"title\":\"Internal server error\",\"status\":500,\"detail\":\"the request could not be completed\",\"instance\":\"/accounts\",\"code\":\"INTERNAL_ERROR\"}", w.Body.String())
 }
 
 func TestAccountsCreate_Success(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity))
 
 	mockDao := daoMocks.NewDao(t)
-	mockDao.EXPECT().Create(int64(123), 100.23344).Return(accounts_model.Accounts{}, nil)
+	mockDao.EXPECT().Create(mock.Anything, mock.Anything, int64(123), 100.23344, "admin").Return(accounts_model.Accounts{}, nil)
+	mockOutboxDao := outboxMocks.NewDao(t)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeAccountCreated, int64(123), eventsmodel.AccountCreated{AccountId: 123, OwnerId: "admin", Balance: 100.23344}).Return(nil)
 
-	h := NewHandler(mockDao)
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectCommit()
+
+	h := NewHandler(accountsservice.NewService(mockDB, mockDao, mockOutboxDao))
 	h.RouteGroup(router)
 
 	body := `{
@@ -103,14 +129,16 @@ func TestAccountsCreate_Success(t *testing.T) {
 
 	assert.Equal(t, http.StatusNoContent, w.Code)
 	assert.Empty(t, w.Body)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
 }
 
 func TestAccountsGet_BadAccountId(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity))
 
 	mockDao := daoMocks.NewDao(t)
 
-	h := NewHandler(mockDao)
+	h := NewHandler(accountsservice.NewService(nil, mockDao, outboxMocks.NewDao(t)))
 	h.RouteGroup(router)
 
 	w := httptest.NewRecorder()
@@ -118,18 +146,19 @@ func TestAccountsGet_BadAccountId(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "{\"error\":\"strconv.ParseInt: parsing \\\"abc\\\": invalid syntax\"}", w.Body.String())
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:validation-failed\",\"title\":\"The request is invalid\",\"status\":400,\"detail\":\"the request has invalid fields\",\"instance\":\"/accounts/abc\",\"code\":\"VALIDATION_FAILED\",\"errors\":[{\"field\":\"id\",\"message\":\"must be an integer\"}]}", w.Body.String())
 }
 
 func TestAccountsGet_DaoReturnNoRowsError(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity))
 
 	accountId := int64(123)
 
 	mockDao := daoMocks.NewDao(t)
-	mockDao.EXPECT().GetById(accountId).Return(accounts_model.Accounts{}, pgx.ErrNoRows)
+	mockDao.EXPECT().GetById(mock.Anything, accountId).Return(accounts_model.Accounts{}, pgx.ErrNoRows)
 
-	h := NewHandler(mockDao)
+	h := NewHandler(accountsservice.NewService(nil, mockDao, outboxMocks.NewDao(t)))
 	h.RouteGroup(router)
 
 	w := httptest.NewRecorder()
@@ -138,18 +167,19 @@ func TestAccountsGet_DaoReturnNoRowsError(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusNotFound, w.Code)
-	assert.Equal(t, "{\"error\":\"no rows in result set\"}", w.Body.String())
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:account-not-found\",\"title\":\"The account was not found\",\"status\":404,\"detail\":\"account 123 was not found\",\"instance\":\"/accounts/123\",\"code\":\"ACCOUNT_NOT_FOUND\"}", w.Body.String())
 }
 
 func TestAccountsGet_DaoReturnError(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity))
 
 	accountId := int64(123)
 
 	mockDao := daoMocks.NewDao(t)
-	mockDao.EXPECT().GetById(accountId).Return(accounts_model.Accounts{}, errors.New("test"))
+	mockDao.EXPECT().GetById(mock.Anything, accountId).Return(accounts_model.Accounts{}, errors.New("test"))
 
-	h := NewHandler(mockDao)
+	h := NewHandler(accountsservice.NewService(nil, mockDao, outboxMocks.NewDao(t)))
 	h.RouteGroup(router)
 
 	w := httptest.NewRecorder()
@@ -158,11 +188,12 @@ func TestAccountsGet_DaoReturnError(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "{\"error\":\"test\"}", w.Body.String())
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:internal-error\",\"title\":\"Internal server error\",\"status\":500,\"detail\":\"the request could not be completed\",\"instance\":\"/accounts/123\",\"code\":\"INTERNAL_ERROR\"}", w.Body.String())
 }
 
 func TestAccountsGet_Success(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity))
 
 	accountId := int64(123)
 	balance := 123.234
@@ -172,11 +203,11 @@ func TestAccountsGet_Success(t *testing.T) {
 	account := accounts_model.Accounts{}
 	account.SetId(accountId)
 	account.SetBalance(balance)
-	mockDao.EXPECT().GetById(accountId).Return(account, nil)
+	mockDao.EXPECT().GetById(mock.Anything, accountId).Return(account, nil)
 
 	expectedResponse := "{\"account_id\":123,\"balance\":123.234}"
 
-	h := NewHandler(mockDao)
+	h := NewHandler(accountsservice.NewService(nil, mockDao, outboxMocks.NewDao(t)))
 	h.RouteGroup(router)
 
 	w := httptest.NewRecorder()
@@ -187,3 +218,155 @@ func TestAccountsGet_Success(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, expectedResponse, w.Body.String())
 }
+
+func TestAccountsCreate_OwnedByCaller(t *testing.T) {
+	router := gin.Default()
+	router.Use(auth.WithIdentity(clientIdentity))
+
+	mockDao := daoMocks.NewDao(t)
+	mockDao.EXPECT().Create(mock.Anything, mock.Anything, int64(123), 10.0, "client-1").Return(accounts_model.Accounts{}, nil)
+	mockOutboxDao := outboxMocks.NewDao(t)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeAccountCreated, int64(123), eventsmodel.AccountCreated{AccountId: 123, OwnerId: "client-1", Balance: 10.0}).Return(nil)
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectCommit()
+
+	h := NewHandler(accountsservice.NewService(mockDB, mockDao, mockOutboxDao))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/accounts", strings.NewReader(`{"account_id": 123, "initial_balance": "10"}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestAccountsCreate_ForOtherOwnerRequiresAdmin(t *testing.T) {
+	router := gin.Default()
+	router.Use(auth.WithIdentity(clientIdentity))
+
+	mockDao := daoMocks.NewDao(t)
+
+	h := NewHandler(accountsservice.NewService(nil, mockDao, outboxMocks.NewDao(t)))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/accounts", strings.NewReader(`{"account_id": 123, "initial_balance": "10", "owner_id": "client-2"}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:forbidden\",\"title\":\"The request is not allowed\",\"status\":403,\"detail\":\"only admins may create accounts for other clients\",\"instance\":\"/accounts\",\"code\":\"FORBIDDEN\"}", w.Body.String())
+}
+
+func TestAccountsCreate_AdminForOtherOwner(t *testing.T) {
+	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity))
+
+	mockDao := daoMocks.NewDao(t)
+	mockDao.EXPECT().Create(mock.Anything, mock.Anything, int64(123), 10.0, "client-2").Return(accounts_model.Accounts{}, nil)
+	mockOutboxDao := outboxMocks.NewDao(t)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeAccountCreated, int64(123), eventsmodel.AccountCreated{AccountId: 123, OwnerId: "client-2", Balance: 10.0}).Return(nil)
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectCommit()
+
+	h := NewHandler(accountsservice.NewService(mockDB, mockDao, mockOutboxDao))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/accounts", strings.NewReader(`{"account_id": 123, "initial_balance": "10", "owner_id": "client-2"}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestAccountsCreate_RequiresTransferRole(t *testing.T) {
+	router := gin.Default()
+	router.Use(auth.WithIdentity(auth.Identity{ClientID: "client-1", Roles: []string{auth.RoleRead}}))
+
+	mockDao := daoMocks.NewDao(t)
+
+	h := NewHandler(accountsservice.NewService(nil, mockDao, outboxMocks.NewDao(t)))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/accounts", strings.NewReader(`{"account_id": 123, "initial_balance": "10"}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:forbidden\",\"title\":\"The request is not allowed\",\"status\":403,\"detail\":\"insufficient permissions\",\"instance\":\"/accounts\",\"code\":\"FORBIDDEN\"}", w.Body.String())
+}
+
+func TestAccountsGet_OwnAccount(t *testing.T) {
+	router := gin.Default()
+	router.Use(auth.WithIdentity(clientIdentity))
+
+	account := accounts_model.Accounts{}
+	account.SetId(123)
+	account.SetBalance(10)
+	account.SetOwnerId("client-1")
+
+	mockDao := daoMocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, int64(123)).Return(account, nil)
+
+	h := NewHandler(accountsservice.NewService(nil, mockDao, outboxMocks.NewDao(t)))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/accounts/123", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{\"account_id\":123,\"balance\":10,\"owner_id\":\"client-1\"}", w.Body.String())
+}
+
+func TestAccountsGet_ForeignAccount(t *testing.T) {
+	router := gin.Default()
+	router.Use(auth.WithIdentity(clientIdentity))
+
+	account := accounts_model.Accounts{}
+	account.SetId(123)
+	account.SetOwnerId("client-2")
+
+	mockDao := daoMocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, int64(123)).Return(account, nil)
+
+	h := NewHandler(accountsservice.NewService(nil, mockDao, outboxMocks.NewDao(t)))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/accounts/123", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:forbidden\",\"title\":\"The request is not allowed\",\"status\":403,\"detail\":\"you do not have access to this account\",\"instance\":\"/accounts/123\",\"code\":\"FORBIDDEN\"}", w.Body.String())
+}
+
+func TestAccountsGet_MissingAccountLooksForeign(t *testing.T) {
+	router := gin.Default()
+	router.Use(auth.WithIdentity(clientIdentity))
+
+	mockDao := daoMocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, int64(123)).Return(accounts_model.Accounts{}, pgx.ErrNoRows)
+
+	h := NewHandler(accountsservice.NewService(nil, mockDao, outboxMocks.NewDao(t)))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/accounts/123", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:forbidden\",\"title\":\"The request is not allowed\",\"status\":403,\"detail\":\"you do not have access to this account\",\"instance\":\"/accounts/123\",\"code\":\"FORBIDDEN\"}", w.Body.String())
+}
+
+func TestAccounts_DescribesRegisteredRoutes(t *testing.T) {
+	router := gin.Default()
+
+	h := NewHandler(accountsservice.NewService(nil, daoMocks.NewDao(t), outboxMocks.NewDao(t)))
+	h.RouteGroup(router)
+
+	assert.Empty(t, openapi.Drift(openapi.New(h), router.Routes()))
+}
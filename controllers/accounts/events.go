@@ -0,0 +1,200 @@
+package accounts
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	eventsmodel "github.com/ashwin-m/transactions/models/events"
+	accountsservice "github.com/ashwin-m/transactions/services/accounts"
+	"github.com/ashwin-m/transactions/services/events"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// lastEventIdHeader is sent by clients reconnecting to a stream, with
+	// the id of the last event they received.
+	lastEventIdHeader = "Last-Event-ID"
+
+	// replayPageSize is how many events are read from the outbox at a time.
+	replayPageSize = 100
+
+	// heldBackRetryInterval and heldBackRetries are how often, and how many
+	// times, the outbox is read again for an announced event it holds back.
+	heldBackRetryInterval = 500 * time.Millisecond
+	heldBackRetries       = 10
+
+	// keepAliveInterval is how often an idle stream sends a comment, so
+	// proxies don't time it out.
+	keepAliveInterval = 15 * time.Second
+)
+
+type eventsHandler struct {
+	service accountsservice.Service
+	broker  *events.Broker
+}
+
+// NewEventsHandler builds the stream of account events. It is separate from
+// the account endpoints as it is only served under /v1.
+func NewEventsHandler(service accountsservice.Service, broker *events.Broker) Handler {
+	return &eventsHandler{
+		service: service,
+		broker:  broker,
+	}
+}
+
+func (h *eventsHandler) RouteGroup(r gin.IRouter) {
+	rg := r.Group("/accounts")
+
+	rg.GET("/:id/events", auth.RequireRole(auth.RoleRead), h.stream)
+}
+
+// Describe documents the routes registered by RouteGroup.
+func (h *eventsHandler) Describe(doc *openapi3.T) {
+	stream := openapi3.NewOperation()
+	stream.OperationID = "streamAccountEvents"
+	stream.Summary = "Stream the events of an account as server-sent events"
+	stream.AddParameter(openapi3.NewPathParameter("id").WithSchema(openapi3.NewInt64Schema()))
+	stream.AddParameter(openapi3.NewHeaderParameter(lastEventIdHeader).
+		WithDescription("Resumes a stream after the event with this id").
+		WithSchema(openapi3.NewStringSchema().WithPattern(`^[0-9]+-[0-9]+$`)))
+	stream.AddResponse(http.StatusOK, openapi3.NewResponse().
+		WithDescription("A stream of events, each a message of the outbox").
+		WithContent(openapi3.NewContentWithSchema(openapi3.NewStringSchema(), []string{sse.ContentType})))
+	openapi.Problems(stream, apperrors.CodeValidationFailed, apperrors.CodeUnauthorized, apperrors.CodeForbidden,
+		apperrors.CodeAccountNotFound, apperrors.CodeRateLimited, apperrors.CodeInternal)
+	openapi.Operation(doc, http.MethodGet, "/accounts/:id/events", stream)
+}
+
+// stream sends the events about an account in the order of their cursors,
+// each with its cursor as its id. A client reconnecting with Last-Event-ID
+// gets the events after it, others the ones committed from now on. The
+// outbox is read a page at a time whenever the broker announces an event,
+// and read again shortly after while an earlier transaction holds the event
+// back. The stream ends when the broker drops it, and the client resumes
+// from where it was.
+func (h *eventsHandler) stream(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Validation(apperrors.FieldError{Field: "id", Message: "must be an integer"}))
+		return
+	}
+
+	var cursor eventsmodel.Cursor
+	resume := c.GetHeader(lastEventIdHeader) != ""
+	if resume {
+		cursor, err = eventsmodel.ParseCursor(c.GetHeader(lastEventIdHeader))
+		if err != nil {
+			apperrors.Abort(c, apperrors.Validation(apperrors.FieldError{Field: lastEventIdHeader, Message: "must be an event id sent by this stream"}))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	tenantId, _ := tenant.FromContext(ctx)
+
+	// subscribe before reading the outbox, so no event is committed between
+	// the two unannounced
+	subscription := h.broker.Subscribe(tenantId, id)
+	defer subscription.Close()
+
+	// the outbox is read before responding, so a client without access to
+	// the account gets an error
+	var page []eventsmodel.Events
+	if resume {
+		page, err = h.service.Events(ctx, id, cursor, replayPageSize)
+	} else {
+		cursor, err = h.service.EventsHorizon(ctx, id)
+	}
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// announced holds the ids of the events announced by the broker and not
+	// sent yet. An event read before its announcement arrives stays in it
+	// until the retries run out.
+	announced := map[int64]bool{}
+	catchUp := func() bool {
+		for {
+			for _, event := range page {
+				send(c, event)
+				cursor = event.Cursor()
+				delete(announced, event.GetId())
+			}
+			c.Writer.Flush()
+
+			if len(page) < replayPageSize {
+				return true
+			}
+			page, err = h.service.Events(ctx, id, cursor, replayPageSize)
+			if err != nil {
+				// the client resumes from the last event it got
+				slog.ErrorContext(ctx, "unable to read account events", slog.Int64("account_id", id), slog.Any("error", err))
+				return false
+			}
+		}
+	}
+	if !catchUp() {
+		return
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	// events held back past the retries are read at the next keep-alive
+	var retry <-chan time.Time
+	retries := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-subscription.Messages():
+			if !ok {
+				return
+			}
+			announced[message.Id] = true
+			retries = heldBackRetries
+		case <-retry:
+			retries--
+		case <-keepAlive.C:
+			_, _ = c.Writer.WriteString(": keep-alive\n\n")
+		}
+
+		page, err = h.service.Events(ctx, id, cursor, replayPageSize)
+		if err != nil {
+			slog.ErrorContext(ctx, "unable to read account events", slog.Int64("account_id", id), slog.Any("error", err))
+			return
+		}
+		if !catchUp() {
+			return
+		}
+
+		retry = nil
+		if len(announced) > 0 && retries > 0 {
+			retry = time.After(heldBackRetryInterval)
+		} else {
+			clear(announced)
+		}
+	}
+}
+
+func send(c *gin.Context, event eventsmodel.Events) {
+	c.Render(-1, sse.Event{
+		Id:    event.Cursor().String(),
+		Event: event.GetType(),
+		Data:  events.NewMessage(event),
+	})
+}
@@ -0,0 +1,236 @@
+package accounts
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	daoMocks "github.com/ashwin-m/transactions/daos/accounts/mocks"
+	outboxMocks "github.com/ashwin-m/transactions/daos/outbox/mocks"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	accounts_model "github.com/ashwin-m/transactions/models/accounts"
+	eventsmodel "github.com/ashwin-m/transactions/models/events"
+	accountsservice "github.com/ashwin-m/transactions/services/accounts"
+	"github.com/ashwin-m/transactions/services/events"
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func ownedAccount(id int64, ownerId string) accounts_model.Accounts {
+	account := accounts_model.Accounts{}
+	account.SetId(id)
+	account.SetOwnerId(ownerId)
+	return account
+}
+
+func outboxEvent(xid uint64, id int64) eventsmodel.Events {
+	event := eventsmodel.Events{}
+	event.SetId(id)
+	event.SetXid(xid)
+	event.SetType(eventsmodel.TypeAccountBalanceChanged)
+	event.SetAccountId(123)
+	event.SetPayload(json.RawMessage(`{"balance":10}`))
+	return event
+}
+
+func notification(id int64) string {
+	message, _ := json.Marshal(events.NewMessage(outboxEvent(0, id)))
+	return string(message)
+}
+
+func cursor(xid uint64, id int64) eventsmodel.Cursor {
+	return eventsmodel.Cursor{Xid: xid, Id: id}
+}
+
+func newStreamServer(t *testing.T, service accountsservice.Service, broker *events.Broker) *httptest.Server {
+	router := gin.New()
+	router.Use(auth.WithIdentity(clientIdentity))
+	NewEventsHandler(service, broker).RouteGroup(router)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// readIds reads a stream until it has seen count events, and returns their
+// ids.
+func readIds(t *testing.T, resp *http.Response, count int) []string {
+	var ids []string
+	scanner := bufio.NewScanner(resp.Body)
+	for len(ids) < count && scanner.Scan() {
+		if id, ok := strings.CutPrefix(scanner.Text(), "id:"); ok {
+			ids = append(ids, id)
+		}
+	}
+	assert.NoError(t, scanner.Err())
+	return ids
+}
+
+func TestAccountsEvents_StreamsCommittedEvents(t *testing.T) {
+	broker := events.NewBroker(nil, nil)
+
+	mockDao := daoMocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, int64(123)).Return(ownedAccount(123, "client-1"), nil)
+
+	mockOutboxDao := outboxMocks.NewDao(t)
+	mockOutboxDao.EXPECT().Horizon(mock.Anything).RunAndReturn(func(ctx context.Context) (eventsmodel.Cursor, error) {
+		// the stream is subscribed by the time the horizon is read
+		broker.Dispatch(notification(7))
+		return cursor(10, 0), nil
+	})
+	mockOutboxDao.EXPECT().ListByAccount(mock.Anything, int64(123), cursor(10, 0), replayPageSize).
+		Return([]eventsmodel.Events{outboxEvent(10, 7)}, nil)
+	mockOutboxDao.EXPECT().ListByAccount(mock.Anything, int64(123), cursor(10, 7), replayPageSize).
+		Return([]eventsmodel.Events{}, nil).Maybe()
+
+	server := newStreamServer(t, accountsservice.NewService(nil, mockDao, mockOutboxDao), broker)
+
+	resp, err := http.Get(server.URL + "/accounts/123/events")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	scanner := bufio.NewScanner(resp.Body)
+	var lines []string
+	for len(lines) < 3 && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	assert.Equal(t, "id:10-7", lines[0])
+	assert.Equal(t, "event:account.balance_changed", lines[1])
+	assert.JSONEq(t, notification(7), strings.TrimPrefix(lines[2], "data:"))
+}
+
+func TestAccountsEvents_ResumesAfterLastEventId(t *testing.T) {
+	broker := events.NewBroker(nil, nil)
+
+	mockDao := daoMocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, int64(123)).Return(ownedAccount(123, "client-1"), nil)
+
+	mockOutboxDao := outboxMocks.NewDao(t)
+	mockOutboxDao.EXPECT().ListByAccount(mock.Anything, int64(123), cursor(10, 5), replayPageSize).
+		RunAndReturn(func(ctx context.Context, accountId int64, after eventsmodel.Cursor, limit int) ([]eventsmodel.Events, error) {
+			// 7 is announced after it was read, 8 after the replay
+			broker.Dispatch(notification(7))
+			broker.Dispatch(notification(8))
+			return []eventsmodel.Events{outboxEvent(10, 6), outboxEvent(11, 7)}, nil
+		})
+	mockOutboxDao.EXPECT().ListByAccount(mock.Anything, int64(123), cursor(11, 7), replayPageSize).
+		Return([]eventsmodel.Events{outboxEvent(12, 8)}, nil).Once()
+	mockOutboxDao.EXPECT().ListByAccount(mock.Anything, int64(123), cursor(12, 8), replayPageSize).
+		Return([]eventsmodel.Events{}, nil).Maybe()
+
+	server := newStreamServer(t, accountsservice.NewService(nil, mockDao, mockOutboxDao), broker)
+
+	req, _ := http.NewRequest("GET", server.URL+"/accounts/123/events", nil)
+	req.Header.Set("Last-Event-ID", "10-5")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, []string{"10-6", "11-7", "12-8"}, readIds(t, resp, 3))
+}
+
+func TestAccountsEvents_WaitsForEarlierTransactions(t *testing.T) {
+	broker := events.NewBroker(nil, nil)
+
+	mockDao := daoMocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, int64(123)).Return(ownedAccount(123, "client-1"), nil)
+
+	mockOutboxDao := outboxMocks.NewDao(t)
+	// 7 got its id in transaction 10 and 8 in transaction 11, which commits
+	// first: the outbox holds 8 back until 10 commits
+	mockOutboxDao.EXPECT().Horizon(mock.Anything).RunAndReturn(func(ctx context.Context) (eventsmodel.Cursor, error) {
+		broker.Dispatch(notification(8))
+		return cursor(10, 0), nil
+	})
+	mockOutboxDao.EXPECT().ListByAccount(mock.Anything, int64(123), cursor(10, 0), replayPageSize).
+		Return([]eventsmodel.Events{}, nil).Once()
+	mockOutboxDao.EXPECT().ListByAccount(mock.Anything, int64(123), cursor(10, 0), replayPageSize).
+		RunAndReturn(func(ctx context.Context, accountId int64, after eventsmodel.Cursor, limit int) ([]eventsmodel.Events, error) {
+			broker.Dispatch(notification(7))
+			return []eventsmodel.Events{outboxEvent(10, 7), outboxEvent(11, 8)}, nil
+		}).Once()
+	mockOutboxDao.EXPECT().ListByAccount(mock.Anything, int64(123), cursor(11, 8), replayPageSize).
+		Return([]eventsmodel.Events{}, nil).Maybe()
+
+	server := newStreamServer(t, accountsservice.NewService(nil, mockDao, mockOutboxDao), broker)
+
+	resp, err := http.Get(server.URL + "/accounts/123/events")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, []string{"10-7", "11-8"}, readIds(t, resp, 2))
+}
+
+func TestAccountsEvents_ReplaysPageByPage(t *testing.T) {
+	mockDao := daoMocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, int64(123)).Return(ownedAccount(123, "client-1"), nil)
+
+	var firstPage []eventsmodel.Events
+	for id := range int64(replayPageSize) {
+		firstPage = append(firstPage, outboxEvent(10, id+1))
+	}
+	mockOutboxDao := outboxMocks.NewDao(t)
+	mockOutboxDao.EXPECT().ListByAccount(mock.Anything, int64(123), cursor(0, 0), replayPageSize).Return(firstPage, nil)
+	mockOutboxDao.EXPECT().ListByAccount(mock.Anything, int64(123), cursor(10, replayPageSize), replayPageSize).
+		Return([]eventsmodel.Events{outboxEvent(11, replayPageSize+1)}, nil)
+
+	server := newStreamServer(t, accountsservice.NewService(nil, mockDao, mockOutboxDao), events.NewBroker(nil, nil))
+
+	req, _ := http.NewRequest("GET", server.URL+"/accounts/123/events", nil)
+	req.Header.Set("Last-Event-ID", "0-0")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	ids := readIds(t, resp, replayPageSize+1)
+	assert.Len(t, ids, replayPageSize+1)
+	assert.Equal(t, "11-101", ids[replayPageSize])
+}
+
+func TestAccountsEvents_ForeignAccount(t *testing.T) {
+	mockDao := daoMocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, int64(123)).Return(ownedAccount(123, "client-2"), nil)
+
+	server := newStreamServer(t, accountsservice.NewService(nil, mockDao, outboxMocks.NewDao(t)), events.NewBroker(nil, nil))
+
+	resp, err := http.Get(server.URL + "/accounts/123/events")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestAccountsEvents_InvalidLastEventId(t *testing.T) {
+	router := gin.New()
+	router.Use(auth.WithIdentity(clientIdentity))
+	NewEventsHandler(nil, events.NewBroker(nil, nil)).RouteGroup(router)
+
+	// plain event ids can't tell which events were missed
+	for _, lastEventId := range []string{"latest", "41", "10-", "-41"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/accounts/123/events", nil)
+		req.Header.Set("Last-Event-ID", lastEventId)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, lastEventId)
+		assert.Contains(t, w.Body.String(), `{"field":"Last-Event-ID","message":"must be an event id sent by this stream"}`)
+	}
+}
+
+func TestAccountsEvents_DescribesRegisteredRoutes(t *testing.T) {
+	router := gin.New()
+
+	h := NewEventsHandler(nil, nil)
+	h.RouteGroup(router)
+
+	assert.Empty(t, openapi.Drift(openapi.New(h), router.Routes()))
+}
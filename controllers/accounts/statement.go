@@ -0,0 +1,110 @@
+package accounts
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	"github.com/ashwin-m/transactions/services/statements"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+type statementHandler struct {
+	service statements.Service
+}
+
+// NewStatementHandler builds the account statement endpoint. It is separate
+// from the account endpoints as it is only served under /v1.
+func NewStatementHandler(service statements.Service) Handler {
+	return &statementHandler{
+		service: service,
+	}
+}
+
+func (h *statementHandler) RouteGroup(r gin.IRouter) {
+	rg := r.Group("/accounts")
+
+	rg.GET("/:id/statement", auth.RequireRole(auth.RoleRead), h.get)
+}
+
+// Describe documents the routes registered by RouteGroup.
+func (h *statementHandler) Describe(doc *openapi3.T) {
+	formats := make([]any, len(statements.Formats))
+	for i, format := range statements.Formats {
+		formats[i] = format
+	}
+
+	content := openapi3.NewContentWithJSONSchemaRef(openapi.Schema(statements.Statement{}))
+	content[statements.ContentTypes[statements.FormatCSV]] = openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema())
+	content[statements.ContentTypes[statements.FormatPDF]] = openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema().WithFormat("binary"))
+
+	get := openapi3.NewOperation()
+	get.OperationID = "getAccountStatement"
+	get.Summary = "Get the statement of an account over a period"
+	get.AddParameter(openapi3.NewPathParameter("id").WithSchema(openapi3.NewInt64Schema()))
+	get.AddParameter(openapi3.NewQueryParameter("from").
+		WithDescription("First day of the period, in UTC. Defaults to the first day of the current month. " +
+			"For an account with transfers older than their dates, it must be after the day those were dated").
+		WithSchema(openapi3.NewStringSchema().WithFormat("date")))
+	get.AddParameter(openapi3.NewQueryParameter("to").
+		WithDescription("Last day of the period, in UTC. Defaults to today").
+		WithSchema(openapi3.NewStringSchema().WithFormat("date")))
+	get.AddParameter(openapi3.NewQueryParameter("format").
+		WithSchema(openapi3.NewStringSchema().WithEnum(formats...).WithDefault(statements.FormatJSON)))
+	get.AddResponse(http.StatusOK, openapi3.NewResponse().
+		WithDescription("The opening balance, every transfer with the balance after it, and the closing balance").
+		WithContent(content))
+	openapi.Problems(get, apperrors.CodeValidationFailed, apperrors.CodeUnauthorized, apperrors.CodeForbidden,
+		apperrors.CodeAccountNotFound, apperrors.CodeRateLimited, apperrors.CodeInternal)
+	openapi.Operation(doc, http.MethodGet, "/accounts/:id/statement", get)
+}
+
+func (h *statementHandler) get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Validation(apperrors.FieldError{Field: "id", Message: "must be an integer"}))
+		return
+	}
+
+	format := c.DefaultQuery("format", statements.FormatJSON)
+	if !slices.Contains(statements.Formats, format) {
+		apperrors.Abort(c, apperrors.Validation(apperrors.FieldError{Field: "format", Message: "must be one of json, csv or pdf"}))
+		return
+	}
+
+	ctx := c.Request.Context()
+	statement, err := h.service.Get(ctx, statements.Request{
+		AccountId: id,
+		From:      c.Query("from"),
+		To:        c.Query("to"),
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	if format != statements.FormatJSON {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="statement-%d-%s-%s.%s"`, statement.AccountId, statement.From, statement.To, format))
+	}
+	c.Header("Content-Type", statements.ContentTypes[format])
+	c.Status(http.StatusOK)
+
+	// the statement is sent as its transfers are read. A failure is still
+	// reported as a problem until the first bytes are sent, the statement
+	// is cut short after that.
+	err = h.service.Write(ctx, c.Writer, format, statement)
+	if err != nil {
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			apperrors.Abort(c, err)
+			return
+		}
+		slog.ErrorContext(ctx, "unable to write the statement", slog.Int64("account_id", id), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,160 @@
+package accounts
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	daoMocks "github.com/ashwin-m/transactions/daos/accounts/mocks"
+	transactionsMocks "github.com/ashwin-m/transactions/daos/transactions/mocks"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	accounts_model "github.com/ashwin-m/transactions/models/accounts"
+	transactionsmodel "github.com/ashwin-m/transactions/models/transactions"
+	accountsservice "github.com/ashwin-m/transactions/services/accounts"
+	"github.com/ashwin-m/transactions/services/statements"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newStatementRouter(t *testing.T) (*gin.Engine, *daoMocks.Dao, *transactionsMocks.Dao) {
+	router := gin.New()
+	router.Use(auth.WithIdentity(clientIdentity))
+
+	mockDao := daoMocks.NewDao(t)
+	mockTransactionsDao := transactionsMocks.NewDao(t)
+	NewStatementHandler(statements.NewService(accountsservice.NewService(nil, mockDao, nil), mockTransactionsDao)).RouteGroup(router)
+
+	return router, mockDao, mockTransactionsDao
+}
+
+func expectStatement(mockDao *daoMocks.Dao, mockTransactionsDao *transactionsMocks.Dao) {
+	account := ownedAccount(123, clientIdentity.ClientID)
+	account.SetTenantId("default")
+	account.SetInitialBalance(50)
+	mockDao.EXPECT().GetById(mock.Anything, int64(123)).Return(account, nil)
+
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	transfer := transactionsmodel.Transactions{}
+	transfer.SetId(7)
+	transfer.SetTenantId("default")
+	transfer.SetSourceAccountId(456)
+	transfer.SetDestinationTenantId("default")
+	transfer.SetDestinationAccountId(123)
+	transfer.SetAmount(12.5)
+	transfer.SetCreatedAt(time.Date(2026, 9, 2, 10, 0, 0, 0, time.UTC))
+
+	mockTransactionsDao.EXPECT().Undated(mock.Anything, int64(123)).Return(time.Time{}, nil)
+	mockTransactionsDao.EXPECT().NetByAccount(mock.Anything, int64(123), from).Return(10, nil)
+	mockTransactionsDao.EXPECT().ListByAccountBetween(mock.Anything, int64(123), from, int64(0), to, mock.Anything).Return([]transactionsmodel.Transactions{transfer}, nil)
+}
+
+func TestAccountsStatement_JSON(t *testing.T) {
+	router, mockDao, mockTransactionsDao := newStatementRouter(t)
+	expectStatement(mockDao, mockTransactionsDao)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/accounts/123/statement?from=2026-09-01&to=2026-09-30", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+	assert.Contains(t, w.Body.String(), `"opening_balance":60,"transfers":[{"transfer_id":7,"date":"2026-09-02T10:00:00Z","type":"credit","counterparty_tenant_id":"default","counterparty_account_id":456,"amount":12.5,"balance":72.5}],"credits":12.5,"debits":0,"closing_balance":72.5}`)
+}
+
+func TestAccountsStatement_CSV(t *testing.T) {
+	router, mockDao, mockTransactionsDao := newStatementRouter(t)
+	expectStatement(mockDao, mockTransactionsDao)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/accounts/123/statement?from=2026-09-01&to=2026-09-30&format=csv", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="statement-123-2026-09-01-2026-09-30.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "date,description,transfer_id,counterparty_tenant_id,counterparty_account_id,amount,balance\n"+
+		"2026-09-01,opening balance,,,,,60\n"+
+		"2026-09-02T10:00:00Z,credit,7,default,456,12.5,72.5\n"+
+		"2026-09-30,closing balance,,,,,72.5\n", w.Body.String())
+}
+
+func TestAccountsStatement_PDF(t *testing.T) {
+	router, mockDao, mockTransactionsDao := newStatementRouter(t)
+	expectStatement(mockDao, mockTransactionsDao)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/accounts/123/statement?from=2026-09-01&to=2026-09-30&format=pdf", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(w.Body.String(), "%PDF-"))
+}
+
+func TestAccountsStatement_BadFormat(t *testing.T) {
+	router, _, _ := newStatementRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/accounts/123/statement?format=xml", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:validation-failed\",\"title\":\"The request is invalid\",\"status\":400,\"detail\":\"the request has invalid fields\",\"instance\":\"/accounts/123/statement\",\"code\":\"VALIDATION_FAILED\",\"errors\":[{\"field\":\"format\",\"message\":\"must be one of json, csv or pdf\"}]}", w.Body.String())
+}
+
+func TestAccountsStatement_BadPeriod(t *testing.T) {
+	router, _, _ := newStatementRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/accounts/123/statement?from=2026-09-30&to=2026-09-01", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "{\"field\":\"to\",\"message\":\"must not be before from\"}")
+}
+
+func TestAccountsStatement_FailureBeforeSending(t *testing.T) {
+	router, mockDao, mockTransactionsDao := newStatementRouter(t)
+	account := ownedAccount(123, clientIdentity.ClientID)
+	account.SetTenantId("default")
+	mockDao.EXPECT().GetById(mock.Anything, int64(123)).Return(account, nil)
+	mockTransactionsDao.EXPECT().Undated(mock.Anything, int64(123)).Return(time.Time{}, nil)
+	mockTransactionsDao.EXPECT().NetByAccount(mock.Anything, int64(123), mock.Anything).Return(0, nil)
+	mockTransactionsDao.EXPECT().ListByAccountBetween(mock.Anything, int64(123), mock.Anything, int64(0), mock.Anything, mock.Anything).Return(nil, errors.New("test"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/accounts/123/statement?from=2026-09-01&to=2026-09-30&format=csv", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, apperrors.ContentType, w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+}
+
+func TestAccountsStatement_ForeignAccount(t *testing.T) {
+	router, mockDao, _ := newStatementRouter(t)
+	mockDao.EXPECT().GetById(mock.Anything, int64(123)).Return(accounts_model.Accounts{}, pgx.ErrNoRows)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/accounts/123/statement", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestStatementHandler_DescribesRegisteredRoutes(t *testing.T) {
+	router := gin.New()
+	h := NewStatementHandler(nil)
+	h.RouteGroup(router)
+
+	assert.Empty(t, openapi.Drift(openapi.New(h), router.Routes()))
+}
@@ -0,0 +1,162 @@
+package apikeys
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	apikeysdao "github.com/ashwin-m/transactions/daos/apikeys"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	apikeysmodel "github.com/ashwin-m/transactions/models/apikeys"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/secretbox"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+type createApiKeyRequest struct {
+	ClientId string   `json:"client_id" binding:"required"`
+	TenantId string   `json:"tenant_id"`
+	Name     string   `json:"name"`
+	Roles    []string `json:"roles"`
+}
+
+type apiKey struct {
+	KeyId    string   `json:"key_id"`
+	ClientId string   `json:"client_id"`
+	TenantId string   `json:"tenant_id"`
+	Name     string   `json:"name"`
+	Roles    []string `json:"roles"`
+	ApiKey   string   `json:"api_key"`
+	// SigningSecret signs HMAC requests, it is only issued when the
+	// server has a signing key.
+	SigningSecret string    `json:"signing_secret,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type handler struct {
+	dao       apikeysdao.Dao
+	box       *secretbox.Box
+	adminAuth gin.HandlerFunc
+}
+
+type Handler interface {
+	RouteGroup(gin.IRouter)
+}
+
+// NewHandler serves the admin API for keys. Keys are issued with a signing
+// secret sealed with box, unless it is nil.
+func NewHandler(dao apikeysdao.Dao, box *secretbox.Box, adminAuth gin.HandlerFunc) Handler {
+	return &handler{
+		dao:       dao,
+		box:       box,
+		adminAuth: adminAuth,
+	}
+}
+
+func (h *handler) RouteGroup(r gin.IRouter) {
+	rg := r.Group("/admin/api-keys", h.adminAuth)
+
+	rg.POST("", h.create)
+	rg.POST("/:id/rotate", h.rotate)
+	rg.DELETE("/:id", h.revoke)
+}
+
+func (h *handler) create(c *gin.Context) {
+	var request createApiKeyRequest
+
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromBinding(err))
+		return
+	}
+
+	tenantId := request.TenantId
+	if tenantId == "" {
+		tenantId = tenant.Default
+	}
+	if !tenant.Valid(tenantId) {
+		apperrors.Abort(c, apperrors.Validation(apperrors.FieldError{Field: "tenant_id", Message: "is not a valid tenant id"}))
+		return
+	}
+
+	roles := request.Roles
+	if len(roles) == 0 {
+		roles = auth.DefaultRoles
+	}
+	for _, role := range roles {
+		if !auth.ValidRole(role) {
+			apperrors.Abort(c, apperrors.Validation(apperrors.FieldError{Field: "roles", Message: fmt.Sprintf("unknown role %q", role)}))
+			return
+		}
+	}
+
+	id, key, hash := auth.GenerateAPIKey()
+	signingSecret, sealed := h.signingSecret(id)
+	created, err := h.dao.Create(c.Request.Context(), id, request.ClientId, tenantId, request.Name, roles, hash, sealed)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newApiKeyResponse(created, key, signingSecret))
+}
+
+func (h *handler) rotate(c *gin.Context) {
+	id, key, hash := auth.GenerateAPIKey()
+	signingSecret, sealed := h.signingSecret(id)
+	rotated, err := h.dao.Rotate(c.Request.Context(), c.Param("id"), id, hash, sealed)
+	if err != nil {
+		if errors.Is(err, apikeysdao.ErrNotFound) {
+			apperrors.Abort(c, apperrors.Newf(apperrors.CodeNotFound, "api key %s was not found", c.Param("id")))
+			return
+		}
+
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newApiKeyResponse(rotated, key, signingSecret))
+}
+
+func (h *handler) revoke(c *gin.Context) {
+	err := h.dao.Revoke(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, apikeysdao.ErrNotFound) {
+			apperrors.Abort(c, apperrors.Newf(apperrors.CodeNotFound, "api key %s was not found", c.Param("id")))
+			return
+		}
+
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// signingSecret issues the signing secret of a new key and seals it for
+// storage, bound to the key id. Without a box keys have none.
+func (h *handler) signingSecret(id string) (string, []byte) {
+	if h.box == nil {
+		return "", nil
+	}
+
+	signingSecret := auth.GenerateSigningSecret()
+	return signingSecret, h.box.Seal([]byte(signingSecret), []byte(id))
+}
+
+// newApiKeyResponse is the only place the plain key and signing secret are
+// ever returned, they can't be recovered later.
+func newApiKeyResponse(model apikeysmodel.ApiKeys, key, signingSecret string) apiKey {
+	return apiKey{
+		KeyId:         model.GetId(),
+		ClientId:      model.GetClientId(),
+		TenantId:      model.GetTenantId(),
+		Name:          model.GetName(),
+		Roles:         model.GetRoles(),
+		ApiKey:        key,
+		SigningSecret: signingSecret,
+		CreatedAt:     model.GetCreatedAt(),
+	}
+}
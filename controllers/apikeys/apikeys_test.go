@@ -0,0 +1,232 @@
+package apikeys
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	apikeysdao "github.com/ashwin-m/transactions/daos/apikeys"
+	apikeysdaomocks "github.com/ashwin-m/transactions/daos/apikeys/mocks"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	apikeysmodel "github.com/ashwin-m/transactions/models/apikeys"
+	"github.com/ashwin-m/transactions/utils/secretbox"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const adminToken = "test-admin-token"
+
+var box, _ = secretbox.New(bytes.Repeat([]byte{1}, secretbox.KeySize))
+
+func adminRequest(method, url, body string) *http.Request {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	return req
+}
+
+func TestApiKeysCreate_RequiresAdmin(t *testing.T) {
+	router := gin.Default()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+
+	h := NewHandler(mockDao, nil, auth.AdminToken(adminToken))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/api-keys", strings.NewReader(`{"client_id": "client-1"}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestApiKeysCreate_MissingClientId(t *testing.T) {
+	router := gin.Default()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+
+	h := NewHandler(mockDao, nil, auth.AdminToken(adminToken))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("POST", "/admin/api-keys", `{"name": "ci"}`))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestApiKeysCreate_Success(t *testing.T) {
+	router := gin.Default()
+
+	createdAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	mockDao := apikeysdaomocks.NewDao(t)
+	mockDao.EXPECT().Create(mock.Anything, mock.Anything, "client-1", "retail", "ci", []string{auth.RoleRead}, mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, id, clientId, tenantId, name string, roles []string, keyHash, signingSecret []byte) (apikeysmodel.ApiKeys, error) {
+			apiKey := apikeysmodel.ApiKeys{}
+			apiKey.SetId(id)
+			apiKey.SetClientId(clientId)
+			apiKey.SetTenantId(tenantId)
+			apiKey.SetName(name)
+			apiKey.SetRoles(roles)
+			apiKey.SetKeyHash(keyHash)
+			apiKey.SetSigningSecret(signingSecret)
+			apiKey.SetCreatedAt(createdAt)
+			return apiKey, nil
+		})
+
+	h := NewHandler(mockDao, box, auth.AdminToken(adminToken))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("POST", "/admin/api-keys", `{"client_id": "client-1", "tenant_id": "retail", "name": "ci", "roles": ["read"]}`))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response apiKey
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "client-1", response.ClientId)
+	assert.Equal(t, "retail", response.TenantId)
+	assert.Equal(t, "ci", response.Name)
+	assert.Equal(t, []string{auth.RoleRead}, response.Roles)
+	assert.Equal(t, createdAt, response.CreatedAt)
+	assert.True(t, strings.HasPrefix(response.ApiKey, "tk_"+response.KeyId+"_"))
+	assert.True(t, strings.HasPrefix(response.SigningSecret, "tksec_"))
+}
+
+func TestApiKeysCreate_WithoutSigningKey(t *testing.T) {
+	router := gin.Default()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+	mockDao.EXPECT().Create(mock.Anything, mock.Anything, "client-1", tenant.Default, "", auth.DefaultRoles, mock.Anything, []byte(nil)).Return(apikeysmodel.ApiKeys{}, nil)
+
+	h := NewHandler(mockDao, nil, auth.AdminToken(adminToken))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("POST", "/admin/api-keys", `{"client_id": "client-1"}`))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "signing_secret")
+}
+
+func TestApiKeysCreate_DaoReturnsError(t *testing.T) {
+	router := gin.Default()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+	mockDao.EXPECT().Create(mock.Anything, mock.Anything, "client-1", tenant.Default, "", auth.DefaultRoles, mock.Anything, mock.Anything).Return(apikeysmodel.ApiKeys{}, errors.New("test"))
+
+	h := NewHandler(mockDao, nil, auth.AdminToken(adminToken))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("POST", "/admin/api-keys", `{"client_id": "client-1"}`))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:internal-error\",\"title\":\"Internal server error\",\"status\":500,\"detail\":\"the request could not be completed\",\"instance\":\"/admin/api-keys\",\"code\":\"INTERNAL_ERROR\"}", w.Body.String())
+}
+
+func TestApiKeysCreate_InvalidTenant(t *testing.T) {
+	router := gin.Default()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+
+	h := NewHandler(mockDao, nil, auth.AdminToken(adminToken))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("POST", "/admin/api-keys", `{"client_id": "client-1", "tenant_id": "Retail,Corp"}`))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:validation-failed\",\"title\":\"The request is invalid\",\"status\":400,\"detail\":\"the request has invalid fields\",\"instance\":\"/admin/api-keys\",\"code\":\"VALIDATION_FAILED\",\"errors\":[{\"field\":\"tenant_id\",\"message\":\"is not a valid tenant id\"}]}", w.Body.String())
+}
+
+func TestApiKeysCreate_UnknownRole(t *testing.T) {
+	router := gin.Default()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+
+	h := NewHandler(mockDao, nil, auth.AdminToken(adminToken))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("POST", "/admin/api-keys", `{"client_id": "client-1", "roles": ["superuser"]}`))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:validation-failed\",\"title\":\"The request is invalid\",\"status\":400,\"detail\":\"the request has invalid fields\",\"instance\":\"/admin/api-keys\",\"code\":\"VALIDATION_FAILED\",\"errors\":[{\"field\":\"roles\",\"message\":\"unknown role \\\"superuser\\\"\"}]}", w.Body.String())
+}
+
+func TestApiKeysRotate_NotFound(t *testing.T) {
+	router := gin.Default()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+	mockDao.EXPECT().Rotate(mock.Anything, "abc", mock.Anything, mock.Anything, mock.Anything).Return(apikeysmodel.ApiKeys{}, apikeysdao.ErrNotFound)
+
+	h := NewHandler(mockDao, nil, auth.AdminToken(adminToken))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("POST", "/admin/api-keys/abc/rotate", ""))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:not-found\",\"title\":\"The resource was not found\",\"status\":404,\"detail\":\"api key abc was not found\",\"instance\":\"/admin/api-keys/abc/rotate\",\"code\":\"NOT_FOUND\"}", w.Body.String())
+}
+
+func TestApiKeysRotate_Success(t *testing.T) {
+	router := gin.Default()
+
+	rotated := apikeysmodel.ApiKeys{}
+	rotated.SetId("def")
+	rotated.SetClientId("client-1")
+
+	mockDao := apikeysdaomocks.NewDao(t)
+	mockDao.EXPECT().Rotate(mock.Anything, "abc", mock.Anything, mock.Anything, mock.Anything).Return(rotated, nil)
+
+	h := NewHandler(mockDao, box, auth.AdminToken(adminToken))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("POST", "/admin/api-keys/abc/rotate", ""))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), "\"api_key\":\"tk_")
+	assert.Contains(t, w.Body.String(), "\"signing_secret\":\"tksec_")
+}
+
+func TestApiKeysRevoke_NotFound(t *testing.T) {
+	router := gin.Default()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+	mockDao.EXPECT().Revoke(mock.Anything, "abc").Return(apikeysdao.ErrNotFound)
+
+	h := NewHandler(mockDao, nil, auth.AdminToken(adminToken))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("DELETE", "/admin/api-keys/abc", ""))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestApiKeysRevoke_Success(t *testing.T) {
+	router := gin.Default()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+	mockDao.EXPECT().Revoke(mock.Anything, "abc").Return(nil)
+
+	h := NewHandler(mockDao, nil, auth.AdminToken(adminToken))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, adminRequest("DELETE", "/admin/api-keys/abc", ""))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body)
+}
@@ -0,0 +1,212 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	auditmodel "github.com/ashwin-m/transactions/models/audit"
+	auditservice "github.com/ashwin-m/transactions/services/audit"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+type accountState struct {
+	Balance float64 `json:"balance"`
+	OwnerId string  `json:"owner_id"`
+	Version int64   `json:"version"`
+}
+
+type snapshot struct {
+	TenantId  string `json:"tenant_id"`
+	AccountId int64  `json:"account_id"`
+	// Before is null for accounts the call created.
+	Before *accountState `json:"before"`
+	After  *accountState `json:"after"`
+}
+
+type entry struct {
+	Id         int64     `json:"id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Actor      string    `json:"actor"`
+	AuthMethod string    `json:"auth_method"`
+	RequestId  string    `json:"request_id"`
+	Method     string    `json:"method"`
+	Endpoint   string    `json:"endpoint"`
+	Status     int       `json:"status"`
+	ErrorCode  string    `json:"error_code,omitempty"`
+	// Payload is the request body, with credentials redacted.
+	Payload   any        `json:"payload"`
+	Snapshots []snapshot `json:"snapshots"`
+	PrevHash  string     `json:"prev_hash"`
+	Hash      string     `json:"hash"`
+}
+
+type listEntriesResponse struct {
+	Entries []entry `json:"entries"`
+}
+
+type verification struct {
+	Entries  int64 `json:"entries"`
+	Valid    bool  `json:"valid"`
+	BrokenAt int64 `json:"broken_at,omitempty"`
+}
+
+type handler struct {
+	service auditservice.Service
+}
+
+type Handler interface {
+	RouteGroup(gin.IRouter)
+	Describe(*openapi3.T)
+}
+
+func NewHandler(service auditservice.Service) Handler {
+	return &handler{
+		service: service,
+	}
+}
+
+func (h *handler) RouteGroup(r gin.IRouter) {
+	rg := r.Group("/audit", auth.RequireRole(auth.RoleAudit))
+
+	rg.GET("", h.list)
+	rg.GET("/verify", h.verify)
+}
+
+// Describe documents the routes registered by RouteGroup.
+func (h *handler) Describe(doc *openapi3.T) {
+	list := openapi3.NewOperation()
+	list.OperationID = "listAuditEntries"
+	list.Summary = "List the audit log, newest first"
+	list.AddParameter(openapi3.NewQueryParameter("actor").WithSchema(openapi3.NewStringSchema()))
+	list.AddParameter(openapi3.NewQueryParameter("account_id").WithSchema(openapi3.NewInt64Schema()))
+	list.AddParameter(openapi3.NewQueryParameter("request_id").WithSchema(openapi3.NewStringSchema()))
+	list.AddParameter(openapi3.NewQueryParameter("from").WithSchema(openapi3.NewDateTimeSchema()))
+	list.AddParameter(openapi3.NewQueryParameter("to").WithSchema(openapi3.NewDateTimeSchema()))
+	list.AddParameter(openapi3.NewQueryParameter("page_size").WithSchema(openapi3.NewInt32Schema().WithMin(0).WithMax(auditservice.MaxPageSize)))
+	list.AddParameter(openapi3.NewQueryParameter("before_id").WithSchema(openapi3.NewInt64Schema()))
+	list.AddResponse(http.StatusOK, openapi.JSONResponse("A page of entries, a short one ends the listing", listEntriesResponse{}))
+	openapi.Problems(list, apperrors.CodeValidationFailed, apperrors.CodeUnauthorized, apperrors.CodeForbidden,
+		apperrors.CodeRateLimited, apperrors.CodeInternal)
+	openapi.Operation(doc, http.MethodGet, "/audit", list)
+
+	verify := openapi3.NewOperation()
+	verify.OperationID = "verifyAuditLog"
+	verify.Summary = "Check the hash chain of the audit log"
+	verify.AddResponse(http.StatusOK, openapi.JSONResponse("The outcome of the check", verification{}))
+	openapi.Problems(verify, apperrors.CodeUnauthorized, apperrors.CodeForbidden, apperrors.CodeRateLimited, apperrors.CodeInternal)
+	openapi.Operation(doc, http.MethodGet, "/audit/verify", verify)
+}
+
+func (h *handler) list(c *gin.Context) {
+	request := auditservice.ListRequest{Actor: c.Query("actor"), RequestId: c.Query("request_id")}
+
+	var fields []apperrors.FieldError
+	var err error
+	if accountId := c.Query("account_id"); accountId != "" {
+		request.AccountId, err = strconv.ParseInt(accountId, 10, 64)
+		if err != nil {
+			fields = append(fields, apperrors.FieldError{Field: "account_id", Message: "must be an integer"})
+		}
+	}
+	if from := c.Query("from"); from != "" {
+		request.From, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			fields = append(fields, apperrors.FieldError{Field: "from", Message: "must be an RFC 3339 timestamp"})
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		request.To, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			fields = append(fields, apperrors.FieldError{Field: "to", Message: "must be an RFC 3339 timestamp"})
+		}
+	}
+	if pageSize := c.Query("page_size"); pageSize != "" {
+		request.PageSize, err = strconv.Atoi(pageSize)
+		if err != nil {
+			fields = append(fields, apperrors.FieldError{Field: "page_size", Message: "must be an integer"})
+		}
+	}
+	if beforeId := c.Query("before_id"); beforeId != "" {
+		request.BeforeId, err = strconv.ParseInt(beforeId, 10, 64)
+		if err != nil {
+			fields = append(fields, apperrors.FieldError{Field: "before_id", Message: "must be an integer"})
+		}
+	}
+	if len(fields) > 0 {
+		apperrors.Abort(c, apperrors.Validation(fields...))
+		return
+	}
+
+	entries, err := h.service.List(c.Request.Context(), request)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	response := listEntriesResponse{Entries: make([]entry, len(entries))}
+	for i, e := range entries {
+		response.Entries[i] = newEntry(e)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *handler) verify(c *gin.Context) {
+	result, err := h.service.Verify(c.Request.Context())
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, verification{
+		Entries:  result.Entries,
+		Valid:    result.Valid,
+		BrokenAt: result.BrokenAt,
+	})
+}
+
+func newEntry(model auditmodel.Entries) entry {
+	response := entry{
+		Id:         model.GetId(),
+		OccurredAt: model.GetOccurredAt(),
+		Actor:      model.GetActor(),
+		AuthMethod: model.GetAuthMethod(),
+		RequestId:  model.GetRequestId(),
+		Method:     model.GetMethod(),
+		Endpoint:   model.GetEndpoint(),
+		Status:     model.GetStatus(),
+		ErrorCode:  model.GetErrorCode(),
+		Payload:    json.RawMessage(model.GetPayload()),
+		Snapshots:  make([]snapshot, len(model.GetSnapshots())),
+		PrevHash:   model.GetPrevHash(),
+		Hash:       model.GetHash(),
+	}
+	for i, s := range model.GetSnapshots() {
+		response.Snapshots[i] = snapshot{
+			TenantId:  s.TenantId,
+			AccountId: s.AccountId,
+			Before:    newAccountState(s.Before),
+			After:     newAccountState(s.After),
+		}
+	}
+
+	return response
+}
+
+func newAccountState(state *auditmodel.AccountState) *accountState {
+	if state == nil {
+		return nil
+	}
+
+	return &accountState{
+		Balance: state.Balance,
+		OwnerId: state.OwnerId,
+		Version: state.Version,
+	}
+}
@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	auditdao "github.com/ashwin-m/transactions/daos/audit"
+	daoMocks "github.com/ashwin-m/transactions/daos/audit/mocks"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	auditmodel "github.com/ashwin-m/transactions/models/audit"
+	auditservice "github.com/ashwin-m/transactions/services/audit"
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	auditorIdentity = auth.Identity{ClientID: "auditor", Roles: []string{auth.RoleAudit}}
+	clientIdentity  = auth.Identity{ClientID: "client-1", Roles: []string{auth.RoleRead, auth.RoleTransfer}}
+
+	occurredAt = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+)
+
+func newRouter(t *testing.T, identity auth.Identity) (*gin.Engine, *daoMocks.Dao) {
+	router := gin.New()
+	router.Use(auth.WithIdentity(identity))
+
+	mockDao := daoMocks.NewDao(t)
+	NewHandler(auditservice.NewService(nil, mockDao, nil)).RouteGroup(router)
+
+	return router, mockDao
+}
+
+func serve(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func auditEntry() auditmodel.Entries {
+	e := auditmodel.Entries{}
+	e.SetId(9)
+	e.SetTenantId(tenant.Default)
+	e.SetOccurredAt(occurredAt)
+	e.SetActor("client-1")
+	e.SetAuthMethod(auth.MethodAPIKey)
+	e.SetRequestId("req-1")
+	e.SetMethod("POST")
+	e.SetEndpoint("/v1/accounts")
+	e.SetStatus(http.StatusCreated)
+	e.SetPayload(json.RawMessage(`{"account_id":7,"initial_balance":"10"}`))
+	e.SetSnapshots([]auditmodel.Snapshot{{TenantId: tenant.Default, AccountId: 7, After: &auditmodel.AccountState{Balance: 10, OwnerId: "client-1", Version: 1}}})
+	e.SetPrevHash("prev")
+	e.SetHash("hash")
+	return e
+}
+
+func TestAuditList(t *testing.T) {
+	router, mockDao := newRouter(t, auditorIdentity)
+	mockDao.EXPECT().List(mock.Anything, auditdao.Filter{
+		Actor:     "client-1",
+		AccountId: 7,
+		From:      occurredAt,
+		BeforeId:  10,
+		Limit:     auditservice.DefaultPageSize,
+	}).Return([]auditmodel.Entries{auditEntry()}, nil)
+
+	w := serve(router, "/audit?actor=client-1&account_id=7&from=2026-01-02T03:04:05Z&before_id=10")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"entries":[{"id":9,"occurred_at":"2026-01-02T03:04:05Z","actor":"client-1","auth_method":"api_key","request_id":"req-1",
+		"method":"POST","endpoint":"/v1/accounts","status":201,"payload":{"account_id":7,"initial_balance":"10"},
+		"snapshots":[{"tenant_id":"default","account_id":7,"before":null,"after":{"balance":10,"owner_id":"client-1","version":1}}],
+		"prev_hash":"prev","hash":"hash"}]}`, w.Body.String())
+}
+
+func TestAuditList_InvalidQuery(t *testing.T) {
+	router, _ := newRouter(t, auditorIdentity)
+
+	w := serve(router, "/audit?account_id=x&from=yesterday")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `{"field":"account_id","message":"must be an integer"}`)
+	assert.Contains(t, w.Body.String(), `{"field":"from","message":"must be an RFC 3339 timestamp"}`)
+}
+
+func TestAuditList_RequiresAuditRole(t *testing.T) {
+	router, _ := newRouter(t, clientIdentity)
+
+	w := serve(router, "/audit")
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAuditVerify(t *testing.T) {
+	router, mockDao := newRouter(t, auditorIdentity)
+	e := auditEntry()
+	e.SetPrevHash("")
+	mockDao.EXPECT().Chain(mock.Anything, int64(0), mock.Anything).Return([]auditmodel.Entries{e}, nil)
+
+	w := serve(router, "/audit/verify")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"entries":1,"valid":false,"broken_at":9}`, w.Body.String())
+}
+
+func TestAudit_DescribesRegisteredRoutes(t *testing.T) {
+	router, _ := newRouter(t, auditorIdentity)
+	h := NewHandler(nil)
+
+	assert.Empty(t, openapi.Drift(openapi.New(h), router.Routes()))
+}
@@ -0,0 +1,91 @@
+package exports
+
+import (
+	"net/http"
+
+	"github.com/ashwin-m/transactions/services/export"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+type listRunsResponse struct {
+	Runs []export.Manifest `json:"runs"`
+}
+
+type handler struct {
+	service   export.Service
+	adminAuth gin.HandlerFunc
+}
+
+type Handler interface {
+	RouteGroup(gin.IRouter)
+	Describe(*openapi3.T)
+}
+
+// NewHandler serves exports to the operator, authenticated by adminAuth. A
+// run covers every exported tenant, so the admin role, which is granted per
+// tenant, isn't enough.
+func NewHandler(service export.Service, adminAuth gin.HandlerFunc) Handler {
+	return &handler{
+		service:   service,
+		adminAuth: adminAuth,
+	}
+}
+
+func (h *handler) RouteGroup(r gin.IRouter) {
+	rg := r.Group("/exports", h.adminAuth)
+
+	rg.POST("", h.create)
+	rg.GET("", h.list)
+}
+
+// Describe documents the routes registered by RouteGroup.
+func (h *handler) Describe(doc *openapi3.T) {
+	create := openapi3.NewOperation()
+	create.OperationID = "createExport"
+	create.Summary = "Export what changed in the ledger since the previous export"
+	create.Description = "Authenticated with the admin token, a run covers every exported tenant."
+	create.Security = adminSecurity()
+	create.AddResponse(http.StatusCreated, openapi.JSONResponse("The manifest of the run", export.Manifest{}))
+	openapi.Problems(create, apperrors.CodeUnauthorized, apperrors.CodeForbidden, apperrors.CodeExportInProgress,
+		apperrors.CodeRateLimited, apperrors.CodeInternal)
+	openapi.Operation(doc, http.MethodPost, "/exports", create)
+
+	list := openapi3.NewOperation()
+	list.OperationID = "listExports"
+	list.Summary = "List the export runs, newest first"
+	list.Description = "Authenticated with the admin token, runs cover every exported tenant."
+	list.Security = adminSecurity()
+	list.AddResponse(http.StatusOK, openapi.JSONResponse("The manifest of every run", listRunsResponse{}))
+	openapi.Problems(list, apperrors.CodeUnauthorized, apperrors.CodeForbidden, apperrors.CodeRateLimited, apperrors.CodeInternal)
+	openapi.Operation(doc, http.MethodGet, "/exports", list)
+}
+
+// adminSecurity is the admin token, sent as a bearer token.
+func adminSecurity() *openapi3.SecurityRequirements {
+	return openapi3.NewSecurityRequirements().With(openapi3.NewSecurityRequirement().Authenticate("bearer"))
+}
+
+// create runs an export while the request waits, exports are incremental
+// so runs are short when made regularly.
+func (h *handler) create(c *gin.Context) {
+	manifest, err := h.service.Export(c.Request.Context())
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, manifest)
+}
+
+func (h *handler) list(c *gin.Context) {
+	runs, err := h.service.Runs()
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, listRunsResponse{Runs: runs})
+}
@@ -0,0 +1,113 @@
+package exports
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	exportdao "github.com/ashwin-m/transactions/daos/export"
+	daoMocks "github.com/ashwin-m/transactions/daos/export/mocks"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	"github.com/ashwin-m/transactions/services/export"
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const adminToken = "test-admin-token"
+
+var snapshotAt = time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)
+
+func newRouter(t *testing.T) (*gin.Engine, *daoMocks.Dao, pgxmock.PgxPoolIface) {
+	router := gin.New()
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+
+	mockDao := daoMocks.NewDao(t)
+	NewHandler(export.NewExporter(mockDB, mockDao, export.Options{Dir: t.TempDir()}), auth.AdminToken(adminToken)).RouteGroup(router)
+
+	return router, mockDao, mockDB
+}
+
+func serve(router *gin.Engine, method string) *httptest.ResponseRecorder {
+	return serveWith(router, method, adminToken)
+}
+
+func serveWith(router *gin.Engine, method, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, "/exports", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestExportsCreate(t *testing.T) {
+	router, mockDao, mockDB := newRouter(t)
+	mockDB.ExpectCommit()
+	mockDao.EXPECT().Snapshot(mock.Anything, mock.Anything).Return(nil)
+	mockDao.EXPECT().Lock(mock.Anything, mock.Anything).Return(true, nil)
+	mockDao.EXPECT().Watermarks(mock.Anything, mock.Anything).Return(map[string]uint64{}, nil)
+	mockDao.EXPECT().Horizon(mock.Anything, mock.Anything).Return(uint64(7_500), nil)
+	mockDao.EXPECT().Now(mock.Anything, mock.Anything).Return(snapshotAt, nil)
+	mockDao.EXPECT().Accounts(mock.Anything, mock.Anything, uint64(0), uint64(7_500), mock.Anything).
+		RunAndReturn(func(ctx context.Context, tx pgx.Tx, from, to uint64, fn func(exportdao.Account) error) error {
+			return fn(exportdao.Account{TenantId: "default", Id: 1, Balance: 10, InitialBalance: 10, Version: 1, UpdatedAt: snapshotAt.Add(-time.Hour)})
+		})
+	mockDao.EXPECT().Transactions(mock.Anything, mock.Anything, uint64(0), uint64(7_500), mock.Anything).Return(nil)
+	mockDao.EXPECT().SetWatermark(mock.Anything, mock.Anything, "default", uint64(7_500), "20261019T093000.000000Z").Return(nil)
+
+	w := serve(router, "POST")
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"20261019T093000.000000Z","format":"csv"`)
+	assert.Contains(t, w.Body.String(), `"windows":{"default":{"from_xid":0,"to_xid":7500}}`)
+	assert.Contains(t, w.Body.String(), `{"table":"accounts","name":"accounts.csv","rows":1,`)
+
+	w = serve(router, "GET")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `{"runs":[{"id":"20261019T093000.000000Z"`)
+}
+
+func TestExportsCreate_InProgress(t *testing.T) {
+	router, mockDao, _ := newRouter(t)
+	mockDao.EXPECT().Snapshot(mock.Anything, mock.Anything).Return(nil)
+	mockDao.EXPECT().Lock(mock.Anything, mock.Anything).Return(false, nil)
+
+	w := serve(router, "POST")
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:export-in-progress\",\"title\":\"An export is already running\",\"status\":409,\"detail\":\"another export is running, retry once it finishes\",\"instance\":\"/exports\",\"code\":\"EXPORT_IN_PROGRESS\"}", w.Body.String())
+}
+
+func TestExportsList_Empty(t *testing.T) {
+	router, _, _ := newRouter(t)
+
+	w := serve(router, "GET")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"runs":[]}`, w.Body.String())
+}
+
+// the admin role is granted per tenant, a tenant's admin key mustn't see
+// the runs of every tenant
+func TestExports_RequiresAdminToken(t *testing.T) {
+	router, _, _ := newRouter(t)
+
+	assert.Equal(t, http.StatusUnauthorized, serveWith(router, "POST", "tk_acme-admin-key").Code)
+	assert.Equal(t, http.StatusUnauthorized, serveWith(router, "GET", "tk_acme-admin-key").Code)
+}
+
+func TestExports_DescribesRegisteredRoutes(t *testing.T) {
+	router := gin.New()
+	h := NewHandler(nil, auth.AdminToken(adminToken))
+	h.RouteGroup(router)
+
+	assert.Empty(t, openapi.Drift(openapi.New(h), router.Routes()))
+}
@@ -0,0 +1,177 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	migrationsdao "github.com/ashwin-m/transactions/daos/migrations"
+	"github.com/ashwin-m/transactions/utils/jobs"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	statusOk          = "ok"
+	statusUnavailable = "unavailable"
+)
+
+type Pinger interface {
+	Ping(context.Context) error
+}
+
+type PoolStats struct {
+	AcquireCount         int64   `json:"acquire_count"`
+	AcquireDurationMs    float64 `json:"acquire_duration_ms"`
+	AcquiredConns        int32   `json:"acquired_conns"`
+	CanceledAcquireCount int64   `json:"canceled_acquire_count"`
+	EmptyAcquireCount    int64   `json:"empty_acquire_count"`
+	IdleConns            int32   `json:"idle_conns"`
+	MaxConns             int32   `json:"max_conns"`
+	TotalConns           int32   `json:"total_conns"`
+}
+
+type check struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type migrationCheck struct {
+	check
+	Version         int64 `json:"version"`
+	RequiredVersion int64 `json:"required_version"`
+}
+
+type checks struct {
+	Database   check          `json:"database"`
+	Migrations migrationCheck `json:"migrations"`
+}
+
+type readinessResponse struct {
+	Status string        `json:"status"`
+	Checks checks        `json:"checks"`
+	Pool   *PoolStats    `json:"pool,omitempty"`
+	Jobs   []jobs.Status `json:"jobs"`
+}
+
+type livenessResponse struct {
+	Status string        `json:"status"`
+	Jobs   []jobs.Status `json:"jobs"`
+}
+
+type handler struct {
+	db            Pinger
+	migrationsDao migrationsdao.Dao
+	poolStats     func() PoolStats
+	jobs          *jobs.Registry
+	timeout       time.Duration
+}
+
+type Handler interface {
+	RouteGroup(gin.IRouter)
+}
+
+// NewHandler builds the liveness and readiness endpoints. poolStats may be nil
+// when pool statistics are not available.
+func NewHandler(db Pinger, migrationsDao migrationsdao.Dao, poolStats func() PoolStats, jobs *jobs.Registry, timeout time.Duration) Handler {
+	return &handler{
+		db:            db,
+		migrationsDao: migrationsDao,
+		poolStats:     poolStats,
+		jobs:          jobs,
+		timeout:       timeout,
+	}
+}
+
+func (h *handler) RouteGroup(r gin.IRouter) {
+	r.GET("/healthz", h.liveness)
+	r.GET("/readyz", h.readiness)
+}
+
+func (h *handler) liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, livenessResponse{
+		Status: statusOk,
+		Jobs:   h.jobs.Statuses(),
+	})
+}
+
+func (h *handler) readiness(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	response := readinessResponse{
+		Status: statusOk,
+		Checks: checks{
+			Database:   h.checkDatabase(ctx),
+			Migrations: h.checkMigrations(ctx),
+		},
+		Jobs: h.jobs.Statuses(),
+	}
+
+	if h.poolStats != nil {
+		stats := h.poolStats()
+		response.Pool = &stats
+	}
+
+	status := http.StatusOK
+	if response.Checks.Database.Status != statusOk || response.Checks.Migrations.Status != statusOk {
+		response.Status = statusUnavailable
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, response)
+}
+
+func (h *handler) checkDatabase(ctx context.Context) check {
+	start := time.Now()
+	err := h.db.Ping(ctx)
+
+	return newCheck(start, err)
+}
+
+func (h *handler) checkMigrations(ctx context.Context) migrationCheck {
+	start := time.Now()
+	version, err := h.migrationsDao.Version(ctx)
+
+	result := migrationCheck{
+		check:           newCheck(start, err),
+		Version:         version,
+		RequiredVersion: migrationsdao.RequiredVersion,
+	}
+	if err == nil && version < migrationsdao.RequiredVersion {
+		result.Status = statusUnavailable
+		result.Error = "database schema is older than required"
+	}
+
+	return result
+}
+
+func newCheck(start time.Time, err error) check {
+	result := check{
+		Status:    statusOk,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = statusUnavailable
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+func PoolStatsFrom(pool *pgxpool.Pool) func() PoolStats {
+	return func() PoolStats {
+		stat := pool.Stat()
+		return PoolStats{
+			AcquireCount:         stat.AcquireCount(),
+			AcquireDurationMs:    float64(stat.AcquireDuration().Microseconds()) / 1000,
+			AcquiredConns:        stat.AcquiredConns(),
+			CanceledAcquireCount: stat.CanceledAcquireCount(),
+			EmptyAcquireCount:    stat.EmptyAcquireCount(),
+			IdleConns:            stat.IdleConns(),
+			MaxConns:             stat.MaxConns(),
+			TotalConns:           stat.TotalConns(),
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	migrationsdao "github.com/ashwin-m/transactions/daos/migrations"
+	migrationsdaomocks "github.com/ashwin-m/transactions/daos/migrations/mocks"
+	"github.com/ashwin-m/transactions/utils/jobs"
+	"github.com/gin-gonic/gin"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func poolStats() PoolStats {
+	return PoolStats{AcquiredConns: 1, IdleConns: 2, MaxConns: 10, TotalConns: 3}
+}
+
+func TestHealthLiveness(t *testing.T) {
+	router := gin.Default()
+
+	mockDB, _ := pgxmock.NewPool()
+	mockMigrationsDao := migrationsdaomocks.NewDao(t)
+
+	registry := jobs.NewRegistry()
+	registry.Register("outbox-relay")
+
+	h := NewHandler(mockDB, mockMigrationsDao, poolStats, registry, time.Second)
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{\"status\":\"ok\",\"jobs\":[{\"name\":\"outbox-relay\",\"running\":false,\"healthy\":true}]}", w.Body.String())
+}
+
+func TestHealthReadiness_Success(t *testing.T) {
+	router := gin.Default()
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectPing()
+	mockMigrationsDao := migrationsdaomocks.NewDao(t)
+	mockMigrationsDao.EXPECT().Version(mock.Anything).Return(migrationsdao.RequiredVersion, nil)
+
+	h := NewHandler(mockDB, mockMigrationsDao, poolStats, jobs.NewRegistry(), time.Second)
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "\"status\":\"ok\"")
+	assert.Contains(t, w.Body.String(), fmt.Sprintf("\"version\":%d,\"required_version\":%d", migrationsdao.RequiredVersion, migrationsdao.RequiredVersion))
+	assert.Contains(t, w.Body.String(), "\"pool\":{\"acquire_count\":0,\"acquire_duration_ms\":0,\"acquired_conns\":1,\"canceled_acquire_count\":0,\"empty_acquire_count\":0,\"idle_conns\":2,\"max_conns\":10,\"total_conns\":3}")
+}
+
+func TestHealthReadiness_DatabaseDown(t *testing.T) {
+	router := gin.Default()
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectPing().WillReturnError(errors.New("connection refused"))
+	mockMigrationsDao := migrationsdaomocks.NewDao(t)
+	mockMigrationsDao.EXPECT().Version(mock.Anything).Return(0, errors.New("connection refused"))
+
+	h := NewHandler(mockDB, mockMigrationsDao, nil, jobs.NewRegistry(), time.Second)
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "\"status\":\"unavailable\"")
+	assert.Contains(t, w.Body.String(), "\"error\":\"connection refused\"")
+	assert.NotContains(t, w.Body.String(), "\"pool\"")
+}
+
+func TestHealthReadiness_PingTimesOut(t *testing.T) {
+	router := gin.Default()
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectPing().WillDelayFor(time.Second)
+	mockMigrationsDao := migrationsdaomocks.NewDao(t)
+	mockMigrationsDao.EXPECT().Version(mock.Anything).Return(migrationsdao.RequiredVersion, nil)
+
+	h := NewHandler(mockDB, mockMigrationsDao, nil, jobs.NewRegistry(), 10*time.Millisecond)
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "\"database\":{\"status\":\"unavailable\"")
+}
+
+func TestHealthReadiness_SchemaTooOld(t *testing.T) {
+	router := gin.Default()
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectPing()
+	mockMigrationsDao := migrationsdaomocks.NewDao(t)
+	mockMigrationsDao.EXPECT().Version(mock.Anything).Return(migrationsdao.RequiredVersion-1, nil)
+
+	h := NewHandler(mockDB, mockMigrationsDao, nil, jobs.NewRegistry(), time.Second)
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "\"error\":\"database schema is older than required\"")
+}
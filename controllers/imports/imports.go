@@ -0,0 +1,70 @@
+package imports
+
+import (
+	"net/http"
+
+	"github.com/ashwin-m/transactions/middlewares/audit"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	"github.com/ashwin-m/transactions/services/imports"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+type handler struct {
+	service imports.Service
+}
+
+type Handler interface {
+	RouteGroup(gin.IRouter)
+	Describe(*openapi3.T)
+}
+
+func NewHandler(service imports.Service) Handler {
+	return &handler{
+		service: service,
+	}
+}
+
+func (h *handler) RouteGroup(r gin.IRouter) {
+	rg := r.Group("/imports", auth.RequireRole(auth.RoleAdmin))
+
+	// uploads are streamed into the import, the audit log only records
+	// their size
+	rg.POST("/accounts", audit.WithoutPayload, h.accounts)
+}
+
+// Describe documents the routes registered by RouteGroup.
+func (h *handler) Describe(doc *openapi3.T) {
+	formats := make([]any, len(imports.Formats))
+	for i, format := range imports.Formats {
+		formats[i] = format
+	}
+
+	accounts := openapi3.NewOperation()
+	accounts.OperationID = "importAccounts"
+	accounts.Summary = "Create accounts in bulk, skipping those that already exist"
+	accounts.AddParameter(openapi3.NewQueryParameter("format").
+		WithSchema(openapi3.NewStringSchema().WithEnum(formats...).WithDefault(imports.FormatCSV)))
+	accounts.RequestBody = openapi.StreamBody("A row per account with account_id, initial_balance and optionally owner_id",
+		"text/csv", "application/x-ndjson")
+	accounts.AddResponse(http.StatusOK, openapi.JSONResponse("What became of every row", imports.Report{}))
+	openapi.Problems(accounts, apperrors.CodeValidationFailed, apperrors.CodeUnauthorized, apperrors.CodeForbidden,
+		apperrors.CodeRateLimited, apperrors.CodeInternal)
+	openapi.Operation(doc, http.MethodPost, "/imports/accounts", accounts)
+}
+
+// accounts imports the body while it is uploaded. Rows that fail don't fail
+// the request, they are listed in the report.
+func (h *handler) accounts(c *gin.Context) {
+	format := c.DefaultQuery("format", imports.FormatCSV)
+
+	report, err := h.service.Import(c.Request.Context(), format, c.Request.Body)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,133 @@
+package imports
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	importsdao "github.com/ashwin-m/transactions/daos/imports"
+	daoMocks "github.com/ashwin-m/transactions/daos/imports/mocks"
+	"github.com/ashwin-m/transactions/middlewares/audit"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	auditmodel "github.com/ashwin-m/transactions/models/audit"
+	"github.com/ashwin-m/transactions/services/imports"
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	adminIdentity  = auth.Identity{ClientID: "admin", Roles: []string{auth.RoleAdmin}}
+	clientIdentity = auth.Identity{ClientID: "client-1", Roles: []string{auth.RoleRead, auth.RoleTransfer}}
+)
+
+func newRouter(t *testing.T, identity auth.Identity) (*gin.Engine, *daoMocks.Dao, pgxmock.PgxPoolIface) {
+	router := gin.New()
+	router.Use(auth.WithIdentity(identity))
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDao := daoMocks.NewDao(t)
+	NewHandler(imports.NewService(mockDB, mockDao, 0)).RouteGroup(router)
+
+	return router, mockDao, mockDB
+}
+
+func serve(router *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestImportAccounts(t *testing.T) {
+	router, mockDao, mockDB := newRouter(t, adminIdentity)
+	mockDB.ExpectBegin()
+	mockDao.EXPECT().Insert(mock.Anything, mock.Anything, []importsdao.Account{
+		{Id: 1, Balance: 10, OwnerId: "client-1"},
+		{Id: 2, Balance: 20, OwnerId: "admin"},
+	}).Return(importsdao.Result{Created: []int64{2}}, nil)
+	mockDB.ExpectCommit()
+
+	w := serve(router, "/imports/accounts", "account_id,initial_balance,owner_id\n1,10,client-1\n2,20,\n3,x,\n")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"rows":3,"created":1,"existing":1,"failed":1,"errors":[{"line":4,"account_id":3,"field":"initial_balance","message":"must be a decimal number"}]}`, w.Body.String())
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestImportAccounts_NDJSON(t *testing.T) {
+	router, mockDao, mockDB := newRouter(t, adminIdentity)
+	mockDB.ExpectBegin()
+	mockDao.EXPECT().Insert(mock.Anything, mock.Anything, []importsdao.Account{
+		{Id: 1, Balance: 10, OwnerId: "admin"},
+	}).Return(importsdao.Result{Created: []int64{1}}, nil)
+	mockDB.ExpectCommit()
+
+	w := serve(router, "/imports/accounts?format=ndjson", `{"account_id": 1, "initial_balance": 10}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"rows":1,"created":1,"existing":0,"failed":0,"errors":[]}`, w.Body.String())
+}
+
+func TestImportAccounts_InvalidHeader(t *testing.T) {
+	router, _, _ := newRouter(t, adminIdentity)
+
+	w := serve(router, "/imports/accounts", "id,balance\n1,10\n")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"errors":[{"field":"header","message":"has the unknown column \"id\", columns are account_id, initial_balance, owner_id"}]`)
+}
+
+func TestImportAccounts_RequiresAdmin(t *testing.T) {
+	router, _, _ := newRouter(t, clientIdentity)
+
+	w := serve(router, "/imports/accounts", "account_id,initial_balance\n1,10\n")
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+type recorderFunc func(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error)
+
+func (f recorderFunc) Record(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error) {
+	return f(ctx, entry)
+}
+
+func (f recorderFunc) RecordTx(ctx context.Context, txn pgx.Tx, entry auditmodel.Entries) (auditmodel.Entries, error) {
+	return f(ctx, entry)
+}
+
+func TestImportAccounts_AuditsOnlyTheSize(t *testing.T) {
+	var recorded []auditmodel.Entries
+	router := gin.New()
+	router.Use(auth.WithIdentity(adminIdentity), audit.Middleware(recorderFunc(func(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error) {
+		recorded = append(recorded, entry)
+		return entry, nil
+	})))
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDao := daoMocks.NewDao(t)
+	NewHandler(imports.NewService(mockDB, mockDao, 0)).RouteGroup(router)
+	mockDB.ExpectBegin()
+	mockDao.EXPECT().Insert(mock.Anything, mock.Anything, mock.Anything).Return(importsdao.Result{Created: []int64{1}}, nil)
+	mockDB.ExpectCommit()
+
+	w := serve(router, "/imports/accounts", "account_id,initial_balance\n1,10\n")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Len(t, recorded, 1) {
+		assert.Equal(t, `"streamed body of 32 bytes omitted"`, string(recorded[0].GetPayload()))
+	}
+}
+
+func TestImports_DescribesRegisteredRoutes(t *testing.T) {
+	router := gin.New()
+	h := NewHandler(nil)
+	h.RouteGroup(router)
+
+	assert.Empty(t, openapi.Drift(openapi.New(h), router.Routes()))
+}
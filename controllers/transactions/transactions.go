@@ -1,52 +1,66 @@
 package transactions
 
 import (
-	"context"
-	"errors"
-	"math/big"
 	"net/http"
 
-	accountsdao "github.com/ashwin-m/transactions/daos/accounts"
-	transactionsdao "github.com/ashwin-m/transactions/daos/transactions"
-	accountsmodel "github.com/ashwin-m/transactions/models/accounts"
-	"github.com/ashwin-m/transactions/utils/pgxiface"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	"github.com/ashwin-m/transactions/services/transfers"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/gin-gonic/gin"
 )
 
-const (
-	prec                                = 100
-	min_transaction_amount              = 0
-	min_account_balance_for_transaction = 0
-)
-
 type createTransactionRequest struct {
-	SourceAccountId      int64  `json:"source_account_id"`
-	DestinationAccountId int64  `json:"destination_account_id"`
-	Amount               string `json:"amount"`
+	SourceAccountId      int64 `json:"source_account_id" binding:"required"`
+	DestinationAccountId int64 `json:"destination_account_id" binding:"required"`
+	// DestinationTenantId names the tenant of the destination account, the
+	// caller's own tenant when empty.
+	DestinationTenantId string `json:"destination_tenant_id"`
+	Amount              string `json:"amount" binding:"required,numeric"`
+}
+
+type createTransactionResponse struct {
+	TransactionId int64 `json:"transaction_id"`
 }
 
 type handler struct {
-	dbPool          pgxiface.PgxIface
-	accountsDao     accountsdao.Dao
-	transactionsDao transactionsdao.Dao
+	service    transfers.Service
+	middleware []gin.HandlerFunc
 }
 
 type Handler interface {
-	RouteGroup(*gin.Engine)
+	RouteGroup(gin.IRouter)
+	Describe(*openapi3.T)
 }
 
-func NewHandler(dbPool pgxiface.PgxIface, accountsDao accountsdao.Dao, transactionsDao transactionsdao.Dao) Handler {
+// NewHandler builds the transfer endpoint. middleware runs before each
+// transfer once the caller's permissions have been checked.
+func NewHandler(service transfers.Service, middleware ...gin.HandlerFunc) Handler {
 	return &handler{
-		dbPool:          dbPool,
-		accountsDao:     accountsDao,
-		transactionsDao: transactionsDao,
+		service:    service,
+		middleware: middleware,
 	}
 }
 
-func (h *handler) RouteGroup(r *gin.Engine) {
+func (h *handler) RouteGroup(r gin.IRouter) {
 	rg := r.Group("/transactions")
 
-	rg.POST("", h.create)
+	handlers := append([]gin.HandlerFunc{auth.RequireRole(auth.RoleTransfer)}, h.middleware...)
+	rg.POST("", append(handlers, h.create)...)
+}
+
+// Describe documents the routes registered by RouteGroup.
+func (h *handler) Describe(doc *openapi3.T) {
+	create := openapi3.NewOperation()
+	create.OperationID = "createTransaction"
+	create.Summary = "Transfer an amount from one account to another"
+	create.RequestBody = openapi.JSONBody(createTransactionRequest{})
+	create.AddResponse(http.StatusOK, openapi.JSONResponse("The transfer was made", createTransactionResponse{}))
+	openapi.Problems(create, apperrors.CodeValidationFailed, apperrors.CodeUnauthorized, apperrors.CodeForbidden,
+		apperrors.CodeAccountNotFound, apperrors.CodeInsufficientFunds, apperrors.CodeVersionConflict,
+		apperrors.CodeRateLimited, apperrors.CodeInternal)
+	openapi.Operation(doc, http.MethodPost, "/transactions", create)
 }
 
 func (h *handler) create(c *gin.Context) {
@@ -54,93 +68,20 @@ func (h *handler) create(c *gin.Context) {
 
 	err := c.ShouldBindJSON(&request)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	amount, ok := new(big.Float).SetPrec(prec).SetString(request.Amount)
-	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "unable to parse request amount"})
-		return
-	}
-
-	if amount.Cmp(big.NewFloat(min_transaction_amount)) == -1 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "request amount cant be less than 0"})
-		return
-	}
-
-	sourceAccount, err := h.accountsDao.GetById(request.SourceAccountId)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		return
-	}
-
-	sourceAccountBalanceFloat := sourceAccount.GetBalance()
-	sourceAccountBalance := new(big.Float).SetPrec(prec).SetFloat64(sourceAccountBalanceFloat)
-
-	err = validateSourceAccount(sourceAccount, amount)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	destinationAccount, err := h.accountsDao.GetById(request.DestinationAccountId)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		return
-	}
-
-	destinationAccountBalanceFloat := destinationAccount.GetBalance()
-	destinationAccountBalance := new(big.Float).SetPrec(prec).SetFloat64(destinationAccountBalanceFloat)
-
-	txn, err := h.dbPool.Begin(context.Background())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	amountFloat, _ := amount.Float64()
-	transactionId, err := h.transactionsDao.Create(txn, sourceAccount.GetId(), destinationAccount.GetId(), amountFloat)
-	if err != nil {
-		txn.Rollback(context.Background())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperrors.Abort(c, apperrors.FromBinding(err))
 		return
 	}
 
-	newSourceAccountBalance := big.NewFloat(0).Sub(sourceAccountBalance, amount)
-	newSourceAccountBalanceFloat, _ := newSourceAccountBalance.Float64()
-	_, err = h.accountsDao.UpdateBalance(txn, request.SourceAccountId, sourceAccount.GetVersion(), newSourceAccountBalanceFloat)
+	result, err := h.service.Transfer(c.Request.Context(), transfers.TransferRequest{
+		SourceAccountId:      request.SourceAccountId,
+		DestinationAccountId: request.DestinationAccountId,
+		DestinationTenantId:  request.DestinationTenantId,
+		Amount:               request.Amount,
+	})
 	if err != nil {
-		txn.Rollback(context.Background())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperrors.Abort(c, err)
 		return
 	}
 
-	newDestinationAccountBalance := big.NewFloat(0).Add(destinationAccountBalance, amount)
-	newDestinationAccountBalanceFloat, _ := newDestinationAccountBalance.Float64()
-	_, err = h.accountsDao.UpdateBalance(txn, request.DestinationAccountId, destinationAccount.GetVersion(), newDestinationAccountBalanceFloat)
-	if err != nil {
-		txn.Rollback(context.Background())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	txn.Commit(context.Background())
-	c.JSON(http.StatusOK, gin.H{"transaction_id": transactionId})
-
-}
-
-func validateSourceAccount(sourceAccount accountsmodel.Accounts, transactionAmount *big.Float) error {
-	sourceAccountBalanceFloat := sourceAccount.GetBalance()
-	sourceAccountBalance := new(big.Float).SetPrec(prec).SetFloat64(sourceAccountBalanceFloat)
-
-	if sourceAccountBalance.Cmp(transactionAmount) == -1 {
-		return errors.New("account balance is less than transaction")
-	}
-
-	if sourceAccountBalance.Cmp(big.NewFloat(min_account_balance_for_transaction)) == -1 {
-		return errors.New("account balance is less than minimum amount for transactions")
-	}
-
-	return nil
+	c.JSON(http.StatusOK, createTransactionResponse{TransactionId: result.TransactionId})
 }
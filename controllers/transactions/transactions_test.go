@@ -1,29 +1,45 @@
 package transactions
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	accountsdao "github.com/ashwin-m/transactions/daos/accounts"
 	accountsdaomocks "github.com/ashwin-m/transactions/daos/accounts/mocks"
+	outboxdaomocks "github.com/ashwin-m/transactions/daos/outbox/mocks"
 	transactionsdaomocks "github.com/ashwin-m/transactions/daos/transactions/mocks"
+	"github.com/ashwin-m/transactions/middlewares/auth"
 	accountsmodel "github.com/ashwin-m/transactions/models/accounts"
+	eventsmodel "github.com/ashwin-m/transactions/models/events"
+	"github.com/ashwin-m/transactions/services/transfers"
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/ashwin-m/transactions/utils/tenant"
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+var (
+	adminIdentity  = auth.Identity{ClientID: "admin", Roles: []string{auth.RoleAdmin}}
+	clientIdentity = auth.Identity{ClientID: "client-1", Roles: []string{auth.RoleRead, auth.RoleTransfer}}
+)
+
 func TestTransactionsCreate_BalancePassedAsInt(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity), auth.Tenancy())
 
 	mockAccountsDao := accountsdaomocks.NewDao(t)
 	mocktransactionsDao := transactionsdaomocks.NewDao(t)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
 	mockDB, _ := pgxmock.NewPool()
 
-	h := NewHandler(mockDB, mockAccountsDao, mocktransactionsDao)
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
 	h.RouteGroup(router)
 
 	body := `{
@@ -38,17 +54,19 @@ func TestTransactionsCreate_BalancePassedAsInt(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "{\"error\":\"json: cannot unmarshal number into Go struct field createTransactionRequest.amount of type string\"}", w.Body.String())
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:validation-failed\",\"title\":\"The request is invalid\",\"status\":400,\"detail\":\"the request has invalid fields\",\"instance\":\"/transactions\",\"code\":\"VALIDATION_FAILED\",\"errors\":[{\"field\":\"amount\",\"message\":\"must be a string\"}]}", w.Body.String())
 }
 
 func TestTransactionsCreate_BalancePassedAsBadFloat(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity), auth.Tenancy())
 
 	mockAccountsDao := accountsdaomocks.NewDao(t)
 	mocktransactionsDao := transactionsdaomocks.NewDao(t)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
 	mockDB, _ := pgxmock.NewPool()
 
-	h := NewHandler(mockDB, mockAccountsDao, mocktransactionsDao)
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
 	h.RouteGroup(router)
 
 	body := `{
@@ -63,18 +81,71 @@ func TestTransactionsCreate_BalancePassedAsBadFloat(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "{\"error\":\"unable to parse request amount\"}", w.Body.String())
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:validation-failed\",\"title\":\"The request is invalid\",\"status\":400,\"detail\":\"the request has invalid fields\",\"instance\":\"/transactions\",\"code\":\"VALIDATION_FAILED\",\"errors\":[{\"field\":\"amount\",\"message\":\"must be a decimal number\"}]}", w.Body.String())
 }
 
 func TestTransactionsCreate_SourceAccountDaoReturnsError(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity), auth.Tenancy())
+
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(accountsmodel.Accounts{}, errors.New("test"))
+	mocktransactionsDao := transactionsdaomocks.NewDao(t)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockDB, _ := pgxmock.NewPool()
+
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
+	h.RouteGroup(router)
+
+	body := `{
+		"source_account_id": 123,
+		"destination_account_id": 456,
+		"amount": "100.12345"
+	}`
+	bodyReader := strings.NewReader(body)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", bodyReader)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:internal-error\",\"title\":\"Internal server error\",\"status\":500,\"detail\":\"the request could not be completed\",\"instance\":\"/transactions\",\"code\":\"INTERNAL_ERROR\"}", w.Body.String())
+}
+
+func TestTransactionsCreate_MissingFields(t *testing.T) {
+	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity), auth.Tenancy())
+
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+	mocktransactionsDao := transactionsdaomocks.NewDao(t)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockDB, _ := pgxmock.NewPool()
+
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
+	h.RouteGroup(router)
+
+	bodyReader := strings.NewReader(`{"source_account_id": 123}`)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", bodyReader)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:validation-failed\",\"title\":\"The request is invalid\",\"status\":400,\"detail\":\"the request has invalid fields\",\"instance\":\"/transactions\",\"code\":\"VALIDATION_FAILED\",\"errors\":[{\"field\":\"destination_account_id\",\"message\":\"is required\"},{\"field\":\"amount\",\"message\":\"is required\"}]}", w.Body.String())
+}
+
+func TestTransactionsCreate_SourceAccountNotFound(t *testing.T) {
+	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity), auth.Tenancy())
 
 	mockAccountsDao := accountsdaomocks.NewDao(t)
-	mockAccountsDao.EXPECT().GetById(int64(123)).Return(accountsmodel.Accounts{}, errors.New("test"))
+	mockAccountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(accountsmodel.Accounts{}, pgx.ErrNoRows)
 	mocktransactionsDao := transactionsdaomocks.NewDao(t)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
 	mockDB, _ := pgxmock.NewPool()
 
-	h := NewHandler(mockDB, mockAccountsDao, mocktransactionsDao)
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
 	h.RouteGroup(router)
 
 	body := `{
@@ -89,11 +160,12 @@ func TestTransactionsCreate_SourceAccountDaoReturnsError(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusNotFound, w.Code)
-	assert.Equal(t, "{\"error\":\"test\"}", w.Body.String())
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:account-not-found\",\"title\":\"The account was not found\",\"status\":404,\"detail\":\"account 123 was not found\",\"instance\":\"/transactions\",\"code\":\"ACCOUNT_NOT_FOUND\"}", w.Body.String())
 }
 
 func TestTransactionsCreate_DestinationAccountDaoReturnsError(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity), auth.Tenancy())
 
 	mockAccountsDao := accountsdaomocks.NewDao(t)
 
@@ -101,16 +173,17 @@ func TestTransactionsCreate_DestinationAccountDaoReturnsError(t *testing.T) {
 	sourceAccount := accountsmodel.Accounts{}
 	sourceAccount.SetId(sourceAccountId)
 	sourceAccount.SetBalance(200.1)
-	mockAccountsDao.EXPECT().GetById(sourceAccountId).Return(sourceAccount, nil)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, sourceAccountId).Return(sourceAccount, nil)
 
 	destinationAccountId := int64(456)
 	destinationAccount := accountsmodel.Accounts{}
-	mockAccountsDao.EXPECT().GetById(destinationAccountId).Return(destinationAccount, errors.New("test"))
+	mockAccountsDao.EXPECT().GetById(mock.Anything, destinationAccountId).Return(destinationAccount, errors.New("test"))
 
 	mocktransactionsDao := transactionsdaomocks.NewDao(t)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
 	mockDB, _ := pgxmock.NewPool()
 
-	h := NewHandler(mockDB, mockAccountsDao, mocktransactionsDao)
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
 	h.RouteGroup(router)
 
 	body := `{
@@ -124,12 +197,13 @@ func TestTransactionsCreate_DestinationAccountDaoReturnsError(t *testing.T) {
 	req, _ := http.NewRequest("POST", "/transactions", bodyReader)
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusNotFound, w.Code)
-	assert.Equal(t, "{\"error\":\"test\"}", w.Body.String())
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:internal-error\",\"title\":\"Internal server error\",\"status\":500,\"detail\":\"the request could not be completed\",\"instance\":\"/transactions\",\"code\":\"INTERNAL_ERROR\"}", w.Body.String())
 }
 
 func TestTransactionsCreate_SourceAccountHasLessBalance(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity), auth.Tenancy())
 
 	mockAccountsDao := accountsdaomocks.NewDao(t)
 
@@ -137,12 +211,16 @@ func TestTransactionsCreate_SourceAccountHasLessBalance(t *testing.T) {
 	sourceAccount := accountsmodel.Accounts{}
 	sourceAccount.SetId(sourceAccountId)
 	sourceAccount.SetBalance(100.1)
-	mockAccountsDao.EXPECT().GetById(sourceAccountId).Return(sourceAccount, nil)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, sourceAccountId).Return(sourceAccount, nil)
 
 	mocktransactionsDao := transactionsdaomocks.NewDao(t)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeTransferFailed, sourceAccountId, mock.Anything).Return(nil)
 	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectCommit()
 
-	h := NewHandler(mockDB, mockAccountsDao, mocktransactionsDao)
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
 	h.RouteGroup(router)
 
 	body := `{
@@ -156,12 +234,13 @@ func TestTransactionsCreate_SourceAccountHasLessBalance(t *testing.T) {
 	req, _ := http.NewRequest("POST", "/transactions", bodyReader)
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Equal(t, "{\"error\":\"account balance is less than transaction\"}", w.Body.String())
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:insufficient-funds\",\"title\":\"The account has insufficient funds\",\"status\":422,\"detail\":\"account balance is less than transaction\",\"instance\":\"/transactions\",\"code\":\"INSUFFICIENT_FUNDS\"}", w.Body.String())
 }
 
 func TestTransactionsCreate_UnableToStartTxn(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity), auth.Tenancy())
 
 	mockAccountsDao := accountsdaomocks.NewDao(t)
 
@@ -169,20 +248,21 @@ func TestTransactionsCreate_UnableToStartTxn(t *testing.T) {
 	sourceAccount := accountsmodel.Accounts{}
 	sourceAccount.SetId(sourceAccountId)
 	sourceAccount.SetBalance(300.1)
-	mockAccountsDao.EXPECT().GetById(sourceAccountId).Return(sourceAccount, nil)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, sourceAccountId).Return(sourceAccount, nil)
 
 	destinationAccountId := int64(456)
 	destinationAccount := accountsmodel.Accounts{}
 	destinationAccount.SetId(destinationAccountId)
 	destinationAccount.SetBalance(200.1)
-	mockAccountsDao.EXPECT().GetById(destinationAccountId).Return(destinationAccount, nil)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, destinationAccountId).Return(destinationAccount, nil)
 
 	mocktransactionsDao := transactionsdaomocks.NewDao(t)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
 
 	mockDB, _ := pgxmock.NewPool()
 	mockDB.ExpectBegin().WillReturnError(errors.New("test"))
 
-	h := NewHandler(mockDB, mockAccountsDao, mocktransactionsDao)
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
 	h.RouteGroup(router)
 
 	body := `{
@@ -197,11 +277,12 @@ func TestTransactionsCreate_UnableToStartTxn(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "{\"error\":\"test\"}", w.Body.String())
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:internal-error\",\"title\":\"Internal server error\",\"status\":500,\"detail\":\"the request could not be completed\",\"instance\":\"/transactions\",\"code\":\"INTERNAL_ERROR\"}", w.Body.String())
 }
 
 func TestTransactionsCreate_TransactionCreateReturnsError(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity), auth.Tenancy())
 
 	amount := 100.12345
 
@@ -211,22 +292,23 @@ func TestTransactionsCreate_TransactionCreateReturnsError(t *testing.T) {
 	sourceAccount := accountsmodel.Accounts{}
 	sourceAccount.SetId(sourceAccountId)
 	sourceAccount.SetBalance(300.1)
-	mockAccountsDao.EXPECT().GetById(sourceAccountId).Return(sourceAccount, nil)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, sourceAccountId).Return(sourceAccount, nil)
 
 	destinationAccountId := int64(456)
 	destinationAccount := accountsmodel.Accounts{}
 	destinationAccount.SetId(destinationAccountId)
 	destinationAccount.SetBalance(200.1)
-	mockAccountsDao.EXPECT().GetById(destinationAccountId).Return(destinationAccount, nil)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, destinationAccountId).Return(destinationAccount, nil)
 
 	mocktransactionsDao := transactionsdaomocks.NewDao(t)
-	mocktransactionsDao.EXPECT().Create(mock.Anything, sourceAccountId, destinationAccountId, amount).Return(0, errors.New("test"))
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mocktransactionsDao.EXPECT().Create(mock.Anything, mock.Anything, sourceAccountId, destinationAccountId, tenant.Default, amount, "admin").Return(0, errors.New("test"))
 
 	mockDB, _ := pgxmock.NewPool()
 	mockDB.ExpectBegin()
 	mockDB.ExpectRollback()
 
-	h := NewHandler(mockDB, mockAccountsDao, mocktransactionsDao)
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
 	h.RouteGroup(router)
 
 	body := `{
@@ -241,11 +323,12 @@ func TestTransactionsCreate_TransactionCreateReturnsError(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "{\"error\":\"test\"}", w.Body.String())
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:internal-error\",\"title\":\"Internal server error\",\"status\":500,\"detail\":\"the request could not be completed\",\"instance\":\"/transactions\",\"code\":\"INTERNAL_ERROR\"}", w.Body.String())
 }
 
 func TestTransactionsCreate_UpdateSourceAccountReturnsError(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity), auth.Tenancy())
 
 	amount := 100.12345
 
@@ -258,26 +341,28 @@ func TestTransactionsCreate_UpdateSourceAccountReturnsError(t *testing.T) {
 	sourceAccount.SetBalance(sourceAccountBalance)
 	sourceVersion := int64(1)
 	sourceAccount.SetVersion(sourceVersion)
-	mockAccountsDao.EXPECT().GetById(sourceAccountId).Return(sourceAccount, nil)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, sourceAccountId).Return(sourceAccount, nil)
 
 	destinationAccountId := int64(456)
 	destinationAccount := accountsmodel.Accounts{}
 	destinationAccount.SetId(destinationAccountId)
 	destinationAccountBalance := 200.1
 	destinationAccount.SetBalance(destinationAccountBalance)
-	mockAccountsDao.EXPECT().GetById(destinationAccountId).Return(destinationAccount, nil)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, destinationAccountId).Return(destinationAccount, nil)
 
 	mocktransactionsDao := transactionsdaomocks.NewDao(t)
-	mocktransactionsDao.EXPECT().Create(mock.Anything, sourceAccountId, destinationAccountId, amount).Return(1, nil)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeTransferPosted, sourceAccountId, mock.Anything).Return(nil)
+	mocktransactionsDao.EXPECT().Create(mock.Anything, mock.Anything, sourceAccountId, destinationAccountId, tenant.Default, amount, "admin").Return(1, nil)
 
 	newSourceAccountBalance := sourceAccountBalance - amount
-	mockAccountsDao.EXPECT().UpdateBalance(mock.Anything, sourceAccountId, sourceVersion, newSourceAccountBalance).Return(sourceAccount, errors.New("test"))
+	mockAccountsDao.EXPECT().UpdateBalance(mock.Anything, mock.Anything, sourceAccountId, sourceVersion, newSourceAccountBalance).Return(sourceAccount, errors.New("test"))
 
 	mockDB, _ := pgxmock.NewPool()
 	mockDB.ExpectBegin()
 	mockDB.ExpectRollback()
 
-	h := NewHandler(mockDB, mockAccountsDao, mocktransactionsDao)
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
 	h.RouteGroup(router)
 
 	body := `{
@@ -292,11 +377,12 @@ func TestTransactionsCreate_UpdateSourceAccountReturnsError(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "{\"error\":\"test\"}", w.Body.String())
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:internal-error\",\"title\":\"Internal server error\",\"status\":500,\"detail\":\"the request could not be completed\",\"instance\":\"/transactions\",\"code\":\"INTERNAL_ERROR\"}", w.Body.String())
 }
 
 func TestTransactionsCreate_UpdateDestinationAccountReturnsError(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity), auth.Tenancy())
 
 	amount := 100.12345
 
@@ -309,7 +395,7 @@ func TestTransactionsCreate_UpdateDestinationAccountReturnsError(t *testing.T) {
 	sourceAccount.SetBalance(sourceAccountBalance)
 	sourceVersion := int64(1)
 	sourceAccount.SetVersion(sourceVersion)
-	mockAccountsDao.EXPECT().GetById(sourceAccountId).Return(sourceAccount, nil)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, sourceAccountId).Return(sourceAccount, nil)
 
 	destinationAccountId := int64(456)
 	destinationAccount := accountsmodel.Accounts{}
@@ -318,22 +404,25 @@ func TestTransactionsCreate_UpdateDestinationAccountReturnsError(t *testing.T) {
 	destinationAccount.SetBalance(destinationAccountBalance)
 	destinationVersion := int64(2)
 	destinationAccount.SetVersion(destinationVersion)
-	mockAccountsDao.EXPECT().GetById(destinationAccountId).Return(destinationAccount, nil)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, destinationAccountId).Return(destinationAccount, nil)
 
 	mocktransactionsDao := transactionsdaomocks.NewDao(t)
-	mocktransactionsDao.EXPECT().Create(mock.Anything, sourceAccountId, destinationAccountId, amount).Return(1, nil)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeTransferPosted, sourceAccountId, mock.Anything).Return(nil)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeAccountBalanceChanged, sourceAccountId, mock.Anything).Return(nil)
+	mocktransactionsDao.EXPECT().Create(mock.Anything, mock.Anything, sourceAccountId, destinationAccountId, tenant.Default, amount, "admin").Return(1, nil)
 
 	newSourceAccountBalance := sourceAccountBalance - amount
-	mockAccountsDao.EXPECT().UpdateBalance(mock.Anything, sourceAccountId, sourceVersion, newSourceAccountBalance).Return(sourceAccount, nil)
+	mockAccountsDao.EXPECT().UpdateBalance(mock.Anything, mock.Anything, sourceAccountId, sourceVersion, newSourceAccountBalance).Return(sourceAccount, nil)
 
 	newDestinationAccountBalance := destinationAccountBalance + amount
-	mockAccountsDao.EXPECT().UpdateBalance(mock.Anything, destinationAccountId, destinationVersion, newDestinationAccountBalance).Return(sourceAccount, errors.New("test"))
+	mockAccountsDao.EXPECT().UpdateBalance(mock.Anything, mock.Anything, destinationAccountId, destinationVersion, newDestinationAccountBalance).Return(sourceAccount, errors.New("test"))
 
 	mockDB, _ := pgxmock.NewPool()
 	mockDB.ExpectBegin()
 	mockDB.ExpectRollback()
 
-	h := NewHandler(mockDB, mockAccountsDao, mocktransactionsDao)
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
 	h.RouteGroup(router)
 
 	body := `{
@@ -348,11 +437,129 @@ func TestTransactionsCreate_UpdateDestinationAccountReturnsError(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "{\"error\":\"test\"}", w.Body.String())
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:internal-error\",\"title\":\"Internal server error\",\"status\":500,\"detail\":\"the request could not be completed\",\"instance\":\"/transactions\",\"code\":\"INTERNAL_ERROR\"}", w.Body.String())
+}
+
+func TestTransactionsCreate_UpdateAccountVersionConflict(t *testing.T) {
+	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity), auth.Tenancy())
+
+	amount := 100.12345
+
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+
+	sourceAccountId := int64(123)
+	sourceAccount := accountsmodel.Accounts{}
+	sourceAccount.SetId(sourceAccountId)
+	sourceAccountBalance := 300.1
+	sourceAccount.SetBalance(sourceAccountBalance)
+	sourceVersion := int64(1)
+	sourceAccount.SetVersion(sourceVersion)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, sourceAccountId).Return(sourceAccount, nil)
+
+	destinationAccountId := int64(456)
+	destinationAccount := accountsmodel.Accounts{}
+	destinationAccount.SetId(destinationAccountId)
+	destinationAccount.SetBalance(200.1)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, destinationAccountId).Return(destinationAccount, nil)
+
+	mocktransactionsDao := transactionsdaomocks.NewDao(t)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeTransferPosted, sourceAccountId, mock.Anything).Return(nil)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeTransferFailed, sourceAccountId, mock.Anything).Return(nil)
+	mocktransactionsDao.EXPECT().Create(mock.Anything, mock.Anything, sourceAccountId, destinationAccountId, tenant.Default, amount, "admin").Return(1, nil)
+
+	newSourceAccountBalance := sourceAccountBalance - amount
+	mockAccountsDao.EXPECT().UpdateBalance(mock.Anything, mock.Anything, sourceAccountId, sourceVersion, newSourceAccountBalance).Return(accountsmodel.Accounts{}, accountsdao.ErrVersionConflict)
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectRollback()
+	mockDB.ExpectBegin()
+	mockDB.ExpectCommit()
+
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
+	h.RouteGroup(router)
+
+	body := `{
+		"source_account_id": 123,
+		"destination_account_id": 456,
+		"amount": "100.12345"
+	}`
+	bodyReader := strings.NewReader(body)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", bodyReader)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:version-conflict\",\"title\":\"The account was modified concurrently\",\"status\":409,\"detail\":\"account was modified concurrently, please retry\",\"instance\":\"/transactions\",\"code\":\"VERSION_CONFLICT\"}", w.Body.String())
 }
 
 func TestTransactionsCreate_Success(t *testing.T) {
 	router := gin.Default()
+	router.Use(auth.WithIdentity(adminIdentity), auth.Tenancy())
+
+	amount := 100.12345
+
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+
+	sourceAccountId := int64(123)
+	sourceAccount := accountsmodel.Accounts{}
+	sourceAccount.SetId(sourceAccountId)
+	sourceAccountBalance := 300.1
+	sourceAccount.SetBalance(sourceAccountBalance)
+	sourceVersion := int64(1)
+	sourceAccount.SetVersion(sourceVersion)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, sourceAccountId).Return(sourceAccount, nil)
+
+	destinationAccountId := int64(456)
+	destinationAccount := accountsmodel.Accounts{}
+	destinationAccount.SetId(destinationAccountId)
+	destinationAccountBalance := 200.1
+	destinationAccount.SetBalance(destinationAccountBalance)
+	destinationVersion := int64(2)
+	destinationAccount.SetVersion(destinationVersion)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, destinationAccountId).Return(destinationAccount, nil)
+
+	mocktransactionsDao := transactionsdaomocks.NewDao(t)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeTransferPosted, sourceAccountId, mock.Anything).Return(nil)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeAccountBalanceChanged, sourceAccountId, mock.Anything).Return(nil)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeAccountBalanceChanged, destinationAccountId, mock.Anything).Return(nil)
+	mocktransactionsDao.EXPECT().Create(mock.Anything, mock.Anything, sourceAccountId, destinationAccountId, tenant.Default, amount, "admin").Return(1, nil)
+
+	newSourceAccountBalance := sourceAccountBalance - amount
+	mockAccountsDao.EXPECT().UpdateBalance(mock.Anything, mock.Anything, sourceAccountId, sourceVersion, newSourceAccountBalance).Return(sourceAccount, nil)
+
+	newDestinationAccountBalance := destinationAccountBalance + amount
+	mockAccountsDao.EXPECT().UpdateBalance(mock.Anything, mock.Anything, destinationAccountId, destinationVersion, newDestinationAccountBalance).Return(sourceAccount, nil)
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectCommit()
+
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
+	h.RouteGroup(router)
+
+	body := `{
+		"source_account_id": 123,
+		"destination_account_id": 456,
+		"amount": "100.12345"
+	}`
+	bodyReader := strings.NewReader(body)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", bodyReader)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{\"transaction_id\":1}", w.Body.String())
+}
+
+func TestTransactionsCreate_RecordsAuthenticatedClient(t *testing.T) {
+	router := gin.Default()
+	router.Use(auth.WithIdentity(clientIdentity), auth.Tenancy())
 
 	amount := 100.12345
 
@@ -365,7 +572,8 @@ func TestTransactionsCreate_Success(t *testing.T) {
 	sourceAccount.SetBalance(sourceAccountBalance)
 	sourceVersion := int64(1)
 	sourceAccount.SetVersion(sourceVersion)
-	mockAccountsDao.EXPECT().GetById(sourceAccountId).Return(sourceAccount, nil)
+	sourceAccount.SetOwnerId("client-1")
+	mockAccountsDao.EXPECT().GetById(mock.Anything, sourceAccountId).Return(sourceAccount, nil)
 
 	destinationAccountId := int64(456)
 	destinationAccount := accountsmodel.Accounts{}
@@ -374,22 +582,26 @@ func TestTransactionsCreate_Success(t *testing.T) {
 	destinationAccount.SetBalance(destinationAccountBalance)
 	destinationVersion := int64(2)
 	destinationAccount.SetVersion(destinationVersion)
-	mockAccountsDao.EXPECT().GetById(destinationAccountId).Return(destinationAccount, nil)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, destinationAccountId).Return(destinationAccount, nil)
 
 	mocktransactionsDao := transactionsdaomocks.NewDao(t)
-	mocktransactionsDao.EXPECT().Create(mock.Anything, sourceAccountId, destinationAccountId, amount).Return(1, nil)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeTransferPosted, sourceAccountId, mock.Anything).Return(nil)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeAccountBalanceChanged, sourceAccountId, mock.Anything).Return(nil)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeAccountBalanceChanged, destinationAccountId, mock.Anything).Return(nil)
+	mocktransactionsDao.EXPECT().Create(mock.Anything, mock.Anything, sourceAccountId, destinationAccountId, tenant.Default, amount, "client-1").Return(1, nil)
 
 	newSourceAccountBalance := sourceAccountBalance - amount
-	mockAccountsDao.EXPECT().UpdateBalance(mock.Anything, sourceAccountId, sourceVersion, newSourceAccountBalance).Return(sourceAccount, nil)
+	mockAccountsDao.EXPECT().UpdateBalance(mock.Anything, mock.Anything, sourceAccountId, sourceVersion, newSourceAccountBalance).Return(sourceAccount, nil)
 
 	newDestinationAccountBalance := destinationAccountBalance + amount
-	mockAccountsDao.EXPECT().UpdateBalance(mock.Anything, destinationAccountId, destinationVersion, newDestinationAccountBalance).Return(sourceAccount, nil)
+	mockAccountsDao.EXPECT().UpdateBalance(mock.Anything, mock.Anything, destinationAccountId, destinationVersion, newDestinationAccountBalance).Return(sourceAccount, nil)
 
 	mockDB, _ := pgxmock.NewPool()
 	mockDB.ExpectBegin()
 	mockDB.ExpectCommit()
 
-	h := NewHandler(mockDB, mockAccountsDao, mocktransactionsDao)
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
 	h.RouteGroup(router)
 
 	body := `{
@@ -406,3 +618,200 @@ func TestTransactionsCreate_Success(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "{\"transaction_id\":1}", w.Body.String())
 }
+
+func TestTransactionsCreate_ForeignSourceAccount(t *testing.T) {
+	router := gin.Default()
+	router.Use(auth.WithIdentity(clientIdentity), auth.Tenancy())
+
+	sourceAccount := accountsmodel.Accounts{}
+	sourceAccount.SetId(123)
+	sourceAccount.SetBalance(300.1)
+	sourceAccount.SetOwnerId("client-2")
+
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(sourceAccount, nil)
+	mocktransactionsDao := transactionsdaomocks.NewDao(t)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockDB, _ := pgxmock.NewPool()
+
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
+	h.RouteGroup(router)
+
+	body := `{
+		"source_account_id": 123,
+		"destination_account_id": 456,
+		"amount": "100.12345"
+	}`
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:forbidden\",\"title\":\"The request is not allowed\",\"status\":403,\"detail\":\"you do not have access to the source account\",\"instance\":\"/transactions\",\"code\":\"FORBIDDEN\"}", w.Body.String())
+}
+
+func TestTransactionsCreate_MissingSourceAccountLooksForeign(t *testing.T) {
+	router := gin.Default()
+	router.Use(auth.WithIdentity(clientIdentity), auth.Tenancy())
+
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(accountsmodel.Accounts{}, pgx.ErrNoRows)
+	mocktransactionsDao := transactionsdaomocks.NewDao(t)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockDB, _ := pgxmock.NewPool()
+
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
+	h.RouteGroup(router)
+
+	body := `{
+		"source_account_id": 123,
+		"destination_account_id": 456,
+		"amount": "100.12345"
+	}`
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:forbidden\",\"title\":\"The request is not allowed\",\"status\":403,\"detail\":\"you do not have access to the source account\",\"instance\":\"/transactions\",\"code\":\"FORBIDDEN\"}", w.Body.String())
+}
+
+func TestTransactionsCreate_RequiresTransferRole(t *testing.T) {
+	router := gin.Default()
+	router.Use(auth.WithIdentity(auth.Identity{ClientID: "client-1", Roles: []string{auth.RoleRead}}))
+
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+	mocktransactionsDao := transactionsdaomocks.NewDao(t)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockDB, _ := pgxmock.NewPool()
+
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(`{}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:forbidden\",\"title\":\"The request is not allowed\",\"status\":403,\"detail\":\"insufficient permissions\",\"instance\":\"/transactions\",\"code\":\"FORBIDDEN\"}", w.Body.String())
+}
+
+func TestTransactionsCreate_CrossTenantForbiddenByDefault(t *testing.T) {
+	router := gin.Default()
+	router.Use(auth.WithIdentity(clientIdentity), auth.Tenancy())
+
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+	mocktransactionsDao := transactionsdaomocks.NewDao(t)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockDB, _ := pgxmock.NewPool()
+
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false))
+	h.RouteGroup(router)
+
+	body := `{
+		"source_account_id": 123,
+		"destination_account_id": 456,
+		"destination_tenant_id": "corporate",
+		"amount": "100.12345"
+	}`
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:forbidden\",\"title\":\"The request is not allowed\",\"status\":403,\"detail\":\"transfers between tenants are not allowed\",\"instance\":\"/transactions\",\"code\":\"FORBIDDEN\"}", w.Body.String())
+}
+
+func TestTransactionsCreate_CrossTenantWhenAllowed(t *testing.T) {
+	router := gin.Default()
+	router.Use(auth.WithIdentity(auth.Identity{ClientID: "client-1", Roles: []string{auth.RoleTransfer}, TenantID: "retail"}), auth.Tenancy())
+
+	amount := 100.12345
+	tenantOf := func(ctx context.Context) string {
+		id, _ := tenant.FromContext(ctx)
+		return id
+	}
+	inTenant := func(id string) any {
+		return mock.MatchedBy(func(ctx context.Context) bool { return tenantOf(ctx) == id })
+	}
+
+	sourceAccount := accountsmodel.Accounts{}
+	sourceAccount.SetId(123)
+	sourceAccount.SetBalance(300.1)
+	sourceAccount.SetVersion(1)
+	sourceAccount.SetOwnerId("client-1")
+
+	destinationAccount := accountsmodel.Accounts{}
+	destinationAccount.SetId(456)
+	destinationAccount.SetBalance(200.1)
+	destinationAccount.SetVersion(2)
+
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+	mockAccountsDao.EXPECT().GetById(inTenant("retail"), int64(123)).Return(sourceAccount, nil)
+	mockAccountsDao.EXPECT().GetById(inTenant("corporate"), int64(456)).Return(destinationAccount, nil)
+	mockAccountsDao.EXPECT().UpdateBalance(inTenant("retail"), mock.Anything, int64(123), int64(1), 300.1-amount).Return(sourceAccount, nil)
+	mockAccountsDao.EXPECT().UpdateBalance(inTenant("corporate"), mock.Anything, int64(456), int64(2), 200.1+amount).Return(destinationAccount, nil)
+
+	mocktransactionsDao := transactionsdaomocks.NewDao(t)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockOutboxDao.EXPECT().Add(inTenant("retail"), mock.Anything, eventsmodel.TypeTransferPosted, int64(123), mock.Anything).Return(nil)
+	mockOutboxDao.EXPECT().Add(inTenant("retail"), mock.Anything, eventsmodel.TypeAccountBalanceChanged, int64(123), mock.Anything).Return(nil)
+	mockOutboxDao.EXPECT().Add(inTenant("corporate"), mock.Anything, eventsmodel.TypeAccountBalanceChanged, int64(456), mock.Anything).Return(nil)
+	mocktransactionsDao.EXPECT().Create(inTenant("retail"), mock.Anything, int64(123), int64(456), "corporate", amount, "client-1").Return(1, nil)
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectCommit()
+
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, true))
+	h.RouteGroup(router)
+
+	body := `{
+		"source_account_id": 123,
+		"destination_account_id": 456,
+		"destination_tenant_id": "corporate",
+		"amount": "100.12345"
+	}`
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{\"transaction_id\":1}", w.Body.String())
+}
+
+func TestTransactionsCreate_RunsMiddlewareBeforeTransfer(t *testing.T) {
+	router := gin.Default()
+	router.Use(auth.WithIdentity(clientIdentity), auth.Tenancy())
+
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+	mocktransactionsDao := transactionsdaomocks.NewDao(t)
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockDB, _ := pgxmock.NewPool()
+
+	limited := func(c *gin.Context) {
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+	}
+
+	h := NewHandler(transfers.NewService(mockDB, mockAccountsDao, mocktransactionsDao, mockOutboxDao, false), limited)
+	h.RouteGroup(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(`{"source_account_id": 123}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestTransactions_DescribesRegisteredRoutes(t *testing.T) {
+	router := gin.Default()
+
+	h := NewHandler(transfers.NewService(nil, accountsdaomocks.NewDao(t), transactionsdaomocks.NewDao(t), outboxdaomocks.NewDao(t), false))
+	h.RouteGroup(router)
+
+	assert.Empty(t, openapi.Drift(openapi.New(h), router.Routes()))
+}
@@ -0,0 +1,380 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	webhooksmodel "github.com/ashwin-m/transactions/models/webhooks"
+	webhooksservice "github.com/ashwin-m/transactions/services/webhooks"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+type createWebhookRequest struct {
+	Url string `json:"url" binding:"required"`
+	// EventTypes are the types of events delivered, every type when empty.
+	EventTypes []string `json:"event_types"`
+}
+
+type webhook struct {
+	Id         int64    `json:"id"`
+	Url        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// OwnerId limits the subscription to the events of the accounts of an
+	// owner. Subscriptions of admins get the events of the whole tenant.
+	OwnerId   string    `json:"owner_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Secret signs the deliveries, it is only returned when the
+	// subscription is created.
+	Secret string `json:"secret,omitempty"`
+}
+
+type listWebhooksResponse struct {
+	Webhooks []webhook `json:"webhooks"`
+}
+
+type delivery struct {
+	Id        int64  `json:"id"`
+	EventId   int64  `json:"event_id"`
+	EventType string `json:"event_type"`
+	Status    string `json:"status"`
+	Attempts  int    `json:"attempts"`
+	// NextAttemptAt is only set while the delivery is pending.
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+type listDeliveriesResponse struct {
+	Deliveries []delivery `json:"deliveries"`
+}
+
+type attempt struct {
+	AttemptedAt time.Time `json:"attempted_at"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DurationMs  int64     `json:"duration_ms"`
+}
+
+type deliveryDetail struct {
+	delivery
+	// Payload is the body that is POSTed, an event message.
+	Payload    any       `json:"payload"`
+	AttemptLog []attempt `json:"attempt_log"`
+}
+
+type handler struct {
+	service webhooksservice.Service
+}
+
+type Handler interface {
+	RouteGroup(gin.IRouter)
+	Describe(*openapi3.T)
+}
+
+func NewHandler(service webhooksservice.Service) Handler {
+	return &handler{
+		service: service,
+	}
+}
+
+func (h *handler) RouteGroup(r gin.IRouter) {
+	rg := r.Group("/webhooks")
+
+	rg.POST("", auth.RequireRole(auth.RoleTransfer), h.create)
+	rg.GET("", auth.RequireRole(auth.RoleRead), h.list)
+	rg.GET("/:id", auth.RequireRole(auth.RoleRead), h.get)
+	rg.DELETE("/:id", auth.RequireRole(auth.RoleTransfer), h.delete)
+	rg.GET("/:id/deliveries", auth.RequireRole(auth.RoleRead), h.listDeliveries)
+	rg.GET("/:id/deliveries/:delivery_id", auth.RequireRole(auth.RoleRead), h.getDelivery)
+	rg.POST("/:id/deliveries/:delivery_id/redeliver", auth.RequireRole(auth.RoleTransfer), h.redeliver)
+}
+
+// Describe documents the routes registered by RouteGroup.
+func (h *handler) Describe(doc *openapi3.T) {
+	id := openapi3.NewPathParameter("id").WithSchema(openapi3.NewInt64Schema())
+	deliveryId := openapi3.NewPathParameter("delivery_id").WithSchema(openapi3.NewInt64Schema())
+
+	create := openapi3.NewOperation()
+	create.OperationID = "createWebhook"
+	create.Summary = "Subscribe an endpoint to events"
+	create.RequestBody = openapi.JSONBody(createWebhookRequest{})
+	create.AddResponse(http.StatusCreated, openapi.JSONResponse("The subscription, with its signing secret", webhook{}))
+	openapi.Problems(create, apperrors.CodeValidationFailed, apperrors.CodeUnauthorized, apperrors.CodeForbidden,
+		apperrors.CodeRateLimited, apperrors.CodeInternal)
+	openapi.Operation(doc, http.MethodPost, "/webhooks", create)
+
+	list := openapi3.NewOperation()
+	list.OperationID = "listWebhooks"
+	list.Summary = "List webhook subscriptions"
+	list.AddResponse(http.StatusOK, openapi.JSONResponse("The subscriptions", listWebhooksResponse{}))
+	openapi.Problems(list, apperrors.CodeUnauthorized, apperrors.CodeForbidden, apperrors.CodeRateLimited, apperrors.CodeInternal)
+	openapi.Operation(doc, http.MethodGet, "/webhooks", list)
+
+	get := openapi3.NewOperation()
+	get.OperationID = "getWebhook"
+	get.Summary = "Get a webhook subscription by id"
+	get.AddParameter(id)
+	get.AddResponse(http.StatusOK, openapi.JSONResponse("The subscription", webhook{}))
+	openapi.Problems(get, apperrors.CodeValidationFailed, apperrors.CodeUnauthorized, apperrors.CodeForbidden,
+		apperrors.CodeWebhookNotFound, apperrors.CodeRateLimited, apperrors.CodeInternal)
+	openapi.Operation(doc, http.MethodGet, "/webhooks/:id", get)
+
+	remove := openapi3.NewOperation()
+	remove.OperationID = "deleteWebhook"
+	remove.Summary = "Unsubscribe an endpoint, dropping its pending deliveries"
+	remove.AddParameter(id)
+	remove.AddResponse(http.StatusNoContent, openapi.EmptyResponse("The subscription was deleted"))
+	openapi.Problems(remove, apperrors.CodeValidationFailed, apperrors.CodeUnauthorized, apperrors.CodeForbidden,
+		apperrors.CodeWebhookNotFound, apperrors.CodeRateLimited, apperrors.CodeInternal)
+	openapi.Operation(doc, http.MethodDelete, "/webhooks/:id", remove)
+
+	listDeliveries := openapi3.NewOperation()
+	listDeliveries.OperationID = "listWebhookDeliveries"
+	listDeliveries.Summary = "List the deliveries of a subscription, newest first"
+	listDeliveries.AddParameter(id)
+	listDeliveries.AddParameter(openapi3.NewQueryParameter("status").WithSchema(openapi3.NewStringSchema().WithEnum(enum(webhooksmodel.Statuses)...)))
+	listDeliveries.AddParameter(openapi3.NewQueryParameter("page_size").WithSchema(openapi3.NewInt32Schema().WithMin(0).WithMax(webhooksservice.MaxPageSize)))
+	listDeliveries.AddParameter(openapi3.NewQueryParameter("before_id").WithSchema(openapi3.NewInt64Schema()))
+	listDeliveries.AddResponse(http.StatusOK, openapi.JSONResponse("A page of deliveries, a short one ends the listing", listDeliveriesResponse{}))
+	openapi.Problems(listDeliveries, apperrors.CodeValidationFailed, apperrors.CodeUnauthorized, apperrors.CodeForbidden,
+		apperrors.CodeWebhookNotFound, apperrors.CodeRateLimited, apperrors.CodeInternal)
+	openapi.Operation(doc, http.MethodGet, "/webhooks/:id/deliveries", listDeliveries)
+
+	getDelivery := openapi3.NewOperation()
+	getDelivery.OperationID = "getWebhookDelivery"
+	getDelivery.Summary = "Get a delivery with its payload and attempts"
+	getDelivery.AddParameter(id)
+	getDelivery.AddParameter(deliveryId)
+	getDelivery.AddResponse(http.StatusOK, openapi.JSONResponse("The delivery", deliveryDetail{}))
+	openapi.Problems(getDelivery, apperrors.CodeValidationFailed, apperrors.CodeUnauthorized, apperrors.CodeForbidden,
+		apperrors.CodeWebhookNotFound, apperrors.CodeDeliveryNotFound, apperrors.CodeRateLimited, apperrors.CodeInternal)
+	openapi.Operation(doc, http.MethodGet, "/webhooks/:id/deliveries/:delivery_id", getDelivery)
+
+	redeliver := openapi3.NewOperation()
+	redeliver.OperationID = "redeliverWebhook"
+	redeliver.Summary = "Queue a delivery again, with a fresh set of attempts"
+	redeliver.AddParameter(id)
+	redeliver.AddParameter(deliveryId)
+	redeliver.AddResponse(http.StatusAccepted, openapi.JSONResponse("The delivery was queued", delivery{}))
+	openapi.Problems(redeliver, apperrors.CodeValidationFailed, apperrors.CodeUnauthorized, apperrors.CodeForbidden,
+		apperrors.CodeWebhookNotFound, apperrors.CodeDeliveryNotFound, apperrors.CodeRateLimited, apperrors.CodeInternal)
+	openapi.Operation(doc, http.MethodPost, "/webhooks/:id/deliveries/:delivery_id/redeliver", redeliver)
+}
+
+func (h *handler) create(c *gin.Context) {
+	var request createWebhookRequest
+
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		apperrors.Abort(c, apperrors.FromBinding(err))
+		return
+	}
+
+	created, err := h.service.Create(c.Request.Context(), webhooksservice.CreateRequest{
+		Url:        request.Url,
+		EventTypes: request.EventTypes,
+	})
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	response := newWebhook(created)
+	response.Secret = created.GetSecret()
+
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *handler) list(c *gin.Context) {
+	subscriptions, err := h.service.List(c.Request.Context())
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	response := listWebhooksResponse{Webhooks: make([]webhook, len(subscriptions))}
+	for i, subscription := range subscriptions {
+		response.Webhooks[i] = newWebhook(subscription)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *handler) get(c *gin.Context) {
+	id, ok := int64Param(c, "id")
+	if !ok {
+		return
+	}
+
+	subscription, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newWebhook(subscription))
+}
+
+func (h *handler) delete(c *gin.Context) {
+	id, ok := int64Param(c, "id")
+	if !ok {
+		return
+	}
+
+	err := h.service.Delete(c.Request.Context(), id)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *handler) listDeliveries(c *gin.Context) {
+	id, ok := int64Param(c, "id")
+	if !ok {
+		return
+	}
+
+	request := webhooksservice.ListDeliveriesRequest{SubscriptionId: id, Status: c.Query("status")}
+	if pageSize := c.Query("page_size"); pageSize != "" {
+		size, err := strconv.Atoi(pageSize)
+		if err != nil {
+			apperrors.Abort(c, apperrors.Validation(apperrors.FieldError{Field: "page_size", Message: "must be an integer"}))
+			return
+		}
+		request.PageSize = size
+	}
+	if beforeId := c.Query("before_id"); beforeId != "" {
+		before, err := strconv.ParseInt(beforeId, 10, 64)
+		if err != nil {
+			apperrors.Abort(c, apperrors.Validation(apperrors.FieldError{Field: "before_id", Message: "must be an integer"}))
+			return
+		}
+		request.BeforeId = before
+	}
+
+	deliveries, err := h.service.ListDeliveries(c.Request.Context(), request)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	response := listDeliveriesResponse{Deliveries: make([]delivery, len(deliveries))}
+	for i, d := range deliveries {
+		response.Deliveries[i] = newDelivery(d)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *handler) getDelivery(c *gin.Context) {
+	id, ok := int64Param(c, "id")
+	if !ok {
+		return
+	}
+	deliveryId, ok := int64Param(c, "delivery_id")
+	if !ok {
+		return
+	}
+
+	d, attempts, err := h.service.GetDelivery(c.Request.Context(), id, deliveryId)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	response := deliveryDetail{
+		delivery:   newDelivery(d),
+		Payload:    json.RawMessage(d.GetPayload()),
+		AttemptLog: make([]attempt, len(attempts)),
+	}
+	for i, a := range attempts {
+		response.AttemptLog[i] = attempt{
+			AttemptedAt: a.GetAttemptedAt(),
+			StatusCode:  a.GetStatusCode(),
+			Error:       a.GetError(),
+			DurationMs:  a.GetDuration().Milliseconds(),
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *handler) redeliver(c *gin.Context) {
+	id, ok := int64Param(c, "id")
+	if !ok {
+		return
+	}
+	deliveryId, ok := int64Param(c, "delivery_id")
+	if !ok {
+		return
+	}
+
+	d, err := h.service.Redeliver(c.Request.Context(), id, deliveryId)
+	if err != nil {
+		apperrors.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, newDelivery(d))
+}
+
+// int64Param parses a path parameter, aborting the request when it isn't an
+// integer.
+func int64Param(c *gin.Context, name string) (int64, bool) {
+	value, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		apperrors.Abort(c, apperrors.Validation(apperrors.FieldError{Field: name, Message: "must be an integer"}))
+		return 0, false
+	}
+
+	return value, true
+}
+
+func newWebhook(model webhooksmodel.Subscriptions) webhook {
+	return webhook{
+		Id:         model.GetId(),
+		Url:        model.GetUrl(),
+		EventTypes: model.GetEventTypes(),
+		OwnerId:    model.GetOwnerId(),
+		CreatedAt:  model.GetCreatedAt(),
+	}
+}
+
+func newDelivery(model webhooksmodel.Deliveries) delivery {
+	response := delivery{
+		Id:             model.GetId(),
+		EventId:        model.GetEventId(),
+		EventType:      model.GetEventType(),
+		Status:         model.GetStatus(),
+		Attempts:       model.GetAttempts(),
+		LastStatusCode: model.GetLastStatusCode(),
+		LastError:      model.GetLastError(),
+		CreatedAt:      model.GetCreatedAt(),
+		DeliveredAt:    model.GetDeliveredAt(),
+	}
+	if model.GetStatus() == webhooksmodel.StatusPending {
+		nextAttemptAt := model.GetNextAttemptAt()
+		response.NextAttemptAt = &nextAttemptAt
+	}
+
+	return response
+}
+
+func enum(values []string) []any {
+	enum := make([]any, len(values))
+	for i, value := range values {
+		enum[i] = value
+	}
+
+	return enum
+}
@@ -0,0 +1,193 @@
+package webhooks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	daoMocks "github.com/ashwin-m/transactions/daos/webhooks/mocks"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	webhooksmodel "github.com/ashwin-m/transactions/models/webhooks"
+	webhooksservice "github.com/ashwin-m/transactions/services/webhooks"
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	adminIdentity  = auth.Identity{ClientID: "admin", Roles: []string{auth.RoleAdmin}}
+	clientIdentity = auth.Identity{ClientID: "client-1", Roles: []string{auth.RoleRead, auth.RoleTransfer}}
+
+	createdAt = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+)
+
+func newRouter(t *testing.T, identity auth.Identity) (*gin.Engine, *daoMocks.Dao) {
+	router := gin.New()
+	router.Use(auth.WithIdentity(identity))
+
+	mockDao := daoMocks.NewDao(t)
+	NewHandler(webhooksservice.NewService(mockDao)).RouteGroup(router)
+
+	return router, mockDao
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func subscription(clientId string) webhooksmodel.Subscriptions {
+	s := webhooksmodel.Subscriptions{}
+	s.SetId(5)
+	s.SetClientId(clientId)
+	s.SetOwnerId(clientId)
+	s.SetUrl("https://partner.example/hooks")
+	s.SetSecret("whsec_test")
+	s.SetEventTypes([]string{"transfer.posted"})
+	s.SetCreatedAt(createdAt)
+	return s
+}
+
+func TestWebhooksCreate_ReturnsTheSecretOnce(t *testing.T) {
+	router, mockDao := newRouter(t, clientIdentity)
+	mockDao.EXPECT().Create(mock.Anything, mock.Anything).Return(subscription("client-1"), nil)
+	mockDao.EXPECT().GetById(mock.Anything, int64(5)).Return(subscription("client-1"), nil)
+
+	w := serve(router, "POST", "/webhooks", `{"url":"https://partner.example/hooks","event_types":["transfer.posted"]}`)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"id":5,"url":"https://partner.example/hooks","event_types":["transfer.posted"],"owner_id":"client-1","created_at":"2026-01-02T03:04:05Z","secret":"whsec_test"}`, w.Body.String())
+
+	w = serve(router, "GET", "/webhooks/5", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "secret")
+}
+
+func TestWebhooksCreate_InvalidUrl(t *testing.T) {
+	router, _ := newRouter(t, clientIdentity)
+
+	w := serve(router, "POST", "/webhooks", `{"url":"partner.example"}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `{"field":"url","message":"must be an absolute http or https URL"}`)
+}
+
+func TestWebhooksGet_AnotherClientsSubscription(t *testing.T) {
+	router, mockDao := newRouter(t, clientIdentity)
+	mockDao.EXPECT().GetById(mock.Anything, int64(5)).Return(subscription("client-2"), nil)
+
+	w := serve(router, "GET", "/webhooks/5", "")
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestWebhooksDelete_Missing(t *testing.T) {
+	router, mockDao := newRouter(t, adminIdentity)
+	mockDao.EXPECT().GetById(mock.Anything, int64(5)).Return(webhooksmodel.Subscriptions{}, pgx.ErrNoRows)
+
+	w := serve(router, "DELETE", "/webhooks/5", "")
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"WEBHOOK_NOT_FOUND"`)
+}
+
+func TestWebhooksListDeliveries(t *testing.T) {
+	router, mockDao := newRouter(t, clientIdentity)
+	mockDao.EXPECT().GetById(mock.Anything, int64(5)).Return(subscription("client-1"), nil)
+
+	dead := webhooksmodel.Deliveries{}
+	dead.SetId(9)
+	dead.SetEventId(7)
+	dead.SetEventType("transfer.posted")
+	dead.SetStatus(webhooksmodel.StatusDead)
+	dead.SetAttempts(8)
+	dead.SetLastStatusCode(http.StatusServiceUnavailable)
+	dead.SetLastError("endpoint responded 503 Service Unavailable")
+	dead.SetCreatedAt(createdAt)
+	mockDao.EXPECT().ListDeliveries(mock.Anything, int64(5), webhooksmodel.StatusDead, int64(20), 10).Return([]webhooksmodel.Deliveries{dead}, nil)
+
+	w := serve(router, "GET", "/webhooks/5/deliveries?status=dead&page_size=10&before_id=20", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"deliveries":[{"id":9,"event_id":7,"event_type":"transfer.posted","status":"dead","attempts":8,"last_status_code":503,"last_error":"endpoint responded 503 Service Unavailable","created_at":"2026-01-02T03:04:05Z"}]}`, w.Body.String())
+}
+
+func TestWebhooksGetDelivery_IncludesTheAttemptLog(t *testing.T) {
+	router, mockDao := newRouter(t, clientIdentity)
+	mockDao.EXPECT().GetById(mock.Anything, int64(5)).Return(subscription("client-1"), nil)
+
+	pending := webhooksmodel.Deliveries{}
+	pending.SetId(9)
+	pending.SetEventId(7)
+	pending.SetEventType("transfer.posted")
+	pending.SetPayload([]byte(`{"id":7}`))
+	pending.SetStatus(webhooksmodel.StatusPending)
+	pending.SetAttempts(1)
+	pending.SetNextAttemptAt(createdAt.Add(time.Minute))
+	pending.SetCreatedAt(createdAt)
+	mockDao.EXPECT().GetDelivery(mock.Anything, int64(5), int64(9)).Return(pending, nil)
+
+	failed := webhooksmodel.Attempts{}
+	failed.SetAttemptedAt(createdAt)
+	failed.SetError("connection refused")
+	failed.SetDuration(12 * time.Millisecond)
+	mockDao.EXPECT().ListAttempts(mock.Anything, int64(9)).Return([]webhooksmodel.Attempts{failed}, nil)
+
+	w := serve(router, "GET", "/webhooks/5/deliveries/9", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":9,"event_id":7,"event_type":"transfer.posted","status":"pending","attempts":1,"next_attempt_at":"2026-01-02T03:05:05Z","created_at":"2026-01-02T03:04:05Z",
+		"payload":{"id":7},"attempt_log":[{"attempted_at":"2026-01-02T03:04:05Z","error":"connection refused","duration_ms":12}]}`, w.Body.String())
+}
+
+func TestWebhooksRedeliver(t *testing.T) {
+	router, mockDao := newRouter(t, clientIdentity)
+	mockDao.EXPECT().GetById(mock.Anything, int64(5)).Return(subscription("client-1"), nil)
+
+	queued := webhooksmodel.Deliveries{}
+	queued.SetId(9)
+	queued.SetStatus(webhooksmodel.StatusPending)
+	queued.SetNextAttemptAt(createdAt)
+	mockDao.EXPECT().Redeliver(mock.Anything, int64(5), int64(9)).Return(queued, nil)
+
+	w := serve(router, "POST", "/webhooks/5/deliveries/9/redeliver", "")
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"pending"`)
+}
+
+func TestWebhooks_RequireReadRole(t *testing.T) {
+	router, _ := newRouter(t, auth.Identity{ClientID: "client-1"})
+
+	w := serve(router, "GET", "/webhooks", "")
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestWebhooks_RequireTransferRoleToManage(t *testing.T) {
+	router, _ := newRouter(t, auth.Identity{ClientID: "client-1", Roles: []string{auth.RoleRead}})
+
+	for _, route := range [][2]string{
+		{"POST", "/webhooks"},
+		{"DELETE", "/webhooks/5"},
+		{"POST", "/webhooks/5/deliveries/9/redeliver"},
+	} {
+		w := serve(router, route[0], route[1], `{"url":"https://partner.example/hooks","event_types":["transfer.posted"]}`)
+
+		assert.Equal(t, http.StatusForbidden, w.Code, route[0]+" "+route[1])
+	}
+}
+
+func TestWebhooks_DescribesRegisteredRoutes(t *testing.T) {
+	router, _ := newRouter(t, clientIdentity)
+	h := NewHandler(nil)
+
+	assert.Empty(t, openapi.Drift(openapi.New(h), router.Routes()))
+}
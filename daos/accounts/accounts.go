@@ -2,17 +2,31 @@ package accounts
 
 import (
 	"context"
+	"errors"
 
 	accounts_model "github.com/ashwin-m/transactions/models/accounts"
+	"github.com/ashwin-m/transactions/utils/metrics"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/ashwin-m/transactions/utils/tracing"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrVersionConflict is returned by UpdateBalance when the account was modified
+// since it was read.
+var ErrVersionConflict = errors.New("account was modified concurrently, please retry")
+
+var tracer = otel.Tracer("github.com/ashwin-m/transactions/daos/accounts")
+
 //go:generate mockery --name=Dao --output=mocks --outpkg=mocks --with-expecter
 type Dao interface {
-	GetById(id int64) (accounts_model.Accounts, error)
-	Create(id int64, balanace float64) (accounts_model.Accounts, error)
-	UpdateBalance(tx pgx.Tx, id, version int64, newBalance float64) (accounts_model.Accounts, error)
+	GetById(ctx context.Context, id int64) (accounts_model.Accounts, error)
+	Create(ctx context.Context, tx pgx.Tx, id int64, balanace float64, ownerId string) (accounts_model.Accounts, error)
+	UpdateBalance(ctx context.Context, tx pgx.Tx, id, version int64, newBalance float64) (accounts_model.Accounts, error)
 }
 
 type dao struct {
@@ -25,42 +39,80 @@ func NewDao(dbPool *pgxpool.Pool) Dao {
 	}
 }
 
-func (d *dao) GetById(id int64) (accounts_model.Accounts, error) {
+func (d *dao) GetById(ctx context.Context, id int64) (account accounts_model.Accounts, err error) {
+	ctx, span := tracer.Start(ctx, "accountsDao.GetById", trace.WithAttributes(attribute.Int64("account.id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return account, err
+	}
+
 	var accountId, version int64
-	var balance float64
-	var account accounts_model.Accounts
+	var balance, initialBalance float64
+	var ownerId pgtype.Text
 
-	sqlStatement := "select id, balance, version from Accounts where id=$1"
-	err := d.dbPool.QueryRow(context.Background(), sqlStatement, id).Scan(&accountId, &balance, &version)
+	sqlStatement := "select id, balance, initial_balance, version, owner_id from Accounts where tenant_id=$1 and id=$2"
+	err = d.dbPool.QueryRow(ctx, sqlStatement, tenantId, id).Scan(&accountId, &balance, &initialBalance, &version, &ownerId)
 	if err == nil {
 		account.SetId(id)
+		account.SetTenantId(tenantId)
 		account.SetBalance(balance)
+		account.SetInitialBalance(initialBalance)
 		account.SetVersion(version)
+		account.SetOwnerId(ownerId.String)
 	}
 
 	return account, err
 }
 
-func (d *dao) Create(id int64, balance float64) (accounts_model.Accounts, error) {
-	var account accounts_model.Accounts
-	sqlStatement := "insert into Accounts(id, balance, version) values ($1, $2, 1)"
-	_, err := d.dbPool.Exec(context.Background(), sqlStatement, id, balance)
+func (d *dao) Create(ctx context.Context, tx pgx.Tx, id int64, balance float64, ownerId string) (account accounts_model.Accounts, err error) {
+	ctx, span := tracer.Start(ctx, "accountsDao.Create", trace.WithAttributes(attribute.Int64("account.id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return account, err
+	}
+
+	sqlStatement := "insert into Accounts(tenant_id, id, balance, initial_balance, version, owner_id) values ($1, $2, $3, $3, 1, $4)"
+	_, err = tx.Exec(ctx, sqlStatement, tenantId, id, balance, ownerId)
 	if err == nil {
 		account.SetId(id)
+		account.SetTenantId(tenantId)
 		account.SetBalance(balance)
+		account.SetInitialBalance(balance)
+		account.SetVersion(1)
+		account.SetOwnerId(ownerId)
 	}
 
 	return account, err
 }
 
-func (d *dao) UpdateBalance(tx pgx.Tx, id, version int64, newBalance float64) (accounts_model.Accounts, error) {
-	var account accounts_model.Accounts
-	sqlStatement := "UPDATE accounts SET balance=$2, version=version+1 where id=$1 AND version=$3"
-	_, err := tx.Exec(context.Background(), sqlStatement, id, newBalance, version)
-	if err == nil {
-		account.SetId(id)
-		account.SetBalance(newBalance)
+func (d *dao) UpdateBalance(ctx context.Context, tx pgx.Tx, id, version int64, newBalance float64) (account accounts_model.Accounts, err error) {
+	ctx, span := tracer.Start(ctx, "accountsDao.UpdateBalance", trace.WithAttributes(attribute.Int64("account.id", id), attribute.Int64("account.version", version)))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return account, err
 	}
 
-	return account, err
+	sqlStatement := "UPDATE accounts SET balance=$3, version=version+1 where tenant_id=$1 AND id=$2 AND version=$4"
+	tag, err := tx.Exec(ctx, sqlStatement, tenantId, id, newBalance, version)
+	if err != nil {
+		return account, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		metrics.VersionConflict()
+		return account, ErrVersionConflict
+	}
+
+	account.SetId(id)
+	account.SetTenantId(tenantId)
+	account.SetBalance(newBalance)
+	account.SetVersion(version + 1)
+
+	return account, nil
 }
@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	accounts "github.com/ashwin-m/transactions/models/accounts"
 
 	mock "github.com/stretchr/testify/mock"
@@ -23,9 +25,9 @@ func (_m *Dao) EXPECT() *Dao_Expecter {
 	return &Dao_Expecter{mock: &_m.Mock}
 }
 
-// Create provides a mock function with given fields: id, balanace
-func (_m *Dao) Create(id int64, balanace float64) (accounts.Accounts, error) {
-	ret := _m.Called(id, balanace)
+// Create provides a mock function with given fields: ctx, tx, id, balanace, ownerId
+func (_m *Dao) Create(ctx context.Context, tx pgx.Tx, id int64, balanace float64, ownerId string) (accounts.Accounts, error) {
+	ret := _m.Called(ctx, tx, id, balanace, ownerId)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Create")
@@ -33,17 +35,17 @@ func (_m *Dao) Create(id int64, balanace float64) (accounts.Accounts, error) {
 
 	var r0 accounts.Accounts
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int64, float64) (accounts.Accounts, error)); ok {
-		return rf(id, balanace)
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, int64, float64, string) (accounts.Accounts, error)); ok {
+		return rf(ctx, tx, id, balanace, ownerId)
 	}
-	if rf, ok := ret.Get(0).(func(int64, float64) accounts.Accounts); ok {
-		r0 = rf(id, balanace)
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, int64, float64, string) accounts.Accounts); ok {
+		r0 = rf(ctx, tx, id, balanace, ownerId)
 	} else {
 		r0 = ret.Get(0).(accounts.Accounts)
 	}
 
-	if rf, ok := ret.Get(1).(func(int64, float64) error); ok {
-		r1 = rf(id, balanace)
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx, int64, float64, string) error); ok {
+		r1 = rf(ctx, tx, id, balanace, ownerId)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -57,15 +59,18 @@ type Dao_Create_Call struct {
 }
 
 // Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
 //   - id int64
 //   - balanace float64
-func (_e *Dao_Expecter) Create(id interface{}, balanace interface{}) *Dao_Create_Call {
-	return &Dao_Create_Call{Call: _e.mock.On("Create", id, balanace)}
+//   - ownerId string
+func (_e *Dao_Expecter) Create(ctx interface{}, tx interface{}, id interface{}, balanace interface{}, ownerId interface{}) *Dao_Create_Call {
+	return &Dao_Create_Call{Call: _e.mock.On("Create", ctx, tx, id, balanace, ownerId)}
 }
 
-func (_c *Dao_Create_Call) Run(run func(id int64, balanace float64)) *Dao_Create_Call {
+func (_c *Dao_Create_Call) Run(run func(ctx context.Context, tx pgx.Tx, id int64, balanace float64, ownerId string)) *Dao_Create_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(int64), args[1].(float64))
+		run(args[0].(context.Context), args[1].(pgx.Tx), args[2].(int64), args[3].(float64), args[4].(string))
 	})
 	return _c
 }
@@ -75,14 +80,14 @@ func (_c *Dao_Create_Call) Return(_a0 accounts.Accounts, _a1 error) *Dao_Create_
 	return _c
 }
 
-func (_c *Dao_Create_Call) RunAndReturn(run func(int64, float64) (accounts.Accounts, error)) *Dao_Create_Call {
+func (_c *Dao_Create_Call) RunAndReturn(run func(context.Context, pgx.Tx, int64, float64, string) (accounts.Accounts, error)) *Dao_Create_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// GetById provides a mock function with given fields: id
-func (_m *Dao) GetById(id int64) (accounts.Accounts, error) {
-	ret := _m.Called(id)
+// GetById provides a mock function with given fields: ctx, id
+func (_m *Dao) GetById(ctx context.Context, id int64) (accounts.Accounts, error) {
+	ret := _m.Called(ctx, id)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetById")
@@ -90,17 +95,17 @@ func (_m *Dao) GetById(id int64) (accounts.Accounts, error) {
 
 	var r0 accounts.Accounts
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int64) (accounts.Accounts, error)); ok {
-		return rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (accounts.Accounts, error)); ok {
+		return rf(ctx, id)
 	}
-	if rf, ok := ret.Get(0).(func(int64) accounts.Accounts); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) accounts.Accounts); ok {
+		r0 = rf(ctx, id)
 	} else {
 		r0 = ret.Get(0).(accounts.Accounts)
 	}
 
-	if rf, ok := ret.Get(1).(func(int64) error); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -114,14 +119,15 @@ type Dao_GetById_Call struct {
 }
 
 // GetById is a helper method to define mock.On call
+//   - ctx context.Context
 //   - id int64
-func (_e *Dao_Expecter) GetById(id interface{}) *Dao_GetById_Call {
-	return &Dao_GetById_Call{Call: _e.mock.On("GetById", id)}
+func (_e *Dao_Expecter) GetById(ctx interface{}, id interface{}) *Dao_GetById_Call {
+	return &Dao_GetById_Call{Call: _e.mock.On("GetById", ctx, id)}
 }
 
-func (_c *Dao_GetById_Call) Run(run func(id int64)) *Dao_GetById_Call {
+func (_c *Dao_GetById_Call) Run(run func(ctx context.Context, id int64)) *Dao_GetById_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(int64))
+		run(args[0].(context.Context), args[1].(int64))
 	})
 	return _c
 }
@@ -131,14 +137,14 @@ func (_c *Dao_GetById_Call) Return(_a0 accounts.Accounts, _a1 error) *Dao_GetByI
 	return _c
 }
 
-func (_c *Dao_GetById_Call) RunAndReturn(run func(int64) (accounts.Accounts, error)) *Dao_GetById_Call {
+func (_c *Dao_GetById_Call) RunAndReturn(run func(context.Context, int64) (accounts.Accounts, error)) *Dao_GetById_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// UpdateBalance provides a mock function with given fields: tx, id, version, newBalance
-func (_m *Dao) UpdateBalance(tx pgx.Tx, id int64, version int64, newBalance float64) (accounts.Accounts, error) {
-	ret := _m.Called(tx, id, version, newBalance)
+// UpdateBalance provides a mock function with given fields: ctx, tx, id, version, newBalance
+func (_m *Dao) UpdateBalance(ctx context.Context, tx pgx.Tx, id int64, version int64, newBalance float64) (accounts.Accounts, error) {
+	ret := _m.Called(ctx, tx, id, version, newBalance)
 
 	if len(ret) == 0 {
 		panic("no return value specified for UpdateBalance")
@@ -146,17 +152,17 @@ func (_m *Dao) UpdateBalance(tx pgx.Tx, id int64, version int64, newBalance floa
 
 	var r0 accounts.Accounts
 	var r1 error
-	if rf, ok := ret.Get(0).(func(pgx.Tx, int64, int64, float64) (accounts.Accounts, error)); ok {
-		return rf(tx, id, version, newBalance)
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, int64, int64, float64) (accounts.Accounts, error)); ok {
+		return rf(ctx, tx, id, version, newBalance)
 	}
-	if rf, ok := ret.Get(0).(func(pgx.Tx, int64, int64, float64) accounts.Accounts); ok {
-		r0 = rf(tx, id, version, newBalance)
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, int64, int64, float64) accounts.Accounts); ok {
+		r0 = rf(ctx, tx, id, version, newBalance)
 	} else {
 		r0 = ret.Get(0).(accounts.Accounts)
 	}
 
-	if rf, ok := ret.Get(1).(func(pgx.Tx, int64, int64, float64) error); ok {
-		r1 = rf(tx, id, version, newBalance)
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx, int64, int64, float64) error); ok {
+		r1 = rf(ctx, tx, id, version, newBalance)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -170,17 +176,18 @@ type Dao_UpdateBalance_Call struct {
 }
 
 // UpdateBalance is a helper method to define mock.On call
+//   - ctx context.Context
 //   - tx pgx.Tx
 //   - id int64
 //   - version int64
 //   - newBalance float64
-func (_e *Dao_Expecter) UpdateBalance(tx interface{}, id interface{}, version interface{}, newBalance interface{}) *Dao_UpdateBalance_Call {
-	return &Dao_UpdateBalance_Call{Call: _e.mock.On("UpdateBalance", tx, id, version, newBalance)}
+func (_e *Dao_Expecter) UpdateBalance(ctx interface{}, tx interface{}, id interface{}, version interface{}, newBalance interface{}) *Dao_UpdateBalance_Call {
+	return &Dao_UpdateBalance_Call{Call: _e.mock.On("UpdateBalance", ctx, tx, id, version, newBalance)}
 }
 
-func (_c *Dao_UpdateBalance_Call) Run(run func(tx pgx.Tx, id int64, version int64, newBalance float64)) *Dao_UpdateBalance_Call {
+func (_c *Dao_UpdateBalance_Call) Run(run func(ctx context.Context, tx pgx.Tx, id int64, version int64, newBalance float64)) *Dao_UpdateBalance_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(pgx.Tx), args[1].(int64), args[2].(int64), args[3].(float64))
+		run(args[0].(context.Context), args[1].(pgx.Tx), args[2].(int64), args[3].(int64), args[4].(float64))
 	})
 	return _c
 }
@@ -190,7 +197,7 @@ func (_c *Dao_UpdateBalance_Call) Return(_a0 accounts.Accounts, _a1 error) *Dao_
 	return _c
 }
 
-func (_c *Dao_UpdateBalance_Call) RunAndReturn(run func(pgx.Tx, int64, int64, float64) (accounts.Accounts, error)) *Dao_UpdateBalance_Call {
+func (_c *Dao_UpdateBalance_Call) RunAndReturn(run func(context.Context, pgx.Tx, int64, int64, float64) (accounts.Accounts, error)) *Dao_UpdateBalance_Call {
 	_c.Call.Return(run)
 	return _c
 }
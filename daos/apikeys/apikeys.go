@@ -0,0 +1,155 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	apikeys_model "github.com/ashwin-m/transactions/models/apikeys"
+	"github.com/ashwin-m/transactions/utils/tracing"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrNotFound is returned when the key does not exist or is already revoked.
+var ErrNotFound = errors.New("api key not found")
+
+var tracer = otel.Tracer("github.com/ashwin-m/transactions/daos/apikeys")
+
+//go:generate mockery --name=Dao --output=mocks --outpkg=mocks --with-expecter
+type Dao interface {
+	GetById(ctx context.Context, id string) (apikeys_model.ApiKeys, error)
+	// Create stores a key by the hash of the key and its signing secret,
+	// sealed.
+	Create(ctx context.Context, id, clientId, tenantId, name string, roles []string, keyHash, signingSecret []byte) (apikeys_model.ApiKeys, error)
+	Rotate(ctx context.Context, oldId, newId string, newKeyHash, newSigningSecret []byte) (apikeys_model.ApiKeys, error)
+	Revoke(ctx context.Context, id string) error
+}
+
+type dao struct {
+	dbPool *pgxpool.Pool
+}
+
+func NewDao(dbPool *pgxpool.Pool) Dao {
+	return &dao{
+		dbPool: dbPool,
+	}
+}
+
+func (d *dao) GetById(ctx context.Context, id string) (apiKey apikeys_model.ApiKeys, err error) {
+	ctx, span := tracer.Start(ctx, "apiKeysDao.GetById", trace.WithAttributes(attribute.String("api_key.id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	var clientId, tenantId, name string
+	var roles []string
+	var keyHash, signingSecret []byte
+	var createdAt time.Time
+	var revokedAt *time.Time
+
+	sqlStatement := "select client_id, tenant_id, name, roles, key_hash, signing_secret, created_at, revoked_at from api_keys where id=$1"
+	err = d.dbPool.QueryRow(ctx, sqlStatement, id).Scan(&clientId, &tenantId, &name, &roles, &keyHash, &signingSecret, &createdAt, &revokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apiKey, ErrNotFound
+	}
+	if err == nil {
+		apiKey.SetId(id)
+		apiKey.SetClientId(clientId)
+		apiKey.SetTenantId(tenantId)
+		apiKey.SetName(name)
+		apiKey.SetRoles(roles)
+		apiKey.SetKeyHash(keyHash)
+		apiKey.SetSigningSecret(signingSecret)
+		apiKey.SetCreatedAt(createdAt)
+		apiKey.SetRevokedAt(revokedAt)
+	}
+
+	return apiKey, err
+}
+
+func (d *dao) Create(ctx context.Context, id, clientId, tenantId, name string, roles []string, keyHash, signingSecret []byte) (apiKey apikeys_model.ApiKeys, err error) {
+	ctx, span := tracer.Start(ctx, "apiKeysDao.Create", trace.WithAttributes(attribute.String("api_key.id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	var createdAt time.Time
+	sqlStatement := "insert into api_keys(id, client_id, tenant_id, name, roles, key_hash, signing_secret) values ($1, $2, $3, $4, $5, $6, $7) returning created_at"
+	err = d.dbPool.QueryRow(ctx, sqlStatement, id, clientId, tenantId, name, roles, keyHash, signingSecret).Scan(&createdAt)
+	if err == nil {
+		apiKey.SetId(id)
+		apiKey.SetClientId(clientId)
+		apiKey.SetTenantId(tenantId)
+		apiKey.SetName(name)
+		apiKey.SetRoles(roles)
+		apiKey.SetKeyHash(keyHash)
+		apiKey.SetSigningSecret(signingSecret)
+		apiKey.SetCreatedAt(createdAt)
+	}
+
+	return apiKey, err
+}
+
+// Rotate revokes oldId and issues newId to the same client, with the same
+// tenant and roles, in one transaction.
+func (d *dao) Rotate(ctx context.Context, oldId, newId string, newKeyHash, newSigningSecret []byte) (apiKey apikeys_model.ApiKeys, err error) {
+	ctx, span := tracer.Start(ctx, "apiKeysDao.Rotate", trace.WithAttributes(attribute.String("api_key.id", oldId)))
+	defer func() { tracing.End(span, err) }()
+
+	txn, err := d.dbPool.Begin(ctx)
+	if err != nil {
+		return apiKey, err
+	}
+	defer txn.Rollback(ctx)
+
+	var clientId, tenantId, name string
+	var roles []string
+	sqlStatement := "update api_keys set revoked_at=now() where id=$1 and revoked_at is null returning client_id, tenant_id, name, roles"
+	err = txn.QueryRow(ctx, sqlStatement, oldId).Scan(&clientId, &tenantId, &name, &roles)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apiKey, ErrNotFound
+	}
+	if err != nil {
+		return apiKey, err
+	}
+
+	var createdAt time.Time
+	sqlStatement = "insert into api_keys(id, client_id, tenant_id, name, roles, key_hash, signing_secret) values ($1, $2, $3, $4, $5, $6, $7) returning created_at"
+	err = txn.QueryRow(ctx, sqlStatement, newId, clientId, tenantId, name, roles, newKeyHash, newSigningSecret).Scan(&createdAt)
+	if err != nil {
+		return apiKey, err
+	}
+
+	err = txn.Commit(ctx)
+	if err != nil {
+		return apiKey, err
+	}
+
+	apiKey.SetId(newId)
+	apiKey.SetClientId(clientId)
+	apiKey.SetTenantId(tenantId)
+	apiKey.SetName(name)
+	apiKey.SetRoles(roles)
+	apiKey.SetKeyHash(newKeyHash)
+	apiKey.SetSigningSecret(newSigningSecret)
+	apiKey.SetCreatedAt(createdAt)
+
+	return apiKey, nil
+}
+
+func (d *dao) Revoke(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "apiKeysDao.Revoke", trace.WithAttributes(attribute.String("api_key.id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	sqlStatement := "update api_keys set revoked_at=now() where id=$1 and revoked_at is null"
+	tag, err := d.dbPool.Exec(ctx, sqlStatement, id)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
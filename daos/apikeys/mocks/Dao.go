@@ -0,0 +1,265 @@
+// Code generated by mockery v2.43.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	apikeys "github.com/ashwin-m/transactions/models/apikeys"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Dao is an autogenerated mock type for the Dao type
+type Dao struct {
+	mock.Mock
+}
+
+type Dao_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Dao) EXPECT() *Dao_Expecter {
+	return &Dao_Expecter{mock: &_m.Mock}
+}
+
+// Create provides a mock function with given fields: ctx, id, clientId, tenantId, name, roles, keyHash, signingSecret
+func (_m *Dao) Create(ctx context.Context, id string, clientId string, tenantId string, name string, roles []string, keyHash []byte, signingSecret []byte) (apikeys.ApiKeys, error) {
+	ret := _m.Called(ctx, id, clientId, tenantId, name, roles, keyHash, signingSecret)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 apikeys.ApiKeys
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string, []string, []byte, []byte) (apikeys.ApiKeys, error)); ok {
+		return rf(ctx, id, clientId, tenantId, name, roles, keyHash, signingSecret)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string, []string, []byte, []byte) apikeys.ApiKeys); ok {
+		r0 = rf(ctx, id, clientId, tenantId, name, roles, keyHash, signingSecret)
+	} else {
+		r0 = ret.Get(0).(apikeys.ApiKeys)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, string, []string, []byte, []byte) error); ok {
+		r1 = rf(ctx, id, clientId, tenantId, name, roles, keyHash, signingSecret)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type Dao_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - clientId string
+//   - tenantId string
+//   - name string
+//   - roles []string
+//   - keyHash []byte
+//   - signingSecret []byte
+func (_e *Dao_Expecter) Create(ctx interface{}, id interface{}, clientId interface{}, tenantId interface{}, name interface{}, roles interface{}, keyHash interface{}, signingSecret interface{}) *Dao_Create_Call {
+	return &Dao_Create_Call{Call: _e.mock.On("Create", ctx, id, clientId, tenantId, name, roles, keyHash, signingSecret)}
+}
+
+func (_c *Dao_Create_Call) Run(run func(ctx context.Context, id string, clientId string, tenantId string, name string, roles []string, keyHash []byte, signingSecret []byte)) *Dao_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string), args[4].(string), args[5].([]string), args[6].([]byte), args[7].([]byte))
+	})
+	return _c
+}
+
+func (_c *Dao_Create_Call) Return(_a0 apikeys.ApiKeys, _a1 error) *Dao_Create_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Create_Call) RunAndReturn(run func(context.Context, string, string, string, string, []string, []byte, []byte) (apikeys.ApiKeys, error)) *Dao_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetById provides a mock function with given fields: ctx, id
+func (_m *Dao) GetById(ctx context.Context, id string) (apikeys.ApiKeys, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetById")
+	}
+
+	var r0 apikeys.ApiKeys
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (apikeys.ApiKeys, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) apikeys.ApiKeys); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(apikeys.ApiKeys)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_GetById_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetById'
+type Dao_GetById_Call struct {
+	*mock.Call
+}
+
+// GetById is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *Dao_Expecter) GetById(ctx interface{}, id interface{}) *Dao_GetById_Call {
+	return &Dao_GetById_Call{Call: _e.mock.On("GetById", ctx, id)}
+}
+
+func (_c *Dao_GetById_Call) Run(run func(ctx context.Context, id string)) *Dao_GetById_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Dao_GetById_Call) Return(_a0 apikeys.ApiKeys, _a1 error) *Dao_GetById_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_GetById_Call) RunAndReturn(run func(context.Context, string) (apikeys.ApiKeys, error)) *Dao_GetById_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Revoke provides a mock function with given fields: ctx, id
+func (_m *Dao) Revoke(ctx context.Context, id string) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Revoke")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Dao_Revoke_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Revoke'
+type Dao_Revoke_Call struct {
+	*mock.Call
+}
+
+// Revoke is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *Dao_Expecter) Revoke(ctx interface{}, id interface{}) *Dao_Revoke_Call {
+	return &Dao_Revoke_Call{Call: _e.mock.On("Revoke", ctx, id)}
+}
+
+func (_c *Dao_Revoke_Call) Run(run func(ctx context.Context, id string)) *Dao_Revoke_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Dao_Revoke_Call) Return(_a0 error) *Dao_Revoke_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Dao_Revoke_Call) RunAndReturn(run func(context.Context, string) error) *Dao_Revoke_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Rotate provides a mock function with given fields: ctx, oldId, newId, newKeyHash, newSigningSecret
+func (_m *Dao) Rotate(ctx context.Context, oldId string, newId string, newKeyHash []byte, newSigningSecret []byte) (apikeys.ApiKeys, error) {
+	ret := _m.Called(ctx, oldId, newId, newKeyHash, newSigningSecret)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Rotate")
+	}
+
+	var r0 apikeys.ApiKeys
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []byte, []byte) (apikeys.ApiKeys, error)); ok {
+		return rf(ctx, oldId, newId, newKeyHash, newSigningSecret)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []byte, []byte) apikeys.ApiKeys); ok {
+		r0 = rf(ctx, oldId, newId, newKeyHash, newSigningSecret)
+	} else {
+		r0 = ret.Get(0).(apikeys.ApiKeys)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, []byte, []byte) error); ok {
+		r1 = rf(ctx, oldId, newId, newKeyHash, newSigningSecret)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Rotate_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Rotate'
+type Dao_Rotate_Call struct {
+	*mock.Call
+}
+
+// Rotate is a helper method to define mock.On call
+//   - ctx context.Context
+//   - oldId string
+//   - newId string
+//   - newKeyHash []byte
+//   - newSigningSecret []byte
+func (_e *Dao_Expecter) Rotate(ctx interface{}, oldId interface{}, newId interface{}, newKeyHash interface{}, newSigningSecret interface{}) *Dao_Rotate_Call {
+	return &Dao_Rotate_Call{Call: _e.mock.On("Rotate", ctx, oldId, newId, newKeyHash, newSigningSecret)}
+}
+
+func (_c *Dao_Rotate_Call) Run(run func(ctx context.Context, oldId string, newId string, newKeyHash []byte, newSigningSecret []byte)) *Dao_Rotate_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].([]byte), args[4].([]byte))
+	})
+	return _c
+}
+
+func (_c *Dao_Rotate_Call) Return(_a0 apikeys.ApiKeys, _a1 error) *Dao_Rotate_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Rotate_Call) RunAndReturn(run func(context.Context, string, string, []byte, []byte) (apikeys.ApiKeys, error)) *Dao_Rotate_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewDao creates a new instance of Dao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Dao {
+	mock := &Dao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,261 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	auditmodel "github.com/ashwin-m/transactions/models/audit"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/ashwin-m/transactions/utils/tracing"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// chainLockKey, with the tenant, is the advisory lock held while appending
+// to the chain of a tenant, so entries are chained one at a time.
+const chainLockKey = 7_340_002
+
+var tracer = otel.Tracer("github.com/ashwin-m/transactions/daos/audit")
+
+// Filter narrows a listing of the audit log. Zero fields don't filter.
+type Filter struct {
+	Actor     string
+	AccountId int64
+	RequestId string
+	// From and To bound when the entries occurred, To excluded.
+	From time.Time
+	To   time.Time
+	// BeforeId continues a listing after the last entry of a page.
+	BeforeId int64
+	Limit    int
+}
+
+// Cutover is where the chain of a tenant started being keyed: entries from
+// KeyedFrom on are hashed with the key. MAC, keyed with it too, stops the
+// cutover being moved.
+type Cutover struct {
+	KeyedFrom int64
+	MAC       string
+}
+
+//go:generate mockery --name=Dao --output=mocks --outpkg=mocks --with-expecter
+type Dao interface {
+	// Lock takes the chain lock of the tenant on ctx until tx ends.
+	Lock(ctx context.Context, tx pgx.Tx) error
+	// LastHash returns the hash of the newest entry of the tenant on ctx,
+	// empty when it has none.
+	LastHash(ctx context.Context, tx pgx.Tx) (string, error)
+	// Append stores an entry of the tenant on ctx and returns it with its
+	// id.
+	Append(ctx context.Context, tx pgx.Tx, entry auditmodel.Entries) (auditmodel.Entries, error)
+	// List returns the entries of the tenant on ctx matching filter, newest
+	// first.
+	List(ctx context.Context, filter Filter) ([]auditmodel.Entries, error)
+	// Chain returns up to limit entries of the tenant on ctx after the entry
+	// afterId, oldest first.
+	Chain(ctx context.Context, afterId int64, limit int) ([]auditmodel.Entries, error)
+	// Cutover returns the cutover of the tenant on ctx, false when none was
+	// recorded.
+	Cutover(ctx context.Context) (Cutover, bool, error)
+	// SetCutover records the cutover of the tenant on ctx, which can't be
+	// changed once recorded.
+	SetCutover(ctx context.Context, tx pgx.Tx, cutover Cutover) error
+}
+
+type dao struct {
+	dbPool *pgxpool.Pool
+}
+
+func NewDao(dbPool *pgxpool.Pool) Dao {
+	return &dao{
+		dbPool: dbPool,
+	}
+}
+
+const selectEntries = "select id, tenant_id, occurred_at, actor, auth_method, request_id, method, endpoint, status, error_code, payload, snapshots, prev_hash, hash from audit_log"
+
+func (d *dao) Lock(ctx context.Context, tx pgx.Tx) (err error) {
+	ctx, span := tracer.Start(ctx, "auditDao.Lock")
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "select pg_advisory_xact_lock($1, hashtext($2))", chainLockKey, tenantId)
+
+	return err
+}
+
+func (d *dao) LastHash(ctx context.Context, tx pgx.Tx) (hash string, err error) {
+	ctx, span := tracer.Start(ctx, "auditDao.LastHash")
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.QueryRow(ctx, "select hash from audit_log where tenant_id=$1 order by id desc limit 1", tenantId).Scan(&hash)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+
+	return hash, err
+}
+
+func (d *dao) Append(ctx context.Context, tx pgx.Tx, entry auditmodel.Entries) (appended auditmodel.Entries, err error) {
+	ctx, span := tracer.Start(ctx, "auditDao.Append", trace.WithAttributes(attribute.String("audit.endpoint", entry.GetEndpoint())))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return appended, err
+	}
+
+	snapshots, err := json.Marshal(entry.GetSnapshots())
+	if err != nil {
+		return appended, err
+	}
+
+	sqlStatement := `insert into audit_log(tenant_id, occurred_at, actor, auth_method, request_id, method, endpoint, status, error_code, payload, snapshots, account_ids, prev_hash, hash)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) returning id`
+	var id int64
+	err = tx.QueryRow(ctx, sqlStatement, tenantId, entry.GetOccurredAt(), entry.GetActor(), entry.GetAuthMethod(), entry.GetRequestId(),
+		entry.GetMethod(), entry.GetEndpoint(), entry.GetStatus(), entry.GetErrorCode(), string(entry.GetPayload()), string(snapshots),
+		entry.GetAccountIds(), entry.GetPrevHash(), entry.GetHash()).Scan(&id)
+	if err != nil {
+		return appended, err
+	}
+
+	appended = entry
+	appended.SetId(id)
+	appended.SetTenantId(tenantId)
+
+	return appended, nil
+}
+
+func (d *dao) List(ctx context.Context, filter Filter) (entries []auditmodel.Entries, err error) {
+	ctx, span := tracer.Start(ctx, "auditDao.List")
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStatement := selectEntries + ` where tenant_id=$1
+		and ($2 = '' or actor=$2)
+		and ($3::bigint = 0 or $3 = any(account_ids))
+		and ($4 = '' or request_id=$4)
+		and ($5::timestamptz is null or occurred_at >= $5)
+		and ($6::timestamptz is null or occurred_at < $6)
+		and ($7::bigint = 0 or id < $7)
+		order by id desc limit $8`
+	rows, err := d.dbPool.Query(ctx, sqlStatement, tenantId, filter.Actor, filter.AccountId, filter.RequestId,
+		optionalTime(filter.From), optionalTime(filter.To), filter.BeforeId, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanEntry)
+}
+
+func (d *dao) Chain(ctx context.Context, afterId int64, limit int) (entries []auditmodel.Entries, err error) {
+	ctx, span := tracer.Start(ctx, "auditDao.Chain")
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.dbPool.Query(ctx, selectEntries+" where tenant_id=$1 and id > $2 order by id limit $3", tenantId, afterId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanEntry)
+}
+
+func (d *dao) Cutover(ctx context.Context) (cutover Cutover, found bool, err error) {
+	ctx, span := tracer.Start(ctx, "auditDao.Cutover")
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return cutover, false, err
+	}
+
+	err = d.dbPool.QueryRow(ctx, "select keyed_from, mac from audit_key_cutovers where tenant_id=$1", tenantId).Scan(&cutover.KeyedFrom, &cutover.MAC)
+	if err == pgx.ErrNoRows {
+		return cutover, false, nil
+	}
+
+	return cutover, err == nil, err
+}
+
+func (d *dao) SetCutover(ctx context.Context, tx pgx.Tx, cutover Cutover) (err error) {
+	ctx, span := tracer.Start(ctx, "auditDao.SetCutover", trace.WithAttributes(attribute.Int64("audit.keyed_from", cutover.KeyedFrom)))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "insert into audit_key_cutovers(tenant_id, keyed_from, mac) values ($1, $2, $3)", tenantId, cutover.KeyedFrom, cutover.MAC)
+
+	return err
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+func scanEntry(row pgx.CollectableRow) (entry auditmodel.Entries, err error) {
+	var id int64
+	var status int
+	var tenantId, actor, authMethod, requestId, method, endpoint, errorCode, prevHash, hash string
+	var payload, snapshots string
+	var occurredAt time.Time
+
+	err = row.Scan(&id, &tenantId, &occurredAt, &actor, &authMethod, &requestId, &method, &endpoint, &status, &errorCode, &payload, &snapshots, &prevHash, &hash)
+	if err != nil {
+		return entry, err
+	}
+
+	// the snapshots are stored as they were marshalled when hashed, and
+	// marshal back to the same text
+	var decoded []auditmodel.Snapshot
+	err = json.Unmarshal([]byte(snapshots), &decoded)
+	if err != nil {
+		return entry, err
+	}
+
+	entry.SetId(id)
+	entry.SetTenantId(tenantId)
+	entry.SetOccurredAt(occurredAt.UTC())
+	entry.SetActor(actor)
+	entry.SetAuthMethod(authMethod)
+	entry.SetRequestId(requestId)
+	entry.SetMethod(method)
+	entry.SetEndpoint(endpoint)
+	entry.SetStatus(status)
+	entry.SetErrorCode(errorCode)
+	entry.SetPayload(json.RawMessage(payload))
+	entry.SetSnapshots(decoded)
+	entry.SetPrevHash(prevHash)
+	entry.SetHash(hash)
+
+	return entry, nil
+}
@@ -0,0 +1,434 @@
+// Code generated by mockery v2.43.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	audit "github.com/ashwin-m/transactions/models/audit"
+
+	daosaudit "github.com/ashwin-m/transactions/daos/audit"
+
+	mock "github.com/stretchr/testify/mock"
+
+	pgx "github.com/jackc/pgx/v5"
+)
+
+// Dao is an autogenerated mock type for the Dao type
+type Dao struct {
+	mock.Mock
+}
+
+type Dao_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Dao) EXPECT() *Dao_Expecter {
+	return &Dao_Expecter{mock: &_m.Mock}
+}
+
+// Append provides a mock function with given fields: ctx, tx, entry
+func (_m *Dao) Append(ctx context.Context, tx pgx.Tx, entry audit.Entries) (audit.Entries, error) {
+	ret := _m.Called(ctx, tx, entry)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Append")
+	}
+
+	var r0 audit.Entries
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, audit.Entries) (audit.Entries, error)); ok {
+		return rf(ctx, tx, entry)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, audit.Entries) audit.Entries); ok {
+		r0 = rf(ctx, tx, entry)
+	} else {
+		r0 = ret.Get(0).(audit.Entries)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx, audit.Entries) error); ok {
+		r1 = rf(ctx, tx, entry)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Append_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Append'
+type Dao_Append_Call struct {
+	*mock.Call
+}
+
+// Append is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+//   - entry audit.Entries
+func (_e *Dao_Expecter) Append(ctx interface{}, tx interface{}, entry interface{}) *Dao_Append_Call {
+	return &Dao_Append_Call{Call: _e.mock.On("Append", ctx, tx, entry)}
+}
+
+func (_c *Dao_Append_Call) Run(run func(ctx context.Context, tx pgx.Tx, entry audit.Entries)) *Dao_Append_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx), args[2].(audit.Entries))
+	})
+	return _c
+}
+
+func (_c *Dao_Append_Call) Return(_a0 audit.Entries, _a1 error) *Dao_Append_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Append_Call) RunAndReturn(run func(context.Context, pgx.Tx, audit.Entries) (audit.Entries, error)) *Dao_Append_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Chain provides a mock function with given fields: ctx, afterId, limit
+func (_m *Dao) Chain(ctx context.Context, afterId int64, limit int) ([]audit.Entries, error) {
+	ret := _m.Called(ctx, afterId, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Chain")
+	}
+
+	var r0 []audit.Entries
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) ([]audit.Entries, error)); ok {
+		return rf(ctx, afterId, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) []audit.Entries); ok {
+		r0 = rf(ctx, afterId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]audit.Entries)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int) error); ok {
+		r1 = rf(ctx, afterId, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Chain_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Chain'
+type Dao_Chain_Call struct {
+	*mock.Call
+}
+
+// Chain is a helper method to define mock.On call
+//   - ctx context.Context
+//   - afterId int64
+//   - limit int
+func (_e *Dao_Expecter) Chain(ctx interface{}, afterId interface{}, limit interface{}) *Dao_Chain_Call {
+	return &Dao_Chain_Call{Call: _e.mock.On("Chain", ctx, afterId, limit)}
+}
+
+func (_c *Dao_Chain_Call) Run(run func(ctx context.Context, afterId int64, limit int)) *Dao_Chain_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int64), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *Dao_Chain_Call) Return(_a0 []audit.Entries, _a1 error) *Dao_Chain_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Chain_Call) RunAndReturn(run func(context.Context, int64, int) ([]audit.Entries, error)) *Dao_Chain_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Cutover provides a mock function with given fields: ctx
+func (_m *Dao) Cutover(ctx context.Context) (daosaudit.Cutover, bool, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Cutover")
+	}
+
+	var r0 daosaudit.Cutover
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context) (daosaudit.Cutover, bool, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) daosaudit.Cutover); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(daosaudit.Cutover)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) bool); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context) error); ok {
+		r2 = rf(ctx)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Dao_Cutover_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Cutover'
+type Dao_Cutover_Call struct {
+	*mock.Call
+}
+
+// Cutover is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Dao_Expecter) Cutover(ctx interface{}) *Dao_Cutover_Call {
+	return &Dao_Cutover_Call{Call: _e.mock.On("Cutover", ctx)}
+}
+
+func (_c *Dao_Cutover_Call) Run(run func(ctx context.Context)) *Dao_Cutover_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Dao_Cutover_Call) Return(_a0 daosaudit.Cutover, _a1 bool, _a2 error) *Dao_Cutover_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *Dao_Cutover_Call) RunAndReturn(run func(context.Context) (daosaudit.Cutover, bool, error)) *Dao_Cutover_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// LastHash provides a mock function with given fields: ctx, tx
+func (_m *Dao) LastHash(ctx context.Context, tx pgx.Tx) (string, error) {
+	ret := _m.Called(ctx, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LastHash")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) (string, error)); ok {
+		return rf(ctx, tx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) string); ok {
+		r0 = rf(ctx, tx)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx) error); ok {
+		r1 = rf(ctx, tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_LastHash_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LastHash'
+type Dao_LastHash_Call struct {
+	*mock.Call
+}
+
+// LastHash is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+func (_e *Dao_Expecter) LastHash(ctx interface{}, tx interface{}) *Dao_LastHash_Call {
+	return &Dao_LastHash_Call{Call: _e.mock.On("LastHash", ctx, tx)}
+}
+
+func (_c *Dao_LastHash_Call) Run(run func(ctx context.Context, tx pgx.Tx)) *Dao_LastHash_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx))
+	})
+	return _c
+}
+
+func (_c *Dao_LastHash_Call) Return(_a0 string, _a1 error) *Dao_LastHash_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_LastHash_Call) RunAndReturn(run func(context.Context, pgx.Tx) (string, error)) *Dao_LastHash_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// List provides a mock function with given fields: ctx, filter
+func (_m *Dao) List(ctx context.Context, filter daosaudit.Filter) ([]audit.Entries, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 []audit.Entries
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, daosaudit.Filter) ([]audit.Entries, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, daosaudit.Filter) []audit.Entries); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]audit.Entries)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, daosaudit.Filter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_List_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'List'
+type Dao_List_Call struct {
+	*mock.Call
+}
+
+// List is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter daosaudit.Filter
+func (_e *Dao_Expecter) List(ctx interface{}, filter interface{}) *Dao_List_Call {
+	return &Dao_List_Call{Call: _e.mock.On("List", ctx, filter)}
+}
+
+func (_c *Dao_List_Call) Run(run func(ctx context.Context, filter daosaudit.Filter)) *Dao_List_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(daosaudit.Filter))
+	})
+	return _c
+}
+
+func (_c *Dao_List_Call) Return(_a0 []audit.Entries, _a1 error) *Dao_List_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_List_Call) RunAndReturn(run func(context.Context, daosaudit.Filter) ([]audit.Entries, error)) *Dao_List_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Lock provides a mock function with given fields: ctx, tx
+func (_m *Dao) Lock(ctx context.Context, tx pgx.Tx) error {
+	ret := _m.Called(ctx, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Lock")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) error); ok {
+		r0 = rf(ctx, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Dao_Lock_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Lock'
+type Dao_Lock_Call struct {
+	*mock.Call
+}
+
+// Lock is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+func (_e *Dao_Expecter) Lock(ctx interface{}, tx interface{}) *Dao_Lock_Call {
+	return &Dao_Lock_Call{Call: _e.mock.On("Lock", ctx, tx)}
+}
+
+func (_c *Dao_Lock_Call) Run(run func(ctx context.Context, tx pgx.Tx)) *Dao_Lock_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx))
+	})
+	return _c
+}
+
+func (_c *Dao_Lock_Call) Return(_a0 error) *Dao_Lock_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Dao_Lock_Call) RunAndReturn(run func(context.Context, pgx.Tx) error) *Dao_Lock_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetCutover provides a mock function with given fields: ctx, tx, cutover
+func (_m *Dao) SetCutover(ctx context.Context, tx pgx.Tx, cutover daosaudit.Cutover) error {
+	ret := _m.Called(ctx, tx, cutover)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetCutover")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, daosaudit.Cutover) error); ok {
+		r0 = rf(ctx, tx, cutover)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Dao_SetCutover_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetCutover'
+type Dao_SetCutover_Call struct {
+	*mock.Call
+}
+
+// SetCutover is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+//   - cutover daosaudit.Cutover
+func (_e *Dao_Expecter) SetCutover(ctx interface{}, tx interface{}, cutover interface{}) *Dao_SetCutover_Call {
+	return &Dao_SetCutover_Call{Call: _e.mock.On("SetCutover", ctx, tx, cutover)}
+}
+
+func (_c *Dao_SetCutover_Call) Run(run func(ctx context.Context, tx pgx.Tx, cutover daosaudit.Cutover)) *Dao_SetCutover_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx), args[2].(daosaudit.Cutover))
+	})
+	return _c
+}
+
+func (_c *Dao_SetCutover_Call) Return(_a0 error) *Dao_SetCutover_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Dao_SetCutover_Call) RunAndReturn(run func(context.Context, pgx.Tx, daosaudit.Cutover) error) *Dao_SetCutover_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewDao creates a new instance of Dao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Dao {
+	mock := &Dao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
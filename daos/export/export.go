@@ -0,0 +1,216 @@
+package export
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/ashwin-m/transactions/utils/tracing"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// lockKey is the advisory lock held while exporting, so two exports never
+// write runs from the same watermarks.
+const lockKey = 7_340_003
+
+var tracer = otel.Tracer("github.com/ashwin-m/transactions/daos/export")
+
+// Account is an account as exported, OwnerId is empty for accounts without
+// an owner.
+type Account struct {
+	TenantId       string
+	Id             int64
+	OwnerId        string
+	Balance        float64
+	InitialBalance float64
+	Version        int64
+	UpdatedAt      time.Time
+}
+
+// Transaction is a transfer as exported, ClientId is empty for transfers
+// made before clients were recorded.
+type Transaction struct {
+	TenantId             string
+	Id                   int64
+	SourceAccountId      int64
+	DestinationTenantId  string
+	DestinationAccountId int64
+	Amount               float64
+	ClientId             string
+	CreatedAt            time.Time
+}
+
+//go:generate mockery --name=Dao --output=mocks --outpkg=mocks --with-expecter
+type Dao interface {
+	// Snapshot makes tx a transaction in which every query sees the same
+	// state of the ledger. It must come first in tx.
+	Snapshot(ctx context.Context, tx pgx.Tx) error
+	// Lock takes the export lock until tx ends. It returns false when
+	// another export holds it.
+	Lock(ctx context.Context, tx pgx.Tx) (bool, error)
+	// Now returns the time tx started at, by the database clock.
+	Now(ctx context.Context, tx pgx.Tx) (time.Time, error)
+	// Horizon returns the oldest transaction still running when the
+	// snapshot of tx was taken. Every transaction before it has ended, so
+	// the rows they wrote are all visible to tx.
+	Horizon(ctx context.Context, tx pgx.Tx) (uint64, error)
+	// Watermarks returns, per tenant, the transaction the last export
+	// stopped before.
+	Watermarks(ctx context.Context, tx pgx.Tx) (map[string]uint64, error)
+	// SetWatermark records that run exported the tenant up to xid,
+	// excluded. It is kept once tx commits.
+	SetWatermark(ctx context.Context, tx pgx.Tx, tenantId string, xid uint64, runId string) error
+	// Accounts calls fn with every account of the tenant on ctx last
+	// written by a transaction from from until to, excluded, in the order
+	// of those transactions.
+	Accounts(ctx context.Context, tx pgx.Tx, from, to uint64, fn func(Account) error) error
+	// Transactions calls fn with every transfer out of an account of the
+	// tenant on ctx made by a transaction from from until to, excluded, in
+	// the order of those transactions.
+	Transactions(ctx context.Context, tx pgx.Tx, from, to uint64, fn func(Transaction) error) error
+}
+
+type dao struct {
+	dbPool *pgxpool.Pool
+}
+
+func NewDao(dbPool *pgxpool.Pool) Dao {
+	return &dao{
+		dbPool: dbPool,
+	}
+}
+
+func (d *dao) Snapshot(ctx context.Context, tx pgx.Tx) (err error) {
+	ctx, span := tracer.Start(ctx, "exportDao.Snapshot")
+	defer func() { tracing.End(span, err) }()
+
+	// not read only, the watermarks are written in the same transaction
+	_, err = tx.Exec(ctx, "set transaction isolation level repeatable read")
+
+	return err
+}
+
+func (d *dao) Lock(ctx context.Context, tx pgx.Tx) (locked bool, err error) {
+	ctx, span := tracer.Start(ctx, "exportDao.Lock")
+	defer func() { tracing.End(span, err) }()
+
+	err = tx.QueryRow(ctx, "select pg_try_advisory_xact_lock($1)", lockKey).Scan(&locked)
+
+	return locked, err
+}
+
+func (d *dao) Now(ctx context.Context, tx pgx.Tx) (now time.Time, err error) {
+	ctx, span := tracer.Start(ctx, "exportDao.Now")
+	defer func() { tracing.End(span, err) }()
+
+	err = tx.QueryRow(ctx, "select now()").Scan(&now)
+
+	return now, err
+}
+
+func (d *dao) Horizon(ctx context.Context, tx pgx.Tx) (xid uint64, err error) {
+	ctx, span := tracer.Start(ctx, "exportDao.Horizon")
+	defer func() { tracing.End(span, err) }()
+
+	var horizon string
+	err = tx.QueryRow(ctx, "select pg_snapshot_xmin(pg_current_snapshot())::text").Scan(&horizon)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(horizon, 10, 64)
+}
+
+func (d *dao) Watermarks(ctx context.Context, tx pgx.Tx) (watermarks map[string]uint64, err error) {
+	ctx, span := tracer.Start(ctx, "exportDao.Watermarks")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := tx.Query(ctx, "select tenant_id, xid::text from export_watermarks")
+	if err != nil {
+		return nil, err
+	}
+
+	watermarks = map[string]uint64{}
+	var tenantId, xid string
+	_, err = pgx.ForEachRow(rows, []any{&tenantId, &xid}, func() error {
+		watermark, err := strconv.ParseUint(xid, 10, 64)
+		if err != nil {
+			return err
+		}
+		watermarks[tenantId] = watermark
+		return nil
+	})
+
+	return watermarks, err
+}
+
+func (d *dao) SetWatermark(ctx context.Context, tx pgx.Tx, tenantId string, xid uint64, runId string) (err error) {
+	ctx, span := tracer.Start(ctx, "exportDao.SetWatermark")
+	defer func() { tracing.End(span, err) }()
+	span.SetAttributes(attribute.String("tenant.id", tenantId))
+
+	sqlStatement := `insert into export_watermarks(tenant_id, xid, run_id) values ($1, $2::text::xid8, $3)
+		on conflict (tenant_id) do update set xid=excluded.xid, run_id=excluded.run_id, updated_at=now()`
+	_, err = tx.Exec(ctx, sqlStatement, tenantId, strconv.FormatUint(xid, 10), runId)
+
+	return err
+}
+
+func (d *dao) Accounts(ctx context.Context, tx pgx.Tx, from, to uint64, fn func(Account) error) (err error) {
+	ctx, span := tracer.Start(ctx, "exportDao.Accounts")
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(attribute.String("tenant.id", tenantId))
+
+	sqlStatement := `select id, owner_id, balance, initial_balance, version, updated_at from accounts
+		where tenant_id=$1 and xid >= $2::text::xid8 and xid < $3::text::xid8 order by xid, id`
+	rows, err := tx.Query(ctx, sqlStatement, tenantId, strconv.FormatUint(from, 10), strconv.FormatUint(to, 10))
+	if err != nil {
+		return err
+	}
+
+	account := Account{TenantId: tenantId}
+	var ownerId pgtype.Text
+	_, err = pgx.ForEachRow(rows, []any{&account.Id, &ownerId, &account.Balance, &account.InitialBalance, &account.Version, &account.UpdatedAt}, func() error {
+		account.OwnerId = ownerId.String
+		return fn(account)
+	})
+
+	return err
+}
+
+func (d *dao) Transactions(ctx context.Context, tx pgx.Tx, from, to uint64, fn func(Transaction) error) (err error) {
+	ctx, span := tracer.Start(ctx, "exportDao.Transactions")
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(attribute.String("tenant.id", tenantId))
+
+	sqlStatement := `select id, source_account_id, destination_tenant_id, destination_account_id, amount, client_id, created_at from transactions
+		where tenant_id=$1 and xid >= $2::text::xid8 and xid < $3::text::xid8 order by xid, id`
+	rows, err := tx.Query(ctx, sqlStatement, tenantId, strconv.FormatUint(from, 10), strconv.FormatUint(to, 10))
+	if err != nil {
+		return err
+	}
+
+	transaction := Transaction{TenantId: tenantId}
+	var clientId pgtype.Text
+	_, err = pgx.ForEachRow(rows, []any{&transaction.Id, &transaction.SourceAccountId, &transaction.DestinationTenantId, &transaction.DestinationAccountId, &transaction.Amount, &clientId, &transaction.CreatedAt}, func() error {
+		transaction.ClientId = clientId.String
+		return fn(transaction)
+	})
+
+	return err
+}
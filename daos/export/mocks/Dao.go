@@ -0,0 +1,468 @@
+// Code generated by mockery v2.43.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	export "github.com/ashwin-m/transactions/daos/export"
+	mock "github.com/stretchr/testify/mock"
+
+	pgx "github.com/jackc/pgx/v5"
+
+	time "time"
+)
+
+// Dao is an autogenerated mock type for the Dao type
+type Dao struct {
+	mock.Mock
+}
+
+type Dao_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Dao) EXPECT() *Dao_Expecter {
+	return &Dao_Expecter{mock: &_m.Mock}
+}
+
+// Accounts provides a mock function with given fields: ctx, tx, from, to, fn
+func (_m *Dao) Accounts(ctx context.Context, tx pgx.Tx, from uint64, to uint64, fn func(export.Account) error) error {
+	ret := _m.Called(ctx, tx, from, to, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Accounts")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, uint64, uint64, func(export.Account) error) error); ok {
+		r0 = rf(ctx, tx, from, to, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Dao_Accounts_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Accounts'
+type Dao_Accounts_Call struct {
+	*mock.Call
+}
+
+// Accounts is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+//   - from uint64
+//   - to uint64
+//   - fn func(export.Account) error
+func (_e *Dao_Expecter) Accounts(ctx interface{}, tx interface{}, from interface{}, to interface{}, fn interface{}) *Dao_Accounts_Call {
+	return &Dao_Accounts_Call{Call: _e.mock.On("Accounts", ctx, tx, from, to, fn)}
+}
+
+func (_c *Dao_Accounts_Call) Run(run func(ctx context.Context, tx pgx.Tx, from uint64, to uint64, fn func(export.Account) error)) *Dao_Accounts_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx), args[2].(uint64), args[3].(uint64), args[4].(func(export.Account) error))
+	})
+	return _c
+}
+
+func (_c *Dao_Accounts_Call) Return(_a0 error) *Dao_Accounts_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Dao_Accounts_Call) RunAndReturn(run func(context.Context, pgx.Tx, uint64, uint64, func(export.Account) error) error) *Dao_Accounts_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Horizon provides a mock function with given fields: ctx, tx
+func (_m *Dao) Horizon(ctx context.Context, tx pgx.Tx) (uint64, error) {
+	ret := _m.Called(ctx, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Horizon")
+	}
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) (uint64, error)); ok {
+		return rf(ctx, tx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) uint64); ok {
+		r0 = rf(ctx, tx)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx) error); ok {
+		r1 = rf(ctx, tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Horizon_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Horizon'
+type Dao_Horizon_Call struct {
+	*mock.Call
+}
+
+// Horizon is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+func (_e *Dao_Expecter) Horizon(ctx interface{}, tx interface{}) *Dao_Horizon_Call {
+	return &Dao_Horizon_Call{Call: _e.mock.On("Horizon", ctx, tx)}
+}
+
+func (_c *Dao_Horizon_Call) Run(run func(ctx context.Context, tx pgx.Tx)) *Dao_Horizon_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx))
+	})
+	return _c
+}
+
+func (_c *Dao_Horizon_Call) Return(_a0 uint64, _a1 error) *Dao_Horizon_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Horizon_Call) RunAndReturn(run func(context.Context, pgx.Tx) (uint64, error)) *Dao_Horizon_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Lock provides a mock function with given fields: ctx, tx
+func (_m *Dao) Lock(ctx context.Context, tx pgx.Tx) (bool, error) {
+	ret := _m.Called(ctx, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Lock")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) (bool, error)); ok {
+		return rf(ctx, tx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) bool); ok {
+		r0 = rf(ctx, tx)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx) error); ok {
+		r1 = rf(ctx, tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Lock_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Lock'
+type Dao_Lock_Call struct {
+	*mock.Call
+}
+
+// Lock is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+func (_e *Dao_Expecter) Lock(ctx interface{}, tx interface{}) *Dao_Lock_Call {
+	return &Dao_Lock_Call{Call: _e.mock.On("Lock", ctx, tx)}
+}
+
+func (_c *Dao_Lock_Call) Run(run func(ctx context.Context, tx pgx.Tx)) *Dao_Lock_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx))
+	})
+	return _c
+}
+
+func (_c *Dao_Lock_Call) Return(_a0 bool, _a1 error) *Dao_Lock_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Lock_Call) RunAndReturn(run func(context.Context, pgx.Tx) (bool, error)) *Dao_Lock_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Now provides a mock function with given fields: ctx, tx
+func (_m *Dao) Now(ctx context.Context, tx pgx.Tx) (time.Time, error) {
+	ret := _m.Called(ctx, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Now")
+	}
+
+	var r0 time.Time
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) (time.Time, error)); ok {
+		return rf(ctx, tx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) time.Time); ok {
+		r0 = rf(ctx, tx)
+	} else {
+		r0 = ret.Get(0).(time.Time)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx) error); ok {
+		r1 = rf(ctx, tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Now_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Now'
+type Dao_Now_Call struct {
+	*mock.Call
+}
+
+// Now is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+func (_e *Dao_Expecter) Now(ctx interface{}, tx interface{}) *Dao_Now_Call {
+	return &Dao_Now_Call{Call: _e.mock.On("Now", ctx, tx)}
+}
+
+func (_c *Dao_Now_Call) Run(run func(ctx context.Context, tx pgx.Tx)) *Dao_Now_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx))
+	})
+	return _c
+}
+
+func (_c *Dao_Now_Call) Return(_a0 time.Time, _a1 error) *Dao_Now_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Now_Call) RunAndReturn(run func(context.Context, pgx.Tx) (time.Time, error)) *Dao_Now_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetWatermark provides a mock function with given fields: ctx, tx, tenantId, xid, runId
+func (_m *Dao) SetWatermark(ctx context.Context, tx pgx.Tx, tenantId string, xid uint64, runId string) error {
+	ret := _m.Called(ctx, tx, tenantId, xid, runId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetWatermark")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, string, uint64, string) error); ok {
+		r0 = rf(ctx, tx, tenantId, xid, runId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Dao_SetWatermark_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetWatermark'
+type Dao_SetWatermark_Call struct {
+	*mock.Call
+}
+
+// SetWatermark is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+//   - tenantId string
+//   - xid uint64
+//   - runId string
+func (_e *Dao_Expecter) SetWatermark(ctx interface{}, tx interface{}, tenantId interface{}, xid interface{}, runId interface{}) *Dao_SetWatermark_Call {
+	return &Dao_SetWatermark_Call{Call: _e.mock.On("SetWatermark", ctx, tx, tenantId, xid, runId)}
+}
+
+func (_c *Dao_SetWatermark_Call) Run(run func(ctx context.Context, tx pgx.Tx, tenantId string, xid uint64, runId string)) *Dao_SetWatermark_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx), args[2].(string), args[3].(uint64), args[4].(string))
+	})
+	return _c
+}
+
+func (_c *Dao_SetWatermark_Call) Return(_a0 error) *Dao_SetWatermark_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Dao_SetWatermark_Call) RunAndReturn(run func(context.Context, pgx.Tx, string, uint64, string) error) *Dao_SetWatermark_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Snapshot provides a mock function with given fields: ctx, tx
+func (_m *Dao) Snapshot(ctx context.Context, tx pgx.Tx) error {
+	ret := _m.Called(ctx, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Snapshot")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) error); ok {
+		r0 = rf(ctx, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Dao_Snapshot_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Snapshot'
+type Dao_Snapshot_Call struct {
+	*mock.Call
+}
+
+// Snapshot is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+func (_e *Dao_Expecter) Snapshot(ctx interface{}, tx interface{}) *Dao_Snapshot_Call {
+	return &Dao_Snapshot_Call{Call: _e.mock.On("Snapshot", ctx, tx)}
+}
+
+func (_c *Dao_Snapshot_Call) Run(run func(ctx context.Context, tx pgx.Tx)) *Dao_Snapshot_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx))
+	})
+	return _c
+}
+
+func (_c *Dao_Snapshot_Call) Return(_a0 error) *Dao_Snapshot_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Dao_Snapshot_Call) RunAndReturn(run func(context.Context, pgx.Tx) error) *Dao_Snapshot_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Transactions provides a mock function with given fields: ctx, tx, from, to, fn
+func (_m *Dao) Transactions(ctx context.Context, tx pgx.Tx, from uint64, to uint64, fn func(export.Transaction) error) error {
+	ret := _m.Called(ctx, tx, from, to, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Transactions")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, uint64, uint64, func(export.Transaction) error) error); ok {
+		r0 = rf(ctx, tx, from, to, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Dao_Transactions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Transactions'
+type Dao_Transactions_Call struct {
+	*mock.Call
+}
+
+// Transactions is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+//   - from uint64
+//   - to uint64
+//   - fn func(export.Transaction) error
+func (_e *Dao_Expecter) Transactions(ctx interface{}, tx interface{}, from interface{}, to interface{}, fn interface{}) *Dao_Transactions_Call {
+	return &Dao_Transactions_Call{Call: _e.mock.On("Transactions", ctx, tx, from, to, fn)}
+}
+
+func (_c *Dao_Transactions_Call) Run(run func(ctx context.Context, tx pgx.Tx, from uint64, to uint64, fn func(export.Transaction) error)) *Dao_Transactions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx), args[2].(uint64), args[3].(uint64), args[4].(func(export.Transaction) error))
+	})
+	return _c
+}
+
+func (_c *Dao_Transactions_Call) Return(_a0 error) *Dao_Transactions_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Dao_Transactions_Call) RunAndReturn(run func(context.Context, pgx.Tx, uint64, uint64, func(export.Transaction) error) error) *Dao_Transactions_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Watermarks provides a mock function with given fields: ctx, tx
+func (_m *Dao) Watermarks(ctx context.Context, tx pgx.Tx) (map[string]uint64, error) {
+	ret := _m.Called(ctx, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Watermarks")
+	}
+
+	var r0 map[string]uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) (map[string]uint64, error)); ok {
+		return rf(ctx, tx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) map[string]uint64); ok {
+		r0 = rf(ctx, tx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]uint64)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx) error); ok {
+		r1 = rf(ctx, tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Watermarks_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Watermarks'
+type Dao_Watermarks_Call struct {
+	*mock.Call
+}
+
+// Watermarks is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+func (_e *Dao_Expecter) Watermarks(ctx interface{}, tx interface{}) *Dao_Watermarks_Call {
+	return &Dao_Watermarks_Call{Call: _e.mock.On("Watermarks", ctx, tx)}
+}
+
+func (_c *Dao_Watermarks_Call) Run(run func(ctx context.Context, tx pgx.Tx)) *Dao_Watermarks_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx))
+	})
+	return _c
+}
+
+func (_c *Dao_Watermarks_Call) Return(_a0 map[string]uint64, _a1 error) *Dao_Watermarks_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Watermarks_Call) RunAndReturn(run func(context.Context, pgx.Tx) (map[string]uint64, error)) *Dao_Watermarks_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewDao creates a new instance of Dao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Dao {
+	mock := &Dao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,113 @@
+package imports
+
+import (
+	"context"
+
+	eventsmodel "github.com/ashwin-m/transactions/models/events"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/ashwin-m/transactions/utils/tracing"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/ashwin-m/transactions/daos/imports")
+
+// Account is an account to import, OwnerId is empty for accounts without an
+// owner.
+type Account struct {
+	Id      int64
+	Balance float64
+	OwnerId string
+}
+
+// Result tells apart the accounts of a batch that were created from those
+// that already existed with a different initial balance or owner. The rest
+// already existed as imported.
+type Result struct {
+	Created     []int64
+	Conflicting []int64
+}
+
+//go:generate mockery --name=Dao --output=mocks --outpkg=mocks --with-expecter
+type Dao interface {
+	// Insert creates the accounts of the tenant on ctx that don't exist yet,
+	// each with its AccountCreated event, and leaves the others as they are.
+	// Ids must be unique within accounts.
+	Insert(ctx context.Context, tx pgx.Tx, accounts []Account) (Result, error)
+}
+
+type dao struct {
+	dbPool *pgxpool.Pool
+}
+
+func NewDao(dbPool *pgxpool.Pool) Dao {
+	return &dao{
+		dbPool: dbPool,
+	}
+}
+
+func (d *dao) Insert(ctx context.Context, tx pgx.Tx, accounts []Account) (result Result, err error) {
+	ctx, span := tracer.Start(ctx, "importsDao.Insert", trace.WithAttributes(attribute.Int("import.rows", len(accounts))))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	// COPY is much faster than inserts but fails on the first existing
+	// account, so the batch is staged and merged from there.
+	_, err = tx.Exec(ctx, "create temporary table import_staging (id bigint, balance float8, owner_id text) on commit drop")
+	if err != nil {
+		return result, err
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"import_staging"}, []string{"id", "balance", "owner_id"},
+		pgx.CopyFromSlice(len(accounts), func(i int) ([]any, error) {
+			return []any{accounts[i].Id, accounts[i].Balance, accounts[i].OwnerId}, nil
+		}))
+	if err != nil {
+		return result, err
+	}
+
+	// Every part of the statement sees the accounts as they were before it,
+	// so conflicting never matches an account created by it.
+	sqlStatement := `with created as (
+			insert into accounts(tenant_id, id, balance, initial_balance, version, owner_id)
+			select $1, id, balance, balance, 1, owner_id from import_staging
+			on conflict (tenant_id, id) do nothing
+			returning id, balance, owner_id
+		), events as (
+			insert into outbox(tenant_id, event_type, account_id, payload)
+			select $1, $2, id, jsonb_build_object('account_id', id, 'balance', balance) ||
+				case when owner_id <> '' then jsonb_build_object('owner_id', owner_id) else '{}' end
+			from created order by id
+		), conflicting as (
+			select s.id from import_staging s join accounts a on a.tenant_id = $1 and a.id = s.id
+			where a.initial_balance <> s.balance or coalesce(a.owner_id, '') <> s.owner_id
+		)
+		select id, true from created
+		union all
+		select id, false from conflicting`
+	rows, err := tx.Query(ctx, sqlStatement, tenantId, eventsmodel.TypeAccountCreated)
+	if err != nil {
+		return result, err
+	}
+
+	var id int64
+	var created bool
+	_, err = pgx.ForEachRow(rows, []any{&id, &created}, func() error {
+		if created {
+			result.Created = append(result.Created, id)
+		} else {
+			result.Conflicting = append(result.Conflicting, id)
+		}
+		return nil
+	})
+	span.SetAttributes(attribute.Int("import.created", len(result.Created)))
+
+	return result, err
+}
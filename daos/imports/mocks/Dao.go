@@ -0,0 +1,97 @@
+// Code generated by mockery v2.43.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	imports "github.com/ashwin-m/transactions/daos/imports"
+	mock "github.com/stretchr/testify/mock"
+
+	pgx "github.com/jackc/pgx/v5"
+)
+
+// Dao is an autogenerated mock type for the Dao type
+type Dao struct {
+	mock.Mock
+}
+
+type Dao_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Dao) EXPECT() *Dao_Expecter {
+	return &Dao_Expecter{mock: &_m.Mock}
+}
+
+// Insert provides a mock function with given fields: ctx, tx, accounts
+func (_m *Dao) Insert(ctx context.Context, tx pgx.Tx, accounts []imports.Account) (imports.Result, error) {
+	ret := _m.Called(ctx, tx, accounts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Insert")
+	}
+
+	var r0 imports.Result
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, []imports.Account) (imports.Result, error)); ok {
+		return rf(ctx, tx, accounts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, []imports.Account) imports.Result); ok {
+		r0 = rf(ctx, tx, accounts)
+	} else {
+		r0 = ret.Get(0).(imports.Result)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx, []imports.Account) error); ok {
+		r1 = rf(ctx, tx, accounts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Insert_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Insert'
+type Dao_Insert_Call struct {
+	*mock.Call
+}
+
+// Insert is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+//   - accounts []imports.Account
+func (_e *Dao_Expecter) Insert(ctx interface{}, tx interface{}, accounts interface{}) *Dao_Insert_Call {
+	return &Dao_Insert_Call{Call: _e.mock.On("Insert", ctx, tx, accounts)}
+}
+
+func (_c *Dao_Insert_Call) Run(run func(ctx context.Context, tx pgx.Tx, accounts []imports.Account)) *Dao_Insert_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx), args[2].([]imports.Account))
+	})
+	return _c
+}
+
+func (_c *Dao_Insert_Call) Return(_a0 imports.Result, _a1 error) *Dao_Insert_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Insert_Call) RunAndReturn(run func(context.Context, pgx.Tx, []imports.Account) (imports.Result, error)) *Dao_Insert_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewDao creates a new instance of Dao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Dao {
+	mock := &Dao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
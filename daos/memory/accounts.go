@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	accountsdao "github.com/ashwin-m/transactions/daos/accounts"
+	accounts_model "github.com/ashwin-m/transactions/models/accounts"
+	"github.com/ashwin-m/transactions/utils/metrics"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type accountsDao struct {
+	store *Store
+}
+
+func NewAccountsDao(store *Store) accountsdao.Dao {
+	return &accountsDao{
+		store: store,
+	}
+}
+
+// GetById reads the committed account, like the Postgres dao reading
+// outside of any transaction.
+func (d *accountsDao) GetById(ctx context.Context, id int64) (account accounts_model.Accounts, err error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return account, err
+	}
+
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	a, ok := d.store.accounts[accountKey{tenantId, id}]
+	if !ok {
+		return account, pgx.ErrNoRows
+	}
+
+	return toAccount(tenantId, id, a), nil
+}
+
+func (d *accountsDao) Create(ctx context.Context, tx pgx.Tx, id int64, balance float64, ownerId string) (account accounts_model.Accounts, err error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return account, err
+	}
+
+	t, err := d.store.txFrom(tx)
+	if err != nil {
+		return account, err
+	}
+
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	// an account being created by another transaction exists once it
+	// commits
+	key := accountKey{tenantId, id}
+	err = t.lock(ctx, key)
+	if err != nil {
+		return account, err
+	}
+	if _, exists := t.account(key); exists {
+		return account, t.fail(&pgconn.PgError{
+			Severity:       "ERROR",
+			Code:           pgerrcode.UniqueViolation,
+			Message:        `duplicate key value violates unique constraint "accounts_pkey"`,
+			Detail:         fmt.Sprintf("Key (tenant_id, id)=(%s, %d) already exists.", tenantId, id),
+			TableName:      "accounts",
+			ConstraintName: "accounts_pkey",
+		})
+	}
+
+	a := accountRow{balance: balance, initialBalance: balance, version: 1, ownerId: ownerId}
+	t.accounts[key] = a
+
+	return toAccount(tenantId, id, a), nil
+}
+
+// UpdateBalance returns the account with the columns it changed, like the
+// Postgres dao.
+func (d *accountsDao) UpdateBalance(ctx context.Context, tx pgx.Tx, id, version int64, newBalance float64) (account accounts_model.Accounts, err error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return account, err
+	}
+
+	t, err := d.store.txFrom(tx)
+	if err != nil {
+		return account, err
+	}
+
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	key := accountKey{tenantId, id}
+	err = t.lock(ctx, key)
+	if err != nil {
+		return account, err
+	}
+
+	a, exists := t.account(key)
+	if !exists || a.version != version {
+		metrics.VersionConflict()
+		return account, accountsdao.ErrVersionConflict
+	}
+
+	a.balance = newBalance
+	a.version++
+	t.accounts[key] = a
+
+	account.SetId(id)
+	account.SetTenantId(tenantId)
+	account.SetBalance(newBalance)
+	account.SetVersion(a.version)
+
+	return account, nil
+}
+
+func toAccount(tenantId string, id int64, a accountRow) (account accounts_model.Accounts) {
+	account.SetId(id)
+	account.SetTenantId(tenantId)
+	account.SetBalance(a.balance)
+	account.SetInitialBalance(a.initialBalance)
+	account.SetVersion(a.version)
+	account.SetOwnerId(a.ownerId)
+
+	return account
+}
@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	apikeysdao "github.com/ashwin-m/transactions/daos/apikeys"
+	apikeys_model "github.com/ashwin-m/transactions/models/apikeys"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type apiKeysDao struct {
+	store *Store
+}
+
+func NewApiKeysDao(store *Store) apikeysdao.Dao {
+	return &apiKeysDao{
+		store: store,
+	}
+}
+
+// GetById returns revoked keys too, like the Postgres dao, it is up to the
+// caller to refuse them.
+func (d *apiKeysDao) GetById(ctx context.Context, id string) (apikeys_model.ApiKeys, error) {
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	apiKey, ok := d.store.apiKeys[id]
+	if !ok {
+		return apikeys_model.ApiKeys{}, apikeysdao.ErrNotFound
+	}
+
+	return copyApiKey(apiKey), nil
+}
+
+func (d *apiKeysDao) Create(ctx context.Context, id, clientId, tenantId, name string, roles []string, keyHash, signingSecret []byte) (apikeys_model.ApiKeys, error) {
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	if _, ok := d.store.apiKeys[id]; ok {
+		return apikeys_model.ApiKeys{}, duplicateApiKey(id)
+	}
+
+	var apiKey apikeys_model.ApiKeys
+	apiKey.SetId(id)
+	apiKey.SetClientId(clientId)
+	apiKey.SetTenantId(tenantId)
+	apiKey.SetName(name)
+	apiKey.SetRoles(roles)
+	apiKey.SetKeyHash(keyHash)
+	apiKey.SetSigningSecret(signingSecret)
+	apiKey.SetCreatedAt(time.Now())
+	d.store.apiKeys[id] = copyApiKey(apiKey)
+
+	return apiKey, nil
+}
+
+// Rotate revokes oldId and issues newId to the same client, with the same
+// tenant and roles, at once.
+func (d *apiKeysDao) Rotate(ctx context.Context, oldId, newId string, newKeyHash, newSigningSecret []byte) (apikeys_model.ApiKeys, error) {
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	old, ok := d.store.apiKeys[oldId]
+	if !ok || old.IsRevoked() {
+		return apikeys_model.ApiKeys{}, apikeysdao.ErrNotFound
+	}
+	if _, ok := d.store.apiKeys[newId]; ok {
+		return apikeys_model.ApiKeys{}, duplicateApiKey(newId)
+	}
+
+	now := time.Now()
+	old.SetRevokedAt(&now)
+	d.store.apiKeys[oldId] = old
+
+	var apiKey apikeys_model.ApiKeys
+	apiKey.SetId(newId)
+	apiKey.SetClientId(old.GetClientId())
+	apiKey.SetTenantId(old.GetTenantId())
+	apiKey.SetName(old.GetName())
+	apiKey.SetRoles(old.GetRoles())
+	apiKey.SetKeyHash(newKeyHash)
+	apiKey.SetSigningSecret(newSigningSecret)
+	apiKey.SetCreatedAt(now)
+	d.store.apiKeys[newId] = copyApiKey(apiKey)
+
+	return copyApiKey(apiKey), nil
+}
+
+func (d *apiKeysDao) Revoke(ctx context.Context, id string) error {
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	apiKey, ok := d.store.apiKeys[id]
+	if !ok || apiKey.IsRevoked() {
+		return apikeysdao.ErrNotFound
+	}
+
+	now := time.Now()
+	apiKey.SetRevokedAt(&now)
+	d.store.apiKeys[id] = apiKey
+
+	return nil
+}
+
+// copyApiKey returns apiKey with slices of its own, so callers can't change
+// the stored key.
+func copyApiKey(apiKey apikeys_model.ApiKeys) apikeys_model.ApiKeys {
+	apiKey.SetRoles(slices.Clone(apiKey.GetRoles()))
+	apiKey.SetKeyHash(slices.Clone(apiKey.GetKeyHash()))
+	apiKey.SetSigningSecret(slices.Clone(apiKey.GetSigningSecret()))
+	if revokedAt := apiKey.GetRevokedAt(); revokedAt != nil {
+		at := *revokedAt
+		apiKey.SetRevokedAt(&at)
+	}
+
+	return apiKey
+}
+
+func duplicateApiKey(id string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           pgerrcode.UniqueViolation,
+		Message:        `duplicate key value violates unique constraint "api_keys_pkey"`,
+		Detail:         fmt.Sprintf("Key (id)=(%s) already exists.", id),
+		TableName:      "api_keys",
+		ConstraintName: "api_keys_pkey",
+	}
+}
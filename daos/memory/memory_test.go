@@ -0,0 +1,461 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	accountsdao "github.com/ashwin-m/transactions/daos/accounts"
+	apikeysdao "github.com/ashwin-m/transactions/daos/apikeys"
+	eventsmodel "github.com/ashwin-m/transactions/models/events"
+	transactionsmodel "github.com/ashwin-m/transactions/models/transactions"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/ashwin-m/transactions/utils/tracing"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ctx = tenant.NewContext(context.Background(), tenant.Default)
+
+// seed commits accounts with the given balances, by id.
+func seed(t *testing.T, store *Store, balances map[int64]float64) {
+	tx, err := store.Begin(ctx)
+	require.NoError(t, err)
+	for id, balance := range balances {
+		_, err = NewAccountsDao(store).Create(ctx, tx, id, balance, "client-1")
+		require.NoError(t, err)
+	}
+	require.NoError(t, tx.Commit(ctx))
+}
+
+func pgCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+func TestTx_CommitAndRollback(t *testing.T) {
+	store := NewStore()
+	accounts := NewAccountsDao(store)
+	transactions := NewTransactionsDao(store)
+	outbox := NewOutboxDao(store)
+
+	tx, err := store.Begin(ctx)
+	require.NoError(t, err)
+	_, err = accounts.Create(ctx, tx, 1, 100, "client-1")
+	assert.NoError(t, err)
+	id, err := transactions.Create(ctx, tx, 1, 2, tenant.Default, 10, "client-1")
+	assert.NoError(t, err)
+	assert.NoError(t, outbox.Add(ctx, tx, "account.created", 1, map[string]int{"balance": 100}))
+
+	// nothing is seen outside tx before it commits
+	_, err = accounts.GetById(ctx, 1)
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+	_, err = transactions.GetById(ctx, id)
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+
+	require.NoError(t, tx.Commit(ctx))
+	assert.ErrorIs(t, tx.Rollback(ctx), pgx.ErrTxClosed)
+
+	account, err := accounts.GetById(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 100.0, account.GetBalance())
+	assert.Equal(t, int64(1), account.GetVersion())
+	assert.Equal(t, "client-1", account.GetOwnerId())
+	transaction, err := transactions.GetById(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, transaction.GetAmount())
+	events, err := outbox.ListByAccount(ctx, 1, eventsmodel.Cursor{}, 10)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	tx, err = store.Begin(ctx)
+	require.NoError(t, err)
+	_, err = accounts.UpdateBalance(ctx, tx, 1, 1, 50)
+	assert.NoError(t, err)
+	_, err = accounts.Create(ctx, tx, 2, 5, "client-1")
+	assert.NoError(t, err)
+	require.NoError(t, tx.Rollback(ctx))
+
+	account, err = accounts.GetById(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 100.0, account.GetBalance())
+	_, err = accounts.GetById(ctx, 2)
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestAccounts_TenantsAreApart(t *testing.T) {
+	store := NewStore()
+	seed(t, store, map[int64]float64{1: 100})
+
+	_, err := NewAccountsDao(store).GetById(tenant.NewContext(context.Background(), "acme"), 1)
+
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestAccounts_CreateExisting(t *testing.T) {
+	store := NewStore()
+	seed(t, store, map[int64]float64{1: 100})
+
+	tx, err := store.Begin(ctx)
+	require.NoError(t, err)
+	_, err = NewAccountsDao(store).Create(ctx, tx, 1, 5, "client-1")
+
+	assert.Equal(t, pgerrcode.UniqueViolation, pgCode(err))
+	// the failed statement aborted the transaction
+	_, err = NewAccountsDao(store).Create(ctx, tx, 2, 5, "client-1")
+	assert.Equal(t, pgerrcode.InFailedSQLTransaction, pgCode(err))
+	assert.ErrorIs(t, tx.Commit(ctx), pgx.ErrTxCommitRollback)
+}
+
+func TestAccounts_UpdateBalanceChecksVersion(t *testing.T) {
+	store := NewStore()
+	seed(t, store, map[int64]float64{1: 100})
+	accounts := NewAccountsDao(store)
+
+	tx, err := store.Begin(ctx)
+	require.NoError(t, err)
+	account, err := accounts.UpdateBalance(ctx, tx, 1, 1, 90)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), account.GetVersion())
+	// tx sees its own update
+	account, err = accounts.UpdateBalance(ctx, tx, 1, 2, 80)
+	assert.NoError(t, err)
+	require.NoError(t, tx.Commit(ctx))
+
+	tx, err = store.Begin(ctx)
+	require.NoError(t, err)
+	_, err = accounts.UpdateBalance(ctx, tx, 1, 1, 70)
+	assert.ErrorIs(t, err, accountsdao.ErrVersionConflict)
+	_, err = accounts.UpdateBalance(ctx, tx, 2, 1, 70)
+	assert.ErrorIs(t, err, accountsdao.ErrVersionConflict)
+	require.NoError(t, tx.Rollback(ctx))
+
+	account, err = accounts.GetById(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 80.0, account.GetBalance())
+	assert.Equal(t, int64(3), account.GetVersion())
+}
+
+func TestAccounts_ConcurrentUpdateWaitsThenConflicts(t *testing.T) {
+	store := NewStore()
+	seed(t, store, map[int64]float64{1: 100})
+	accounts := NewAccountsDao(store)
+
+	first, err := store.Begin(ctx)
+	require.NoError(t, err)
+	_, err = accounts.UpdateBalance(ctx, first, 1, 1, 90)
+	require.NoError(t, err)
+
+	updated := make(chan error)
+	go func() {
+		second, err := store.Begin(ctx)
+		require.NoError(t, err)
+		defer second.Rollback(ctx)
+		_, err = accounts.UpdateBalance(ctx, second, 1, 1, 50)
+		updated <- err
+	}()
+
+	select {
+	case <-updated:
+		t.Fatal("the update didn't wait for the lock")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	require.NoError(t, first.Commit(ctx))
+	assert.ErrorIs(t, <-updated, accountsdao.ErrVersionConflict)
+}
+
+func TestAccounts_Deadlock(t *testing.T) {
+	store := NewStore()
+	seed(t, store, map[int64]float64{1: 100, 2: 100})
+	accounts := NewAccountsDao(store)
+
+	first, err := store.Begin(ctx)
+	require.NoError(t, err)
+	second, err := store.Begin(ctx)
+	require.NoError(t, err)
+	_, err = accounts.UpdateBalance(ctx, first, 1, 1, 90)
+	require.NoError(t, err)
+	_, err = accounts.UpdateBalance(ctx, second, 2, 1, 90)
+	require.NoError(t, err)
+
+	waited := make(chan error)
+	go func() {
+		_, err := accounts.UpdateBalance(ctx, first, 2, 1, 110)
+		waited <- err
+	}()
+	// the first transaction waits for the second before it closes the loop
+	require.Eventually(t, func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return first.(*tx).waiting != nil
+	}, time.Second, time.Millisecond)
+
+	_, err = accounts.UpdateBalance(ctx, second, 1, 1, 110)
+	assert.Equal(t, pgerrcode.DeadlockDetected, pgCode(err))
+	require.NoError(t, second.Rollback(ctx))
+
+	assert.NoError(t, <-waited)
+	assert.NoError(t, first.Commit(ctx))
+}
+
+func TestAccounts_WaitIsCancelled(t *testing.T) {
+	store := NewStore()
+	seed(t, store, map[int64]float64{1: 100})
+	accounts := NewAccountsDao(store)
+
+	first, err := store.Begin(ctx)
+	require.NoError(t, err)
+	defer first.Rollback(ctx)
+	_, err = accounts.UpdateBalance(ctx, first, 1, 1, 90)
+	require.NoError(t, err)
+
+	second, err := store.Begin(ctx)
+	require.NoError(t, err)
+	cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = accounts.UpdateBalance(cancelled, second, 1, 1, 50)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, second.Commit(ctx), pgx.ErrTxCommitRollback)
+}
+
+func TestStore_TracedTransactions(t *testing.T) {
+	store := NewStore()
+
+	tx, err := tracing.Beginner(store).Begin(ctx)
+	require.NoError(t, err)
+	_, err = NewAccountsDao(store).Create(ctx, tx, 1, 100, "client-1")
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit(ctx))
+
+	other, err := NewStore().Begin(ctx)
+	require.NoError(t, err)
+	_, err = NewAccountsDao(store).Create(ctx, other, 2, 100, "client-1")
+	assert.ErrorIs(t, err, errForeignTx)
+
+	_, err = tx.Exec(ctx, "select 1")
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestOutbox_RelayAndListeners(t *testing.T) {
+	store := NewStore()
+	outbox := NewOutboxDao(store)
+	var payloads []string
+	store.Listen(func(payload string) { payloads = append(payloads, payload) })
+
+	tx, err := store.Begin(ctx)
+	require.NoError(t, err)
+	require.NoError(t, outbox.Add(ctx, tx, "account.created", 1, map[string]int{"balance": 100}))
+	require.NoError(t, outbox.Add(ctx, tx, "account.created", 2, map[string]int{"balance": 5}))
+	assert.Empty(t, payloads)
+	require.NoError(t, tx.Commit(ctx))
+
+	require.Len(t, payloads, 2)
+	var message struct {
+		Id        int64           `json:"id"`
+		Type      string          `json:"type"`
+		TenantId  string          `json:"tenant_id"`
+		AccountId int64           `json:"account_id"`
+		Data      json.RawMessage `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(payloads[1]), &message))
+	assert.Equal(t, int64(2), message.Id)
+	assert.Equal(t, "account.created", message.Type)
+	assert.Equal(t, tenant.Default, message.TenantId)
+	assert.JSONEq(t, `{"balance": 5}`, string(message.Data))
+
+	unlock, locked, err := outbox.Lock(ctx)
+	assert.NoError(t, err)
+	assert.True(t, locked)
+
+	_, locked, err = outbox.Lock(ctx)
+	assert.NoError(t, err)
+	assert.False(t, locked)
+
+	events, err := outbox.Unpublished(ctx, 1)
+	assert.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(1), events[0].GetId())
+	require.NoError(t, outbox.MarkPublished(ctx, []int64{1}))
+	unlock()
+
+	unlock, locked, err = outbox.Lock(ctx)
+	require.NoError(t, err)
+	require.True(t, locked)
+	defer unlock()
+	events, err = outbox.Unpublished(ctx, 10)
+	assert.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(2), events[0].GetId())
+}
+
+func TestOutbox_RetriesAndParks(t *testing.T) {
+	store := NewStore()
+	outbox := NewOutboxDao(store)
+
+	tx, err := store.Begin(ctx)
+	require.NoError(t, err)
+	require.NoError(t, outbox.Add(ctx, tx, "account.created", 1, map[string]int{}))
+	require.NoError(t, outbox.Add(ctx, tx, "account.balance_changed", 1, map[string]int{}))
+	require.NoError(t, outbox.Add(ctx, tx, "account.created", 2, map[string]int{}))
+	require.NoError(t, tx.Commit(ctx))
+
+	// the event waiting to be retried holds back the later one of account 1
+	require.NoError(t, outbox.Failed(ctx, 1, "connection refused", time.Now().Add(time.Hour)))
+	events, err := outbox.Unpublished(ctx, 10)
+	assert.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(3), events[0].GetId())
+
+	require.NoError(t, outbox.Park(ctx, 1, "connection refused"))
+	events, err = outbox.Unpublished(ctx, 10)
+	assert.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(2), events[0].GetId())
+}
+
+func TestOutbox_InCommitOrder(t *testing.T) {
+	store := NewStore()
+	outbox := NewOutboxDao(store)
+
+	first, err := store.Begin(ctx)
+	require.NoError(t, err)
+	require.NoError(t, outbox.Add(ctx, first, "account.created", 1, map[string]int{}))
+	second, err := store.Begin(ctx)
+	require.NoError(t, err)
+	require.NoError(t, outbox.Add(ctx, second, "account.created", 2, map[string]int{}))
+	require.NoError(t, second.Commit(ctx))
+	require.NoError(t, first.Commit(ctx))
+
+	events, err := outbox.Unpublished(ctx, 10)
+	assert.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, []int64{2, 1}, []int64{events[0].GetId(), events[1].GetId()})
+}
+
+func TestOutbox_ListsByAccountInCommitOrder(t *testing.T) {
+	store := NewStore()
+	outbox := NewOutboxDao(store)
+
+	horizon, err := outbox.Horizon(ctx)
+	require.NoError(t, err)
+
+	first, err := store.Begin(ctx)
+	require.NoError(t, err)
+	require.NoError(t, outbox.Add(ctx, first, "account.created", 1, map[string]int{}))
+	second, err := store.Begin(ctx)
+	require.NoError(t, err)
+	require.NoError(t, outbox.Add(ctx, second, "account.balance_changed", 1, map[string]int{}))
+	require.NoError(t, second.Commit(ctx))
+
+	events, err := outbox.ListByAccount(ctx, 1, horizon, 10)
+	assert.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(2), events[0].GetId())
+
+	// the event with the lower id commits after the other was read, and
+	// still comes after it
+	require.NoError(t, first.Commit(ctx))
+	events, err = outbox.ListByAccount(ctx, 1, events[0].Cursor(), 10)
+	assert.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(1), events[0].GetId())
+
+	events, err = outbox.ListByAccount(ctx, 1, events[0].Cursor(), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestTransactions_Lists(t *testing.T) {
+	store := NewStore()
+	transactions := NewTransactionsDao(store)
+	acme := tenant.NewContext(context.Background(), "acme")
+
+	for _, transfer := range []struct {
+		source, destination int64
+		destinationTenantId string
+		amount              float64
+	}{{1, 2, tenant.Default, 10}, {2, 1, tenant.Default, 4}, {1, 7, "acme", 1}, {3, 2, tenant.Default, 2}} {
+		tx, err := store.Begin(ctx)
+		require.NoError(t, err)
+		_, err = transactions.Create(ctx, tx, transfer.source, transfer.destination, transfer.destinationTenantId, transfer.amount, "")
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit(ctx))
+	}
+
+	listed, err := transactions.ListByAccount(ctx, 1, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3, 2, 1}, ids(listed))
+	listed, err = transactions.ListByAccount(ctx, 1, 3, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2}, ids(listed))
+
+	listed, err = transactions.ListByAccountBetween(ctx, 2, time.Time{}, 0, time.Now(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, ids(listed))
+	listed, err = transactions.ListByAccountBetween(ctx, 2, listed[1].GetCreatedAt(), listed[1].GetId(), time.Now(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{4}, ids(listed))
+
+	net, err := transactions.NetByAccount(ctx, 1, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, -7.0, net)
+
+	// the destination tenant sees the transfer into its account
+	listed, err = transactions.ListByAccount(acme, 7, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3}, ids(listed))
+	_, err = transactions.GetById(acme, 3)
+	assert.NoError(t, err)
+	_, err = transactions.GetById(acme, 1)
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
+
+func TestApiKeys_CreateRotateRevoke(t *testing.T) {
+	apiKeys := NewApiKeysDao(NewStore())
+
+	created, err := apiKeys.Create(ctx, "key-1", "client-1", tenant.Default, "ci", []string{"read"}, []byte("hash-1"), []byte("secret-1"))
+	require.NoError(t, err)
+	_, err = apiKeys.Create(ctx, "key-1", "client-2", tenant.Default, "ci", nil, nil, nil)
+	assert.Equal(t, pgerrcode.UniqueViolation, pgCode(err))
+
+	// the stored key can't be changed through what was returned
+	created.GetRoles()[0] = "admin"
+	got, err := apiKeys.GetById(ctx, "key-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read"}, got.GetRoles())
+	assert.Equal(t, []byte("hash-1"), got.GetKeyHash())
+	assert.False(t, got.IsRevoked())
+
+	rotated, err := apiKeys.Rotate(ctx, "key-1", "key-2", []byte("hash-2"), []byte("secret-2"))
+	require.NoError(t, err)
+	assert.Equal(t, "client-1", rotated.GetClientId())
+	assert.Equal(t, []string{"read"}, rotated.GetRoles())
+	got, err = apiKeys.GetById(ctx, "key-1")
+	require.NoError(t, err)
+	assert.True(t, got.IsRevoked())
+	_, err = apiKeys.Rotate(ctx, "key-1", "key-3", nil, nil)
+	assert.ErrorIs(t, err, apikeysdao.ErrNotFound)
+
+	assert.NoError(t, apiKeys.Revoke(ctx, "key-2"))
+	assert.ErrorIs(t, apiKeys.Revoke(ctx, "key-2"), apikeysdao.ErrNotFound)
+	_, err = apiKeys.GetById(ctx, "key-3")
+	assert.ErrorIs(t, err, apikeysdao.ErrNotFound)
+}
+
+func ids(transactions []transactionsmodel.Transactions) []int64 {
+	ids := make([]int64, len(transactions))
+	for i := range transactions {
+		ids[i] = transactions[i].GetId()
+	}
+	return ids
+}
@@ -0,0 +1,179 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ashwin-m/transactions/daos/outbox"
+	eventsmodel "github.com/ashwin-m/transactions/models/events"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+type outboxDao struct {
+	store *Store
+}
+
+func NewOutboxDao(store *Store) outbox.Dao {
+	return &outboxDao{
+		store: store,
+	}
+}
+
+func (d *outboxDao) Add(ctx context.Context, tx pgx.Tx, eventType string, accountId int64, data any) error {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	t, err := d.store.txFrom(tx)
+	if err != nil {
+		return err
+	}
+
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	err = t.usable()
+	if err != nil {
+		return err
+	}
+
+	d.store.eventId++
+	e := &event{}
+	e.SetId(d.store.eventId)
+	e.SetTenantId(tenantId)
+	e.SetType(eventType)
+	e.SetAccountId(accountId)
+	e.SetPayload(payload)
+	e.SetCreatedAt(t.started)
+	t.events = append(t.events, e)
+
+	return nil
+}
+
+// Lock takes the relay lock until unlock is called, like the advisory lock
+// of Postgres it never waits.
+func (d *outboxDao) Lock(ctx context.Context) (func(), bool, error) {
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	if d.store.relayLocked {
+		return nil, false, nil
+	}
+	d.store.relayLocked = true
+
+	return func() {
+		d.store.mu.Lock()
+		defer d.store.mu.Unlock()
+		d.store.relayLocked = false
+	}, true, nil
+}
+
+// Unpublished returns committed events in the order they were committed. An
+// event waiting to be retried holds back the later events of its account.
+func (d *outboxDao) Unpublished(ctx context.Context, limit int) ([]eventsmodel.Events, error) {
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	now := time.Now()
+	retrying := map[accountKey]bool{}
+	events := []eventsmodel.Events{}
+	for _, e := range d.store.events {
+		if len(events) == limit {
+			break
+		}
+		if e.published || e.parked {
+			continue
+		}
+
+		key := accountKey{e.GetTenantId(), e.GetAccountId()}
+		if e.retryAt.After(now) {
+			retrying[key] = true
+		}
+		if !retrying[key] {
+			events = append(events, e.Events)
+		}
+	}
+
+	return events, nil
+}
+
+func (d *outboxDao) MarkPublished(ctx context.Context, ids []int64) error {
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	for _, id := range ids {
+		if e := d.store.event(id); e != nil {
+			e.published = true
+		}
+	}
+
+	return nil
+}
+
+func (d *outboxDao) Failed(ctx context.Context, id int64, message string, retryAt time.Time) error {
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	if e := d.store.event(id); e != nil {
+		e.SetAttempts(e.GetAttempts() + 1)
+		e.lastError = message
+		e.retryAt = retryAt
+	}
+
+	return nil
+}
+
+func (d *outboxDao) Park(ctx context.Context, id int64, message string) error {
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	if e := d.store.event(id); e != nil {
+		e.SetAttempts(e.GetAttempts() + 1)
+		e.lastError = message
+		e.parked = true
+	}
+
+	return nil
+}
+
+// ListByAccount returns up to limit committed events about an account of
+// the tenant on ctx that come after the cursor after, in the order of their
+// cursors. Transactions are numbered as they commit, so every committed
+// event is returned.
+func (d *outboxDao) ListByAccount(ctx context.Context, accountId int64, after eventsmodel.Cursor, limit int) ([]eventsmodel.Events, error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	events := []eventsmodel.Events{}
+	for _, e := range d.store.events {
+		if len(events) == limit {
+			break
+		}
+		if e.GetTenantId() == tenantId && e.GetAccountId() == accountId && after.Before(e.Cursor()) {
+			events = append(events, e.Events)
+		}
+	}
+
+	return events, nil
+}
+
+// Horizon returns the cursor of the next transaction to commit.
+func (d *outboxDao) Horizon(ctx context.Context) (eventsmodel.Cursor, error) {
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	return eventsmodel.Cursor{Xid: d.store.xid + 1}, nil
+}
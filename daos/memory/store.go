@@ -0,0 +1,411 @@
+// Package memory keeps accounts, transfers, outbox events and API keys in
+// memory, for running the server and behavior-level tests without Postgres.
+// Its daos implement those of the accounts, transactions, outbox and
+// apikeys packages over a Store, and its transactions behave like those of
+// Postgres at read committed:
+//
+//   - writes are only seen by the transaction making them until it commits,
+//     and are discarded when it rolls back
+//   - a transaction writing an account locks it until it ends, and anyone
+//     else writing it waits for that. A balance update then checks the
+//     version against the committed account, as the update statement of
+//     Postgres does once the lock is released
+//   - transactions waiting for each other fail with a deadlock_detected
+//     error, and so do inserts of an account that exists with a
+//     unique_violation, both as *pgconn.PgError
+//   - a transaction is aborted by a failed statement, and committing it
+//     rolls it back
+//
+// Nothing is persisted, and no SQL is run: Exec, Query and the other pgx.Tx
+// methods that take SQL fail.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	migrationsdao "github.com/ashwin-m/transactions/daos/migrations"
+	apikeys_model "github.com/ashwin-m/transactions/models/apikeys"
+	eventsmodel "github.com/ashwin-m/transactions/models/events"
+	transactionsmodel "github.com/ashwin-m/transactions/models/transactions"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrUnsupported is returned by the pgx.Tx methods running SQL.
+	ErrUnsupported = errors.New("the memory store doesn't run SQL")
+
+	errForeignTx = errors.New("the transaction wasn't begun by this memory store")
+)
+
+type accountKey struct {
+	tenantId string
+	id       int64
+}
+
+type accountRow struct {
+	balance        float64
+	initialBalance float64
+	version        int64
+	ownerId        string
+}
+
+type event struct {
+	eventsmodel.Events
+	published bool
+	parked    bool
+	lastError string
+	retryAt   time.Time
+}
+
+// Store holds the committed state. It implements pgxiface.PgxIface, the
+// health.Pinger and migrations.Dao used by the readiness probe.
+type Store struct {
+	mu        sync.Mutex
+	accounts  map[accountKey]accountRow
+	transfers []transactionsmodel.Transactions
+	events    []*event
+	// transferId and eventId are the last ids handed out. Like sequences
+	// they aren't rolled back.
+	transferId int64
+	eventId    int64
+	// xid is the last transaction that committed events. Unlike Postgres,
+	// transactions are numbered as they commit.
+	xid uint64
+	// locks maps every locked key to the transaction holding it.
+	locks map[any]*tx
+	// relayLocked is set while a relay holds the outbox.
+	relayLocked bool
+	listeners   []func(payload string)
+	// apiKeys maps the id of every key, revoked or not, to it. Keys aren't
+	// written in transactions, so they are always committed.
+	apiKeys map[string]apikeys_model.ApiKeys
+}
+
+func NewStore() *Store {
+	return &Store{
+		accounts: map[accountKey]accountRow{},
+		locks:    map[any]*tx{},
+		apiKeys:  map[string]apikeys_model.ApiKeys{},
+	}
+}
+
+func (s *Store) Begin(ctx context.Context) (pgx.Tx, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return &tx{
+		store:    s,
+		started:  time.Now(),
+		done:     make(chan struct{}),
+		accounts: map[accountKey]accountRow{},
+	}, nil
+}
+
+// Ping always succeeds, the store is in the process.
+func (s *Store) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Version is always the schema version this build requires.
+func (s *Store) Version(ctx context.Context) (int64, error) {
+	return migrationsdao.RequiredVersion, nil
+}
+
+// Listen calls listener with every event committed from now on, with the
+// payload the outbox trigger of Postgres announces on events.Channel.
+func (s *Store) Listen(listener func(payload string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listeners = append(s.listeners, listener)
+}
+
+// tx is a transaction of a Store. Every field but store, started and done
+// is guarded by the mutex of the store.
+type tx struct {
+	store   *Store
+	started time.Time
+	// done is closed when the transaction ends, releasing its locks.
+	done chan struct{}
+
+	closed bool
+	failed bool
+	// waiting is the transaction holding a lock this one waits for.
+	waiting *tx
+	locked  []any
+
+	accounts  map[accountKey]accountRow
+	transfers []transactionsmodel.Transactions
+	events    []*event
+}
+
+// event returns the committed event id, nil when there is none. It must be
+// called with the mutex of the store held.
+func (s *Store) event(id int64) *event {
+	for _, e := range s.events {
+		if e.GetId() == id {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// txFrom returns the transaction of s behind t, which may be wrapped, by
+// tracing.Beginner for instance.
+func (s *Store) txFrom(t pgx.Tx) (*tx, error) {
+	for {
+		switch wrapper := t.(type) {
+		case *tx:
+			if wrapper.store != s {
+				return nil, errForeignTx
+			}
+			return wrapper, nil
+		case interface{ Unwrap() pgx.Tx }:
+			t = wrapper.Unwrap()
+		default:
+			return nil, errForeignTx
+		}
+	}
+}
+
+// usable reports why statements can't run in t, if they can't. It must be
+// called with the mutex of the store held.
+func (t *tx) usable() error {
+	switch {
+	case t.closed:
+		return pgx.ErrTxClosed
+	case t.failed:
+		return &pgconn.PgError{Severity: "ERROR", Code: pgerrcode.InFailedSQLTransaction, Message: "current transaction is aborted, commands ignored until end of transaction block"}
+	}
+
+	return nil
+}
+
+// fail aborts t because of err, and returns it. It must be called with the
+// mutex of the store held.
+func (t *tx) fail(err error) error {
+	t.failed = true
+	return err
+}
+
+// lock takes the lock on key, waiting for the transaction holding it to
+// end. It must be called with the mutex of the store held, which is
+// released while waiting.
+func (t *tx) lock(ctx context.Context, key any) error {
+	s := t.store
+	for {
+		if err := t.usable(); err != nil {
+			return err
+		}
+
+		owner, locked := s.locks[key]
+		if !locked {
+			s.locks[key] = t
+			t.locked = append(t.locked, key)
+			return nil
+		}
+		if owner == t {
+			return nil
+		}
+
+		for waiter := owner; waiter != nil; waiter = waiter.waiting {
+			if waiter == t {
+				return t.fail(&pgconn.PgError{Severity: "ERROR", Code: pgerrcode.DeadlockDetected, Message: "deadlock detected"})
+			}
+		}
+
+		t.waiting = owner
+		s.mu.Unlock()
+		select {
+		case <-owner.done:
+		case <-ctx.Done():
+		}
+		s.mu.Lock()
+		t.waiting = nil
+
+		if err := ctx.Err(); err != nil {
+			return t.fail(err)
+		}
+	}
+}
+
+// tryLock takes the lock on key unless another transaction holds it. It
+// must be called with the mutex of the store held.
+func (t *tx) tryLock(key any) (bool, error) {
+	if err := t.usable(); err != nil {
+		return false, err
+	}
+
+	owner, locked := t.store.locks[key]
+	if locked {
+		return owner == t, nil
+	}
+	t.store.locks[key] = t
+	t.locked = append(t.locked, key)
+
+	return true, nil
+}
+
+// account returns an account as t sees it. It must be called with the mutex
+// of the store held.
+func (t *tx) account(key accountKey) (accountRow, bool) {
+	if a, ok := t.accounts[key]; ok {
+		return a, true
+	}
+	a, ok := t.store.accounts[key]
+	return a, ok
+}
+
+func (t *tx) Commit(ctx context.Context) error {
+	return t.end(true)
+}
+
+func (t *tx) Rollback(ctx context.Context) error {
+	return t.end(false)
+}
+
+// end applies the writes of t when it commits, and releases its locks.
+// Committing a failed transaction rolls it back.
+func (t *tx) end(commit bool) error {
+	s := t.store
+	s.mu.Lock()
+	if t.closed {
+		s.mu.Unlock()
+		return pgx.ErrTxClosed
+	}
+	t.closed = true
+
+	var payloads []string
+	if commit && !t.failed {
+		payloads = t.apply()
+	}
+	for _, key := range t.locked {
+		delete(s.locks, key)
+	}
+	close(t.done)
+	listeners := s.listeners
+	s.mu.Unlock()
+
+	for _, payload := range payloads {
+		for _, listener := range listeners {
+			listener(payload)
+		}
+	}
+
+	if commit && t.failed {
+		return pgx.ErrTxCommitRollback
+	}
+	return nil
+}
+
+// apply writes what t changed to the store, and returns the notifications
+// of the events it recorded. It must be called with the mutex of the store
+// held.
+func (t *tx) apply() []string {
+	s := t.store
+	for key, a := range t.accounts {
+		s.accounts[key] = a
+	}
+
+	// ids are handed out in the order of the writes, transactions may
+	// commit in another
+	s.transfers = append(s.transfers, t.transfers...)
+	sort.Slice(s.transfers, func(i, j int) bool { return s.transfers[i].GetId() < s.transfers[j].GetId() })
+	// events stay in the order they were committed, which is the order the
+	// relay publishes them in
+	if len(t.events) > 0 {
+		s.xid++
+		for _, e := range t.events {
+			e.SetXid(s.xid)
+		}
+	}
+	s.events = append(s.events, t.events...)
+
+	payloads := make([]string, 0, len(t.events))
+	for _, e := range t.events {
+		payload, err := json.Marshal(struct {
+			Id         int64           `json:"id"`
+			Type       string          `json:"type"`
+			TenantId   string          `json:"tenant_id"`
+			AccountId  int64           `json:"account_id"`
+			OccurredAt time.Time       `json:"occurred_at"`
+			Data       json.RawMessage `json:"data"`
+		}{e.GetId(), e.GetType(), e.GetTenantId(), e.GetAccountId(), e.GetCreatedAt(), e.GetPayload()})
+		if err == nil {
+			payloads = append(payloads, string(payload))
+		}
+	}
+
+	return payloads
+}
+
+// Begin would start a savepoint, which the store doesn't support.
+func (t *tx) Begin(ctx context.Context) (pgx.Tx, error) {
+	return nil, ErrUnsupported
+}
+
+func (t *tx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return 0, ErrUnsupported
+}
+
+func (t *tx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return unsupportedBatch{}
+}
+
+func (t *tx) LargeObjects() pgx.LargeObjects {
+	return pgx.LargeObjects{}
+}
+
+func (t *tx) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	return nil, ErrUnsupported
+}
+
+func (t *tx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, ErrUnsupported
+}
+
+func (t *tx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return nil, ErrUnsupported
+}
+
+func (t *tx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return unsupportedRow{}
+}
+
+// Conn is nil, there is no connection.
+func (t *tx) Conn() *pgx.Conn {
+	return nil
+}
+
+type unsupportedRow struct{}
+
+func (unsupportedRow) Scan(dest ...any) error {
+	return ErrUnsupported
+}
+
+type unsupportedBatch struct{}
+
+func (unsupportedBatch) Exec() (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, ErrUnsupported
+}
+
+func (unsupportedBatch) Query() (pgx.Rows, error) {
+	return nil, ErrUnsupported
+}
+
+func (unsupportedBatch) QueryRow() pgx.Row {
+	return unsupportedRow{}
+}
+
+func (unsupportedBatch) Close() error {
+	return nil
+}
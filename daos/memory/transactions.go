@@ -0,0 +1,175 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	transactionsdao "github.com/ashwin-m/transactions/daos/transactions"
+	transactionsmodel "github.com/ashwin-m/transactions/models/transactions"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+type transactionsDao struct {
+	store *Store
+}
+
+func NewTransactionsDao(store *Store) transactionsdao.Dao {
+	return &transactionsDao{
+		store: store,
+	}
+}
+
+// Create records a transfer out of an account of the tenant on ctx, made at
+// the start of txn like the default of created_at.
+func (d *transactionsDao) Create(ctx context.Context, txn pgx.Tx, sourceAccountId, destinationAccountId int64, destinationTenantId string, amount float64, clientId string) (int64, error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	t, err := d.store.txFrom(txn)
+	if err != nil {
+		return 0, err
+	}
+
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	err = t.usable()
+	if err != nil {
+		return 0, err
+	}
+
+	d.store.transferId++
+	var transaction transactionsmodel.Transactions
+	transaction.SetId(d.store.transferId)
+	transaction.SetTenantId(tenantId)
+	transaction.SetSourceAccountId(sourceAccountId)
+	transaction.SetDestinationTenantId(destinationTenantId)
+	transaction.SetDestinationAccountId(destinationAccountId)
+	transaction.SetAmount(amount)
+	transaction.SetClientId(clientId)
+	transaction.SetCreatedAt(t.started)
+	t.transfers = append(t.transfers, transaction)
+
+	return transaction.GetId(), nil
+}
+
+// GetById returns a committed transfer made by or to the tenant on ctx.
+func (d *transactionsDao) GetById(ctx context.Context, id int64) (transaction transactionsmodel.Transactions, err error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return transaction, err
+	}
+
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	i := sort.Search(len(d.store.transfers), func(i int) bool { return d.store.transfers[i].GetId() >= id })
+	if i == len(d.store.transfers) || d.store.transfers[i].GetId() != id {
+		return transaction, pgx.ErrNoRows
+	}
+	transaction = d.store.transfers[i]
+	if transaction.GetTenantId() != tenantId && transaction.GetDestinationTenantId() != tenantId {
+		return transactionsmodel.Transactions{}, pgx.ErrNoRows
+	}
+
+	return transaction, nil
+}
+
+// ListByAccount returns up to limit transfers into or out of an account of
+// the tenant on ctx, newest first. Only transfers with an id below beforeId
+// are returned, unless it is 0.
+func (d *transactionsDao) ListByAccount(ctx context.Context, accountId, beforeId int64, limit int) ([]transactionsmodel.Transactions, error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	transactions := []transactionsmodel.Transactions{}
+	for i := len(d.store.transfers) - 1; i >= 0 && len(transactions) < limit; i-- {
+		transaction := d.store.transfers[i]
+		if (beforeId == 0 || transaction.GetId() < beforeId) && touches(transaction, tenantId, accountId) {
+			transactions = append(transactions, transaction)
+		}
+	}
+
+	return transactions, nil
+}
+
+// ListByAccountBetween returns at most limit transfers into or out of an
+// account of the tenant on ctx made from from until to, excluded, oldest
+// first. Of those made at from, only the ones after afterId are returned.
+func (d *transactionsDao) ListByAccountBetween(ctx context.Context, accountId int64, from time.Time, afterId int64, to time.Time, limit int) ([]transactionsmodel.Transactions, error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	transactions := []transactionsmodel.Transactions{}
+	for _, transaction := range d.store.transfers {
+		createdAt := transaction.GetCreatedAt()
+		after := createdAt.After(from) || createdAt.Equal(from) && transaction.GetId() > afterId
+		if after && createdAt.Before(to) && touches(transaction, tenantId, accountId) {
+			transactions = append(transactions, transaction)
+		}
+	}
+	// transfers are kept by id, a transaction begun earlier may have
+	// committed later
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].GetCreatedAt().Before(transactions[j].GetCreatedAt())
+	})
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+	}
+
+	return transactions, nil
+}
+
+// Undated returns the zero time, transfers kept in memory are always dated.
+func (d *transactionsDao) Undated(ctx context.Context, accountId int64) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+// NetByAccount returns what the transfers made before before added to the
+// balance of an account of the tenant on ctx, those into it less those out
+// of it.
+func (d *transactionsDao) NetByAccount(ctx context.Context, accountId int64, before time.Time) (float64, error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	var in, out float64
+	for _, transaction := range d.store.transfers {
+		if !transaction.GetCreatedAt().Before(before) {
+			continue
+		}
+		if transaction.GetDestinationTenantId() == tenantId && transaction.GetDestinationAccountId() == accountId {
+			in += transaction.GetAmount()
+		}
+		if transaction.GetTenantId() == tenantId && transaction.GetSourceAccountId() == accountId {
+			out += transaction.GetAmount()
+		}
+	}
+
+	return in - out, nil
+}
+
+// touches reports whether a transfer is into or out of an account of a
+// tenant.
+func touches(transaction transactionsmodel.Transactions, tenantId string, accountId int64) bool {
+	return (transaction.GetTenantId() == tenantId && transaction.GetSourceAccountId() == accountId) ||
+		(transaction.GetDestinationTenantId() == tenantId && transaction.GetDestinationAccountId() == accountId)
+}
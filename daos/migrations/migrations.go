@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/ashwin-m/transactions/utils/tracing"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+)
+
+// RequiredVersion is the schema version this build expects. Bump it together
+// with every change to resources/db.
+const RequiredVersion = 17
+
+var tracer = otel.Tracer("github.com/ashwin-m/transactions/daos/migrations")
+
+//go:generate mockery --name=Dao --output=mocks --outpkg=mocks --with-expecter
+type Dao interface {
+	Version(ctx context.Context) (int64, error)
+}
+
+type dao struct {
+	dbPool *pgxpool.Pool
+}
+
+func NewDao(dbPool *pgxpool.Pool) Dao {
+	return &dao{
+		dbPool: dbPool,
+	}
+}
+
+func (d *dao) Version(ctx context.Context) (version int64, err error) {
+	ctx, span := tracer.Start(ctx, "migrationsDao.Version")
+	defer func() { tracing.End(span, err) }()
+
+	sqlStatement := "select coalesce(max(version), 0) from schema_migrations"
+	err = d.dbPool.QueryRow(ctx, sqlStatement).Scan(&version)
+
+	return version, err
+}
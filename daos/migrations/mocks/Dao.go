@@ -0,0 +1,92 @@
+// Code generated by mockery v2.43.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Dao is an autogenerated mock type for the Dao type
+type Dao struct {
+	mock.Mock
+}
+
+type Dao_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Dao) EXPECT() *Dao_Expecter {
+	return &Dao_Expecter{mock: &_m.Mock}
+}
+
+// Version provides a mock function with given fields: ctx
+func (_m *Dao) Version(ctx context.Context) (int64, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Version")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (int64, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Version_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Version'
+type Dao_Version_Call struct {
+	*mock.Call
+}
+
+// Version is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Dao_Expecter) Version(ctx interface{}) *Dao_Version_Call {
+	return &Dao_Version_Call{Call: _e.mock.On("Version", ctx)}
+}
+
+func (_c *Dao_Version_Call) Run(run func(ctx context.Context)) *Dao_Version_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Dao_Version_Call) Return(_a0 int64, _a1 error) *Dao_Version_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Version_Call) RunAndReturn(run func(context.Context) (int64, error)) *Dao_Version_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewDao creates a new instance of Dao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Dao {
+	mock := &Dao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,476 @@
+// Code generated by mockery v2.43.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	events "github.com/ashwin-m/transactions/models/events"
+	mock "github.com/stretchr/testify/mock"
+
+	pgx "github.com/jackc/pgx/v5"
+
+	time "time"
+)
+
+// Dao is an autogenerated mock type for the Dao type
+type Dao struct {
+	mock.Mock
+}
+
+type Dao_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Dao) EXPECT() *Dao_Expecter {
+	return &Dao_Expecter{mock: &_m.Mock}
+}
+
+// Add provides a mock function with given fields: ctx, tx, eventType, accountId, data
+func (_m *Dao) Add(ctx context.Context, tx pgx.Tx, eventType string, accountId int64, data interface{}) error {
+	ret := _m.Called(ctx, tx, eventType, accountId, data)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Add")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, string, int64, interface{}) error); ok {
+		r0 = rf(ctx, tx, eventType, accountId, data)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Dao_Add_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Add'
+type Dao_Add_Call struct {
+	*mock.Call
+}
+
+// Add is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+//   - eventType string
+//   - accountId int64
+//   - data interface{}
+func (_e *Dao_Expecter) Add(ctx interface{}, tx interface{}, eventType interface{}, accountId interface{}, data interface{}) *Dao_Add_Call {
+	return &Dao_Add_Call{Call: _e.mock.On("Add", ctx, tx, eventType, accountId, data)}
+}
+
+func (_c *Dao_Add_Call) Run(run func(ctx context.Context, tx pgx.Tx, eventType string, accountId int64, data interface{})) *Dao_Add_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx), args[2].(string), args[3].(int64), args[4].(interface{}))
+	})
+	return _c
+}
+
+func (_c *Dao_Add_Call) Return(_a0 error) *Dao_Add_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Dao_Add_Call) RunAndReturn(run func(context.Context, pgx.Tx, string, int64, interface{}) error) *Dao_Add_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Failed provides a mock function with given fields: ctx, id, message, retryAt
+func (_m *Dao) Failed(ctx context.Context, id int64, message string, retryAt time.Time) error {
+	ret := _m.Called(ctx, id, message, retryAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Failed")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, time.Time) error); ok {
+		r0 = rf(ctx, id, message, retryAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Dao_Failed_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Failed'
+type Dao_Failed_Call struct {
+	*mock.Call
+}
+
+// Failed is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id int64
+//   - message string
+//   - retryAt time.Time
+func (_e *Dao_Expecter) Failed(ctx interface{}, id interface{}, message interface{}, retryAt interface{}) *Dao_Failed_Call {
+	return &Dao_Failed_Call{Call: _e.mock.On("Failed", ctx, id, message, retryAt)}
+}
+
+func (_c *Dao_Failed_Call) Run(run func(ctx context.Context, id int64, message string, retryAt time.Time)) *Dao_Failed_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int64), args[2].(string), args[3].(time.Time))
+	})
+	return _c
+}
+
+func (_c *Dao_Failed_Call) Return(_a0 error) *Dao_Failed_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Dao_Failed_Call) RunAndReturn(run func(context.Context, int64, string, time.Time) error) *Dao_Failed_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Horizon provides a mock function with given fields: ctx
+func (_m *Dao) Horizon(ctx context.Context) (events.Cursor, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Horizon")
+	}
+
+	var r0 events.Cursor
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (events.Cursor, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) events.Cursor); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(events.Cursor)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Horizon_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Horizon'
+type Dao_Horizon_Call struct {
+	*mock.Call
+}
+
+// Horizon is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Dao_Expecter) Horizon(ctx interface{}) *Dao_Horizon_Call {
+	return &Dao_Horizon_Call{Call: _e.mock.On("Horizon", ctx)}
+}
+
+func (_c *Dao_Horizon_Call) Run(run func(ctx context.Context)) *Dao_Horizon_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Dao_Horizon_Call) Return(_a0 events.Cursor, _a1 error) *Dao_Horizon_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Horizon_Call) RunAndReturn(run func(context.Context) (events.Cursor, error)) *Dao_Horizon_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListByAccount provides a mock function with given fields: ctx, accountId, after, limit
+func (_m *Dao) ListByAccount(ctx context.Context, accountId int64, after events.Cursor, limit int) ([]events.Events, error) {
+	ret := _m.Called(ctx, accountId, after, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListByAccount")
+	}
+
+	var r0 []events.Events
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, events.Cursor, int) ([]events.Events, error)); ok {
+		return rf(ctx, accountId, after, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, events.Cursor, int) []events.Events); ok {
+		r0 = rf(ctx, accountId, after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]events.Events)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, events.Cursor, int) error); ok {
+		r1 = rf(ctx, accountId, after, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_ListByAccount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListByAccount'
+type Dao_ListByAccount_Call struct {
+	*mock.Call
+}
+
+// ListByAccount is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountId int64
+//   - after events.Cursor
+//   - limit int
+func (_e *Dao_Expecter) ListByAccount(ctx interface{}, accountId interface{}, after interface{}, limit interface{}) *Dao_ListByAccount_Call {
+	return &Dao_ListByAccount_Call{Call: _e.mock.On("ListByAccount", ctx, accountId, after, limit)}
+}
+
+func (_c *Dao_ListByAccount_Call) Run(run func(ctx context.Context, accountId int64, after events.Cursor, limit int)) *Dao_ListByAccount_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int64), args[2].(events.Cursor), args[3].(int))
+	})
+	return _c
+}
+
+func (_c *Dao_ListByAccount_Call) Return(_a0 []events.Events, _a1 error) *Dao_ListByAccount_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_ListByAccount_Call) RunAndReturn(run func(context.Context, int64, events.Cursor, int) ([]events.Events, error)) *Dao_ListByAccount_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Lock provides a mock function with given fields: ctx
+func (_m *Dao) Lock(ctx context.Context) (func(), bool, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Lock")
+	}
+
+	var r0 func()
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context) (func(), bool, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) func()); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(func())
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) bool); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context) error); ok {
+		r2 = rf(ctx)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Dao_Lock_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Lock'
+type Dao_Lock_Call struct {
+	*mock.Call
+}
+
+// Lock is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Dao_Expecter) Lock(ctx interface{}) *Dao_Lock_Call {
+	return &Dao_Lock_Call{Call: _e.mock.On("Lock", ctx)}
+}
+
+func (_c *Dao_Lock_Call) Run(run func(ctx context.Context)) *Dao_Lock_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Dao_Lock_Call) Return(unlock func(), locked bool, err error) *Dao_Lock_Call {
+	_c.Call.Return(unlock, locked, err)
+	return _c
+}
+
+func (_c *Dao_Lock_Call) RunAndReturn(run func(context.Context) (func(), bool, error)) *Dao_Lock_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// MarkPublished provides a mock function with given fields: ctx, ids
+func (_m *Dao) MarkPublished(ctx context.Context, ids []int64) error {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkPublished")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) error); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Dao_MarkPublished_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'MarkPublished'
+type Dao_MarkPublished_Call struct {
+	*mock.Call
+}
+
+// MarkPublished is a helper method to define mock.On call
+//   - ctx context.Context
+//   - ids []int64
+func (_e *Dao_Expecter) MarkPublished(ctx interface{}, ids interface{}) *Dao_MarkPublished_Call {
+	return &Dao_MarkPublished_Call{Call: _e.mock.On("MarkPublished", ctx, ids)}
+}
+
+func (_c *Dao_MarkPublished_Call) Run(run func(ctx context.Context, ids []int64)) *Dao_MarkPublished_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]int64))
+	})
+	return _c
+}
+
+func (_c *Dao_MarkPublished_Call) Return(_a0 error) *Dao_MarkPublished_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Dao_MarkPublished_Call) RunAndReturn(run func(context.Context, []int64) error) *Dao_MarkPublished_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Park provides a mock function with given fields: ctx, id, message
+func (_m *Dao) Park(ctx context.Context, id int64, message string) error {
+	ret := _m.Called(ctx, id, message)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Park")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, id, message)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Dao_Park_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Park'
+type Dao_Park_Call struct {
+	*mock.Call
+}
+
+// Park is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id int64
+//   - message string
+func (_e *Dao_Expecter) Park(ctx interface{}, id interface{}, message interface{}) *Dao_Park_Call {
+	return &Dao_Park_Call{Call: _e.mock.On("Park", ctx, id, message)}
+}
+
+func (_c *Dao_Park_Call) Run(run func(ctx context.Context, id int64, message string)) *Dao_Park_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int64), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Dao_Park_Call) Return(_a0 error) *Dao_Park_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Dao_Park_Call) RunAndReturn(run func(context.Context, int64, string) error) *Dao_Park_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Unpublished provides a mock function with given fields: ctx, limit
+func (_m *Dao) Unpublished(ctx context.Context, limit int) ([]events.Events, error) {
+	ret := _m.Called(ctx, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Unpublished")
+	}
+
+	var r0 []events.Events
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]events.Events, error)); ok {
+		return rf(ctx, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []events.Events); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]events.Events)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Unpublished_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Unpublished'
+type Dao_Unpublished_Call struct {
+	*mock.Call
+}
+
+// Unpublished is a helper method to define mock.On call
+//   - ctx context.Context
+//   - limit int
+func (_e *Dao_Expecter) Unpublished(ctx interface{}, limit interface{}) *Dao_Unpublished_Call {
+	return &Dao_Unpublished_Call{Call: _e.mock.On("Unpublished", ctx, limit)}
+}
+
+func (_c *Dao_Unpublished_Call) Run(run func(ctx context.Context, limit int)) *Dao_Unpublished_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *Dao_Unpublished_Call) Return(_a0 []events.Events, _a1 error) *Dao_Unpublished_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Unpublished_Call) RunAndReturn(run func(context.Context, int) ([]events.Events, error)) *Dao_Unpublished_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewDao creates a new instance of Dao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Dao {
+	mock := &Dao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
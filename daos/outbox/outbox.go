@@ -0,0 +1,230 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	eventsmodel "github.com/ashwin-m/transactions/models/events"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/ashwin-m/transactions/utils/tracing"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// relayLockKey is the advisory lock held by the relay publishing events, so
+// only one process at a time publishes and events keep their order.
+const relayLockKey = 7_340_001
+
+// selectEvents reads events with the columns scanEvent expects.
+const selectEvents = "select id, tenant_id, event_type, account_id, payload, created_at, xid::text, attempts from outbox"
+
+var tracer = otel.Tracer("github.com/ashwin-m/transactions/daos/outbox")
+
+//go:generate mockery --name=Dao --output=mocks --outpkg=mocks --with-expecter
+type Dao interface {
+	// Add records an event about an account of the tenant on ctx as part of
+	// tx, so it is only published if tx commits. data is stored as JSON.
+	Add(ctx context.Context, tx pgx.Tx, eventType string, accountId int64, data any) error
+	// Lock takes the relay lock until unlock is called, outside of any
+	// transaction. It returns false when another relay holds it. The lock is
+	// released when the process holding it dies.
+	Lock(ctx context.Context) (unlock func(), locked bool, err error)
+	// Unpublished returns up to limit events of every tenant that are due to
+	// be published, in the order they were committed. Events of transactions
+	// that may still be followed by an earlier one aren't returned yet, nor
+	// are parked events, an event waiting to be retried and the later events
+	// of its account.
+	Unpublished(ctx context.Context, limit int) ([]eventsmodel.Events, error)
+	MarkPublished(ctx context.Context, ids []int64) error
+	// Failed records a failed attempt at publishing an event, which is
+	// retried from retryAt.
+	Failed(ctx context.Context, id int64, message string, retryAt time.Time) error
+	// Park records the last failed attempt at publishing an event, which is
+	// set aside and no longer holds back the later events of its account.
+	Park(ctx context.Context, id int64, message string) error
+	// ListByAccount returns up to limit events about an account of the
+	// tenant on ctx that come after the cursor after, in the order of their
+	// cursors. Like Unpublished, it leaves out the events of transactions
+	// that may still be followed by an earlier one.
+	ListByAccount(ctx context.Context, accountId int64, after eventsmodel.Cursor, limit int) ([]eventsmodel.Events, error)
+	// Horizon returns the cursor the events of transactions still running,
+	// or yet to begin, come after.
+	Horizon(ctx context.Context) (eventsmodel.Cursor, error)
+}
+
+type dao struct {
+	dbPool *pgxpool.Pool
+}
+
+func NewDao(dbPool *pgxpool.Pool) Dao {
+	return &dao{
+		dbPool: dbPool,
+	}
+}
+
+func (d *dao) Add(ctx context.Context, tx pgx.Tx, eventType string, accountId int64, data any) (err error) {
+	ctx, span := tracer.Start(ctx, "outboxDao.Add", trace.WithAttributes(attribute.String("event.type", eventType), attribute.Int64("account.id", accountId)))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	sqlStatement := "insert into outbox(tenant_id, event_type, account_id, payload) values ($1, $2, $3, $4)"
+	_, err = tx.Exec(ctx, sqlStatement, tenantId, eventType, accountId, payload)
+
+	return err
+}
+
+func (d *dao) Lock(ctx context.Context) (unlock func(), locked bool, err error) {
+	ctx, span := tracer.Start(ctx, "outboxDao.Lock")
+	defer func() { tracing.End(span, err) }()
+
+	// a session lock, held on a connection of its own
+	conn, err := d.dbPool.Acquire(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = conn.QueryRow(ctx, "select pg_try_advisory_lock($1)", relayLockKey).Scan(&locked)
+	if err != nil || !locked {
+		conn.Release()
+		return nil, false, err
+	}
+
+	return func() {
+		ctx := context.WithoutCancel(ctx)
+		_, err := conn.Exec(ctx, "select pg_advisory_unlock($1)", relayLockKey)
+		if err != nil {
+			// the lock may still be held, closing the connection releases it
+			_ = conn.Hijack().Close(ctx)
+			return
+		}
+		conn.Release()
+	}, true, nil
+}
+
+func (d *dao) Unpublished(ctx context.Context, limit int) (events []eventsmodel.Events, err error) {
+	ctx, span := tracer.Start(ctx, "outboxDao.Unpublished")
+	defer func() { tracing.End(span, err) }()
+
+	// transactions that began before the oldest one still running have all
+	// ended, their events can't be followed by earlier ones
+	sqlStatement := selectEvents + ` o where published_at is null and parked_at is null
+		and xid < pg_snapshot_xmin(pg_current_snapshot())
+		and not exists (
+			select 1 from outbox r
+			where r.tenant_id = o.tenant_id and r.account_id = o.account_id
+				and r.published_at is null and r.parked_at is null and r.next_attempt_at > now()
+				and (r.xid, r.id) <= (o.xid, o.id)
+		)
+		order by xid, id limit $1`
+	rows, err := d.dbPool.Query(ctx, sqlStatement, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanEvent)
+}
+
+func (d *dao) MarkPublished(ctx context.Context, ids []int64) (err error) {
+	ctx, span := tracer.Start(ctx, "outboxDao.MarkPublished", trace.WithAttributes(attribute.Int("event.count", len(ids))))
+	defer func() { tracing.End(span, err) }()
+
+	_, err = d.dbPool.Exec(ctx, "update outbox set published_at=now(), next_attempt_at=null where id = any($1)", ids)
+
+	return err
+}
+
+func (d *dao) Failed(ctx context.Context, id int64, message string, retryAt time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "outboxDao.Failed", trace.WithAttributes(attribute.Int64("event.id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	_, err = d.dbPool.Exec(ctx, "update outbox set attempts=attempts+1, last_error=$2, next_attempt_at=$3 where id=$1", id, message, retryAt)
+
+	return err
+}
+
+func (d *dao) Park(ctx context.Context, id int64, message string) (err error) {
+	ctx, span := tracer.Start(ctx, "outboxDao.Park", trace.WithAttributes(attribute.Int64("event.id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	_, err = d.dbPool.Exec(ctx, "update outbox set attempts=attempts+1, last_error=$2, next_attempt_at=null, parked_at=now() where id=$1", id, message)
+
+	return err
+}
+
+func (d *dao) ListByAccount(ctx context.Context, accountId int64, after eventsmodel.Cursor, limit int) (events []eventsmodel.Events, err error) {
+	ctx, span := tracer.Start(ctx, "outboxDao.ListByAccount", trace.WithAttributes(attribute.Int64("account.id", accountId)))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStatement := selectEvents + ` where tenant_id=$1 and account_id=$2
+		and (xid, id) > ($3::text::xid8, $4) and xid < pg_snapshot_xmin(pg_current_snapshot())
+		order by xid, id limit $5`
+	rows, err := d.dbPool.Query(ctx, sqlStatement, tenantId, accountId, strconv.FormatUint(after.Xid, 10), after.Id, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanEvent)
+}
+
+func (d *dao) Horizon(ctx context.Context) (cursor eventsmodel.Cursor, err error) {
+	ctx, span := tracer.Start(ctx, "outboxDao.Horizon")
+	defer func() { tracing.End(span, err) }()
+
+	var horizon string
+	err = d.dbPool.QueryRow(ctx, "select pg_snapshot_xmin(pg_current_snapshot())::text").Scan(&horizon)
+	if err != nil {
+		return cursor, err
+	}
+
+	// ids start at 1, every event of the horizon comes after it
+	cursor.Xid, err = strconv.ParseUint(horizon, 10, 64)
+
+	return cursor, err
+}
+
+func scanEvent(row pgx.CollectableRow) (event eventsmodel.Events, err error) {
+	var id, accountId int64
+	var tenantId, eventType, xid string
+	var payload []byte
+	var createdAt time.Time
+	var attempts int
+
+	err = row.Scan(&id, &tenantId, &eventType, &accountId, &payload, &createdAt, &xid, &attempts)
+	if err != nil {
+		return event, err
+	}
+	transaction, err := strconv.ParseUint(xid, 10, 64)
+	if err != nil {
+		return event, err
+	}
+
+	event.SetId(id)
+	event.SetTenantId(tenantId)
+	event.SetType(eventType)
+	event.SetAccountId(accountId)
+	event.SetPayload(payload)
+	event.SetCreatedAt(createdAt)
+	event.SetXid(transaction)
+	event.SetAttempts(attempts)
+
+	return event, nil
+}
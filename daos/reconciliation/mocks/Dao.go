@@ -0,0 +1,260 @@
+// Code generated by mockery v2.43.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	pgx "github.com/jackc/pgx/v5"
+	mock "github.com/stretchr/testify/mock"
+
+	reconciliation "github.com/ashwin-m/transactions/daos/reconciliation"
+)
+
+// Dao is an autogenerated mock type for the Dao type
+type Dao struct {
+	mock.Mock
+}
+
+type Dao_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Dao) EXPECT() *Dao_Expecter {
+	return &Dao_Expecter{mock: &_m.Mock}
+}
+
+// AccountTotals provides a mock function with given fields: ctx, tx
+func (_m *Dao) AccountTotals(ctx context.Context, tx pgx.Tx) ([]reconciliation.AccountTotals, error) {
+	ret := _m.Called(ctx, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AccountTotals")
+	}
+
+	var r0 []reconciliation.AccountTotals
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) ([]reconciliation.AccountTotals, error)); ok {
+		return rf(ctx, tx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) []reconciliation.AccountTotals); ok {
+		r0 = rf(ctx, tx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]reconciliation.AccountTotals)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx) error); ok {
+		r1 = rf(ctx, tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_AccountTotals_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AccountTotals'
+type Dao_AccountTotals_Call struct {
+	*mock.Call
+}
+
+// AccountTotals is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+func (_e *Dao_Expecter) AccountTotals(ctx interface{}, tx interface{}) *Dao_AccountTotals_Call {
+	return &Dao_AccountTotals_Call{Call: _e.mock.On("AccountTotals", ctx, tx)}
+}
+
+func (_c *Dao_AccountTotals_Call) Run(run func(ctx context.Context, tx pgx.Tx)) *Dao_AccountTotals_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx))
+	})
+	return _c
+}
+
+func (_c *Dao_AccountTotals_Call) Return(_a0 []reconciliation.AccountTotals, _a1 error) *Dao_AccountTotals_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_AccountTotals_Call) RunAndReturn(run func(context.Context, pgx.Tx) ([]reconciliation.AccountTotals, error)) *Dao_AccountTotals_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Lock provides a mock function with given fields: ctx, tx
+func (_m *Dao) Lock(ctx context.Context, tx pgx.Tx) (bool, error) {
+	ret := _m.Called(ctx, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Lock")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) (bool, error)); ok {
+		return rf(ctx, tx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) bool); ok {
+		r0 = rf(ctx, tx)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx) error); ok {
+		r1 = rf(ctx, tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Lock_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Lock'
+type Dao_Lock_Call struct {
+	*mock.Call
+}
+
+// Lock is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+func (_e *Dao_Expecter) Lock(ctx interface{}, tx interface{}) *Dao_Lock_Call {
+	return &Dao_Lock_Call{Call: _e.mock.On("Lock", ctx, tx)}
+}
+
+func (_c *Dao_Lock_Call) Run(run func(ctx context.Context, tx pgx.Tx)) *Dao_Lock_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx))
+	})
+	return _c
+}
+
+func (_c *Dao_Lock_Call) Return(_a0 bool, _a1 error) *Dao_Lock_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Lock_Call) RunAndReturn(run func(context.Context, pgx.Tx) (bool, error)) *Dao_Lock_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Snapshot provides a mock function with given fields: ctx, tx
+func (_m *Dao) Snapshot(ctx context.Context, tx pgx.Tx) error {
+	ret := _m.Called(ctx, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Snapshot")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx) error); ok {
+		r0 = rf(ctx, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Dao_Snapshot_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Snapshot'
+type Dao_Snapshot_Call struct {
+	*mock.Call
+}
+
+// Snapshot is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+func (_e *Dao_Expecter) Snapshot(ctx interface{}, tx interface{}) *Dao_Snapshot_Call {
+	return &Dao_Snapshot_Call{Call: _e.mock.On("Snapshot", ctx, tx)}
+}
+
+func (_c *Dao_Snapshot_Call) Run(run func(ctx context.Context, tx pgx.Tx)) *Dao_Snapshot_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx))
+	})
+	return _c
+}
+
+func (_c *Dao_Snapshot_Call) Return(_a0 error) *Dao_Snapshot_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Dao_Snapshot_Call) RunAndReturn(run func(context.Context, pgx.Tx) error) *Dao_Snapshot_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Totals provides a mock function with given fields: ctx, tx, tenants
+func (_m *Dao) Totals(ctx context.Context, tx pgx.Tx, tenants []string) (reconciliation.Totals, error) {
+	ret := _m.Called(ctx, tx, tenants)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Totals")
+	}
+
+	var r0 reconciliation.Totals
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, []string) (reconciliation.Totals, error)); ok {
+		return rf(ctx, tx, tenants)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, []string) reconciliation.Totals); ok {
+		r0 = rf(ctx, tx, tenants)
+	} else {
+		r0 = ret.Get(0).(reconciliation.Totals)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx, []string) error); ok {
+		r1 = rf(ctx, tx, tenants)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Totals_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Totals'
+type Dao_Totals_Call struct {
+	*mock.Call
+}
+
+// Totals is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+//   - tenants []string
+func (_e *Dao_Expecter) Totals(ctx interface{}, tx interface{}, tenants interface{}) *Dao_Totals_Call {
+	return &Dao_Totals_Call{Call: _e.mock.On("Totals", ctx, tx, tenants)}
+}
+
+func (_c *Dao_Totals_Call) Run(run func(ctx context.Context, tx pgx.Tx, tenants []string)) *Dao_Totals_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx), args[2].([]string))
+	})
+	return _c
+}
+
+func (_c *Dao_Totals_Call) Return(_a0 reconciliation.Totals, _a1 error) *Dao_Totals_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Totals_Call) RunAndReturn(run func(context.Context, pgx.Tx, []string) (reconciliation.Totals, error)) *Dao_Totals_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewDao creates a new instance of Dao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Dao {
+	mock := &Dao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,139 @@
+package reconciliation
+
+import (
+	"context"
+
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/ashwin-m/transactions/utils/tracing"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// lockKey is the advisory lock held while reconciling, so replicas take
+// turns rather than all reading the whole ledger at once.
+const lockKey = 7_340_004
+
+var tracer = otel.Tracer("github.com/ashwin-m/transactions/daos/reconciliation")
+
+// AccountTotals is an account with the sums of the transfers into and out
+// of it. InitialBalanceInferred is set for accounts that predate initial
+// balances, whose initial balance was inferred from their transfers.
+type AccountTotals struct {
+	Id                     int64
+	OwnerId                string
+	InitialBalance         float64
+	InitialBalanceInferred bool
+	Balance                float64
+	Credits                float64
+	Debits                 float64
+	Transfers              int64
+}
+
+// Totals sums the balances of a set of tenants, and the transfers crossing
+// its boundary.
+type Totals struct {
+	Balance        float64
+	InitialBalance float64
+	// InferredInitialBalance is the part of InitialBalance inferred from
+	// transfers.
+	InferredInitialBalance float64
+	// Inflow and Outflow are the transfers into and out of the set, from and
+	// to tenants outside it.
+	Inflow  float64
+	Outflow float64
+}
+
+//go:generate mockery --name=Dao --output=mocks --outpkg=mocks --with-expecter
+type Dao interface {
+	// Snapshot makes tx a read only transaction in which every query sees
+	// the same state of the ledger. It must come first in tx.
+	Snapshot(ctx context.Context, tx pgx.Tx) error
+	// Lock takes the reconciliation lock until tx ends. It returns false
+	// when another reconciliation holds it.
+	Lock(ctx context.Context, tx pgx.Tx) (bool, error)
+	// AccountTotals returns the accounts of the tenant on ctx in id order.
+	AccountTotals(ctx context.Context, tx pgx.Tx) ([]AccountTotals, error)
+	// Totals sums the accounts of tenants, which must all be visible to tx.
+	Totals(ctx context.Context, tx pgx.Tx, tenants []string) (Totals, error)
+}
+
+type dao struct {
+	dbPool *pgxpool.Pool
+}
+
+func NewDao(dbPool *pgxpool.Pool) Dao {
+	return &dao{
+		dbPool: dbPool,
+	}
+}
+
+func (d *dao) Snapshot(ctx context.Context, tx pgx.Tx) (err error) {
+	ctx, span := tracer.Start(ctx, "reconciliationDao.Snapshot")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = tx.Exec(ctx, "set transaction isolation level repeatable read read only")
+
+	return err
+}
+
+func (d *dao) Lock(ctx context.Context, tx pgx.Tx) (locked bool, err error) {
+	ctx, span := tracer.Start(ctx, "reconciliationDao.Lock")
+	defer func() { tracing.End(span, err) }()
+
+	err = tx.QueryRow(ctx, "select pg_try_advisory_xact_lock($1)", lockKey).Scan(&locked)
+
+	return locked, err
+}
+
+func (d *dao) AccountTotals(ctx context.Context, tx pgx.Tx) (accounts []AccountTotals, err error) {
+	ctx, span := tracer.Start(ctx, "reconciliationDao.AccountTotals")
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("tenant.id", tenantId))
+
+	sqlStatement := `with debits as (
+			select source_account_id as id, sum(amount) as total, count(*) as transfers
+			from transactions where tenant_id=$1 group by source_account_id
+		), credits as (
+			select destination_account_id as id, sum(amount) as total, count(*) as transfers
+			from transactions where destination_tenant_id=$1 group by destination_account_id
+		)
+		select a.id, coalesce(a.owner_id, ''), a.initial_balance, a.initial_balance_inferred, a.balance,
+			coalesce(c.total, 0), coalesce(d.total, 0), coalesce(c.transfers, 0) + coalesce(d.transfers, 0)
+		from accounts a
+		left join debits d on d.id = a.id
+		left join credits c on c.id = a.id
+		where a.tenant_id=$1
+		order by a.id`
+	rows, err := tx.Query(ctx, sqlStatement, tenantId)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (account AccountTotals, err error) {
+		err = row.Scan(&account.Id, &account.OwnerId, &account.InitialBalance, &account.InitialBalanceInferred, &account.Balance, &account.Credits, &account.Debits, &account.Transfers)
+		return account, err
+	})
+}
+
+func (d *dao) Totals(ctx context.Context, tx pgx.Tx, tenants []string) (totals Totals, err error) {
+	ctx, span := tracer.Start(ctx, "reconciliationDao.Totals", trace.WithAttributes(attribute.StringSlice("tenant.ids", tenants)))
+	defer func() { tracing.End(span, err) }()
+
+	sqlStatement := `select
+		(select coalesce(sum(balance), 0) from accounts where tenant_id = any($1)),
+		(select coalesce(sum(initial_balance), 0) from accounts where tenant_id = any($1)),
+		(select coalesce(sum(initial_balance), 0) from accounts where tenant_id = any($1) and initial_balance_inferred),
+		(select coalesce(sum(amount), 0) from transactions where destination_tenant_id = any($1) and not tenant_id = any($1)),
+		(select coalesce(sum(amount), 0) from transactions where tenant_id = any($1) and not destination_tenant_id = any($1))`
+	err = tx.QueryRow(ctx, sqlStatement, tenants).Scan(&totals.Balance, &totals.InitialBalance, &totals.InferredInitialBalance, &totals.Inflow, &totals.Outflow)
+
+	return totals, err
+}
@@ -3,8 +3,14 @@
 package mocks
 
 import (
+	context "context"
+
 	pgx "github.com/jackc/pgx/v5"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+
+	transactions "github.com/ashwin-m/transactions/models/transactions"
 )
 
 // Dao is an autogenerated mock type for the Dao type
@@ -20,9 +26,9 @@ func (_m *Dao) EXPECT() *Dao_Expecter {
 	return &Dao_Expecter{mock: &_m.Mock}
 }
 
-// Create provides a mock function with given fields: txn, sourceAccountId, destinationAccountId, amount
-func (_m *Dao) Create(txn pgx.Tx, sourceAccountId int64, destinationAccountId int64, amount float64) (int64, error) {
-	ret := _m.Called(txn, sourceAccountId, destinationAccountId, amount)
+// Create provides a mock function with given fields: ctx, txn, sourceAccountId, destinationAccountId, destinationTenantId, amount, clientId
+func (_m *Dao) Create(ctx context.Context, txn pgx.Tx, sourceAccountId int64, destinationAccountId int64, destinationTenantId string, amount float64, clientId string) (int64, error) {
+	ret := _m.Called(ctx, txn, sourceAccountId, destinationAccountId, destinationTenantId, amount, clientId)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Create")
@@ -30,17 +36,17 @@ func (_m *Dao) Create(txn pgx.Tx, sourceAccountId int64, destinationAccountId in
 
 	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(pgx.Tx, int64, int64, float64) (int64, error)); ok {
-		return rf(txn, sourceAccountId, destinationAccountId, amount)
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, int64, int64, string, float64, string) (int64, error)); ok {
+		return rf(ctx, txn, sourceAccountId, destinationAccountId, destinationTenantId, amount, clientId)
 	}
-	if rf, ok := ret.Get(0).(func(pgx.Tx, int64, int64, float64) int64); ok {
-		r0 = rf(txn, sourceAccountId, destinationAccountId, amount)
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, int64, int64, string, float64, string) int64); ok {
+		r0 = rf(ctx, txn, sourceAccountId, destinationAccountId, destinationTenantId, amount, clientId)
 	} else {
 		r0 = ret.Get(0).(int64)
 	}
 
-	if rf, ok := ret.Get(1).(func(pgx.Tx, int64, int64, float64) error); ok {
-		r1 = rf(txn, sourceAccountId, destinationAccountId, amount)
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx, int64, int64, string, float64, string) error); ok {
+		r1 = rf(ctx, txn, sourceAccountId, destinationAccountId, destinationTenantId, amount, clientId)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -54,17 +60,20 @@ type Dao_Create_Call struct {
 }
 
 // Create is a helper method to define mock.On call
+//   - ctx context.Context
 //   - txn pgx.Tx
 //   - sourceAccountId int64
 //   - destinationAccountId int64
+//   - destinationTenantId string
 //   - amount float64
-func (_e *Dao_Expecter) Create(txn interface{}, sourceAccountId interface{}, destinationAccountId interface{}, amount interface{}) *Dao_Create_Call {
-	return &Dao_Create_Call{Call: _e.mock.On("Create", txn, sourceAccountId, destinationAccountId, amount)}
+//   - clientId string
+func (_e *Dao_Expecter) Create(ctx interface{}, txn interface{}, sourceAccountId interface{}, destinationAccountId interface{}, destinationTenantId interface{}, amount interface{}, clientId interface{}) *Dao_Create_Call {
+	return &Dao_Create_Call{Call: _e.mock.On("Create", ctx, txn, sourceAccountId, destinationAccountId, destinationTenantId, amount, clientId)}
 }
 
-func (_c *Dao_Create_Call) Run(run func(txn pgx.Tx, sourceAccountId int64, destinationAccountId int64, amount float64)) *Dao_Create_Call {
+func (_c *Dao_Create_Call) Run(run func(ctx context.Context, txn pgx.Tx, sourceAccountId int64, destinationAccountId int64, destinationTenantId string, amount float64, clientId string)) *Dao_Create_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(pgx.Tx), args[1].(int64), args[2].(int64), args[3].(float64))
+		run(args[0].(context.Context), args[1].(pgx.Tx), args[2].(int64), args[3].(int64), args[4].(string), args[5].(float64), args[6].(string))
 	})
 	return _c
 }
@@ -74,7 +83,303 @@ func (_c *Dao_Create_Call) Return(_a0 int64, _a1 error) *Dao_Create_Call {
 	return _c
 }
 
-func (_c *Dao_Create_Call) RunAndReturn(run func(pgx.Tx, int64, int64, float64) (int64, error)) *Dao_Create_Call {
+func (_c *Dao_Create_Call) RunAndReturn(run func(context.Context, pgx.Tx, int64, int64, string, float64, string) (int64, error)) *Dao_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetById provides a mock function with given fields: ctx, id
+func (_m *Dao) GetById(ctx context.Context, id int64) (transactions.Transactions, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetById")
+	}
+
+	var r0 transactions.Transactions
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (transactions.Transactions, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) transactions.Transactions); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(transactions.Transactions)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_GetById_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetById'
+type Dao_GetById_Call struct {
+	*mock.Call
+}
+
+// GetById is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id int64
+func (_e *Dao_Expecter) GetById(ctx interface{}, id interface{}) *Dao_GetById_Call {
+	return &Dao_GetById_Call{Call: _e.mock.On("GetById", ctx, id)}
+}
+
+func (_c *Dao_GetById_Call) Run(run func(ctx context.Context, id int64)) *Dao_GetById_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int64))
+	})
+	return _c
+}
+
+func (_c *Dao_GetById_Call) Return(_a0 transactions.Transactions, _a1 error) *Dao_GetById_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_GetById_Call) RunAndReturn(run func(context.Context, int64) (transactions.Transactions, error)) *Dao_GetById_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListByAccount provides a mock function with given fields: ctx, accountId, beforeId, limit
+func (_m *Dao) ListByAccount(ctx context.Context, accountId int64, beforeId int64, limit int) ([]transactions.Transactions, error) {
+	ret := _m.Called(ctx, accountId, beforeId, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListByAccount")
+	}
+
+	var r0 []transactions.Transactions
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int) ([]transactions.Transactions, error)); ok {
+		return rf(ctx, accountId, beforeId, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int) []transactions.Transactions); ok {
+		r0 = rf(ctx, accountId, beforeId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]transactions.Transactions)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, int) error); ok {
+		r1 = rf(ctx, accountId, beforeId, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_ListByAccount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListByAccount'
+type Dao_ListByAccount_Call struct {
+	*mock.Call
+}
+
+// ListByAccount is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountId int64
+//   - beforeId int64
+//   - limit int
+func (_e *Dao_Expecter) ListByAccount(ctx interface{}, accountId interface{}, beforeId interface{}, limit interface{}) *Dao_ListByAccount_Call {
+	return &Dao_ListByAccount_Call{Call: _e.mock.On("ListByAccount", ctx, accountId, beforeId, limit)}
+}
+
+func (_c *Dao_ListByAccount_Call) Run(run func(ctx context.Context, accountId int64, beforeId int64, limit int)) *Dao_ListByAccount_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int64), args[2].(int64), args[3].(int))
+	})
+	return _c
+}
+
+func (_c *Dao_ListByAccount_Call) Return(_a0 []transactions.Transactions, _a1 error) *Dao_ListByAccount_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_ListByAccount_Call) RunAndReturn(run func(context.Context, int64, int64, int) ([]transactions.Transactions, error)) *Dao_ListByAccount_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListByAccountBetween provides a mock function with given fields: ctx, accountId, from, afterId, to, limit
+func (_m *Dao) ListByAccountBetween(ctx context.Context, accountId int64, from time.Time, afterId int64, to time.Time, limit int) ([]transactions.Transactions, error) {
+	ret := _m.Called(ctx, accountId, from, afterId, to, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListByAccountBetween")
+	}
+
+	var r0 []transactions.Transactions
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time, int64, time.Time, int) ([]transactions.Transactions, error)); ok {
+		return rf(ctx, accountId, from, afterId, to, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time, int64, time.Time, int) []transactions.Transactions); ok {
+		r0 = rf(ctx, accountId, from, afterId, to, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]transactions.Transactions)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, time.Time, int64, time.Time, int) error); ok {
+		r1 = rf(ctx, accountId, from, afterId, to, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_ListByAccountBetween_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListByAccountBetween'
+type Dao_ListByAccountBetween_Call struct {
+	*mock.Call
+}
+
+// ListByAccountBetween is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountId int64
+//   - from time.Time
+//   - afterId int64
+//   - to time.Time
+//   - limit int
+func (_e *Dao_Expecter) ListByAccountBetween(ctx interface{}, accountId interface{}, from interface{}, afterId interface{}, to interface{}, limit interface{}) *Dao_ListByAccountBetween_Call {
+	return &Dao_ListByAccountBetween_Call{Call: _e.mock.On("ListByAccountBetween", ctx, accountId, from, afterId, to, limit)}
+}
+
+func (_c *Dao_ListByAccountBetween_Call) Run(run func(ctx context.Context, accountId int64, from time.Time, afterId int64, to time.Time, limit int)) *Dao_ListByAccountBetween_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int64), args[2].(time.Time), args[3].(int64), args[4].(time.Time), args[5].(int))
+	})
+	return _c
+}
+
+func (_c *Dao_ListByAccountBetween_Call) Return(_a0 []transactions.Transactions, _a1 error) *Dao_ListByAccountBetween_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_ListByAccountBetween_Call) RunAndReturn(run func(context.Context, int64, time.Time, int64, time.Time, int) ([]transactions.Transactions, error)) *Dao_ListByAccountBetween_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NetByAccount provides a mock function with given fields: ctx, accountId, before
+func (_m *Dao) NetByAccount(ctx context.Context, accountId int64, before time.Time) (float64, error) {
+	ret := _m.Called(ctx, accountId, before)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NetByAccount")
+	}
+
+	var r0 float64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time) (float64, error)); ok {
+		return rf(ctx, accountId, before)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time) float64); ok {
+		r0 = rf(ctx, accountId, before)
+	} else {
+		r0 = ret.Get(0).(float64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, time.Time) error); ok {
+		r1 = rf(ctx, accountId, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_NetByAccount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'NetByAccount'
+type Dao_NetByAccount_Call struct {
+	*mock.Call
+}
+
+// NetByAccount is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountId int64
+//   - before time.Time
+func (_e *Dao_Expecter) NetByAccount(ctx interface{}, accountId interface{}, before interface{}) *Dao_NetByAccount_Call {
+	return &Dao_NetByAccount_Call{Call: _e.mock.On("NetByAccount", ctx, accountId, before)}
+}
+
+func (_c *Dao_NetByAccount_Call) Run(run func(ctx context.Context, accountId int64, before time.Time)) *Dao_NetByAccount_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int64), args[2].(time.Time))
+	})
+	return _c
+}
+
+func (_c *Dao_NetByAccount_Call) Return(_a0 float64, _a1 error) *Dao_NetByAccount_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_NetByAccount_Call) RunAndReturn(run func(context.Context, int64, time.Time) (float64, error)) *Dao_NetByAccount_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Undated provides a mock function with given fields: ctx, accountId
+func (_m *Dao) Undated(ctx context.Context, accountId int64) (time.Time, error) {
+	ret := _m.Called(ctx, accountId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Undated")
+	}
+
+	var r0 time.Time
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (time.Time, error)); ok {
+		return rf(ctx, accountId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) time.Time); ok {
+		r0 = rf(ctx, accountId)
+	} else {
+		r0 = ret.Get(0).(time.Time)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, accountId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Undated_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Undated'
+type Dao_Undated_Call struct {
+	*mock.Call
+}
+
+// Undated is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountId int64
+func (_e *Dao_Expecter) Undated(ctx interface{}, accountId interface{}) *Dao_Undated_Call {
+	return &Dao_Undated_Call{Call: _e.mock.On("Undated", ctx, accountId)}
+}
+
+func (_c *Dao_Undated_Call) Run(run func(ctx context.Context, accountId int64)) *Dao_Undated_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int64))
+	})
+	return _c
+}
+
+func (_c *Dao_Undated_Call) Return(_a0 time.Time, _a1 error) *Dao_Undated_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Undated_Call) RunAndReturn(run func(context.Context, int64) (time.Time, error)) *Dao_Undated_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -2,14 +2,29 @@ package transactionledger
 
 import (
 	"context"
+	"time"
 
+	transactionsmodel "github.com/ashwin-m/transactions/models/transactions"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/ashwin-m/transactions/utils/tracing"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/ashwin-m/transactions/daos/transactions")
+
 //go:generate mockery --name=Dao --output=mocks --outpkg=mocks --with-expecter
 type Dao interface {
-	Create(txn pgx.Tx, sourceAccountId, destinationAccountId int64, amount float64) (int64, error)
+	Create(ctx context.Context, txn pgx.Tx, sourceAccountId, destinationAccountId int64, destinationTenantId string, amount float64, clientId string) (int64, error)
+	GetById(ctx context.Context, id int64) (transactionsmodel.Transactions, error)
+	ListByAccount(ctx context.Context, accountId, beforeId int64, limit int) ([]transactionsmodel.Transactions, error)
+	ListByAccountBetween(ctx context.Context, accountId int64, from time.Time, afterId int64, to time.Time, limit int) ([]transactionsmodel.Transactions, error)
+	NetByAccount(ctx context.Context, accountId int64, before time.Time) (float64, error)
+	Undated(ctx context.Context, accountId int64) (time.Time, error)
 }
 
 type dao struct {
@@ -22,10 +37,156 @@ func NewDao(dbPool *pgxpool.Pool) Dao {
 	}
 }
 
-func (d *dao) Create(txn pgx.Tx, sourceAccountId, destinationAccountId int64, amount float64) (int64, error) {
-	var transactionId int64
-	sqlStatement := "insert into transactions(source_account_id, destination_account_id, amount) values ($1, $2, $3)"
-	err := txn.QueryRow(context.Background(), sqlStatement, sourceAccountId, destinationAccountId, amount).Scan(&transactionId)
+// Create records a transfer out of an account of the tenant on ctx.
+func (d *dao) Create(ctx context.Context, txn pgx.Tx, sourceAccountId, destinationAccountId int64, destinationTenantId string, amount float64, clientId string) (transactionId int64, err error) {
+	ctx, span := tracer.Start(ctx, "transactionsDao.Create", trace.WithAttributes(
+		attribute.Int64("transaction.source_account_id", sourceAccountId),
+		attribute.Int64("transaction.destination_account_id", destinationAccountId),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// transactions made without an authenticated client store a null client_id
+	client := pgtype.Text{String: clientId, Valid: clientId != ""}
+
+	sqlStatement := "insert into transactions(tenant_id, source_account_id, destination_tenant_id, destination_account_id, amount, client_id) values ($1, $2, $3, $4, $5, $6) returning id"
+	err = txn.QueryRow(ctx, sqlStatement, tenantId, sourceAccountId, destinationTenantId, destinationAccountId, amount, client).Scan(&transactionId)
 
 	return transactionId, err
 }
+
+const selectTransactions = "select id, tenant_id, source_account_id, destination_tenant_id, destination_account_id, amount, client_id, created_at from transactions"
+
+// GetById returns a transfer made by or to the tenant on ctx.
+func (d *dao) GetById(ctx context.Context, id int64) (transaction transactionsmodel.Transactions, err error) {
+	ctx, span := tracer.Start(ctx, "transactionsDao.GetById", trace.WithAttributes(attribute.Int64("transaction.id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return transaction, err
+	}
+
+	sqlStatement := selectTransactions + " where (tenant_id=$1 or destination_tenant_id=$1) and id=$2"
+	rows, err := d.dbPool.Query(ctx, sqlStatement, tenantId, id)
+	if err != nil {
+		return transaction, err
+	}
+
+	return pgx.CollectExactlyOneRow(rows, scanTransaction)
+}
+
+// ListByAccount returns up to limit transfers into or out of an account of
+// the tenant on ctx, newest first. Only transfers with an id below beforeId
+// are returned, unless it is 0.
+func (d *dao) ListByAccount(ctx context.Context, accountId, beforeId int64, limit int) (transactions []transactionsmodel.Transactions, err error) {
+	ctx, span := tracer.Start(ctx, "transactionsDao.ListByAccount", trace.WithAttributes(attribute.Int64("account.id", accountId)))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStatement := selectTransactions + " where ((tenant_id=$1 and source_account_id=$2) or (destination_tenant_id=$1 and destination_account_id=$2)) and ($3 = 0 or id < $3) order by id desc limit $4"
+	rows, err := d.dbPool.Query(ctx, sqlStatement, tenantId, accountId, beforeId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanTransaction)
+}
+
+// ListByAccountBetween returns at most limit transfers into or out of an
+// account of the tenant on ctx made from from until to, excluded, oldest
+// first. Of those made at from, only the ones after afterId are returned,
+// so the last transfer of a page starts the next one.
+func (d *dao) ListByAccountBetween(ctx context.Context, accountId int64, from time.Time, afterId int64, to time.Time, limit int) (transactions []transactionsmodel.Transactions, err error) {
+	ctx, span := tracer.Start(ctx, "transactionsDao.ListByAccountBetween", trace.WithAttributes(attribute.Int64("account.id", accountId)))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStatement := selectTransactions + " where ((tenant_id=$1 and source_account_id=$2) or (destination_tenant_id=$1 and destination_account_id=$2)) and (created_at, id) > ($3, $4) and created_at < $5 order by created_at, id limit $6"
+	rows, err := d.dbPool.Query(ctx, sqlStatement, tenantId, accountId, from, afterId, to, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanTransaction)
+}
+
+// NetByAccount returns what the transfers made before before added to the
+// balance of an account of the tenant on ctx, those into it less those out
+// of it.
+func (d *dao) NetByAccount(ctx context.Context, accountId int64, before time.Time) (net float64, err error) {
+	ctx, span := tracer.Start(ctx, "transactionsDao.NetByAccount", trace.WithAttributes(attribute.Int64("account.id", accountId)))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	sqlStatement := `select
+		coalesce(sum(amount) filter (where destination_tenant_id=$1 and destination_account_id=$2), 0)
+		- coalesce(sum(amount) filter (where tenant_id=$1 and source_account_id=$2), 0)
+		from transactions
+		where ((tenant_id=$1 and source_account_id=$2) or (destination_tenant_id=$1 and destination_account_id=$2)) and created_at < $3`
+	err = d.dbPool.QueryRow(ctx, sqlStatement, tenantId, accountId, before).Scan(&net)
+
+	return net, err
+}
+
+// Undated returns the time the transfers into or out of an account of the
+// tenant on ctx made before transfers were dated were given when they were,
+// by schema version 5. It returns the zero time when the account has none.
+func (d *dao) Undated(ctx context.Context, accountId int64) (undated time.Time, err error) {
+	ctx, span := tracer.Start(ctx, "transactionsDao.Undated", trace.WithAttributes(attribute.Int64("account.id", accountId)))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// the migration dated them all before recording itself
+	sqlStatement := `select max(created_at) from transactions
+		where ((tenant_id=$1 and source_account_id=$2) or (destination_tenant_id=$1 and destination_account_id=$2))
+		and created_at <= (select applied_at from schema_migrations where version = 5)`
+	var createdAt pgtype.Timestamptz
+	err = d.dbPool.QueryRow(ctx, sqlStatement, tenantId, accountId).Scan(&createdAt)
+
+	return createdAt.Time, err
+}
+
+func scanTransaction(row pgx.CollectableRow) (transaction transactionsmodel.Transactions, err error) {
+	var id, sourceAccountId, destinationAccountId int64
+	var tenantId, destinationTenantId string
+	var amount float64
+	var clientId pgtype.Text
+	var createdAt time.Time
+
+	err = row.Scan(&id, &tenantId, &sourceAccountId, &destinationTenantId, &destinationAccountId, &amount, &clientId, &createdAt)
+	if err != nil {
+		return transaction, err
+	}
+
+	transaction.SetId(id)
+	transaction.SetTenantId(tenantId)
+	transaction.SetSourceAccountId(sourceAccountId)
+	transaction.SetDestinationTenantId(destinationTenantId)
+	transaction.SetDestinationAccountId(destinationAccountId)
+	transaction.SetAmount(amount)
+	transaction.SetClientId(clientId.String)
+	transaction.SetCreatedAt(createdAt)
+
+	return transaction, nil
+}
@@ -0,0 +1,667 @@
+// Code generated by mockery v2.43.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	daoswebhooks "github.com/ashwin-m/transactions/daos/webhooks"
+	mock "github.com/stretchr/testify/mock"
+
+	pgx "github.com/jackc/pgx/v5"
+
+	webhooks "github.com/ashwin-m/transactions/models/webhooks"
+)
+
+// Dao is an autogenerated mock type for the Dao type
+type Dao struct {
+	mock.Mock
+}
+
+type Dao_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Dao) EXPECT() *Dao_Expecter {
+	return &Dao_Expecter{mock: &_m.Mock}
+}
+
+// Create provides a mock function with given fields: ctx, subscription
+func (_m *Dao) Create(ctx context.Context, subscription webhooks.Subscriptions) (webhooks.Subscriptions, error) {
+	ret := _m.Called(ctx, subscription)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 webhooks.Subscriptions
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, webhooks.Subscriptions) (webhooks.Subscriptions, error)); ok {
+		return rf(ctx, subscription)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, webhooks.Subscriptions) webhooks.Subscriptions); ok {
+		r0 = rf(ctx, subscription)
+	} else {
+		r0 = ret.Get(0).(webhooks.Subscriptions)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, webhooks.Subscriptions) error); ok {
+		r1 = rf(ctx, subscription)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type Dao_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - subscription webhooks.Subscriptions
+func (_e *Dao_Expecter) Create(ctx interface{}, subscription interface{}) *Dao_Create_Call {
+	return &Dao_Create_Call{Call: _e.mock.On("Create", ctx, subscription)}
+}
+
+func (_c *Dao_Create_Call) Run(run func(ctx context.Context, subscription webhooks.Subscriptions)) *Dao_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(webhooks.Subscriptions))
+	})
+	return _c
+}
+
+func (_c *Dao_Create_Call) Return(_a0 webhooks.Subscriptions, _a1 error) *Dao_Create_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Create_Call) RunAndReturn(run func(context.Context, webhooks.Subscriptions) (webhooks.Subscriptions, error)) *Dao_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Delete provides a mock function with given fields: ctx, id
+func (_m *Dao) Delete(ctx context.Context, id int64) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Dao_Delete_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Delete'
+type Dao_Delete_Call struct {
+	*mock.Call
+}
+
+// Delete is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id int64
+func (_e *Dao_Expecter) Delete(ctx interface{}, id interface{}) *Dao_Delete_Call {
+	return &Dao_Delete_Call{Call: _e.mock.On("Delete", ctx, id)}
+}
+
+func (_c *Dao_Delete_Call) Run(run func(ctx context.Context, id int64)) *Dao_Delete_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int64))
+	})
+	return _c
+}
+
+func (_c *Dao_Delete_Call) Return(_a0 error) *Dao_Delete_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Dao_Delete_Call) RunAndReturn(run func(context.Context, int64) error) *Dao_Delete_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Due provides a mock function with given fields: ctx, tx, limit
+func (_m *Dao) Due(ctx context.Context, tx pgx.Tx, limit int) ([]daoswebhooks.Pending, error) {
+	ret := _m.Called(ctx, tx, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Due")
+	}
+
+	var r0 []daoswebhooks.Pending
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, int) ([]daoswebhooks.Pending, error)); ok {
+		return rf(ctx, tx, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, int) []daoswebhooks.Pending); ok {
+		r0 = rf(ctx, tx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]daoswebhooks.Pending)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, pgx.Tx, int) error); ok {
+		r1 = rf(ctx, tx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Due_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Due'
+type Dao_Due_Call struct {
+	*mock.Call
+}
+
+// Due is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+//   - limit int
+func (_e *Dao_Expecter) Due(ctx interface{}, tx interface{}, limit interface{}) *Dao_Due_Call {
+	return &Dao_Due_Call{Call: _e.mock.On("Due", ctx, tx, limit)}
+}
+
+func (_c *Dao_Due_Call) Run(run func(ctx context.Context, tx pgx.Tx, limit int)) *Dao_Due_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *Dao_Due_Call) Return(_a0 []daoswebhooks.Pending, _a1 error) *Dao_Due_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Due_Call) RunAndReturn(run func(context.Context, pgx.Tx, int) ([]daoswebhooks.Pending, error)) *Dao_Due_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Enqueue provides a mock function with given fields: ctx, eventId, eventType, accountId, payload
+func (_m *Dao) Enqueue(ctx context.Context, eventId int64, eventType string, accountId int64, payload []byte) (int64, error) {
+	ret := _m.Called(ctx, eventId, eventType, accountId, payload)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Enqueue")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, int64, []byte) (int64, error)); ok {
+		return rf(ctx, eventId, eventType, accountId, payload)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, int64, []byte) int64); ok {
+		r0 = rf(ctx, eventId, eventType, accountId, payload)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, int64, []byte) error); ok {
+		r1 = rf(ctx, eventId, eventType, accountId, payload)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Enqueue_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Enqueue'
+type Dao_Enqueue_Call struct {
+	*mock.Call
+}
+
+// Enqueue is a helper method to define mock.On call
+//   - ctx context.Context
+//   - eventId int64
+//   - eventType string
+//   - accountId int64
+//   - payload []byte
+func (_e *Dao_Expecter) Enqueue(ctx interface{}, eventId interface{}, eventType interface{}, accountId interface{}, payload interface{}) *Dao_Enqueue_Call {
+	return &Dao_Enqueue_Call{Call: _e.mock.On("Enqueue", ctx, eventId, eventType, accountId, payload)}
+}
+
+func (_c *Dao_Enqueue_Call) Run(run func(ctx context.Context, eventId int64, eventType string, accountId int64, payload []byte)) *Dao_Enqueue_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int64), args[2].(string), args[3].(int64), args[4].([]byte))
+	})
+	return _c
+}
+
+func (_c *Dao_Enqueue_Call) Return(_a0 int64, _a1 error) *Dao_Enqueue_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Enqueue_Call) RunAndReturn(run func(context.Context, int64, string, int64, []byte) (int64, error)) *Dao_Enqueue_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetById provides a mock function with given fields: ctx, id
+func (_m *Dao) GetById(ctx context.Context, id int64) (webhooks.Subscriptions, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetById")
+	}
+
+	var r0 webhooks.Subscriptions
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (webhooks.Subscriptions, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) webhooks.Subscriptions); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(webhooks.Subscriptions)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_GetById_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetById'
+type Dao_GetById_Call struct {
+	*mock.Call
+}
+
+// GetById is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id int64
+func (_e *Dao_Expecter) GetById(ctx interface{}, id interface{}) *Dao_GetById_Call {
+	return &Dao_GetById_Call{Call: _e.mock.On("GetById", ctx, id)}
+}
+
+func (_c *Dao_GetById_Call) Run(run func(ctx context.Context, id int64)) *Dao_GetById_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int64))
+	})
+	return _c
+}
+
+func (_c *Dao_GetById_Call) Return(_a0 webhooks.Subscriptions, _a1 error) *Dao_GetById_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_GetById_Call) RunAndReturn(run func(context.Context, int64) (webhooks.Subscriptions, error)) *Dao_GetById_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetDelivery provides a mock function with given fields: ctx, subscriptionId, id
+func (_m *Dao) GetDelivery(ctx context.Context, subscriptionId int64, id int64) (webhooks.Deliveries, error) {
+	ret := _m.Called(ctx, subscriptionId, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDelivery")
+	}
+
+	var r0 webhooks.Deliveries
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (webhooks.Deliveries, error)); ok {
+		return rf(ctx, subscriptionId, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) webhooks.Deliveries); ok {
+		r0 = rf(ctx, subscriptionId, id)
+	} else {
+		r0 = ret.Get(0).(webhooks.Deliveries)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, subscriptionId, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_GetDelivery_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetDelivery'
+type Dao_GetDelivery_Call struct {
+	*mock.Call
+}
+
+// GetDelivery is a helper method to define mock.On call
+//   - ctx context.Context
+//   - subscriptionId int64
+//   - id int64
+func (_e *Dao_Expecter) GetDelivery(ctx interface{}, subscriptionId interface{}, id interface{}) *Dao_GetDelivery_Call {
+	return &Dao_GetDelivery_Call{Call: _e.mock.On("GetDelivery", ctx, subscriptionId, id)}
+}
+
+func (_c *Dao_GetDelivery_Call) Run(run func(ctx context.Context, subscriptionId int64, id int64)) *Dao_GetDelivery_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int64), args[2].(int64))
+	})
+	return _c
+}
+
+func (_c *Dao_GetDelivery_Call) Return(_a0 webhooks.Deliveries, _a1 error) *Dao_GetDelivery_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_GetDelivery_Call) RunAndReturn(run func(context.Context, int64, int64) (webhooks.Deliveries, error)) *Dao_GetDelivery_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// List provides a mock function with given fields: ctx, clientId
+func (_m *Dao) List(ctx context.Context, clientId string) ([]webhooks.Subscriptions, error) {
+	ret := _m.Called(ctx, clientId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 []webhooks.Subscriptions
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]webhooks.Subscriptions, error)); ok {
+		return rf(ctx, clientId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []webhooks.Subscriptions); ok {
+		r0 = rf(ctx, clientId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]webhooks.Subscriptions)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, clientId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_List_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'List'
+type Dao_List_Call struct {
+	*mock.Call
+}
+
+// List is a helper method to define mock.On call
+//   - ctx context.Context
+//   - clientId string
+func (_e *Dao_Expecter) List(ctx interface{}, clientId interface{}) *Dao_List_Call {
+	return &Dao_List_Call{Call: _e.mock.On("List", ctx, clientId)}
+}
+
+func (_c *Dao_List_Call) Run(run func(ctx context.Context, clientId string)) *Dao_List_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Dao_List_Call) Return(_a0 []webhooks.Subscriptions, _a1 error) *Dao_List_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_List_Call) RunAndReturn(run func(context.Context, string) ([]webhooks.Subscriptions, error)) *Dao_List_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListAttempts provides a mock function with given fields: ctx, deliveryId
+func (_m *Dao) ListAttempts(ctx context.Context, deliveryId int64) ([]webhooks.Attempts, error) {
+	ret := _m.Called(ctx, deliveryId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAttempts")
+	}
+
+	var r0 []webhooks.Attempts
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]webhooks.Attempts, error)); ok {
+		return rf(ctx, deliveryId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []webhooks.Attempts); ok {
+		r0 = rf(ctx, deliveryId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]webhooks.Attempts)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, deliveryId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_ListAttempts_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListAttempts'
+type Dao_ListAttempts_Call struct {
+	*mock.Call
+}
+
+// ListAttempts is a helper method to define mock.On call
+//   - ctx context.Context
+//   - deliveryId int64
+func (_e *Dao_Expecter) ListAttempts(ctx interface{}, deliveryId interface{}) *Dao_ListAttempts_Call {
+	return &Dao_ListAttempts_Call{Call: _e.mock.On("ListAttempts", ctx, deliveryId)}
+}
+
+func (_c *Dao_ListAttempts_Call) Run(run func(ctx context.Context, deliveryId int64)) *Dao_ListAttempts_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int64))
+	})
+	return _c
+}
+
+func (_c *Dao_ListAttempts_Call) Return(_a0 []webhooks.Attempts, _a1 error) *Dao_ListAttempts_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_ListAttempts_Call) RunAndReturn(run func(context.Context, int64) ([]webhooks.Attempts, error)) *Dao_ListAttempts_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListDeliveries provides a mock function with given fields: ctx, subscriptionId, status, beforeId, limit
+func (_m *Dao) ListDeliveries(ctx context.Context, subscriptionId int64, status string, beforeId int64, limit int) ([]webhooks.Deliveries, error) {
+	ret := _m.Called(ctx, subscriptionId, status, beforeId, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDeliveries")
+	}
+
+	var r0 []webhooks.Deliveries
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, int64, int) ([]webhooks.Deliveries, error)); ok {
+		return rf(ctx, subscriptionId, status, beforeId, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, int64, int) []webhooks.Deliveries); ok {
+		r0 = rf(ctx, subscriptionId, status, beforeId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]webhooks.Deliveries)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, int64, int) error); ok {
+		r1 = rf(ctx, subscriptionId, status, beforeId, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_ListDeliveries_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListDeliveries'
+type Dao_ListDeliveries_Call struct {
+	*mock.Call
+}
+
+// ListDeliveries is a helper method to define mock.On call
+//   - ctx context.Context
+//   - subscriptionId int64
+//   - status string
+//   - beforeId int64
+//   - limit int
+func (_e *Dao_Expecter) ListDeliveries(ctx interface{}, subscriptionId interface{}, status interface{}, beforeId interface{}, limit interface{}) *Dao_ListDeliveries_Call {
+	return &Dao_ListDeliveries_Call{Call: _e.mock.On("ListDeliveries", ctx, subscriptionId, status, beforeId, limit)}
+}
+
+func (_c *Dao_ListDeliveries_Call) Run(run func(ctx context.Context, subscriptionId int64, status string, beforeId int64, limit int)) *Dao_ListDeliveries_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int64), args[2].(string), args[3].(int64), args[4].(int))
+	})
+	return _c
+}
+
+func (_c *Dao_ListDeliveries_Call) Return(_a0 []webhooks.Deliveries, _a1 error) *Dao_ListDeliveries_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_ListDeliveries_Call) RunAndReturn(run func(context.Context, int64, string, int64, int) ([]webhooks.Deliveries, error)) *Dao_ListDeliveries_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RecordAttempt provides a mock function with given fields: ctx, tx, delivery, attempt
+func (_m *Dao) RecordAttempt(ctx context.Context, tx pgx.Tx, delivery webhooks.Deliveries, attempt webhooks.Attempts) error {
+	ret := _m.Called(ctx, tx, delivery, attempt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordAttempt")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.Tx, webhooks.Deliveries, webhooks.Attempts) error); ok {
+		r0 = rf(ctx, tx, delivery, attempt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Dao_RecordAttempt_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RecordAttempt'
+type Dao_RecordAttempt_Call struct {
+	*mock.Call
+}
+
+// RecordAttempt is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tx pgx.Tx
+//   - delivery webhooks.Deliveries
+//   - attempt webhooks.Attempts
+func (_e *Dao_Expecter) RecordAttempt(ctx interface{}, tx interface{}, delivery interface{}, attempt interface{}) *Dao_RecordAttempt_Call {
+	return &Dao_RecordAttempt_Call{Call: _e.mock.On("RecordAttempt", ctx, tx, delivery, attempt)}
+}
+
+func (_c *Dao_RecordAttempt_Call) Run(run func(ctx context.Context, tx pgx.Tx, delivery webhooks.Deliveries, attempt webhooks.Attempts)) *Dao_RecordAttempt_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(pgx.Tx), args[2].(webhooks.Deliveries), args[3].(webhooks.Attempts))
+	})
+	return _c
+}
+
+func (_c *Dao_RecordAttempt_Call) Return(_a0 error) *Dao_RecordAttempt_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Dao_RecordAttempt_Call) RunAndReturn(run func(context.Context, pgx.Tx, webhooks.Deliveries, webhooks.Attempts) error) *Dao_RecordAttempt_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Redeliver provides a mock function with given fields: ctx, subscriptionId, id
+func (_m *Dao) Redeliver(ctx context.Context, subscriptionId int64, id int64) (webhooks.Deliveries, error) {
+	ret := _m.Called(ctx, subscriptionId, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Redeliver")
+	}
+
+	var r0 webhooks.Deliveries
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (webhooks.Deliveries, error)); ok {
+		return rf(ctx, subscriptionId, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) webhooks.Deliveries); ok {
+		r0 = rf(ctx, subscriptionId, id)
+	} else {
+		r0 = ret.Get(0).(webhooks.Deliveries)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, subscriptionId, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Dao_Redeliver_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Redeliver'
+type Dao_Redeliver_Call struct {
+	*mock.Call
+}
+
+// Redeliver is a helper method to define mock.On call
+//   - ctx context.Context
+//   - subscriptionId int64
+//   - id int64
+func (_e *Dao_Expecter) Redeliver(ctx interface{}, subscriptionId interface{}, id interface{}) *Dao_Redeliver_Call {
+	return &Dao_Redeliver_Call{Call: _e.mock.On("Redeliver", ctx, subscriptionId, id)}
+}
+
+func (_c *Dao_Redeliver_Call) Run(run func(ctx context.Context, subscriptionId int64, id int64)) *Dao_Redeliver_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int64), args[2].(int64))
+	})
+	return _c
+}
+
+func (_c *Dao_Redeliver_Call) Return(_a0 webhooks.Deliveries, _a1 error) *Dao_Redeliver_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Dao_Redeliver_Call) RunAndReturn(run func(context.Context, int64, int64) (webhooks.Deliveries, error)) *Dao_Redeliver_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewDao creates a new instance of Dao. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDao(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Dao {
+	mock := &Dao{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
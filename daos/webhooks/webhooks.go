@@ -0,0 +1,385 @@
+package webhooks
+
+import (
+	"context"
+	"time"
+
+	webhooksmodel "github.com/ashwin-m/transactions/models/webhooks"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/ashwin-m/transactions/utils/tracing"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/ashwin-m/transactions/daos/webhooks")
+
+// Pending is a delivery due for an attempt, with the subscription it is sent
+// to.
+type Pending struct {
+	Delivery     webhooksmodel.Deliveries
+	Subscription webhooksmodel.Subscriptions
+}
+
+//go:generate mockery --name=Dao --output=mocks --outpkg=mocks --with-expecter
+type Dao interface {
+	// Create stores a subscription of the tenant on ctx.
+	Create(ctx context.Context, subscription webhooksmodel.Subscriptions) (webhooksmodel.Subscriptions, error)
+	GetById(ctx context.Context, id int64) (webhooksmodel.Subscriptions, error)
+	// List returns the subscriptions of the tenant on ctx created by
+	// clientId, or by anyone when it is empty.
+	List(ctx context.Context, clientId string) ([]webhooksmodel.Subscriptions, error)
+	Delete(ctx context.Context, id int64) error
+
+	// Enqueue creates a delivery of an event about an account of the tenant
+	// on ctx for every subscription it matches, and returns how many were
+	// created. Enqueuing an event again creates no new deliveries.
+	Enqueue(ctx context.Context, eventId int64, eventType string, accountId int64, payload []byte) (int64, error)
+	// Due locks up to limit pending deliveries of every tenant whose next
+	// attempt is due, until tx ends. Deliveries locked by another
+	// transaction are skipped.
+	Due(ctx context.Context, tx pgx.Tx, limit int) ([]Pending, error)
+	// RecordAttempt logs an attempt at a delivery and saves its new state.
+	RecordAttempt(ctx context.Context, tx pgx.Tx, delivery webhooksmodel.Deliveries, attempt webhooksmodel.Attempts) error
+
+	// ListDeliveries returns up to limit deliveries of a subscription,
+	// newest first, only those with status unless it is empty and only
+	// those with an id below beforeId unless it is 0.
+	ListDeliveries(ctx context.Context, subscriptionId int64, status string, beforeId int64, limit int) ([]webhooksmodel.Deliveries, error)
+	GetDelivery(ctx context.Context, subscriptionId, id int64) (webhooksmodel.Deliveries, error)
+	// ListAttempts returns the attempts at a delivery, oldest first.
+	ListAttempts(ctx context.Context, deliveryId int64) ([]webhooksmodel.Attempts, error)
+	// Redeliver makes a delivery pending again, with a fresh set of
+	// attempts starting right away.
+	Redeliver(ctx context.Context, subscriptionId, id int64) (webhooksmodel.Deliveries, error)
+}
+
+type dao struct {
+	dbPool *pgxpool.Pool
+}
+
+func NewDao(dbPool *pgxpool.Pool) Dao {
+	return &dao{
+		dbPool: dbPool,
+	}
+}
+
+const selectSubscriptions = "select id, tenant_id, client_id, owner_id, url, secret, event_types, created_at from webhook_subscriptions"
+
+func (d *dao) Create(ctx context.Context, subscription webhooksmodel.Subscriptions) (created webhooksmodel.Subscriptions, err error) {
+	ctx, span := tracer.Start(ctx, "webhooksDao.Create")
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return created, err
+	}
+
+	owner := pgtype.Text{String: subscription.GetOwnerId(), Valid: subscription.GetOwnerId() != ""}
+
+	sqlStatement := "insert into webhook_subscriptions(tenant_id, client_id, owner_id, url, secret, event_types) values ($1, $2, $3, $4, $5, $6) returning id, created_at"
+	var id int64
+	var createdAt time.Time
+	err = d.dbPool.QueryRow(ctx, sqlStatement, tenantId, subscription.GetClientId(), owner, subscription.GetUrl(), subscription.GetSecret(), subscription.GetEventTypes()).Scan(&id, &createdAt)
+	if err != nil {
+		return created, err
+	}
+
+	created = subscription
+	created.SetId(id)
+	created.SetTenantId(tenantId)
+	created.SetCreatedAt(createdAt)
+
+	return created, nil
+}
+
+func (d *dao) GetById(ctx context.Context, id int64) (subscription webhooksmodel.Subscriptions, err error) {
+	ctx, span := tracer.Start(ctx, "webhooksDao.GetById", trace.WithAttributes(attribute.Int64("webhook.id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return subscription, err
+	}
+
+	rows, err := d.dbPool.Query(ctx, selectSubscriptions+" where tenant_id=$1 and id=$2", tenantId, id)
+	if err != nil {
+		return subscription, err
+	}
+
+	return pgx.CollectExactlyOneRow(rows, scanSubscription)
+}
+
+func (d *dao) List(ctx context.Context, clientId string) (subscriptions []webhooksmodel.Subscriptions, err error) {
+	ctx, span := tracer.Start(ctx, "webhooksDao.List")
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.dbPool.Query(ctx, selectSubscriptions+" where tenant_id=$1 and ($2 = '' or client_id=$2) order by id", tenantId, clientId)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanSubscription)
+}
+
+func (d *dao) Delete(ctx context.Context, id int64) (err error) {
+	ctx, span := tracer.Start(ctx, "webhooksDao.Delete", trace.WithAttributes(attribute.Int64("webhook.id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := d.dbPool.Exec(ctx, "delete from webhook_subscriptions where tenant_id=$1 and id=$2", tenantId, id)
+	if err == nil && tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return err
+}
+
+func (d *dao) Enqueue(ctx context.Context, eventId int64, eventType string, accountId int64, payload []byte) (count int64, err error) {
+	ctx, span := tracer.Start(ctx, "webhooksDao.Enqueue", trace.WithAttributes(attribute.Int64("event.id", eventId), attribute.String("event.type", eventType)))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// subscriptions limited to an owner only get the events of the
+	// accounts it owns
+	sqlStatement := `insert into webhook_deliveries(subscription_id, tenant_id, event_id, event_type, payload)
+		select s.id, s.tenant_id, $2, $3, $5 from webhook_subscriptions s
+		where s.tenant_id=$1 and $3 = any(s.event_types)
+			and (s.owner_id is null or s.owner_id = (select a.owner_id from accounts a where a.tenant_id=$1 and a.id=$4))
+		on conflict (subscription_id, event_id) do nothing`
+	tag, err := d.dbPool.Exec(ctx, sqlStatement, tenantId, eventId, eventType, accountId, payload)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+const selectDeliveries = "select d.id, d.subscription_id, d.tenant_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.created_at, d.delivered_at from webhook_deliveries d"
+
+func (d *dao) Due(ctx context.Context, tx pgx.Tx, limit int) (pending []Pending, err error) {
+	ctx, span := tracer.Start(ctx, "webhooksDao.Due")
+	defer func() { tracing.End(span, err) }()
+
+	sqlStatement := `select d.id, d.subscription_id, d.tenant_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.created_at, d.delivered_at,
+			s.client_id, s.owner_id, s.url, s.secret, s.event_types, s.created_at
+		from webhook_deliveries d join webhook_subscriptions s on s.id = d.subscription_id
+		where d.status=$1 and d.next_attempt_at <= now()
+		order by d.next_attempt_at, d.id limit $2
+		for update of d skip locked`
+	rows, err := tx.Query(ctx, sqlStatement, webhooksmodel.StatusPending, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Pending, error) {
+		var p Pending
+		var clientId, url, secret string
+		var ownerId pgtype.Text
+		var eventTypes []string
+		var createdAt time.Time
+
+		delivery, err := scanDelivery(row, &clientId, &ownerId, &url, &secret, &eventTypes, &createdAt)
+		if err != nil {
+			return p, err
+		}
+
+		p.Delivery = delivery
+		p.Subscription.SetId(delivery.GetSubscriptionId())
+		p.Subscription.SetTenantId(delivery.GetTenantId())
+		p.Subscription.SetClientId(clientId)
+		p.Subscription.SetOwnerId(ownerId.String)
+		p.Subscription.SetUrl(url)
+		p.Subscription.SetSecret(secret)
+		p.Subscription.SetEventTypes(eventTypes)
+		p.Subscription.SetCreatedAt(createdAt)
+
+		return p, nil
+	})
+}
+
+func (d *dao) RecordAttempt(ctx context.Context, tx pgx.Tx, delivery webhooksmodel.Deliveries, attempt webhooksmodel.Attempts) (err error) {
+	ctx, span := tracer.Start(ctx, "webhooksDao.RecordAttempt", trace.WithAttributes(attribute.Int64("webhook.delivery_id", delivery.GetId())))
+	defer func() { tracing.End(span, err) }()
+
+	statusCode := pgtype.Int4{Int32: int32(attempt.GetStatusCode()), Valid: attempt.GetStatusCode() != 0}
+	message := pgtype.Text{String: attempt.GetError(), Valid: attempt.GetError() != ""}
+
+	sqlStatement := "insert into webhook_attempts(delivery_id, attempted_at, status_code, error, duration_ms) values ($1, $2, $3, $4, $5)"
+	_, err = tx.Exec(ctx, sqlStatement, delivery.GetId(), attempt.GetAttemptedAt(), statusCode, message, attempt.GetDuration().Milliseconds())
+	if err != nil {
+		return err
+	}
+
+	sqlStatement = "update webhook_deliveries set status=$2, attempts=$3, next_attempt_at=$4, last_status_code=$5, last_error=$6, delivered_at=$7 where id=$1"
+	_, err = tx.Exec(ctx, sqlStatement, delivery.GetId(), delivery.GetStatus(), delivery.GetAttempts(), delivery.GetNextAttemptAt(), statusCode, message, delivery.GetDeliveredAt())
+
+	return err
+}
+
+func (d *dao) ListDeliveries(ctx context.Context, subscriptionId int64, status string, beforeId int64, limit int) (deliveries []webhooksmodel.Deliveries, err error) {
+	ctx, span := tracer.Start(ctx, "webhooksDao.ListDeliveries", trace.WithAttributes(attribute.Int64("webhook.id", subscriptionId)))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStatement := selectDeliveries + " where d.tenant_id=$1 and d.subscription_id=$2 and ($3 = '' or d.status=$3) and ($4 = 0 or d.id < $4) order by d.id desc limit $5"
+	rows, err := d.dbPool.Query(ctx, sqlStatement, tenantId, subscriptionId, status, beforeId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (webhooksmodel.Deliveries, error) {
+		return scanDelivery(row)
+	})
+}
+
+func (d *dao) GetDelivery(ctx context.Context, subscriptionId, id int64) (delivery webhooksmodel.Deliveries, err error) {
+	ctx, span := tracer.Start(ctx, "webhooksDao.GetDelivery", trace.WithAttributes(attribute.Int64("webhook.id", subscriptionId), attribute.Int64("webhook.delivery_id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return delivery, err
+	}
+
+	rows, err := d.dbPool.Query(ctx, selectDeliveries+" where d.tenant_id=$1 and d.subscription_id=$2 and d.id=$3", tenantId, subscriptionId, id)
+	if err != nil {
+		return delivery, err
+	}
+
+	return pgx.CollectExactlyOneRow(rows, func(row pgx.CollectableRow) (webhooksmodel.Deliveries, error) {
+		return scanDelivery(row)
+	})
+}
+
+func (d *dao) ListAttempts(ctx context.Context, deliveryId int64) (attempts []webhooksmodel.Attempts, err error) {
+	ctx, span := tracer.Start(ctx, "webhooksDao.ListAttempts", trace.WithAttributes(attribute.Int64("webhook.delivery_id", deliveryId)))
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := d.dbPool.Query(ctx, "select id, attempted_at, status_code, error, duration_ms from webhook_attempts where delivery_id=$1 order by id", deliveryId)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (attempt webhooksmodel.Attempts, err error) {
+		var id int64
+		var attemptedAt time.Time
+		var statusCode pgtype.Int4
+		var message pgtype.Text
+		var durationMs int64
+
+		err = row.Scan(&id, &attemptedAt, &statusCode, &message, &durationMs)
+		if err != nil {
+			return attempt, err
+		}
+
+		attempt.SetId(id)
+		attempt.SetDeliveryId(deliveryId)
+		attempt.SetAttemptedAt(attemptedAt)
+		attempt.SetStatusCode(int(statusCode.Int32))
+		attempt.SetError(message.String)
+		attempt.SetDuration(time.Duration(durationMs) * time.Millisecond)
+
+		return attempt, nil
+	})
+}
+
+func (d *dao) Redeliver(ctx context.Context, subscriptionId, id int64) (delivery webhooksmodel.Deliveries, err error) {
+	ctx, span := tracer.Start(ctx, "webhooksDao.Redeliver", trace.WithAttributes(attribute.Int64("webhook.id", subscriptionId), attribute.Int64("webhook.delivery_id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return delivery, err
+	}
+
+	sqlStatement := `update webhook_deliveries d set status=$4, attempts=0, next_attempt_at=now(), delivered_at=null
+		where d.tenant_id=$1 and d.subscription_id=$2 and d.id=$3
+		returning d.id, d.subscription_id, d.tenant_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.created_at, d.delivered_at`
+	rows, err := d.dbPool.Query(ctx, sqlStatement, tenantId, subscriptionId, id, webhooksmodel.StatusPending)
+	if err != nil {
+		return delivery, err
+	}
+
+	return pgx.CollectExactlyOneRow(rows, func(row pgx.CollectableRow) (webhooksmodel.Deliveries, error) {
+		return scanDelivery(row)
+	})
+}
+
+func scanSubscription(row pgx.CollectableRow) (subscription webhooksmodel.Subscriptions, err error) {
+	var id int64
+	var tenantId, clientId, url, secret string
+	var ownerId pgtype.Text
+	var eventTypes []string
+	var createdAt time.Time
+
+	err = row.Scan(&id, &tenantId, &clientId, &ownerId, &url, &secret, &eventTypes, &createdAt)
+	if err != nil {
+		return subscription, err
+	}
+
+	subscription.SetId(id)
+	subscription.SetTenantId(tenantId)
+	subscription.SetClientId(clientId)
+	subscription.SetOwnerId(ownerId.String)
+	subscription.SetUrl(url)
+	subscription.SetSecret(secret)
+	subscription.SetEventTypes(eventTypes)
+	subscription.SetCreatedAt(createdAt)
+
+	return subscription, nil
+}
+
+// scanDelivery scans the columns of selectDeliveries, followed by extra.
+func scanDelivery(row pgx.CollectableRow, extra ...any) (delivery webhooksmodel.Deliveries, err error) {
+	var id, subscriptionId, eventId int64
+	var tenantId, eventType, status string
+	var payload []byte
+	var attempts int
+	var nextAttemptAt, createdAt time.Time
+	var lastStatusCode pgtype.Int4
+	var lastError pgtype.Text
+	var deliveredAt *time.Time
+
+	dest := append([]any{&id, &subscriptionId, &tenantId, &eventId, &eventType, &payload, &status, &attempts, &nextAttemptAt, &lastStatusCode, &lastError, &createdAt, &deliveredAt}, extra...)
+	err = row.Scan(dest...)
+	if err != nil {
+		return delivery, err
+	}
+
+	delivery.SetId(id)
+	delivery.SetSubscriptionId(subscriptionId)
+	delivery.SetTenantId(tenantId)
+	delivery.SetEventId(eventId)
+	delivery.SetEventType(eventType)
+	delivery.SetPayload(payload)
+	delivery.SetStatus(status)
+	delivery.SetAttempts(attempts)
+	delivery.SetNextAttemptAt(nextAttemptAt)
+	delivery.SetLastStatusCode(int(lastStatusCode.Int32))
+	delivery.SetLastError(lastError.String)
+	delivery.SetCreatedAt(createdAt)
+	delivery.SetDeliveredAt(deliveredAt)
+
+	return delivery, nil
+}
@@ -0,0 +1,387 @@
+// Package audit records the state-changing calls made to the API in the
+// audit log. A call is audited with the accounts it changed: services report
+// each change with Changed, on the context of the call, and commit it with
+// Commit so its entry is written in the same transaction.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	"github.com/ashwin-m/transactions/middlewares/requestid"
+	accountsmodel "github.com/ashwin-m/transactions/models/accounts"
+	auditmodel "github.com/ashwin-m/transactions/models/audit"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/metrics"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxPayload bounds the sanitized payload kept with an entry.
+	maxPayload = 16 << 10
+	// maxCapture bounds how much of a request body is kept to be recorded.
+	// A larger body can't be kept whole once sanitized.
+	maxCapture = 4 * maxPayload
+
+	redacted = "[REDACTED]"
+)
+
+// sensitiveKeys are redacted from payloads wherever they appear in a key.
+var sensitiveKeys = []string{"password", "secret", "token", "api_key", "authorization", "signature"}
+
+// Recorder writes entries to the audit log.
+type Recorder interface {
+	Record(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error)
+	// RecordTx writes an entry within txn, so it is only kept when txn
+	// commits.
+	RecordTx(ctx context.Context, txn pgx.Tx, entry auditmodel.Entries) (auditmodel.Entries, error)
+}
+
+// Call is a state-changing call to be audited.
+type Call struct {
+	Method string
+	// Endpoint is the route template of the call, or the gRPC method.
+	Endpoint string
+	Status   int
+	// ErrorCode is the code of the error the call failed with.
+	ErrorCode string
+	// Payload is the request body, sanitized before it is recorded.
+	Payload []byte
+}
+
+type collector struct {
+	recorder Recorder
+	// succeeded describes the call as it will have succeeded, for the entry
+	// written with its changes, before the call completes.
+	succeeded func() Call
+
+	mu        sync.Mutex
+	snapshots []auditmodel.Snapshot
+	// recorded is set once the entry of the call is committed with its
+	// changes.
+	recorded bool
+}
+
+type contextKey struct{}
+
+// NewContext returns a context collecting the changes made during a call.
+// Changes committed with Commit are recorded by recorder as succeeded
+// describes the call.
+func NewContext(ctx context.Context, recorder Recorder, succeeded func() Call) context.Context {
+	return context.WithValue(ctx, contextKey{}, &collector{
+		recorder:  recorder,
+		succeeded: succeeded,
+	})
+}
+
+// Changed reports that an account went from before to after, before being
+// nil when it was created. It does nothing unless ctx is collecting changes,
+// and must be called in the transaction of the change, before Commit.
+func Changed(ctx context.Context, before, after *accountsmodel.Accounts) {
+	c, ok := ctx.Value(contextKey{}).(*collector)
+	if !ok || after == nil {
+		return
+	}
+
+	tenantId := after.GetTenantId()
+	if tenantId == "" {
+		tenantId, _ = tenant.FromContext(ctx)
+	}
+	snapshot := auditmodel.Snapshot{
+		TenantId:  tenantId,
+		AccountId: after.GetId(),
+		Before:    state(before),
+		After:     state(after),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.snapshots = append(c.snapshots, snapshot)
+}
+
+func state(account *accountsmodel.Accounts) *auditmodel.AccountState {
+	if account == nil {
+		return nil
+	}
+
+	return &auditmodel.AccountState{
+		Balance: account.GetBalance(),
+		OwnerId: account.GetOwnerId(),
+		Version: account.GetVersion(),
+	}
+}
+
+// Commit commits txn, the transaction of the changes reported with Changed on
+// ctx. When ctx is collecting the changes of a call, its entry is written in
+// txn first, so the changes are only committed with their entry: when the
+// entry can't be written txn is rolled back and the call fails.
+func Commit(ctx context.Context, txn pgx.Tx) error {
+	c, ok := ctx.Value(contextKey{}).(*collector)
+	if !ok || c.recorder == nil {
+		return txn.Commit(ctx)
+	}
+
+	c.mu.Lock()
+	snapshots := c.snapshots
+	c.mu.Unlock()
+
+	_, err := c.recorder.RecordTx(ctx, txn, newEntry(ctx, c.succeeded(), snapshots))
+	if err != nil {
+		txn.Rollback(ctx)
+		metrics.AuditFailed()
+		c.discard()
+		return apperrors.New(apperrors.CodeInternal, "unable to write the audit log").Wrap(err)
+	}
+
+	err = txn.Commit(ctx)
+	if err != nil {
+		c.discard()
+		return err
+	}
+
+	c.mu.Lock()
+	c.recorded = true
+	c.mu.Unlock()
+
+	return nil
+}
+
+// discard forgets the changes of a transaction that didn't commit.
+func (c *collector) discard() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.snapshots = nil
+}
+
+// Record writes the audit entry of call, made with ctx, which must come from
+// NewContext, unless it was committed with the changes of the call. The
+// entry is written even when the caller has gone away. A failure is logged
+// rather than failing the call, which has already been carried out.
+func Record(ctx context.Context, recorder Recorder, call Call) {
+	var snapshots []auditmodel.Snapshot
+	if c, ok := ctx.Value(contextKey{}).(*collector); ok {
+		c.mu.Lock()
+		recorded := c.recorded
+		snapshots = c.snapshots
+		c.mu.Unlock()
+
+		if recorded {
+			return
+		}
+	}
+
+	_, err := recorder.Record(context.WithoutCancel(ctx), newEntry(ctx, call, snapshots))
+	if err != nil {
+		metrics.AuditFailed()
+		slog.ErrorContext(ctx, "unable to write audit entry", slog.String("endpoint", call.Endpoint), slog.Any("error", err))
+	}
+}
+
+func newEntry(ctx context.Context, call Call, snapshots []auditmodel.Snapshot) auditmodel.Entries {
+	identity, _ := auth.FromContext(ctx)
+
+	entry := auditmodel.Entries{}
+	entry.SetOccurredAt(time.Now())
+	entry.SetActor(identity.ClientID)
+	entry.SetAuthMethod(identity.Method)
+	entry.SetRequestId(requestid.FromContext(ctx))
+	entry.SetMethod(call.Method)
+	entry.SetEndpoint(call.Endpoint)
+	entry.SetStatus(call.Status)
+	entry.SetErrorCode(call.ErrorCode)
+	entry.SetPayload(Sanitize(call.Payload))
+	entry.SetSnapshots(snapshots)
+
+	return entry
+}
+
+const (
+	// withoutPayloadKey marks, on the gin context, requests whose body isn't
+	// recorded.
+	withoutPayloadKey = "audit.withoutPayload"
+	// succeedsKey holds, on the gin context, the status a route answers
+	// with when it succeeds.
+	succeedsKey = "audit.succeeds"
+)
+
+// capture keeps the start of a request body as the handler reads it, so the
+// body is neither buffered ahead of the handler nor kept whole.
+type capture struct {
+	io.ReadCloser
+	kept bytes.Buffer
+	size int
+}
+
+func (c *capture) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.size += n
+	if room := maxCapture - c.kept.Len(); room > 0 {
+		c.kept.Write(p[:min(n, room)])
+	}
+
+	return n, err
+}
+
+// payload returns the body to record. What the handler left unread is read
+// too, as far as maxCapture, so the body of a request rejected early is
+// still recorded.
+func (c *capture) payload() []byte {
+	if c.kept.Len() < maxCapture {
+		_, _ = io.CopyN(io.Discard, c, int64(maxCapture-c.kept.Len()+1))
+	}
+	if c.size > maxCapture {
+		return note(fmt.Sprintf("body of more than %d bytes omitted", maxCapture))
+	}
+
+	return c.kept.Bytes()
+}
+
+// WithoutPayload keeps the body of a route out of its audit entry, only its
+// size is recorded. Routes streaming large bodies use it, their body is
+// neither kept nor read further for the audit log.
+func WithoutPayload(c *gin.Context) {
+	c.Set(withoutPayloadKey, true)
+	c.Next()
+}
+
+// Succeeds sets the status a route answers with when it succeeds, 200 OK by
+// default. The entry of a request is written with its changes, before the
+// response, and records that status.
+func Succeeds(status int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(succeedsKey, status)
+		c.Next()
+	}
+}
+
+// Middleware audits every request that may change state, that is every
+// request but GET, HEAD and OPTIONS, whatever its outcome. It must run after
+// authentication and tenancy, and before anything that may reject the
+// request. The payload recorded is at most the first maxCapture bytes of the
+// body, captured while the handler reads it.
+func Middleware(recorder Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		var body *capture
+		if c.Request.Body != nil {
+			body = &capture{ReadCloser: c.Request.Body}
+			c.Request.Body = body
+		}
+
+		call := func(status int) Call {
+			var payload []byte
+			switch {
+			case body == nil:
+			case c.GetBool(withoutPayloadKey):
+				payload = note(fmt.Sprintf("streamed body of %d bytes omitted", body.size))
+			default:
+				payload = body.payload()
+			}
+
+			endpoint := c.FullPath()
+			if endpoint == "" {
+				endpoint = c.Request.URL.Path
+			}
+
+			return Call{
+				Method:   c.Request.Method,
+				Endpoint: endpoint,
+				Status:   status,
+				Payload:  payload,
+			}
+		}
+
+		ctx := NewContext(c.Request.Context(), recorder, func() Call {
+			status, ok := c.Get(succeedsKey)
+			if !ok {
+				return call(http.StatusOK)
+			}
+			return call(status.(int))
+		})
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		completed := call(c.Writer.Status())
+		if last := c.Errors.Last(); last != nil {
+			completed.ErrorCode = string(apperrors.From(last.Err).Code)
+		}
+
+		Record(ctx, recorder, completed)
+	}
+}
+
+// Sanitize returns the JSON payload kept for a request body. Values of keys
+// naming credentials are redacted, and bodies that aren't JSON or are too
+// large are replaced by a note of their size.
+func Sanitize(body []byte) json.RawMessage {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return json.RawMessage("null")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value any
+	if decoder.Decode(&value) != nil || decoder.More() {
+		return note(fmt.Sprintf("non-JSON body of %d bytes", len(body)))
+	}
+
+	sanitized, err := json.Marshal(redact(value))
+	if err != nil || len(sanitized) > maxPayload {
+		return note(fmt.Sprintf("body of %d bytes omitted", len(body)))
+	}
+
+	return sanitized
+}
+
+func redact(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, nested := range v {
+			if sensitive(key) {
+				v[key] = redacted
+			} else {
+				v[key] = redact(nested)
+			}
+		}
+	case []any:
+		for i, nested := range v {
+			v[i] = redact(nested)
+		}
+	}
+
+	return value
+}
+
+func sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitiveKey := range sensitiveKeys {
+		if strings.Contains(key, sensitiveKey) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func note(message string) json.RawMessage {
+	b, _ := json.Marshal(message)
+	return b
+}
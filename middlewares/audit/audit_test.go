@@ -0,0 +1,311 @@
+package audit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	"github.com/ashwin-m/transactions/middlewares/requestid"
+	accountsmodel "github.com/ashwin-m/transactions/models/accounts"
+	auditmodel "github.com/ashwin-m/transactions/models/audit"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+type recorderFunc func(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error)
+
+func (f recorderFunc) Record(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error) {
+	return f(ctx, entry)
+}
+
+// RecordTx records entries committed with their changes with a status of
+// the transaction, so tests can tell them apart.
+func (f recorderFunc) RecordTx(ctx context.Context, txn pgx.Tx, entry auditmodel.Entries) (auditmodel.Entries, error) {
+	entry.SetErrorCode("in transaction")
+	return f(ctx, entry)
+}
+
+func account(id int64, balance float64, version int64) *accountsmodel.Accounts {
+	a := &accountsmodel.Accounts{}
+	a.SetId(id)
+	a.SetBalance(balance)
+	a.SetOwnerId("client-1")
+	a.SetVersion(version)
+	return a
+}
+
+func newRouter(recorder Recorder, handler gin.HandlerFunc) *gin.Engine {
+	router := gin.New()
+	router.Use(requestid.Middleware(), auth.WithIdentity(auth.Identity{ClientID: "client-1", Method: auth.MethodAPIKey}), func(c *gin.Context) {
+		c.Request = c.Request.WithContext(tenant.NewContext(c.Request.Context(), tenant.Default))
+	}, Middleware(recorder))
+	router.GET("/accounts/:id", handler)
+	router.POST("/transactions", handler)
+	router.POST("/accounts", Succeeds(http.StatusNoContent), handler)
+
+	return router
+}
+
+func TestMiddleware_RecordsMutatingRequests(t *testing.T) {
+	var recorded []auditmodel.Entries
+	recorder := recorderFunc(func(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error) {
+		recorded = append(recorded, entry)
+		return entry, nil
+	})
+
+	router := newRouter(recorder, func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		assert.Equal(t, `{"source_account_id":1,"destination_account_id":2,"amount":"10.5"}`, string(body))
+
+		Changed(c.Request.Context(), account(1, 100, 1), account(1, 89.5, 2))
+		Changed(c.Request.Context(), account(2, 0, 1), account(2, 10.5, 2))
+		c.Status(http.StatusCreated)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(`{"source_account_id":1,"destination_account_id":2,"amount":"10.5"}`))
+	req.Header.Set(requestid.Header, "req-1")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	if assert.Len(t, recorded, 1) {
+		entry := recorded[0]
+		assert.Equal(t, "client-1", entry.GetActor())
+		assert.Equal(t, auth.MethodAPIKey, entry.GetAuthMethod())
+		assert.Equal(t, "req-1", entry.GetRequestId())
+		assert.Equal(t, "POST", entry.GetMethod())
+		assert.Equal(t, "/transactions", entry.GetEndpoint())
+		assert.Equal(t, http.StatusCreated, entry.GetStatus())
+		assert.Empty(t, entry.GetErrorCode())
+		assert.JSONEq(t, `{"source_account_id":1,"destination_account_id":2,"amount":"10.5"}`, string(entry.GetPayload()))
+		assert.Equal(t, []auditmodel.Snapshot{
+			{TenantId: tenant.Default, AccountId: 1, Before: &auditmodel.AccountState{Balance: 100, OwnerId: "client-1", Version: 1}, After: &auditmodel.AccountState{Balance: 89.5, OwnerId: "client-1", Version: 2}},
+			{TenantId: tenant.Default, AccountId: 2, Before: &auditmodel.AccountState{Balance: 0, OwnerId: "client-1", Version: 1}, After: &auditmodel.AccountState{Balance: 10.5, OwnerId: "client-1", Version: 2}},
+		}, entry.GetSnapshots())
+	}
+}
+
+func TestMiddleware_RecordsFailures(t *testing.T) {
+	var recorded []auditmodel.Entries
+	recorder := recorderFunc(func(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error) {
+		recorded = append(recorded, entry)
+		return entry, nil
+	})
+
+	router := newRouter(recorder, func(c *gin.Context) {
+		apperrors.Abort(c, apperrors.New(apperrors.CodeInsufficientFunds, "insufficient funds"))
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(`{"amount":"10"}`))
+	router.ServeHTTP(w, req)
+
+	if assert.Len(t, recorded, 1) {
+		assert.Equal(t, w.Code, recorded[0].GetStatus())
+		assert.Equal(t, string(apperrors.CodeInsufficientFunds), recorded[0].GetErrorCode())
+		assert.Empty(t, recorded[0].GetSnapshots())
+	}
+}
+
+func TestMiddleware_CapsThePayload(t *testing.T) {
+	var recorded []auditmodel.Entries
+	recorder := recorderFunc(func(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error) {
+		recorded = append(recorded, entry)
+		return entry, nil
+	})
+
+	large := `{"data":"` + strings.Repeat("x", 2*maxCapture) + `"}`
+	router := newRouter(recorder, func(c *gin.Context) {
+		// the handler still gets the whole body
+		body, _ := io.ReadAll(c.Request.Body)
+		assert.Equal(t, large, string(body))
+		c.Status(http.StatusCreated)
+	})
+	router.POST("/imports", WithoutPayload, func(c *gin.Context) {
+		_, _ = io.Copy(io.Discard, c.Request.Body)
+		c.Status(http.StatusOK)
+	})
+
+	for _, path := range []string{"/transactions", "/imports"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, strings.NewReader(large))
+		router.ServeHTTP(w, req)
+	}
+
+	if assert.Len(t, recorded, 2) {
+		assert.Equal(t, `"body of more than 65536 bytes omitted"`, string(recorded[0].GetPayload()))
+		assert.Equal(t, `"streamed body of 131083 bytes omitted"`, string(recorded[1].GetPayload()))
+	}
+}
+
+func TestMiddleware_RecordsBodiesLeftUnread(t *testing.T) {
+	var recorded []auditmodel.Entries
+	recorder := recorderFunc(func(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error) {
+		recorded = append(recorded, entry)
+		return entry, nil
+	})
+
+	router := newRouter(recorder, func(c *gin.Context) {
+		apperrors.Abort(c, apperrors.New(apperrors.CodeRateLimited, "rate limit exceeded"))
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(`{"amount":"10"}`))
+	router.ServeHTTP(w, req)
+
+	if assert.Len(t, recorded, 1) {
+		assert.JSONEq(t, `{"amount":"10"}`, string(recorded[0].GetPayload()))
+	}
+}
+
+func TestMiddleware_SkipsReads(t *testing.T) {
+	recorder := recorderFunc(func(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error) {
+		t.Error("reads must not be audited")
+		return entry, nil
+	})
+
+	router := newRouter(recorder, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/accounts/1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestMiddleware_FailedWritesDontFailTheRequest(t *testing.T) {
+	recorder := recorderFunc(func(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error) {
+		return entry, assert.AnError
+	})
+
+	router := newRouter(recorder, func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(`{}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestCommit_WritesTheEntryWithTheChanges(t *testing.T) {
+	var recorded []auditmodel.Entries
+	recorder := recorderFunc(func(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error) {
+		recorded = append(recorded, entry)
+		return entry, nil
+	})
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectCommit()
+
+	router := newRouter(recorder, func(c *gin.Context) {
+		ctx := c.Request.Context()
+		_, _ = io.ReadAll(c.Request.Body)
+
+		txn, _ := mockDB.Begin(ctx)
+		Changed(ctx, nil, account(1, 10, 1))
+		assert.NoError(t, Commit(ctx, txn))
+
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/accounts", strings.NewReader(`{"id":1,"balance":"10"}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+	if assert.Len(t, recorded, 1) {
+		entry := recorded[0]
+		assert.Equal(t, "in transaction", entry.GetErrorCode())
+		assert.Equal(t, http.StatusNoContent, entry.GetStatus())
+		assert.Equal(t, "/accounts", entry.GetEndpoint())
+		assert.JSONEq(t, `{"id":1,"balance":"10"}`, string(entry.GetPayload()))
+		assert.Len(t, entry.GetSnapshots(), 1)
+	}
+}
+
+func TestCommit_RollsBackWhenTheEntryCantBeWritten(t *testing.T) {
+	var recorded []auditmodel.Entries
+	recorder := recorderFunc(func(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error) {
+		if entry.GetErrorCode() == "in transaction" {
+			return entry, assert.AnError
+		}
+		recorded = append(recorded, entry)
+		return entry, nil
+	})
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectRollback()
+
+	router := newRouter(recorder, func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		txn, _ := mockDB.Begin(ctx)
+		Changed(ctx, account(1, 10, 1), account(1, 5, 2))
+		err := Commit(ctx, txn)
+
+		assert.ErrorIs(t, err, assert.AnError)
+		apperrors.Abort(c, err)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(`{}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+	// the failure is still recorded, without the changes that were undone
+	if assert.Len(t, recorded, 1) {
+		assert.Equal(t, http.StatusInternalServerError, recorded[0].GetStatus())
+		assert.Empty(t, recorded[0].GetSnapshots())
+	}
+}
+
+func TestCommit_OutsideAuditedCalls(t *testing.T) {
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectCommit()
+
+	txn, _ := mockDB.Begin(context.Background())
+
+	assert.NoError(t, Commit(context.Background(), txn))
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestChanged_IgnoredOutsideAuditedCalls(t *testing.T) {
+	assert.NotPanics(t, func() {
+		Changed(context.Background(), nil, account(1, 10, 1))
+	})
+}
+
+func TestSanitize(t *testing.T) {
+	tests := map[string]struct {
+		body     string
+		expected string
+	}{
+		"empty":       {"", `null`},
+		"credentials": {`{"name":"ci","api_key":"k","nested":[{"client_secret":"s","Password":"p"}]}`, `{"api_key":"[REDACTED]","name":"ci","nested":[{"Password":"[REDACTED]","client_secret":"[REDACTED]"}]}`},
+		"numbers":     {`{"amount":12345678901234567890.123}`, `{"amount":12345678901234567890.123}`},
+		"not json":    {`amount=10`, `"non-JSON body of 9 bytes"`},
+		"too large":   {`{"data":"` + strings.Repeat("x", maxPayload) + `"}`, `"body of 16395 bytes omitted"`},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, string(Sanitize([]byte(test.body))))
+		})
+	}
+}
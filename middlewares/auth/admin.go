@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminToken only lets through requests presenting token as a bearer token.
+// An empty token disables the protected routes entirely.
+func AdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			apperrors.Abort(c, apperrors.New(apperrors.CodeForbidden, "admin api is disabled"))
+			return
+		}
+
+		presented, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			unauthorized(c, ErrInvalidCredentials.Error())
+			return
+		}
+
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), Identity{ClientID: "admin", Method: MethodAdminToken, Roles: []string{RoleAdmin}}))
+		c.Next()
+	}
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	apikeysdao "github.com/ashwin-m/transactions/daos/apikeys"
+)
+
+const (
+	apiKeyHeader        = "X-API-Key"
+	apiKeyPrefix        = "tk"
+	signingSecretPrefix = "tksec_"
+)
+
+// GenerateAPIKey returns a new key id, the full key to hand to the client and
+// the hash to store. The key has the form tk_<id>_<secret>; only the hash of
+// the whole key is ever persisted.
+func GenerateAPIKey() (id, key string, hash []byte) {
+	idBytes := make([]byte, 8)
+	_, _ = rand.Read(idBytes)
+	secret := make([]byte, 32)
+	_, _ = rand.Read(secret)
+
+	id = hex.EncodeToString(idBytes)
+	key = fmt.Sprintf("%s_%s_%s", apiKeyPrefix, id, base64.RawURLEncoding.EncodeToString(secret))
+
+	return id, key, HashAPIKey(key)
+}
+
+// GenerateSigningSecret returns a new secret to sign requests with, see
+// Sign. It is issued with a key and stored sealed, so that it can be read
+// back to check signatures.
+func GenerateSigningSecret() string {
+	secret := make([]byte, 32)
+	_, _ = rand.Read(secret)
+
+	return signingSecretPrefix + base64.RawURLEncoding.EncodeToString(secret)
+}
+
+func HashAPIKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+func parseAPIKeyId(key string) (string, bool) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+
+	return parts[1], true
+}
+
+type apiKeyAuthenticator struct {
+	dao apikeysdao.Dao
+}
+
+// APIKeys authenticates requests sending an API key in the X-API-Key header or
+// as a bearer token.
+func APIKeys(dao apikeysdao.Dao) Authenticator {
+	return &apiKeyAuthenticator{
+		dao: dao,
+	}
+}
+
+func (a *apiKeyAuthenticator) Authenticate(r *http.Request) (Identity, error) {
+	key := r.Header.Get(apiKeyHeader)
+	if key == "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !strings.HasPrefix(token, apiKeyPrefix+"_") {
+			return Identity{}, ErrNoCredentials
+		}
+		key = token
+	}
+
+	id, ok := parseAPIKeyId(key)
+	if !ok {
+		return Identity{}, fmt.Errorf("%w: malformed api key", ErrInvalidCredentials)
+	}
+
+	apiKey, err := a.dao.GetById(r.Context(), id)
+	if errors.Is(err, apikeysdao.ErrNotFound) {
+		return Identity{}, fmt.Errorf("%w: unknown api key %s", ErrInvalidCredentials, id)
+	}
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if apiKey.IsRevoked() {
+		return Identity{}, fmt.Errorf("%w: api key %s is revoked", ErrInvalidCredentials, id)
+	}
+
+	if subtle.ConstantTimeCompare(apiKey.GetKeyHash(), HashAPIKey(key)) != 1 {
+		return Identity{}, fmt.Errorf("%w: api key %s does not match", ErrInvalidCredentials, id)
+	}
+
+	return Identity{ClientID: apiKey.GetClientId(), KeyID: id, Method: MethodAPIKey, Roles: apiKey.GetRoles(), TenantID: apiKey.GetTenantId()}, nil
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	MethodAPIKey     = "api_key"
+	MethodHMAC       = "hmac"
+	MethodJWT        = "jwt"
+	MethodAdminToken = "admin_token"
+	MethodNone       = "none"
+)
+
+var (
+	// ErrNoCredentials is returned by an Authenticator when the request carries
+	// no credentials of the kind it handles, so the next one should be tried.
+	ErrNoCredentials = errors.New("no credentials")
+
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	ClientID string   `json:"client_id"`
+	KeyID    string   `json:"key_id,omitempty"`
+	Method   string   `json:"method"`
+	Roles    []string `json:"roles,omitempty"`
+	// TenantID is the tenant the credentials belong to. It is empty for
+	// identities that may act on any tenant.
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+type Authenticator interface {
+	Authenticate(r *http.Request) (Identity, error)
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(contextKey{}).(Identity)
+	return identity, ok
+}
+
+// Middleware rejects requests that none of the authenticators accept. The
+// identity of accepted requests is stored on the request context.
+func Middleware(authenticators ...Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, err := Authenticate(c.Request, authenticators...)
+		if err != nil {
+			var appErr *apperrors.Error
+			if errors.As(err, &appErr) && appErr.Code == apperrors.CodeUnauthorized {
+				c.Header("WWW-Authenticate", `Bearer realm="transactions", HMAC-SHA256 realm="transactions"`)
+			}
+			apperrors.Abort(c, err)
+			return
+		}
+
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), identity))
+		c.Next()
+	}
+}
+
+// Authenticate returns the identity of the first authenticator holding
+// credentials for r. Requests without valid credentials fail with an
+// UNAUTHORIZED error.
+func Authenticate(r *http.Request, authenticators ...Authenticator) (Identity, error) {
+	for _, authenticator := range authenticators {
+		identity, err := authenticator.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+
+		if errors.Is(err, ErrInvalidCredentials) {
+			slog.WarnContext(r.Context(), "authentication failed", slog.Any("error", err))
+			return Identity{}, apperrors.New(apperrors.CodeUnauthorized, ErrInvalidCredentials.Error()).Wrap(err)
+		}
+
+		if err != nil {
+			return Identity{}, err
+		}
+
+		return identity, nil
+	}
+
+	return Identity{}, apperrors.New(apperrors.CodeUnauthorized, "missing credentials")
+}
+
+func unauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="transactions", HMAC-SHA256 realm="transactions"`)
+	apperrors.Abort(c, apperrors.New(apperrors.CodeUnauthorized, message))
+}
@@ -0,0 +1,362 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	apikeysdao "github.com/ashwin-m/transactions/daos/apikeys"
+	apikeysdaomocks "github.com/ashwin-m/transactions/daos/apikeys/mocks"
+	apikeysmodel "github.com/ashwin-m/transactions/models/apikeys"
+	"github.com/ashwin-m/transactions/utils/secretbox"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newRouter(authenticators ...Authenticator) *gin.Engine {
+	router := gin.New()
+	router.Use(Middleware(authenticators...))
+	router.POST("/transactions", func(c *gin.Context) {
+		identity, _ := FromContext(c.Request.Context())
+		c.JSON(http.StatusOK, identity)
+	})
+
+	return router
+}
+
+var testBox, _ = secretbox.New(bytes.Repeat([]byte{1}, secretbox.KeySize))
+
+func storedKey(id, clientId, key string) apikeysmodel.ApiKeys {
+	apiKey := apikeysmodel.ApiKeys{}
+	apiKey.SetId(id)
+	apiKey.SetClientId(clientId)
+	apiKey.SetRoles(DefaultRoles)
+	apiKey.SetKeyHash(HashAPIKey(key))
+	return apiKey
+}
+
+// signingKey is a stored key issued with signingSecret.
+func signingKey(id, clientId, key, signingSecret string) apikeysmodel.ApiKeys {
+	apiKey := storedKey(id, clientId, key)
+	apiKey.SetSigningSecret(testBox.Seal([]byte(signingSecret), []byte(id)))
+	return apiKey
+}
+
+func TestAuth_MissingCredentials(t *testing.T) {
+	mockDao := apikeysdaomocks.NewDao(t)
+	router := newRouter(APIKeys(mockDao), HMAC(mockDao, testBox, time.Minute))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:unauthorized\",\"title\":\"Authentication is required\",\"status\":401,\"detail\":\"missing credentials\",\"instance\":\"/transactions\",\"code\":\"UNAUTHORIZED\"}", w.Body.String())
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+}
+
+func TestAuth_APIKeySuccess(t *testing.T) {
+	id, key, _ := GenerateAPIKey()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, id).Return(storedKey(id, "client-1", key), nil)
+	router := newRouter(APIKeys(mockDao))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", nil)
+	req.Header.Set("X-API-Key", key)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{\"client_id\":\"client-1\",\"key_id\":\""+id+"\",\"method\":\"api_key\",\"roles\":[\"read\",\"transfer\"]}", w.Body.String())
+}
+
+func TestAuth_APIKeyAsBearerToken(t *testing.T) {
+	id, key, _ := GenerateAPIKey()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, id).Return(storedKey(id, "client-1", key), nil)
+	router := newRouter(APIKeys(mockDao))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAuth_APIKeyWrongSecret(t *testing.T) {
+	id, key, _ := GenerateAPIKey()
+	_, otherKey, _ := GenerateAPIKey()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, id).Return(storedKey(id, "client-1", otherKey), nil)
+	router := newRouter(APIKeys(mockDao))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", nil)
+	req.Header.Set("X-API-Key", key)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:unauthorized\",\"title\":\"Authentication is required\",\"status\":401,\"detail\":\"invalid credentials\",\"instance\":\"/transactions\",\"code\":\"UNAUTHORIZED\"}", w.Body.String())
+}
+
+func TestAuth_APIKeyRevoked(t *testing.T) {
+	id, key, _ := GenerateAPIKey()
+	revokedAt := time.Now()
+	apiKey := storedKey(id, "client-1", key)
+	apiKey.SetRevokedAt(&revokedAt)
+
+	mockDao := apikeysdaomocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, id).Return(apiKey, nil)
+	router := newRouter(APIKeys(mockDao))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", nil)
+	req.Header.Set("X-API-Key", key)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuth_APIKeyUnknown(t *testing.T) {
+	id, key, _ := GenerateAPIKey()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, id).Return(apikeysmodel.ApiKeys{}, apikeysdao.ErrNotFound)
+	router := newRouter(APIKeys(mockDao))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", nil)
+	req.Header.Set("X-API-Key", key)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuth_APIKeyDaoError(t *testing.T) {
+	id, key, _ := GenerateAPIKey()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, id).Return(apikeysmodel.ApiKeys{}, errors.New("test"))
+	router := newRouter(APIKeys(mockDao))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", nil)
+	req.Header.Set("X-API-Key", key)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:internal-error\",\"title\":\"Internal server error\",\"status\":500,\"detail\":\"the request could not be completed\",\"instance\":\"/transactions\",\"code\":\"INTERNAL_ERROR\"}", w.Body.String())
+}
+
+func signedRequest(keyId, signingSecret, timestamp, body string) *http.Request {
+	req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(body))
+	signature := Sign(signingSecret, "POST", "/transactions", timestamp, []byte(body))
+	req.Header.Set("Authorization", AuthorizationHeader(keyId, signature))
+	req.Header.Set("X-Timestamp", timestamp)
+	return req
+}
+
+func TestAuth_HMACSuccess(t *testing.T) {
+	id, key, _ := GenerateAPIKey()
+	signingSecret := GenerateSigningSecret()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, id).Return(signingKey(id, "client-1", key, signingSecret), nil)
+	router := newRouter(APIKeys(mockDao), HMAC(mockDao, testBox, time.Minute))
+
+	w := httptest.NewRecorder()
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	router.ServeHTTP(w, signedRequest(id, signingSecret, timestamp, `{"amount":"1"}`))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{\"client_id\":\"client-1\",\"key_id\":\""+id+"\",\"method\":\"hmac\",\"roles\":[\"read\",\"transfer\"]}", w.Body.String())
+}
+
+func TestAuth_HMACTamperedBody(t *testing.T) {
+	id, key, _ := GenerateAPIKey()
+	signingSecret := GenerateSigningSecret()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, id).Return(signingKey(id, "client-1", key, signingSecret), nil)
+	router := newRouter(HMAC(mockDao, testBox, time.Minute))
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req := signedRequest(id, signingSecret, timestamp, `{"amount":"1"}`)
+	req.Body = io.NopCloser(strings.NewReader(`{"amount":"1000"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuth_HMACStaleTimestamp(t *testing.T) {
+	id, _, _ := GenerateAPIKey()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+	router := newRouter(HMAC(mockDao, testBox, time.Minute))
+
+	w := httptest.NewRecorder()
+	timestamp := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	router.ServeHTTP(w, signedRequest(id, GenerateSigningSecret(), timestamp, `{}`))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuth_HMACNeedsTheSigningSecret(t *testing.T) {
+	id, key, _ := GenerateAPIKey()
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	// what the database holds isn't enough to sign
+	mockDao := apikeysdaomocks.NewDao(t)
+	stored := signingKey(id, "client-1", key, GenerateSigningSecret())
+	mockDao.EXPECT().GetById(mock.Anything, id).Return(stored, nil)
+	router := newRouter(HMAC(mockDao, testBox, time.Minute))
+
+	for _, secret := range []string{string(stored.GetKeyHash()), string(stored.GetSigningSecret())} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, signedRequest(id, secret, timestamp, `{}`))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	// keys issued without a signing secret can't sign
+	mockDao = apikeysdaomocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, id).Return(storedKey(id, "client-1", key), nil)
+	router = newRouter(HMAC(mockDao, testBox, time.Minute))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, signedRequest(id, "", timestamp, `{}`))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuth_HMACBodyTooLarge(t *testing.T) {
+	id, key, _ := GenerateAPIKey()
+	signingSecret := GenerateSigningSecret()
+
+	mockDao := apikeysdaomocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, id).Return(signingKey(id, "client-1", key, signingSecret), nil)
+	router := newRouter(HMAC(mockDao, testBox, time.Minute))
+
+	w := httptest.NewRecorder()
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	router.ServeHTTP(w, signedRequest(id, signingSecret, timestamp, strings.Repeat("a", MaxSignedBody+1)))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"BODY_TOO_LARGE"`)
+}
+
+func TestAdminToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		expected      int
+	}{
+		{"valid token", "secret-admin-token", "Bearer secret-admin-token", http.StatusOK},
+		{"wrong token", "secret-admin-token", "Bearer nope", http.StatusUnauthorized},
+		{"missing token", "secret-admin-token", "", http.StatusUnauthorized},
+		{"admin disabled", "", "Bearer ", http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/admin", AdminToken(test.token), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/admin", nil)
+			req.Header.Set("Authorization", test.authorization)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, test.expected, w.Code)
+		})
+	}
+}
+
+func TestIdentity_CanAccess(t *testing.T) {
+	client := Identity{ClientID: "client-1", Roles: []string{RoleRead}}
+	admin := Identity{ClientID: "ops", Roles: []string{RoleAdmin}}
+
+	assert.True(t, client.CanAccess("client-1"))
+	assert.False(t, client.CanAccess("client-2"))
+	assert.False(t, client.CanAccess(""))
+	assert.True(t, admin.CanAccess("client-2"))
+	assert.True(t, admin.HasRole(RoleTransfer))
+	assert.False(t, client.HasRole(RoleTransfer))
+}
+
+func TestRequireRole(t *testing.T) {
+	router := gin.New()
+	router.POST("/anonymous", RequireRole(RoleRead), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	router.POST("/reader", WithIdentity(Identity{ClientID: "client-1", Roles: []string{RoleRead}}), RequireRole(RoleTransfer), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	router.POST("/admin", WithIdentity(Identity{ClientID: "ops", Roles: []string{RoleAdmin}}), RequireRole(RoleTransfer), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for path, code := range map[string]int{
+		"/anonymous": http.StatusUnauthorized,
+		"/reader":    http.StatusForbidden,
+		"/admin":     http.StatusNoContent,
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, code, w.Code, path)
+	}
+}
+
+func TestTenancy(t *testing.T) {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if id := c.GetHeader("X-Test-Tenant"); id != "-" {
+			c.Request = c.Request.WithContext(NewContext(c.Request.Context(), Identity{ClientID: "client-1", TenantID: id}))
+		}
+	}, Tenancy())
+	router.GET("/tenant", func(c *gin.Context) {
+		id, _ := tenant.FromContext(c.Request.Context())
+		c.String(http.StatusOK, id)
+	})
+
+	cases := []struct {
+		name      string
+		bound     string
+		requested string
+		code      int
+		body      string
+	}{
+		{name: "credentials tenant", bound: "retail", code: http.StatusOK, body: "retail"},
+		{name: "matching header", bound: "retail", requested: "retail", code: http.StatusOK, body: "retail"},
+		{name: "other tenant", bound: "retail", requested: "corporate", code: http.StatusForbidden, body: "{\"type\":\"urn:transactions:problem:forbidden\",\"title\":\"The request is not allowed\",\"status\":403,\"detail\":\"credentials are not valid for this tenant\",\"instance\":\"/tenant\",\"code\":\"FORBIDDEN\"}"},
+		{name: "unbound picks tenant", requested: "corporate", code: http.StatusOK, body: "corporate"},
+		{name: "unbound default", code: http.StatusOK, body: tenant.Default},
+		{name: "no identity", bound: "-", code: http.StatusOK, body: tenant.Default},
+		{name: "invalid", requested: "Corporate,Retail", code: http.StatusBadRequest, body: "{\"type\":\"urn:transactions:problem:validation-failed\",\"title\":\"The request is invalid\",\"status\":400,\"detail\":\"the request has invalid fields\",\"instance\":\"/tenant\",\"code\":\"VALIDATION_FAILED\",\"errors\":[{\"field\":\"X-Tenant-ID\",\"message\":\"is not a valid tenant id\"}]}"},
+	}
+
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/tenant", nil)
+		req.Header.Set("X-Test-Tenant", tc.bound)
+		if tc.requested != "" {
+			req.Header.Set(TenantHeader, tc.requested)
+		}
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.code, w.Code, tc.name)
+		assert.Equal(t, tc.body, w.Body.String(), tc.name)
+	}
+}
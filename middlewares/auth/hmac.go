@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	apikeysdao "github.com/ashwin-m/transactions/daos/apikeys"
+	"github.com/ashwin-m/transactions/utils/secretbox"
+)
+
+const (
+	hmacScheme      = "HMAC-SHA256"
+	timestampHeader = "X-Timestamp"
+
+	// MaxSignedBody is the largest body of a signed request, it is read
+	// whole to check the signature.
+	MaxSignedBody = 1 << 20
+)
+
+// Sign computes the signature of a request with the signing secret issued
+// with its API key:
+// signature = HMAC-SHA256(signingSecret, method \n requestURI \n timestamp \n hex(SHA-256(body))).
+func Sign(signingSecret string, method, requestURI, timestamp string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	stringToSign := strings.Join([]string{method, requestURI, timestamp, hex.EncodeToString(bodyHash[:])}, "\n")
+
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte(stringToSign))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// AuthorizationHeader builds the Authorization header value for a signed request.
+func AuthorizationHeader(keyId, signature string) string {
+	return fmt.Sprintf("%s KeyId=%s,Signature=%s", hmacScheme, keyId, signature)
+}
+
+type hmacAuthenticator struct {
+	dao     apikeysdao.Dao
+	box     *secretbox.Box
+	maxSkew time.Duration
+	now     func() time.Time
+}
+
+// HMAC authenticates requests signed with the signing secret of an API key,
+// see Sign, opening the stored secrets with box. Requests whose X-Timestamp
+// differs from the server clock by more than maxSkew are rejected to limit
+// replays.
+func HMAC(dao apikeysdao.Dao, box *secretbox.Box, maxSkew time.Duration) Authenticator {
+	return &hmacAuthenticator{
+		dao:     dao,
+		box:     box,
+		maxSkew: maxSkew,
+		now:     time.Now,
+	}
+}
+
+func (a *hmacAuthenticator) Authenticate(r *http.Request) (Identity, error) {
+	params, ok := strings.CutPrefix(r.Header.Get("Authorization"), hmacScheme+" ")
+	if !ok {
+		return Identity{}, ErrNoCredentials
+	}
+
+	keyId, signature, err := parseHMACParams(params)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	timestamp := r.Header.Get(timestampHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: invalid %s header", ErrInvalidCredentials, timestampHeader)
+	}
+
+	skew := a.now().Sub(time.Unix(seconds, 0))
+	if skew > a.maxSkew || skew < -a.maxSkew {
+		return Identity{}, fmt.Errorf("%w: request timestamp outside allowed skew", ErrInvalidCredentials)
+	}
+
+	apiKey, err := a.dao.GetById(r.Context(), keyId)
+	if errors.Is(err, apikeysdao.ErrNotFound) {
+		return Identity{}, fmt.Errorf("%w: unknown api key %s", ErrInvalidCredentials, keyId)
+	}
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if apiKey.IsRevoked() {
+		return Identity{}, fmt.Errorf("%w: api key %s is revoked", ErrInvalidCredentials, keyId)
+	}
+
+	if apiKey.GetSigningSecret() == nil {
+		return Identity{}, fmt.Errorf("%w: api key %s has no signing secret, rotate it to sign requests", ErrInvalidCredentials, keyId)
+	}
+	signingSecret, err := a.box.Open(apiKey.GetSigningSecret(), []byte(keyId))
+	if err != nil {
+		return Identity{}, fmt.Errorf("unable to open the signing secret of api key %s: %w", keyId, err)
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, MaxSignedBody))
+	if err != nil {
+		return Identity{}, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	expected := Sign(string(signingSecret), r.Method, r.URL.RequestURI(), timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return Identity{}, fmt.Errorf("%w: signature mismatch for api key %s", ErrInvalidCredentials, keyId)
+	}
+
+	return Identity{ClientID: apiKey.GetClientId(), KeyID: keyId, Method: MethodHMAC, Roles: apiKey.GetRoles(), TenantID: apiKey.GetTenantId()}, nil
+}
+
+func parseHMACParams(params string) (keyId, signature string, err error) {
+	for _, param := range strings.Split(params, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok {
+			continue
+		}
+
+		switch name {
+		case "KeyId":
+			keyId = value
+		case "Signature":
+			signature = value
+		}
+	}
+
+	if keyId == "" || signature == "" {
+		return "", "", fmt.Errorf("%w: HMAC authorization needs KeyId and Signature", ErrInvalidCredentials)
+	}
+
+	return keyId, signature, nil
+}
@@ -0,0 +1,319 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// minJWKSRefresh limits how often the key set is reloaded, whether it
+	// is stale or a token names an unknown kid, so tokens with made up key
+	// ids and a failing source can't be used to hammer the JWKS source.
+	minJWKSRefresh = 30 * time.Second
+
+	// jwksFetchTimeout bounds a reload, which doesn't belong to any one
+	// request.
+	jwksFetchTimeout = 10 * time.Second
+
+	maxJWKSSize = 1 << 20
+)
+
+type JWKSOptions struct {
+	// File and URL are the two places a key set can be loaded from, exactly
+	// one of them must be set.
+	File string
+	URL  string
+	// RefreshInterval is how long a loaded key set is used before it is
+	// reloaded.
+	RefreshInterval time.Duration
+	// Client fetches URL, http.DefaultClient when nil.
+	Client *http.Client
+}
+
+// JWKS is a cached JSON Web Key Set. Keys are reloaded once RefreshInterval
+// has passed, or sooner when a token names a key id that isn't in the cache,
+// which is how signing key rotations are picked up. Reloads are shared by
+// every request waiting for them and the cached keys are kept when one
+// fails.
+type JWKS struct {
+	opts JWKSOptions
+	now  func() time.Time
+
+	mu       sync.Mutex
+	keys     map[string]any
+	loadedAt time.Time
+	// attemptedAt is when the last reload started, whatever its outcome.
+	attemptedAt time.Time
+	// refreshing is closed when the reload in progress ends, nil when
+	// there is none.
+	refreshing chan struct{}
+}
+
+// NewJWKS loads the key set once so that misconfiguration is reported at
+// startup rather than on the first request.
+func NewJWKS(ctx context.Context, opts JWKSOptions) (*JWKS, error) {
+	if (opts.File == "") == (opts.URL == "") {
+		return nil, errors.New("jwks: exactly one of File or URL must be set")
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+
+	jwks := &JWKS{
+		opts: opts,
+		now:  time.Now,
+	}
+
+	jwks.attemptedAt = jwks.now()
+	err := jwks.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return jwks, nil
+}
+
+// Key returns the verification key with the given id. A stale key set is
+// reloaded in the background while its keys are still served, only a
+// request naming an unknown kid waits for the reload.
+func (j *JWKS) Key(ctx context.Context, kid string) (any, error) {
+	j.mu.Lock()
+	key, ok := j.keys[kid]
+	var done chan struct{}
+	if !ok || j.now().Sub(j.loadedAt) >= j.opts.RefreshInterval {
+		done = j.startRefresh(ctx)
+	}
+	j.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+	if done == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	j.mu.Lock()
+	key, ok = j.keys[kid]
+	j.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	return key, nil
+}
+
+// startRefresh reloads the key set in the background, unless a reload is
+// already in progress, which is joined, or one started less than
+// minJWKSRefresh ago. It returns a channel closed once the reload ends, nil
+// when there is none. mu must be held.
+func (j *JWKS) startRefresh(ctx context.Context) chan struct{} {
+	if j.refreshing != nil {
+		return j.refreshing
+	}
+	if j.now().Sub(j.attemptedAt) < minJWKSRefresh {
+		return nil
+	}
+
+	j.attemptedAt = j.now()
+	done := make(chan struct{})
+	j.refreshing = done
+
+	// the reload outlives the request that started it
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jwksFetchTimeout)
+	go func() {
+		defer cancel()
+
+		err := j.refresh(ctx)
+		if err != nil {
+			// Keep serving the cached keys while the source is unavailable.
+			slog.WarnContext(ctx, "unable to refresh jwks", slog.Any("error", err))
+		}
+
+		j.mu.Lock()
+		j.refreshing = nil
+		j.mu.Unlock()
+		close(done)
+	}()
+
+	return done
+}
+
+// refresh loads the key set and replaces the cached one. It must be called
+// without mu held.
+func (j *JWKS) refresh(ctx context.Context) error {
+	data, err := j.read(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.loadedAt = j.now()
+	j.mu.Unlock()
+
+	return nil
+}
+
+func (j *JWKS) read(ctx context.Context) ([]byte, error) {
+	if j.opts.File != "" {
+		data, err := os.ReadFile(j.opts.File)
+		if err != nil {
+			return nil, fmt.Errorf("jwks: %w", err)
+		}
+		return data, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.opts.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+
+	resp, err := j.opts.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: %s returned %s", j.opts.URL, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+	if err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+
+	return data, nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS returns the RSA and P-256 signing keys of a key set by key id.
+// Keys of other types are skipped so a shared key set may contain them.
+func parseJWKS(data []byte) (map[string]any, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	err := json.Unmarshal(data, &set)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+
+	keys := map[string]any{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var key any
+		switch jwk.Kty {
+		case "RSA":
+			key, err = parseRSAKey(jwk)
+		case "EC":
+			if jwk.Crv != "P-256" {
+				continue
+			}
+			key, err = parseP256Key(jwk)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jwks: key %q: %w", jwk.Kid, err)
+		}
+
+		keys[jwk.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("jwks: no usable signing keys")
+	}
+
+	return keys, nil
+}
+
+func parseRSAKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) < 256 || !exponent.IsInt64() || exponent.Int64() < 3 {
+		return nil, errors.New("rsa keys must be at least 2048 bits")
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+func parseP256Key(jwk jsonWebKey) (*ecdsa.PublicKey, error) {
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil || len(x) != 32 {
+		return nil, errors.New("invalid x coordinate")
+	}
+	y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil || len(y) != 32 {
+		return nil, errors.New("invalid y coordinate")
+	}
+
+	// ecdh checks the point is on the curve.
+	_, err = ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+}
+
+// keyfunc resolves the key a token was signed with.
+func (j *JWKS) keyfunc(ctx context.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token has no kid header")
+		}
+
+		return j.Key(ctx, kid)
+	}
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtAlgorithms are the only signing algorithms accepted, so tokens signed
+// with "none" or an HMAC secret can never pass.
+var jwtAlgorithms = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}
+
+type JWTOptions struct {
+	Issuer   string
+	Audience string
+	// ClientIDClaim names the claim used as the client id, sub by default.
+	ClientIDClaim string
+	// RolesClaim names the claim holding the granted roles, either as a list
+	// or a space separated string. Tokens without it get no roles.
+	RolesClaim string
+	// TenantClaim names the claim holding the caller's tenant, tenant_id by
+	// default. Tokens without it belong to the default tenant.
+	TenantClaim string
+	// Leeway allows for clock skew when checking exp, nbf and iat.
+	Leeway time.Duration
+}
+
+type jwtAuthenticator struct {
+	jwks   *JWKS
+	opts   JWTOptions
+	parser *jwt.Parser
+}
+
+// JWT authenticates requests carrying a bearer token issued by the gateway.
+// Tokens must be signed with RS256 or ES256 by a key in jwks, come from the
+// configured issuer and audience, and expire.
+func JWT(jwks *JWKS, opts JWTOptions) Authenticator {
+	if opts.ClientIDClaim == "" {
+		opts.ClientIDClaim = "sub"
+	}
+	if opts.RolesClaim == "" {
+		opts.RolesClaim = "roles"
+	}
+	if opts.TenantClaim == "" {
+		opts.TenantClaim = "tenant_id"
+	}
+
+	return &jwtAuthenticator{
+		jwks: jwks,
+		opts: opts,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(jwtAlgorithms),
+			jwt.WithIssuer(opts.Issuer),
+			jwt.WithAudience(opts.Audience),
+			jwt.WithExpirationRequired(),
+			jwt.WithIssuedAt(),
+			jwt.WithLeeway(opts.Leeway),
+		),
+	}
+}
+
+func (a *jwtAuthenticator) Authenticate(r *http.Request) (Identity, error) {
+	raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || strings.HasPrefix(raw, apiKeyPrefix+"_") {
+		return Identity{}, ErrNoCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(raw, claims, a.jwks.keyfunc(r.Context()))
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+
+	clientId, _ := claims[a.opts.ClientIDClaim].(string)
+	if clientId == "" {
+		return Identity{}, fmt.Errorf("%w: token has no %s claim", ErrInvalidCredentials, a.opts.ClientIDClaim)
+	}
+
+	tenantId, _ := claims[a.opts.TenantClaim].(string)
+	if tenantId == "" {
+		tenantId = tenant.Default
+	}
+
+	keyId, _ := claims["jti"].(string)
+
+	return Identity{ClientID: clientId, KeyID: keyId, Method: MethodJWT, Roles: rolesFromClaim(claims[a.opts.RolesClaim]), TenantID: tenantId}, nil
+}
+
+// rolesFromClaim keeps the known roles of a claim, ignoring any other scopes
+// the gateway may have granted.
+func rolesFromClaim(claim any) []string {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		values = strings.Fields(v)
+	case []any:
+		for _, value := range v {
+			if s, ok := value.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	var roles []string
+	for _, value := range values {
+		if ValidRole(value) {
+			roles = append(roles, value)
+		}
+	}
+
+	return roles
+}
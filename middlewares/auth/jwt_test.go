@@ -0,0 +1,317 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testIssuer   = "https://gateway.example.com"
+	testAudience = "transactions"
+)
+
+func jwkFor(kid string, key crypto.PublicKey) map[string]string {
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": encode(k.N.Bytes()), "e": encode(big.NewInt(int64(k.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": encode(k.X.FillBytes(make([]byte, 32))), "y": encode(k.Y.FillBytes(make([]byte, 32)))}
+	}
+
+	panic("unsupported key")
+}
+
+func jwksDocument(t *testing.T, keys ...map[string]string) []byte {
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	assert.NoError(t, err)
+	return data
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key crypto.PrivateKey, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	assert.NoError(t, err)
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":       testIssuer,
+		"aud":       testAudience,
+		"sub":       "client-1",
+		"iat":       time.Now().Unix(),
+		"exp":       time.Now().Add(time.Minute).Unix(),
+		"roles":     []string{"read", "transfer", "billing"},
+		"tenant_id": "retail",
+	}
+}
+
+func newJWTRouter(jwks *JWKS) http.Handler {
+	return newRouter(JWT(jwks, JWTOptions{Issuer: testIssuer, Audience: testAudience}))
+}
+
+func authenticate(router http.Handler, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestJWT_RS256FromFile(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, jwksDocument(t, jwkFor("rsa-1", &key.PublicKey)), 0o600))
+
+	jwks, err := NewJWKS(context.Background(), JWKSOptions{File: path, RefreshInterval: time.Hour})
+	assert.NoError(t, err)
+
+	w := authenticate(newJWTRouter(jwks), signToken(t, jwt.SigningMethodRS256, "rsa-1", key, validClaims()))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{\"client_id\":\"client-1\",\"method\":\"jwt\",\"roles\":[\"read\",\"transfer\"],\"tenant_id\":\"retail\"}", w.Body.String())
+}
+
+func TestJWT_ES256FromURL(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(jwksDocument(t, jwkFor("ec-1", &key.PublicKey)))
+	}))
+	defer server.Close()
+
+	jwks, err := NewJWKS(context.Background(), JWKSOptions{URL: server.URL, RefreshInterval: time.Hour})
+	assert.NoError(t, err)
+
+	w := authenticate(newJWTRouter(jwks), signToken(t, jwt.SigningMethodES256, "ec-1", key, validClaims()))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestJWT_RejectsInvalidTokens(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, jwksDocument(t, jwkFor("rsa-1", &key.PublicKey)), 0o600))
+
+	jwks, err := NewJWKS(context.Background(), JWKSOptions{File: path, RefreshInterval: time.Hour})
+	assert.NoError(t, err)
+	router := newJWTRouter(jwks)
+
+	withClaim := func(name string, value any) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tokens := map[string]string{
+		"wrong issuer":   signToken(t, jwt.SigningMethodRS256, "rsa-1", key, withClaim("iss", "https://evil.example.com")),
+		"wrong audience": signToken(t, jwt.SigningMethodRS256, "rsa-1", key, withClaim("aud", "other-service")),
+		"expired":        signToken(t, jwt.SigningMethodRS256, "rsa-1", key, withClaim("exp", time.Now().Add(-time.Hour).Unix())),
+		"no expiry":      signToken(t, jwt.SigningMethodRS256, "rsa-1", key, withClaim("exp", nil)),
+		"no subject":     signToken(t, jwt.SigningMethodRS256, "rsa-1", key, withClaim("sub", nil)),
+		"wrong key":      signToken(t, jwt.SigningMethodRS256, "rsa-1", otherKey, validClaims()),
+		"unknown kid":    signToken(t, jwt.SigningMethodRS256, "rsa-2", key, validClaims()),
+		"hmac":           signToken(t, jwt.SigningMethodHS256, "rsa-1", []byte("secret"), validClaims()),
+		"none":           signToken(t, jwt.SigningMethodNone, "rsa-1", jwt.UnsafeAllowNoneSignatureType, validClaims()),
+		"malformed":      "not-a-token",
+	}
+
+	for name, token := range tokens {
+		w := authenticate(router, token)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code, name)
+		assert.Equal(t, "{\"type\":\"urn:transactions:problem:unauthorized\",\"title\":\"Authentication is required\",\"status\":401,\"detail\":\"invalid credentials\",\"instance\":\"/transactions\",\"code\":\"UNAUTHORIZED\"}", w.Body.String(), name)
+	}
+}
+
+func TestJWT_LeavesAPIKeyBearerTokens(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, jwksDocument(t, jwkFor("rsa-1", &key.PublicKey)), 0o600))
+
+	jwks, err := NewJWKS(context.Background(), JWKSOptions{File: path, RefreshInterval: time.Hour})
+	assert.NoError(t, err)
+
+	w := authenticate(newJWTRouter(jwks), "tk_abc_def")
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:unauthorized\",\"title\":\"Authentication is required\",\"status\":401,\"detail\":\"missing credentials\",\"instance\":\"/transactions\",\"code\":\"UNAUTHORIZED\"}", w.Body.String())
+}
+
+func TestJWKS_PicksUpRotatedKeys(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	var document atomic.Value
+	document.Store(jwksDocument(t, jwkFor("old", &oldKey.PublicKey)))
+	var fetches atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write(document.Load().([]byte))
+	}))
+	defer server.Close()
+
+	jwks, err := NewJWKS(context.Background(), JWKSOptions{URL: server.URL, RefreshInterval: time.Hour})
+	assert.NoError(t, err)
+
+	now := time.Now()
+	jwks.now = func() time.Time { return now }
+	router := newJWTRouter(jwks)
+
+	document.Store(jwksDocument(t, jwkFor("new", &newKey.PublicKey)))
+	newToken := signToken(t, jwt.SigningMethodRS256, "new", newKey, validClaims())
+
+	// An unknown kid right after loading doesn't trigger another fetch.
+	assert.Equal(t, http.StatusUnauthorized, authenticate(router, newToken).Code)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	now = now.Add(minJWKSRefresh)
+	assert.Equal(t, http.StatusOK, authenticate(router, newToken).Code)
+	assert.Equal(t, int32(2), fetches.Load())
+
+	// The cached set is replaced, so the retired key no longer verifies.
+	oldToken := signToken(t, jwt.SigningMethodRS256, "old", oldKey, validClaims())
+	assert.Equal(t, http.StatusUnauthorized, authenticate(router, oldToken).Code)
+}
+
+func TestJWKS_KeepsCachedKeysWhenSourceFails(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write(jwksDocument(t, jwkFor("rsa-1", &key.PublicKey)))
+	}))
+	defer server.Close()
+
+	jwks, err := NewJWKS(context.Background(), JWKSOptions{URL: server.URL, RefreshInterval: time.Minute})
+	assert.NoError(t, err)
+
+	now := time.Now()
+	jwks.now = func() time.Time { return now.Add(time.Hour) }
+	failing.Store(true)
+
+	w := authenticate(newJWTRouter(jwks), signToken(t, jwt.SigningMethodRS256, "rsa-1", key, validClaims()))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestJWKS_SharesAndLimitsRefreshes(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	var document atomic.Value
+	document.Store(jwksDocument(t, jwkFor("old", &key.PublicKey)))
+	var fetches atomic.Int32
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			<-release
+		}
+		w.Write(document.Load().([]byte))
+	}))
+	defer server.Close()
+
+	jwks, err := NewJWKS(context.Background(), JWKSOptions{URL: server.URL, RefreshInterval: time.Hour})
+	assert.NoError(t, err)
+
+	var now atomic.Int64
+	now.Store(time.Now().Add(minJWKSRefresh).UnixNano())
+	jwks.now = func() time.Time { return time.Unix(0, now.Load()) }
+	document.Store(jwksDocument(t, jwkFor("new", &key.PublicKey)))
+
+	// a request giving up doesn't stop the reload it started
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = jwks.Key(ctx, "new")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// requests for an unknown kid wait for the reload in progress
+	results := make(chan error, 10)
+	for range 10 {
+		go func() {
+			_, err := jwks.Key(context.Background(), "new")
+			results <- err
+		}()
+	}
+	close(release)
+	for range 10 {
+		assert.NoError(t, <-results)
+	}
+	assert.Equal(t, int32(2), fetches.Load())
+
+	// a failed reload keeps the keys and isn't retried right away
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	now.Add(int64(time.Hour))
+
+	_, err = jwks.Key(context.Background(), "missing")
+	assert.Error(t, err)
+	_, err = jwks.Key(context.Background(), "missing")
+	assert.Error(t, err)
+	_, err = jwks.Key(context.Background(), "new")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), fetches.Load())
+}
+
+func TestNewJWKS_RejectsUnusableKeySets(t *testing.T) {
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+
+	for name, document := range map[string][]byte{
+		"empty":    []byte(`{"keys":[]}`),
+		"weak rsa": jwksDocument(t, jwkFor("weak", &weakKey.PublicKey)),
+		"bad ec":   []byte(`{"keys":[{"kty":"EC","kid":"ec","crv":"P-256","x":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA","y":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"}]}`),
+		"not json": []byte(`keys`),
+	} {
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		assert.NoError(t, os.WriteFile(path, document, 0o600))
+
+		_, err := NewJWKS(context.Background(), JWKSOptions{File: path, RefreshInterval: time.Hour})
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RoleRead allows reading accounts the caller owns.
+	RoleRead = "read"
+	// RoleTransfer allows creating accounts and moving money out of accounts
+	// the caller owns.
+	RoleTransfer = "transfer"
+	// RoleAudit allows reading and verifying the audit log of the tenant.
+	RoleAudit = "audit"
+	// RoleAdmin grants every permission on every account.
+	RoleAdmin = "admin"
+)
+
+var (
+	Roles = []string{RoleRead, RoleTransfer, RoleAudit, RoleAdmin}
+
+	// DefaultRoles are given to api keys created without explicit roles.
+	DefaultRoles = []string{RoleRead, RoleTransfer}
+)
+
+func ValidRole(role string) bool {
+	return slices.Contains(Roles, role)
+}
+
+// HasRole reports whether the identity was granted role. Admins have every
+// role.
+func (i Identity) HasRole(role string) bool {
+	return slices.Contains(i.Roles, RoleAdmin) || slices.Contains(i.Roles, role)
+}
+
+// CanAccess reports whether the identity may act on an account owned by
+// ownerId.
+func (i Identity) CanAccess(ownerId string) bool {
+	if i.HasRole(RoleAdmin) {
+		return true
+	}
+
+	return ownerId != "" && i.ClientID == ownerId
+}
+
+// RequireRole rejects requests whose identity lacks role. It must run after
+// the authentication middleware.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, ok := FromContext(c.Request.Context())
+		if !ok {
+			unauthorized(c, "missing credentials")
+			return
+		}
+
+		if !identity.HasRole(role) {
+			apperrors.Abort(c, apperrors.New(apperrors.CodeForbidden, "insufficient permissions"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// WithIdentity attaches a fixed identity to every request. It is used in
+// place of authentication when auth is disabled, and in tests.
+func WithIdentity(identity Identity) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), identity))
+		c.Next()
+	}
+}
+
+// Static authenticates every request as identity, the counterpart of
+// WithIdentity for APIs that aren't served by gin.
+func Static(identity Identity) Authenticator {
+	return staticAuthenticator{identity: identity}
+}
+
+type staticAuthenticator struct {
+	identity Identity
+}
+
+func (a staticAuthenticator) Authenticate(*http.Request) (Identity, error) {
+	return a.identity, nil
+}
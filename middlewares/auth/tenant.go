@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+const TenantHeader = "X-Tenant-ID"
+
+// Tenancy stores the tenant a request acts on in the request context. It is
+// the tenant of the caller's credentials. Identities that aren't bound to a
+// tenant pick one with the X-Tenant-ID header, and get the default tenant
+// otherwise. It must run after the authentication middleware.
+func Tenancy() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, _ := FromContext(c.Request.Context())
+
+		tenantId, err := ResolveTenant(identity, c.GetHeader(TenantHeader))
+		if err != nil {
+			apperrors.Abort(c, err)
+			return
+		}
+
+		c.Request = c.Request.WithContext(tenant.NewContext(c.Request.Context(), tenantId))
+		c.Next()
+	}
+}
+
+// ResolveTenant picks the tenant identity acts on given the tenant it
+// requested, which may be empty.
+func ResolveTenant(identity Identity, requested string) (string, error) {
+	tenantId := identity.TenantID
+	switch {
+	case tenantId == "" && requested != "":
+		tenantId = requested
+	case tenantId == "":
+		tenantId = tenant.Default
+	case requested != "" && requested != tenantId:
+		return "", apperrors.New(apperrors.CodeForbidden, "credentials are not valid for this tenant")
+	}
+
+	if !tenant.Valid(tenantId) {
+		return "", apperrors.Validation(apperrors.FieldError{Field: TenantHeader, Message: "is not a valid tenant id"})
+	}
+
+	return tenantId, nil
+}
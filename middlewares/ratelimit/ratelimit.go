@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/metrics"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	headerLimit      = "RateLimit-Limit"
+	headerRemaining  = "RateLimit-Remaining"
+	headerReset      = "RateLimit-Reset"
+	headerRetryAfter = "Retry-After"
+)
+
+// maxTransferBody is the largest transfer BySourceAccount reads, far more
+// than any valid one.
+const maxTransferBody = 64 << 10
+
+// KeyFunc names the bucket a request is counted against. Requests it returns
+// false for are not limited, unless it aborted them.
+type KeyFunc func(c *gin.Context) (string, bool)
+
+// Middleware limits requests per key with a token bucket. The RateLimit
+// headers describe whichever limit applied to the request has the fewest
+// requests left. A failing store lets requests through rather than taking
+// the service down with it.
+func Middleware(name string, store Store, limit Limit, key KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !limit.Enabled() {
+			c.Next()
+			return
+		}
+
+		k, ok := key(c)
+		if c.IsAborted() {
+			return
+		}
+		if !ok {
+			c.Next()
+			return
+		}
+
+		result, err := store.Take(c.Request.Context(), name+":"+k, limit)
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "rate limit store failed, allowing request", slog.String("limiter", name), slog.Any("error", err))
+			c.Next()
+			return
+		}
+
+		setHeaders(c, result)
+
+		if !result.Allowed {
+			metrics.RateLimited(name)
+			c.Header(headerRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			apperrors.Abort(c, apperrors.New(apperrors.CodeRateLimited, "rate limit exceeded"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func setHeaders(c *gin.Context, result Result) {
+	if current := c.Writer.Header().Get(headerRemaining); current != "" {
+		remaining, err := strconv.Atoi(current)
+		if err == nil && remaining <= result.Remaining {
+			return
+		}
+	}
+
+	c.Header(headerLimit, strconv.Itoa(result.Limit))
+	c.Header(headerRemaining, strconv.Itoa(result.Remaining))
+	c.Header(headerReset, strconv.Itoa(ceilSeconds(result.Reset)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// ByClient counts requests against the authenticated client.
+func ByClient(c *gin.Context) (string, bool) {
+	identity, ok := auth.FromContext(c.Request.Context())
+	if !ok {
+		return "", false
+	}
+
+	tenantId, _ := tenant.FromContext(c.Request.Context())
+	return fmt.Sprintf("%s/%s", tenantId, identity.ClientID), true
+}
+
+// BySourceAccount counts the transfers of a client against the account they
+// debit. Whether the client may debit it is only known later, so each client
+// has its own bucket per account, and transfers attempted by others can't
+// use up the owner's. The body is put back for the handler, which also
+// reports malformed requests, those over maxTransferBody are rejected.
+func BySourceAccount(c *gin.Context) (string, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxTransferBody))
+	if err != nil {
+		apperrors.Abort(c, err)
+		return "", false
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var request struct {
+		SourceAccountId *int64 `json:"source_account_id"`
+	}
+	if json.Unmarshal(body, &request) != nil || request.SourceAccountId == nil {
+		return "", false
+	}
+
+	identity, _ := auth.FromContext(c.Request.Context())
+	tenantId, _ := tenant.FromContext(c.Request.Context())
+	return AccountKey(tenantId, identity.ClientID, *request.SourceAccountId), true
+}
+
+// AccountKey names the bucket the transfers of a client out of an account
+// are counted against.
+func AccountKey(tenantId, clientId string, accountId int64) string {
+	return fmt.Sprintf("%s/%s/%d", tenantId, clientId, accountId)
+}
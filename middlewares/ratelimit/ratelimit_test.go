@@ -0,0 +1,154 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit) (Result, error) {
+	return Result{}, errors.New("store unavailable")
+}
+
+func newRouter(handlers ...gin.HandlerFunc) *gin.Engine {
+	router := gin.New()
+	router.Use(auth.WithIdentity(auth.Identity{ClientID: "client-1"}))
+	router.POST("/transactions", append(handlers, func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})...)
+
+	return router
+}
+
+func post(router http.Handler, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transactions", strings.NewReader(body))
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware_LimitsClient(t *testing.T) {
+	router := newRouter(Middleware("client", NewMemoryStore(), Limit{Rate: 1, Burst: 2}, ByClient))
+
+	w := post(router, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Reset"))
+
+	w = post(router, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	w = post(router, "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:rate-limited\",\"title\":\"Too many requests\",\"status\":429,\"detail\":\"rate limit exceeded\",\"instance\":\"/transactions\",\"code\":\"RATE_LIMITED\"}", w.Body.String())
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+}
+
+func TestMiddleware_LimitsEachSourceAccount(t *testing.T) {
+	router := newRouter(Middleware("account", NewMemoryStore(), Limit{Rate: 1, Burst: 1}, BySourceAccount))
+
+	w := post(router, `{"source_account_id": 1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"source_account_id": 1}`, w.Body.String())
+
+	assert.Equal(t, http.StatusTooManyRequests, post(router, `{"source_account_id": 1}`).Code)
+	assert.Equal(t, http.StatusOK, post(router, `{"source_account_id": 2}`).Code)
+
+	// malformed bodies are left for the handler to reject
+	assert.Equal(t, http.StatusOK, post(router, `{"source_account_id": "x"}`).Code)
+}
+
+func TestMiddleware_SourceAccountBucketsArePerClient(t *testing.T) {
+	store := NewMemoryStore()
+	routerFor := func(clientId string) *gin.Engine {
+		router := gin.New()
+		router.Use(auth.WithIdentity(auth.Identity{ClientID: clientId}))
+		router.POST("/transactions", Middleware("account", store, Limit{Rate: 1, Burst: 1}, BySourceAccount), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		return router
+	}
+
+	// another client draining account 1 doesn't lock its owner out
+	assert.Equal(t, http.StatusOK, post(routerFor("client-2"), `{"source_account_id": 1}`).Code)
+	assert.Equal(t, http.StatusTooManyRequests, post(routerFor("client-2"), `{"source_account_id": 1}`).Code)
+	assert.Equal(t, http.StatusOK, post(routerFor("client-1"), `{"source_account_id": 1}`).Code)
+}
+
+func TestMiddleware_SourceAccountBodyTooLarge(t *testing.T) {
+	router := newRouter(Middleware("account", NewMemoryStore(), Limit{Rate: 1, Burst: 1}, BySourceAccount))
+
+	w := post(router, `{"source_account_id": 1, "note": "`+strings.Repeat("a", maxTransferBody)+`"}`)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"BODY_TOO_LARGE"`)
+}
+
+func TestMiddleware_ReportsTightestLimit(t *testing.T) {
+	store := NewMemoryStore()
+	router := newRouter(
+		Middleware("client", store, Limit{Rate: 10, Burst: 10}, ByClient),
+		Middleware("account", store, Limit{Rate: 1, Burst: 3}, BySourceAccount),
+	)
+
+	w := post(router, `{"source_account_id": 1}`)
+	assert.Equal(t, "3", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Remaining"))
+}
+
+func TestMiddleware_AllowsWhenStoreFails(t *testing.T) {
+	router := newRouter(Middleware("client", failingStore{}, Limit{Rate: 1, Burst: 1}, ByClient))
+
+	assert.Equal(t, http.StatusOK, post(router, "").Code)
+	assert.Equal(t, http.StatusOK, post(router, "").Code)
+}
+
+func TestMemoryStore_Refills(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	limit := Limit{Rate: 2, Burst: 2}
+
+	for range 2 {
+		result, _ := store.Take(context.Background(), "key", limit)
+		assert.True(t, result.Allowed)
+	}
+
+	result, _ := store.Take(context.Background(), "key", limit)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+	assert.Equal(t, time.Second, result.Reset)
+
+	now = now.Add(500 * time.Millisecond)
+	result, _ = store.Take(context.Background(), "key", limit)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
+
+func TestMemoryStore_SweepsFullBuckets(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 1}
+
+	store.Take(context.Background(), "idle", limit)
+	now = now.Add(sweepInterval)
+	store.Take(context.Background(), "busy", limit)
+
+	assert.NotContains(t, store.buckets, "idle")
+	assert.Contains(t, store.buckets, "busy")
+}
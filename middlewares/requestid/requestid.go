@@ -0,0 +1,70 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	Header = "X-Request-ID"
+
+	// maxLength bounds ids supplied by callers so they can't flood the logs.
+	maxLength = 128
+)
+
+type contextKey struct{}
+
+// Middleware assigns every request an id, reusing the X-Request-ID header when
+// the caller sent one. The id is echoed in the response headers and stored on
+// the request context for logging.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := Ensure(c.GetHeader(Header))
+
+		c.Header(Header, id)
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), id))
+
+		c.Next()
+	}
+}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Ensure returns id when it is a usable request id, and a new one otherwise.
+func Ensure(id string) string {
+	if !valid(id) {
+		return generate()
+	}
+
+	return id
+}
+
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+func generate() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
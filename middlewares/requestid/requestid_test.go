@@ -0,0 +1,56 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/id", func(c *gin.Context) {
+		c.String(http.StatusOK, FromContext(c.Request.Context()))
+	})
+
+	return router
+}
+
+func TestRequestId_HonorsIncomingHeader(t *testing.T) {
+	router := newRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/id", nil)
+	req.Header.Set(Header, "abc-123")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "abc-123", w.Header().Get(Header))
+	assert.Equal(t, "abc-123", w.Body.String())
+}
+
+func TestRequestId_GeneratedWhenMissing(t *testing.T) {
+	router := newRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/id", nil)
+	router.ServeHTTP(w, req)
+
+	id := w.Header().Get(Header)
+	assert.Len(t, id, 32)
+	assert.Equal(t, id, w.Body.String())
+}
+
+func TestRequestId_InvalidIncomingHeaderReplaced(t *testing.T) {
+	router := newRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/id", nil)
+	req.Header.Set(Header, strings.Repeat("a", maxLength+1))
+	router.ServeHTTP(w, req)
+
+	assert.Len(t, w.Header().Get(Header), 32)
+}
@@ -0,0 +1,111 @@
+// Package versioning serves several versions of the API side by side. Each
+// version registers its own handlers under a prefix like /v1, so a /v2 can
+// change response shapes while /v1 clients keep working. Versions on their
+// way out announce it with Deprecation and Sunset headers.
+package versioning
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler is implemented by the controllers making up a version.
+type Handler interface {
+	RouteGroup(gin.IRouter)
+	Describe(*openapi3.T)
+}
+
+type Version struct {
+	// Prefix is the path the routes of the version are registered under,
+	// empty for the routes served before the API was versioned.
+	Prefix   string
+	Handlers []Handler
+	// Deprecation marks the version deprecated when set.
+	Deprecation *Deprecation
+}
+
+type Deprecation struct {
+	// Since is when the version was deprecated.
+	Since time.Time
+	// Sunset is when the version stops being served. It isn't announced
+	// when zero.
+	Sunset time.Time
+	// Successor is the prefix of the version replacing this one.
+	Successor string
+}
+
+// Only serves the routes of h as it does, and describes them as only
+// served with the given storage backend.
+func Only(backend string, h Handler) Handler {
+	return only{Handler: h, backend: backend}
+}
+
+type only struct {
+	Handler
+	backend string
+}
+
+func (o only) Describe(doc *openapi3.T) {
+	openapi.Only(o.backend, o.Handler).Describe(doc)
+}
+
+// Register adds the routes of every version to r. middleware runs for every
+// route, after the deprecation headers are set so that errors carry them too.
+func Register(r gin.IRouter, versions []Version, middleware ...gin.HandlerFunc) {
+	for _, version := range versions {
+		group := r.Group(version.Prefix)
+		if version.Deprecation != nil {
+			group.Use(Deprecated(version.Prefix, *version.Deprecation))
+		}
+		group.Use(middleware...)
+
+		for _, h := range version.Handlers {
+			h.RouteGroup(group)
+		}
+	}
+}
+
+// Describe documents every version, marking the operations of deprecated
+// ones.
+func Describe(versions []Version) []openapi.Describer {
+	var describers []openapi.Describer
+	for _, version := range versions {
+		handlers := make([]openapi.Describer, len(version.Handlers))
+		for i, h := range version.Handlers {
+			handlers[i] = h
+		}
+		describers = append(describers, openapi.Mount(version.Prefix, version.Deprecation != nil, handlers...))
+	}
+
+	return describers
+}
+
+// Deprecated sets the Deprecation (RFC 9745) and Sunset (RFC 8594) headers
+// on responses of a version registered under prefix, and links to the same
+// route in the successor version.
+func Deprecated(prefix string, deprecation Deprecation) gin.HandlerFunc {
+	since := fmt.Sprintf("@%d", deprecation.Since.Unix())
+	var sunset string
+	if !deprecation.Sunset.IsZero() {
+		sunset = deprecation.Sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(c *gin.Context) {
+		c.Header("Deprecation", since)
+		if sunset != "" {
+			c.Header("Sunset", sunset)
+		}
+		if deprecation.Successor != "" {
+			path := deprecation.Successor + strings.TrimPrefix(c.Request.URL.Path, prefix)
+			c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, path))
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,105 @@
+package versioning
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// greeter is a stand in for a controller, answering with a different shape
+// in each version.
+type greeter struct {
+	greeting string
+}
+
+func (g greeter) RouteGroup(r gin.IRouter) {
+	r.GET("/greetings/:name", func(c *gin.Context) {
+		c.String(http.StatusOK, g.greeting+" "+c.Param("name"))
+	})
+}
+
+func (g greeter) Describe(doc *openapi3.T) {
+	op := openapi3.NewOperation()
+	op.OperationID = "greet"
+	op.AddResponse(http.StatusOK, openapi.EmptyResponse("A greeting"))
+	openapi.Operation(doc, http.MethodGet, "/greetings/:name", op)
+}
+
+var versions = []Version{
+	{Prefix: "/v2", Handlers: []Handler{greeter{"hello"}}},
+	{Prefix: "/v1", Handlers: []Handler{greeter{"hi"}}, Deprecation: &Deprecation{
+		Since:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:    time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+		Successor: "/v2",
+	}},
+}
+
+func get(router http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRegister_ServesVersionsSideBySide(t *testing.T) {
+	router := gin.New()
+	Register(router, versions)
+
+	w := get(router, "/v2/greetings/ann")
+	assert.Equal(t, "hello ann", w.Body.String())
+	assert.Empty(t, w.Header().Get("Deprecation"))
+
+	w = get(router, "/v1/greetings/ann")
+	assert.Equal(t, "hi ann", w.Body.String())
+	assert.Equal(t, "@1767225600", w.Header().Get("Deprecation"))
+	assert.Equal(t, "Wed, 01 Jul 2026 00:00:00 GMT", w.Header().Get("Sunset"))
+	assert.Equal(t, `</v2/greetings/ann>; rel="successor-version"`, w.Header().Get("Link"))
+}
+
+func TestRegister_ErrorsCarryDeprecationHeaders(t *testing.T) {
+	router := gin.New()
+	Register(router, versions, func(c *gin.Context) {
+		apperrors.Abort(c, apperrors.New(apperrors.CodeUnauthorized, "missing credentials"))
+	})
+
+	w := get(router, "/v1/greetings/ann")
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "@1767225600", w.Header().Get("Deprecation"))
+}
+
+func TestDeprecated_UnversionedRoutes(t *testing.T) {
+	router := gin.New()
+	Register(router, []Version{{Handlers: []Handler{greeter{"hi"}}, Deprecation: &Deprecation{Since: time.Unix(0, 0), Successor: "/v1"}}})
+
+	w := get(router, "/greetings/ann")
+
+	assert.Equal(t, "@0", w.Header().Get("Deprecation"))
+	assert.Empty(t, w.Header().Get("Sunset"))
+	assert.Equal(t, `</v1/greetings/ann>; rel="successor-version"`, w.Header().Get("Link"))
+}
+
+func TestDescribe(t *testing.T) {
+	doc := openapi.New(Describe(versions)...)
+	assert.NoError(t, doc.Validate(context.Background()))
+
+	v1 := doc.Paths.Find("/v1/greetings/{name}").Get
+	assert.Equal(t, "v1Greet", v1.OperationID)
+	assert.True(t, v1.Deprecated)
+
+	v2 := doc.Paths.Find("/v2/greetings/{name}").Get
+	assert.Equal(t, "v2Greet", v2.OperationID)
+	assert.False(t, v2.Deprecated)
+
+	router := gin.New()
+	Register(router, versions)
+	assert.Empty(t, openapi.Drift(doc, router.Routes()))
+}
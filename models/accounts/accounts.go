@@ -1,9 +1,12 @@
 package accounts
 
 type Accounts struct {
-	id      int64
-	balance float64
-	version int64
+	id             int64
+	balance        float64
+	initialBalance float64
+	version        int64
+	ownerId        string
+	tenantId       string
 }
 
 func (a *Accounts) GetId() int64 {
@@ -18,6 +21,10 @@ func (a *Accounts) GetVersion() int64 {
 	return a.version
 }
 
+func (a *Accounts) GetOwnerId() string {
+	return a.ownerId
+}
+
 func (a *Accounts) SetId(id int64) {
 	a.id = id
 }
@@ -29,3 +36,23 @@ func (a *Accounts) SetBalance(balance float64) {
 func (a *Accounts) SetVersion(version int64) {
 	a.version = version
 }
+
+func (a *Accounts) SetOwnerId(ownerId string) {
+	a.ownerId = ownerId
+}
+
+func (a *Accounts) GetTenantId() string {
+	return a.tenantId
+}
+
+func (a *Accounts) SetTenantId(tenantId string) {
+	a.tenantId = tenantId
+}
+
+func (a *Accounts) GetInitialBalance() float64 {
+	return a.initialBalance
+}
+
+func (a *Accounts) SetInitialBalance(initialBalance float64) {
+	a.initialBalance = initialBalance
+}
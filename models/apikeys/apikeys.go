@@ -0,0 +1,93 @@
+package apikeys
+
+import "time"
+
+type ApiKeys struct {
+	id       string
+	clientId string
+	tenantId string
+	name     string
+	roles    []string
+	keyHash  []byte
+	// signingSecret is sealed with the server signing key, nil for keys
+	// issued before signing secrets were.
+	signingSecret []byte
+	createdAt     time.Time
+	revokedAt     *time.Time
+}
+
+func (a *ApiKeys) GetId() string {
+	return a.id
+}
+
+func (a *ApiKeys) GetClientId() string {
+	return a.clientId
+}
+
+func (a *ApiKeys) GetTenantId() string {
+	return a.tenantId
+}
+
+func (a *ApiKeys) GetName() string {
+	return a.name
+}
+
+func (a *ApiKeys) GetRoles() []string {
+	return a.roles
+}
+
+func (a *ApiKeys) GetKeyHash() []byte {
+	return a.keyHash
+}
+
+func (a *ApiKeys) GetSigningSecret() []byte {
+	return a.signingSecret
+}
+
+func (a *ApiKeys) GetCreatedAt() time.Time {
+	return a.createdAt
+}
+
+func (a *ApiKeys) GetRevokedAt() *time.Time {
+	return a.revokedAt
+}
+
+func (a *ApiKeys) IsRevoked() bool {
+	return a.revokedAt != nil
+}
+
+func (a *ApiKeys) SetId(id string) {
+	a.id = id
+}
+
+func (a *ApiKeys) SetClientId(clientId string) {
+	a.clientId = clientId
+}
+
+func (a *ApiKeys) SetTenantId(tenantId string) {
+	a.tenantId = tenantId
+}
+
+func (a *ApiKeys) SetName(name string) {
+	a.name = name
+}
+
+func (a *ApiKeys) SetRoles(roles []string) {
+	a.roles = roles
+}
+
+func (a *ApiKeys) SetKeyHash(keyHash []byte) {
+	a.keyHash = keyHash
+}
+
+func (a *ApiKeys) SetSigningSecret(signingSecret []byte) {
+	a.signingSecret = signingSecret
+}
+
+func (a *ApiKeys) SetCreatedAt(createdAt time.Time) {
+	a.createdAt = createdAt
+}
+
+func (a *ApiKeys) SetRevokedAt(revokedAt *time.Time) {
+	a.revokedAt = revokedAt
+}
@@ -0,0 +1,160 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Snapshot is the state of an account before and after a request changed
+// it. Before is nil for accounts the request created.
+type Snapshot struct {
+	TenantId  string        `json:"tenant_id"`
+	AccountId int64         `json:"account_id"`
+	Before    *AccountState `json:"before"`
+	After     *AccountState `json:"after"`
+}
+
+type AccountState struct {
+	Balance float64 `json:"balance"`
+	OwnerId string  `json:"owner_id"`
+	Version int64   `json:"version"`
+}
+
+type Entries struct {
+	id         int64
+	tenantId   string
+	occurredAt time.Time
+	actor      string
+	authMethod string
+	requestId  string
+	method     string
+	endpoint   string
+	status     int
+	errorCode  string
+	payload    json.RawMessage
+	snapshots  []Snapshot
+	prevHash   string
+	hash       string
+}
+
+func (e *Entries) GetId() int64 {
+	return e.id
+}
+
+func (e *Entries) GetTenantId() string {
+	return e.tenantId
+}
+
+func (e *Entries) GetOccurredAt() time.Time {
+	return e.occurredAt
+}
+
+func (e *Entries) GetActor() string {
+	return e.actor
+}
+
+func (e *Entries) GetAuthMethod() string {
+	return e.authMethod
+}
+
+func (e *Entries) GetRequestId() string {
+	return e.requestId
+}
+
+func (e *Entries) GetMethod() string {
+	return e.method
+}
+
+func (e *Entries) GetEndpoint() string {
+	return e.endpoint
+}
+
+func (e *Entries) GetStatus() int {
+	return e.status
+}
+
+func (e *Entries) GetErrorCode() string {
+	return e.errorCode
+}
+
+func (e *Entries) GetPayload() json.RawMessage {
+	return e.payload
+}
+
+func (e *Entries) GetSnapshots() []Snapshot {
+	return e.snapshots
+}
+
+func (e *Entries) GetPrevHash() string {
+	return e.prevHash
+}
+
+func (e *Entries) GetHash() string {
+	return e.hash
+}
+
+func (e *Entries) SetId(id int64) {
+	e.id = id
+}
+
+func (e *Entries) SetTenantId(tenantId string) {
+	e.tenantId = tenantId
+}
+
+func (e *Entries) SetOccurredAt(occurredAt time.Time) {
+	e.occurredAt = occurredAt
+}
+
+func (e *Entries) SetActor(actor string) {
+	e.actor = actor
+}
+
+func (e *Entries) SetAuthMethod(authMethod string) {
+	e.authMethod = authMethod
+}
+
+func (e *Entries) SetRequestId(requestId string) {
+	e.requestId = requestId
+}
+
+func (e *Entries) SetMethod(method string) {
+	e.method = method
+}
+
+func (e *Entries) SetEndpoint(endpoint string) {
+	e.endpoint = endpoint
+}
+
+func (e *Entries) SetStatus(status int) {
+	e.status = status
+}
+
+func (e *Entries) SetErrorCode(errorCode string) {
+	e.errorCode = errorCode
+}
+
+func (e *Entries) SetPayload(payload json.RawMessage) {
+	e.payload = payload
+}
+
+func (e *Entries) SetSnapshots(snapshots []Snapshot) {
+	e.snapshots = snapshots
+}
+
+func (e *Entries) SetPrevHash(prevHash string) {
+	e.prevHash = prevHash
+}
+
+func (e *Entries) SetHash(hash string) {
+	e.hash = hash
+}
+
+// GetAccountIds returns the accounts the entry has snapshots of.
+func (e *Entries) GetAccountIds() []int64 {
+	ids := make([]int64, len(e.snapshots))
+	for i, snapshot := range e.snapshots {
+		ids[i] = snapshot.AccountId
+	}
+
+	return ids
+}
@@ -0,0 +1,185 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The types of the domain events written to the outbox.
+const (
+	TypeTransferPosted        = "transfer.posted"
+	TypeTransferFailed        = "transfer.failed"
+	TypeAccountCreated        = "account.created"
+	TypeAccountBalanceChanged = "account.balance_changed"
+)
+
+// Types lists every event type, in the order they are documented.
+var Types = []string{TypeTransferPosted, TypeTransferFailed, TypeAccountCreated, TypeAccountBalanceChanged}
+
+// Events is an event read back from the outbox. Its payload is one of the
+// data types below, as JSON.
+type Events struct {
+	id        int64
+	tenantId  string
+	eventType string
+	accountId int64
+	payload   json.RawMessage
+	createdAt time.Time
+	// xid is the transaction that recorded the event.
+	xid uint64
+	// attempts is the number of failed attempts at publishing the event.
+	attempts int
+}
+
+func (e *Events) GetId() int64 {
+	return e.id
+}
+
+func (e *Events) GetTenantId() string {
+	return e.tenantId
+}
+
+func (e *Events) GetType() string {
+	return e.eventType
+}
+
+func (e *Events) GetAccountId() int64 {
+	return e.accountId
+}
+
+func (e *Events) GetPayload() json.RawMessage {
+	return e.payload
+}
+
+func (e *Events) GetCreatedAt() time.Time {
+	return e.createdAt
+}
+
+func (e *Events) GetXid() uint64 {
+	return e.xid
+}
+
+func (e *Events) GetAttempts() int {
+	return e.attempts
+}
+
+// Cursor returns the position of the event among the events of its account.
+func (e *Events) Cursor() Cursor {
+	return Cursor{Xid: e.xid, Id: e.id}
+}
+
+func (e *Events) SetId(id int64) {
+	e.id = id
+}
+
+func (e *Events) SetTenantId(tenantId string) {
+	e.tenantId = tenantId
+}
+
+func (e *Events) SetType(eventType string) {
+	e.eventType = eventType
+}
+
+func (e *Events) SetAccountId(accountId int64) {
+	e.accountId = accountId
+}
+
+func (e *Events) SetPayload(payload json.RawMessage) {
+	e.payload = payload
+}
+
+func (e *Events) SetCreatedAt(createdAt time.Time) {
+	e.createdAt = createdAt
+}
+
+func (e *Events) SetXid(xid uint64) {
+	e.xid = xid
+}
+
+func (e *Events) SetAttempts(attempts int) {
+	e.attempts = attempts
+}
+
+// Cursor is a position in the events of an account, ordered by the
+// transaction that recorded them and then by id. Ids are handed out before
+// transactions commit, so they can't tell what was missed. Once every
+// transaction older than the oldest one still running has ended, no event
+// can come before the ones already read.
+type Cursor struct {
+	Xid uint64
+	Id  int64
+}
+
+// String formats c as its transaction and id, as in 812-42.
+func (c Cursor) String() string {
+	return strconv.FormatUint(c.Xid, 10) + "-" + strconv.FormatInt(c.Id, 10)
+}
+
+// Before tells whether c comes before other.
+func (c Cursor) Before(other Cursor) bool {
+	return c.Xid < other.Xid || c.Xid == other.Xid && c.Id < other.Id
+}
+
+// ParseCursor parses a cursor formatted by Cursor.String.
+func ParseCursor(s string) (Cursor, error) {
+	xid, id, ok := strings.Cut(s, "-")
+	if !ok {
+		return Cursor{}, errors.New("missing the event id")
+	}
+
+	var c Cursor
+	var err error
+	c.Xid, err = strconv.ParseUint(xid, 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid transaction: %w", err)
+	}
+	c.Id, err = strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid event id: %w", err)
+	}
+
+	return c, nil
+}
+
+// TransferPosted is recorded, about the source account, when a transfer is
+// committed.
+type TransferPosted struct {
+	TransferId           int64   `json:"transfer_id"`
+	SourceAccountId      int64   `json:"source_account_id"`
+	DestinationTenantId  string  `json:"destination_tenant_id"`
+	DestinationAccountId int64   `json:"destination_account_id"`
+	Amount               float64 `json:"amount"`
+	ClientId             string  `json:"client_id,omitempty"`
+}
+
+// TransferFailed is recorded, about the source account, when a transfer out
+// of it is rejected, for instance for insufficient funds.
+type TransferFailed struct {
+	SourceAccountId      int64   `json:"source_account_id"`
+	DestinationTenantId  string  `json:"destination_tenant_id"`
+	DestinationAccountId int64   `json:"destination_account_id"`
+	Amount               float64 `json:"amount"`
+	ClientId             string  `json:"client_id,omitempty"`
+	// Code is the error code the transfer was rejected with.
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+type AccountCreated struct {
+	AccountId int64   `json:"account_id"`
+	OwnerId   string  `json:"owner_id,omitempty"`
+	Balance   float64 `json:"balance"`
+}
+
+// AccountBalanceChanged is recorded for both accounts of a transfer.
+type AccountBalanceChanged struct {
+	AccountId       int64   `json:"account_id"`
+	TransferId      int64   `json:"transfer_id"`
+	PreviousBalance float64 `json:"previous_balance"`
+	Balance         float64 `json:"balance"`
+	Version         int64   `json:"version"`
+}
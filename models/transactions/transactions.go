@@ -1,10 +1,16 @@
 package transactions
 
+import "time"
+
 type Transactions struct {
 	id                   int64
 	sourceAccountId      int64
 	destinationAccountId int64
 	amount               float64
+	clientId             string
+	tenantId             string
+	destinationTenantId  string
+	createdAt            time.Time
 }
 
 func (t *Transactions) GetId() int64 {
@@ -23,6 +29,10 @@ func (t *Transactions) GetAmount() float64 {
 	return t.amount
 }
 
+func (t *Transactions) GetClientId() string {
+	return t.clientId
+}
+
 func (t *Transactions) SetId(id int64) {
 	t.id = id
 }
@@ -38,3 +48,31 @@ func (t *Transactions) SetDestinationAccountId(destinationAccountId int64) {
 func (t *Transactions) SetAmount(amount float64) {
 	t.amount = amount
 }
+
+func (t *Transactions) SetClientId(clientId string) {
+	t.clientId = clientId
+}
+
+func (t *Transactions) GetTenantId() string {
+	return t.tenantId
+}
+
+func (t *Transactions) GetDestinationTenantId() string {
+	return t.destinationTenantId
+}
+
+func (t *Transactions) SetTenantId(tenantId string) {
+	t.tenantId = tenantId
+}
+
+func (t *Transactions) SetDestinationTenantId(destinationTenantId string) {
+	t.destinationTenantId = destinationTenantId
+}
+
+func (t *Transactions) GetCreatedAt() time.Time {
+	return t.createdAt
+}
+
+func (t *Transactions) SetCreatedAt(createdAt time.Time) {
+	t.createdAt = createdAt
+}
@@ -0,0 +1,271 @@
+package webhooks
+
+import "time"
+
+// The states of a delivery. A delivery is dead once every attempt failed, it
+// is only attempted again when redelivered.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+var Statuses = []string{StatusPending, StatusDelivered, StatusDead}
+
+// Subscriptions receive the events of their tenant. An empty ownerId
+// subscribes to the events of every account, otherwise only to those of the
+// accounts ownerId owns.
+type Subscriptions struct {
+	id         int64
+	tenantId   string
+	clientId   string
+	ownerId    string
+	url        string
+	secret     string
+	eventTypes []string
+	createdAt  time.Time
+}
+
+func (s *Subscriptions) GetId() int64 {
+	return s.id
+}
+
+func (s *Subscriptions) GetTenantId() string {
+	return s.tenantId
+}
+
+func (s *Subscriptions) GetClientId() string {
+	return s.clientId
+}
+
+func (s *Subscriptions) GetOwnerId() string {
+	return s.ownerId
+}
+
+func (s *Subscriptions) GetUrl() string {
+	return s.url
+}
+
+func (s *Subscriptions) GetSecret() string {
+	return s.secret
+}
+
+func (s *Subscriptions) GetEventTypes() []string {
+	return s.eventTypes
+}
+
+func (s *Subscriptions) GetCreatedAt() time.Time {
+	return s.createdAt
+}
+
+func (s *Subscriptions) SetId(id int64) {
+	s.id = id
+}
+
+func (s *Subscriptions) SetTenantId(tenantId string) {
+	s.tenantId = tenantId
+}
+
+func (s *Subscriptions) SetClientId(clientId string) {
+	s.clientId = clientId
+}
+
+func (s *Subscriptions) SetOwnerId(ownerId string) {
+	s.ownerId = ownerId
+}
+
+func (s *Subscriptions) SetUrl(url string) {
+	s.url = url
+}
+
+func (s *Subscriptions) SetSecret(secret string) {
+	s.secret = secret
+}
+
+func (s *Subscriptions) SetEventTypes(eventTypes []string) {
+	s.eventTypes = eventTypes
+}
+
+func (s *Subscriptions) SetCreatedAt(createdAt time.Time) {
+	s.createdAt = createdAt
+}
+
+// Deliveries are the events sent, or to be sent, to a subscription.
+type Deliveries struct {
+	id             int64
+	subscriptionId int64
+	tenantId       string
+	eventId        int64
+	eventType      string
+	payload        []byte
+	status         string
+	attempts       int
+	nextAttemptAt  time.Time
+	lastStatusCode int
+	lastError      string
+	createdAt      time.Time
+	deliveredAt    *time.Time
+}
+
+func (d *Deliveries) GetId() int64 {
+	return d.id
+}
+
+func (d *Deliveries) GetSubscriptionId() int64 {
+	return d.subscriptionId
+}
+
+func (d *Deliveries) GetTenantId() string {
+	return d.tenantId
+}
+
+func (d *Deliveries) GetEventId() int64 {
+	return d.eventId
+}
+
+func (d *Deliveries) GetEventType() string {
+	return d.eventType
+}
+
+func (d *Deliveries) GetPayload() []byte {
+	return d.payload
+}
+
+func (d *Deliveries) GetStatus() string {
+	return d.status
+}
+
+func (d *Deliveries) GetAttempts() int {
+	return d.attempts
+}
+
+func (d *Deliveries) GetNextAttemptAt() time.Time {
+	return d.nextAttemptAt
+}
+
+func (d *Deliveries) GetLastStatusCode() int {
+	return d.lastStatusCode
+}
+
+func (d *Deliveries) GetLastError() string {
+	return d.lastError
+}
+
+func (d *Deliveries) GetCreatedAt() time.Time {
+	return d.createdAt
+}
+
+func (d *Deliveries) GetDeliveredAt() *time.Time {
+	return d.deliveredAt
+}
+
+func (d *Deliveries) SetId(id int64) {
+	d.id = id
+}
+
+func (d *Deliveries) SetSubscriptionId(subscriptionId int64) {
+	d.subscriptionId = subscriptionId
+}
+
+func (d *Deliveries) SetTenantId(tenantId string) {
+	d.tenantId = tenantId
+}
+
+func (d *Deliveries) SetEventId(eventId int64) {
+	d.eventId = eventId
+}
+
+func (d *Deliveries) SetEventType(eventType string) {
+	d.eventType = eventType
+}
+
+func (d *Deliveries) SetPayload(payload []byte) {
+	d.payload = payload
+}
+
+func (d *Deliveries) SetStatus(status string) {
+	d.status = status
+}
+
+func (d *Deliveries) SetAttempts(attempts int) {
+	d.attempts = attempts
+}
+
+func (d *Deliveries) SetNextAttemptAt(nextAttemptAt time.Time) {
+	d.nextAttemptAt = nextAttemptAt
+}
+
+func (d *Deliveries) SetLastStatusCode(lastStatusCode int) {
+	d.lastStatusCode = lastStatusCode
+}
+
+func (d *Deliveries) SetLastError(lastError string) {
+	d.lastError = lastError
+}
+
+func (d *Deliveries) SetCreatedAt(createdAt time.Time) {
+	d.createdAt = createdAt
+}
+
+func (d *Deliveries) SetDeliveredAt(deliveredAt *time.Time) {
+	d.deliveredAt = deliveredAt
+}
+
+// Attempts log each try at a delivery. statusCode is 0 when no response
+// was received.
+type Attempts struct {
+	id          int64
+	deliveryId  int64
+	attemptedAt time.Time
+	statusCode  int
+	error       string
+	duration    time.Duration
+}
+
+func (a *Attempts) GetId() int64 {
+	return a.id
+}
+
+func (a *Attempts) GetDeliveryId() int64 {
+	return a.deliveryId
+}
+
+func (a *Attempts) GetAttemptedAt() time.Time {
+	return a.attemptedAt
+}
+
+func (a *Attempts) GetStatusCode() int {
+	return a.statusCode
+}
+
+func (a *Attempts) GetError() string {
+	return a.error
+}
+
+func (a *Attempts) GetDuration() time.Duration {
+	return a.duration
+}
+
+func (a *Attempts) SetId(id int64) {
+	a.id = id
+}
+
+func (a *Attempts) SetDeliveryId(deliveryId int64) {
+	a.deliveryId = deliveryId
+}
+
+func (a *Attempts) SetAttemptedAt(attemptedAt time.Time) {
+	a.attemptedAt = attemptedAt
+}
+
+func (a *Attempts) SetStatusCode(statusCode int) {
+	a.statusCode = statusCode
+}
+
+func (a *Attempts) SetError(message string) {
+	a.error = message
+}
+
+func (a *Attempts) SetDuration(duration time.Duration) {
+	a.duration = duration
+}
@@ -13,3 +13,391 @@ CREATE TABLE transactions(
     destination_account_id INTEGER,
     amount FLOAT8
 );
+
+
+CREATE TABLE schema_migrations(
+    version INTEGER PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO schema_migrations(version) VALUES (1);
+
+
+-- version 2: api keys and the client recorded on each transaction
+CREATE TABLE api_keys(
+    id TEXT PRIMARY KEY,
+    client_id TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    key_hash BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX api_keys_client_id_idx ON api_keys(client_id);
+
+ALTER TABLE transactions ADD COLUMN client_id TEXT;
+
+INSERT INTO schema_migrations(version) VALUES (2);
+
+
+-- version 3: account ownership and api key roles
+ALTER TABLE accounts ADD COLUMN owner_id TEXT;
+
+CREATE INDEX accounts_owner_id_idx ON accounts(owner_id);
+
+ALTER TABLE api_keys ADD COLUMN roles TEXT[] NOT NULL DEFAULT '{read,transfer}';
+
+INSERT INTO schema_migrations(version) VALUES (3);
+
+
+-- version 4: tenants
+-- Account ids are only unique within a tenant. Row level security is a second
+-- line of defense behind the tenant filter in every query: sessions only see
+-- the rows of the tenants listed in app.tenant_ids, which the application
+-- sets whenever it takes a connection from its pool. Superusers and roles
+-- with BYPASSRLS are not restricted, so run the service as a regular role.
+ALTER TABLE accounts ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE accounts DROP CONSTRAINT accounts_pkey;
+ALTER TABLE accounts ADD PRIMARY KEY (tenant_id, id);
+
+ALTER TABLE transactions ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE transactions ADD COLUMN destination_tenant_id TEXT NOT NULL DEFAULT 'default';
+CREATE INDEX transactions_tenant_id_idx ON transactions(tenant_id);
+CREATE INDEX transactions_destination_tenant_id_idx ON transactions(destination_tenant_id);
+
+ALTER TABLE api_keys ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+
+ALTER TABLE accounts ENABLE ROW LEVEL SECURITY;
+ALTER TABLE accounts FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON accounts
+    USING (tenant_id = ANY (string_to_array(current_setting('app.tenant_ids', true), ',')))
+    WITH CHECK (tenant_id = ANY (string_to_array(current_setting('app.tenant_ids', true), ',')));
+
+-- a transfer between tenants is visible to both of them
+ALTER TABLE transactions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE transactions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON transactions
+    USING (tenant_id = ANY (string_to_array(current_setting('app.tenant_ids', true), ','))
+        OR destination_tenant_id = ANY (string_to_array(current_setting('app.tenant_ids', true), ',')))
+    WITH CHECK (tenant_id = ANY (string_to_array(current_setting('app.tenant_ids', true), ',')));
+
+INSERT INTO schema_migrations(version) VALUES (4);
+
+
+-- version 5: transfer listings
+ALTER TABLE transactions ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+CREATE INDEX transactions_source_idx ON transactions(tenant_id, source_account_id, id);
+CREATE INDEX transactions_destination_idx ON transactions(destination_tenant_id, destination_account_id, id);
+
+INSERT INTO schema_migrations(version) VALUES (5);
+
+
+-- version 6: transactional outbox
+-- Domain events are written in the same transaction as the change they
+-- describe, and relayed to the configured sink by a single relay at a time.
+-- The relay reads the events of every tenant, so the table has no row level
+-- security. account_id is the account the event is about, events of one
+-- account are published in id order.
+CREATE TABLE outbox (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    account_id BIGINT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    published_at TIMESTAMPTZ
+);
+
+CREATE INDEX outbox_unpublished_idx ON outbox(id) WHERE published_at IS NULL;
+
+INSERT INTO schema_migrations(version) VALUES (6);
+
+
+-- version 7: webhooks
+-- Subscriptions receive the outbox events of their tenant, of every account
+-- when owner_id is null and of the accounts of owner_id otherwise. The
+-- secret signs deliveries, so it is stored as is. Deliveries are attempted
+-- by a worker across tenants, like the outbox these tables have no row
+-- level security and every query filters by tenant.
+CREATE TABLE webhook_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    client_id TEXT NOT NULL,
+    owner_id TEXT,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX webhook_subscriptions_tenant_id_idx ON webhook_subscriptions(tenant_id);
+
+CREATE TABLE webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    tenant_id TEXT NOT NULL,
+    event_id BIGINT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    -- pending, delivered or dead once every attempt failed
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_status_code INT,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at TIMESTAMPTZ,
+    UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE webhook_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    attempted_at TIMESTAMPTZ NOT NULL,
+    status_code INT,
+    error TEXT,
+    duration_ms INT NOT NULL
+);
+
+CREATE INDEX webhook_attempts_delivery_id_idx ON webhook_attempts(delivery_id);
+
+INSERT INTO schema_migrations(version) VALUES (7);
+
+
+-- version 8: outbox notifications
+-- Every event is announced on the outbox_events channel, with the event
+-- itself as payload. Notifications are only delivered once the transaction
+-- recording the event commits, listeners never see rolled back events.
+CREATE FUNCTION outbox_notify() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('outbox_events', json_build_object(
+        'id', NEW.id,
+        'type', NEW.event_type,
+        'tenant_id', NEW.tenant_id,
+        'account_id', NEW.account_id,
+        'occurred_at', NEW.created_at,
+        'data', NEW.payload
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER outbox_notify AFTER INSERT ON outbox
+    FOR EACH ROW EXECUTE FUNCTION outbox_notify();
+
+-- streams resuming after a disconnect replay the events of their account
+CREATE INDEX outbox_account_idx ON outbox(tenant_id, account_id, id);
+
+INSERT INTO schema_migrations(version) VALUES (8);
+
+
+-- version 9: audit log
+-- Every mutating API request is appended here once it completes. Entries
+-- of a tenant form a hash chain: hash covers the entry and the hash of the
+-- entry before it, so changing or removing an entry breaks the chain from
+-- there on. payload and snapshots are JSON rather than JSONB so they keep the
+-- exact text that was hashed. Updates, deletes and truncation are rejected.
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    actor TEXT NOT NULL,
+    auth_method TEXT NOT NULL,
+    request_id TEXT NOT NULL,
+    method TEXT NOT NULL,
+    endpoint TEXT NOT NULL,
+    status INT NOT NULL,
+    error_code TEXT NOT NULL,
+    payload JSON NOT NULL,
+    snapshots JSON NOT NULL,
+    account_ids BIGINT[] NOT NULL,
+    prev_hash TEXT NOT NULL,
+    hash TEXT NOT NULL
+);
+
+CREATE INDEX audit_log_tenant_idx ON audit_log(tenant_id, id);
+CREATE INDEX audit_log_account_ids_idx ON audit_log USING GIN (account_ids);
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+ALTER TABLE audit_log ENABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_log FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON audit_log
+    USING (tenant_id = ANY (string_to_array(current_setting('app.tenant_ids', true), ',')))
+    WITH CHECK (tenant_id = ANY (string_to_array(current_setting('app.tenant_ids', true), ',')));
+
+INSERT INTO schema_migrations(version) VALUES (9);
+
+
+-- version 10: initial balances
+-- Balances are updated in place, the balance an account was created with is
+-- kept so reconciliation can recompute the balance from the transfers since.
+-- Accounts that predate this version are given the initial balance their
+-- transfers imply, which takes any drift of their balance as a given, so
+-- they are flagged as inferred and reconciliation reports them apart. The
+-- backfill must run as a role that sees every tenant, the NOT NULL
+-- constraint fails otherwise.
+ALTER TABLE accounts
+    ADD COLUMN initial_balance FLOAT8,
+    ADD COLUMN initial_balance_inferred BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE accounts ALTER COLUMN initial_balance_inferred SET DEFAULT false;
+
+UPDATE accounts a SET initial_balance = a.balance
+    - coalesce((SELECT sum(t.amount) FROM transactions t WHERE t.destination_tenant_id = a.tenant_id AND t.destination_account_id = a.id), 0)
+    + coalesce((SELECT sum(t.amount) FROM transactions t WHERE t.tenant_id = a.tenant_id AND t.source_account_id = a.id), 0);
+
+ALTER TABLE accounts ALTER COLUMN initial_balance SET NOT NULL;
+
+INSERT INTO schema_migrations(version) VALUES (10);
+
+
+-- version 11: export watermarks
+-- Exports pick up the accounts written and the transfers made since the
+-- previous export. updated_at is kept by a trigger so every way of updating
+-- an account maintains it. Existing accounts count as written now.
+ALTER TABLE accounts ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE FUNCTION accounts_touch() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at = now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER accounts_touch BEFORE UPDATE ON accounts
+    FOR EACH ROW EXECUTE FUNCTION accounts_touch();
+
+CREATE INDEX accounts_updated_at_idx ON accounts(tenant_id, updated_at, id);
+CREATE INDEX transactions_created_at_idx ON transactions(tenant_id, created_at, id);
+
+INSERT INTO schema_migrations(version) VALUES (11);
+
+
+-- version 12: API key signing secrets
+-- HMAC signed requests are keyed with a secret issued with the key rather
+-- than the hash of the key, so reading this table isn't enough to sign
+-- requests. The secret is encrypted with AUTH_SIGNING_KEY, which only the
+-- servers hold. Keys issued before have none and must be rotated to sign.
+ALTER TABLE api_keys ADD COLUMN signing_secret BYTEA;
+
+INSERT INTO schema_migrations(version) VALUES (12);
+
+
+-- version 13: outbox retries
+-- The relay publishes outside of any transaction and records every failed
+-- attempt at an event. A failed event is retried with backoff, holding back
+-- the later events of its account, and parked once it has failed too often
+-- so it doesn't hold them back forever. xid is the transaction that
+-- recorded the event. Events are only published once every transaction
+-- that began before theirs has ended, in xid order, so an event never
+-- commits behind one that was already published.
+ALTER TABLE outbox
+    ADD COLUMN xid xid8 NOT NULL DEFAULT pg_current_xact_id(),
+    ADD COLUMN attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN last_error TEXT,
+    ADD COLUMN next_attempt_at TIMESTAMPTZ,
+    ADD COLUMN parked_at TIMESTAMPTZ;
+
+DROP INDEX outbox_unpublished_idx;
+CREATE INDEX outbox_unpublished_idx ON outbox(xid, id) WHERE published_at IS NULL AND parked_at IS NULL;
+CREATE INDEX outbox_retrying_idx ON outbox(tenant_id, account_id) WHERE published_at IS NULL AND parked_at IS NULL AND next_attempt_at IS NOT NULL;
+
+INSERT INTO schema_migrations(version) VALUES (13);
+
+
+-- version 14: commit ordered export watermarks
+-- Exports used to pick up rows by the time they were written at, which is
+-- when their transaction began, so a row from a transaction that committed
+-- after a run had moved past that time was never exported. Rows now record
+-- the transaction that last wrote them, and a run exports the rows of the
+-- transactions that ended before it began, up to the oldest one still
+-- running. The watermark of each tenant is kept here rather than next to
+-- the files, so every export shares it. Existing rows count as written by
+-- this migration, the first run after it exports everything again.
+ALTER TABLE accounts ADD COLUMN xid xid8 NOT NULL DEFAULT pg_current_xact_id();
+ALTER TABLE transactions ADD COLUMN xid xid8 NOT NULL DEFAULT pg_current_xact_id();
+
+CREATE OR REPLACE FUNCTION accounts_touch() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at = now();
+    NEW.xid = pg_current_xact_id();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX accounts_updated_at_idx;
+CREATE INDEX accounts_xid_idx ON accounts(tenant_id, xid, id);
+CREATE INDEX transactions_xid_idx ON transactions(tenant_id, xid, id);
+
+CREATE TABLE export_watermarks (
+    tenant_id TEXT PRIMARY KEY,
+    xid xid8 NOT NULL,
+    run_id TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO schema_migrations(version) VALUES (14);
+
+
+-- version 15: statement pages
+-- Statements read the transfers of an account a page at a time, in the
+-- order they were made.
+CREATE INDEX transactions_source_created_at_idx ON transactions(tenant_id, source_account_id, created_at, id);
+CREATE INDEX transactions_destination_created_at_idx ON transactions(destination_tenant_id, destination_account_id, created_at, id);
+
+INSERT INTO schema_migrations(version) VALUES (15);
+
+
+-- version 16: audit key cutovers
+-- Where the audit chain of each tenant started being keyed with AUDIT_KEY.
+-- Entries before it are checked by their plain hash and every entry from it
+-- on must be keyed, so the chain can't be rehashed without the key. The
+-- cutover is keyed too, and can't be changed once recorded.
+CREATE TABLE audit_key_cutovers (
+    tenant_id TEXT PRIMARY KEY,
+    keyed_from BIGINT NOT NULL,
+    mac TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE FUNCTION audit_key_cutovers_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_key_cutovers is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_key_cutovers_append_only BEFORE UPDATE OR DELETE ON audit_key_cutovers
+    FOR EACH ROW EXECUTE FUNCTION audit_key_cutovers_append_only();
+CREATE TRIGGER audit_key_cutovers_no_truncate BEFORE TRUNCATE ON audit_key_cutovers
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_key_cutovers_append_only();
+
+ALTER TABLE audit_key_cutovers ENABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_key_cutovers FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON audit_key_cutovers
+    USING (tenant_id = ANY (string_to_array(current_setting('app.tenant_ids', true), ',')))
+    WITH CHECK (tenant_id = ANY (string_to_array(current_setting('app.tenant_ids', true), ',')));
+
+INSERT INTO schema_migrations(version) VALUES (16);
+
+
+-- version 17: commit ordered event streams
+-- Streams resuming after a disconnect used to replay the events of their
+-- account by id, which is handed out before the transaction commits, so an
+-- event committed after a later one was read was never replayed. They now
+-- replay in the order of the transactions recording the events, as the
+-- relay publishes, up to the oldest one still running.
+DROP INDEX outbox_account_idx;
+CREATE INDEX outbox_account_xid_idx ON outbox(tenant_id, account_id, xid, id);
+
+INSERT INTO schema_migrations(version) VALUES (17);
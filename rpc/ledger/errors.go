@@ -0,0 +1,72 @@
+package ledger
+
+import (
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// errorDomain is the ErrorInfo domain of every status returned by the API.
+const errorDomain = "transactions"
+
+var grpcCodes = map[apperrors.Code]codes.Code{
+	apperrors.CodeValidationFailed:     codes.InvalidArgument,
+	apperrors.CodeUnauthorized:         codes.Unauthenticated,
+	apperrors.CodeForbidden:            codes.PermissionDenied,
+	apperrors.CodeNotFound:             codes.NotFound,
+	apperrors.CodeAccountNotFound:      codes.NotFound,
+	apperrors.CodeAccountAlreadyExists: codes.AlreadyExists,
+	apperrors.CodeTransferNotFound:     codes.NotFound,
+	apperrors.CodeWebhookNotFound:      codes.NotFound,
+	apperrors.CodeDeliveryNotFound:     codes.NotFound,
+	apperrors.CodeInsufficientFunds:    codes.FailedPrecondition,
+	apperrors.CodeVersionConflict:      codes.Aborted,
+	apperrors.CodeRateLimited:          codes.ResourceExhausted,
+	apperrors.CodeBodyTooLarge:         codes.ResourceExhausted,
+	apperrors.CodeInternal:             codes.Internal,
+}
+
+// Code is the gRPC status code errors with code are returned with.
+func Code(code apperrors.Code) codes.Code {
+	if c, ok := grpcCodes[code]; ok {
+		return c
+	}
+
+	return codes.Internal
+}
+
+// Status converts err into a gRPC status error. Like the problem documents of
+// the HTTP API it carries the stable error code, as the reason of an ErrorInfo
+// detail, and the invalid fields as a BadRequest detail.
+func Status(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	appErr := apperrors.From(err)
+	st := status.New(Code(appErr.Code), appErr.Detail)
+
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: string(appErr.Code), Domain: errorDomain}}
+	if len(appErr.Fields) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, field := range appErr.Fields {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       field.Field,
+				Description: field.Message,
+			})
+		}
+		details = append(details, badRequest)
+	}
+
+	withDetails, detailsErr := st.WithDetails(details...)
+	if detailsErr != nil {
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
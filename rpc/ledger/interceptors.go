@@ -0,0 +1,295 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ashwin-m/transactions/middlewares/audit"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	"github.com/ashwin-m/transactions/middlewares/ratelimit"
+	"github.com/ashwin-m/transactions/middlewares/requestid"
+	ledgerv1 "github.com/ashwin-m/transactions/proto/ledger/v1"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/metrics"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const instrumentationName = "github.com/ashwin-m/transactions/rpc/ledger"
+
+// methodRoles is the role each method requires, as RouteGroup requires them
+// of the matching HTTP routes.
+var methodRoles = map[string]string{
+	ledgerv1.Ledger_CreateAccount_FullMethodName:  auth.RoleTransfer,
+	ledgerv1.Ledger_GetAccount_FullMethodName:     auth.RoleRead,
+	ledgerv1.Ledger_CreateTransfer_FullMethodName: auth.RoleTransfer,
+	ledgerv1.Ledger_GetTransfer_FullMethodName:    auth.RoleRead,
+	ledgerv1.Ledger_ListTransfers_FullMethodName:  auth.RoleRead,
+}
+
+// mutatingMethods are the methods audited as state-changing calls.
+var mutatingMethods = map[string]bool{
+	ledgerv1.Ledger_CreateAccount_FullMethodName:  true,
+	ledgerv1.Ledger_CreateTransfer_FullMethodName: true,
+}
+
+// auditMethod is recorded as the method of audited calls.
+const auditMethod = "GRPC"
+
+// requestIdMetadata carries the request id, like the X-Request-ID header.
+const requestIdMetadata = "x-request-id"
+
+// requestId reuses the x-request-id sent by the caller or assigns a new one,
+// and returns it in the response headers.
+func requestId(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := requestid.Ensure(first(md, requestIdMetadata))
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIdMetadata, id))
+
+	return handler(requestid.NewContext(ctx, id), req)
+}
+
+// tracing starts a server span for every call, continuing the trace passed
+// in the traceparent metadata when there is one.
+func tracing(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, info.FullMethod,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.RPCSystemGRPC,
+			semconv.RPCMethod(info.FullMethod),
+		),
+	)
+	defer span.End()
+
+	resp, err := handler(ctx, req)
+
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	if code == codes.Internal || code == codes.Unknown {
+		span.SetStatus(otelcodes.Error, code.String())
+	}
+
+	return resp, err
+}
+
+// logging writes one access log line per call, and turns the errors of the
+// service layer into gRPC statuses once their cause has been logged.
+func logging(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		grpcErr := Status(err)
+		code := status.Code(grpcErr)
+		level := slog.LevelInfo
+		if code == codes.Internal || code == codes.Unknown {
+			level = slog.LevelError
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", info.FullMethod),
+			slog.String("code", code.String()),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("errors", err.Error()))
+		}
+
+		logger.LogAttrs(ctx, level, "rpc", attrs...)
+
+		return resp, grpcErr
+	}
+}
+
+// recovery turns panics into an internal error and logs them with the
+// request id.
+func recovery(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				logger.ErrorContext(ctx, "panic recovered", slog.Any("panic", p))
+				err = apperrors.Internal(fmt.Errorf("panic: %v", p))
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// authentication accepts the same bearer credentials as the HTTP API, passed
+// as authorization or x-api-key metadata, then resolves the tenant from
+// x-tenant-id and checks the role the method requires. Calls to other
+// services, like health checks, don't need credentials.
+func authentication(authenticators []auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		role, ok := methodRoles[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		r, err := http.NewRequestWithContext(ctx, http.MethodPost, info.FullMethod, nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range md {
+			for _, value := range values {
+				r.Header.Add(key, value)
+			}
+		}
+
+		identity, err := auth.Authenticate(r, authenticators...)
+		if err != nil {
+			return nil, err
+		}
+		ctx = auth.NewContext(ctx, identity)
+
+		tenantId, err := auth.ResolveTenant(identity, r.Header.Get(auth.TenantHeader))
+		if err != nil {
+			return nil, err
+		}
+		ctx = tenant.NewContext(ctx, tenantId)
+
+		if !identity.HasRole(role) {
+			return nil, apperrors.New(apperrors.CodeForbidden, "insufficient permissions")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// auditing records the calls of mutatingMethods in the audit log, with the
+// HTTP status matching their outcome, like the middleware of the HTTP API.
+func auditing(recorder audit.Recorder) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !mutatingMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		succeeded := func() audit.Call {
+			call := audit.Call{
+				Method:   auditMethod,
+				Endpoint: info.FullMethod,
+				Status:   http.StatusOK,
+			}
+			if message, ok := req.(proto.Message); ok {
+				call.Payload, _ = protojson.Marshal(message)
+			}
+			return call
+		}
+		ctx = audit.NewContext(ctx, recorder, succeeded)
+
+		resp, err := handler(ctx, req)
+
+		call := succeeded()
+		if err != nil {
+			appErr := apperrors.From(err)
+			call.Status = appErr.Code.Status()
+			call.ErrorCode = string(appErr.Code)
+		}
+
+		audit.Record(ctx, recorder, call)
+
+		return resp, err
+	}
+}
+
+// rateLimiting counts calls against the same buckets as the HTTP API, so a
+// client can't double its allowance by using both. As there, transfers are
+// counted against a bucket of the client per source account, so clients
+// that may not debit an account can't use up its owner's allowance.
+func rateLimiting(store ratelimit.Store, clientLimit, accountLimit ratelimit.Limit) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		identity, ok := auth.FromContext(ctx)
+		if !ok {
+			return handler(ctx, req)
+		}
+		tenantId, _ := tenant.FromContext(ctx)
+
+		err := take(ctx, store, "client", fmt.Sprintf("%s/%s", tenantId, identity.ClientID), clientLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		if transfer, ok := req.(*ledgerv1.CreateTransferRequest); ok {
+			err = take(ctx, store, "account", ratelimit.AccountKey(tenantId, identity.ClientID, transfer.GetSourceAccountId()), accountLimit)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// take counts a call against a bucket. As with the HTTP middleware a failing
+// store lets calls through.
+func take(ctx context.Context, store ratelimit.Store, name, key string, limit ratelimit.Limit) error {
+	if !limit.Enabled() {
+		return nil
+	}
+
+	result, err := store.Take(ctx, name+":"+key, limit)
+	if err != nil {
+		slog.WarnContext(ctx, "rate limit store failed, allowing request", slog.String("limiter", name), slog.Any("error", err))
+		return nil
+	}
+
+	if !result.Allowed {
+		metrics.RateLimited(name)
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds())))))
+		return apperrors.New(apperrors.CodeRateLimited, "rate limit exceeded")
+	}
+
+	return nil
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// metadataCarrier lets the trace propagator read incoming metadata.
+type metadataCarrier metadata.MD
+
+var _ propagation.TextMapCarrier = metadataCarrier{}
+
+func (c metadataCarrier) Get(key string) string {
+	return first(metadata.MD(c), key)
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
@@ -0,0 +1,202 @@
+// Package ledger serves the gRPC Ledger API. It translates between protobuf
+// messages and the service layer shared with the HTTP API, so both APIs apply
+// the same rules and return the same errors.
+package ledger
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/ashwin-m/transactions/middlewares/audit"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	"github.com/ashwin-m/transactions/middlewares/ratelimit"
+	accountsmodel "github.com/ashwin-m/transactions/models/accounts"
+	transactionsmodel "github.com/ashwin-m/transactions/models/transactions"
+	ledgerv1 "github.com/ashwin-m/transactions/proto/ledger/v1"
+	accountsservice "github.com/ashwin-m/transactions/services/accounts"
+	"github.com/ashwin-m/transactions/services/transfers"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Options configures the interceptors every call passes through.
+type Options struct {
+	Logger         *slog.Logger
+	Authenticators []auth.Authenticator
+	// Audit records state-changing calls in the audit log when set.
+	Audit audit.Recorder
+	// Limits enables rate limiting of clients and transfers when set.
+	Limits       ratelimit.Store
+	ClientLimit  ratelimit.Limit
+	AccountLimit ratelimit.Limit
+}
+
+// New builds a gRPC server serving the Ledger API and the standard health
+// service.
+func New(options Options, accounts accountsservice.Service, transfers transfers.Service) *grpc.Server {
+	interceptors := []grpc.UnaryServerInterceptor{
+		requestId,
+		tracing,
+		logging(options.Logger),
+		recovery(options.Logger),
+		authentication(options.Authenticators),
+	}
+	if options.Audit != nil {
+		interceptors = append(interceptors, auditing(options.Audit))
+	}
+	if options.Limits != nil {
+		interceptors = append(interceptors, rateLimiting(options.Limits, options.ClientLimit, options.AccountLimit))
+	}
+
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+	ledgerv1.RegisterLedgerServer(srv, NewServer(accounts, transfers))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+
+	return srv
+}
+
+type server struct {
+	ledgerv1.UnimplementedLedgerServer
+
+	accounts  accountsservice.Service
+	transfers transfers.Service
+}
+
+func NewServer(accounts accountsservice.Service, transfers transfers.Service) ledgerv1.LedgerServer {
+	return &server{
+		accounts:  accounts,
+		transfers: transfers,
+	}
+}
+
+func (s *server) CreateAccount(ctx context.Context, request *ledgerv1.CreateAccountRequest) (*ledgerv1.Account, error) {
+	account, err := s.accounts.Create(ctx, accountsservice.CreateRequest{
+		Id:             request.GetAccountId(),
+		InitialBalance: request.GetInitialBalance(),
+		OwnerId:        request.GetOwnerId(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return accountMessage(account), nil
+}
+
+func (s *server) GetAccount(ctx context.Context, request *ledgerv1.GetAccountRequest) (*ledgerv1.Account, error) {
+	account, err := s.accounts.Get(ctx, request.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+
+	return accountMessage(account), nil
+}
+
+func (s *server) CreateTransfer(ctx context.Context, request *ledgerv1.CreateTransferRequest) (*ledgerv1.Transfer, error) {
+	result, err := s.transfers.Transfer(ctx, transfers.TransferRequest{
+		SourceAccountId:      request.GetSourceAccountId(),
+		DestinationAccountId: request.GetDestinationAccountId(),
+		DestinationTenantId:  request.GetDestinationTenantId(),
+		Amount:               request.GetAmount(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The transfer has been made, read it back for its timestamps but don't
+	// fail the call if that doesn't work.
+	transaction, err := s.transfers.Get(ctx, result.TransactionId)
+	if err != nil {
+		slog.WarnContext(ctx, "unable to read back transfer", slog.Int64("transfer_id", result.TransactionId), slog.Any("error", err))
+		tenantId, _ := tenant.FromContext(ctx)
+		identity, _ := auth.FromContext(ctx)
+		transaction.SetId(result.TransactionId)
+		transaction.SetSourceAccountId(request.GetSourceAccountId())
+		transaction.SetDestinationAccountId(request.GetDestinationAccountId())
+		transaction.SetTenantId(tenantId)
+		transaction.SetDestinationTenantId(result.DestinationTenantId)
+		transaction.SetAmount(result.Amount)
+		transaction.SetClientId(identity.ClientID)
+		return transferMessage(transaction), nil
+	}
+
+	return transferMessage(transaction), nil
+}
+
+func (s *server) GetTransfer(ctx context.Context, request *ledgerv1.GetTransferRequest) (*ledgerv1.Transfer, error) {
+	transaction, err := s.transfers.Get(ctx, request.GetTransferId())
+	if err != nil {
+		return nil, err
+	}
+
+	return transferMessage(transaction), nil
+}
+
+// ListTransfers pages through transfers newest first. The page token is the
+// id of the last transfer of the previous page.
+func (s *server) ListTransfers(ctx context.Context, request *ledgerv1.ListTransfersRequest) (*ledgerv1.ListTransfersResponse, error) {
+	var beforeId int64
+	if request.GetPageToken() != "" {
+		var err error
+		beforeId, err = strconv.ParseInt(request.GetPageToken(), 10, 64)
+		if err != nil || beforeId <= 0 {
+			return nil, apperrors.Validation(apperrors.FieldError{Field: "page_token", Message: "is not a valid page token"})
+		}
+	}
+
+	transactions, err := s.transfers.List(ctx, transfers.ListRequest{
+		AccountId: request.GetAccountId(),
+		PageSize:  int(request.GetPageSize()),
+		BeforeId:  beforeId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ledgerv1.ListTransfersResponse{}
+	for _, transaction := range transactions {
+		response.Transfers = append(response.Transfers, transferMessage(transaction))
+	}
+
+	// A short page is the last one.
+	if len(transactions) == transfers.PageSize(int(request.GetPageSize())) {
+		last := transactions[len(transactions)-1]
+		response.NextPageToken = strconv.FormatInt(last.GetId(), 10)
+	}
+
+	return response, nil
+}
+
+func accountMessage(account accountsmodel.Accounts) *ledgerv1.Account {
+	return &ledgerv1.Account{
+		AccountId: account.GetId(),
+		Balance:   decimal(account.GetBalance()),
+		OwnerId:   account.GetOwnerId(),
+		TenantId:  account.GetTenantId(),
+	}
+}
+
+func transferMessage(transaction transactionsmodel.Transactions) *ledgerv1.Transfer {
+	message := &ledgerv1.Transfer{
+		TransferId:           transaction.GetId(),
+		SourceAccountId:      transaction.GetSourceAccountId(),
+		DestinationAccountId: transaction.GetDestinationAccountId(),
+		TenantId:             transaction.GetTenantId(),
+		DestinationTenantId:  transaction.GetDestinationTenantId(),
+		Amount:               decimal(transaction.GetAmount()),
+		ClientId:             transaction.GetClientId(),
+	}
+	if !transaction.GetCreatedAt().IsZero() {
+		message.CreatedAt = timestamppb.New(transaction.GetCreatedAt())
+	}
+
+	return message
+}
+
+func decimal(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
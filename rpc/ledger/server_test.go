@@ -0,0 +1,462 @@
+package ledger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	accountsdaomocks "github.com/ashwin-m/transactions/daos/accounts/mocks"
+	outboxdaomocks "github.com/ashwin-m/transactions/daos/outbox/mocks"
+	transactionsdaomocks "github.com/ashwin-m/transactions/daos/transactions/mocks"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	"github.com/ashwin-m/transactions/middlewares/ratelimit"
+	accountsmodel "github.com/ashwin-m/transactions/models/accounts"
+	auditmodel "github.com/ashwin-m/transactions/models/audit"
+	eventsmodel "github.com/ashwin-m/transactions/models/events"
+	transactionsmodel "github.com/ashwin-m/transactions/models/transactions"
+	ledgerv1 "github.com/ashwin-m/transactions/proto/ledger/v1"
+	accountsservice "github.com/ashwin-m/transactions/services/accounts"
+	"github.com/ashwin-m/transactions/services/transfers"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var (
+	adminIdentity    = auth.Identity{ClientID: "admin", Roles: []string{auth.RoleAdmin}}
+	clientIdentity   = auth.Identity{ClientID: "client-1", Roles: []string{auth.RoleRead, auth.RoleTransfer}}
+	readOnlyIdentity = auth.Identity{ClientID: "client-1", Roles: []string{auth.RoleRead}}
+)
+
+type fixture struct {
+	accountsDao     *accountsdaomocks.Dao
+	transactionsDao *transactionsdaomocks.Dao
+	outboxDao       *outboxdaomocks.Dao
+	db              pgxmock.PgxPoolIface
+}
+
+func newFixture(t *testing.T) *fixture {
+	db, _ := pgxmock.NewPool()
+
+	return &fixture{
+		accountsDao:     accountsdaomocks.NewDao(t),
+		transactionsDao: transactionsdaomocks.NewDao(t),
+		outboxDao:       outboxdaomocks.NewDao(t),
+		db:              db,
+	}
+}
+
+// dial serves the API over an in-memory connection and returns a client.
+func (f *fixture) dial(t *testing.T, options Options) (ledgerv1.LedgerClient, *grpc.ClientConn) {
+	options.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := New(options, accountsservice.NewService(f.db, f.accountsDao, f.outboxDao), transfers.NewService(f.db, f.accountsDao, f.transactionsDao, f.outboxDao, false))
+
+	listener := bufconn.Listen(1024 * 1024)
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return ledgerv1.NewLedgerClient(conn), conn
+}
+
+func as(identity auth.Identity) Options {
+	return Options{Authenticators: []auth.Authenticator{auth.Static(identity)}}
+}
+
+func reason(err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.GetReason()
+		}
+	}
+
+	return ""
+}
+
+func TestGetAccount(t *testing.T) {
+	f := newFixture(t)
+	account := accountsmodel.Accounts{}
+	account.SetId(123)
+	account.SetBalance(100.5)
+	account.SetOwnerId("client-1")
+	account.SetTenantId(tenant.Default)
+	f.accountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(account, nil)
+
+	client, _ := f.dial(t, as(clientIdentity))
+	response, err := client.GetAccount(context.Background(), &ledgerv1.GetAccountRequest{AccountId: 123})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123), response.GetAccountId())
+	assert.Equal(t, "100.5", response.GetBalance())
+	assert.Equal(t, "client-1", response.GetOwnerId())
+	assert.Equal(t, tenant.Default, response.GetTenantId())
+}
+
+func TestGetAccount_OtherClientsAccount(t *testing.T) {
+	f := newFixture(t)
+	account := accountsmodel.Accounts{}
+	account.SetId(123)
+	account.SetOwnerId("client-2")
+	f.accountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(account, nil)
+
+	client, _ := f.dial(t, as(clientIdentity))
+	_, err := client.GetAccount(context.Background(), &ledgerv1.GetAccountRequest{AccountId: 123})
+
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, "you do not have access to this account", status.Convert(err).Message())
+	assert.Equal(t, "FORBIDDEN", reason(err))
+}
+
+func violations(err error) []*errdetails.BadRequest_FieldViolation {
+	for _, detail := range status.Convert(err).Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			return badRequest.GetFieldViolations()
+		}
+	}
+
+	return nil
+}
+
+// the gRPC API doesn't go through the numeric binding of the HTTP API, the
+// services refuse what it would
+func TestCreateAccount_InvalidBalance(t *testing.T) {
+	tests := map[string]string{
+		"ten":   "must be a decimal number",
+		"NaN":   "must be a decimal number",
+		"Inf":   "must be a decimal number",
+		"0x1p3": "must be a decimal number",
+		"1e3":   "must be a decimal number",
+		"-5":    "must not be negative",
+	}
+	for balance, message := range tests {
+		f := newFixture(t)
+
+		client, _ := f.dial(t, as(clientIdentity))
+		_, err := client.CreateAccount(context.Background(), &ledgerv1.CreateAccountRequest{AccountId: 1, InitialBalance: balance})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), balance)
+		assert.Equal(t, "VALIDATION_FAILED", reason(err), balance)
+		if assert.Len(t, violations(err), 1, balance) {
+			assert.Equal(t, "initial_balance", violations(err)[0].GetField(), balance)
+			assert.Equal(t, message, violations(err)[0].GetDescription(), balance)
+		}
+	}
+}
+
+func TestCreateTransfer_InvalidAmount(t *testing.T) {
+	for _, amount := range []string{"NaN", "Inf", "0x1p3", "1e3"} {
+		f := newFixture(t)
+
+		client, _ := f.dial(t, as(clientIdentity))
+		_, err := client.CreateTransfer(context.Background(), &ledgerv1.CreateTransferRequest{SourceAccountId: 1, DestinationAccountId: 2, Amount: amount})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), amount)
+		if assert.Len(t, violations(err), 1, amount) {
+			assert.Equal(t, "amount", violations(err)[0].GetField(), amount)
+			assert.Equal(t, "must be a decimal number", violations(err)[0].GetDescription(), amount)
+		}
+	}
+}
+
+func TestCreateTransfer(t *testing.T) {
+	f := newFixture(t)
+
+	source := accountsmodel.Accounts{}
+	source.SetId(123)
+	source.SetBalance(300)
+	source.SetVersion(1)
+	source.SetOwnerId("client-1")
+	f.accountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(source, nil)
+
+	destination := accountsmodel.Accounts{}
+	destination.SetId(456)
+	destination.SetBalance(200)
+	destination.SetVersion(2)
+	f.accountsDao.EXPECT().GetById(mock.Anything, int64(456)).Return(destination, nil)
+
+	f.db.ExpectBegin()
+	f.transactionsDao.EXPECT().Create(mock.Anything, mock.Anything, int64(123), int64(456), tenant.Default, 100.5, "client-1").Return(7, nil)
+	f.accountsDao.EXPECT().UpdateBalance(mock.Anything, mock.Anything, int64(123), int64(1), 199.5).Return(accountsmodel.Accounts{}, nil)
+	f.accountsDao.EXPECT().UpdateBalance(mock.Anything, mock.Anything, int64(456), int64(2), 300.5).Return(accountsmodel.Accounts{}, nil)
+	f.outboxDao.EXPECT().Add(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Times(3)
+	f.db.ExpectCommit()
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	transaction := transactionsmodel.Transactions{}
+	transaction.SetId(7)
+	transaction.SetSourceAccountId(123)
+	transaction.SetDestinationAccountId(456)
+	transaction.SetTenantId(tenant.Default)
+	transaction.SetDestinationTenantId(tenant.Default)
+	transaction.SetAmount(100.5)
+	transaction.SetClientId("client-1")
+	transaction.SetCreatedAt(createdAt)
+	f.transactionsDao.EXPECT().GetById(mock.Anything, int64(7)).Return(transaction, nil)
+
+	client, _ := f.dial(t, as(clientIdentity))
+	response, err := client.CreateTransfer(context.Background(), &ledgerv1.CreateTransferRequest{
+		SourceAccountId:      123,
+		DestinationAccountId: 456,
+		Amount:               "100.5",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), response.GetTransferId())
+	assert.Equal(t, "100.5", response.GetAmount())
+	assert.Equal(t, "client-1", response.GetClientId())
+	assert.Equal(t, createdAt, response.GetCreatedAt().AsTime())
+}
+
+func TestCreateTransfer_InsufficientFunds(t *testing.T) {
+	f := newFixture(t)
+	source := accountsmodel.Accounts{}
+	source.SetId(123)
+	source.SetBalance(100)
+	source.SetOwnerId("client-1")
+	f.accountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(source, nil)
+	f.db.ExpectBegin()
+	f.outboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeTransferFailed, int64(123), mock.Anything).Return(nil)
+	f.db.ExpectCommit()
+
+	client, _ := f.dial(t, as(clientIdentity))
+	_, err := client.CreateTransfer(context.Background(), &ledgerv1.CreateTransferRequest{
+		SourceAccountId:      123,
+		DestinationAccountId: 456,
+		Amount:               "200",
+	})
+
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, "account balance is less than transaction", status.Convert(err).Message())
+	assert.Equal(t, "INSUFFICIENT_FUNDS", reason(err))
+}
+
+type recorderFunc func(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error)
+
+func (f recorderFunc) Record(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error) {
+	return f(ctx, entry)
+}
+
+func (f recorderFunc) RecordTx(ctx context.Context, txn pgx.Tx, entry auditmodel.Entries) (auditmodel.Entries, error) {
+	entry.SetErrorCode("in transaction")
+	return f(ctx, entry)
+}
+
+func TestCreateAccount_AuditedWithTheChange(t *testing.T) {
+	f := newFixture(t)
+	account := accountsmodel.Accounts{}
+	account.SetId(1)
+	account.SetBalance(10)
+	account.SetOwnerId("client-1")
+	f.db.ExpectBegin()
+	f.accountsDao.EXPECT().Create(mock.Anything, mock.Anything, int64(1), float64(10), "client-1").Return(account, nil)
+	f.outboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeAccountCreated, int64(1), mock.Anything).Return(nil)
+	f.db.ExpectCommit()
+
+	var recorded []auditmodel.Entries
+	options := as(clientIdentity)
+	options.Audit = recorderFunc(func(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error) {
+		recorded = append(recorded, entry)
+		return entry, nil
+	})
+	client, _ := f.dial(t, options)
+	_, err := client.CreateAccount(context.Background(), &ledgerv1.CreateAccountRequest{AccountId: 1, InitialBalance: "10"})
+
+	assert.NoError(t, err)
+	assert.NoError(t, f.db.ExpectationsWereMet())
+	if assert.Len(t, recorded, 1) {
+		assert.Equal(t, "in transaction", recorded[0].GetErrorCode())
+		assert.Equal(t, http.StatusOK, recorded[0].GetStatus())
+		assert.Equal(t, ledgerv1.Ledger_CreateAccount_FullMethodName, recorded[0].GetEndpoint())
+		assert.Len(t, recorded[0].GetSnapshots(), 1)
+	}
+}
+
+func TestCreateTransfer_Audited(t *testing.T) {
+	f := newFixture(t)
+	source := accountsmodel.Accounts{}
+	source.SetId(123)
+	source.SetBalance(100)
+	source.SetOwnerId("client-1")
+	f.accountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(source, nil)
+	f.db.ExpectBegin()
+	f.outboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeTransferFailed, int64(123), mock.Anything).Return(nil)
+	f.db.ExpectCommit()
+
+	var recorded []auditmodel.Entries
+	options := as(clientIdentity)
+	options.Audit = recorderFunc(func(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error) {
+		recorded = append(recorded, entry)
+		return entry, nil
+	})
+	client, _ := f.dial(t, options)
+	_, err := client.CreateTransfer(context.Background(), &ledgerv1.CreateTransferRequest{
+		SourceAccountId:      123,
+		DestinationAccountId: 456,
+		Amount:               "200",
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	if assert.Len(t, recorded, 1) {
+		assert.Equal(t, "client-1", recorded[0].GetActor())
+		assert.Equal(t, "GRPC", recorded[0].GetMethod())
+		assert.Equal(t, ledgerv1.Ledger_CreateTransfer_FullMethodName, recorded[0].GetEndpoint())
+		assert.Equal(t, http.StatusUnprocessableEntity, recorded[0].GetStatus())
+		assert.Equal(t, "INSUFFICIENT_FUNDS", recorded[0].GetErrorCode())
+		assert.JSONEq(t, `{"sourceAccountId":"123","destinationAccountId":"456","amount":"200"}`, string(recorded[0].GetPayload()))
+	}
+}
+
+func TestCreateTransfer_RequiresTransferRole(t *testing.T) {
+	f := newFixture(t)
+
+	client, _ := f.dial(t, as(readOnlyIdentity))
+	_, err := client.CreateTransfer(context.Background(), &ledgerv1.CreateTransferRequest{SourceAccountId: 123, DestinationAccountId: 456, Amount: "1"})
+
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, "insufficient permissions", status.Convert(err).Message())
+}
+
+func TestCreateTransfer_RateLimitsSourceAccount(t *testing.T) {
+	f := newFixture(t)
+	f.accountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(accountsmodel.Accounts{}, pgx.ErrNoRows).Once()
+
+	options := as(clientIdentity)
+	options.Limits = ratelimit.NewMemoryStore()
+	options.AccountLimit = ratelimit.Limit{Rate: 1, Burst: 1}
+	client, _ := f.dial(t, options)
+
+	request := &ledgerv1.CreateTransferRequest{SourceAccountId: 123, DestinationAccountId: 456, Amount: "1"}
+	_, err := client.CreateTransfer(context.Background(), request)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	var header metadata.MD
+	_, err = client.CreateTransfer(context.Background(), request, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, "RATE_LIMITED", reason(err))
+	assert.Equal(t, []string{"1"}, header.Get("retry-after"))
+}
+
+func TestCreateTransfer_SourceAccountLimitIsPerClient(t *testing.T) {
+	f := newFixture(t)
+	f.accountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(accountsmodel.Accounts{}, pgx.ErrNoRows).Twice()
+
+	limits := ratelimit.NewMemoryStore()
+	dial := func(identity auth.Identity) ledgerv1.LedgerClient {
+		options := as(identity)
+		options.Limits = limits
+		options.AccountLimit = ratelimit.Limit{Rate: 1, Burst: 1}
+		client, _ := f.dial(t, options)
+		return client
+	}
+	other := dial(auth.Identity{ClientID: "client-2", Roles: []string{auth.RoleTransfer}})
+	owner := dial(clientIdentity)
+
+	request := &ledgerv1.CreateTransferRequest{SourceAccountId: 123, DestinationAccountId: 456, Amount: "1"}
+	_, err := other.CreateTransfer(context.Background(), request)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = other.CreateTransfer(context.Background(), request)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// the owner's allowance is untouched
+	_, err = owner.CreateTransfer(context.Background(), request)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestGetTransfer_NotFound(t *testing.T) {
+	f := newFixture(t)
+	f.transactionsDao.EXPECT().GetById(mock.Anything, int64(7)).Return(transactionsmodel.Transactions{}, pgx.ErrNoRows)
+
+	client, _ := f.dial(t, as(adminIdentity))
+	_, err := client.GetTransfer(context.Background(), &ledgerv1.GetTransferRequest{TransferId: 7})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, "TRANSFER_NOT_FOUND", reason(err))
+}
+
+func TestListTransfers_Pages(t *testing.T) {
+	f := newFixture(t)
+	account := accountsmodel.Accounts{}
+	account.SetId(123)
+	account.SetOwnerId("client-1")
+	f.accountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(account, nil)
+
+	page := make([]transactionsmodel.Transactions, 2)
+	page[0].SetId(9)
+	page[1].SetId(8)
+	f.transactionsDao.EXPECT().ListByAccount(mock.Anything, int64(123), int64(10), 2).Return(page, nil)
+
+	client, _ := f.dial(t, as(clientIdentity))
+	response, err := client.ListTransfers(context.Background(), &ledgerv1.ListTransfersRequest{AccountId: 123, PageSize: 2, PageToken: "10"})
+
+	assert.NoError(t, err)
+	assert.Len(t, response.GetTransfers(), 2)
+	assert.Equal(t, "8", response.GetNextPageToken())
+}
+
+func TestListTransfers_InvalidPageToken(t *testing.T) {
+	f := newFixture(t)
+
+	client, _ := f.dial(t, as(clientIdentity))
+	_, err := client.ListTransfers(context.Background(), &ledgerv1.ListTransfersRequest{AccountId: 123, PageToken: "abc"})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAuthentication_MissingCredentials(t *testing.T) {
+	f := newFixture(t)
+
+	client, conn := f.dial(t, Options{})
+	_, err := client.GetAccount(context.Background(), &ledgerv1.GetAccountRequest{AccountId: 123})
+
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Equal(t, "missing credentials", status.Convert(err).Message())
+
+	// health checks don't need credentials
+	health, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, health.GetStatus())
+}
+
+func TestAuthentication_TenantMetadata(t *testing.T) {
+	f := newFixture(t)
+
+	client, _ := f.dial(t, as(auth.Identity{ClientID: "client-1", Roles: []string{auth.RoleRead}, TenantID: "acme"}))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant-id", "other")
+	_, err := client.GetAccount(ctx, &ledgerv1.GetAccountRequest{AccountId: 123})
+
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, "credentials are not valid for this tenant", status.Convert(err).Message())
+}
+
+func TestRequestId_Echoed(t *testing.T) {
+	f := newFixture(t)
+
+	client, _ := f.dial(t, Options{})
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "abc")
+	_, _ = client.GetAccount(ctx, &ledgerv1.GetAccountRequest{AccountId: 123}, grpc.Header(&header))
+
+	assert.Equal(t, []string{"abc"}, header.Get("x-request-id"))
+}
@@ -0,0 +1,171 @@
+// Package accounts holds the account operations shared by the HTTP and gRPC
+// APIs. The caller is taken from the identity on the context. Creating an
+// account records an AccountCreated event in the outbox.
+package accounts
+
+import (
+	"context"
+	"errors"
+
+	accountsdao "github.com/ashwin-m/transactions/daos/accounts"
+	"github.com/ashwin-m/transactions/daos/outbox"
+	"github.com/ashwin-m/transactions/middlewares/audit"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	accountsmodel "github.com/ashwin-m/transactions/models/accounts"
+	eventsmodel "github.com/ashwin-m/transactions/models/events"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/decimal"
+	"github.com/ashwin-m/transactions/utils/pgxiface"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const errAccountForbidden = "you do not have access to this account"
+
+type CreateRequest struct {
+	Id             int64
+	InitialBalance string
+	// OwnerId may only be set by admins, accounts are otherwise owned by the
+	// client creating them.
+	OwnerId string
+}
+
+type Service interface {
+	Create(ctx context.Context, request CreateRequest) (accountsmodel.Accounts, error)
+	Get(ctx context.Context, id int64) (accountsmodel.Accounts, error)
+	// Events returns up to limit events about an account the caller has
+	// access to that come after the cursor after, in the order of their
+	// cursors. Events that may still be preceded by one yet to commit are
+	// left for a later call.
+	Events(ctx context.Context, id int64, after eventsmodel.Cursor, limit int) ([]eventsmodel.Events, error)
+	// EventsHorizon returns the cursor the events about an account the
+	// caller has access to, committed from now on, come after.
+	EventsHorizon(ctx context.Context, id int64) (eventsmodel.Cursor, error)
+}
+
+type service struct {
+	dbPool    pgxiface.PgxIface
+	dao       accountsdao.Dao
+	outboxDao outbox.Dao
+}
+
+func NewService(dbPool pgxiface.PgxIface, dao accountsdao.Dao, outboxDao outbox.Dao) Service {
+	return &service{
+		dbPool:    dbPool,
+		dao:       dao,
+		outboxDao: outboxDao,
+	}
+}
+
+func (s *service) Create(ctx context.Context, request CreateRequest) (accountsmodel.Accounts, error) {
+	// the gRPC API doesn't go through the numeric binding, so values like
+	// NaN or 0x1p3 are refused here
+	initialBalance, ok := decimal.ParseFloat(request.InitialBalance)
+	if !ok {
+		return accountsmodel.Accounts{}, apperrors.Validation(apperrors.FieldError{Field: "initial_balance", Message: "must be a decimal number"})
+	}
+	if initialBalance < 0 {
+		return accountsmodel.Accounts{}, apperrors.Validation(apperrors.FieldError{Field: "initial_balance", Message: "must not be negative"})
+	}
+
+	identity, _ := auth.FromContext(ctx)
+	ownerId := identity.ClientID
+	if request.OwnerId != "" && request.OwnerId != ownerId {
+		if !identity.HasRole(auth.RoleAdmin) {
+			return accountsmodel.Accounts{}, apperrors.New(apperrors.CodeForbidden, "only admins may create accounts for other clients")
+		}
+		ownerId = request.OwnerId
+	}
+
+	txn, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return accountsmodel.Accounts{}, err
+	}
+
+	account, err := s.dao.Create(ctx, txn, request.Id, initialBalance, ownerId)
+	if err != nil {
+		txn.Rollback(ctx)
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation:
+			return account, s.alreadyExists(ctx, request.Id).Wrap(err)
+		case errors.As(err, &pgErr) && pgerrcode.IsIntegrityConstraintViolation(pgErr.Code):
+			return account, apperrors.New(apperrors.CodeValidationFailed, "the account violates a database constraint").Wrap(err)
+		}
+		return account, err
+	}
+
+	err = s.outboxDao.Add(ctx, txn, eventsmodel.TypeAccountCreated, request.Id, eventsmodel.AccountCreated{
+		AccountId: request.Id,
+		OwnerId:   ownerId,
+		Balance:   initialBalance,
+	})
+	if err != nil {
+		txn.Rollback(ctx)
+		return accountsmodel.Accounts{}, err
+	}
+
+	audit.Changed(ctx, nil, &account)
+	err = audit.Commit(ctx, txn)
+	if err != nil {
+		return accountsmodel.Accounts{}, err
+	}
+
+	return account, nil
+}
+
+// alreadyExists reports an account id that is taken. Ids are picked by
+// clients so the conflict itself can't be hidden, but only those with access
+// to the account are told it is an account of theirs.
+func (s *service) alreadyExists(ctx context.Context, id int64) *apperrors.Error {
+	identity, _ := auth.FromContext(ctx)
+
+	existing, err := s.dao.GetById(ctx, id)
+	if err == nil && identity.CanAccess(existing.GetOwnerId()) {
+		return apperrors.Newf(apperrors.CodeAccountAlreadyExists, "account %d already exists", id)
+	}
+
+	return apperrors.New(apperrors.CodeAccountAlreadyExists, "the account id is not available")
+}
+
+// Get returns an account the caller has access to. Only admins learn that an
+// account doesn't exist, everyone else gets the same error as for someone
+// else's account.
+func (s *service) Get(ctx context.Context, id int64) (accountsmodel.Accounts, error) {
+	identity, _ := auth.FromContext(ctx)
+
+	account, err := s.dao.GetById(ctx, id)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows) && !identity.HasRole(auth.RoleAdmin):
+		return account, apperrors.New(apperrors.CodeForbidden, errAccountForbidden)
+	case errors.Is(err, pgx.ErrNoRows):
+		return account, apperrors.Newf(apperrors.CodeAccountNotFound, "account %d was not found", id)
+	case err != nil:
+		return account, err
+	}
+
+	if !identity.CanAccess(account.GetOwnerId()) {
+		return accountsmodel.Accounts{}, apperrors.New(apperrors.CodeForbidden, errAccountForbidden)
+	}
+
+	return account, nil
+}
+
+func (s *service) Events(ctx context.Context, id int64, after eventsmodel.Cursor, limit int) ([]eventsmodel.Events, error) {
+	_, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.outboxDao.ListByAccount(ctx, id, after, limit)
+}
+
+func (s *service) EventsHorizon(ctx context.Context, id int64) (eventsmodel.Cursor, error) {
+	_, err := s.Get(ctx, id)
+	if err != nil {
+		return eventsmodel.Cursor{}, err
+	}
+
+	return s.outboxDao.Horizon(ctx)
+}
@@ -0,0 +1,45 @@
+package accounts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ashwin-m/transactions/daos/memory"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func contextFor(identity auth.Identity) context.Context {
+	return tenant.NewContext(auth.NewContext(context.Background(), identity), tenant.Default)
+}
+
+func newService() Service {
+	store := memory.NewStore()
+	return NewService(store, memory.NewAccountsDao(store), memory.NewOutboxDao(store))
+}
+
+func TestCreate_AlreadyExists(t *testing.T) {
+	service := newService()
+	owner := auth.Identity{ClientID: "client-1", Roles: []string{auth.RoleRead, auth.RoleTransfer}}
+	other := auth.Identity{ClientID: "client-2", Roles: []string{auth.RoleRead, auth.RoleTransfer}}
+	admin := auth.Identity{ClientID: "admin", Roles: []string{auth.RoleAdmin}}
+
+	_, err := service.Create(contextFor(owner), CreateRequest{Id: 1, InitialBalance: "100"})
+	require.NoError(t, err)
+
+	for identity, detail := range map[*auth.Identity]string{
+		&owner: "account 1 already exists",
+		&admin: "account 1 already exists",
+		// someone else's account is only known to be taken
+		&other: "the account id is not available",
+	} {
+		_, err = service.Create(contextFor(*identity), CreateRequest{Id: 1, InitialBalance: "5"})
+
+		appErr := apperrors.From(err)
+		assert.Equal(t, apperrors.CodeAccountAlreadyExists, appErr.Code, identity.ClientID)
+		assert.Equal(t, detail, appErr.Detail, identity.ClientID)
+	}
+}
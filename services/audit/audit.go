@@ -0,0 +1,370 @@
+// Package audit keeps the audit log of the state-changing calls made to the
+// API. Entries can't be changed once written, and each one is chained to
+// the one before it by an HMAC keyed with a key only the servers hold, so
+// tampering with stored entries shows up when the chain is verified, even
+// when done by someone able to write to the database.
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	auditdao "github.com/ashwin-m/transactions/daos/audit"
+	auditmodel "github.com/ashwin-m/transactions/models/audit"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/pgxiface"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 100
+
+	// verifyBatch is how many entries Verify reads at a time.
+	verifyBatch = 500
+)
+
+type ListRequest struct {
+	Actor     string
+	AccountId int64
+	RequestId string
+	From      time.Time
+	To        time.Time
+	// PageSize defaults to DefaultPageSize and is capped at MaxPageSize.
+	PageSize int
+	// BeforeId continues a listing after the last entry of a page.
+	BeforeId int64
+}
+
+// Verification is the outcome of checking the chain of a tenant.
+type Verification struct {
+	// Entries is how many entries were checked.
+	Entries int64 `json:"entries"`
+	Valid   bool  `json:"valid"`
+	// BrokenAt is the first entry whose hash or link to the entry before it
+	// doesn't match, when the chain isn't valid.
+	BrokenAt int64 `json:"broken_at,omitempty"`
+}
+
+// Service keeps the audit log of the tenant on the context.
+type Service interface {
+	// Record appends an entry to the chain and returns it with its id and
+	// hashes.
+	Record(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error)
+	// RecordTx appends an entry to the chain within txn, the transaction of
+	// the changes it records, so it is only kept when they are committed.
+	// The chain of the tenant stays locked until txn ends.
+	RecordTx(ctx context.Context, txn pgx.Tx, entry auditmodel.Entries) (auditmodel.Entries, error)
+	// List returns the entries matching request, newest first.
+	List(ctx context.Context, request ListRequest) ([]auditmodel.Entries, error)
+	// Verify recomputes the chain from its first entry.
+	Verify(ctx context.Context) (Verification, error)
+}
+
+type service struct {
+	dbPool pgxiface.PgxIface
+	dao    auditdao.Dao
+	// key keys the hashes of the chain. Without one entries are hashed with
+	// plain SHA-256, which anyone able to write to the database can forge.
+	key []byte
+	// cutovers holds the tenants whose cutover is known to be recorded.
+	cutovers sync.Map
+}
+
+func NewService(dbPool pgxiface.PgxIface, dao auditdao.Dao, key []byte) Service {
+	return &service{
+		dbPool: dbPool,
+		dao:    dao,
+		key:    key,
+	}
+}
+
+func (s *service) Record(ctx context.Context, entry auditmodel.Entries) (auditmodel.Entries, error) {
+	txn, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return auditmodel.Entries{}, err
+	}
+
+	entry, err = s.RecordTx(ctx, txn, entry)
+	if err != nil {
+		txn.Rollback(ctx)
+		return auditmodel.Entries{}, err
+	}
+
+	err = txn.Commit(ctx)
+	if err != nil {
+		return auditmodel.Entries{}, err
+	}
+
+	return entry, nil
+}
+
+func (s *service) RecordTx(ctx context.Context, txn pgx.Tx, entry auditmodel.Entries) (auditmodel.Entries, error) {
+	tenantId, err := tenant.Require(ctx)
+	if err != nil {
+		return auditmodel.Entries{}, err
+	}
+	entry.SetTenantId(tenantId)
+
+	// postgres keeps microseconds, the hash must cover what is stored
+	entry.SetOccurredAt(entry.GetOccurredAt().UTC().Truncate(time.Microsecond))
+	if len(entry.GetPayload()) == 0 {
+		entry.SetPayload(json.RawMessage("null"))
+	}
+	if entry.GetSnapshots() == nil {
+		entry.SetSnapshots([]auditmodel.Snapshot{})
+	}
+
+	err = s.dao.Lock(ctx, txn)
+	if err != nil {
+		return auditmodel.Entries{}, err
+	}
+
+	prevHash, err := s.dao.LastHash(ctx, txn)
+	if err != nil {
+		return auditmodel.Entries{}, err
+	}
+
+	entry.SetPrevHash(prevHash)
+	hash, err := Hash(s.key, entry)
+	if err != nil {
+		return auditmodel.Entries{}, err
+	}
+	entry.SetHash(hash)
+
+	appended, err := s.dao.Append(ctx, txn, entry)
+	if err != nil {
+		return auditmodel.Entries{}, err
+	}
+
+	err = s.recordCutover(ctx, txn, tenantId, appended.GetId())
+	if err != nil {
+		return auditmodel.Entries{}, err
+	}
+
+	return appended, nil
+}
+
+// recordCutover records where the chain of the tenant started being keyed,
+// unless it already is, with the chain lock held. id is the entry just
+// appended, the cutover when no committed entry is keyed yet. A cutover
+// recorded with an entry that is rolled back is rolled back with it.
+func (s *service) recordCutover(ctx context.Context, txn pgx.Tx, tenantId string, id int64) error {
+	if len(s.key) == 0 {
+		return nil
+	}
+	if _, ok := s.cutovers.Load(tenantId); ok {
+		return nil
+	}
+
+	_, found, err := s.dao.Cutover(ctx)
+	if err != nil {
+		return err
+	}
+	if found {
+		s.cutovers.Store(tenantId, true)
+		return nil
+	}
+
+	// chains keyed before cutovers were recorded start at their first
+	// keyed entry
+	keyedFrom, err := s.firstKeyed(ctx)
+	if err != nil {
+		return err
+	}
+	if keyedFrom == 0 {
+		keyedFrom = id
+	}
+
+	return s.dao.SetCutover(ctx, txn, auditdao.Cutover{KeyedFrom: keyedFrom, MAC: cutoverMAC(s.key, tenantId, keyedFrom)})
+}
+
+// firstKeyed returns the id of the first committed entry of the chain
+// hashed with the key, 0 when there is none.
+func (s *service) firstKeyed(ctx context.Context) (int64, error) {
+	var afterId int64
+	for {
+		entries, err := s.dao.Chain(ctx, afterId, verifyBatch)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, entry := range entries {
+			hash, err := Hash(s.key, entry)
+			if err != nil {
+				return 0, err
+			}
+			if entry.GetHash() == hash {
+				return entry.GetId(), nil
+			}
+			afterId = entry.GetId()
+		}
+
+		if len(entries) < verifyBatch {
+			return 0, nil
+		}
+	}
+}
+
+// cutoverMAC is the hex HMAC-SHA256, keyed with key, of the cutover of a
+// tenant.
+func cutoverMAC(key []byte, tenantId string, keyedFrom int64) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "audit key cutover\n%s\n%d", tenantId, keyedFrom)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *service) List(ctx context.Context, request ListRequest) ([]auditmodel.Entries, error) {
+	var fields []apperrors.FieldError
+	if request.PageSize < 0 {
+		fields = append(fields, apperrors.FieldError{Field: "page_size", Message: "must not be negative"})
+	}
+	if !request.From.IsZero() && !request.To.IsZero() && !request.From.Before(request.To) {
+		fields = append(fields, apperrors.FieldError{Field: "to", Message: "must be after from"})
+	}
+	if len(fields) > 0 {
+		return nil, apperrors.Validation(fields...)
+	}
+
+	return s.dao.List(ctx, auditdao.Filter{
+		Actor:     request.Actor,
+		AccountId: request.AccountId,
+		RequestId: request.RequestId,
+		From:      request.From,
+		To:        request.To,
+		BeforeId:  request.BeforeId,
+		Limit:     PageSize(request.PageSize),
+	})
+}
+
+func (s *service) Verify(ctx context.Context) (Verification, error) {
+	verification := Verification{Valid: true}
+
+	// entries from the cutover on are checked with the key, those before it
+	// by their plain hash. Without a valid cutover every entry must be
+	// keyed, so the chain can't be rehashed without the key by removing it.
+	keyedFrom := int64(0)
+	if len(s.key) > 0 {
+		cutover, found, err := s.dao.Cutover(ctx)
+		if err != nil {
+			return Verification{}, err
+		}
+		tenantId, _ := tenant.FromContext(ctx)
+		if found && hmac.Equal([]byte(cutover.MAC), []byte(cutoverMAC(s.key, tenantId, cutover.KeyedFrom))) {
+			keyedFrom = cutover.KeyedFrom
+		}
+	}
+
+	var afterId int64
+	prevHash := ""
+	for {
+		entries, err := s.dao.Chain(ctx, afterId, verifyBatch)
+		if err != nil {
+			return Verification{}, err
+		}
+
+		for _, entry := range entries {
+			verification.Entries++
+
+			key := s.key
+			if entry.GetId() < keyedFrom {
+				key = nil
+			}
+			hash, err := Hash(key, entry)
+			if err != nil {
+				return Verification{}, err
+			}
+
+			if entry.GetPrevHash() != prevHash || entry.GetHash() != hash {
+				verification.Valid = false
+				verification.BrokenAt = entry.GetId()
+				return verification, nil
+			}
+
+			prevHash = entry.GetHash()
+			afterId = entry.GetId()
+		}
+
+		if len(entries) < verifyBatch {
+			return verification, nil
+		}
+	}
+}
+
+// hashed is what the hash of an entry covers. Its fields are marshalled in
+// order, so the same entry always hashes the same.
+type hashed struct {
+	PrevHash   string                `json:"prev_hash"`
+	TenantId   string                `json:"tenant_id"`
+	OccurredAt string                `json:"occurred_at"`
+	Actor      string                `json:"actor"`
+	AuthMethod string                `json:"auth_method"`
+	RequestId  string                `json:"request_id"`
+	Method     string                `json:"method"`
+	Endpoint   string                `json:"endpoint"`
+	Status     int                   `json:"status"`
+	ErrorCode  string                `json:"error_code"`
+	Payload    json.RawMessage       `json:"payload"`
+	Snapshots  []auditmodel.Snapshot `json:"snapshots"`
+}
+
+// Hash returns the hash of an entry: the hex HMAC-SHA256, keyed with key, of
+// its fields, including the hash of the entry before it. Without a key it is
+// their plain SHA-256.
+func Hash(key []byte, entry auditmodel.Entries) (string, error) {
+	payload := entry.GetPayload()
+	if len(payload) == 0 {
+		payload = json.RawMessage("null")
+	}
+	snapshots := entry.GetSnapshots()
+	if snapshots == nil {
+		snapshots = []auditmodel.Snapshot{}
+	}
+
+	b, err := json.Marshal(hashed{
+		PrevHash:   entry.GetPrevHash(),
+		TenantId:   entry.GetTenantId(),
+		OccurredAt: entry.GetOccurredAt().UTC().Format(time.RFC3339Nano),
+		Actor:      entry.GetActor(),
+		AuthMethod: entry.GetAuthMethod(),
+		RequestId:  entry.GetRequestId(),
+		Method:     entry.GetMethod(),
+		Endpoint:   entry.GetEndpoint(),
+		Status:     entry.GetStatus(),
+		ErrorCode:  entry.GetErrorCode(),
+		Payload:    payload,
+		Snapshots:  snapshots,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(key) == 0 {
+		sum := sha256.Sum256(b)
+		return hex.EncodeToString(sum[:]), nil
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// PageSize is the number of entries List returns for a requested page size.
+// Fewer means the listing is complete.
+func PageSize(requested int) int {
+	switch {
+	case requested <= 0:
+		return DefaultPageSize
+	case requested > MaxPageSize:
+		return MaxPageSize
+	}
+
+	return requested
+}
@@ -0,0 +1,370 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	auditdao "github.com/ashwin-m/transactions/daos/audit"
+	auditdaomocks "github.com/ashwin-m/transactions/daos/audit/mocks"
+	auditmodel "github.com/ashwin-m/transactions/models/audit"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func entry(id int64, requestId string) auditmodel.Entries {
+	e := auditmodel.Entries{}
+	e.SetId(id)
+	e.SetTenantId(tenant.Default)
+	e.SetOccurredAt(time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC))
+	e.SetActor("client-1")
+	e.SetRequestId(requestId)
+	e.SetMethod("POST")
+	e.SetEndpoint("/v1/transactions")
+	e.SetStatus(201)
+	e.SetPayload(json.RawMessage(`{"amount":"10"}`))
+	e.SetSnapshots([]auditmodel.Snapshot{{
+		TenantId:  tenant.Default,
+		AccountId: 1,
+		Before:    &auditmodel.AccountState{Balance: 100, OwnerId: "client-1", Version: 1},
+		After:     &auditmodel.AccountState{Balance: 90, OwnerId: "client-1", Version: 2},
+	}})
+	return e
+}
+
+// cutover is the cutover of the default tenant at keyedFrom.
+func cutover(keyedFrom int64) auditdao.Cutover {
+	return auditdao.Cutover{KeyedFrom: keyedFrom, MAC: cutoverMAC(testKey, tenant.Default, keyedFrom)}
+}
+
+// chain links entries the way Record does, keyed with key.
+func chain(t *testing.T, key []byte, entries ...auditmodel.Entries) []auditmodel.Entries {
+	prevHash := ""
+	if len(entries) > 0 {
+		prevHash = entries[0].GetPrevHash()
+	}
+	for i := range entries {
+		entries[i].SetPrevHash(prevHash)
+		hash, err := Hash(key, entries[i])
+		assert.NoError(t, err)
+		entries[i].SetHash(hash)
+		prevHash = hash
+	}
+	return entries
+}
+
+func TestRecord_ChainsToTheLastEntry(t *testing.T) {
+	ctx := tenant.NewContext(context.Background(), tenant.Default)
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectCommit()
+
+	mockDao := auditdaomocks.NewDao(t)
+	mockDao.EXPECT().Lock(mock.Anything, mock.Anything).Return(nil)
+	mockDao.EXPECT().LastHash(mock.Anything, mock.Anything).Return("previous", nil)
+	mockDao.EXPECT().Append(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, tx pgx.Tx, e auditmodel.Entries) (auditmodel.Entries, error) {
+		e.SetId(7)
+		return e, nil
+	})
+	mockDao.EXPECT().Cutover(mock.Anything).Return(cutover(5), true, nil)
+
+	recorded := entry(0, "req-1")
+	recorded.SetOccurredAt(time.Date(2024, 5, 1, 14, 0, 0, 123456789, time.FixedZone("CEST", 2*60*60)))
+	recorded.SetTenantId("")
+
+	appended, err := NewService(mockDB, mockDao, testKey).Record(ctx, recorded)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), appended.GetId())
+	assert.Equal(t, tenant.Default, appended.GetTenantId())
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC), appended.GetOccurredAt())
+	assert.Equal(t, "previous", appended.GetPrevHash())
+	hash, _ := Hash(testKey, appended)
+	assert.Equal(t, hash, appended.GetHash())
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestRecord_RollsBackWhenAppendFails(t *testing.T) {
+	ctx := tenant.NewContext(context.Background(), tenant.Default)
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectRollback()
+
+	mockDao := auditdaomocks.NewDao(t)
+	mockDao.EXPECT().Lock(mock.Anything, mock.Anything).Return(nil)
+	mockDao.EXPECT().LastHash(mock.Anything, mock.Anything).Return("", nil)
+	mockDao.EXPECT().Append(mock.Anything, mock.Anything, mock.Anything).Return(auditmodel.Entries{}, assert.AnError)
+
+	_, err := NewService(mockDB, mockDao, testKey).Record(ctx, entry(0, "req-1"))
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestRecordTx_WritesInTheGivenTransaction(t *testing.T) {
+	ctx := tenant.NewContext(context.Background(), tenant.Default)
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	txn, _ := mockDB.Begin(ctx)
+
+	mockDao := auditdaomocks.NewDao(t)
+	mockDao.EXPECT().Lock(mock.Anything, txn).Return(nil)
+	mockDao.EXPECT().LastHash(mock.Anything, txn).Return("", nil)
+	mockDao.EXPECT().Append(mock.Anything, txn, mock.Anything).RunAndReturn(func(ctx context.Context, tx pgx.Tx, e auditmodel.Entries) (auditmodel.Entries, error) {
+		return e, nil
+	})
+	mockDao.EXPECT().Cutover(mock.Anything).Return(cutover(5), true, nil)
+
+	_, err := NewService(mockDB, mockDao, testKey).RecordTx(ctx, txn, entry(0, "req-1"))
+
+	// neither committed nor rolled back, that is up to the caller
+	assert.NoError(t, err)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestRecordTx_RecordsTheCutover(t *testing.T) {
+	ctx := tenant.NewContext(context.Background(), tenant.Default)
+
+	t.Run("at the first keyed entry", func(t *testing.T) {
+		entries := chain(t, nil, entry(1, "req-1"), entry(2, "req-2"))
+		next := entry(3, "req-3")
+		next.SetPrevHash(entries[1].GetHash())
+		entries = append(entries, chain(t, testKey, next)...)
+
+		mockDao := auditdaomocks.NewDao(t)
+		mockDao.EXPECT().Lock(mock.Anything, nil).Return(nil)
+		mockDao.EXPECT().LastHash(mock.Anything, nil).Return(entries[2].GetHash(), nil)
+		mockDao.EXPECT().Append(mock.Anything, nil, mock.Anything).RunAndReturn(func(ctx context.Context, tx pgx.Tx, e auditmodel.Entries) (auditmodel.Entries, error) {
+			e.SetId(4)
+			return e, nil
+		})
+		mockDao.EXPECT().Cutover(mock.Anything).Return(auditdao.Cutover{}, false, nil).Once()
+		mockDao.EXPECT().Chain(mock.Anything, int64(0), verifyBatch).Return(entries, nil)
+		mockDao.EXPECT().SetCutover(mock.Anything, nil, cutover(3)).Return(nil)
+
+		service := NewService(nil, mockDao, testKey)
+		_, err := service.RecordTx(ctx, nil, entry(0, "req-4"))
+		assert.NoError(t, err)
+
+		// once seen recorded, it isn't looked up again
+		mockDao.EXPECT().Cutover(mock.Anything).Return(cutover(3), true, nil).Once()
+		_, err = service.RecordTx(ctx, nil, entry(0, "req-5"))
+		assert.NoError(t, err)
+		_, err = service.RecordTx(ctx, nil, entry(0, "req-6"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("at the entry appended when none is keyed", func(t *testing.T) {
+		mockDao := auditdaomocks.NewDao(t)
+		mockDao.EXPECT().Lock(mock.Anything, nil).Return(nil)
+		mockDao.EXPECT().LastHash(mock.Anything, nil).Return("", nil)
+		mockDao.EXPECT().Append(mock.Anything, nil, mock.Anything).RunAndReturn(func(ctx context.Context, tx pgx.Tx, e auditmodel.Entries) (auditmodel.Entries, error) {
+			e.SetId(1)
+			return e, nil
+		})
+		mockDao.EXPECT().Cutover(mock.Anything).Return(auditdao.Cutover{}, false, nil)
+		mockDao.EXPECT().Chain(mock.Anything, int64(0), verifyBatch).Return(nil, nil)
+		mockDao.EXPECT().SetCutover(mock.Anything, nil, cutover(1)).Return(nil)
+
+		_, err := NewService(nil, mockDao, testKey).RecordTx(ctx, nil, entry(0, "req-1"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("not without a key", func(t *testing.T) {
+		mockDao := auditdaomocks.NewDao(t)
+		mockDao.EXPECT().Lock(mock.Anything, nil).Return(nil)
+		mockDao.EXPECT().LastHash(mock.Anything, nil).Return("", nil)
+		mockDao.EXPECT().Append(mock.Anything, nil, mock.Anything).RunAndReturn(func(ctx context.Context, tx pgx.Tx, e auditmodel.Entries) (auditmodel.Entries, error) {
+			return e, nil
+		})
+
+		_, err := NewService(nil, mockDao, nil).RecordTx(ctx, nil, entry(0, "req-1"))
+		assert.NoError(t, err)
+	})
+}
+
+func TestHash_IsKeyed(t *testing.T) {
+	keyed, _ := Hash(testKey, entry(1, "req-1"))
+	otherKey, _ := Hash([]byte("another key of thirty-two bytes!"), entry(1, "req-1"))
+	plain, _ := Hash(nil, entry(1, "req-1"))
+
+	assert.NotEqual(t, keyed, otherKey)
+	assert.NotEqual(t, keyed, plain)
+}
+
+func TestHash_CoversEveryField(t *testing.T) {
+	original, _ := Hash(testKey, entry(1, "req-1"))
+
+	changes := map[string]func(e *auditmodel.Entries){
+		"prev hash":  func(e *auditmodel.Entries) { e.SetPrevHash("other") },
+		"actor":      func(e *auditmodel.Entries) { e.SetActor("client-2") },
+		"request id": func(e *auditmodel.Entries) { e.SetRequestId("req-2") },
+		"status":     func(e *auditmodel.Entries) { e.SetStatus(422) },
+		"payload":    func(e *auditmodel.Entries) { e.SetPayload(json.RawMessage(`{"amount":"11"}`)) },
+		"snapshots":  func(e *auditmodel.Entries) { e.GetSnapshots()[0].After.Balance = 1000 },
+		"time":       func(e *auditmodel.Entries) { e.SetOccurredAt(e.GetOccurredAt().Add(time.Microsecond)) },
+	}
+	for name, change := range changes {
+		t.Run(name, func(t *testing.T) {
+			changed := entry(1, "req-1")
+			change(&changed)
+
+			hash, err := Hash(testKey, changed)
+
+			assert.NoError(t, err)
+			assert.NotEqual(t, original, hash)
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ctx := tenant.NewContext(context.Background(), tenant.Default)
+
+	t.Run("intact chain", func(t *testing.T) {
+		mockDao := auditdaomocks.NewDao(t)
+		mockDao.EXPECT().Cutover(mock.Anything).Return(cutover(1), true, nil)
+		mockDao.EXPECT().Chain(mock.Anything, int64(0), verifyBatch).Return(chain(t, testKey, entry(1, "req-1"), entry(2, "req-2"), entry(3, "req-3")), nil)
+
+		verification, err := NewService(nil, mockDao, testKey).Verify(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Verification{Entries: 3, Valid: true}, verification)
+	})
+
+	t.Run("tampered entry", func(t *testing.T) {
+		entries := chain(t, testKey, entry(1, "req-1"), entry(2, "req-2"), entry(3, "req-3"))
+		entries[1].SetStatus(500)
+
+		mockDao := auditdaomocks.NewDao(t)
+		mockDao.EXPECT().Cutover(mock.Anything).Return(cutover(1), true, nil)
+		mockDao.EXPECT().Chain(mock.Anything, int64(0), verifyBatch).Return(entries, nil)
+
+		verification, err := NewService(nil, mockDao, testKey).Verify(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Verification{Entries: 2, Valid: false, BrokenAt: 2}, verification)
+	})
+
+	t.Run("deleted entry", func(t *testing.T) {
+		entries := chain(t, testKey, entry(1, "req-1"), entry(2, "req-2"), entry(3, "req-3"))
+
+		mockDao := auditdaomocks.NewDao(t)
+		mockDao.EXPECT().Cutover(mock.Anything).Return(cutover(1), true, nil)
+		mockDao.EXPECT().Chain(mock.Anything, int64(0), verifyBatch).Return([]auditmodel.Entries{entries[0], entries[2]}, nil)
+
+		verification, err := NewService(nil, mockDao, testKey).Verify(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Verification{Entries: 2, Valid: false, BrokenAt: 3}, verification)
+	})
+
+	t.Run("entries from before the key", func(t *testing.T) {
+		entries := chain(t, nil, entry(1, "req-1"), entry(2, "req-2"))
+		next := entry(3, "req-3")
+		next.SetPrevHash(entries[1].GetHash())
+		entries = append(entries, chain(t, testKey, next)...)
+
+		mockDao := auditdaomocks.NewDao(t)
+		mockDao.EXPECT().Cutover(mock.Anything).Return(cutover(3), true, nil)
+		mockDao.EXPECT().Chain(mock.Anything, int64(0), verifyBatch).Return(entries, nil)
+
+		verification, err := NewService(nil, mockDao, testKey).Verify(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Verification{Entries: 3, Valid: true}, verification)
+	})
+
+	t.Run("unkeyed entry forged after keyed ones", func(t *testing.T) {
+		entries := chain(t, testKey, entry(1, "req-1"), entry(2, "req-2"))
+		forged := entry(3, "req-3")
+		forged.SetPrevHash(entries[1].GetHash())
+		entries = append(entries, chain(t, nil, forged)...)
+
+		mockDao := auditdaomocks.NewDao(t)
+		mockDao.EXPECT().Cutover(mock.Anything).Return(cutover(1), true, nil)
+		mockDao.EXPECT().Chain(mock.Anything, int64(0), verifyBatch).Return(entries, nil)
+
+		verification, err := NewService(nil, mockDao, testKey).Verify(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Verification{Entries: 3, Valid: false, BrokenAt: 3}, verification)
+	})
+
+	// someone able to write to the database rehashes the whole chain
+	// without the key
+	rehashed := func(t *testing.T) []auditmodel.Entries {
+		return chain(t, nil, entry(1, "req-1"), entry(2, "req-2"), entry(3, "req-3"))
+	}
+	t.Run("unkeyed chain rehashed after the cutover", func(t *testing.T) {
+		mockDao := auditdaomocks.NewDao(t)
+		mockDao.EXPECT().Cutover(mock.Anything).Return(cutover(2), true, nil)
+		mockDao.EXPECT().Chain(mock.Anything, int64(0), verifyBatch).Return(rehashed(t), nil)
+
+		verification, err := NewService(nil, mockDao, testKey).Verify(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Verification{Entries: 2, Valid: false, BrokenAt: 2}, verification)
+	})
+
+	t.Run("unkeyed chain rehashed and cutover removed", func(t *testing.T) {
+		mockDao := auditdaomocks.NewDao(t)
+		mockDao.EXPECT().Cutover(mock.Anything).Return(auditdao.Cutover{}, false, nil)
+		mockDao.EXPECT().Chain(mock.Anything, int64(0), verifyBatch).Return(rehashed(t), nil)
+
+		verification, err := NewService(nil, mockDao, testKey).Verify(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Verification{Entries: 1, Valid: false, BrokenAt: 1}, verification)
+	})
+
+	t.Run("unkeyed chain rehashed and cutover moved", func(t *testing.T) {
+		moved := cutover(2)
+		moved.KeyedFrom = 4
+
+		mockDao := auditdaomocks.NewDao(t)
+		mockDao.EXPECT().Cutover(mock.Anything).Return(moved, true, nil)
+		mockDao.EXPECT().Chain(mock.Anything, int64(0), verifyBatch).Return(rehashed(t), nil)
+
+		verification, err := NewService(nil, mockDao, testKey).Verify(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Verification{Entries: 1, Valid: false, BrokenAt: 1}, verification)
+	})
+
+	t.Run("unkeyed chain without a key", func(t *testing.T) {
+		mockDao := auditdaomocks.NewDao(t)
+		mockDao.EXPECT().Chain(mock.Anything, int64(0), verifyBatch).Return(rehashed(t), nil)
+
+		verification, err := NewService(nil, mockDao, nil).Verify(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Verification{Entries: 3, Valid: true}, verification)
+	})
+}
+
+func TestList(t *testing.T) {
+	ctx := tenant.NewContext(context.Background(), tenant.Default)
+
+	mockDao := auditdaomocks.NewDao(t)
+	mockDao.EXPECT().List(mock.Anything, auditdao.Filter{AccountId: 1, BeforeId: 40, Limit: MaxPageSize}).Return(nil, nil)
+
+	_, err := NewService(nil, mockDao, testKey).List(ctx, ListRequest{AccountId: 1, BeforeId: 40, PageSize: 1000})
+	assert.NoError(t, err)
+
+	now := time.Now()
+	_, err = NewService(nil, mockDao, testKey).List(ctx, ListRequest{From: now, To: now})
+	var appErr *apperrors.Error
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, apperrors.CodeValidationFailed, appErr.Code)
+	}
+}
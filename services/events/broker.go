@@ -0,0 +1,199 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ashwin-m/transactions/utils/jobs"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// Channel is the notification channel every committed outbox event is
+	// announced on.
+	Channel = "outbox_events"
+
+	// subscriptionBuffer is how many messages a subscriber may fall behind
+	// before it is dropped.
+	subscriptionBuffer = 64
+
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Broker streams the events of accounts as they are committed. It listens
+// for the notifications of the outbox on a connection of its own and hands
+// each event to the subscribers of its account.
+//
+// Subscribers can miss events: while the broker reconnects, or when they
+// fall behind. Their subscription is closed then, and they catch up from the
+// outbox by cursor.
+type Broker struct {
+	connect func(ctx context.Context) (*pgx.Conn, error)
+	job     *jobs.Job
+
+	mu          sync.Mutex
+	subscribers map[account]map[*Subscription]struct{}
+}
+
+type account struct {
+	tenantId string
+	id       int64
+}
+
+// NewBroker builds a broker listening on connections opened with connect,
+// and reporting the state of the listener to job, which may be nil.
+func NewBroker(connect func(ctx context.Context) (*pgx.Conn, error), job *jobs.Job) *Broker {
+	return &Broker{
+		connect:     connect,
+		job:         job,
+		subscribers: map[account]map[*Subscription]struct{}{},
+	}
+}
+
+// Run listens for events until ctx is done, reconnecting with a growing delay
+// when the connection fails. Every subscription is closed when it returns.
+func (b *Broker) Run(ctx context.Context) {
+	defer b.closeAll()
+
+	delay := time.Second
+	for {
+		if b.job != nil {
+			b.job.Started()
+		}
+
+		err := b.listen(ctx)
+		// subscribers may have missed events while the listener was down
+		b.closeAll()
+		if ctx.Err() != nil {
+			return
+		}
+
+		slog.ErrorContext(ctx, "lost the outbox listener", slog.Any("error", err))
+		if b.job != nil {
+			b.job.Failed(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, maxReconnectDelay)
+	}
+}
+
+func (b *Broker) listen(ctx context.Context) error {
+	conn, err := b.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "listen "+Channel)
+	if err != nil {
+		return err
+	}
+	if b.job != nil {
+		b.job.Succeeded()
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		b.Dispatch(notification.Payload)
+	}
+}
+
+// Dispatch hands an event, as announced on Channel, to the subscribers of
+// its account. Subscribers that fell too far behind are dropped.
+func (b *Broker) Dispatch(payload string) {
+	var message Message
+	err := json.Unmarshal([]byte(payload), &message)
+	if err != nil {
+		slog.Error("invalid outbox notification", slog.String("payload", payload), slog.Any("error", err))
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for subscription := range b.subscribers[account{message.TenantId, message.AccountId}] {
+		select {
+		case subscription.messages <- message:
+		default:
+			b.remove(subscription)
+		}
+	}
+}
+
+// Subscribe streams the events about an account committed from now on. The
+// subscription must be closed once done with.
+func (b *Broker) Subscribe(tenantId string, accountId int64) *Subscription {
+	subscription := &Subscription{
+		broker:   b,
+		key:      account{tenantId, accountId},
+		messages: make(chan Message, subscriptionBuffer),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers[subscription.key] == nil {
+		b.subscribers[subscription.key] = map[*Subscription]struct{}{}
+	}
+	b.subscribers[subscription.key][subscription] = struct{}{}
+
+	return subscription
+}
+
+// remove drops a subscription, closing its channel. b.mu must be held.
+func (b *Broker) remove(subscription *Subscription) {
+	subscribers := b.subscribers[subscription.key]
+	if _, ok := subscribers[subscription]; !ok {
+		return
+	}
+
+	delete(subscribers, subscription)
+	if len(subscribers) == 0 {
+		delete(b.subscribers, subscription.key)
+	}
+	close(subscription.messages)
+}
+
+func (b *Broker) closeAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, subscribers := range b.subscribers {
+		for subscription := range subscribers {
+			b.remove(subscription)
+		}
+	}
+}
+
+// Subscription is the stream of events about one account.
+type Subscription struct {
+	broker   *Broker
+	key      account
+	messages chan Message
+}
+
+// Messages delivers the events in the order they were committed. It is
+// closed when the broker drops the subscription, after which events may have
+// been missed.
+func (s *Subscription) Messages() <-chan Message {
+	return s.messages
+}
+
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	s.broker.remove(s)
+}
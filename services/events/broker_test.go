@@ -0,0 +1,62 @@
+package events
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func notification(id int64, tenantId string, accountId int64) string {
+	return `{"id":` + strconv.FormatInt(id, 10) + `,"type":"account.balance_changed","tenant_id":"` + tenantId + `","account_id":` + strconv.FormatInt(accountId, 10) + `,"occurred_at":"2026-01-02T03:04:05.123456+00:00","data":{"balance":10}}`
+}
+
+func TestBroker_DispatchesToSubscribersOfTheAccount(t *testing.T) {
+	broker := NewBroker(nil, nil)
+	subscription := broker.Subscribe("retail", 123)
+	defer subscription.Close()
+
+	broker.Dispatch(notification(1, "retail", 456))
+	broker.Dispatch(notification(2, "corporate", 123))
+	broker.Dispatch(notification(3, "retail", 123))
+
+	message := <-subscription.Messages()
+	assert.Equal(t, int64(3), message.Id)
+	assert.Equal(t, "account.balance_changed", message.Type)
+	assert.JSONEq(t, `{"balance":10}`, string(message.Data))
+	assert.Empty(t, subscription.Messages())
+}
+
+func TestBroker_DropsSubscribersThatFallBehind(t *testing.T) {
+	broker := NewBroker(nil, nil)
+	slow := broker.Subscribe("retail", 123)
+	defer slow.Close()
+
+	for id := int64(1); id <= subscriptionBuffer+1; id++ {
+		broker.Dispatch(notification(id, "retail", 123))
+	}
+
+	received := 0
+	for range slow.Messages() {
+		received++
+	}
+	assert.Equal(t, subscriptionBuffer, received)
+
+	// a new subscription of the account isn't affected
+	next := broker.Subscribe("retail", 123)
+	defer next.Close()
+	broker.Dispatch(notification(100, "retail", 123))
+	assert.Equal(t, int64(100), (<-next.Messages()).Id)
+}
+
+func TestSubscription_CloseIsIdempotent(t *testing.T) {
+	broker := NewBroker(nil, nil)
+	subscription := broker.Subscribe("retail", 123)
+
+	subscription.Close()
+	subscription.Close()
+	broker.Dispatch(notification(1, "retail", 123))
+
+	_, open := <-subscription.Messages()
+	assert.False(t, open)
+}
@@ -0,0 +1,250 @@
+// Package events publishes the domain events recorded in the outbox. A relay
+// polls the outbox and hands the events to a Sink, marking them published
+// once the sink accepted them. No transaction is held while publishing.
+// Delivery is at least once: an event published right before the process
+// dies is published again, so consumers must deduplicate by id. Events about
+// an account are published in the order they were committed, an event
+// failing to publish is retried with backoff, holding back the later events
+// of its account, until it is parked after MaxAttempts attempts.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/ashwin-m/transactions/daos/outbox"
+	eventsmodel "github.com/ashwin-m/transactions/models/events"
+	"github.com/ashwin-m/transactions/utils/jobs"
+	"github.com/ashwin-m/transactions/utils/metrics"
+)
+
+const (
+	DefaultInterval    = time.Second
+	DefaultBatchSize   = 100
+	DefaultRetryBase   = time.Second
+	DefaultMaxAttempts = 20
+
+	// maxBackoff caps the wait before retrying an event.
+	maxBackoff = 10 * time.Minute
+
+	// maxFailures is the number of failed publishes after which the rest of
+	// a batch waits for the next poll, so an unavailable sink isn't tried
+	// once for every event.
+	maxFailures = 5
+)
+
+// Message is the form events are published in.
+type Message struct {
+	// Id increases with every event about an account and identifies it
+	// across redeliveries.
+	Id         int64           `json:"id"`
+	Type       string          `json:"type"`
+	TenantId   string          `json:"tenant_id"`
+	AccountId  int64           `json:"account_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+func NewMessage(event eventsmodel.Events) Message {
+	return Message{
+		Id:         event.GetId(),
+		Type:       event.GetType(),
+		TenantId:   event.GetTenantId(),
+		AccountId:  event.GetAccountId(),
+		OccurredAt: event.GetCreatedAt(),
+		Data:       event.GetPayload(),
+	}
+}
+
+// Sink is where the relay publishes events. Publish returns once the message
+// is durably accepted, an error has it retried later.
+type Sink interface {
+	Publish(ctx context.Context, message Message) error
+}
+
+type RelayOptions struct {
+	// Interval is how long the relay waits between polls of the outbox,
+	// DefaultInterval when zero.
+	Interval time.Duration
+	// BatchSize is the number of events read per poll, DefaultBatchSize when
+	// zero.
+	BatchSize int
+	// RetryBase is the wait before retrying an event after its first failed
+	// attempt, doubling with each one after it. DefaultRetryBase when zero.
+	RetryBase time.Duration
+	// MaxAttempts is the number of failed attempts after which an event is
+	// parked, DefaultMaxAttempts when zero.
+	MaxAttempts int
+}
+
+type Relay struct {
+	outboxDao outbox.Dao
+	sink      Sink
+	job       *jobs.Job
+	options   RelayOptions
+}
+
+// NewRelay builds a relay reporting its progress to job, which may be nil.
+func NewRelay(outboxDao outbox.Dao, sink Sink, job *jobs.Job, options RelayOptions) *Relay {
+	if options.Interval <= 0 {
+		options.Interval = DefaultInterval
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultBatchSize
+	}
+	if options.RetryBase <= 0 {
+		options.RetryBase = DefaultRetryBase
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = DefaultMaxAttempts
+	}
+
+	return &Relay{
+		outboxDao: outboxDao,
+		sink:      sink,
+		job:       job,
+		options:   options,
+	}
+}
+
+// Run relays events until ctx is done. A full batch is followed by the next
+// one right away, so a backlog drains without waiting for the interval.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		published, err := r.runOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil && published == r.options.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) runOnce(ctx context.Context) (int, error) {
+	if r.job != nil {
+		r.job.Started()
+	}
+
+	published, err := r.RelayOnce(ctx)
+
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to relay events", slog.Any("error", err))
+		if r.job != nil {
+			r.job.Failed(err)
+		}
+		return published, err
+	}
+	if r.job != nil {
+		r.job.Succeeded()
+	}
+
+	return published, nil
+}
+
+// RelayOnce publishes one batch of events and returns how many were
+// published. It returns nothing when another relay holds the outbox.
+//
+// When an event can't be published its attempt is recorded and the later
+// events about the same account are held back, keeping their order, until
+// it is retried. An event failing for the MaxAttempts time is parked
+// instead, so it no longer holds them back. The events that were published
+// are still marked, and the first error of the sink is returned.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	unlock, locked, err := r.outboxDao.Lock(ctx)
+	if err != nil || !locked {
+		return 0, err
+	}
+	defer unlock()
+
+	pending, err := r.outboxDao.Unpublished(ctx, r.options.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	type account struct {
+		tenantId string
+		id       int64
+	}
+	heldBack := map[account]bool{}
+	var published []int64
+	var publishErr error
+	failures := 0
+	for _, event := range pending {
+		key := account{event.GetTenantId(), event.GetAccountId()}
+		if heldBack[key] {
+			continue
+		}
+
+		err = r.sink.Publish(ctx, NewMessage(event))
+		if err != nil {
+			metrics.EventPublished(event.GetType(), metrics.OutcomeFailure)
+			heldBack[key] = true
+			if publishErr == nil {
+				publishErr = err
+			}
+
+			err = r.failed(ctx, event, err)
+			if err != nil {
+				return 0, err
+			}
+
+			failures++
+			if failures == maxFailures {
+				break
+			}
+			continue
+		}
+		metrics.EventPublished(event.GetType(), metrics.OutcomeSuccess)
+		published = append(published, event.GetId())
+	}
+
+	if len(published) > 0 {
+		// published events are marked even when ctx ended meanwhile
+		err = r.outboxDao.MarkPublished(context.WithoutCancel(ctx), published)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(published), publishErr
+}
+
+// failed records a failed attempt at publishing event, parking it once it
+// has failed MaxAttempts times.
+func (r *Relay) failed(ctx context.Context, event eventsmodel.Events, publishErr error) error {
+	ctx = context.WithoutCancel(ctx)
+	attempts := event.GetAttempts() + 1
+	if attempts >= r.options.MaxAttempts {
+		metrics.EventParked(event.GetType())
+		slog.WarnContext(ctx, "parking an event that can't be published", slog.Int64("event_id", event.GetId()), slog.Int("attempts", attempts), slog.Any("error", publishErr))
+		return r.outboxDao.Park(ctx, event.GetId(), publishErr.Error())
+	}
+
+	return r.outboxDao.Failed(ctx, event.GetId(), publishErr.Error(), time.Now().Add(Backoff(r.options.RetryBase, attempts)))
+}
+
+// Backoff is the wait before retrying an event that failed attempts times:
+// base, doubling with each attempt, up to ten minutes.
+func Backoff(base time.Duration, attempts int) time.Duration {
+	backoff := base
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+
+	return min(backoff, maxBackoff)
+}
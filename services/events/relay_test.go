@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	outboxdaomocks "github.com/ashwin-m/transactions/daos/outbox/mocks"
+	eventsmodel "github.com/ashwin-m/transactions/models/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// sinkFunc publishes with a function, recording what it was handed.
+type sinkFunc func(message Message) error
+
+func (f sinkFunc) Publish(ctx context.Context, message Message) error {
+	return f(message)
+}
+
+func event(id int64, tenantId string, accountId int64) eventsmodel.Events {
+	e := eventsmodel.Events{}
+	e.SetId(id)
+	e.SetTenantId(tenantId)
+	e.SetType(eventsmodel.TypeAccountBalanceChanged)
+	e.SetAccountId(accountId)
+	e.SetPayload(json.RawMessage(`{}`))
+	return e
+}
+
+// locked expects the relay lock to be taken and released.
+func locked(t *testing.T, mockOutboxDao *outboxdaomocks.Dao) {
+	unlocked := false
+	mockOutboxDao.EXPECT().Lock(mock.Anything).Return(func() { unlocked = true }, true, nil)
+	t.Cleanup(func() { assert.True(t, unlocked, "the relay lock should be released") })
+}
+
+func TestRelayOnce(t *testing.T) {
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	locked(t, mockOutboxDao)
+	mockOutboxDao.EXPECT().Unpublished(mock.Anything, 10).Return([]eventsmodel.Events{
+		event(1, "retail", 123),
+		event(2, "retail", 456),
+		event(3, "retail", 123),
+	}, nil)
+	mockOutboxDao.EXPECT().MarkPublished(mock.Anything, []int64{1, 2, 3}).Return(nil)
+
+	var published []int64
+	sink := sinkFunc(func(message Message) error {
+		published = append(published, message.Id)
+		return nil
+	})
+
+	count, err := NewRelay(mockOutboxDao, sink, nil, RelayOptions{BatchSize: 10}).RelayOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, []int64{1, 2, 3}, published)
+}
+
+func TestRelayOnce_HoldsBackLaterEventsOfTheAccount(t *testing.T) {
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	locked(t, mockOutboxDao)
+	mockOutboxDao.EXPECT().Unpublished(mock.Anything, DefaultBatchSize).Return([]eventsmodel.Events{
+		event(1, "retail", 123),
+		event(2, "retail", 456),
+		event(3, "retail", 123),
+		// the same account id in another tenant is another account
+		event(4, "corporate", 123),
+	}, nil)
+	mockOutboxDao.EXPECT().Failed(mock.Anything, int64(1), "connection refused", mock.Anything).RunAndReturn(func(ctx context.Context, id int64, message string, retryAt time.Time) error {
+		assert.WithinDuration(t, time.Now().Add(DefaultRetryBase), retryAt, time.Second)
+		return nil
+	})
+	mockOutboxDao.EXPECT().MarkPublished(mock.Anything, []int64{2, 4}).Return(nil)
+
+	var published []int64
+	sink := sinkFunc(func(message Message) error {
+		if message.Id == 1 {
+			return errors.New("connection refused")
+		}
+		published = append(published, message.Id)
+		return nil
+	})
+
+	count, err := NewRelay(mockOutboxDao, sink, nil, RelayOptions{}).RelayOnce(context.Background())
+
+	assert.EqualError(t, err, "connection refused")
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []int64{2, 4}, published)
+}
+
+func TestRelayOnce_ParksEventsFailingTooOften(t *testing.T) {
+	poison := event(1, "retail", 123)
+	poison.SetAttempts(2)
+
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	locked(t, mockOutboxDao)
+	mockOutboxDao.EXPECT().Unpublished(mock.Anything, DefaultBatchSize).Return([]eventsmodel.Events{poison, event(2, "retail", 456)}, nil)
+	mockOutboxDao.EXPECT().Park(mock.Anything, int64(1), "invalid payload").Return(nil)
+	mockOutboxDao.EXPECT().MarkPublished(mock.Anything, []int64{2}).Return(nil)
+
+	sink := sinkFunc(func(message Message) error {
+		if message.Id == 1 {
+			return errors.New("invalid payload")
+		}
+		return nil
+	})
+
+	count, err := NewRelay(mockOutboxDao, sink, nil, RelayOptions{MaxAttempts: 3}).RelayOnce(context.Background())
+
+	assert.EqualError(t, err, "invalid payload")
+	assert.Equal(t, 1, count)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, Backoff(time.Second, 1))
+	assert.Equal(t, 8*time.Second, Backoff(time.Second, 4))
+	assert.Equal(t, maxBackoff, Backoff(time.Second, 30))
+}
+
+func TestRelayOnce_AnotherRelayHoldsTheLock(t *testing.T) {
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockOutboxDao.EXPECT().Lock(mock.Anything).Return(nil, false, nil)
+
+	sink := sinkFunc(func(message Message) error {
+		t.Fatal("nothing should be published")
+		return nil
+	})
+
+	count, err := NewRelay(mockOutboxDao, sink, nil, RelayOptions{}).RelayOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
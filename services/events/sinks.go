@@ -0,0 +1,130 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// writerSink writes every message as a line of JSON.
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink publishes to w, os.Stdout for instance, one JSON message per
+// line.
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{w: w}
+}
+
+func (s *writerSink) Publish(ctx context.Context, message Message) error {
+	line, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// FileSink appends messages to a file, one JSON message per line. Every
+// message is synced to disk before it counts as published.
+type FileSink struct {
+	writerSink
+	file *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSink{writerSink: writerSink{w: file}, file: file}, nil
+}
+
+func (s *FileSink) Publish(ctx context.Context, message Message) error {
+	err := s.writerSink.Publish(ctx, message)
+	if err != nil {
+		return err
+	}
+
+	return s.file.Sync()
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// httpSink POSTs every message to a URL.
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPSink publishes each message as the JSON body of a POST to url. Any
+// 2xx response accepts it. The message id is sent as the Idempotency-Key
+// header, so the receiver can drop redeliveries.
+func NewHTTPSink(url string, client *http.Client) Sink {
+	return &httpSink{url: url, client: client}
+}
+
+func (s *httpSink) Publish(ctx context.Context, message Message) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", strconv.FormatInt(message.Id, 10))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("event sink responded %s", resp.Status)
+	}
+
+	return nil
+}
+
+// fanoutSink publishes every message to several sinks.
+type fanoutSink []Sink
+
+// Fanout publishes each message to every one of sinks, in order. A message
+// that fails in one sink is published to the others anyway and then retried
+// in all of them, so each must tolerate redeliveries.
+func Fanout(sinks ...Sink) Sink {
+	return fanoutSink(sinks)
+}
+
+func (s fanoutSink) Publish(ctx context.Context, message Message) error {
+	var errs []error
+	for _, sink := range s {
+		err := sink.Publish(ctx, message)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
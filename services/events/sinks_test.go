@@ -0,0 +1,92 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var message = Message{
+	Id:         7,
+	Type:       "transfer.posted",
+	TenantId:   "retail",
+	AccountId:  123,
+	OccurredAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	Data:       json.RawMessage(`{"transfer_id":9}`),
+}
+
+const messageJSON = `{"id":7,"type":"transfer.posted","tenant_id":"retail","account_id":123,"occurred_at":"2026-01-02T03:04:05Z","data":{"transfer_id":9}}`
+
+func TestWriterSink(t *testing.T) {
+	var out bytes.Buffer
+
+	err := NewWriterSink(&out).Publish(context.Background(), message)
+
+	assert.NoError(t, err)
+	assert.Equal(t, messageJSON+"\n", out.String())
+}
+
+func TestFileSink_Appends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	assert.NoError(t, os.WriteFile(path, []byte("earlier\n"), 0o644))
+
+	sink, err := NewFileSink(path)
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Publish(context.Background(), message))
+	assert.NoError(t, sink.Close())
+
+	content, _ := os.ReadFile(path)
+	assert.Equal(t, "earlier\n"+messageJSON+"\n", string(content))
+}
+
+func TestHTTPSink(t *testing.T) {
+	var body []byte
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	err := NewHTTPSink(server.URL, server.Client()).Publish(context.Background(), message)
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, messageJSON, string(body))
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.Equal(t, "7", header.Get("Idempotency-Key"))
+}
+
+func TestHTTPSink_RejectedMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := NewHTTPSink(server.URL, server.Client()).Publish(context.Background(), message)
+
+	assert.EqualError(t, err, "event sink responded 503 Service Unavailable")
+}
+
+func TestFanout_PublishesToEverySink(t *testing.T) {
+	var first, second bytes.Buffer
+	failing := sinkFunc(func(message Message) error {
+		return errors.New("unavailable")
+	})
+
+	err := Fanout(NewWriterSink(&first), failing, NewWriterSink(&second)).Publish(context.Background(), message)
+
+	assert.EqualError(t, err, "unavailable")
+	assert.Equal(t, messageJSON+"\n", first.String())
+	assert.Equal(t, messageJSON+"\n", second.String())
+}
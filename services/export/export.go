@@ -0,0 +1,363 @@
+// Package export copies the ledger to files for the data warehouse. Every
+// run writes the accounts written and the transfers made since the previous
+// run into a directory of its own, with a manifest listing each file, its
+// row count and its SHA-256. Runs are incremental: the database records, per
+// tenant, the transaction up to which rows were exported, and the next run
+// starts from there. A run only becomes visible once it is complete, so a
+// failed run is simply retried.
+package export
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	exportdao "github.com/ashwin-m/transactions/daos/export"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/metrics"
+	"github.com/ashwin-m/transactions/utils/pgxiface"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// ManifestName is the name of the manifest in the directory of a run.
+	ManifestName = "manifest.json"
+
+	// runIdLayout names runs so they sort in the order they were made.
+	runIdLayout = "20060102T150405.000000Z"
+)
+
+var ErrInProgress = apperrors.New(apperrors.CodeExportInProgress, "another export is running, retry once it finishes")
+
+type Options struct {
+	// Dir holds a directory per run.
+	Dir string
+	// Format is one of Formats, FormatCSV when empty.
+	Format string
+	// Tenants are exported, tenant.Default when empty. A tenant added later
+	// is exported from the start of its history.
+	Tenants []string
+}
+
+// Window is the range of transactions, To excluded, rows of a tenant were
+// last written by to be part of a run. A run ends its windows at the oldest
+// transaction still running when it began, so a transfer still committing
+// is left to the next run rather than skipped.
+type Window struct {
+	FromXid uint64 `json:"from_xid"`
+	ToXid   uint64 `json:"to_xid"`
+}
+
+// File is a table written by a run.
+type File struct {
+	Table  string `json:"table"`
+	Name   string `json:"name"`
+	Rows   int64  `json:"rows"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+type Manifest struct {
+	Id         string            `json:"id"`
+	Format     string            `json:"format"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Windows    map[string]Window `json:"windows"`
+	Files      []File            `json:"files"`
+}
+
+// Service runs exports and lists them, for the admin endpoint.
+type Service interface {
+	Export(ctx context.Context) (Manifest, error)
+	Runs() ([]Manifest, error)
+}
+
+type Exporter struct {
+	dbPool  pgxiface.PgxIface
+	dao     exportdao.Dao
+	options Options
+	now     func() time.Time
+}
+
+func NewExporter(dbPool pgxiface.PgxIface, dao exportdao.Dao, options Options) *Exporter {
+	if options.Format == "" {
+		options.Format = FormatCSV
+	}
+	if len(options.Tenants) == 0 {
+		options.Tenants = []string{tenant.Default}
+	}
+
+	return &Exporter{
+		dbPool:  dbPool,
+		dao:     dao,
+		options: options,
+		now:     time.Now,
+	}
+}
+
+// Export makes a run, reading everything from one snapshot. It returns
+// ErrInProgress when another export is running.
+func (e *Exporter) Export(ctx context.Context) (Manifest, error) {
+	startedAt := e.now().UTC()
+
+	// every tenant is visible to the snapshot, queries filter by the one
+	// they are about
+	ctx = tenant.NewContext(ctx, e.options.Tenants[0], e.options.Tenants[1:]...)
+	txn, err := e.dbPool.Begin(ctx)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer txn.Rollback(ctx)
+
+	err = e.dao.Snapshot(ctx, txn)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	locked, err := e.dao.Lock(ctx, txn)
+	if err != nil {
+		return Manifest{}, err
+	}
+	if !locked {
+		return Manifest{}, ErrInProgress
+	}
+
+	// no other export is writing a run, so partial ones were left by a
+	// crash
+	err = removePartial(e.options.Dir)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	watermarks, err := e.dao.Watermarks(ctx, txn)
+	if err != nil {
+		return Manifest{}, err
+	}
+	horizon, err := e.dao.Horizon(ctx, txn)
+	if err != nil {
+		return Manifest{}, err
+	}
+	now, err := e.dao.Now(ctx, txn)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	manifest := Manifest{
+		Id:        now.UTC().Format(runIdLayout),
+		Format:    e.options.Format,
+		StartedAt: startedAt,
+		Windows:   map[string]Window{},
+		Files:     []File{},
+	}
+	for _, tenantId := range e.options.Tenants {
+		manifest.Windows[tenantId] = Window{FromXid: watermarks[tenantId], ToXid: horizon}
+	}
+
+	// the run is written under a hidden name and renamed once complete
+	partial := filepath.Join(e.options.Dir, "."+manifest.Id)
+	err = os.MkdirAll(partial, 0o755)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	for _, t := range tables {
+		file, err := e.writeTable(ctx, txn, partial, t, manifest.Windows)
+		if err != nil {
+			os.RemoveAll(partial)
+			return Manifest{}, err
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	manifest.FinishedAt = e.now().UTC()
+	err = writeManifest(partial, manifest)
+	if err == nil {
+		err = os.Rename(partial, filepath.Join(e.options.Dir, manifest.Id))
+	}
+	if err != nil {
+		os.RemoveAll(partial)
+		return Manifest{}, err
+	}
+
+	// the watermarks only move once the run is complete, a run whose
+	// watermarks can't be kept is removed so it's made again
+	for _, tenantId := range e.options.Tenants {
+		err = e.dao.SetWatermark(ctx, txn, tenantId, horizon, manifest.Id)
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = txn.Commit(ctx)
+	}
+	if err != nil {
+		os.RemoveAll(filepath.Join(e.options.Dir, manifest.Id))
+		return Manifest{}, err
+	}
+
+	for _, file := range manifest.Files {
+		metrics.Exported(file.Table, file.Rows)
+	}
+
+	return manifest, nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (e *Exporter) writeTable(ctx context.Context, txn pgx.Tx, dir string, t table, windows map[string]Window) (File, error) {
+	file := File{Table: t.name, Name: t.name + "." + e.options.Format}
+
+	f, err := os.Create(filepath.Join(dir, file.Name))
+	if err != nil {
+		return File{}, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(f, hash)}
+	buffered := bufio.NewWriter(counter)
+
+	writer, err := newTableWriter(e.options.Format, buffered, t.columns)
+	if err != nil {
+		return File{}, err
+	}
+
+	for _, tenantId := range e.options.Tenants {
+		window := windows[tenantId]
+		err = t.rows(tenant.NewContext(ctx, tenantId), e.dao, txn, window.FromXid, window.ToXid, func(row []any) error {
+			file.Rows++
+			return writer.Write(row)
+		})
+		if err != nil {
+			return File{}, err
+		}
+	}
+
+	err = writer.Close()
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		return File{}, err
+	}
+
+	file.Bytes = counter.n
+	file.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	return file, f.Close()
+}
+
+// removePartial removes the runs in dir that were never completed.
+func removePartial(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name, hidden := strings.CutPrefix(entry.Name(), ".")
+		if !entry.IsDir() || !hidden {
+			continue
+		}
+		_, err = time.Parse(runIdLayout, name)
+		if err != nil {
+			// not a run
+			continue
+		}
+
+		err = os.RemoveAll(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeManifest(dir string, manifest Manifest) error {
+	f, err := os.Create(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "    ")
+	err = encoder.Encode(manifest)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	return errors.Join(err, f.Close())
+}
+
+// Runs returns the manifests of the runs made so far, newest first.
+func (e *Exporter) Runs() ([]Manifest, error) {
+	return Runs(e.options.Dir)
+}
+
+// Runs returns the manifests of the complete runs in dir, newest first. A
+// directory that doesn't exist holds no runs.
+func Runs(dir string) ([]Manifest, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []Manifest{}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name(), ManifestName))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var manifest Manifest
+		err = json.Unmarshal(content, &manifest)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, manifest)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].Id > runs[j].Id
+	})
+
+	return runs, nil
+}
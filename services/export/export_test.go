@@ -0,0 +1,261 @@
+package export
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	exportdao "github.com/ashwin-m/transactions/daos/export"
+	"github.com/ashwin-m/transactions/daos/export/mocks"
+	"github.com/ashwin-m/transactions/utils/parquet"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const horizon = 7_500
+
+var snapshotAt = time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)
+
+func newExporter(t *testing.T, options Options) (*Exporter, *mocks.Dao, pgxmock.PgxPoolIface) {
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+
+	mockDao := mocks.NewDao(t)
+	mockDao.EXPECT().Snapshot(mock.Anything, mock.Anything).Return(nil)
+
+	e := NewExporter(mockDB, mockDao, options)
+	e.now = func() time.Time { return snapshotAt }
+
+	return e, mockDao, mockDB
+}
+
+func forTenant(tenantId string) any {
+	return mock.MatchedBy(func(ctx context.Context) bool {
+		active, _ := tenant.FromContext(ctx)
+		return active == tenantId
+	})
+}
+
+// expectRun expects a run to start with watermarks.
+func expectRun(mockDao *mocks.Dao, watermarks map[string]uint64) {
+	mockDao.EXPECT().Lock(mock.Anything, mock.Anything).Return(true, nil)
+	mockDao.EXPECT().Watermarks(mock.Anything, mock.Anything).Return(watermarks, nil)
+	mockDao.EXPECT().Horizon(mock.Anything, mock.Anything).Return(horizon, nil)
+	mockDao.EXPECT().Now(mock.Anything, mock.Anything).Return(snapshotAt, nil)
+}
+
+func expectAccounts(mockDao *mocks.Dao, tenantId string, from uint64, accounts ...exportdao.Account) {
+	mockDao.EXPECT().Accounts(forTenant(tenantId), mock.Anything, from, uint64(horizon), mock.Anything).
+		RunAndReturn(func(ctx context.Context, tx pgx.Tx, from, to uint64, fn func(exportdao.Account) error) error {
+			for _, account := range accounts {
+				err := fn(account)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+}
+
+func expectTransactions(mockDao *mocks.Dao, tenantId string, from uint64, transactions ...exportdao.Transaction) {
+	mockDao.EXPECT().Transactions(forTenant(tenantId), mock.Anything, from, uint64(horizon), mock.Anything).
+		RunAndReturn(func(ctx context.Context, tx pgx.Tx, from, to uint64, fn func(exportdao.Transaction) error) error {
+			for _, transaction := range transactions {
+				err := fn(transaction)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+}
+
+func checksum(t *testing.T, path string) string {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestExport_FirstRun(t *testing.T) {
+	dir := t.TempDir()
+	e, mockDao, mockDB := newExporter(t, Options{Dir: dir, Tenants: []string{"acme", "globex"}})
+	expectRun(mockDao, map[string]uint64{})
+	expectAccounts(mockDao, "acme", 0, exportdao.Account{TenantId: "acme", Id: 1, OwnerId: "client-1", Balance: 89.5, InitialBalance: 100, Version: 2, UpdatedAt: snapshotAt.Add(-time.Hour)})
+	expectAccounts(mockDao, "globex", 0, exportdao.Account{TenantId: "globex", Id: 1, Balance: 10.5, Version: 2, UpdatedAt: snapshotAt.Add(-time.Hour)})
+	expectTransactions(mockDao, "acme", 0, exportdao.Transaction{TenantId: "acme", Id: 7, SourceAccountId: 1, DestinationTenantId: "globex", DestinationAccountId: 1, Amount: 10.5, ClientId: "client-1", CreatedAt: snapshotAt.Add(-time.Hour)})
+	expectTransactions(mockDao, "globex", 0)
+	mockDao.EXPECT().SetWatermark(mock.Anything, mock.Anything, "acme", uint64(horizon), "20261019T093000.000000Z").Return(nil)
+	mockDao.EXPECT().SetWatermark(mock.Anything, mock.Anything, "globex", uint64(horizon), "20261019T093000.000000Z").Return(nil)
+	mockDB.ExpectCommit()
+
+	manifest, err := e.Export(context.Background())
+
+	require.NoError(t, err)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+	assert.Equal(t, "20261019T093000.000000Z", manifest.Id)
+	assert.Equal(t, map[string]Window{"acme": {ToXid: horizon}, "globex": {ToXid: horizon}}, manifest.Windows)
+
+	run := filepath.Join(dir, manifest.Id)
+	accounts, err := os.ReadFile(filepath.Join(run, "accounts.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, "tenant_id,account_id,owner_id,balance,initial_balance,version,updated_at\n"+
+		"acme,1,client-1,89.5,100,2,2026-10-19T08:30:00Z\n"+
+		"globex,1,,10.5,0,2,2026-10-19T08:30:00Z\n", string(accounts))
+
+	require.Len(t, manifest.Files, 2)
+	assert.Equal(t, File{
+		Table:  "accounts",
+		Name:   "accounts.csv",
+		Rows:   2,
+		Bytes:  int64(len(accounts)),
+		SHA256: checksum(t, filepath.Join(run, "accounts.csv")),
+	}, manifest.Files[0])
+	assert.Equal(t, int64(1), manifest.Files[1].Rows)
+	assert.Equal(t, checksum(t, filepath.Join(run, "transactions.csv")), manifest.Files[1].SHA256)
+
+	runs, err := Runs(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []Manifest{manifest}, runs)
+}
+
+func TestExport_ContinuesFromWatermark(t *testing.T) {
+	dir := t.TempDir()
+	previous := snapshotAt.Add(-24 * time.Hour)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "20261018T093000.000000Z"), 0o755))
+	require.NoError(t, writeManifest(filepath.Join(dir, "20261018T093000.000000Z"), Manifest{
+		Id:      "20261018T093000.000000Z",
+		Windows: map[string]Window{tenant.Default: {ToXid: 7_000}},
+	}))
+
+	e, mockDao, mockDB := newExporter(t, Options{Dir: dir, Format: FormatNDJSON})
+	expectRun(mockDao, map[string]uint64{tenant.Default: 7_000})
+	expectAccounts(mockDao, tenant.Default, 7_000)
+	expectTransactions(mockDao, tenant.Default, 7_000, exportdao.Transaction{TenantId: tenant.Default, Id: 9, SourceAccountId: 1, DestinationTenantId: tenant.Default, DestinationAccountId: 2, Amount: 1, CreatedAt: previous})
+	mockDao.EXPECT().SetWatermark(mock.Anything, mock.Anything, tenant.Default, uint64(horizon), mock.Anything).Return(nil)
+	mockDB.ExpectCommit()
+
+	manifest, err := e.Export(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, Window{FromXid: 7_000, ToXid: horizon}, manifest.Windows[tenant.Default])
+
+	transactions, err := os.ReadFile(filepath.Join(dir, manifest.Id, "transactions.ndjson"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"tenant_id":"default","transaction_id":9,"source_account_id":1,"destination_tenant_id":"default","destination_account_id":2,"amount":1,"client_id":null,"created_at":"2026-10-18T09:30:00Z"}`+"\n", string(transactions))
+
+	runs, err := Runs(dir)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, manifest.Id, runs[0].Id)
+}
+
+func TestExport_InProgress(t *testing.T) {
+	dir := t.TempDir()
+	e, mockDao, mockDB := newExporter(t, Options{Dir: dir})
+	mockDB.ExpectRollback()
+	mockDao.EXPECT().Lock(mock.Anything, mock.Anything).Return(false, nil)
+
+	_, err := e.Export(context.Background())
+
+	assert.ErrorIs(t, err, ErrInProgress)
+	entries, _ := os.ReadDir(dir)
+	assert.Empty(t, entries)
+}
+
+func TestExport_FailedRunLeavesNothing(t *testing.T) {
+	dir := t.TempDir()
+	e, mockDao, mockDB := newExporter(t, Options{Dir: dir, Format: FormatParquet})
+	mockDB.ExpectRollback()
+	expectRun(mockDao, map[string]uint64{})
+	expectAccounts(mockDao, tenant.Default, 0)
+	mockDao.EXPECT().Transactions(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("test"))
+
+	_, err := e.Export(context.Background())
+
+	assert.EqualError(t, err, "test")
+	entries, _ := os.ReadDir(dir)
+	assert.Empty(t, entries)
+}
+
+func TestExport_WatermarkNotKept(t *testing.T) {
+	dir := t.TempDir()
+	e, mockDao, mockDB := newExporter(t, Options{Dir: dir})
+	mockDB.ExpectCommit().WillReturnError(errors.New("test"))
+	expectRun(mockDao, map[string]uint64{})
+	expectAccounts(mockDao, tenant.Default, 0)
+	expectTransactions(mockDao, tenant.Default, 0)
+	mockDao.EXPECT().SetWatermark(mock.Anything, mock.Anything, tenant.Default, uint64(horizon), mock.Anything).Return(nil)
+
+	_, err := e.Export(context.Background())
+
+	assert.EqualError(t, err, "test")
+	entries, _ := os.ReadDir(dir)
+	assert.Empty(t, entries)
+}
+
+func TestExport_RemovesPartialRuns(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".20261018T093000.000000Z"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".20261018T093000.000000Z", "accounts.csv"), []byte("tenant_id\n"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".snapshots"), 0o755))
+
+	e, mockDao, mockDB := newExporter(t, Options{Dir: dir})
+	mockDB.ExpectCommit()
+	expectRun(mockDao, map[string]uint64{})
+	expectAccounts(mockDao, tenant.Default, 0)
+	expectTransactions(mockDao, tenant.Default, 0)
+	mockDao.EXPECT().SetWatermark(mock.Anything, mock.Anything, tenant.Default, uint64(horizon), mock.Anything).Return(nil)
+
+	manifest, err := e.Export(context.Background())
+
+	require.NoError(t, err)
+	entries, _ := os.ReadDir(dir)
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{".snapshots", manifest.Id}, names)
+}
+
+func TestRuns_SkipsIncompleteRuns(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".20261019T093000.000000Z"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "20261019T093000.000000Z"), 0o755))
+
+	runs, err := Runs(dir)
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+
+	runs, err = Runs(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+}
+
+func TestTableWriter_Parquet(t *testing.T) {
+	var b strings.Builder
+	writer, err := newTableWriter(FormatParquet, &b, tables[1].columns)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write([]any{"default", int64(9), int64(1), "default", int64(2), 1.0, nil, snapshotAt}))
+	require.NoError(t, writer.Close())
+
+	assert.True(t, strings.HasPrefix(b.String(), "PAR1"))
+	assert.True(t, strings.HasSuffix(b.String(), "PAR1"))
+	assert.Contains(t, b.String(), "destination_account_id")
+}
+
+func TestTableWriter_UnknownFormat(t *testing.T) {
+	_, err := newTableWriter("xml", &strings.Builder{}, []parquet.Column{})
+
+	assert.EqualError(t, err, `unknown export format "xml"`)
+}
@@ -0,0 +1,168 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	exportdao "github.com/ashwin-m/transactions/daos/export"
+	"github.com/ashwin-m/transactions/utils/parquet"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+var Formats = []string{FormatCSV, FormatNDJSON, FormatParquet}
+
+// table is exported to a file per run. Its columns are described for every
+// format in terms of Parquet.
+type table struct {
+	name    string
+	columns []parquet.Column
+	// rows calls fn with the rows of the tenant on ctx last written by the
+	// transactions of a window, a value per column.
+	rows func(ctx context.Context, dao exportdao.Dao, txn pgx.Tx, from, to uint64, fn func([]any) error) error
+}
+
+// An account is exported again every time it is written, the latest row of
+// an account holds its current state. Transfers are exported under the
+// tenant they were made from.
+var tables = []table{
+	{
+		name: "accounts",
+		columns: []parquet.Column{
+			{Name: "tenant_id", Type: parquet.String},
+			{Name: "account_id", Type: parquet.Int64},
+			{Name: "owner_id", Type: parquet.String, Optional: true},
+			{Name: "balance", Type: parquet.Double},
+			{Name: "initial_balance", Type: parquet.Double},
+			{Name: "version", Type: parquet.Int64},
+			{Name: "updated_at", Type: parquet.Timestamp},
+		},
+		rows: func(ctx context.Context, dao exportdao.Dao, txn pgx.Tx, from, to uint64, fn func([]any) error) error {
+			return dao.Accounts(ctx, txn, from, to, func(a exportdao.Account) error {
+				return fn([]any{a.TenantId, a.Id, optional(a.OwnerId), a.Balance, a.InitialBalance, a.Version, a.UpdatedAt.UTC()})
+			})
+		},
+	},
+	{
+		name: "transactions",
+		columns: []parquet.Column{
+			{Name: "tenant_id", Type: parquet.String},
+			{Name: "transaction_id", Type: parquet.Int64},
+			{Name: "source_account_id", Type: parquet.Int64},
+			{Name: "destination_tenant_id", Type: parquet.String},
+			{Name: "destination_account_id", Type: parquet.Int64},
+			{Name: "amount", Type: parquet.Double},
+			{Name: "client_id", Type: parquet.String, Optional: true},
+			{Name: "created_at", Type: parquet.Timestamp},
+		},
+		rows: func(ctx context.Context, dao exportdao.Dao, txn pgx.Tx, from, to uint64, fn func([]any) error) error {
+			return dao.Transactions(ctx, txn, from, to, func(t exportdao.Transaction) error {
+				return fn([]any{t.TenantId, t.Id, t.SourceAccountId, t.DestinationTenantId, t.DestinationAccountId, t.Amount, optional(t.ClientId), t.CreatedAt.UTC()})
+			})
+		},
+	},
+}
+
+// optional exports empty strings as nulls.
+func optional(s string) any {
+	if s == "" {
+		return nil
+	}
+
+	return s
+}
+
+type tableWriter interface {
+	Write(row []any) error
+	// Close finishes the file, without closing the underlying writer.
+	Close() error
+}
+
+func newTableWriter(format string, w io.Writer, columns []parquet.Column) (tableWriter, error) {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		return &csvWriter{writer: writer}, writer.Write(names)
+	case FormatNDJSON:
+		return &ndjsonWriter{w: w, names: names}, nil
+	case FormatParquet:
+		return parquet.NewWriter(w, columns), nil
+	}
+
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// csvWriter writes a header row, then a row per row. Nulls are empty.
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (c *csvWriter) Write(row []any) error {
+	record := make([]string, len(row))
+	for i, value := range row {
+		switch v := value.(type) {
+		case string:
+			record[i] = v
+		case int64:
+			record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case time.Time:
+			record[i] = v.Format(time.RFC3339Nano)
+		}
+	}
+
+	return c.writer.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// ndjsonWriter writes a JSON object per row, on a line of its own, its
+// fields in column order.
+type ndjsonWriter struct {
+	w     io.Writer
+	names []string
+}
+
+func (n *ndjsonWriter) Write(row []any) error {
+	line := []byte{'{'}
+	for i, value := range row {
+		if i > 0 {
+			line = append(line, ',')
+		}
+		line = strconv.AppendQuote(line, n.names[i])
+		line = append(line, ':')
+
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		line = append(line, encoded...)
+	}
+	line = append(line, '}', '\n')
+
+	_, err := n.w.Write(line)
+	return err
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
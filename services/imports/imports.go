@@ -0,0 +1,236 @@
+// Package imports creates accounts in bulk from CSV or NDJSON, for
+// migrations from other ledgers. Rows are validated one by one, an invalid
+// row is reported with its line and the others are still imported. Valid
+// rows are written in batches, each in a transaction of its own, and an
+// account that already exists is left as it is. Importing the same file
+// again is therefore harmless, and resumes an import that was interrupted.
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	importsdao "github.com/ashwin-m/transactions/daos/imports"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	"github.com/ashwin-m/transactions/utils/decimal"
+	"github.com/ashwin-m/transactions/utils/metrics"
+	"github.com/ashwin-m/transactions/utils/pgxiface"
+)
+
+const (
+	// DefaultBatchSize is the number of accounts written per transaction
+	// when none is configured.
+	DefaultBatchSize = 5000
+
+	// MaxReportedErrors bounds Report.Errors, Report.Failed counts every
+	// row that failed.
+	MaxReportedErrors = 1000
+
+	outcomeCreated  = "created"
+	outcomeExisting = "existing"
+	outcomeFailed   = "failed"
+)
+
+// RowError is a row that wasn't imported. Line is the line it starts on,
+// counting the CSV header. Field is empty when the row couldn't be read.
+type RowError struct {
+	Line      int    `json:"line"`
+	AccountId int64  `json:"account_id,omitempty"`
+	Field     string `json:"field,omitempty"`
+	Message   string `json:"message"`
+}
+
+// Report counts the rows read by an import by what became of them. Existing
+// rows are accounts that already existed as imported, an account that
+// exists with another initial balance or owner fails instead.
+type Report struct {
+	Rows     int64      `json:"rows"`
+	Created  int64      `json:"created"`
+	Existing int64      `json:"existing"`
+	Failed   int64      `json:"failed"`
+	Errors   []RowError `json:"errors"`
+}
+
+// Service imports accounts into the tenant on the context. Accounts without
+// an owner_id are owned by the caller, if any.
+type Service interface {
+	// Import reads rows in format, one of Formats, until r is exhausted.
+	// The report covers the rows read so far when an error stops it, the
+	// batches written until then stay written.
+	Import(ctx context.Context, format string, r io.Reader) (Report, error)
+}
+
+type service struct {
+	dbPool    pgxiface.PgxIface
+	dao       importsdao.Dao
+	batchSize int
+}
+
+func NewService(dbPool pgxiface.PgxIface, dao importsdao.Dao, batchSize int) Service {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	return &service{
+		dbPool:    dbPool,
+		dao:       dao,
+		batchSize: batchSize,
+	}
+}
+
+// run is the state of one import.
+type run struct {
+	report Report
+	// lines has the line of every account read, to report duplicates and
+	// conflicts.
+	lines map[int64]int
+	batch []importsdao.Account
+}
+
+func (r *run) fail(rowErr RowError) {
+	r.report.Failed++
+	if len(r.report.Errors) < MaxReportedErrors {
+		r.report.Errors = append(r.report.Errors, rowErr)
+	}
+}
+
+func (s *service) Import(ctx context.Context, format string, reader io.Reader) (Report, error) {
+	rows, err := newRowReader(format, reader)
+	if err != nil {
+		return Report{Errors: []RowError{}}, err
+	}
+
+	identity, _ := auth.FromContext(ctx)
+	r := &run{
+		report: Report{Errors: []RowError{}},
+		lines:  map[int64]int{},
+	}
+
+	for {
+		var next row
+		next, err = rows.next()
+		if err != nil {
+			break
+		}
+		r.report.Rows++
+
+		account, rowErr := validate(next, identity.ClientID)
+		if rowErr != nil {
+			r.fail(*rowErr)
+			metrics.Imported(outcomeFailed, 1)
+			continue
+		}
+		if line, seen := r.lines[account.Id]; seen {
+			r.fail(RowError{Line: next.line, AccountId: account.Id, Field: columnAccountId, Message: fmt.Sprintf("is also on line %d", line)})
+			metrics.Imported(outcomeFailed, 1)
+			continue
+		}
+		r.lines[account.Id] = next.line
+
+		r.batch = append(r.batch, account)
+		if len(r.batch) == s.batchSize {
+			err = s.write(ctx, r)
+			if err != nil {
+				break
+			}
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		err = s.write(ctx, r)
+	}
+
+	// conflicts are found a batch at a time
+	sort.SliceStable(r.report.Errors, func(i, j int) bool {
+		return r.report.Errors[i].Line < r.report.Errors[j].Line
+	})
+
+	return r.report, err
+}
+
+// write imports the batch of r in a transaction and empties it.
+func (s *service) write(ctx context.Context, r *run) error {
+	if len(r.batch) == 0 {
+		return nil
+	}
+
+	txn, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.dao.Insert(ctx, txn, r.batch)
+	if err != nil {
+		txn.Rollback(ctx)
+		return err
+	}
+
+	err = txn.Commit(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range result.Conflicting {
+		r.fail(RowError{Line: r.lines[id], AccountId: id, Field: columnAccountId, Message: "already exists with another initial balance or owner"})
+	}
+	created, conflicting := int64(len(result.Created)), int64(len(result.Conflicting))
+	existing := int64(len(r.batch)) - created - conflicting
+	r.report.Created += created
+	r.report.Existing += existing
+	metrics.Imported(outcomeCreated, created)
+	metrics.Imported(outcomeExisting, existing)
+	metrics.Imported(outcomeFailed, conflicting)
+
+	r.batch = r.batch[:0]
+
+	return nil
+}
+
+// validate turns a row into an account, ownerId owns it when the row names
+// no owner.
+func validate(next row, ownerId string) (importsdao.Account, *RowError) {
+	if next.err != "" {
+		return importsdao.Account{}, &RowError{Line: next.line, Message: next.err}
+	}
+
+	fail := func(field, message string) (importsdao.Account, *RowError) {
+		rowErr := &RowError{Line: next.line, Field: field, Message: message}
+		if id, err := strconv.ParseInt(next.accountId, 10, 64); err == nil {
+			rowErr.AccountId = id
+		}
+		return importsdao.Account{}, rowErr
+	}
+
+	if next.accountId == "" {
+		return fail(columnAccountId, "is required")
+	}
+	id, err := strconv.ParseInt(next.accountId, 10, 64)
+	if err != nil {
+		return fail(columnAccountId, "must be an integer")
+	}
+	// account ids are stored as 32 bit integers
+	if id < 1 || id > math.MaxInt32 {
+		return fail(columnAccountId, fmt.Sprintf("must be between 1 and %d", math.MaxInt32))
+	}
+
+	if next.initialBalance == "" {
+		return fail(columnInitialBalance, "is required")
+	}
+	balance, ok := decimal.ParseFloat(next.initialBalance)
+	if !ok {
+		return fail(columnInitialBalance, "must be a decimal number")
+	}
+	if balance < 0 {
+		return fail(columnInitialBalance, "must not be negative")
+	}
+
+	if next.ownerId != "" {
+		ownerId = next.ownerId
+	}
+
+	return importsdao.Account{Id: id, Balance: balance, OwnerId: ownerId}, nil
+}
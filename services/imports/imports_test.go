@@ -0,0 +1,154 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	importsdao "github.com/ashwin-m/transactions/daos/imports"
+	"github.com/ashwin-m/transactions/daos/imports/mocks"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var adminCtx = tenant.NewContext(
+	auth.NewContext(context.Background(), auth.Identity{ClientID: "admin", Roles: []string{auth.RoleAdmin}}),
+	"acme")
+
+func newService(t *testing.T, batchSize int) (Service, *mocks.Dao, pgxmock.PgxPoolIface) {
+	mockDB, _ := pgxmock.NewPool()
+	mockDao := mocks.NewDao(t)
+
+	return NewService(mockDB, mockDao, batchSize), mockDao, mockDB
+}
+
+func TestImport_CSV(t *testing.T) {
+	s, mockDao, mockDB := newService(t, 2)
+	mockDB.ExpectBegin()
+	mockDao.EXPECT().Insert(mock.Anything, mock.Anything, []importsdao.Account{
+		{Id: 1, Balance: 100.5, OwnerId: "client-1"},
+		{Id: 2, Balance: 0, OwnerId: "admin"},
+	}).Return(importsdao.Result{Created: []int64{1}}, nil)
+	mockDB.ExpectCommit()
+	mockDB.ExpectBegin()
+	mockDao.EXPECT().Insert(mock.Anything, mock.Anything, []importsdao.Account{
+		{Id: 3, Balance: 7, OwnerId: "client-3"},
+	}).Return(importsdao.Result{Conflicting: []int64{3}}, nil)
+	mockDB.ExpectCommit()
+
+	input := "\ufeffowner_id,account_id,initial_balance\n" +
+		"client-1,1,100.50\n" +
+		",2,0\n" +
+		"client-2,abc,10\n" +
+		"client-2,4,-1\n" +
+		"client-2,1,10\n" +
+		"client-3, 3 ,7\n" +
+		"client-4,5\n" +
+		"client-2,6,0x1p3\n"
+
+	report, err := s.Import(adminCtx, FormatCSV, strings.NewReader(input))
+
+	assert.NoError(t, err)
+	assert.Equal(t, Report{Rows: 8, Created: 1, Existing: 1, Failed: 6, Errors: []RowError{
+		{Line: 4, Field: "account_id", Message: "must be an integer"},
+		{Line: 5, AccountId: 4, Field: "initial_balance", Message: "must not be negative"},
+		{Line: 6, AccountId: 1, Field: "account_id", Message: "is also on line 2"},
+		{Line: 7, AccountId: 3, Field: "account_id", Message: "already exists with another initial balance or owner"},
+		{Line: 8, Message: "has 2 fields, the header has 3"},
+		{Line: 9, AccountId: 6, Field: "initial_balance", Message: "must be a decimal number"},
+	}}, report)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestImport_NDJSON(t *testing.T) {
+	s, mockDao, mockDB := newService(t, 0)
+	mockDB.ExpectBegin()
+	mockDao.EXPECT().Insert(mock.Anything, mock.Anything, []importsdao.Account{
+		{Id: 1, Balance: 10.25, OwnerId: "admin"},
+		{Id: 2, Balance: 3, OwnerId: "client-2"},
+	}).Return(importsdao.Result{Created: []int64{1, 2}}, nil)
+	mockDB.ExpectCommit()
+
+	input := `{"account_id": 1, "initial_balance": "10.25"}
+
+{"account_id": 2, "initial_balance": 3, "owner_id": "client-2"}
+{"account_id": 3, "initial_balance": 3, "currency": "EUR"}
+{"account_id": 4
+{"account_id": 2147483648, "initial_balance": 1}
+{"account_id": 5}
+`
+
+	report, err := s.Import(adminCtx, FormatNDJSON, strings.NewReader(input))
+
+	assert.NoError(t, err)
+	assert.Equal(t, Report{Rows: 6, Created: 2, Existing: 0, Failed: 4, Errors: []RowError{
+		{Line: 4, Message: "must be a JSON object with account_id, initial_balance, owner_id"},
+		{Line: 5, Message: "must be a JSON object with account_id, initial_balance, owner_id"},
+		{Line: 6, AccountId: 2147483648, Field: "account_id", Message: "must be between 1 and 2147483647"},
+		{Line: 7, AccountId: 5, Field: "initial_balance", Message: "is required"},
+	}}, report)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestImport_InvalidInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		field  apperrors.FieldError
+	}{
+		{"format", "xlsx", "", apperrors.FieldError{Field: "format", Message: "must be one of csv or ndjson"}},
+		{"empty", FormatCSV, "", apperrors.FieldError{Field: "header", Message: "is required"}},
+		{"missing column", FormatCSV, "account_id,owner_id\n1,a\n", apperrors.FieldError{Field: "header", Message: `must have the column "initial_balance"`}},
+		{"unknown column", FormatCSV, "account_id,balance\n", apperrors.FieldError{Field: "header", Message: `has the unknown column "balance", columns are account_id, initial_balance, owner_id`}},
+		{"repeated column", FormatCSV, "account_id,initial_balance,account_id\n", apperrors.FieldError{Field: "header", Message: `has the column "account_id" twice`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, _, _ := newService(t, 0)
+
+			report, err := s.Import(adminCtx, test.format, strings.NewReader(test.input))
+
+			var appErr *apperrors.Error
+			assert.ErrorAs(t, err, &appErr)
+			assert.Equal(t, []apperrors.FieldError{test.field}, appErr.Fields)
+			assert.Equal(t, Report{Errors: []RowError{}}, report)
+		})
+	}
+}
+
+func TestImport_CapsReportedErrors(t *testing.T) {
+	s, _, _ := newService(t, 0)
+
+	input := "account_id,initial_balance\n" + strings.Repeat("x,1\n", MaxReportedErrors+5)
+	report, err := s.Import(adminCtx, FormatCSV, strings.NewReader(input))
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(MaxReportedErrors+5), report.Failed)
+	assert.Len(t, report.Errors, MaxReportedErrors)
+}
+
+func TestImport_StopsOnDaoError(t *testing.T) {
+	s, mockDao, mockDB := newService(t, 1)
+	mockDB.ExpectBegin()
+	mockDao.EXPECT().Insert(mock.Anything, mock.Anything, mock.Anything).
+		Return(importsdao.Result{Created: []int64{1}}, nil).Once()
+	mockDB.ExpectCommit()
+	mockDB.ExpectBegin()
+	mockDao.EXPECT().Insert(mock.Anything, mock.Anything, mock.Anything).
+		Return(importsdao.Result{}, errors.New("connection reset")).Once()
+	mockDB.ExpectRollback()
+
+	input := "account_id,initial_balance\n1,1\n2,1\n3,1\n"
+	report, err := s.Import(adminCtx, FormatCSV, strings.NewReader(input))
+
+	assert.EqualError(t, err, "connection reset")
+	assert.Equal(t, int64(1), report.Created)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
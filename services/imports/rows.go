@@ -0,0 +1,188 @@
+package imports
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ashwin-m/transactions/utils/apperrors"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+
+	columnAccountId      = "account_id"
+	columnInitialBalance = "initial_balance"
+	columnOwnerId        = "owner_id"
+
+	// maxLineBytes bounds an NDJSON line, real rows are far shorter.
+	maxLineBytes = 64 * 1024
+)
+
+var Formats = []string{FormatCSV, FormatNDJSON}
+
+var columns = []string{columnAccountId, columnInitialBalance, columnOwnerId}
+
+// row is a row as read, before validation. err is set instead of the values
+// when the row couldn't be read at all.
+type row struct {
+	line           int
+	accountId      string
+	initialBalance string
+	ownerId        string
+	err            string
+}
+
+// rowReader returns rows until io.EOF. Any other error stops the import.
+type rowReader interface {
+	next() (row, error)
+}
+
+func newRowReader(format string, r io.Reader) (rowReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 4096), maxLineBytes)
+		return &ndjsonReader{scanner: scanner}, nil
+	}
+
+	return nil, apperrors.Validation(apperrors.FieldError{Field: "format", Message: "must be one of csv or ndjson"})
+}
+
+// csvReader reads CSV with a header row naming the columns, in any order.
+// owner_id may be left out.
+type csvReader struct {
+	reader *csv.Reader
+	// index is the position of each column in a record, -1 when absent.
+	index map[string]int
+	width int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, apperrors.Validation(apperrors.FieldError{Field: "header", Message: "is required"})
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, apperrors.Validation(apperrors.FieldError{Field: "header", Message: "must be a CSV row"})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &csvReader{reader: reader, index: map[string]int{}, width: len(header)}
+	for _, column := range columns {
+		c.index[column] = -1
+	}
+	for i, column := range header {
+		// spreadsheets often start the file with a byte order mark
+		column = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+		position, known := c.index[column]
+		switch {
+		case !known:
+			return nil, apperrors.Validation(apperrors.FieldError{Field: "header", Message: fmt.Sprintf("has the unknown column %q, columns are %s", column, strings.Join(columns, ", "))})
+		case position >= 0:
+			return nil, apperrors.Validation(apperrors.FieldError{Field: "header", Message: fmt.Sprintf("has the column %q twice", column)})
+		}
+		c.index[column] = i
+	}
+	for _, column := range []string{columnAccountId, columnInitialBalance} {
+		if c.index[column] < 0 {
+			return nil, apperrors.Validation(apperrors.FieldError{Field: "header", Message: fmt.Sprintf("must have the column %q", column)})
+		}
+	}
+
+	return c, nil
+}
+
+func (c *csvReader) next() (row, error) {
+	record, err := c.reader.Read()
+
+	var parseErr *csv.ParseError
+	switch {
+	case errors.As(err, &parseErr) && errors.Is(err, csv.ErrFieldCount):
+		line, _ := c.reader.FieldPos(0)
+		return row{line: line, err: fmt.Sprintf("has %d fields, the header has %d", len(record), c.width)}, nil
+	case errors.As(err, &parseErr):
+		return row{line: parseErr.StartLine, err: parseErr.Err.Error()}, nil
+	case err != nil:
+		return row{}, err
+	}
+
+	line, _ := c.reader.FieldPos(0)
+	return row{
+		line:           line,
+		accountId:      c.field(record, columnAccountId),
+		initialBalance: c.field(record, columnInitialBalance),
+		ownerId:        c.field(record, columnOwnerId),
+	}, nil
+}
+
+func (c *csvReader) field(record []string, column string) string {
+	i := c.index[column]
+	if i < 0 {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// ndjsonReader reads a JSON object per line, blank lines are skipped.
+// initial_balance may be a number or a string holding one, so balances
+// aren't rounded on the way in.
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+type ndjsonRow struct {
+	AccountId      json.Number `json:"account_id"`
+	InitialBalance json.Number `json:"initial_balance"`
+	OwnerId        string      `json:"owner_id"`
+}
+
+func (n *ndjsonReader) next() (row, error) {
+	for n.scanner.Scan() {
+		n.line++
+		content := bytes.TrimSpace(n.scanner.Bytes())
+		if len(content) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		var object ndjsonRow
+		err := decoder.Decode(&object)
+		if err != nil || decoder.More() {
+			return row{line: n.line, err: "must be a JSON object with " + strings.Join(columns, ", ")}, nil
+		}
+
+		return row{
+			line:           n.line,
+			accountId:      object.AccountId.String(),
+			initialBalance: object.InitialBalance.String(),
+			ownerId:        strings.TrimSpace(object.OwnerId),
+		}, nil
+	}
+
+	err := n.scanner.Err()
+	if errors.Is(err, bufio.ErrTooLong) {
+		return row{}, apperrors.Validation(apperrors.FieldError{Field: "body", Message: fmt.Sprintf("has a line longer than %d bytes after line %d", maxLineBytes, n.line)})
+	}
+	if err != nil {
+		return row{}, err
+	}
+
+	return row{}, io.EOF
+}
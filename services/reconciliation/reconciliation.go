@@ -0,0 +1,274 @@
+// Package reconciliation checks the integrity of the ledger. Balances are
+// updated in place by transfers, so a reconciler recomputes each balance
+// from the initial balance of the account and every transfer into and out
+// of it, and checks that transfers neither created nor destroyed money.
+// Everything is read from one snapshot, so concurrent transfers can't show
+// up as discrepancies. Accounts that predate initial balances had theirs
+// inferred from their transfers, so only drift since then shows up for them,
+// and they are reported apart.
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"time"
+
+	reconciliationdao "github.com/ashwin-m/transactions/daos/reconciliation"
+	"github.com/ashwin-m/transactions/utils/jobs"
+	"github.com/ashwin-m/transactions/utils/metrics"
+	"github.com/ashwin-m/transactions/utils/pgxiface"
+	"github.com/ashwin-m/transactions/utils/tenant"
+)
+
+const (
+	// DefaultTolerance absorbs the rounding of balances stored as floats.
+	DefaultTolerance = 1e-6
+)
+
+var ErrInProgress = errors.New("another reconciliation is running")
+
+type Options struct {
+	// Tenants are reconciled together, tenant.Default when empty.
+	Tenants []string
+	// Tolerance is the largest difference that isn't a discrepancy,
+	// DefaultTolerance when zero.
+	Tolerance float64
+}
+
+// Mismatch is an account whose balance differs from the one its transfers
+// imply.
+type Mismatch struct {
+	TenantId       string  `json:"tenant_id"`
+	AccountId      int64   `json:"account_id"`
+	OwnerId        string  `json:"owner_id"`
+	InitialBalance float64 `json:"initial_balance"`
+	// InitialBalanceInferred is set when the account predates initial
+	// balances, the difference is then only the drift since.
+	InitialBalanceInferred bool    `json:"initial_balance_inferred"`
+	Credits                float64 `json:"credits"`
+	Debits                 float64 `json:"debits"`
+	Transfers              int64   `json:"transfers"`
+	Expected               float64 `json:"expected_balance"`
+	Balance                float64 `json:"balance"`
+	// Difference is Balance less Expected.
+	Difference float64 `json:"difference"`
+}
+
+// Conservation compares the money held by the reconciled tenants with what
+// they started with and what was transferred across their boundary.
+type Conservation struct {
+	InitialBalance float64 `json:"initial_balance"`
+	// InferredInitialBalance is the part of InitialBalance held by accounts
+	// whose initial balance was inferred.
+	InferredInitialBalance float64 `json:"inferred_initial_balance"`
+	Inflow                 float64 `json:"inflow"`
+	Outflow                float64 `json:"outflow"`
+	Expected               float64 `json:"expected_balance"`
+	Balance                float64 `json:"balance"`
+	Difference             float64 `json:"difference"`
+	Holds                  bool    `json:"holds"`
+}
+
+type Report struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Tenants    []string  `json:"tenants"`
+	Accounts   int64     `json:"accounts"`
+	// Inferred counts the accounts whose initial balance was inferred.
+	Inferred     int64        `json:"inferred"`
+	Mismatches   []Mismatch   `json:"mismatches"`
+	Conservation Conservation `json:"conservation"`
+}
+
+// Consistent reports whether every balance matched and no money was
+// created or destroyed.
+func (r Report) Consistent() bool {
+	return len(r.Mismatches) == 0 && r.Conservation.Holds
+}
+
+type Reconciler struct {
+	dbPool  pgxiface.PgxIface
+	dao     reconciliationdao.Dao
+	job     *jobs.Job
+	options Options
+
+	now func() time.Time
+}
+
+// NewReconciler builds a reconciler reporting its progress to job, which
+// may be nil.
+func NewReconciler(dbPool pgxiface.PgxIface, dao reconciliationdao.Dao, job *jobs.Job, options Options) *Reconciler {
+	if len(options.Tenants) == 0 {
+		options.Tenants = []string{tenant.Default}
+	}
+	if options.Tolerance <= 0 {
+		options.Tolerance = DefaultTolerance
+	}
+
+	return &Reconciler{
+		dbPool:  dbPool,
+		dao:     dao,
+		job:     job,
+		options: options,
+		now:     time.Now,
+	}
+}
+
+// Run reconciles every interval until ctx is done, logging the
+// discrepancies found.
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Reconciler) runOnce(ctx context.Context) {
+	if r.job != nil {
+		r.job.Started()
+	}
+
+	report, err := r.Reconcile(ctx)
+	if errors.Is(err, ErrInProgress) {
+		slog.InfoContext(ctx, "ledger not reconciled, another replica is reconciling it")
+		if r.job != nil {
+			r.job.Succeeded()
+		}
+		return
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		slog.ErrorContext(ctx, "unable to reconcile the ledger", slog.Any("error", err))
+		if r.job != nil {
+			r.job.Failed(err)
+		}
+		return
+	}
+	if r.job != nil {
+		r.job.Succeeded()
+	}
+
+	for _, mismatch := range report.Mismatches {
+		slog.ErrorContext(ctx, "account balance doesn't match its transfers",
+			slog.String("tenant_id", mismatch.TenantId),
+			slog.Int64("account_id", mismatch.AccountId),
+			slog.Float64("balance", mismatch.Balance),
+			slog.Float64("expected_balance", mismatch.Expected),
+			slog.Bool("initial_balance_inferred", mismatch.InitialBalanceInferred))
+	}
+	if !report.Conservation.Holds {
+		slog.ErrorContext(ctx, "money was created or destroyed",
+			slog.Float64("balance", report.Conservation.Balance),
+			slog.Float64("expected_balance", report.Conservation.Expected))
+	}
+	slog.InfoContext(ctx, "ledger reconciled",
+		slog.Int64("accounts", report.Accounts),
+		slog.Int64("inferred", report.Inferred),
+		slog.Int("mismatches", len(report.Mismatches)),
+		slog.Bool("conservation_holds", report.Conservation.Holds))
+}
+
+// Reconcile checks the accounts of every tenant of the options, and that
+// money was conserved across them. It returns ErrInProgress when another
+// reconciliation is running.
+func (r *Reconciler) Reconcile(ctx context.Context) (Report, error) {
+	report := Report{
+		StartedAt:  r.now(),
+		Tenants:    r.options.Tenants,
+		Mismatches: []Mismatch{},
+	}
+
+	// every tenant is visible to the snapshot, queries filter by the one
+	// they are about
+	ctx = tenant.NewContext(ctx, r.options.Tenants[0], r.options.Tenants[1:]...)
+	txn, err := r.dbPool.Begin(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+	// the transaction only reads
+	defer txn.Rollback(ctx)
+
+	err = r.dao.Snapshot(ctx, txn)
+	if err != nil {
+		return Report{}, err
+	}
+
+	locked, err := r.dao.Lock(ctx, txn)
+	if err != nil {
+		return Report{}, err
+	}
+	if !locked {
+		return Report{}, ErrInProgress
+	}
+
+	for _, tenantId := range r.options.Tenants {
+		accounts, err := r.dao.AccountTotals(tenant.NewContext(ctx, tenantId), txn)
+		if err != nil {
+			return Report{}, err
+		}
+
+		report.Accounts += int64(len(accounts))
+		for _, account := range accounts {
+			if account.InitialBalanceInferred {
+				report.Inferred++
+			}
+
+			expected := account.InitialBalance + account.Credits - account.Debits
+			if r.matches(account.Balance, expected) {
+				continue
+			}
+
+			report.Mismatches = append(report.Mismatches, Mismatch{
+				TenantId:               tenantId,
+				AccountId:              account.Id,
+				OwnerId:                account.OwnerId,
+				InitialBalance:         account.InitialBalance,
+				InitialBalanceInferred: account.InitialBalanceInferred,
+				Credits:                account.Credits,
+				Debits:                 account.Debits,
+				Transfers:              account.Transfers,
+				Expected:               expected,
+				Balance:                account.Balance,
+				Difference:             account.Balance - expected,
+			})
+		}
+	}
+
+	totals, err := r.dao.Totals(ctx, txn, r.options.Tenants)
+	if err != nil {
+		return Report{}, err
+	}
+
+	expected := totals.InitialBalance + totals.Inflow - totals.Outflow
+	report.Conservation = Conservation{
+		InitialBalance:         totals.InitialBalance,
+		InferredInitialBalance: totals.InferredInitialBalance,
+		Inflow:                 totals.Inflow,
+		Outflow:                totals.Outflow,
+		Expected:               expected,
+		Balance:                totals.Balance,
+		Difference:             totals.Balance - expected,
+		Holds:                  r.matches(totals.Balance, expected),
+	}
+
+	report.FinishedAt = r.now()
+	metrics.Reconciled(len(report.Mismatches), report.Conservation.Difference)
+
+	return report, nil
+}
+
+func (r *Reconciler) matches(balance, expected float64) bool {
+	return math.Abs(balance-expected) <= r.options.Tolerance
+}
@@ -0,0 +1,187 @@
+package reconciliation
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	reconciliationdao "github.com/ashwin-m/transactions/daos/reconciliation"
+	"github.com/ashwin-m/transactions/daos/reconciliation/mocks"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var reconciledAt = time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)
+
+func newReconciler(t *testing.T, options Options) (*Reconciler, *mocks.Dao, pgxmock.PgxPoolIface) {
+	r, mockDao, mockDB := newUnlockedReconciler(t, options)
+	mockDao.EXPECT().Lock(mock.Anything, mock.Anything).Return(true, nil)
+
+	return r, mockDao, mockDB
+}
+
+// newUnlockedReconciler leaves the reconciliation lock to the test.
+func newUnlockedReconciler(t *testing.T, options Options) (*Reconciler, *mocks.Dao, pgxmock.PgxPoolIface) {
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectRollback()
+
+	mockDao := mocks.NewDao(t)
+	mockDao.EXPECT().Snapshot(mock.Anything, mock.Anything).Return(nil)
+
+	r := NewReconciler(mockDB, mockDao, nil, options)
+	r.now = func() time.Time { return reconciledAt }
+
+	return r, mockDao, mockDB
+}
+
+func forTenant(tenantId string) any {
+	return mock.MatchedBy(func(ctx context.Context) bool {
+		active, _ := tenant.FromContext(ctx)
+		return active == tenantId
+	})
+}
+
+func TestReconcile_Consistent(t *testing.T) {
+	r, mockDao, mockDB := newReconciler(t, Options{})
+	mockDao.EXPECT().AccountTotals(forTenant(tenant.Default), mock.Anything).Return([]reconciliationdao.AccountTotals{
+		{Id: 1, InitialBalance: 100, Balance: 89.9, Debits: 10.1, Transfers: 1},
+		{Id: 2, InitialBalance: 0, Balance: 10.1, Credits: 10.1, Transfers: 1},
+	}, nil)
+	mockDao.EXPECT().Totals(mock.Anything, mock.Anything, []string{tenant.Default}).Return(reconciliationdao.Totals{Balance: 100, InitialBalance: 100}, nil)
+
+	report, err := r.Reconcile(context.Background())
+
+	assert.NoError(t, err)
+	assert.True(t, report.Consistent())
+	assert.Equal(t, int64(2), report.Accounts)
+	assert.Empty(t, report.Mismatches)
+	assert.Equal(t, Conservation{InitialBalance: 100, Expected: 100, Balance: 100, Holds: true}, report.Conservation)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestReconcile_ReportsDiscrepancies(t *testing.T) {
+	r, mockDao, _ := newReconciler(t, Options{Tenants: []string{"acme", "globex"}})
+	mockDao.EXPECT().AccountTotals(forTenant("acme"), mock.Anything).Return([]reconciliationdao.AccountTotals{
+		{Id: 1, OwnerId: "client-1", InitialBalance: 100, Balance: 95, Debits: 10, Transfers: 1},
+	}, nil)
+	mockDao.EXPECT().AccountTotals(forTenant("globex"), mock.Anything).Return([]reconciliationdao.AccountTotals{
+		{Id: 1, InitialBalance: 0, Balance: 10, Credits: 10, Transfers: 1},
+	}, nil)
+	mockDao.EXPECT().Totals(mock.Anything, mock.Anything, []string{"acme", "globex"}).Return(reconciliationdao.Totals{Balance: 105, InitialBalance: 100}, nil)
+
+	report, err := r.Reconcile(context.Background())
+
+	assert.NoError(t, err)
+	assert.False(t, report.Consistent())
+	assert.Equal(t, []Mismatch{{
+		TenantId:       "acme",
+		AccountId:      1,
+		OwnerId:        "client-1",
+		InitialBalance: 100,
+		Debits:         10,
+		Transfers:      1,
+		Expected:       90,
+		Balance:        95,
+		Difference:     5,
+	}}, report.Mismatches)
+	assert.Equal(t, Conservation{InitialBalance: 100, Expected: 100, Balance: 105, Difference: 5}, report.Conservation)
+}
+
+func TestReconcile_ReportsInferredInitialBalancesApart(t *testing.T) {
+	r, mockDao, _ := newReconciler(t, Options{})
+	mockDao.EXPECT().AccountTotals(forTenant(tenant.Default), mock.Anything).Return([]reconciliationdao.AccountTotals{
+		{Id: 1, InitialBalance: 50, InitialBalanceInferred: true, Balance: 40, Debits: 5, Transfers: 1},
+		{Id: 2, InitialBalance: 50, InitialBalanceInferred: true, Balance: 50},
+		{Id: 3, InitialBalance: 10, Balance: 15, Credits: 5, Transfers: 1},
+	}, nil)
+	mockDao.EXPECT().Totals(mock.Anything, mock.Anything, []string{tenant.Default}).Return(reconciliationdao.Totals{Balance: 105, InitialBalance: 110, InferredInitialBalance: 100}, nil)
+
+	report, err := r.Reconcile(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), report.Accounts)
+	assert.Equal(t, int64(2), report.Inferred)
+	assert.Equal(t, []Mismatch{{
+		TenantId:               tenant.Default,
+		AccountId:              1,
+		InitialBalance:         50,
+		InitialBalanceInferred: true,
+		Debits:                 5,
+		Transfers:              1,
+		Expected:               45,
+		Balance:                40,
+		Difference:             -5,
+	}}, report.Mismatches)
+	assert.Equal(t, 100.0, report.Conservation.InferredInitialBalance)
+}
+
+func TestReconcile_InProgress(t *testing.T) {
+	r, mockDao, mockDB := newUnlockedReconciler(t, Options{})
+	mockDao.EXPECT().Lock(mock.Anything, mock.Anything).Return(false, nil)
+
+	_, err := r.Reconcile(context.Background())
+
+	assert.ErrorIs(t, err, ErrInProgress)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestReconcile_AccountsForTransfersAcrossTheTenants(t *testing.T) {
+	r, mockDao, _ := newReconciler(t, Options{Tenants: []string{"acme"}})
+	mockDao.EXPECT().AccountTotals(forTenant("acme"), mock.Anything).Return(nil, nil)
+	mockDao.EXPECT().Totals(mock.Anything, mock.Anything, []string{"acme"}).Return(reconciliationdao.Totals{Balance: 80, InitialBalance: 100, Inflow: 5, Outflow: 25}, nil)
+
+	report, err := r.Reconcile(context.Background())
+
+	assert.NoError(t, err)
+	assert.True(t, report.Conservation.Holds)
+}
+
+func TestReconcile_Failure(t *testing.T) {
+	r, mockDao, mockDB := newReconciler(t, Options{})
+	mockDao.EXPECT().AccountTotals(mock.Anything, mock.Anything).Return(nil, pgx.ErrTxClosed)
+
+	_, err := r.Reconcile(context.Background())
+
+	assert.ErrorIs(t, err, pgx.ErrTxClosed)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestWrite(t *testing.T) {
+	report := Report{
+		StartedAt:  reconciledAt,
+		FinishedAt: reconciledAt,
+		Tenants:    []string{"acme", "globex"},
+		Accounts:   2,
+		Inferred:   1,
+		Mismatches: []Mismatch{{TenantId: "acme", AccountId: 1, OwnerId: "client-1", InitialBalance: 100, InitialBalanceInferred: true, Debits: 10, Transfers: 1, Expected: 90, Balance: 95, Difference: 5}},
+		Conservation: Conservation{
+			InitialBalance: 100, Expected: 100, Balance: 105.5, Difference: 5.5,
+		},
+	}
+
+	var csv bytes.Buffer
+	assert.NoError(t, Write(&csv, FormatCSV, report))
+	assert.Equal(t, "kind,tenant_id,account_id,owner_id,initial_balance,initial_balance_inferred,credits,debits,transfers,expected_balance,balance,difference\n"+
+		"account,acme,1,client-1,100,true,0,10,1,90,95,5\n"+
+		"conservation,acme globex,,,100,,0,0,,100,105.5,5.5\n", csv.String())
+
+	var json bytes.Buffer
+	assert.NoError(t, Write(&json, FormatJSON, report))
+	assert.JSONEq(t, `{
+		"started_at": "2026-10-19T09:30:00Z",
+		"finished_at": "2026-10-19T09:30:00Z",
+		"tenants": ["acme", "globex"],
+		"accounts": 2,
+		"inferred": 1,
+		"mismatches": [{"tenant_id": "acme", "account_id": 1, "owner_id": "client-1", "initial_balance": 100, "initial_balance_inferred": true, "credits": 0, "debits": 10,
+			"transfers": 1, "expected_balance": 90, "balance": 95, "difference": 5}],
+		"conservation": {"initial_balance": 100, "inferred_initial_balance": 0, "inflow": 0, "outflow": 0, "expected_balance": 100, "balance": 105.5, "difference": 5.5, "holds": false}
+	}`, json.String())
+
+	assert.Error(t, Write(&json, "xml", report))
+}
@@ -0,0 +1,76 @@
+package reconciliation
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+var Formats = []string{FormatJSON, FormatCSV}
+
+var csvHeader = []string{"kind", "tenant_id", "account_id", "owner_id", "initial_balance", "initial_balance_inferred", "credits", "debits", "transfers", "expected_balance", "balance", "difference"}
+
+// Write writes the report in format, one of Formats. JSON holds the whole
+// report. CSV only lists the discrepancies: a row per mismatched account,
+// and a conservation row when money wasn't conserved, with the transfers
+// into and out of the reconciled tenants as its credits and debits.
+// initial_balance_inferred marks the accounts whose initial balance was
+// inferred from their transfers.
+func Write(w io.Writer, format string, report Report) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "    ")
+		return encoder.Encode(report)
+	case FormatCSV:
+		return writeCSV(w, report)
+	}
+
+	return fmt.Errorf("unknown report format %q", format)
+}
+
+func writeCSV(w io.Writer, report Report) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write(csvHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range report.Mismatches {
+		err = writer.Write([]string{
+			"account", m.TenantId, strconv.FormatInt(m.AccountId, 10), m.OwnerId,
+			formatFloat(m.InitialBalance), strconv.FormatBool(m.InitialBalanceInferred), formatFloat(m.Credits), formatFloat(m.Debits), strconv.FormatInt(m.Transfers, 10),
+			formatFloat(m.Expected), formatFloat(m.Balance), formatFloat(m.Difference),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if c := report.Conservation; !c.Holds {
+		err = writer.Write([]string{
+			"conservation", strings.Join(report.Tenants, " "), "", "",
+			formatFloat(c.InitialBalance), "", formatFloat(c.Inflow), formatFloat(c.Outflow), "",
+			formatFloat(c.Expected), formatFloat(c.Balance), formatFloat(c.Difference),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,213 @@
+package statements
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ashwin-m/transactions/utils/pdf"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatPDF  = "pdf"
+)
+
+var Formats = []string{FormatJSON, FormatCSV, FormatPDF}
+
+// ContentTypes maps each of Formats to its media type.
+var ContentTypes = map[string]string{
+	FormatJSON: "application/json",
+	FormatCSV:  "text/csv",
+	FormatPDF:  "application/pdf",
+}
+
+var csvHeader = []string{"date", "description", "transfer_id", "counterparty_tenant_id", "counterparty_account_id", "amount", "balance"}
+
+// writer writes a statement as its lines are worked out: open with its
+// opening balance, line for each of its lines, and close with its totals
+// and closing balance. A statement cut short lacks its closing balance.
+type writer interface {
+	open(statement Statement) error
+	line(line Line) error
+	close(statement Statement) error
+}
+
+// newWriter writes in format, one of Formats. JSON is the statement with
+// its lines as transfers. CSV has a row per transfer between an opening and
+// a closing balance row. PDF lays the same out as printable pages.
+func newWriter(w io.Writer, format string) (writer, error) {
+	switch format {
+	case FormatJSON:
+		return &jsonWriter{w: w}, nil
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatPDF:
+		return &pdfWriter{w: w}, nil
+	}
+
+	return nil, fmt.Errorf("unknown statement format %q", format)
+}
+
+// jsonOpening and jsonClosing are the fields of a statement written before
+// and after its transfers.
+type jsonOpening struct {
+	AccountId      int64     `json:"account_id"`
+	TenantId       string    `json:"tenant_id"`
+	OwnerId        string    `json:"owner_id,omitempty"`
+	From           string    `json:"from"`
+	To             string    `json:"to"`
+	GeneratedAt    time.Time `json:"generated_at"`
+	OpeningBalance float64   `json:"opening_balance"`
+}
+
+type jsonClosing struct {
+	Credits        float64 `json:"credits"`
+	Debits         float64 `json:"debits"`
+	ClosingBalance float64 `json:"closing_balance"`
+}
+
+type jsonWriter struct {
+	w     io.Writer
+	lines int
+}
+
+func (j *jsonWriter) open(statement Statement) error {
+	opening, err := json.Marshal(jsonOpening{
+		AccountId:      statement.AccountId,
+		TenantId:       statement.TenantId,
+		OwnerId:        statement.OwnerId,
+		From:           statement.From,
+		To:             statement.To,
+		GeneratedAt:    statement.GeneratedAt,
+		OpeningBalance: statement.OpeningBalance,
+	})
+	if err != nil {
+		return err
+	}
+
+	// the object is left open for the transfers
+	_, err = fmt.Fprintf(j.w, `%s,"transfers":[`, opening[:len(opening)-1])
+	return err
+}
+
+func (j *jsonWriter) line(line Line) error {
+	encoded, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+
+	if j.lines > 0 {
+		_, err = io.WriteString(j.w, ",")
+		if err != nil {
+			return err
+		}
+	}
+	j.lines++
+
+	_, err = j.w.Write(encoded)
+	return err
+}
+
+func (j *jsonWriter) close(statement Statement) error {
+	closing, err := json.Marshal(jsonClosing{
+		Credits:        statement.Credits,
+		Debits:         statement.Debits,
+		ClosingBalance: statement.ClosingBalance,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(j.w, "],%s\n", closing[1:])
+	return err
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) open(statement Statement) error {
+	err := c.w.Write(csvHeader)
+	if err != nil {
+		return err
+	}
+
+	return c.w.Write([]string{statement.From, "opening balance", "", "", "", "", formatFloat(statement.OpeningBalance)})
+}
+
+func (c *csvWriter) line(line Line) error {
+	return c.w.Write([]string{
+		line.Date.Format(time.RFC3339Nano), line.Type, strconv.FormatInt(line.TransferId, 10),
+		line.CounterpartyTenantId, strconv.FormatInt(line.CounterpartyAccountId, 10),
+		formatFloat(line.Amount), formatFloat(line.Balance),
+	})
+}
+
+func (c *csvWriter) close(statement Statement) error {
+	err := c.w.Write([]string{statement.To, "closing balance", "", "", "", "", formatFloat(statement.ClosingBalance)})
+	if err != nil {
+		return err
+	}
+
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// pdfRow lays out a row of the transfers table, 89 characters wide.
+const pdfRow = "%-16s %-6s %10s %-24s %14s %14s"
+
+type pdfWriter struct {
+	w     io.Writer
+	d     *pdf.Writer
+	lines int
+}
+
+func (p *pdfWriter) open(statement Statement) error {
+	p.d = pdf.NewWriter(p.w, fmt.Sprintf("Statement of account %d", statement.AccountId))
+
+	p.d.Heading(fmt.Sprintf("Statement of account %d", statement.AccountId))
+	p.d.Lines(
+		fmt.Sprintf("Tenant:    %s", statement.TenantId),
+		fmt.Sprintf("Owner:     %s", statement.OwnerId),
+		fmt.Sprintf("Period:    %s to %s", statement.From, statement.To),
+		fmt.Sprintf("Generated: %s", statement.GeneratedAt.Format("2006-01-02 15:04 MST")),
+		"",
+		fmt.Sprintf(pdfRow, "Date (UTC)", "Type", "Transfer", "Counterparty", "Amount", "Balance"),
+		fmt.Sprintf(pdfRow, statement.From, "", "", "Opening balance", "", formatFloat(statement.OpeningBalance)),
+	)
+
+	return nil
+}
+
+func (p *pdfWriter) line(line Line) error {
+	counterparty := fmt.Sprintf("%s/%d", line.CounterpartyTenantId, line.CounterpartyAccountId)
+	if len(counterparty) > 24 {
+		counterparty = counterparty[:23] + "~"
+	}
+	p.d.Line(fmt.Sprintf(pdfRow, line.Date.Format("2006-01-02 15:04"), line.Type, strconv.FormatInt(line.TransferId, 10),
+		counterparty, formatFloat(line.Amount), formatFloat(line.Balance)))
+	p.lines++
+
+	return nil
+}
+
+func (p *pdfWriter) close(statement Statement) error {
+	p.d.Lines(
+		fmt.Sprintf(pdfRow, statement.To, "", "", "Closing balance", "", formatFloat(statement.ClosingBalance)),
+		"",
+		fmt.Sprintf("Transfers: %d", p.lines),
+		fmt.Sprintf("Credits:   %s", formatFloat(statement.Credits)),
+		fmt.Sprintf("Debits:    %s", formatFloat(statement.Debits)),
+	)
+
+	return p.d.Close()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,279 @@
+// Package statements builds account statements: the balance of an account
+// at the start of a period, every transfer into or out of it during the
+// period with the balance after it, and the balance at the end. Statements
+// are derived from the transfers, so they add up whatever the account's
+// current balance. They are written as the transfers are read, a page at a
+// time, however many there are. Transfers made before they were dated, by
+// schema version 5, were all dated when they were, so a statement of an
+// account that has some must start after then.
+package statements
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	transactionledger "github.com/ashwin-m/transactions/daos/transactions"
+	accountsservice "github.com/ashwin-m/transactions/services/accounts"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+)
+
+const (
+	// DateLayout is how the first and last days of a period are written.
+	DateLayout = "2006-01-02"
+
+	// MaxDays is the longest period a statement may cover.
+	MaxDays = 366
+
+	TypeCredit = "credit"
+	TypeDebit  = "debit"
+
+	prec = 100
+
+	// pageSize is how many transfers are read at a time.
+	pageSize = 500
+)
+
+type Request struct {
+	AccountId int64
+	// From and To are the first and last days of the period, in UTC, as
+	// DateLayout. From defaults to the first day of the current month and
+	// To to today.
+	From string
+	To   string
+}
+
+// Line is a transfer into or out of the account. A transfer from the
+// account to itself is listed twice, as a debit then a credit.
+type Line struct {
+	TransferId            int64     `json:"transfer_id"`
+	Date                  time.Time `json:"date"`
+	Type                  string    `json:"type"`
+	CounterpartyTenantId  string    `json:"counterparty_tenant_id"`
+	CounterpartyAccountId int64     `json:"counterparty_account_id"`
+	// Amount is negative for debits.
+	Amount  float64 `json:"amount"`
+	Balance float64 `json:"balance"`
+}
+
+type Statement struct {
+	AccountId      int64     `json:"account_id"`
+	TenantId       string    `json:"tenant_id"`
+	OwnerId        string    `json:"owner_id,omitempty"`
+	From           string    `json:"from"`
+	To             string    `json:"to"`
+	GeneratedAt    time.Time `json:"generated_at"`
+	OpeningBalance float64   `json:"opening_balance"`
+	Credits        float64   `json:"credits"`
+	Debits         float64   `json:"debits"`
+	ClosingBalance float64   `json:"closing_balance"`
+	// Lines are only written, Get leaves them out.
+	Lines []Line `json:"transfers"`
+}
+
+type Service interface {
+	// Get returns the statement of an account the caller has access to, up
+	// to its opening balance. Its lines and closing balance are worked out
+	// by Write.
+	Get(ctx context.Context, request Request) (Statement, error)
+	// Write writes statement in format, one of Formats, with a line per
+	// transfer of its period.
+	Write(ctx context.Context, w io.Writer, format string, statement Statement) error
+}
+
+type service struct {
+	accounts        accountsservice.Service
+	transactionsDao transactionledger.Dao
+	now             func() time.Time
+	pageSize        int
+}
+
+func NewService(accounts accountsservice.Service, transactionsDao transactionledger.Dao) Service {
+	return &service{
+		accounts:        accounts,
+		transactionsDao: transactionsDao,
+		now:             time.Now,
+		pageSize:        pageSize,
+	}
+}
+
+func (s *service) Get(ctx context.Context, request Request) (Statement, error) {
+	now := s.now().UTC()
+	from, to, err := period(request, now)
+	if err != nil {
+		return Statement{}, err
+	}
+
+	account, err := s.accounts.Get(ctx, request.AccountId)
+	if err != nil {
+		return Statement{}, err
+	}
+
+	// undated transfers are dated when they were, the balances of any
+	// period until then would be wrong
+	undated, err := s.transactionsDao.Undated(ctx, account.GetId())
+	if err != nil {
+		return Statement{}, err
+	}
+	if !undated.IsZero() && !from.After(undated) {
+		return Statement{}, apperrors.Validation(apperrors.FieldError{
+			Field:   "from",
+			Message: fmt.Sprintf("must be after %s, the transfers of the account made until then weren't dated", undated.UTC().Format(DateLayout)),
+		})
+	}
+
+	net, err := s.transactionsDao.NetByAccount(ctx, account.GetId(), from)
+	if err != nil {
+		return Statement{}, err
+	}
+
+	balance := new(big.Float).SetPrec(prec).SetFloat64(account.GetInitialBalance())
+	balance.Add(balance, new(big.Float).SetPrec(prec).SetFloat64(net))
+
+	statement := Statement{
+		AccountId:   account.GetId(),
+		TenantId:    account.GetTenantId(),
+		OwnerId:     account.GetOwnerId(),
+		From:        from.Format(DateLayout),
+		To:          to.Format(DateLayout),
+		GeneratedAt: now,
+	}
+	statement.OpeningBalance, _ = balance.Float64()
+	statement.ClosingBalance = statement.OpeningBalance
+
+	return statement, nil
+}
+
+func (s *service) Write(ctx context.Context, w io.Writer, format string, statement Statement) error {
+	from, err := time.Parse(DateLayout, statement.From)
+	if err != nil {
+		return err
+	}
+	to, err := time.Parse(DateLayout, statement.To)
+	if err != nil {
+		return err
+	}
+	// the period ends at the start of the day after its last one
+	end := to.AddDate(0, 0, 1)
+
+	buffered := bufio.NewWriter(w)
+	writer, err := newWriter(buffered, format)
+	if err != nil {
+		return err
+	}
+
+	err = writer.open(statement)
+	if err != nil {
+		return err
+	}
+
+	balance := new(big.Float).SetPrec(prec).SetFloat64(statement.OpeningBalance)
+	credits := new(big.Float).SetPrec(prec)
+	debits := new(big.Float).SetPrec(prec)
+
+	var afterId int64
+	for {
+		transactions, err := s.transactionsDao.ListByAccountBetween(ctx, statement.AccountId, from, afterId, end, s.pageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, transaction := range transactions {
+			amount := new(big.Float).SetPrec(prec).SetFloat64(transaction.GetAmount())
+
+			if transaction.GetTenantId() == statement.TenantId && transaction.GetSourceAccountId() == statement.AccountId {
+				balance.Sub(balance, amount)
+				debits.Add(debits, amount)
+
+				line := Line{
+					TransferId:            transaction.GetId(),
+					Date:                  transaction.GetCreatedAt().UTC(),
+					Type:                  TypeDebit,
+					CounterpartyTenantId:  transaction.GetDestinationTenantId(),
+					CounterpartyAccountId: transaction.GetDestinationAccountId(),
+					Amount:                -transaction.GetAmount(),
+				}
+				line.Balance, _ = balance.Float64()
+				err = writer.line(line)
+				if err != nil {
+					return err
+				}
+			}
+
+			if transaction.GetDestinationTenantId() == statement.TenantId && transaction.GetDestinationAccountId() == statement.AccountId {
+				balance.Add(balance, amount)
+				credits.Add(credits, amount)
+
+				line := Line{
+					TransferId:            transaction.GetId(),
+					Date:                  transaction.GetCreatedAt().UTC(),
+					Type:                  TypeCredit,
+					CounterpartyTenantId:  transaction.GetTenantId(),
+					CounterpartyAccountId: transaction.GetSourceAccountId(),
+					Amount:                transaction.GetAmount(),
+				}
+				line.Balance, _ = balance.Float64()
+				err = writer.line(line)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(transactions) < s.pageSize {
+			break
+		}
+		last := transactions[len(transactions)-1]
+		from, afterId = last.GetCreatedAt(), last.GetId()
+	}
+
+	statement.Credits, _ = credits.Float64()
+	statement.Debits, _ = debits.Float64()
+	statement.ClosingBalance, _ = balance.Float64()
+
+	err = writer.close(statement)
+	if err != nil {
+		return err
+	}
+
+	return buffered.Flush()
+}
+
+// period parses the first and last days of the statement, defaulting them
+// from now.
+func period(request Request, now time.Time) (from, to time.Time, err error) {
+	var problems []apperrors.FieldError
+
+	from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if request.From != "" {
+		from, err = time.Parse(DateLayout, request.From)
+		if err != nil {
+			problems = append(problems, apperrors.FieldError{Field: "from", Message: "must be a date formatted as YYYY-MM-DD"})
+		}
+	}
+
+	to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if request.To != "" {
+		to, err = time.Parse(DateLayout, request.To)
+		if err != nil {
+			problems = append(problems, apperrors.FieldError{Field: "to", Message: "must be a date formatted as YYYY-MM-DD"})
+		}
+	}
+
+	switch {
+	case len(problems) > 0:
+	case to.Before(from):
+		problems = append(problems, apperrors.FieldError{Field: "to", Message: "must not be before from"})
+	case to.Sub(from) >= MaxDays*24*time.Hour:
+		problems = append(problems, apperrors.FieldError{Field: "to", Message: fmt.Sprintf("must not make the period longer than %d days", MaxDays)})
+	}
+
+	if len(problems) > 0 {
+		return from, to, apperrors.Validation(problems...)
+	}
+
+	return from, to, nil
+}
@@ -0,0 +1,311 @@
+package statements
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	accountsMocks "github.com/ashwin-m/transactions/daos/accounts/mocks"
+	transactionsMocks "github.com/ashwin-m/transactions/daos/transactions/mocks"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	accountsmodel "github.com/ashwin-m/transactions/models/accounts"
+	transactionsmodel "github.com/ashwin-m/transactions/models/transactions"
+	accountsservice "github.com/ashwin-m/transactions/services/accounts"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	generatedAt = time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)
+
+	clientCtx = auth.NewContext(context.Background(), auth.Identity{ClientID: "client-1", Roles: []string{auth.RoleRead}})
+)
+
+func newService(t *testing.T) (*service, *accountsMocks.Dao, *transactionsMocks.Dao) {
+	accountsDao := accountsMocks.NewDao(t)
+	transactionsDao := transactionsMocks.NewDao(t)
+
+	s := NewService(accountsservice.NewService(nil, accountsDao, nil), transactionsDao).(*service)
+	s.now = func() time.Time { return generatedAt }
+
+	return s, accountsDao, transactionsDao
+}
+
+func account(id int64, initialBalance float64) accountsmodel.Accounts {
+	var a accountsmodel.Accounts
+	a.SetId(id)
+	a.SetTenantId("default")
+	a.SetOwnerId("client-1")
+	a.SetInitialBalance(initialBalance)
+	return a
+}
+
+func transfer(id int64, sourceTenantId string, sourceId int64, destinationTenantId string, destinationId int64, amount float64, createdAt time.Time) transactionsmodel.Transactions {
+	var t transactionsmodel.Transactions
+	t.SetId(id)
+	t.SetTenantId(sourceTenantId)
+	t.SetSourceAccountId(sourceId)
+	t.SetDestinationTenantId(destinationTenantId)
+	t.SetDestinationAccountId(destinationId)
+	t.SetAmount(amount)
+	t.SetCreatedAt(createdAt)
+	return t
+}
+
+func day(d int) time.Time {
+	return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestGet_OpeningBalance(t *testing.T) {
+	s, accountsDao, transactionsDao := newService(t)
+	accountsDao.EXPECT().GetById(mock.Anything, int64(1)).Return(account(1, 100), nil)
+	transactionsDao.EXPECT().Undated(mock.Anything, int64(1)).Return(time.Time{}, nil)
+	transactionsDao.EXPECT().NetByAccount(mock.Anything, int64(1), day(1)).Return(-20, nil)
+
+	statement, err := s.Get(clientCtx, Request{AccountId: 1, From: "2026-10-01", To: "2026-10-15"})
+
+	require.NoError(t, err)
+	assert.Equal(t, Statement{
+		AccountId:      1,
+		TenantId:       "default",
+		OwnerId:        "client-1",
+		From:           "2026-10-01",
+		To:             "2026-10-15",
+		GeneratedAt:    generatedAt,
+		OpeningBalance: 80,
+		ClosingBalance: 80,
+	}, statement)
+}
+
+func TestGet_DefaultsToThisMonth(t *testing.T) {
+	s, accountsDao, transactionsDao := newService(t)
+	accountsDao.EXPECT().GetById(mock.Anything, int64(1)).Return(account(1, 10), nil)
+	transactionsDao.EXPECT().Undated(mock.Anything, int64(1)).Return(time.Time{}, nil)
+	transactionsDao.EXPECT().NetByAccount(mock.Anything, int64(1), day(1)).Return(0, nil)
+
+	statement, err := s.Get(clientCtx, Request{AccountId: 1})
+
+	require.NoError(t, err)
+	assert.Equal(t, "2026-10-01", statement.From)
+	assert.Equal(t, "2026-10-19", statement.To)
+	assert.Equal(t, float64(10), statement.OpeningBalance)
+}
+
+func TestGet_BeforeTransfersWereDated(t *testing.T) {
+	s, accountsDao, transactionsDao := newService(t)
+	accountsDao.EXPECT().GetById(mock.Anything, int64(1)).Return(account(1, 10), nil).Times(2)
+	transactionsDao.EXPECT().Undated(mock.Anything, int64(1)).Return(day(4).Add(14*time.Hour), nil).Times(2)
+	transactionsDao.EXPECT().NetByAccount(mock.Anything, int64(1), day(5)).Return(0, nil)
+
+	_, err := s.Get(clientCtx, Request{AccountId: 1, From: "2026-10-04", To: "2026-10-10"})
+
+	assert.Equal(t, []apperrors.FieldError{{Field: "from", Message: "must be after 2026-10-04, the transfers of the account made until then weren't dated"}}, apperrors.From(err).Fields)
+
+	_, err = s.Get(clientCtx, Request{AccountId: 1, From: "2026-10-05", To: "2026-10-10"})
+
+	assert.NoError(t, err)
+}
+
+func TestGet_InvalidPeriod(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		fields   []apperrors.FieldError
+	}{
+		{"bad dates", "yesterday", "2026-13-01", []apperrors.FieldError{
+			{Field: "from", Message: "must be a date formatted as YYYY-MM-DD"},
+			{Field: "to", Message: "must be a date formatted as YYYY-MM-DD"},
+		}},
+		{"reversed", "2026-10-02", "2026-10-01", []apperrors.FieldError{{Field: "to", Message: "must not be before from"}}},
+		{"too long", "2025-01-01", "2026-01-02", []apperrors.FieldError{{Field: "to", Message: "must not make the period longer than 366 days"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, _ := newService(t)
+
+			_, err := s.Get(clientCtx, Request{AccountId: 1, From: tt.from, To: tt.to})
+
+			assert.Equal(t, tt.fields, apperrors.From(err).Fields)
+		})
+	}
+}
+
+func TestGet_LeapYear(t *testing.T) {
+	s, accountsDao, transactionsDao := newService(t)
+	accountsDao.EXPECT().GetById(mock.Anything, int64(1)).Return(account(1, 0), nil)
+	transactionsDao.EXPECT().Undated(mock.Anything, int64(1)).Return(time.Time{}, nil)
+	transactionsDao.EXPECT().NetByAccount(mock.Anything, int64(1), mock.Anything).Return(0, nil)
+
+	_, err := s.Get(clientCtx, Request{AccountId: 1, From: "2024-01-01", To: "2024-12-31"})
+
+	assert.NoError(t, err)
+}
+
+func TestGet_ForeignAccount(t *testing.T) {
+	s, accountsDao, _ := newService(t)
+	accountsDao.EXPECT().GetById(mock.Anything, int64(1)).Return(accountsmodel.Accounts{}, pgx.ErrNoRows)
+
+	_, err := s.Get(clientCtx, Request{AccountId: 1})
+
+	assert.Equal(t, apperrors.CodeForbidden, apperrors.From(err).Code)
+}
+
+func TestGet_DaoError(t *testing.T) {
+	s, accountsDao, transactionsDao := newService(t)
+	accountsDao.EXPECT().GetById(mock.Anything, int64(1)).Return(account(1, 0), nil)
+	transactionsDao.EXPECT().Undated(mock.Anything, int64(1)).Return(time.Time{}, nil)
+	transactionsDao.EXPECT().NetByAccount(mock.Anything, int64(1), day(1)).Return(0, errors.New("test"))
+
+	_, err := s.Get(clientCtx, Request{AccountId: 1})
+
+	assert.EqualError(t, err, "test")
+}
+
+// opened is the statement Get returns for account 1 over the first half
+// of the month.
+var opened = Statement{
+	AccountId:      1,
+	TenantId:       "default",
+	OwnerId:        "client-1",
+	From:           "2026-10-01",
+	To:             "2026-10-15",
+	GeneratedAt:    generatedAt,
+	OpeningBalance: 80,
+	ClosingBalance: 80,
+}
+
+func expectTransfers(transactionsDao *transactionsMocks.Dao, from time.Time, afterId int64, limit int, transfers ...transactionsmodel.Transactions) {
+	transactionsDao.EXPECT().ListByAccountBetween(mock.Anything, int64(1), from, afterId, day(16), limit).Return(transfers, nil)
+}
+
+func TestWrite_RunningBalance(t *testing.T) {
+	s, _, transactionsDao := newService(t)
+	expectTransfers(transactionsDao, day(1), 0, pageSize,
+		transfer(7, "default", 1, "default", 2, 30.5, day(3).Add(time.Hour)),
+		transfer(9, "acme", 4, "default", 1, 0.1, day(5)),
+		transfer(12, "default", 1, "default", 1, 5, day(15)),
+	)
+
+	var b bytes.Buffer
+	require.NoError(t, s.Write(clientCtx, &b, FormatJSON, opened))
+
+	var statement Statement
+	require.NoError(t, json.Unmarshal(b.Bytes(), &statement))
+	assert.Equal(t, Statement{
+		AccountId:      1,
+		TenantId:       "default",
+		OwnerId:        "client-1",
+		From:           "2026-10-01",
+		To:             "2026-10-15",
+		GeneratedAt:    generatedAt,
+		OpeningBalance: 80,
+		Credits:        5.1,
+		Debits:         35.5,
+		ClosingBalance: 49.6,
+		Lines: []Line{
+			{TransferId: 7, Date: day(3).Add(time.Hour), Type: TypeDebit, CounterpartyTenantId: "default", CounterpartyAccountId: 2, Amount: -30.5, Balance: 49.5},
+			{TransferId: 9, Date: day(5), Type: TypeCredit, CounterpartyTenantId: "acme", CounterpartyAccountId: 4, Amount: 0.1, Balance: 49.6},
+			{TransferId: 12, Date: day(15), Type: TypeDebit, CounterpartyTenantId: "default", CounterpartyAccountId: 1, Amount: -5, Balance: 44.6},
+			{TransferId: 12, Date: day(15), Type: TypeCredit, CounterpartyTenantId: "default", CounterpartyAccountId: 1, Amount: 5, Balance: 49.6},
+		},
+	}, statement)
+}
+
+func TestWrite_NoTransfers(t *testing.T) {
+	s, _, transactionsDao := newService(t)
+	expectTransfers(transactionsDao, day(1), 0, pageSize)
+
+	var b bytes.Buffer
+	require.NoError(t, s.Write(clientCtx, &b, FormatJSON, opened))
+
+	assert.Equal(t, `{"account_id":1,"tenant_id":"default","owner_id":"client-1","from":"2026-10-01","to":"2026-10-15","generated_at":"2026-10-19T09:30:00Z",`+
+		`"opening_balance":80,"transfers":[],"credits":0,"debits":0,"closing_balance":80}`+"\n", b.String())
+}
+
+func TestWrite_ReadsPageByPage(t *testing.T) {
+	s, _, transactionsDao := newService(t)
+	s.pageSize = 2
+	expectTransfers(transactionsDao, day(1), 0, 2,
+		transfer(7, "default", 1, "default", 2, 30.5, day(3)),
+		transfer(9, "acme", 4, "default", 1, 0.1, day(5)),
+	)
+	expectTransfers(transactionsDao, day(5), 9, 2,
+		transfer(10, "default", 3, "default", 1, 1, day(5)),
+	)
+
+	var b bytes.Buffer
+	require.NoError(t, s.Write(clientCtx, &b, FormatCSV, opened))
+
+	rows, err := csv.NewReader(&b).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 6)
+	assert.Equal(t, []string{"2026-10-05T00:00:00Z", "credit", "10", "default", "3", "1", "50.6"}, rows[4])
+	assert.Equal(t, []string{"2026-10-15", "closing balance", "", "", "", "", "50.6"}, rows[5])
+}
+
+func TestWrite_CSV(t *testing.T) {
+	s, _, transactionsDao := newService(t)
+	expectTransfers(transactionsDao, day(1), 0, pageSize,
+		transfer(7, "default", 1, "default", 2, 30.5, day(3).Add(time.Hour)),
+		transfer(9, "acme", 4, "default", 1, 0.1, day(5)),
+	)
+
+	var b bytes.Buffer
+	require.NoError(t, s.Write(clientCtx, &b, FormatCSV, opened))
+
+	rows, err := csv.NewReader(&b).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		csvHeader,
+		{"2026-10-01", "opening balance", "", "", "", "", "80"},
+		{"2026-10-03T01:00:00Z", "debit", "7", "default", "2", "-30.5", "49.5"},
+		{"2026-10-05T00:00:00Z", "credit", "9", "acme", "4", "0.1", "49.6"},
+		{"2026-10-15", "closing balance", "", "", "", "", "49.6"},
+	}, rows)
+}
+
+func TestWrite_PDF(t *testing.T) {
+	s, _, transactionsDao := newService(t)
+	expectTransfers(transactionsDao, day(1), 0, pageSize,
+		transfer(7, "default", 1, "default", 2, 30.5, day(3).Add(time.Hour)),
+		transfer(9, "acme", 4, "default", 1, 0.1, day(5)),
+	)
+
+	var b bytes.Buffer
+	require.NoError(t, s.Write(clientCtx, &b, FormatPDF, opened))
+
+	out := b.String()
+	assert.True(t, strings.HasPrefix(out, "%PDF-"))
+	assert.Contains(t, out, "(Statement of account 1) Tj")
+	assert.Contains(t, out, "(2026-10-03 01:00 debit           7 default/2                         -30.5           49.5) Tj")
+	assert.Contains(t, out, "(Transfers: 2) Tj")
+	assert.Contains(t, out, "(Debits:    30.5) Tj")
+}
+
+func TestWrite_UnknownFormat(t *testing.T) {
+	s, _, _ := newService(t)
+
+	err := s.Write(clientCtx, &bytes.Buffer{}, "xml", opened)
+
+	assert.EqualError(t, err, `unknown statement format "xml"`)
+}
+
+func TestWrite_DaoError(t *testing.T) {
+	s, _, transactionsDao := newService(t)
+	transactionsDao.EXPECT().ListByAccountBetween(mock.Anything, int64(1), day(1), int64(0), day(16), pageSize).Return(nil, errors.New("test"))
+
+	var b bytes.Buffer
+	err := s.Write(clientCtx, &b, FormatCSV, opened)
+
+	assert.EqualError(t, err, "test")
+	assert.Zero(t, b.Len())
+}
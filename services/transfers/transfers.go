@@ -0,0 +1,444 @@
+// Package transfers moves money between accounts. It is shared by the HTTP
+// and gRPC APIs, which only translate requests and errors. The caller is
+// taken from the identity and tenant on the context. Transfers record their
+// domain events in the outbox, in the same transaction as the balances.
+package transfers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/big"
+	"strings"
+	"time"
+
+	accountsdao "github.com/ashwin-m/transactions/daos/accounts"
+	"github.com/ashwin-m/transactions/daos/outbox"
+	transactionsdao "github.com/ashwin-m/transactions/daos/transactions"
+	"github.com/ashwin-m/transactions/middlewares/audit"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	accountsmodel "github.com/ashwin-m/transactions/models/accounts"
+	eventsmodel "github.com/ashwin-m/transactions/models/events"
+	transactionsmodel "github.com/ashwin-m/transactions/models/transactions"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/decimal"
+	"github.com/ashwin-m/transactions/utils/metrics"
+	"github.com/ashwin-m/transactions/utils/pgxiface"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	prec                                = 100
+	min_transaction_amount              = 0
+	min_account_balance_for_transaction = 0
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 100
+)
+
+const (
+	errSourceForbidden   = "you do not have access to the source account"
+	errAccountForbidden  = "you do not have access to this account"
+	errTransferForbidden = "you do not have access to this transfer"
+)
+
+// The errors returned by the service are *apperrors.Error values, which the
+// APIs render as they are. Callers that need to tell failures apart can
+// match them against these with errors.Is.
+var (
+	ErrInvalidRequest    = errors.New("invalid request")
+	ErrForbidden         = errors.New("forbidden")
+	ErrAccountNotFound   = errors.New("account not found")
+	ErrTransferNotFound  = errors.New("transfer not found")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrVersionConflict is returned when an account changed while the
+	// transfer was made. The transfer can be retried.
+	ErrVersionConflict = accountsdao.ErrVersionConflict
+)
+
+type TransferRequest struct {
+	SourceAccountId      int64
+	DestinationAccountId int64
+	// DestinationTenantId names the tenant of the destination account, the
+	// caller's own tenant when empty.
+	DestinationTenantId string
+	// Amount is a decimal string, parsed without losing precision.
+	Amount string
+}
+
+type TransferResult struct {
+	TransactionId       int64
+	DestinationTenantId string
+	Amount              float64
+}
+
+type ListRequest struct {
+	AccountId int64
+	// PageSize defaults to DefaultPageSize and is capped at MaxPageSize.
+	PageSize int
+	// BeforeId continues a listing after the last transfer of a page.
+	BeforeId int64
+}
+
+type Service interface {
+	Transfer(ctx context.Context, request TransferRequest) (TransferResult, error)
+	Get(ctx context.Context, id int64) (transactionsmodel.Transactions, error)
+	List(ctx context.Context, request ListRequest) ([]transactionsmodel.Transactions, error)
+}
+
+type service struct {
+	dbPool               pgxiface.PgxIface
+	accountsDao          accountsdao.Dao
+	transactionsDao      transactionsdao.Dao
+	outboxDao            outbox.Dao
+	crossTenantTransfers bool
+}
+
+// NewService builds the transfer service. Transfers to accounts of another
+// tenant are rejected unless crossTenantTransfers is set.
+func NewService(dbPool pgxiface.PgxIface, accountsDao accountsdao.Dao, transactionsDao transactionsdao.Dao, outboxDao outbox.Dao, crossTenantTransfers bool) Service {
+	return &service{
+		dbPool:               dbPool,
+		accountsDao:          accountsDao,
+		transactionsDao:      transactionsDao,
+		outboxDao:            outboxDao,
+		crossTenantTransfers: crossTenantTransfers,
+	}
+}
+
+// Transfer moves an amount out of an account the caller has access to, and
+// records and logs the outcome.
+func (s *service) Transfer(ctx context.Context, request TransferRequest) (result TransferResult, err error) {
+	start := time.Now()
+	// rejections are only recorded as events once the caller is known to
+	// have access to the source account
+	var sourceChecked bool
+	defer func() {
+		var errorCode string
+		if err != nil {
+			errorCode = strings.ToLower(string(apperrors.From(err).Code))
+		}
+		metrics.ObserveTransfer(errorCode, result.Amount)
+		logTransfer(ctx, request, result.Amount, errorCode, time.Since(start))
+
+		if err != nil && sourceChecked && apperrors.From(err).Code != apperrors.CodeInternal {
+			s.recordFailure(ctx, request, result, err)
+		}
+	}()
+
+	// big.Float also parses hexadecimal and infinity, which the numeric
+	// binding refuses and the gRPC API doesn't go through
+	amount, ok := new(big.Float).SetPrec(prec).SetString(request.Amount)
+	if !ok || !decimal.Valid(request.Amount) {
+		return result, apperrors.Validation(apperrors.FieldError{Field: "amount", Message: "must be a decimal number"}).Wrap(ErrInvalidRequest)
+	}
+	result.Amount, _ = amount.Float64()
+
+	if amount.Cmp(big.NewFloat(min_transaction_amount)) == -1 {
+		return result, apperrors.Validation(apperrors.FieldError{Field: "amount", Message: "must not be negative"}).Wrap(ErrInvalidRequest)
+	}
+
+	identity, _ := auth.FromContext(ctx)
+
+	// Both accounts belong to the caller's tenant unless the transfer names
+	// another one. Cross tenant transfers run with both tenants visible to
+	// the database session, see tenant.NewContext.
+	sourceTenant, _ := tenant.FromContext(ctx)
+	result.DestinationTenantId = sourceTenant
+	destinationCtx, txnCtx, destinationTxnCtx := ctx, ctx, ctx
+	if request.DestinationTenantId != "" && request.DestinationTenantId != sourceTenant {
+		if !s.crossTenantTransfers {
+			return result, apperrors.New(apperrors.CodeForbidden, "transfers between tenants are not allowed").Wrap(ErrForbidden)
+		}
+		if !tenant.Valid(request.DestinationTenantId) {
+			return result, apperrors.Validation(apperrors.FieldError{Field: "destination_tenant_id", Message: "is not a valid tenant id"}).Wrap(ErrInvalidRequest)
+		}
+
+		result.DestinationTenantId = request.DestinationTenantId
+		destinationCtx = tenant.NewContext(ctx, result.DestinationTenantId)
+		txnCtx = tenant.NewContext(ctx, sourceTenant, result.DestinationTenantId)
+		destinationTxnCtx = tenant.NewContext(ctx, result.DestinationTenantId, sourceTenant)
+	}
+
+	sourceAccount, err := s.accountsDao.GetById(ctx, request.SourceAccountId)
+	if errors.Is(err, pgx.ErrNoRows) && !identity.HasRole(auth.RoleAdmin) {
+		// A missing source account is reported like a foreign one so
+		// clients can't probe for account ids they don't own.
+		return result, apperrors.New(apperrors.CodeForbidden, errSourceForbidden).Wrap(ErrForbidden)
+	}
+	if err != nil {
+		return result, accountLookupFailed(err, request.SourceAccountId)
+	}
+
+	if !identity.CanAccess(sourceAccount.GetOwnerId()) {
+		return result, apperrors.New(apperrors.CodeForbidden, errSourceForbidden).Wrap(ErrForbidden)
+	}
+	sourceChecked = true
+
+	sourceAccountBalance := new(big.Float).SetPrec(prec).SetFloat64(sourceAccount.GetBalance())
+
+	err = validateSourceAccount(sourceAccount, amount)
+	if err != nil {
+		return result, err
+	}
+
+	destinationAccount, err := s.accountsDao.GetById(destinationCtx, request.DestinationAccountId)
+	if err != nil {
+		return result, accountLookupFailed(err, request.DestinationAccountId)
+	}
+
+	destinationAccountBalance := new(big.Float).SetPrec(prec).SetFloat64(destinationAccount.GetBalance())
+
+	txn, err := s.dbPool.Begin(txnCtx)
+	if err != nil {
+		return result, err
+	}
+
+	transactionId, err := s.transactionsDao.Create(txnCtx, txn, sourceAccount.GetId(), destinationAccount.GetId(), result.DestinationTenantId, result.Amount, identity.ClientID)
+	if err != nil {
+		txn.Rollback(ctx)
+		return result, err
+	}
+
+	err = s.outboxDao.Add(txnCtx, txn, eventsmodel.TypeTransferPosted, request.SourceAccountId, eventsmodel.TransferPosted{
+		TransferId:           transactionId,
+		SourceAccountId:      request.SourceAccountId,
+		DestinationTenantId:  result.DestinationTenantId,
+		DestinationAccountId: request.DestinationAccountId,
+		Amount:               result.Amount,
+		ClientId:             identity.ClientID,
+	})
+	if err != nil {
+		txn.Rollback(ctx)
+		return result, err
+	}
+
+	newSourceAccountBalance := big.NewFloat(0).Sub(sourceAccountBalance, amount)
+	newSourceAccountBalanceFloat, _ := newSourceAccountBalance.Float64()
+	updatedSourceAccount, err := s.accountsDao.UpdateBalance(txnCtx, txn, request.SourceAccountId, sourceAccount.GetVersion(), newSourceAccountBalanceFloat)
+	if err != nil {
+		txn.Rollback(ctx)
+		return result, updateBalanceFailed(err)
+	}
+
+	err = s.outboxDao.Add(txnCtx, txn, eventsmodel.TypeAccountBalanceChanged, request.SourceAccountId, eventsmodel.AccountBalanceChanged{
+		AccountId:       request.SourceAccountId,
+		TransferId:      transactionId,
+		PreviousBalance: sourceAccount.GetBalance(),
+		Balance:         newSourceAccountBalanceFloat,
+		Version:         updatedSourceAccount.GetVersion(),
+	})
+	if err != nil {
+		txn.Rollback(ctx)
+		return result, err
+	}
+
+	newDestinationAccountBalance := big.NewFloat(0).Add(destinationAccountBalance, amount)
+	newDestinationAccountBalanceFloat, _ := newDestinationAccountBalance.Float64()
+	updatedDestinationAccount, err := s.accountsDao.UpdateBalance(destinationTxnCtx, txn, request.DestinationAccountId, destinationAccount.GetVersion(), newDestinationAccountBalanceFloat)
+	if err != nil {
+		txn.Rollback(ctx)
+		return result, updateBalanceFailed(err)
+	}
+
+	// the event about the destination account belongs to its tenant
+	err = s.outboxDao.Add(destinationTxnCtx, txn, eventsmodel.TypeAccountBalanceChanged, request.DestinationAccountId, eventsmodel.AccountBalanceChanged{
+		AccountId:       request.DestinationAccountId,
+		TransferId:      transactionId,
+		PreviousBalance: destinationAccount.GetBalance(),
+		Balance:         newDestinationAccountBalanceFloat,
+		Version:         updatedDestinationAccount.GetVersion(),
+	})
+	if err != nil {
+		txn.Rollback(ctx)
+		return result, err
+	}
+
+	// UpdateBalance only returns the columns it changed
+	updatedSourceAccount.SetOwnerId(sourceAccount.GetOwnerId())
+	updatedDestinationAccount.SetOwnerId(destinationAccount.GetOwnerId())
+	audit.Changed(ctx, &sourceAccount, &updatedSourceAccount)
+	audit.Changed(ctx, &destinationAccount, &updatedDestinationAccount)
+
+	err = audit.Commit(ctx, txn)
+	if err != nil {
+		return result, err
+	}
+
+	result.TransactionId = transactionId
+	return result, nil
+}
+
+// recordFailure records a TransferFailed event. The transaction of the
+// transfer has been rolled back by then, so the event is written in one of
+// its own. A failure to record it is logged and doesn't change the outcome.
+func (s *service) recordFailure(ctx context.Context, request TransferRequest, result TransferResult, transferErr error) {
+	identity, _ := auth.FromContext(ctx)
+	appErr := apperrors.From(transferErr)
+
+	err := func() error {
+		txn, err := s.dbPool.Begin(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = s.outboxDao.Add(ctx, txn, eventsmodel.TypeTransferFailed, request.SourceAccountId, eventsmodel.TransferFailed{
+			SourceAccountId:      request.SourceAccountId,
+			DestinationTenantId:  result.DestinationTenantId,
+			DestinationAccountId: request.DestinationAccountId,
+			Amount:               result.Amount,
+			ClientId:             identity.ClientID,
+			Code:                 string(appErr.Code),
+			Reason:               appErr.Detail,
+		})
+		if err != nil {
+			txn.Rollback(ctx)
+			return err
+		}
+
+		return txn.Commit(ctx)
+	}()
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to record failed transfer", slog.Int64("source_account_id", request.SourceAccountId), slog.Any("error", err))
+	}
+}
+
+// Get returns a transfer into or out of an account the caller has access to.
+// As with accounts, only admins learn that a transfer doesn't exist.
+func (s *service) Get(ctx context.Context, id int64) (transactionsmodel.Transactions, error) {
+	identity, _ := auth.FromContext(ctx)
+
+	transaction, err := s.transactionsDao.GetById(ctx, id)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows) && !identity.HasRole(auth.RoleAdmin):
+		return transaction, apperrors.New(apperrors.CodeForbidden, errTransferForbidden).Wrap(ErrForbidden)
+	case errors.Is(err, pgx.ErrNoRows):
+		return transaction, apperrors.Newf(apperrors.CodeTransferNotFound, "transfer %d was not found", id).Wrap(ErrTransferNotFound)
+	case err != nil:
+		return transaction, err
+	}
+
+	if identity.HasRole(auth.RoleAdmin) {
+		return transaction, nil
+	}
+
+	// The caller sees the transfer through whichever side of it is in their
+	// tenant.
+	activeTenant, _ := tenant.FromContext(ctx)
+	for _, side := range []struct {
+		tenantId  string
+		accountId int64
+	}{
+		{transaction.GetTenantId(), transaction.GetSourceAccountId()},
+		{transaction.GetDestinationTenantId(), transaction.GetDestinationAccountId()},
+	} {
+		if side.tenantId != activeTenant {
+			continue
+		}
+
+		account, err := s.accountsDao.GetById(ctx, side.accountId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return transactionsmodel.Transactions{}, err
+		}
+		if identity.CanAccess(account.GetOwnerId()) {
+			return transaction, nil
+		}
+	}
+
+	return transactionsmodel.Transactions{}, apperrors.New(apperrors.CodeForbidden, errTransferForbidden).Wrap(ErrForbidden)
+}
+
+// List returns the transfers into and out of an account the caller has
+// access to, newest first.
+func (s *service) List(ctx context.Context, request ListRequest) ([]transactionsmodel.Transactions, error) {
+	if request.PageSize < 0 {
+		return nil, apperrors.Validation(apperrors.FieldError{Field: "page_size", Message: "must not be negative"}).Wrap(ErrInvalidRequest)
+	}
+	pageSize := PageSize(request.PageSize)
+
+	identity, _ := auth.FromContext(ctx)
+
+	account, err := s.accountsDao.GetById(ctx, request.AccountId)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows) && !identity.HasRole(auth.RoleAdmin):
+		return nil, apperrors.New(apperrors.CodeForbidden, errAccountForbidden).Wrap(ErrForbidden)
+	case err != nil:
+		return nil, accountLookupFailed(err, request.AccountId)
+	}
+
+	if !identity.CanAccess(account.GetOwnerId()) {
+		return nil, apperrors.New(apperrors.CodeForbidden, errAccountForbidden).Wrap(ErrForbidden)
+	}
+
+	return s.transactionsDao.ListByAccount(ctx, request.AccountId, request.BeforeId, pageSize)
+}
+
+// PageSize is the number of transfers List returns for a requested page
+// size. Fewer means the listing is complete.
+func PageSize(requested int) int {
+	switch {
+	case requested <= 0:
+		return DefaultPageSize
+	case requested > MaxPageSize:
+		return MaxPageSize
+	}
+
+	return requested
+}
+
+// accountLookupFailed tells a missing account apart from a failed lookup,
+// which must not be reported as one.
+func accountLookupFailed(err error, accountId int64) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apperrors.Newf(apperrors.CodeAccountNotFound, "account %d was not found", accountId).Wrap(ErrAccountNotFound)
+	}
+
+	return err
+}
+
+func updateBalanceFailed(err error) error {
+	if errors.Is(err, accountsdao.ErrVersionConflict) {
+		return apperrors.New(apperrors.CodeVersionConflict, err.Error()).Wrap(ErrVersionConflict)
+	}
+
+	return err
+}
+
+func logTransfer(ctx context.Context, request TransferRequest, amount float64, errorCode string, latency time.Duration) {
+	outcome := metrics.OutcomeSuccess
+	level := slog.LevelInfo
+	if errorCode != "" {
+		outcome = metrics.OutcomeFailure
+		level = slog.LevelWarn
+	}
+
+	slog.LogAttrs(ctx, level, "transfer",
+		slog.Int64("source_account_id", request.SourceAccountId),
+		slog.Int64("destination_account_id", request.DestinationAccountId),
+		slog.Float64("amount", amount),
+		slog.String("outcome", outcome),
+		slog.String("error_code", errorCode),
+		slog.Float64("latency_ms", float64(latency.Microseconds())/1000),
+	)
+}
+
+func validateSourceAccount(sourceAccount accountsmodel.Accounts, transactionAmount *big.Float) error {
+	sourceAccountBalanceFloat := sourceAccount.GetBalance()
+	sourceAccountBalance := new(big.Float).SetPrec(prec).SetFloat64(sourceAccountBalanceFloat)
+
+	if sourceAccountBalance.Cmp(transactionAmount) == -1 {
+		return apperrors.New(apperrors.CodeInsufficientFunds, "account balance is less than transaction").Wrap(ErrInsufficientFunds)
+	}
+
+	if sourceAccountBalance.Cmp(big.NewFloat(min_account_balance_for_transaction)) == -1 {
+		return apperrors.New(apperrors.CodeInsufficientFunds, "account balance is less than minimum amount for transactions").Wrap(ErrInsufficientFunds)
+	}
+
+	return nil
+}
@@ -0,0 +1,332 @@
+package transfers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	accountsdao "github.com/ashwin-m/transactions/daos/accounts"
+	accountsdaomocks "github.com/ashwin-m/transactions/daos/accounts/mocks"
+	"github.com/ashwin-m/transactions/daos/memory"
+	outboxdaomocks "github.com/ashwin-m/transactions/daos/outbox/mocks"
+	transactionsdaomocks "github.com/ashwin-m/transactions/daos/transactions/mocks"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	accountsmodel "github.com/ashwin-m/transactions/models/accounts"
+	eventsmodel "github.com/ashwin-m/transactions/models/events"
+	transactionsmodel "github.com/ashwin-m/transactions/models/transactions"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	adminIdentity  = auth.Identity{ClientID: "admin", Roles: []string{auth.RoleAdmin}}
+	clientIdentity = auth.Identity{ClientID: "client-1", Roles: []string{auth.RoleRead, auth.RoleTransfer}}
+)
+
+func contextFor(identity auth.Identity) context.Context {
+	return tenant.NewContext(auth.NewContext(context.Background(), identity), tenant.Default)
+}
+
+func account(id int64, balance float64, version int64, ownerId string) accountsmodel.Accounts {
+	a := accountsmodel.Accounts{}
+	a.SetId(id)
+	a.SetBalance(balance)
+	a.SetVersion(version)
+	a.SetOwnerId(ownerId)
+	return a
+}
+
+func code(err error) apperrors.Code {
+	return apperrors.From(err).Code
+}
+
+func TestTransfer(t *testing.T) {
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(account(123, 300.5, 1, "client-1"), nil)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, int64(456)).Return(account(456, 200, 4, "client-2"), nil)
+	mockAccountsDao.EXPECT().UpdateBalance(mock.Anything, mock.Anything, int64(123), int64(1), 200.25).Return(account(123, 200.25, 2, "client-1"), nil)
+	mockAccountsDao.EXPECT().UpdateBalance(mock.Anything, mock.Anything, int64(456), int64(4), 300.25).Return(account(456, 300.25, 5, "client-2"), nil)
+
+	mockTransactionsDao := transactionsdaomocks.NewDao(t)
+	mockTransactionsDao.EXPECT().Create(mock.Anything, mock.Anything, int64(123), int64(456), tenant.Default, 100.25, "client-1").Return(9, nil)
+
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeTransferPosted, int64(123), eventsmodel.TransferPosted{
+		TransferId: 9, SourceAccountId: 123, DestinationTenantId: tenant.Default, DestinationAccountId: 456, Amount: 100.25, ClientId: "client-1",
+	}).Return(nil)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeAccountBalanceChanged, int64(123), eventsmodel.AccountBalanceChanged{
+		AccountId: 123, TransferId: 9, PreviousBalance: 300.5, Balance: 200.25, Version: 2,
+	}).Return(nil)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeAccountBalanceChanged, int64(456), eventsmodel.AccountBalanceChanged{
+		AccountId: 456, TransferId: 9, PreviousBalance: 200, Balance: 300.25, Version: 5,
+	}).Return(nil)
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectCommit()
+
+	service := NewService(mockDB, mockAccountsDao, mockTransactionsDao, mockOutboxDao, false)
+	result, err := service.Transfer(contextFor(clientIdentity), TransferRequest{SourceAccountId: 123, DestinationAccountId: 456, Amount: "100.25"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, TransferResult{TransactionId: 9, DestinationTenantId: tenant.Default, Amount: 100.25}, result)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestTransfer_InvalidAmount(t *testing.T) {
+	service := NewService(nil, accountsdaomocks.NewDao(t), transactionsdaomocks.NewDao(t), outboxdaomocks.NewDao(t), false)
+
+	for _, amount := range []string{"", "ten", "-1"} {
+		_, err := service.Transfer(contextFor(clientIdentity), TransferRequest{SourceAccountId: 123, DestinationAccountId: 456, Amount: amount})
+
+		assert.ErrorIs(t, err, ErrInvalidRequest, amount)
+		assert.Equal(t, "amount", apperrors.From(err).Fields[0].Field, amount)
+	}
+}
+
+func TestTransfer_CrossTenantNotAllowed(t *testing.T) {
+	service := NewService(nil, accountsdaomocks.NewDao(t), transactionsdaomocks.NewDao(t), outboxdaomocks.NewDao(t), false)
+
+	_, err := service.Transfer(contextFor(clientIdentity), TransferRequest{SourceAccountId: 123, DestinationAccountId: 456, DestinationTenantId: "acme", Amount: "1"})
+
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.Equal(t, "transfers between tenants are not allowed", apperrors.From(err).Detail)
+}
+
+func TestTransfer_SourceAccountOfAnotherClient(t *testing.T) {
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(account(123, 300, 1, "client-2"), nil)
+
+	service := NewService(nil, mockAccountsDao, transactionsdaomocks.NewDao(t), outboxdaomocks.NewDao(t), false)
+	_, err := service.Transfer(contextFor(clientIdentity), TransferRequest{SourceAccountId: 123, DestinationAccountId: 456, Amount: "1"})
+
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.Equal(t, apperrors.CodeForbidden, code(err))
+}
+
+func TestTransfer_MissingSourceAccount(t *testing.T) {
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(accountsmodel.Accounts{}, pgx.ErrNoRows)
+
+	service := NewService(nil, mockAccountsDao, transactionsdaomocks.NewDao(t), outboxdaomocks.NewDao(t), false)
+
+	// clients can't tell a missing account from someone else's
+	_, err := service.Transfer(contextFor(clientIdentity), TransferRequest{SourceAccountId: 123, DestinationAccountId: 456, Amount: "1"})
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	_, err = service.Transfer(contextFor(adminIdentity), TransferRequest{SourceAccountId: 123, DestinationAccountId: 456, Amount: "1"})
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+	assert.Equal(t, "account 123 was not found", apperrors.From(err).Detail)
+}
+
+func TestTransfer_InsufficientFunds(t *testing.T) {
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(account(123, 99.99, 1, "client-1"), nil)
+
+	// the rejection is recorded in a transaction of its own
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeTransferFailed, int64(123), eventsmodel.TransferFailed{
+		SourceAccountId: 123, DestinationTenantId: tenant.Default, DestinationAccountId: 456, Amount: 100, ClientId: "client-1",
+		Code: string(apperrors.CodeInsufficientFunds), Reason: "account balance is less than transaction",
+	}).Return(nil)
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectCommit()
+
+	service := NewService(mockDB, mockAccountsDao, transactionsdaomocks.NewDao(t), mockOutboxDao, false)
+	result, err := service.Transfer(contextFor(clientIdentity), TransferRequest{SourceAccountId: 123, DestinationAccountId: 456, Amount: "100"})
+
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+	assert.Equal(t, apperrors.CodeInsufficientFunds, code(err))
+	assert.Equal(t, int64(0), result.TransactionId)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestTransfer_FailureIsReportedWhenItCantBeRecorded(t *testing.T) {
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(account(123, 99.99, 1, "client-1"), nil)
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin().WillReturnError(errors.New("connection reset"))
+
+	service := NewService(mockDB, mockAccountsDao, transactionsdaomocks.NewDao(t), outboxdaomocks.NewDao(t), false)
+	_, err := service.Transfer(contextFor(clientIdentity), TransferRequest{SourceAccountId: 123, DestinationAccountId: 456, Amount: "100"})
+
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestTransfer_VersionConflictRollsBack(t *testing.T) {
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(account(123, 300, 1, "client-1"), nil)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, int64(456)).Return(account(456, 0, 2, "client-2"), nil)
+	mockAccountsDao.EXPECT().UpdateBalance(mock.Anything, mock.Anything, int64(123), int64(1), float64(200)).Return(accountsmodel.Accounts{}, accountsdao.ErrVersionConflict)
+
+	mockTransactionsDao := transactionsdaomocks.NewDao(t)
+	mockTransactionsDao.EXPECT().Create(mock.Anything, mock.Anything, int64(123), int64(456), tenant.Default, float64(100), "client-1").Return(9, nil)
+
+	mockOutboxDao := outboxdaomocks.NewDao(t)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeTransferPosted, int64(123), mock.Anything).Return(nil)
+	mockOutboxDao.EXPECT().Add(mock.Anything, mock.Anything, eventsmodel.TypeTransferFailed, int64(123), mock.Anything).Return(nil)
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectRollback()
+	mockDB.ExpectBegin()
+	mockDB.ExpectCommit()
+
+	service := NewService(mockDB, mockAccountsDao, mockTransactionsDao, mockOutboxDao, false)
+	_, err := service.Transfer(contextFor(clientIdentity), TransferRequest{SourceAccountId: 123, DestinationAccountId: 456, Amount: "100"})
+
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	assert.Equal(t, apperrors.CodeVersionConflict, code(err))
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestTransfer_DaoErrorsAreInternal(t *testing.T) {
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(accountsmodel.Accounts{}, errors.New("connection reset"))
+
+	service := NewService(nil, mockAccountsDao, transactionsdaomocks.NewDao(t), outboxdaomocks.NewDao(t), false)
+	_, err := service.Transfer(contextFor(adminIdentity), TransferRequest{SourceAccountId: 123, DestinationAccountId: 456, Amount: "1"})
+
+	assert.EqualError(t, err, "connection reset")
+	assert.Equal(t, apperrors.CodeInternal, code(err))
+}
+
+func TestGet_ThroughDestinationAccount(t *testing.T) {
+	transaction := transactionsmodel.Transactions{}
+	transaction.SetId(9)
+	transaction.SetSourceAccountId(123)
+	transaction.SetDestinationAccountId(456)
+	transaction.SetTenantId("acme")
+	transaction.SetDestinationTenantId(tenant.Default)
+
+	mockTransactionsDao := transactionsdaomocks.NewDao(t)
+	mockTransactionsDao.EXPECT().GetById(mock.Anything, int64(9)).Return(transaction, nil)
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, int64(456)).Return(account(456, 0, 1, "client-1"), nil)
+
+	service := NewService(nil, mockAccountsDao, mockTransactionsDao, outboxdaomocks.NewDao(t), false)
+	got, err := service.Get(contextFor(clientIdentity), 9)
+
+	assert.NoError(t, err)
+	assert.Equal(t, transaction, got)
+}
+
+func TestGet_NotFound(t *testing.T) {
+	mockTransactionsDao := transactionsdaomocks.NewDao(t)
+	mockTransactionsDao.EXPECT().GetById(mock.Anything, int64(9)).Return(transactionsmodel.Transactions{}, pgx.ErrNoRows)
+
+	service := NewService(nil, accountsdaomocks.NewDao(t), mockTransactionsDao, outboxdaomocks.NewDao(t), false)
+
+	_, err := service.Get(contextFor(clientIdentity), 9)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	_, err = service.Get(contextFor(adminIdentity), 9)
+	assert.ErrorIs(t, err, ErrTransferNotFound)
+}
+
+func TestList_CapsPageSize(t *testing.T) {
+	mockAccountsDao := accountsdaomocks.NewDao(t)
+	mockAccountsDao.EXPECT().GetById(mock.Anything, int64(123)).Return(account(123, 0, 1, "client-1"), nil)
+	mockTransactionsDao := transactionsdaomocks.NewDao(t)
+	mockTransactionsDao.EXPECT().ListByAccount(mock.Anything, int64(123), int64(0), MaxPageSize).Return(nil, nil)
+
+	service := NewService(nil, mockAccountsDao, mockTransactionsDao, outboxdaomocks.NewDao(t), false)
+	_, err := service.List(contextFor(clientIdentity), ListRequest{AccountId: 123, PageSize: 1000})
+
+	assert.NoError(t, err)
+}
+
+func TestList_NegativePageSize(t *testing.T) {
+	service := NewService(nil, accountsdaomocks.NewDao(t), transactionsdaomocks.NewDao(t), outboxdaomocks.NewDao(t), false)
+
+	_, err := service.List(contextFor(clientIdentity), ListRequest{AccountId: 123, PageSize: -1})
+
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+// Concurrent transfers between the accounts of the memory store either
+// commit or fail as a whole, so money is conserved and every balance is its
+// initial one plus the transfers that committed.
+func TestTransfer_ConcurrentlyInMemory(t *testing.T) {
+	store := memory.NewStore()
+	accountsDao := memory.NewAccountsDao(store)
+	transactionsDao := memory.NewTransactionsDao(store)
+	ctx := contextFor(adminIdentity)
+
+	txn, err := store.Begin(ctx)
+	require.NoError(t, err)
+	for id := int64(1); id <= 3; id++ {
+		_, err = accountsDao.Create(ctx, txn, id, 100, "client-1")
+		require.NoError(t, err)
+	}
+	require.NoError(t, txn.Commit(ctx))
+
+	service := NewService(store, accountsDao, transactionsDao, memory.NewOutboxDao(store), false)
+
+	var mu sync.Mutex
+	outcomes := map[string]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < 60; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var pgErr *pgconn.PgError
+			_, err := service.Transfer(ctx, TransferRequest{
+				SourceAccountId:      int64(i%3 + 1),
+				DestinationAccountId: int64((i+1)%3 + 1),
+				Amount:               fmt.Sprint(10 + i%40),
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				outcomes["committed"]++
+			case errors.Is(err, ErrVersionConflict), errors.Is(err, ErrInsufficientFunds):
+				outcomes["rejected"]++
+			case errors.As(err, &pgErr) && pgErr.Code == pgerrcode.DeadlockDetected:
+				// as with Postgres, transfers around the cycle of accounts
+				// may wait for each other
+				outcomes["rejected"]++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 60, outcomes["committed"]+outcomes["rejected"])
+	assert.Positive(t, outcomes["committed"])
+
+	var total float64
+	var transfers int
+	for id := int64(1); id <= 3; id++ {
+		account, err := accountsDao.GetById(ctx, id)
+		require.NoError(t, err)
+		net, err := transactionsDao.NetByAccount(ctx, id, time.Now().Add(time.Second))
+		require.NoError(t, err)
+		listed, err := transactionsDao.ListByAccount(ctx, id, 0, 1000)
+		require.NoError(t, err)
+
+		assert.InDelta(t, 100+net, account.GetBalance(), 1e-9)
+		total += account.GetBalance()
+		transfers += len(listed)
+	}
+	assert.InDelta(t, 300, total, 1e-9)
+	// each committed transfer is listed by both of its accounts
+	assert.Equal(t, 2*outcomes["committed"], transfers)
+}
@@ -0,0 +1,105 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errBlockedAddress is returned when an endpoint resolves to an address
+// deliveries may not be sent to.
+var errBlockedAddress = errors.New("endpoint address is not allowed")
+
+// reserved are the ranges, beyond the private, loopback and link-local ones
+// of net/netip, that aren't reachable on the internet.
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// NewClient builds the client deliveries are sent with. It only connects to
+// public addresses, checked once the endpoint is resolved so a name can't
+// point somewhere else between the check and the connection, ignores proxy
+// settings and doesn't follow redirects, so subscriptions can't be used to
+// reach the network the service runs in.
+func NewClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !Public(addrPort.Addr()) {
+				return errBlockedAddress
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Public reports whether addr is reachable on the internet, that is neither
+// private, loopback, link-local, multicast nor otherwise reserved.
+func Public(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reserved {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// publicHost reports whether host, the host of an endpoint without its port,
+// may be public. Names are only checked once they are resolved, when
+// delivering, except for localhost.
+func publicHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return true
+	}
+
+	return Public(addr)
+}
+
+// describe turns the error of an attempt into what is recorded on it, which
+// subscribers can read back. Errors of the transport are summed up so they
+// don't tell more about the network than whether the endpoint answered.
+func describe(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errBlockedAddress):
+		return errBlockedAddress.Error()
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "endpoint timed out"
+	default:
+		return "unable to reach the endpoint"
+	}
+}
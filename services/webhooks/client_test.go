@@ -0,0 +1,96 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	webhooksdao "github.com/ashwin-m/transactions/daos/webhooks"
+	webhooksdaomocks "github.com/ashwin-m/transactions/daos/webhooks/mocks"
+	webhooksmodel "github.com/ashwin-m/transactions/models/webhooks"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPublic(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"93.184.216.34":        true,
+		"2606:2800:220:1::":    true,
+		"127.0.0.1":            false,
+		"10.0.0.1":             false,
+		"172.16.5.4":           false,
+		"192.168.1.1":          false,
+		"169.254.169.254":      false,
+		"100.64.0.1":           false,
+		"0.0.0.0":              false,
+		"255.255.255.255":      false,
+		"::1":                  false,
+		"fe80::1":              false,
+		"fd00:ec2::254":        false,
+		"::ffff:127.0.0.1":     false,
+		"64:ff9b::a9fe:a9fe":   false,
+		"ff02::1":              false,
+		"::ffff:93.184.216.34": true,
+	} {
+		assert.Equal(t, public, Public(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestNewClient_RefusesPrivateAddresses(t *testing.T) {
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("a private endpoint was reached")
+	}))
+	defer endpoint.Close()
+
+	resp, err := NewClient().Post(endpoint.URL, "application/json", nil)
+	if resp != nil {
+		resp.Body.Close()
+	}
+
+	assert.ErrorIs(t, err, errBlockedAddress)
+}
+
+func TestNewClient_DoesNotFollowRedirects(t *testing.T) {
+	client := NewClient()
+	// only the redirect policy is under test here
+	client.Transport = http.DefaultTransport
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/hooks" {
+			t.Error("a redirect was followed")
+		}
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+	}))
+	defer endpoint.Close()
+
+	resp, err := client.Post(endpoint.URL+"/hooks", "application/json", nil)
+
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+}
+
+func TestDeliverOnce_RecordsTransportErrorsVaguely(t *testing.T) {
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	endpoint.Close()
+
+	mockDao := webhooksdaomocks.NewDao(t)
+	mockDao.EXPECT().Due(mock.Anything, mock.Anything, DefaultBatchSize).Return([]webhooksdao.Pending{pending(1, endpoint.URL, 0)}, nil)
+	mockDao.EXPECT().RecordAttempt(mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, tx pgx.Tx, delivery webhooksmodel.Deliveries, attempt webhooksmodel.Attempts) error {
+			assert.Equal(t, 0, attempt.GetStatusCode())
+			assert.Equal(t, "unable to reach the endpoint", attempt.GetError())
+			return nil
+		})
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectCommit()
+
+	_, err := deliverer(mockDB, mockDao).DeliverOnce(context.Background())
+
+	assert.NoError(t, err)
+}
@@ -0,0 +1,296 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	webhooksdao "github.com/ashwin-m/transactions/daos/webhooks"
+	webhooksmodel "github.com/ashwin-m/transactions/models/webhooks"
+	"github.com/ashwin-m/transactions/utils/jobs"
+	"github.com/ashwin-m/transactions/utils/metrics"
+	"github.com/ashwin-m/transactions/utils/pgxiface"
+)
+
+const (
+	DefaultInterval    = time.Second
+	DefaultBatchSize   = 20
+	DefaultTimeout     = 10 * time.Second
+	DefaultRetryBase   = 30 * time.Second
+	DefaultMaxAttempts = 8
+
+	// maxBackoff caps the wait between two attempts.
+	maxBackoff = 6 * time.Hour
+
+	// maxResponseBody is how much of a response is read before the
+	// connection is reused, the body itself is ignored.
+	maxResponseBody = 64 << 10
+)
+
+// The headers sent with every delivery.
+const (
+	// IdHeader is the id of the event, the same across redeliveries, so
+	// receivers can drop duplicates.
+	IdHeader        = "Webhook-Id"
+	EventHeader     = "Webhook-Event"
+	SignatureHeader = "Webhook-Signature"
+)
+
+type DelivererOptions struct {
+	// Interval is how long the deliverer waits between polls for due
+	// deliveries, DefaultInterval when zero.
+	Interval time.Duration
+	// BatchSize is the number of deliveries attempted per poll,
+	// DefaultBatchSize when zero.
+	BatchSize int
+	// Timeout bounds every attempt, DefaultTimeout when zero.
+	Timeout time.Duration
+	// RetryBase is the wait after the first failed attempt, doubling with
+	// each one after it. DefaultRetryBase when zero.
+	RetryBase time.Duration
+	// MaxAttempts is the number of attempts after which a delivery is dead,
+	// DefaultMaxAttempts when zero.
+	MaxAttempts int
+	// Client sends the deliveries, NewClient() when nil.
+	Client *http.Client
+}
+
+type Deliverer struct {
+	dbPool  pgxiface.PgxIface
+	dao     webhooksdao.Dao
+	job     *jobs.Job
+	options DelivererOptions
+	now     func() time.Time
+}
+
+// NewDeliverer builds a deliverer reporting its progress to job, which may be
+// nil.
+func NewDeliverer(dbPool pgxiface.PgxIface, dao webhooksdao.Dao, job *jobs.Job, options DelivererOptions) *Deliverer {
+	if options.Interval <= 0 {
+		options.Interval = DefaultInterval
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultBatchSize
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.RetryBase <= 0 {
+		options.RetryBase = DefaultRetryBase
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = DefaultMaxAttempts
+	}
+	if options.Client == nil {
+		options.Client = NewClient()
+	}
+
+	return &Deliverer{
+		dbPool:  dbPool,
+		dao:     dao,
+		job:     job,
+		options: options,
+		now:     time.Now,
+	}
+}
+
+// Run delivers webhooks until ctx is done. A full batch is followed by the
+// next one right away, so a backlog drains without waiting for the interval.
+func (d *Deliverer) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		attempted, err := d.runOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil && attempted == d.options.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Deliverer) runOnce(ctx context.Context) (int, error) {
+	if d.job != nil {
+		d.job.Started()
+	}
+
+	attempted, err := d.DeliverOnce(ctx)
+
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to deliver webhooks", slog.Any("error", err))
+		if d.job != nil {
+			d.job.Failed(err)
+		}
+		return attempted, err
+	}
+	if d.job != nil {
+		d.job.Succeeded()
+	}
+
+	return attempted, nil
+}
+
+// DeliverOnce attempts one batch of due deliveries, concurrently, and returns
+// how many were attempted. Deliveries being attempted by another deliverer
+// are left to it. An endpoint failing doesn't fail the batch, it only
+// reschedules the delivery.
+func (d *Deliverer) DeliverOnce(ctx context.Context) (int, error) {
+	txn, err := d.dbPool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer txn.Rollback(ctx)
+
+	due, err := d.dao.Due(ctx, txn, d.options.BatchSize)
+	if err != nil || len(due) == 0 {
+		return 0, err
+	}
+
+	attempts := make([]webhooksmodel.Attempts, len(due))
+	var wg sync.WaitGroup
+	for i, pending := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attempts[i] = d.attempt(ctx, pending)
+		}()
+	}
+	wg.Wait()
+
+	for i, pending := range due {
+		delivery := d.advance(pending.Delivery, attempts[i])
+		metrics.WebhookAttempted(delivery.GetEventType(), delivery.GetStatus())
+
+		err = d.dao.RecordAttempt(ctx, txn, delivery, attempts[i])
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = txn.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(due), nil
+}
+
+// attempt POSTs a delivery to the endpoint of its subscription.
+func (d *Deliverer) attempt(ctx context.Context, pending webhooksdao.Pending) webhooksmodel.Attempts {
+	attempt := webhooksmodel.Attempts{}
+	attempt.SetDeliveryId(pending.Delivery.GetId())
+	attempt.SetAttemptedAt(d.now())
+
+	ctx, cancel := context.WithTimeout(ctx, d.options.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	statusCode, err := d.post(ctx, pending)
+	attempt.SetDuration(time.Since(start))
+	attempt.SetStatusCode(statusCode)
+	if err != nil {
+		attempt.SetError(err.Error())
+	}
+
+	return attempt
+}
+
+func (d *Deliverer) post(ctx context.Context, pending webhooksdao.Pending) (int, error) {
+	body := pending.Delivery.GetPayload()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pending.Subscription.GetUrl(), bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdHeader, strconv.FormatInt(pending.Delivery.GetEventId(), 10))
+	req.Header.Set(EventHeader, pending.Delivery.GetEventType())
+	req.Header.Set(SignatureHeader, Sign(pending.Subscription.GetSecret(), d.now(), body))
+
+	resp, err := d.options.Client.Do(req)
+	if err != nil {
+		slog.WarnContext(ctx, "unable to deliver webhook",
+			slog.Int64("delivery_id", pending.Delivery.GetId()),
+			slog.Any("error", err),
+		)
+		return 0, errors.New(describe(err))
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// advance returns the state of a delivery after an attempt: delivered when
+// the endpoint accepted it, dead once it has been attempted MaxAttempts
+// times, and otherwise pending until its next attempt.
+func (d *Deliverer) advance(delivery webhooksmodel.Deliveries, attempt webhooksmodel.Attempts) webhooksmodel.Deliveries {
+	delivery.SetAttempts(delivery.GetAttempts() + 1)
+	delivery.SetLastStatusCode(attempt.GetStatusCode())
+	delivery.SetLastError(attempt.GetError())
+
+	switch {
+	case attempt.GetError() == "":
+		deliveredAt := attempt.GetAttemptedAt()
+		delivery.SetStatus(webhooksmodel.StatusDelivered)
+		delivery.SetDeliveredAt(&deliveredAt)
+	case delivery.GetAttempts() >= d.options.MaxAttempts:
+		delivery.SetStatus(webhooksmodel.StatusDead)
+	default:
+		delivery.SetNextAttemptAt(attempt.GetAttemptedAt().Add(Backoff(d.options.RetryBase, delivery.GetAttempts())))
+	}
+
+	return delivery
+}
+
+// Backoff is the wait before the next attempt at a delivery that failed
+// attempts times: base, doubling with each attempt, up to six hours.
+func Backoff(base time.Duration, attempts int) time.Duration {
+	backoff := base
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+
+	return min(backoff, maxBackoff)
+}
+
+// Sign computes the Webhook-Signature header of a body sent at timestamp:
+// the unix timestamp and the hex HMAC-SHA256, keyed with the secret of the
+// subscription, of the timestamp and the body joined by a dot. Receivers
+// recompute it to check the body came from us, and reject old timestamps to
+// stop replays.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
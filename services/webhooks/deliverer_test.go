@@ -0,0 +1,141 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	webhooksdao "github.com/ashwin-m/transactions/daos/webhooks"
+	webhooksdaomocks "github.com/ashwin-m/transactions/daos/webhooks/mocks"
+	webhooksmodel "github.com/ashwin-m/transactions/models/webhooks"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var now = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func pending(id int64, url string, attempts int) webhooksdao.Pending {
+	p := webhooksdao.Pending{}
+	p.Delivery.SetId(id)
+	p.Delivery.SetEventId(100 + id)
+	p.Delivery.SetEventType("transfer.posted")
+	p.Delivery.SetPayload([]byte(`{"id":7}`))
+	p.Delivery.SetStatus(webhooksmodel.StatusPending)
+	p.Delivery.SetAttempts(attempts)
+	p.Subscription.SetUrl(url)
+	p.Subscription.SetSecret("whsec_test")
+	return p
+}
+
+func deliverer(mockDB pgxmock.PgxPoolIface, mockDao *webhooksdaomocks.Dao) *Deliverer {
+	// the test endpoints listen on loopback, which NewClient refuses
+	d := NewDeliverer(mockDB, mockDao, nil, DelivererOptions{RetryBase: time.Minute, MaxAttempts: 3, Client: http.DefaultClient})
+	d.now = func() time.Time { return now }
+	return d
+}
+
+func TestDeliverOnce_SignsAndDelivers(t *testing.T) {
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, `{"id":7}`, string(body))
+		assert.Equal(t, "101", r.Header.Get(IdHeader))
+		assert.Equal(t, "transfer.posted", r.Header.Get(EventHeader))
+		assert.Equal(t, Sign("whsec_test", now, body), r.Header.Get(SignatureHeader))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer endpoint.Close()
+
+	mockDao := webhooksdaomocks.NewDao(t)
+	mockDao.EXPECT().Due(mock.Anything, mock.Anything, DefaultBatchSize).Return([]webhooksdao.Pending{pending(1, endpoint.URL, 0)}, nil)
+	mockDao.EXPECT().RecordAttempt(mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, tx pgx.Tx, delivery webhooksmodel.Deliveries, attempt webhooksmodel.Attempts) error {
+			assert.Equal(t, webhooksmodel.StatusDelivered, delivery.GetStatus())
+			assert.Equal(t, 1, delivery.GetAttempts())
+			assert.Equal(t, &now, delivery.GetDeliveredAt())
+			assert.Equal(t, http.StatusNoContent, attempt.GetStatusCode())
+			assert.Empty(t, attempt.GetError())
+			return nil
+		})
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectCommit()
+
+	count, err := deliverer(mockDB, mockDao).DeliverOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestDeliverOnce_RetriesThenGivesUp(t *testing.T) {
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer endpoint.Close()
+
+	mockDao := webhooksdaomocks.NewDao(t)
+	mockDao.EXPECT().Due(mock.Anything, mock.Anything, DefaultBatchSize).Return([]webhooksdao.Pending{
+		pending(1, endpoint.URL, 1),
+		pending(2, endpoint.URL, 2),
+	}, nil)
+	recorded := map[int64]webhooksmodel.Deliveries{}
+	mockDao.EXPECT().RecordAttempt(mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, tx pgx.Tx, delivery webhooksmodel.Deliveries, attempt webhooksmodel.Attempts) error {
+			assert.Equal(t, http.StatusServiceUnavailable, attempt.GetStatusCode())
+			assert.Equal(t, "endpoint responded 503 Service Unavailable", attempt.GetError())
+			recorded[delivery.GetId()] = delivery
+			return nil
+		})
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectCommit()
+
+	count, err := deliverer(mockDB, mockDao).DeliverOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	retried := recorded[1]
+	assert.Equal(t, webhooksmodel.StatusPending, retried.GetStatus())
+	assert.Equal(t, 2, retried.GetAttempts())
+	assert.Equal(t, now.Add(2*time.Minute), retried.GetNextAttemptAt())
+	assert.Equal(t, http.StatusServiceUnavailable, retried.GetLastStatusCode())
+
+	dead := recorded[2]
+	assert.Equal(t, webhooksmodel.StatusDead, dead.GetStatus())
+	assert.Equal(t, 3, dead.GetAttempts())
+	assert.Nil(t, dead.GetDeliveredAt())
+}
+
+func TestDeliverOnce_NothingDue(t *testing.T) {
+	mockDao := webhooksdaomocks.NewDao(t)
+	mockDao.EXPECT().Due(mock.Anything, mock.Anything, DefaultBatchSize).Return(nil, nil)
+
+	mockDB, _ := pgxmock.NewPool()
+	mockDB.ExpectBegin()
+	mockDB.ExpectRollback()
+
+	count, err := deliverer(mockDB, mockDao).DeliverOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(30*time.Second, 1))
+	assert.Equal(t, 4*time.Minute, Backoff(30*time.Second, 4))
+	assert.Equal(t, 6*time.Hour, Backoff(30*time.Second, 40))
+}
+
+func TestSign(t *testing.T) {
+	// computed independently with openssl dgst -sha256 -hmac
+	assert.Equal(t, "t=1767323045,v1=1d1c36625e88ee13377929e33384bff4c48ef7a1b44a82dca8c936006436b493", Sign("whsec_test", now, []byte(`{"id":7}`)))
+}
@@ -0,0 +1,32 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+
+	webhooksdao "github.com/ashwin-m/transactions/daos/webhooks"
+	"github.com/ashwin-m/transactions/services/events"
+	"github.com/ashwin-m/transactions/utils/tenant"
+)
+
+// sink queues the events handed over by the relay for delivery.
+type sink struct {
+	dao webhooksdao.Dao
+}
+
+// NewSink queues a delivery of every published event to each subscription of
+// its tenant that matches it. The delivered body is the message, as other
+// sinks publish it. Publishing an event again queues nothing new.
+func NewSink(dao webhooksdao.Dao) events.Sink {
+	return &sink{dao: dao}
+}
+
+func (s *sink) Publish(ctx context.Context, message events.Message) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.dao.Enqueue(tenant.NewContext(ctx, message.TenantId), message.Id, message.Type, message.AccountId, payload)
+	return err
+}
@@ -0,0 +1,258 @@
+// Package webhooks lets clients subscribe endpoints to domain events. The
+// events relay hands every event to the Sink of this package, which queues a
+// delivery for each matching subscription. A Deliverer then POSTs the
+// deliveries, signed with the secret of their subscription, retrying with
+// exponential backoff until they are accepted or given up on as dead.
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"slices"
+
+	webhooksdao "github.com/ashwin-m/transactions/daos/webhooks"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	eventsmodel "github.com/ashwin-m/transactions/models/events"
+	webhooksmodel "github.com/ashwin-m/transactions/models/webhooks"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 100
+)
+
+const (
+	errWebhookForbidden = "you do not have access to this webhook"
+
+	// secretPrefix marks the signing secrets of subscriptions, like the
+	// prefix of API keys.
+	secretPrefix = "whsec_"
+)
+
+type CreateRequest struct {
+	Url string
+	// EventTypes are the types of events delivered, every type when empty.
+	EventTypes []string
+}
+
+type ListDeliveriesRequest struct {
+	SubscriptionId int64
+	// Status only lists the deliveries with this status, unless empty.
+	Status string
+	// PageSize defaults to DefaultPageSize and is capped at MaxPageSize.
+	PageSize int
+	// BeforeId continues a listing after the last delivery of a page.
+	BeforeId int64
+}
+
+// Service manages the subscriptions of the tenant on the context. Clients
+// see the subscriptions they created, admins those of everyone.
+type Service interface {
+	// Create subscribes an endpoint. The subscription of a client only
+	// receives the events of the accounts it owns, an admin's those of the
+	// whole tenant. The signing secret is only ever returned here.
+	Create(ctx context.Context, request CreateRequest) (webhooksmodel.Subscriptions, error)
+	List(ctx context.Context) ([]webhooksmodel.Subscriptions, error)
+	Get(ctx context.Context, id int64) (webhooksmodel.Subscriptions, error)
+	// Delete unsubscribes an endpoint, dropping its pending deliveries.
+	Delete(ctx context.Context, id int64) error
+
+	// ListDeliveries returns the deliveries of a subscription, newest first.
+	ListDeliveries(ctx context.Context, request ListDeliveriesRequest) ([]webhooksmodel.Deliveries, error)
+	// GetDelivery returns a delivery with its attempts, oldest first.
+	GetDelivery(ctx context.Context, subscriptionId, id int64) (webhooksmodel.Deliveries, []webhooksmodel.Attempts, error)
+	// Redeliver queues a delivery again, whatever its status, with a fresh
+	// set of attempts.
+	Redeliver(ctx context.Context, subscriptionId, id int64) (webhooksmodel.Deliveries, error)
+}
+
+type service struct {
+	dao webhooksdao.Dao
+}
+
+func NewService(dao webhooksdao.Dao) Service {
+	return &service{
+		dao: dao,
+	}
+}
+
+func (s *service) Create(ctx context.Context, request CreateRequest) (webhooksmodel.Subscriptions, error) {
+	var fields []apperrors.FieldError
+	endpoint, err := url.Parse(request.Url)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		fields = append(fields, apperrors.FieldError{Field: "url", Message: "must be an absolute http or https URL"})
+	} else if !publicHost(endpoint.Hostname()) {
+		fields = append(fields, apperrors.FieldError{Field: "url", Message: "must not point to a private, loopback or link-local address"})
+	}
+
+	eventTypes := request.EventTypes
+	if len(eventTypes) == 0 {
+		eventTypes = eventsmodel.Types
+	}
+	for _, eventType := range eventTypes {
+		if !slices.Contains(eventsmodel.Types, eventType) {
+			fields = append(fields, apperrors.FieldError{Field: "event_types", Message: "must only name known event types"})
+			break
+		}
+	}
+
+	if len(fields) > 0 {
+		return webhooksmodel.Subscriptions{}, apperrors.Validation(fields...)
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return webhooksmodel.Subscriptions{}, err
+	}
+
+	identity, _ := auth.FromContext(ctx)
+
+	subscription := webhooksmodel.Subscriptions{}
+	subscription.SetClientId(identity.ClientID)
+	if !identity.HasRole(auth.RoleAdmin) {
+		subscription.SetOwnerId(identity.ClientID)
+	}
+	subscription.SetUrl(request.Url)
+	subscription.SetSecret(secret)
+	eventTypes = slices.Clone(eventTypes)
+	slices.Sort(eventTypes)
+	subscription.SetEventTypes(slices.Compact(eventTypes))
+
+	return s.dao.Create(ctx, subscription)
+}
+
+func (s *service) List(ctx context.Context) ([]webhooksmodel.Subscriptions, error) {
+	identity, _ := auth.FromContext(ctx)
+
+	clientId := identity.ClientID
+	if identity.HasRole(auth.RoleAdmin) {
+		clientId = ""
+	}
+
+	return s.dao.List(ctx, clientId)
+}
+
+// Get returns a subscription the caller has access to. As with accounts,
+// only admins learn that a subscription doesn't exist.
+func (s *service) Get(ctx context.Context, id int64) (webhooksmodel.Subscriptions, error) {
+	identity, _ := auth.FromContext(ctx)
+
+	subscription, err := s.dao.GetById(ctx, id)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows) && !identity.HasRole(auth.RoleAdmin):
+		return subscription, apperrors.New(apperrors.CodeForbidden, errWebhookForbidden)
+	case errors.Is(err, pgx.ErrNoRows):
+		return subscription, apperrors.Newf(apperrors.CodeWebhookNotFound, "webhook %d was not found", id)
+	case err != nil:
+		return subscription, err
+	}
+
+	if !identity.CanAccess(subscription.GetClientId()) {
+		return webhooksmodel.Subscriptions{}, apperrors.New(apperrors.CodeForbidden, errWebhookForbidden)
+	}
+
+	return subscription, nil
+}
+
+func (s *service) Delete(ctx context.Context, id int64) error {
+	_, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.dao.Delete(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apperrors.Newf(apperrors.CodeWebhookNotFound, "webhook %d was not found", id)
+	}
+
+	return err
+}
+
+func (s *service) ListDeliveries(ctx context.Context, request ListDeliveriesRequest) ([]webhooksmodel.Deliveries, error) {
+	var fields []apperrors.FieldError
+	if request.Status != "" && !slices.Contains(webhooksmodel.Statuses, request.Status) {
+		fields = append(fields, apperrors.FieldError{Field: "status", Message: "must be one of pending, delivered or dead"})
+	}
+	if request.PageSize < 0 {
+		fields = append(fields, apperrors.FieldError{Field: "page_size", Message: "must not be negative"})
+	}
+	if len(fields) > 0 {
+		return nil, apperrors.Validation(fields...)
+	}
+
+	_, err := s.Get(ctx, request.SubscriptionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.dao.ListDeliveries(ctx, request.SubscriptionId, request.Status, request.BeforeId, PageSize(request.PageSize))
+}
+
+func (s *service) GetDelivery(ctx context.Context, subscriptionId, id int64) (webhooksmodel.Deliveries, []webhooksmodel.Attempts, error) {
+	_, err := s.Get(ctx, subscriptionId)
+	if err != nil {
+		return webhooksmodel.Deliveries{}, nil, err
+	}
+
+	delivery, err := s.dao.GetDelivery(ctx, subscriptionId, id)
+	if err != nil {
+		return delivery, nil, deliveryLookupFailed(err, id)
+	}
+
+	attempts, err := s.dao.ListAttempts(ctx, id)
+	if err != nil {
+		return delivery, nil, err
+	}
+
+	return delivery, attempts, nil
+}
+
+func (s *service) Redeliver(ctx context.Context, subscriptionId, id int64) (webhooksmodel.Deliveries, error) {
+	_, err := s.Get(ctx, subscriptionId)
+	if err != nil {
+		return webhooksmodel.Deliveries{}, err
+	}
+
+	delivery, err := s.dao.Redeliver(ctx, subscriptionId, id)
+	if err != nil {
+		return delivery, deliveryLookupFailed(err, id)
+	}
+
+	return delivery, nil
+}
+
+// PageSize is the number of deliveries ListDeliveries returns for a
+// requested page size. Fewer means the listing is complete.
+func PageSize(requested int) int {
+	switch {
+	case requested <= 0:
+		return DefaultPageSize
+	case requested > MaxPageSize:
+		return MaxPageSize
+	}
+
+	return requested
+}
+
+func deliveryLookupFailed(err error, id int64) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apperrors.Newf(apperrors.CodeDeliveryNotFound, "delivery %d was not found", id)
+	}
+
+	return err
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return secretPrefix + hex.EncodeToString(b), nil
+}
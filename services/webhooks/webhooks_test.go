@@ -0,0 +1,214 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	webhooksdaomocks "github.com/ashwin-m/transactions/daos/webhooks/mocks"
+	"github.com/ashwin-m/transactions/middlewares/auth"
+	eventsmodel "github.com/ashwin-m/transactions/models/events"
+	webhooksmodel "github.com/ashwin-m/transactions/models/webhooks"
+	"github.com/ashwin-m/transactions/services/events"
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/tenant"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	adminIdentity  = auth.Identity{ClientID: "admin", Roles: []string{auth.RoleAdmin}}
+	clientIdentity = auth.Identity{ClientID: "client-1", Roles: []string{auth.RoleRead, auth.RoleTransfer}}
+)
+
+func contextFor(identity auth.Identity) context.Context {
+	return tenant.NewContext(auth.NewContext(context.Background(), identity), tenant.Default)
+}
+
+func subscription(id int64, clientId string) webhooksmodel.Subscriptions {
+	s := webhooksmodel.Subscriptions{}
+	s.SetId(id)
+	s.SetClientId(clientId)
+	s.SetUrl("https://partner.example/hooks")
+	return s
+}
+
+func assertCode(t *testing.T, code apperrors.Code, err error) {
+	t.Helper()
+	var appErr *apperrors.Error
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, code, appErr.Code)
+	}
+}
+
+func TestCreate_ClientSubscriptionsAreLimitedToTheirAccounts(t *testing.T) {
+	mockDao := webhooksdaomocks.NewDao(t)
+	mockDao.EXPECT().Create(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, s webhooksmodel.Subscriptions) (webhooksmodel.Subscriptions, error) {
+		return s, nil
+	})
+
+	created, err := NewService(mockDao).Create(contextFor(clientIdentity), CreateRequest{
+		Url:        "https://partner.example/hooks",
+		EventTypes: []string{eventsmodel.TypeTransferPosted, eventsmodel.TypeTransferFailed, eventsmodel.TypeTransferPosted},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "client-1", created.GetClientId())
+	assert.Equal(t, "client-1", created.GetOwnerId())
+	assert.Equal(t, []string{eventsmodel.TypeTransferFailed, eventsmodel.TypeTransferPosted}, created.GetEventTypes())
+	assert.True(t, strings.HasPrefix(created.GetSecret(), "whsec_"))
+	assert.Len(t, created.GetSecret(), len("whsec_")+64)
+}
+
+func TestCreate_AdminSubscriptionsGetEveryEventOfTheTenant(t *testing.T) {
+	mockDao := webhooksdaomocks.NewDao(t)
+	mockDao.EXPECT().Create(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, s webhooksmodel.Subscriptions) (webhooksmodel.Subscriptions, error) {
+		return s, nil
+	})
+
+	created, err := NewService(mockDao).Create(contextFor(adminIdentity), CreateRequest{Url: "http://partner.example/hooks"})
+
+	assert.NoError(t, err)
+	assert.Empty(t, created.GetOwnerId())
+	assert.ElementsMatch(t, eventsmodel.Types, created.GetEventTypes())
+}
+
+func TestCreate_InvalidRequest(t *testing.T) {
+	_, err := NewService(webhooksdaomocks.NewDao(t)).Create(contextFor(clientIdentity), CreateRequest{
+		Url:        "ftp://partner.example",
+		EventTypes: []string{"transfer.refunded"},
+	})
+
+	var appErr *apperrors.Error
+	assert.ErrorAs(t, err, &appErr)
+	assert.Equal(t, []apperrors.FieldError{
+		{Field: "url", Message: "must be an absolute http or https URL"},
+		{Field: "event_types", Message: "must only name known event types"},
+	}, appErr.Fields)
+}
+
+func TestCreate_PrivateEndpoint(t *testing.T) {
+	for _, url := range []string{
+		"http://localhost:8080/hooks",
+		"http://127.0.0.1/hooks",
+		"http://10.1.2.3/hooks",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hooks",
+		"http://[::ffff:192.168.0.1]/hooks",
+	} {
+		_, err := NewService(webhooksdaomocks.NewDao(t)).Create(contextFor(clientIdentity), CreateRequest{Url: url})
+
+		var appErr *apperrors.Error
+		assert.ErrorAs(t, err, &appErr, url)
+		assert.Equal(t, []apperrors.FieldError{
+			{Field: "url", Message: "must not point to a private, loopback or link-local address"},
+		}, appErr.Fields, url)
+	}
+}
+
+func TestGet_Access(t *testing.T) {
+	for name, test := range map[string]struct {
+		identity auth.Identity
+		found    bool
+		clientId string
+		code     apperrors.Code
+	}{
+		"own":                  {identity: clientIdentity, found: true, clientId: "client-1"},
+		"another client's":     {identity: clientIdentity, found: true, clientId: "client-2", code: apperrors.CodeForbidden},
+		"missing":              {identity: clientIdentity, code: apperrors.CodeForbidden},
+		"any, as an admin":     {identity: adminIdentity, found: true, clientId: "client-2"},
+		"missing, as an admin": {identity: adminIdentity, code: apperrors.CodeWebhookNotFound},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockDao := webhooksdaomocks.NewDao(t)
+			if test.found {
+				mockDao.EXPECT().GetById(mock.Anything, int64(5)).Return(subscription(5, test.clientId), nil)
+			} else {
+				mockDao.EXPECT().GetById(mock.Anything, int64(5)).Return(webhooksmodel.Subscriptions{}, pgx.ErrNoRows)
+			}
+
+			_, err := NewService(mockDao).Get(contextFor(test.identity), 5)
+
+			if test.code == "" {
+				assert.NoError(t, err)
+			} else {
+				assertCode(t, test.code, err)
+			}
+		})
+	}
+}
+
+func TestList_ClientsSeeTheirOwnSubscriptions(t *testing.T) {
+	mockDao := webhooksdaomocks.NewDao(t)
+	mockDao.EXPECT().List(mock.Anything, "client-1").Return(nil, nil).Once()
+	mockDao.EXPECT().List(mock.Anything, "").Return(nil, nil).Once()
+
+	_, err := NewService(mockDao).List(contextFor(clientIdentity))
+	assert.NoError(t, err)
+
+	_, err = NewService(mockDao).List(contextFor(adminIdentity))
+	assert.NoError(t, err)
+}
+
+func TestListDeliveries(t *testing.T) {
+	mockDao := webhooksdaomocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, int64(5)).Return(subscription(5, "client-1"), nil)
+	mockDao.EXPECT().ListDeliveries(mock.Anything, int64(5), webhooksmodel.StatusDead, int64(40), MaxPageSize).Return(nil, nil)
+
+	_, err := NewService(mockDao).ListDeliveries(contextFor(clientIdentity), ListDeliveriesRequest{
+		SubscriptionId: 5,
+		Status:         webhooksmodel.StatusDead,
+		PageSize:       1000,
+		BeforeId:       40,
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestListDeliveries_UnknownStatus(t *testing.T) {
+	_, err := NewService(webhooksdaomocks.NewDao(t)).ListDeliveries(contextFor(clientIdentity), ListDeliveriesRequest{SubscriptionId: 5, Status: "failed"})
+
+	assertCode(t, apperrors.CodeValidationFailed, err)
+}
+
+func TestRedeliver_MissingDelivery(t *testing.T) {
+	mockDao := webhooksdaomocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, int64(5)).Return(subscription(5, "client-1"), nil)
+	mockDao.EXPECT().Redeliver(mock.Anything, int64(5), int64(9)).Return(webhooksmodel.Deliveries{}, pgx.ErrNoRows)
+
+	_, err := NewService(mockDao).Redeliver(contextFor(clientIdentity), 5, 9)
+
+	assertCode(t, apperrors.CodeDeliveryNotFound, err)
+}
+
+func TestRedeliver_AnotherClientsSubscription(t *testing.T) {
+	mockDao := webhooksdaomocks.NewDao(t)
+	mockDao.EXPECT().GetById(mock.Anything, int64(5)).Return(subscription(5, "client-2"), nil)
+
+	_, err := NewService(mockDao).Redeliver(contextFor(clientIdentity), 5, 9)
+
+	assertCode(t, apperrors.CodeForbidden, err)
+}
+
+func TestSink_EnqueuesInTheTenantOfTheEvent(t *testing.T) {
+	mockDao := webhooksdaomocks.NewDao(t)
+	mockDao.EXPECT().Enqueue(mock.Anything, int64(7), eventsmodel.TypeTransferPosted, int64(123), mock.Anything).
+		RunAndReturn(func(ctx context.Context, eventId int64, eventType string, accountId int64, payload []byte) (int64, error) {
+			tenantId, _ := tenant.FromContext(ctx)
+			assert.Equal(t, "retail", tenantId)
+			assert.JSONEq(t, `{"id":7,"type":"transfer.posted","tenant_id":"retail","account_id":123,"occurred_at":"0001-01-01T00:00:00Z","data":{}}`, string(payload))
+			return 1, errors.New("unavailable")
+		})
+
+	err := NewSink(mockDao).Publish(context.Background(), events.Message{
+		Id:        7,
+		Type:      eventsmodel.TypeTransferPosted,
+		TenantId:  "retail",
+		AccountId: 123,
+		Data:      []byte(`{}`),
+	})
+
+	assert.EqualError(t, err, "unavailable")
+}
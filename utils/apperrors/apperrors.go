@@ -0,0 +1,186 @@
+// Package apperrors is the error model of the API. Every error response is an
+// RFC 7807 problem document carrying a stable, machine readable code, so
+// clients never have to match on messages.
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ashwin-m/transactions/middlewares/requestid"
+	"github.com/gin-gonic/gin"
+)
+
+const ContentType = "application/problem+json"
+
+type Code string
+
+const (
+	CodeValidationFailed     Code = "VALIDATION_FAILED"
+	CodeUnauthorized         Code = "UNAUTHORIZED"
+	CodeForbidden            Code = "FORBIDDEN"
+	CodeNotFound             Code = "NOT_FOUND"
+	CodeAccountNotFound      Code = "ACCOUNT_NOT_FOUND"
+	CodeAccountAlreadyExists Code = "ACCOUNT_ALREADY_EXISTS"
+	CodeTransferNotFound     Code = "TRANSFER_NOT_FOUND"
+	CodeWebhookNotFound      Code = "WEBHOOK_NOT_FOUND"
+	CodeDeliveryNotFound     Code = "DELIVERY_NOT_FOUND"
+	CodeInsufficientFunds    Code = "INSUFFICIENT_FUNDS"
+	CodeVersionConflict      Code = "VERSION_CONFLICT"
+	CodeExportInProgress     Code = "EXPORT_IN_PROGRESS"
+	CodeRateLimited          Code = "RATE_LIMITED"
+	CodeBodyTooLarge         Code = "BODY_TOO_LARGE"
+	CodeInternal             Code = "INTERNAL_ERROR"
+)
+
+type definition struct {
+	status int
+	title  string
+}
+
+var definitions = map[Code]definition{
+	CodeValidationFailed:     {http.StatusBadRequest, "The request is invalid"},
+	CodeUnauthorized:         {http.StatusUnauthorized, "Authentication is required"},
+	CodeForbidden:            {http.StatusForbidden, "The request is not allowed"},
+	CodeNotFound:             {http.StatusNotFound, "The resource was not found"},
+	CodeAccountNotFound:      {http.StatusNotFound, "The account was not found"},
+	CodeAccountAlreadyExists: {http.StatusConflict, "The account already exists"},
+	CodeTransferNotFound:     {http.StatusNotFound, "The transfer was not found"},
+	CodeWebhookNotFound:      {http.StatusNotFound, "The webhook was not found"},
+	CodeDeliveryNotFound:     {http.StatusNotFound, "The webhook delivery was not found"},
+	CodeInsufficientFunds:    {http.StatusUnprocessableEntity, "The account has insufficient funds"},
+	CodeVersionConflict:      {http.StatusConflict, "The account was modified concurrently"},
+	CodeExportInProgress:     {http.StatusConflict, "An export is already running"},
+	CodeRateLimited:          {http.StatusTooManyRequests, "Too many requests"},
+	CodeBodyTooLarge:         {http.StatusRequestEntityTooLarge, "The request body is too large"},
+	CodeInternal:             {http.StatusInternalServerError, "Internal server error"},
+}
+
+// Status is the HTTP status code errors with this code are returned with.
+func (c Code) Status() int {
+	if d, ok := definitions[c]; ok {
+		return d.status
+	}
+
+	return http.StatusInternalServerError
+}
+
+// Type is the problem type URI of the code.
+func (c Code) Type() string {
+	return "urn:transactions:problem:" + strings.ReplaceAll(strings.ToLower(string(c)), "_", "-")
+}
+
+func (c Code) Title() string {
+	return definitions[c].title
+}
+
+// FieldError describes one invalid field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error that can be shown to API clients. Detail is safe to
+// return, the wrapped cause is only ever logged.
+type Error struct {
+	Code   Code
+	Detail string
+	Fields []FieldError
+	cause  error
+}
+
+func New(code Code, detail string) *Error {
+	return &Error{Code: code, Detail: detail}
+}
+
+func Newf(code Code, format string, args ...any) *Error {
+	return New(code, fmt.Sprintf(format, args...))
+}
+
+// Validation reports invalid fields of a request.
+func Validation(fields ...FieldError) *Error {
+	return &Error{Code: CodeValidationFailed, Detail: "the request has invalid fields", Fields: fields}
+}
+
+// Internal hides err from the client behind a generic message.
+func Internal(err error) *Error {
+	return &Error{Code: CodeInternal, Detail: "the request could not be completed", cause: err}
+}
+
+// Wrap returns a copy of e caused by err.
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.cause = err
+	return &wrapped
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Detail, e.cause)
+	}
+
+	return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Problem is the RFC 7807 response body.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	RequestId string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// From converts any error into an *Error. A body cut short by
+// http.MaxBytesReader is too large, other errors that aren't already one are
+// treated as internal.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return Newf(CodeBodyTooLarge, "the request body must not exceed %d bytes", maxBytesErr.Limit).Wrap(err)
+	}
+
+	return Internal(err)
+}
+
+// Abort writes err as a problem response and stops the handler chain. The
+// error, including its cause, is recorded on the context for the access log.
+func Abort(c *gin.Context, err error) {
+	appErr := From(err)
+
+	_ = c.Error(appErr)
+	c.Abort()
+	c.Render(appErr.Code.Status(), problemRender{appErr.problem(c)})
+}
+
+func (e *Error) problem(c *gin.Context) Problem {
+	return Problem{
+		Type:      e.Code.Type(),
+		Title:     e.Code.Title(),
+		Status:    e.Code.Status(),
+		Detail:    e.Detail,
+		Instance:  c.Request.URL.Path,
+		Code:      e.Code,
+		RequestId: requestid.FromContext(c.Request.Context()),
+		Errors:    e.Fields,
+	}
+}
+
+// NoRoute answers requests for paths and methods the router doesn't serve.
+func NoRoute(c *gin.Context) {
+	Abort(c, New(CodeNotFound, "no such endpoint"))
+}
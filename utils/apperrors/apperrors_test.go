@@ -0,0 +1,94 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ashwin-m/transactions/middlewares/requestid"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRouter(handler gin.HandlerFunc) *gin.Engine {
+	router := gin.New()
+	router.Use(requestid.Middleware())
+	router.POST("/fail", handler)
+	router.NoRoute(NoRoute)
+
+	return router
+}
+
+func TestAbort_InternalErrorHidesCause(t *testing.T) {
+	router := newRouter(func(c *gin.Context) {
+		Abort(c, errors.New("connection refused"))
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/fail", nil)
+	req.Header.Set(requestid.Header, "abc-123")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:internal-error\",\"title\":\"Internal server error\",\"status\":500,\"detail\":\"the request could not be completed\",\"instance\":\"/fail\",\"code\":\"INTERNAL_ERROR\",\"request_id\":\"abc-123\"}", w.Body.String())
+}
+
+func TestAbort_WrappedError(t *testing.T) {
+	router := newRouter(func(c *gin.Context) {
+		err := New(CodeVersionConflict, "account was modified").Wrap(errors.New("version 3"))
+		Abort(c, fmt.Errorf("updating balance: %w", err))
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/fail", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "\"code\":\"VERSION_CONFLICT\"")
+	assert.NotContains(t, w.Body.String(), "version 3")
+}
+
+func TestFromBinding(t *testing.T) {
+	type request struct {
+		Name  string `json:"name" binding:"required"`
+		Count int    `json:"count" binding:"max=10"`
+	}
+
+	cases := map[string]struct {
+		body   string
+		fields []FieldError
+		detail string
+	}{
+		"missing field": {body: `{"count": 1}`, fields: []FieldError{{Field: "name", Message: "is required"}}, detail: "the request has invalid fields"},
+		"too large":     {body: `{"name": "a", "count": 11}`, fields: []FieldError{{Field: "count", Message: "must be at most 10"}}, detail: "the request has invalid fields"},
+		"wrong type":    {body: `{"name": 1}`, fields: []FieldError{{Field: "name", Message: "must be a string"}}, detail: "the request has invalid fields"},
+		"not json":      {body: `name=a`, detail: "the request body must be a JSON object"},
+		"empty":         {body: ``, detail: "the request body must be a JSON object"},
+	}
+
+	for name, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("POST", "/", strings.NewReader(tc.body))
+
+		err := FromBinding(c.ShouldBindJSON(&request{}))
+
+		assert.Equal(t, CodeValidationFailed, err.Code, name)
+		assert.Equal(t, tc.detail, err.Detail, name)
+		assert.Equal(t, tc.fields, err.Fields, name)
+	}
+}
+
+func TestNoRoute(t *testing.T) {
+	router := newRouter(nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/missing", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "\"code\":\"NOT_FOUND\"")
+}
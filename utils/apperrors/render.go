@@ -0,0 +1,28 @@
+package apperrors
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// problemRender writes a problem like gin's JSON render, with the problem
+// media type.
+type problemRender struct {
+	problem Problem
+}
+
+func (r problemRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+
+	body, err := json.Marshal(r.problem)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(body)
+	return err
+}
+
+func (r problemRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ContentType)
+}
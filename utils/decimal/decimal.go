@@ -0,0 +1,37 @@
+// Package decimal checks the decimal strings amounts and balances are sent
+// as. They are accepted as the numeric binding of the HTTP API accepts them,
+// so the gRPC API, which doesn't go through it, takes the same values.
+package decimal
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// Pattern matches the decimal strings accepted by the numeric binding:
+// digits with an optional sign and fraction, no exponent, hexadecimal,
+// infinity or NaN.
+const Pattern = `^[-+]?[0-9]+(\.[0-9]+)?$`
+
+var pattern = regexp.MustCompile(Pattern)
+
+// Valid reports whether s is a decimal string.
+func Valid(s string) bool {
+	return pattern.MatchString(s)
+}
+
+// ParseFloat parses the decimal string s, it returns false when s isn't one
+// or is too large to be a finite float64.
+func ParseFloat(s string) (float64, bool) {
+	if !Valid(s) {
+		return 0, false
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) {
+		return 0, false
+	}
+
+	return f, true
+}
@@ -0,0 +1,32 @@
+package decimal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFloat(t *testing.T) {
+	tests := map[string]struct {
+		value float64
+		ok    bool
+	}{
+		"10":                     {10, true},
+		"-0.5":                   {-0.5, true},
+		"+7.25":                  {7.25, true},
+		"":                       {0, false},
+		"1.":                     {0, false},
+		"1e3":                    {0, false},
+		"0x1p3":                  {0, false},
+		"NaN":                    {0, false},
+		"Inf":                    {0, false},
+		" 1":                     {0, false},
+		strings.Repeat("9", 400): {0, false},
+	}
+	for s, test := range tests {
+		value, ok := ParseFloat(s)
+		assert.Equal(t, test.ok, ok, s)
+		assert.Equal(t, test.value, value, s)
+	}
+}
@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type Status struct {
+	Name          string     `json:"name"`
+	Running       bool       `json:"running"`
+	Healthy       bool       `json:"healthy"`
+	LastStartedAt *time.Time `json:"last_started_at,omitempty"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+}
+
+// Registry keeps track of the background jobs running in this process so
+// their state can be reported by the health endpoints.
+type Registry struct {
+	mu   sync.Mutex
+	jobs map[string]*Status
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		jobs: map[string]*Status{},
+	}
+}
+
+// Register adds a job to the registry and returns the handle the job uses to
+// report progress. Registering the same name twice returns the same job.
+func (r *Registry) Register(name string) *Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.jobs[name]; !ok {
+		r.jobs[name] = &Status{Name: name, Healthy: true}
+	}
+
+	return &Job{registry: r, name: name}
+}
+
+func (r *Registry) Statuses() []Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	statuses := make([]Status, 0, len(r.jobs))
+	for _, status := range r.jobs {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses
+}
+
+func (r *Registry) update(name string, fn func(*Status)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fn(r.jobs[name])
+}
+
+type Job struct {
+	registry *Registry
+	name     string
+}
+
+func (j *Job) Started() {
+	now := time.Now()
+	j.registry.update(j.name, func(s *Status) {
+		s.Running = true
+		s.LastStartedAt = &now
+	})
+}
+
+func (j *Job) Succeeded() {
+	now := time.Now()
+	j.registry.update(j.name, func(s *Status) {
+		s.Running = false
+		s.Healthy = true
+		s.LastSuccessAt = &now
+	})
+}
+
+func (j *Job) Failed(err error) {
+	now := time.Now()
+	j.registry.update(j.name, func(s *Status) {
+		s.Running = false
+		s.Healthy = false
+		s.LastError = err.Error()
+		s.LastErrorAt = &now
+	})
+}
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ashwin-m/transactions/middlewares/requestid"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware_LogsRequestWithId(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo, "json")
+
+	router := gin.New()
+	router.Use(requestid.Middleware(), Middleware(logger))
+	router.GET("/accounts/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/accounts/1", nil)
+	req.Header.Set(requestid.Header, "abc-123")
+	router.ServeHTTP(w, req)
+
+	var line map[string]any
+	err := json.Unmarshal(buf.Bytes(), &line)
+	assert.NoError(t, err)
+	assert.Equal(t, "request", line["msg"])
+	assert.Equal(t, "abc-123", line["request_id"])
+	assert.Equal(t, "/accounts/:id", line["route"])
+	assert.Equal(t, float64(http.StatusOK), line["status"])
+}
+
+func TestRecovery_ReturnsRequestId(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo, "json")
+
+	router := gin.New()
+	router.Use(requestid.Middleware(), Recovery(logger))
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/panic", nil)
+	req.Header.Set(requestid.Header, "abc-123")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:internal-error\",\"title\":\"Internal server error\",\"status\":500,\"detail\":\"the request could not be completed\",\"instance\":\"/panic\",\"code\":\"INTERNAL_ERROR\",\"request_id\":\"abc-123\"}", w.Body.String())
+	assert.Contains(t, buf.String(), "\"panic\":\"boom\"")
+}
@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "transactions"
+
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+
+	// unmatchedRoute labels requests that did not hit a registered route, so
+	// arbitrary paths can't blow up the label cardinality.
+	unmatchedRoute = "unmatched"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	transfers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_total",
+		Help:      "Transfers attempted, by outcome and error code.",
+	}, []string{"outcome", "error_code"})
+
+	transferAmount = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "transfer_amount",
+		Help:      "Amounts of attempted transfers, by outcome and error code.",
+		Buckets:   prometheus.ExponentialBuckets(1, 10, 8),
+	}, []string{"outcome", "error_code"})
+
+	versionConflicts = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "account_version_conflicts_total",
+		Help:      "Optimistic lock conflicts when updating account balances.",
+	})
+
+	rateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests rejected by a rate limit, by limiter.",
+	}, []string{"limiter"})
+
+	eventsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_published_total",
+		Help:      "Attempts to publish outbox events, by event type and outcome.",
+	}, []string{"type", "outcome"})
+
+	eventsParked = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_parked_total",
+		Help:      "Outbox events set aside after failing to publish too many times, by event type.",
+	}, []string{"type"})
+
+	webhookAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_attempts_total",
+		Help:      "Attempts to deliver webhooks, by event type and resulting delivery status.",
+	}, []string{"type", "status"})
+
+	reconciliationMismatches = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "reconciliation_mismatched_accounts",
+		Help:      "Accounts whose balance differed from their transfers at the last reconciliation.",
+	})
+
+	reconciliationDifference = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "reconciliation_conservation_difference",
+		Help:      "Money created, or destroyed when negative, across the reconciled tenants at the last reconciliation.",
+	})
+
+	auditFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_write_failures_total",
+		Help:      "Audit log entries that couldn't be written.",
+	})
+
+	exportedRows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "exported_rows_total",
+		Help:      "Rows written by exports, by table.",
+	}, []string{"table"})
+
+	importedRows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "imported_rows_total",
+		Help:      "Rows read by account imports, by outcome: created, existing or failed.",
+	}, []string{"outcome"})
+)
+
+// Middleware records the count and latency of every request, labelled with
+// the route template rather than the raw path.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		httpRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+
+// ObserveTransfer records a transfer attempt. An empty errorCode means the
+// transfer succeeded.
+func ObserveTransfer(errorCode string, amount float64) {
+	outcome := OutcomeSuccess
+	if errorCode != "" {
+		outcome = OutcomeFailure
+	}
+
+	transfers.WithLabelValues(outcome, errorCode).Inc()
+	transferAmount.WithLabelValues(outcome, errorCode).Observe(amount)
+}
+
+func VersionConflict() {
+	versionConflicts.Inc()
+}
+
+func RateLimited(limiter string) {
+	rateLimited.WithLabelValues(limiter).Inc()
+}
+
+func EventPublished(eventType, outcome string) {
+	eventsPublished.WithLabelValues(eventType, outcome).Inc()
+}
+
+func EventParked(eventType string) {
+	eventsParked.WithLabelValues(eventType).Inc()
+}
+
+func WebhookAttempted(eventType, status string) {
+	webhookAttempts.WithLabelValues(eventType, status).Inc()
+}
+
+func AuditFailed() {
+	auditFailures.Inc()
+}
+
+// Reconciled records the outcome of a reconciliation of the ledger.
+func Reconciled(mismatches int, conservationDifference float64) {
+	reconciliationMismatches.Set(float64(mismatches))
+	reconciliationDifference.Set(conservationDifference)
+}
+
+func Exported(table string, rows int64) {
+	exportedRows.WithLabelValues(table).Add(float64(rows))
+}
+
+func Imported(outcome string, rows int64) {
+	importedRows.WithLabelValues(outcome).Add(float64(rows))
+}
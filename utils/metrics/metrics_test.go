@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware_LabelsByRouteTemplate(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/accounts/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, path := range []string{"/accounts/1", "/accounts/2", "/missing"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/accounts/:id", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "unmatched", "404")))
+}
+
+func TestObserveTransfer(t *testing.T) {
+	ObserveTransfer("", 10)
+	ObserveTransfer("insufficient_funds", 500)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(transfers.WithLabelValues(OutcomeSuccess, "")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(transfers.WithLabelValues(OutcomeFailure, "insufficient_funds")))
+}
@@ -0,0 +1,364 @@
+// Package openapi describes the API as an OpenAPI 3 document. Controllers
+// describe the routes they register, with request and response schemas
+// generated from the same structs the handlers bind and render, so the
+// document can't disagree with the handlers about field names or which
+// fields are required.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/ashwin-m/transactions/utils/decimal"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+// decimalPattern matches the decimal strings accepted by the numeric binding.
+const decimalPattern = decimal.Pattern
+
+const problemRef = "#/components/schemas/Problem"
+
+var problemSchema = schemaOf(reflect.TypeOf(apperrors.Problem{}))
+
+// Describer is implemented by controllers documenting their routes.
+type Describer interface {
+	Describe(doc *openapi3.T)
+}
+
+// New builds the document of the API served by describers.
+func New(describers ...Describer) *openapi3.T {
+	doc := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:   "Transactions",
+			Version: "1.0.0",
+		},
+		Paths: openapi3.NewPaths(),
+		Components: &openapi3.Components{
+			Schemas: openapi3.Schemas{
+				"Problem": openapi3.NewSchemaRef("", problemSchema),
+			},
+			SecuritySchemes: openapi3.SecuritySchemes{
+				"bearer": &openapi3.SecuritySchemeRef{Value: openapi3.NewJWTSecurityScheme().
+					WithDescription("An API key, or a JWT issued by the gateway")},
+				"hmac": &openapi3.SecuritySchemeRef{Value: openapi3.NewSecurityScheme().
+					WithType("apiKey").WithIn("header").WithName("Authorization").
+					WithDescription("An HMAC-SHA256 signature of the request made with the signing secret of an API key")},
+			},
+		},
+		Security: openapi3.SecurityRequirements{
+			openapi3.NewSecurityRequirement().Authenticate("bearer"),
+			openapi3.NewSecurityRequirement().Authenticate("hmac"),
+		},
+	}
+
+	for _, d := range describers {
+		d.Describe(doc)
+	}
+
+	return doc
+}
+
+// Mount describes the routes of describers as registered under prefix, such
+// as /v1. Operation ids are prefixed with the version so several versions can
+// be described side by side, unversioned routes are prefixed with
+// "unversioned".
+func Mount(prefix string, deprecated bool, describers ...Describer) Describer {
+	return mount{prefix: prefix, deprecated: deprecated, describers: describers}
+}
+
+type mount struct {
+	prefix     string
+	deprecated bool
+	describers []Describer
+}
+
+func (m mount) Describe(doc *openapi3.T) {
+	scratch := &openapi3.T{Paths: openapi3.NewPaths(), Components: doc.Components}
+	for _, d := range m.describers {
+		d.Describe(scratch)
+	}
+
+	version := strings.Trim(m.prefix, "/")
+	if version == "" {
+		version = "unversioned"
+	}
+
+	for path, item := range scratch.Paths.Map() {
+		for method, op := range item.Operations() {
+			mounted := *op
+			mounted.Deprecated = m.deprecated
+			if mounted.OperationID != "" {
+				mounted.OperationID = version + strings.ToUpper(mounted.OperationID[:1]) + mounted.OperationID[1:]
+			}
+			doc.AddOperation(m.prefix+path, method, &mounted)
+		}
+	}
+}
+
+// BackendExtension names the storage backend an operation is only served
+// with, for the operations described by Only.
+const BackendExtension = "x-storage-backend"
+
+// Only describes the routes of describers as only served with the given
+// storage backend, in their descriptions and BackendExtension.
+func Only(backend string, describers ...Describer) Describer {
+	return only{backend: backend, describers: describers}
+}
+
+type only struct {
+	backend    string
+	describers []Describer
+}
+
+func (o only) Describe(doc *openapi3.T) {
+	scratch := &openapi3.T{Paths: openapi3.NewPaths(), Components: doc.Components}
+	for _, d := range o.describers {
+		d.Describe(scratch)
+	}
+
+	for path, item := range scratch.Paths.Map() {
+		for method, op := range item.Operations() {
+			marked := *op
+			marked.Description = strings.TrimSpace(fmt.Sprintf("%s\n\nOnly served with the %s storage backend.", op.Description, o.backend))
+			marked.Extensions = map[string]any{}
+			for name, value := range op.Extensions {
+				marked.Extensions[name] = value
+			}
+			marked.Extensions[BackendExtension] = o.backend
+			doc.AddOperation(path, method, &marked)
+		}
+	}
+}
+
+// Operation describes a route as registered with gin. Path parameters like
+// :id become templated {id} parameters of type string unless the operation
+// already declares them.
+func Operation(doc *openapi3.T, method, path string, op *openapi3.Operation) {
+	for _, segment := range strings.Split(path, "/") {
+		name, ok := strings.CutPrefix(segment, ":")
+		if ok && op.Parameters.GetByInAndName(openapi3.ParameterInPath, name) == nil {
+			op.AddParameter(openapi3.NewPathParameter(name).WithSchema(openapi3.NewStringSchema()))
+		}
+	}
+
+	doc.AddOperation(templated(path), method, op)
+}
+
+// JSONBody is a required JSON request body shaped like v.
+func JSONBody(v any) *openapi3.RequestBodyRef {
+	return &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().
+		WithRequired(true).
+		WithJSONSchemaRef(Schema(v))}
+}
+
+// StreamBody is a required request body in any of contentTypes, read by the
+// handler as it arrives. Validator leaves it to the handler.
+func StreamBody(description string, contentTypes ...string) *openapi3.RequestBodyRef {
+	content := openapi3.Content{}
+	for _, contentType := range contentTypes {
+		content[contentType] = openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema().WithFormat("binary"))
+	}
+
+	return &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().
+		WithDescription(description).
+		WithRequired(true).
+		WithContent(content)}
+}
+
+// JSONResponse is a response with a JSON body shaped like v.
+func JSONResponse(description string, v any) *openapi3.Response {
+	return openapi3.NewResponse().WithDescription(description).WithJSONSchemaRef(Schema(v))
+}
+
+// EmptyResponse is a response without a body.
+func EmptyResponse(description string) *openapi3.Response {
+	return openapi3.NewResponse().WithDescription(description)
+}
+
+// Problems adds the problem response returned for each of codes.
+func Problems(op *openapi3.Operation, codes ...apperrors.Code) {
+	byStatus := map[int][]string{}
+	for _, code := range codes {
+		byStatus[code.Status()] = append(byStatus[code.Status()], string(code))
+	}
+
+	for status, names := range byStatus {
+		response := openapi3.NewResponse().
+			WithDescription(strings.Join(names, ", ")).
+			WithContent(openapi3.Content{
+				apperrors.ContentType: openapi3.NewMediaType().WithSchemaRef(openapi3.NewSchemaRef(problemRef, problemSchema)),
+			})
+		op.AddResponse(status, response)
+	}
+}
+
+// Schema generates the schema of v from its type. Structs are described by
+// their json tags, and their binding tags mark required fields and the
+// constraints the handler enforces.
+func Schema(v any) *openapi3.SchemaRef {
+	return openapi3.NewSchemaRef("", schemaOf(reflect.TypeOf(v)))
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func schemaOf(t reflect.Type) *openapi3.Schema {
+	if t == timeType {
+		return openapi3.NewDateTimeSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem()).WithNullable()
+	case reflect.String:
+		return openapi3.NewStringSchema()
+	case reflect.Bool:
+		return openapi3.NewBoolSchema()
+	case reflect.Int64, reflect.Uint64:
+		return openapi3.NewInt64Schema()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return openapi3.NewInt32Schema()
+	case reflect.Float32, reflect.Float64:
+		return openapi3.NewFloat64Schema()
+	case reflect.Slice, reflect.Array:
+		return openapi3.NewArraySchema().WithItems(schemaOf(t.Elem()))
+	case reflect.Map:
+		return openapi3.NewObjectSchema().WithAdditionalProperties(schemaOf(t.Elem()))
+	case reflect.Struct:
+		return structSchema(t)
+	}
+
+	return openapi3.NewSchema()
+}
+
+func structSchema(t reflect.Type) *openapi3.Schema {
+	schema := openapi3.NewObjectSchema()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			// embedded structs are flattened, as encoding/json does
+			embedded := structSchema(field.Type)
+			for property, ref := range embedded.Properties {
+				schema.WithPropertyRef(property, ref)
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := schemaOf(field.Type)
+		for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+			tag, param, _ := strings.Cut(rule, "=")
+			switch tag {
+			case "required":
+				schema.Required = append(schema.Required, name)
+			case "numeric":
+				property.WithPattern(decimalPattern)
+			case "oneof":
+				for _, value := range strings.Fields(param) {
+					property.Enum = append(property.Enum, value)
+				}
+			case "min", "max":
+				limit, err := strconv.ParseFloat(param, 64)
+				if err != nil {
+					panic(fmt.Sprintf("openapi: invalid %s=%s on %s.%s", tag, param, t.Name(), field.Name))
+				}
+				constrain(property, tag, limit)
+			}
+		}
+
+		schema.WithProperty(name, property)
+	}
+
+	return schema
+}
+
+// constrain applies a min or max binding the way the validator interprets
+// it: a bound on the value of numbers, and on the length of everything else.
+func constrain(schema *openapi3.Schema, tag string, limit float64) {
+	switch {
+	case schema.Type == openapi3.TypeInteger || schema.Type == openapi3.TypeNumber:
+		if tag == "min" {
+			schema.WithMin(limit)
+		} else {
+			schema.WithMax(limit)
+		}
+	case schema.Type == openapi3.TypeArray:
+		if tag == "min" {
+			schema.WithMinItems(int64(limit))
+		} else {
+			schema.WithMaxItems(int64(limit))
+		}
+	default:
+		if tag == "min" {
+			schema.WithMinLength(int64(limit))
+		} else {
+			schema.WithMaxLength(int64(limit))
+		}
+	}
+}
+
+// Handler serves doc as JSON.
+func Handler(doc *openapi3.T) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, doc)
+	}
+}
+
+// Drift lists the differences between the routes described in doc and the
+// routes registered with gin, as "METHOD /path" entries prefixed with + for
+// undocumented routes and - for documented routes gin doesn't serve.
+func Drift(doc *openapi3.T, routes gin.RoutesInfo) []string {
+	registered := map[string]bool{}
+	for _, route := range routes {
+		registered[route.Method+" "+templated(route.Path)] = true
+	}
+
+	documented := map[string]bool{}
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			documented[method+" "+path] = true
+		}
+	}
+
+	var drift []string
+	for route := range registered {
+		if !documented[route] {
+			drift = append(drift, "+"+route)
+		}
+	}
+	for route := range documented {
+		if !registered[route] {
+			drift = append(drift, "-"+route)
+		}
+	}
+	sort.Strings(drift)
+
+	return drift
+}
+
+func templated(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,84 @@
+package openapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ashwin-m/transactions/controllers/accounts"
+	"github.com/ashwin-m/transactions/controllers/audit"
+	"github.com/ashwin-m/transactions/controllers/exports"
+	"github.com/ashwin-m/transactions/controllers/imports"
+	"github.com/ashwin-m/transactions/controllers/transactions"
+	"github.com/ashwin-m/transactions/controllers/webhooks"
+	"github.com/ashwin-m/transactions/middlewares/versioning"
+	"github.com/ashwin-m/transactions/utils/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// The committed spec is what clients and the README refer to. Regenerate it
+// after changing a handler with: go test ./utils/openapi -update
+var update = flag.Bool("update", false, "rewrite resources/openapi.json")
+
+const specFile = "../../resources/openapi.json"
+
+func TestSpec_MatchesCommittedDocument(t *testing.T) {
+	// served as by default, under /v1 and deprecated at the root, where
+	// only the routes that predate versioning are, with exports served
+	// under /v1 outside the versions
+	handlers := []versioning.Handler{accounts.NewHandler(nil), transactions.NewHandler(nil)}
+	doc := openapi.New(append(versioning.Describe([]versioning.Version{
+		{Prefix: "/v1", Handlers: append(handlers, accounts.NewEventsHandler(nil, nil), accounts.NewStatementHandler(nil), versioning.Only("postgres", webhooks.NewHandler(nil)), versioning.Only("postgres", audit.NewHandler(nil)), versioning.Only("postgres", imports.NewHandler(nil)))},
+		{Handlers: handlers, Deprecation: &versioning.Deprecation{Successor: "/v1"}},
+	}), openapi.Mount("/v1", false, versioning.Only("postgres", exports.NewHandler(nil, nil))))...)
+	assert.NoError(t, doc.Validate(context.Background()))
+
+	generated, err := json.MarshalIndent(doc, "", "  ")
+	assert.NoError(t, err)
+	generated = append(generated, '\n')
+
+	if *update {
+		assert.NoError(t, os.WriteFile(specFile, generated, 0o644))
+	}
+
+	committed, err := os.ReadFile(specFile)
+	assert.NoError(t, err)
+	assert.Equal(t, string(committed), string(generated), "resources/openapi.json is out of date, run go test ./utils/openapi -update")
+}
+
+func TestDrift(t *testing.T) {
+	doc := openapi.New()
+	openapi.Operation(doc, http.MethodGet, "/accounts/:id", openapi3.NewOperation())
+	openapi.Operation(doc, http.MethodDelete, "/accounts/:id", openapi3.NewOperation())
+
+	routes := gin.RoutesInfo{
+		{Method: http.MethodGet, Path: "/accounts/:id"},
+		{Method: http.MethodPost, Path: "/accounts"},
+	}
+
+	assert.Equal(t, []string{"+POST /accounts", "-DELETE /accounts/{id}"}, openapi.Drift(doc, routes))
+}
+
+func TestOnly(t *testing.T) {
+	op := openapi3.NewOperation()
+	op.Description = "Lists the widgets."
+	doc := openapi.New(openapi.Only("postgres", describer(func(doc *openapi3.T) {
+		openapi.Operation(doc, http.MethodGet, "/widgets", op)
+	})))
+
+	marked := doc.Paths.Find("/widgets").Get
+	assert.Equal(t, "Lists the widgets.\n\nOnly served with the postgres storage backend.", marked.Description)
+	assert.Equal(t, "postgres", marked.Extensions[openapi.BackendExtension])
+	assert.Nil(t, op.Extensions)
+}
+
+type describer func(doc *openapi3.T)
+
+func (d describer) Describe(doc *openapi3.T) {
+	d(doc)
+}
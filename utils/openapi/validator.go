@@ -0,0 +1,212 @@
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ashwin-m/transactions/utils/apperrors"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+)
+
+// MaxJSONBody is the largest JSON request body accepted, as much as a
+// signed request may carry.
+const MaxJSONBody = 1 << 20
+
+// BodyLimit caps the JSON request bodies of the operations in doc at limit
+// bytes. The validator buffers them whole, so it must run before it, and
+// before anything else that may read the body. Streamed bodies are left to
+// their handlers.
+func BodyLimit(doc *openapi3.T, limit int64) (gin.HandlerFunc, error) {
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *gin.Context) {
+		route, _, err := router.FindRoute(c.Request)
+		if err != nil || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+		if body := route.Operation.RequestBody; body != nil && body.Value.Content.Get("application/json") == nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			apperrors.Abort(c, &http.MaxBytesError{Limit: limit})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+
+		c.Next()
+	}, nil
+}
+
+// Validator rejects requests that don't match their operation in doc before
+// they reach a handler. Requests for routes doc doesn't describe are passed
+// on untouched, and so are bodies other than JSON, which can be too large to
+// buffer. Credentials are checked by the auth middleware, not here.
+func Validator(doc *openapi3.T) (gin.HandlerFunc, error) {
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &openapi3filter.Options{
+		MultiError:         true,
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+	streamOptions := *options
+	streamOptions.ExcludeRequestBody = true
+
+	return func(c *gin.Context) {
+		route, pathParams, err := router.FindRoute(c.Request)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    options,
+		}
+		if body := route.Operation.RequestBody; body != nil && body.Value.Content.Get("application/json") == nil {
+			input.Options = &streamOptions
+		}
+
+		err = openapi3filter.ValidateRequest(c.Request.Context(), input)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			apperrors.Abort(c, maxBytesErr)
+			return
+		}
+		if err != nil {
+			apperrors.Abort(c, validationError(err))
+			return
+		}
+
+		c.Next()
+	}, nil
+}
+
+// validationError reports every invalid field found by the validator, or
+// the first problem with the request that isn't about a field.
+func validationError(err error) *apperrors.Error {
+	var fields []apperrors.FieldError
+	var detail string
+
+	for _, e := range flatten(err) {
+		var requestErr *openapi3filter.RequestError
+		if !errors.As(e, &requestErr) {
+			if detail == "" {
+				detail = e.Error()
+			}
+			continue
+		}
+
+		var schemaErr *openapi3.SchemaError
+		switch {
+		case requestErr.Parameter != nil:
+			fields = append(fields, apperrors.FieldError{Field: requestErr.Parameter.Name, Message: parameterMessage(requestErr)})
+		case errors.As(requestErr.Err, &schemaErr) && fieldName(schemaErr) != "":
+			fields = append(fields, apperrors.FieldError{Field: fieldName(schemaErr), Message: schemaMessage(schemaErr)})
+		case requestErr.RequestBody != nil && detail == "":
+			detail = "the request body must be a JSON object"
+		case detail == "":
+			detail = requestErr.Error()
+		}
+	}
+
+	if len(fields) > 0 {
+		return apperrors.Validation(fields...).Wrap(err)
+	}
+
+	return apperrors.New(apperrors.CodeValidationFailed, detail).Wrap(err)
+}
+
+func flatten(err error) []error {
+	var multi openapi3.MultiError
+	if !errors.As(err, &multi) {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, e := range multi {
+		// A body with several invalid fields is one request error holding
+		// a schema error per field.
+		var requestErr *openapi3filter.RequestError
+		if errors.As(e, &requestErr) && errors.As(requestErr.Err, &multi) {
+			for _, inner := range flatten(requestErr.Err) {
+				copied := *requestErr
+				copied.Err = inner
+				errs = append(errs, &copied)
+			}
+			continue
+		}
+		errs = append(errs, e)
+	}
+
+	return errs
+}
+
+func fieldName(err *openapi3.SchemaError) string {
+	return strings.Join(err.JSONPointer(), ".")
+}
+
+// schemaMessage words schema errors like the messages of the handlers' own
+// validation.
+func schemaMessage(err *openapi3.SchemaError) string {
+	switch err.SchemaField {
+	case "required":
+		return "is required"
+	case "type":
+		return apperrors.MustBe(err.Schema.Type)
+	case "pattern":
+		if err.Schema.Pattern == decimalPattern {
+			return "must be a decimal number"
+		}
+	case "maximum":
+		return fmt.Sprintf("must be at most %v", *err.Schema.Max)
+	case "minimum":
+		return fmt.Sprintf("must be at least %v", *err.Schema.Min)
+	case "maxLength":
+		return fmt.Sprintf("must be at most %d", *err.Schema.MaxLength)
+	case "minLength":
+		return fmt.Sprintf("must be at least %d", err.Schema.MinLength)
+	case "maxItems":
+		return fmt.Sprintf("must be at most %d", *err.Schema.MaxItems)
+	case "minItems":
+		return fmt.Sprintf("must be at least %d", err.Schema.MinItems)
+	case "enum":
+		return fmt.Sprintf("must be one of %v", err.Schema.Enum)
+	}
+
+	return err.Reason
+}
+
+// parameterMessage describes an invalid path, query or header parameter,
+// which fail to parse before their schema is checked.
+func parameterMessage(err *openapi3filter.RequestError) string {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err.Err, &schemaErr) {
+		return schemaMessage(schemaErr)
+	}
+	if errors.Is(err.Err, openapi3filter.ErrInvalidRequired) {
+		return "is required"
+	}
+
+	var parseErr *openapi3filter.ParseError
+	if errors.As(err.Err, &parseErr) && err.Parameter.Schema != nil && err.Parameter.Schema.Value != nil {
+		return apperrors.MustBe(err.Parameter.Schema.Value.Type)
+	}
+
+	return "is invalid"
+}
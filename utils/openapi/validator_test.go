@@ -0,0 +1,173 @@
+package openapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type transferRequest struct {
+	From   int64  `json:"from" binding:"required"`
+	Amount string `json:"amount" binding:"required,numeric"`
+	Memo   string `json:"memo" binding:"max=8"`
+}
+
+// maxBody is the body limit of the router under test.
+const maxBody = 256
+
+type describerFunc func(doc *openapi3.T)
+
+func (f describerFunc) Describe(doc *openapi3.T) {
+	f(doc)
+}
+
+func newValidatedRouter(t *testing.T) *gin.Engine {
+	doc := New(describerFunc(func(doc *openapi3.T) {
+		transfer := openapi3.NewOperation()
+		transfer.RequestBody = JSONBody(transferRequest{})
+		transfer.AddResponse(http.StatusOK, EmptyResponse("ok"))
+		Operation(doc, http.MethodPost, "/transfers", transfer)
+
+		get := openapi3.NewOperation()
+		get.AddParameter(openapi3.NewPathParameter("id").WithSchema(openapi3.NewInt64Schema()))
+		get.AddResponse(http.StatusOK, EmptyResponse("ok"))
+		Operation(doc, http.MethodGet, "/transfers/:id", get)
+
+		upload := openapi3.NewOperation()
+		upload.RequestBody = StreamBody("rows", "text/csv")
+		upload.AddParameter(openapi3.NewQueryParameter("format").WithSchema(openapi3.NewStringSchema().WithEnum("csv")))
+		upload.AddResponse(http.StatusOK, EmptyResponse("ok"))
+		Operation(doc, http.MethodPost, "/uploads", upload)
+	}))
+
+	bodyLimit, err := BodyLimit(doc, maxBody)
+	assert.NoError(t, err)
+	validator, err := Validator(doc)
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.Use(bodyLimit, validator)
+	echo := func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	}
+	router.POST("/transfers", echo)
+	router.GET("/transfers/:id", echo)
+	router.POST("/uploads", echo)
+	router.GET("/undocumented", echo)
+
+	return router
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestValidator_ValidRequestReachesHandler(t *testing.T) {
+	router := newValidatedRouter(t)
+
+	body := `{"from": 1, "amount": "10.50"}`
+	w := serve(router, "POST", "/transfers", body)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, body, w.Body.String())
+}
+
+func TestValidator_InvalidBody(t *testing.T) {
+	router := newValidatedRouter(t)
+
+	w := serve(router, "POST", "/transfers", `{"from": "1", "memo": "far too long"}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:validation-failed\",\"title\":\"The request is invalid\",\"status\":400,\"detail\":\"the request has invalid fields\",\"instance\":\"/transfers\",\"code\":\"VALIDATION_FAILED\",\"errors\":[{\"field\":\"from\",\"message\":\"must be an integer\"},{\"field\":\"memo\",\"message\":\"must be at most 8\"},{\"field\":\"amount\",\"message\":\"is required\"}]}", w.Body.String())
+}
+
+func TestValidator_InvalidDecimal(t *testing.T) {
+	router := newValidatedRouter(t)
+
+	w := serve(router, "POST", "/transfers", `{"from": 1, "amount": "ten"}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:validation-failed\",\"title\":\"The request is invalid\",\"status\":400,\"detail\":\"the request has invalid fields\",\"instance\":\"/transfers\",\"code\":\"VALIDATION_FAILED\",\"errors\":[{\"field\":\"amount\",\"message\":\"must be a decimal number\"}]}", w.Body.String())
+}
+
+func TestValidator_MissingBody(t *testing.T) {
+	router := newValidatedRouter(t)
+
+	w := serve(router, "POST", "/transfers", "")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:validation-failed\",\"title\":\"The request is invalid\",\"status\":400,\"detail\":\"the request body must be a JSON object\",\"instance\":\"/transfers\",\"code\":\"VALIDATION_FAILED\"}", w.Body.String())
+}
+
+func TestValidator_InvalidPathParameter(t *testing.T) {
+	router := newValidatedRouter(t)
+
+	w := serve(router, "GET", "/transfers/abc", "")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:validation-failed\",\"title\":\"The request is invalid\",\"status\":400,\"detail\":\"the request has invalid fields\",\"instance\":\"/transfers/abc\",\"code\":\"VALIDATION_FAILED\",\"errors\":[{\"field\":\"id\",\"message\":\"must be an integer\"}]}", w.Body.String())
+}
+
+func TestValidator_StreamsOtherBodies(t *testing.T) {
+	router := newValidatedRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/uploads", strings.NewReader("account_id\n1\n"))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "account_id\n1\n", w.Body.String())
+
+	w = serve(router, "POST", "/uploads?format=xlsx", "")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestBodyLimit_TooLarge(t *testing.T) {
+	router := newValidatedRouter(t)
+	body := `{"from": 1, "amount": "10.50", "memo": "` + strings.Repeat("a", maxBody) + `"}`
+
+	w := serve(router, "POST", "/transfers", body)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, "{\"type\":\"urn:transactions:problem:body-too-large\",\"title\":\"The request body is too large\",\"status\":413,\"detail\":\"the request body must not exceed 256 bytes\",\"instance\":\"/transfers\",\"code\":\"BODY_TOO_LARGE\"}", w.Body.String())
+
+	// a body of unknown length is cut short as it is read
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/transfers", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.ContentLength = -1
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// streamed bodies are left to their handler
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/uploads", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestValidator_IgnoresUndocumentedRoutes(t *testing.T) {
+	router := newValidatedRouter(t)
+
+	w := serve(router, "GET", "/undocumented", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
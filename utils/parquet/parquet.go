@@ -0,0 +1,335 @@
+// Package parquet writes flat tables as Apache Parquet files, without
+// dependencies. Columns are PLAIN encoded and uncompressed, in row groups of
+// up to RowGroupSize rows, which every Parquet reader understands.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Type is the type of the values of a column.
+type Type int
+
+const (
+	Int64 Type = iota
+	Double
+	// String is UTF-8 text.
+	String
+	// Timestamp is an instant, stored in microseconds since the epoch.
+	Timestamp
+)
+
+// RowGroupSize is how many rows are buffered before they are written out.
+const RowGroupSize = 64 * 1024
+
+var magic = []byte("PAR1")
+
+// physical types, repetitions, encodings and converted types of the format
+const (
+	physicalInt64     int32 = 2
+	physicalDouble    int32 = 5
+	physicalByteArray int32 = 6
+
+	repetitionRequired int32 = 0
+	repetitionOptional int32 = 1
+
+	encodingPlain int32 = 0
+	encodingRLE   int32 = 3
+
+	convertedUTF8            int32 = 0
+	convertedTimestampMicros int32 = 10
+
+	pageTypeData int32 = 0
+)
+
+type Column struct {
+	Name string
+	Type Type
+	// Optional columns accept nil values.
+	Optional bool
+}
+
+func (c Column) physicalType() int32 {
+	switch c.Type {
+	case Double:
+		return physicalDouble
+	case String:
+		return physicalByteArray
+	}
+
+	return physicalInt64
+}
+
+func (c Column) schemaElement() structure {
+	repetition := repetitionRequired
+	if c.Optional {
+		repetition = repetitionOptional
+	}
+
+	element := structure{
+		{1, c.physicalType()},
+		{3, repetition},
+		{4, c.Name},
+	}
+	switch c.Type {
+	case String:
+		element = append(element, field{6, convertedUTF8}, field{10, structure{{1, structure{}}}})
+	case Timestamp:
+		element = append(element, field{6, convertedTimestampMicros}, field{10, structure{
+			{8, structure{{1, true}, {2, structure{{2, structure{}}}}}},
+		}})
+	}
+
+	return element
+}
+
+// chunk holds the values of a column in the current row group.
+type chunk struct {
+	values bytes.Buffer
+	// defined says which rows of an optional column have a value
+	defined []bool
+}
+
+// Writer writes rows to a Parquet file. Nothing is complete until Close.
+type Writer struct {
+	w       io.Writer
+	columns []Column
+	chunks  []chunk
+
+	offset    int64
+	rows      int
+	totalRows int64
+	rowGroups []structure
+	err       error
+}
+
+func NewWriter(w io.Writer, columns []Column) *Writer {
+	return &Writer{
+		w:       w,
+		columns: columns,
+		chunks:  make([]chunk, len(columns)),
+	}
+}
+
+// Write adds a row, a value per column: int64 for Int64, float64 for Double,
+// string for String and time.Time for Timestamp, or nil in optional columns.
+func (w *Writer) Write(row []any) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("parquet: row has %d values for %d columns", len(row), len(w.columns))
+	}
+
+	// values are checked before any is buffered, so a bad row leaves the
+	// writer usable
+	for i, column := range w.columns {
+		err := check(column, row[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, column := range w.columns {
+		c := &w.chunks[i]
+		if column.Optional {
+			c.defined = append(c.defined, row[i] != nil)
+		}
+
+		switch v := row[i].(type) {
+		case int64:
+			c.values.Write(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+		case float64:
+			c.values.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)))
+		case string:
+			c.values.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(v))))
+			c.values.WriteString(v)
+		case time.Time:
+			c.values.Write(binary.LittleEndian.AppendUint64(nil, uint64(v.UnixMicro())))
+		}
+	}
+
+	w.rows++
+	if w.rows >= RowGroupSize {
+		w.err = w.flush()
+	}
+
+	return w.err
+}
+
+func check(column Column, value any) error {
+	if value == nil {
+		if column.Optional {
+			return nil
+		}
+		return fmt.Errorf("parquet: column %s is required", column.Name)
+	}
+
+	var ok bool
+	switch column.Type {
+	case Int64:
+		_, ok = value.(int64)
+	case Double:
+		_, ok = value.(float64)
+	case String:
+		_, ok = value.(string)
+	case Timestamp:
+		_, ok = value.(time.Time)
+	}
+	if !ok {
+		return fmt.Errorf("parquet: column %s can't hold a %T", column.Name, value)
+	}
+
+	return nil
+}
+
+func (w *Writer) write(p []byte) error {
+	n, err := w.w.Write(p)
+	w.offset += int64(n)
+	return err
+}
+
+// flush writes the buffered rows as a row group, a data page per column.
+func (w *Writer) flush() error {
+	if w.offset == 0 {
+		err := w.write(magic)
+		if err != nil {
+			return err
+		}
+	}
+	if w.rows == 0 {
+		return nil
+	}
+
+	var columnChunks []structure
+	var groupSize int64
+	for i, column := range w.columns {
+		c := &w.chunks[i]
+
+		var page bytes.Buffer
+		if column.Optional {
+			levels := levelRuns(c.defined)
+			page.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(levels))))
+			page.Write(levels)
+		}
+		page.Write(c.values.Bytes())
+
+		header := marshal(structure{
+			{1, pageTypeData},
+			{2, int32(page.Len())},
+			{3, int32(page.Len())},
+			{5, structure{
+				{1, int32(w.rows)},
+				{2, encodingPlain},
+				{3, encodingRLE},
+				{4, encodingRLE},
+			}},
+		})
+
+		pageOffset := w.offset
+		err := w.write(header)
+		if err == nil {
+			err = w.write(page.Bytes())
+		}
+		if err != nil {
+			return err
+		}
+
+		size := int64(len(header) + page.Len())
+		groupSize += size
+		columnChunks = append(columnChunks, structure{
+			{2, pageOffset},
+			{3, structure{
+				{1, column.physicalType()},
+				{2, list{elem: typeI32, items: []any{encodingPlain, encodingRLE}}},
+				{3, list{elem: typeBinary, items: []any{column.Name}}},
+				{4, int32(0)},
+				{5, int64(w.rows)},
+				{6, size},
+				{7, size},
+				{9, pageOffset},
+			}},
+		})
+
+		c.values.Reset()
+		c.defined = c.defined[:0]
+	}
+
+	w.rowGroups = append(w.rowGroups, structure{
+		{1, structs(columnChunks...)},
+		{2, groupSize},
+		{3, int64(w.rows)},
+	})
+	w.totalRows += int64(w.rows)
+	w.rows = 0
+
+	return nil
+}
+
+// levelRuns encodes the definition levels of an optional column with the
+// RLE/bit-packing hybrid, as runs of equal levels one bit wide.
+func levelRuns(defined []bool) []byte {
+	var runs []byte
+	for start := 0; start < len(defined); {
+		end := start
+		for end < len(defined) && defined[end] == defined[start] {
+			end++
+		}
+
+		runs = binary.AppendUvarint(runs, uint64(end-start)<<1)
+		if defined[start] {
+			runs = append(runs, 1)
+		} else {
+			runs = append(runs, 0)
+		}
+		start = end
+	}
+
+	return runs
+}
+
+// Close writes the remaining rows and the file footer. It doesn't close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	w.err = errors.New("parquet: writer is closed")
+
+	err := w.flush()
+	if err != nil {
+		return err
+	}
+
+	schema := []structure{{
+		{4, "schema"},
+		{5, int32(len(w.columns))},
+	}}
+	for _, column := range w.columns {
+		schema = append(schema, column.schemaElement())
+	}
+
+	footer := marshal(structure{
+		{1, int32(1)},
+		{2, structs(schema...)},
+		{3, w.totalRows},
+		{4, structs(w.rowGroups...)},
+		{6, "transactions"},
+	})
+
+	err = w.write(footer)
+	if err == nil {
+		err = w.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	}
+	if err == nil {
+		err = w.write(magic)
+	}
+
+	return err
+}
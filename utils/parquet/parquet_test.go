@@ -0,0 +1,103 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var columns = []Column{
+	{Name: "id", Type: Int64},
+	{Name: "amount", Type: Double},
+	{Name: "client_id", Type: String, Optional: true},
+	{Name: "created_at", Type: Timestamp},
+}
+
+func TestWriter_Layout(t *testing.T) {
+	var b bytes.Buffer
+	w := NewWriter(&b, columns)
+	require.NoError(t, w.Write([]any{int64(1), 10.5, "client-1", time.UnixMicro(42)}))
+	require.NoError(t, w.Write([]any{int64(2), 0.25, nil, time.UnixMicro(43)}))
+	require.NoError(t, w.Close())
+
+	out := b.Bytes()
+	assert.Equal(t, "PAR1", string(out[:4]))
+	assert.Equal(t, "PAR1", string(out[len(out)-4:]))
+
+	footerLength := int(binary.LittleEndian.Uint32(out[len(out)-8:]))
+	footer := out[len(out)-8-footerLength : len(out)-8]
+	// the footer opens with version 1 and holds every column name
+	assert.Equal(t, []byte{0x15, 0x02}, footer[:2])
+	for _, column := range columns {
+		assert.Contains(t, string(footer), column.Name)
+	}
+
+	// the optional column is preceded by its definition levels: a run of
+	// one defined value, then a run of one null
+	assert.Contains(t, string(out), "\x04\x00\x00\x00\x02\x01\x02\x00\x08\x00\x00\x00client-1")
+}
+
+func TestWriter_RowGroups(t *testing.T) {
+	var b bytes.Buffer
+	w := NewWriter(&b, columns[:1])
+	for i := 0; i < RowGroupSize+1; i++ {
+		require.NoError(t, w.Write([]any{int64(i)}))
+	}
+	require.NoError(t, w.Close())
+
+	assert.Len(t, w.rowGroups, 2)
+	assert.Equal(t, int64(RowGroupSize+1), w.totalRows)
+}
+
+func TestWriter_Empty(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, NewWriter(&b, columns).Close())
+
+	assert.Equal(t, "PAR1", b.String()[:4])
+	assert.Equal(t, "PAR1", b.String()[b.Len()-4:])
+}
+
+func TestWriter_InvalidRows(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{}, columns)
+
+	assert.EqualError(t, w.Write([]any{int64(1)}), "parquet: row has 1 values for 4 columns")
+	assert.EqualError(t, w.Write([]any{nil, 1.0, nil, time.Now()}), "parquet: column id is required")
+	assert.EqualError(t, w.Write([]any{int64(1), "1", nil, time.Now()}), "parquet: column amount can't hold a string")
+	assert.NoError(t, w.Write([]any{int64(1), 1.0, nil, time.Now()}))
+	assert.Equal(t, 1, w.rows)
+}
+
+func TestWriter_Closed(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{}, columns)
+	require.NoError(t, w.Close())
+
+	assert.EqualError(t, w.Write([]any{int64(1), 1.0, nil, time.Now()}), "parquet: writer is closed")
+}
+
+func TestLevelRuns(t *testing.T) {
+	assert.Equal(t, []byte{0x06, 0x01, 0x02, 0x00, 0x02, 0x01}, levelRuns([]bool{true, true, true, false, true}))
+	assert.Empty(t, levelRuns(nil))
+}
+
+func TestMarshal(t *testing.T) {
+	encoded := marshal(structure{
+		{1, int32(-1)},
+		{2, "ab"},
+		{20, true},
+		{21, list{elem: typeI32, items: []any{int32(1), int32(2)}}},
+		{22, structure{{1, int64(300)}}},
+	})
+
+	assert.Equal(t, []byte{
+		0x15, 0x01, // field 1, i32 -1 zigzagged
+		0x18, 0x02, 'a', 'b', // field 2, string
+		0x01, 0x28, // field 20, true, id too far for a delta
+		0x19, 0x25, 0x02, 0x04, // field 21, list of two i32
+		0x1c, 0x16, 0xd8, 0x04, 0x00, // field 22, struct holding i64 300
+		0x00,
+	}, encoded)
+}
@@ -0,0 +1,127 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Parquet metadata is serialized with the Thrift compact protocol. Only what
+// the writer needs is implemented: structs, lists, booleans, 32 and 64 bit
+// integers and strings.
+
+const (
+	typeTrue   = 1
+	typeFalse  = 2
+	typeI32    = 5
+	typeI64    = 6
+	typeBinary = 8
+	typeList   = 9
+	typeStruct = 12
+)
+
+type field struct {
+	id    int16
+	value any
+}
+
+// structure is a Thrift struct, its fields in increasing id order. Unset
+// optional fields are left out.
+type structure []field
+
+// list is a Thrift list of values of type elem.
+type list struct {
+	elem  byte
+	items []any
+}
+
+func structs(items ...structure) list {
+	l := list{elem: typeStruct}
+	for _, item := range items {
+		l.items = append(l.items, item)
+	}
+
+	return l
+}
+
+func thriftType(v any) byte {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return typeTrue
+		}
+		return typeFalse
+	case int32:
+		return typeI32
+	case int64:
+		return typeI64
+	case string:
+		return typeBinary
+	case list:
+		return typeList
+	case structure:
+		return typeStruct
+	}
+
+	panic("parquet: no thrift type for value")
+}
+
+type encoder struct {
+	bytes.Buffer
+}
+
+func (e *encoder) varint(v uint64) {
+	e.Write(binary.AppendUvarint(nil, v))
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func (e *encoder) structure(s structure) {
+	var last int16
+	for _, f := range s {
+		t := thriftType(f.value)
+		if delta := f.id - last; delta > 0 && delta <= 15 {
+			e.WriteByte(byte(delta)<<4 | t)
+		} else {
+			e.WriteByte(t)
+			e.varint(zigzag(int64(f.id)))
+		}
+		last = f.id
+
+		if _, ok := f.value.(bool); !ok {
+			e.value(f.value)
+		}
+	}
+	e.WriteByte(0)
+}
+
+func (e *encoder) value(v any) {
+	switch v := v.(type) {
+	case int32:
+		e.varint(zigzag(int64(v)))
+	case int64:
+		e.varint(zigzag(v))
+	case string:
+		e.varint(uint64(len(v)))
+		e.WriteString(v)
+	case structure:
+		e.structure(v)
+	case list:
+		if len(v.items) < 15 {
+			e.WriteByte(byte(len(v.items))<<4 | v.elem)
+		} else {
+			e.WriteByte(0xf0 | v.elem)
+			e.varint(uint64(len(v.items)))
+		}
+		for _, item := range v.items {
+			e.value(item)
+		}
+	}
+}
+
+func marshal(s structure) []byte {
+	var e encoder
+	e.structure(s)
+	return e.Bytes()
+}
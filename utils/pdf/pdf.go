@@ -0,0 +1,219 @@
+// Package pdf writes simple text documents as PDF, without dependencies.
+// Text is set in Courier, whose glyphs all have the same width, so tables
+// line up by padding their columns with spaces. Only printable ASCII is
+// supported, anything else is replaced by a question mark.
+package pdf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// PageWidth and PageHeight are those of A4, in points.
+	PageWidth  = 595
+	PageHeight = 842
+
+	margin   = 50
+	fontSize = 9
+	leading  = 12
+
+	headingSize    = 14
+	headingLeading = 20
+
+	// Columns is how many characters fit on a line.
+	Columns = (PageWidth - 2*margin) * 10 / (fontSize * 6)
+
+	// footerSpace is kept at the bottom of every page for its number.
+	footerSpace = 2 * leading
+)
+
+// Objects 1 to 6 are the catalog, the page tree, the info dictionary, the
+// two fonts and the page count, each page is followed by its content
+// stream. The page tree and the page count are only written once every
+// page is.
+const (
+	catalogObject = iota + 1
+	pagesObject
+	infoObject
+	fontObject
+	boldFontObject
+	countObject
+	firstPageObject
+)
+
+type line struct {
+	text    string
+	heading bool
+}
+
+// Writer writes a document as PDF a page at a time, as its lines are
+// added, so only the page being filled is held. Errors are kept and
+// returned by Close.
+type Writer struct {
+	pw *writer
+	// page is being filled, used is the height taken on it.
+	page  []line
+	used  int
+	pages int
+}
+
+// NewWriter starts a document titled title on w.
+func NewWriter(w io.Writer, title string) *Writer {
+	pw := &writer{w: bufio.NewWriter(w)}
+	pw.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	pw.object(catalogObject, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObject))
+	pw.object(infoObject, fmt.Sprintf("<< /Title (%s) /Producer (transactions) >>", escape(title)))
+	pw.object(fontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	pw.object(boldFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>")
+
+	return &Writer{pw: pw}
+}
+
+// Heading adds a line of large bold text.
+func (w *Writer) Heading(text string) {
+	w.add(line{text: text, heading: true}, headingLeading)
+}
+
+// Line adds a line of text, cut at Columns characters.
+func (w *Writer) Line(text string) {
+	if len(text) > Columns {
+		text = text[:Columns]
+	}
+	w.add(line{text: text}, leading)
+}
+
+// Lines adds a line per argument.
+func (w *Writer) Lines(texts ...string) {
+	for _, text := range texts {
+		w.Line(text)
+	}
+}
+
+func (w *Writer) add(l line, height int) {
+	if w.used+height > PageHeight-2*margin-footerSpace {
+		w.writePage()
+	}
+
+	w.page = append(w.page, l)
+	w.used += height
+}
+
+// writePage writes the page being filled and starts the next one.
+func (w *Writer) writePage() {
+	page := firstPageObject + 2*w.pages
+	w.pages++
+
+	w.pw.object(page, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> /XObject << /Count %d 0 R >> >> /Contents %d 0 R >>",
+		pagesObject, PageWidth, PageHeight, fontObject, boldFontObject, countObject, page+1))
+	w.pw.stream(page+1, "", pageContent(w.page, w.pages))
+
+	w.page = nil
+	w.used = 0
+}
+
+// Close writes the last page and what refers to every page, numbering
+// them. It doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	if len(w.page) > 0 || w.pages == 0 {
+		w.writePage()
+	}
+
+	kids := make([]string, w.pages)
+	for i := range kids {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObject+2*i)
+	}
+	w.pw.object(pagesObject, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), w.pages))
+
+	// the page count is drawn by every footer, where it is left room for
+	w.pw.stream(countObject, fmt.Sprintf("/Type /XObject /Subtype /Form /BBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R >> >> ", PageWidth, PageHeight, fontObject),
+		fmt.Sprintf("BT /F1 %d Tf 0 %d Td (%d) Tj ET", fontSize, margin, w.pages))
+
+	xref := w.pw.n
+	w.pw.printf("xref\n0 %d\n0000000000 65535 f \n", len(w.pw.offsets)+1)
+	for _, offset := range w.pw.offsets {
+		w.pw.printf("%010d 00000 n \n", offset)
+	}
+	w.pw.printf("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.pw.offsets)+1, catalogObject, infoObject, xref)
+
+	if w.pw.err != nil {
+		return w.pw.err
+	}
+
+	return w.pw.w.Flush()
+}
+
+func pageContent(page []line, number int) string {
+	var b strings.Builder
+
+	y := PageHeight - margin
+	for _, l := range page {
+		font, size, height := "F1", fontSize, leading
+		if l.heading {
+			font, size, height = "F2", headingSize, headingLeading
+		}
+		y -= height
+		fmt.Fprintf(&b, "BT /%s %d Tf %d %d Td (%s) Tj ET\n", font, size, margin, y, escape(l.text))
+	}
+
+	// Courier glyphs are 0.6 of the font size wide
+	footer := fmt.Sprintf("Page %d of ", number)
+	fmt.Fprintf(&b, "BT /F1 %d Tf %d %d Td (%s) Tj ET\n", fontSize, margin, margin, escape(footer))
+	fmt.Fprintf(&b, "q 1 0 0 1 %.1f 0 cm /Count Do Q", margin+float64(len(footer)*fontSize)*0.6)
+
+	return b.String()
+}
+
+// escape makes text safe in a PDF string literal.
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < ' ' || r > '~':
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// writer records the offsets of the objects written for the
+// cross-reference table, objects may be written in any order.
+type writer struct {
+	w       *bufio.Writer
+	n       int64
+	offsets []int64
+	err     error
+}
+
+func (w *writer) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+
+	n, err := fmt.Fprintf(w.w, format, args...)
+	w.n += int64(n)
+	w.err = err
+}
+
+func (w *writer) object(number int, body string) {
+	for len(w.offsets) < number {
+		w.offsets = append(w.offsets, 0)
+	}
+	w.offsets[number-1] = w.n
+	w.printf("%d 0 obj\n%s\nendobj\n", number, body)
+}
+
+// stream writes an object holding content, dictionary are the entries of
+// its dictionary besides the length.
+func (w *writer) stream(number int, dictionary, content string) {
+	w.object(number, fmt.Sprintf("<< %s/Length %d >>\nstream\n%s\nendstream", dictionary, len(content), content))
+}
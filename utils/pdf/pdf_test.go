@@ -0,0 +1,99 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func render(t *testing.T, title string, fn func(w *Writer)) string {
+	var b bytes.Buffer
+	w := NewWriter(&b, title)
+	fn(w)
+	require.NoError(t, w.Close())
+
+	return b.String()
+}
+
+func TestWriter_CrossReferences(t *testing.T) {
+	out := render(t, "Statement", func(w *Writer) {
+		w.Heading("Statement")
+		w.Line("Opening balance 10.00")
+	})
+	assert.True(t, strings.HasPrefix(out, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(out, "%%EOF\n"))
+
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(out)
+	require.NotNil(t, startxref)
+	xref, err := strconv.Atoi(startxref[1])
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(out[xref:], "xref\n0 9\n"))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllStringSubmatch(out[xref:], -1)
+	require.Len(t, entries, 8)
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(entry[1])
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(out[offset:], fmt.Sprintf("%d 0 obj\n", i+1)), "object %d", i+1)
+	}
+
+	assert.Contains(t, out, "(Opening balance 10.00) Tj")
+	assert.Contains(t, out, "(Page 1 of ) Tj")
+	assert.Contains(t, out, "/Count Do")
+	assert.Contains(t, out, "(1) Tj")
+}
+
+func TestWriter_StreamLength(t *testing.T) {
+	out := render(t, "Statement", func(w *Writer) {
+		w.Line("one")
+	})
+	match := regexp.MustCompile(`/Length (\d+) >>\nstream\n`).FindStringSubmatchIndex(out)
+	require.NotNil(t, match)
+	length, err := strconv.Atoi(out[match[2]:match[3]])
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out[match[1]+length:], "\nendstream"))
+}
+
+func TestWriter_Pages(t *testing.T) {
+	out := render(t, "Statement", func(w *Writer) {
+		for i := 0; i < 150; i++ {
+			w.Line(fmt.Sprintf("line %d", i))
+		}
+	})
+	assert.Contains(t, out, "/Count 3 >>")
+	assert.Contains(t, out, "(Page 3 of ) Tj")
+	assert.Contains(t, out, "(3) Tj")
+	assert.Equal(t, 3, strings.Count(out, "/Type /Page /Parent"))
+}
+
+func TestWriter_WritesFullPages(t *testing.T) {
+	var b bytes.Buffer
+	w := NewWriter(&b, "Statement")
+	for i := 0; i < 1000; i++ {
+		w.Line(fmt.Sprintf("line %d", i))
+	}
+
+	assert.Contains(t, b.String(), "(line 0) Tj")
+	assert.Less(t, len(w.page), 100)
+	require.NoError(t, w.Close())
+}
+
+func TestWriter_Empty(t *testing.T) {
+	out := render(t, "Empty", func(w *Writer) {})
+	assert.Contains(t, out, "/Count 1 >>")
+}
+
+func TestLine_Escapes(t *testing.T) {
+	out := render(t, "Statement", func(w *Writer) {
+		w.Line(`(a\b) café`)
+		w.Line(strings.Repeat("x", Columns+10))
+	})
+	assert.Contains(t, out, `(\(a\\b\) caf?) Tj`)
+	assert.Contains(t, out, "("+strings.Repeat("x", Columns)+") Tj")
+}
@@ -0,0 +1,152 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// account is an account named by the log. tenantId is empty for the tenant
+// of the caller's credentials.
+type account struct {
+	tenantId string
+	id       int64
+}
+
+// reader is how an account is read back: with the headers of the first
+// entry naming it, under the same API version.
+type reader struct {
+	prefix  string
+	headers map[string]string
+}
+
+// balances has the balance of every account that exists.
+type balances map[account]float64
+
+// Conservation compares the balances of the accounts the log touches after
+// the replay with those before it, plus the initial balances of the
+// accounts it created.
+type Conservation struct {
+	Accounts   int     `json:"accounts"`
+	Before     float64 `json:"before"`
+	Created    float64 `json:"created"`
+	After      float64 `json:"after"`
+	Difference float64 `json:"difference"`
+	Conserved  bool    `json:"conserved"`
+}
+
+type createBody struct {
+	AccountId      int64       `json:"account_id"`
+	InitialBalance json.Number `json:"initial_balance"`
+}
+
+type transferBody struct {
+	SourceAccountId      int64  `json:"source_account_id"`
+	DestinationAccountId int64  `json:"destination_account_id"`
+	DestinationTenantId  string `json:"destination_tenant_id"`
+}
+
+// scan finds the accounts entries create or transfer between, and the
+// initial balance of each account created by the index of its entry. Bodies
+// that don't parse are left to the server to reject.
+func scan(entries []Entry) (map[account]reader, map[int]float64) {
+	accounts := map[account]reader{}
+	creates := map[int]float64{}
+
+	touch := func(entry Entry, prefix, tenantId string, id int64) {
+		key := account{tenantId: tenantId, id: id}
+		if _, seen := accounts[key]; !seen {
+			accounts[key] = reader{prefix: prefix, headers: entry.Headers}
+		}
+	}
+
+	for i, entry := range entries {
+		if entry.Method != http.MethodPost {
+			continue
+		}
+		path, _, _ := strings.Cut(entry.Path, "?")
+		tenantId := entry.header("X-Tenant-ID")
+
+		if prefix, ok := strings.CutSuffix(path, "/accounts"); ok {
+			var body createBody
+			if json.Unmarshal(entry.Body, &body) != nil {
+				continue
+			}
+			balance, err := strconv.ParseFloat(body.InitialBalance.String(), 64)
+			if err != nil {
+				continue
+			}
+			touch(entry, prefix, tenantId, body.AccountId)
+			creates[i] = balance
+		}
+
+		if prefix, ok := strings.CutSuffix(path, "/transactions"); ok {
+			var body transferBody
+			if json.Unmarshal(entry.Body, &body) != nil {
+				continue
+			}
+			destinationTenantId := body.DestinationTenantId
+			if destinationTenantId == "" {
+				destinationTenantId = tenantId
+			}
+			touch(entry, prefix, tenantId, body.SourceAccountId)
+			touch(entry, prefix, destinationTenantId, body.DestinationAccountId)
+		}
+	}
+
+	return accounts, creates
+}
+
+// balances reads every account. An account that doesn't exist has no
+// balance, any other answer than the account stops the check.
+func (r *runner) balances(ctx context.Context, accounts map[account]reader) (balances, error) {
+	read := balances{}
+	for key, reader := range accounts {
+		headers := map[string]string{}
+		for name, value := range reader.headers {
+			if http.CanonicalHeaderKey(name) != "X-Tenant-Id" {
+				headers[name] = value
+			}
+		}
+		if key.tenantId != "" {
+			headers["X-Tenant-ID"] = key.tenantId
+		}
+
+		var body struct {
+			Balance float64 `json:"balance"`
+		}
+		status, code, err := r.do(ctx, http.MethodGet, fmt.Sprintf("%s/accounts/%d", reader.prefix, key.id), headers, nil, &body)
+		switch {
+		case err != nil:
+			return nil, err
+		case status == http.StatusOK:
+			read[key] = body.Balance
+		case status == http.StatusNotFound:
+		case status == http.StatusForbidden:
+			// only admins can tell a missing account from someone else's
+			return nil, fmt.Errorf("account %d of tenant %q can't be read, balances need admin credentials", key.id, key.tenantId)
+		default:
+			return nil, fmt.Errorf("account %d of tenant %q answered %d %s", key.id, key.tenantId, status, code)
+		}
+	}
+
+	return read, nil
+}
+
+func conserved(before, after balances, created, tolerance float64) *Conservation {
+	c := &Conservation{Accounts: len(after), Created: created}
+	for _, balance := range before {
+		c.Before += balance
+	}
+	for _, balance := range after {
+		c.After += balance
+	}
+	c.Difference = c.After - c.Before - c.Created
+	c.Conserved = math.Abs(c.Difference) <= tolerance
+
+	return c
+}
@@ -0,0 +1,115 @@
+// Package replay sends a log of HTTP requests to a running server, checks
+// each response status against what the log expects and reports latencies
+// and outcomes by route. Requests between barriers run concurrently, so a
+// log of transfers exercises the server the way concurrent clients do. The
+// balances of every account the log creates or transfers between are read
+// before and after, and must add up to the same amount once the initial
+// balances of the accounts created are accounted for.
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxLineBytes bounds a line of the log.
+const maxLineBytes = 1024 * 1024
+
+// Entry is a line of the log, either a request or a barrier.
+//
+//	{"name": "transfer", "method": "POST", "path": "/v1/transactions", "body": {...}, "expect": [200, 409, 422]}
+//	{"barrier": true}
+type Entry struct {
+	// Name groups requests in the report, "METHOD path" when empty.
+	Name    string            `json:"name,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	Path    string            `json:"path,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is sent as is, with Content-Type application/json unless Headers
+	// name another.
+	Body json.RawMessage `json:"body,omitempty"`
+	// Expect lists the statuses that count as a success, any 2xx when empty.
+	Expect []int `json:"expect,omitempty"`
+	// Barrier waits for every request before it to complete before sending
+	// any after it.
+	Barrier bool `json:"barrier,omitempty"`
+}
+
+// name is how the entry is reported.
+func (e Entry) name() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	return e.Method + " " + e.Path
+}
+
+func (e Entry) expected(status int) bool {
+	if len(e.Expect) == 0 {
+		return status >= 200 && status <= 299
+	}
+	for _, expected := range e.Expect {
+		if status == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// header returns the value of a header of the entry, whatever its case.
+func (e Entry) header(name string) string {
+	for key, value := range e.Headers {
+		if http.CanonicalHeaderKey(key) == http.CanonicalHeaderKey(name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// Read parses a log, one JSON entry per line. Blank lines are skipped.
+func Read(r io.Reader) ([]Entry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineBytes)
+
+	var entries []Entry
+	line := 0
+	for scanner.Scan() {
+		line++
+		content := strings.TrimSpace(scanner.Text())
+		if content == "" {
+			continue
+		}
+
+		var entry Entry
+		decoder := json.NewDecoder(strings.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err := decoder.Decode(&entry)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		switch {
+		case entry.Barrier && (entry.Method != "" || entry.Path != ""):
+			return nil, fmt.Errorf("line %d: a barrier is not a request", line)
+		case entry.Barrier:
+		case entry.Method == "":
+			return nil, fmt.Errorf("line %d: method is required", line)
+		case !strings.HasPrefix(entry.Path, "/"):
+			return nil, fmt.Errorf("line %d: path must start with /", line)
+		}
+		entry.Method = strings.ToUpper(entry.Method)
+
+		entries = append(entries, entry)
+	}
+
+	err := scanner.Err()
+	if errors.Is(err, bufio.ErrTooLong) {
+		return nil, fmt.Errorf("line %d: longer than %d bytes", line+1, maxLineBytes)
+	}
+
+	return entries, err
+}
@@ -0,0 +1,246 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTolerance is the largest difference in balances that still
+	// conserves money when none is given.
+	DefaultTolerance = 1e-6
+
+	// maxBodyBytes is as much of a response as is read to find the problem
+	// code, the rest is discarded.
+	maxBodyBytes = 64 * 1024
+)
+
+type Options struct {
+	// BaseURL is where the server listens, like http://localhost:8080.
+	BaseURL string
+	// Headers are sent with every request, credentials for instance. The
+	// headers of an entry take precedence.
+	Headers http.Header
+	// Concurrency is the number of requests in flight at most, 1 when zero.
+	Concurrency int
+	// Rate caps the requests sent per second, unlimited when zero.
+	Rate float64
+	// Repeat replays the log this many times in a row, once when zero.
+	Repeat int
+	// Conservation reads the balances the log touches before and after the
+	// replay and checks they add up, see the package documentation.
+	Conservation bool
+	// Tolerance is the largest difference in balances that still conserves
+	// money, DefaultTolerance when zero.
+	Tolerance float64
+	// Client sends the requests, one keeping Concurrency connections open
+	// when nil.
+	Client *http.Client
+}
+
+type runner struct {
+	options  Options
+	recorder *recorder
+	// creates maps the index of each entry creating an account to its
+	// initial balance.
+	creates map[int]float64
+}
+
+// Run replays entries. The report covers what was sent until then when
+// ctx is cancelled, which is returned as the error. Other errors are about
+// reading balances.
+func Run(ctx context.Context, entries []Entry, options Options) (Report, error) {
+	if options.Concurrency <= 0 {
+		options.Concurrency = 1
+	}
+	if options.Repeat <= 0 {
+		options.Repeat = 1
+	}
+	if options.Tolerance <= 0 {
+		options.Tolerance = DefaultTolerance
+	}
+	if options.Client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConnsPerHost = options.Concurrency
+		options.Client = &http.Client{Transport: transport, Timeout: 30 * time.Second}
+	}
+	options.BaseURL = strings.TrimSuffix(options.BaseURL, "/")
+
+	r := &runner{options: options, recorder: newRecorder(entries)}
+
+	var accounts map[account]reader
+	var before balances
+	if options.Conservation {
+		accounts, r.creates = scan(entries)
+
+		var err error
+		before, err = r.balances(ctx, accounts)
+		if err != nil {
+			return Report{}, fmt.Errorf("reading balances before the replay: %w", err)
+		}
+	}
+
+	started := time.Now()
+	err := r.replay(ctx, entries)
+	report := r.recorder.report(time.Since(started))
+	if err != nil {
+		return report, err
+	}
+
+	if options.Conservation {
+		after, err := r.balances(ctx, accounts)
+		if err != nil {
+			return report, fmt.Errorf("reading balances after the replay: %w", err)
+		}
+		report.Conservation = conserved(before, after, r.recorder.created, options.Tolerance)
+	}
+
+	return report, nil
+}
+
+type job struct {
+	index int
+	entry Entry
+}
+
+// replay sends the entries from a pool of workers, pausing at barriers
+// until the requests in flight complete.
+func (r *runner) replay(ctx context.Context, entries []Entry) error {
+	jobs := make(chan job)
+	var workers, inflight sync.WaitGroup
+	for i := 0; i < r.options.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for j := range jobs {
+				r.send(ctx, j)
+				inflight.Done()
+			}
+		}()
+	}
+
+	var interval time.Duration
+	if r.options.Rate > 0 {
+		interval = time.Duration(float64(time.Second) / r.options.Rate)
+	}
+	next := time.Now()
+
+dispatch:
+	for repeat := 0; repeat < r.options.Repeat; repeat++ {
+		for i, entry := range entries {
+			if entry.Barrier {
+				inflight.Wait()
+				continue
+			}
+
+			// requests keep to the schedule, a slow server doesn't lower
+			// the rate they are sent at
+			if interval > 0 {
+				timer := time.NewTimer(time.Until(next))
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					break dispatch
+				}
+				next = next.Add(interval)
+			}
+
+			if ctx.Err() != nil {
+				break dispatch
+			}
+			inflight.Add(1)
+			select {
+			case jobs <- job{index: i, entry: entry}:
+			case <-ctx.Done():
+				inflight.Done()
+				break dispatch
+			}
+		}
+	}
+
+	close(jobs)
+	workers.Wait()
+
+	return ctx.Err()
+}
+
+func (r *runner) send(ctx context.Context, j job) {
+	var body io.Reader
+	if len(j.entry.Body) > 0 {
+		body = bytes.NewReader(j.entry.Body)
+	}
+
+	started := time.Now()
+	status, code, err := r.do(ctx, j.entry.Method, j.entry.Path, j.entry.Headers, body, nil)
+	res := result{
+		name:     j.entry.name(),
+		status:   status,
+		code:     code,
+		err:      err,
+		expected: err == nil && j.entry.expected(status),
+		latency:  time.Since(started),
+	}
+	if balance, creates := r.creates[j.index]; creates && status >= 200 && status <= 299 {
+		res.created = balance
+	}
+
+	r.recorder.record(res)
+}
+
+// do sends a request and returns the status of the response with the code
+// of the problem it holds, if any. into is decoded from a 200 response.
+func (r *runner) do(ctx context.Context, method, path string, headers map[string]string, body io.Reader, into any) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, r.options.BaseURL+path, body)
+	if err != nil {
+		return 0, "", err
+	}
+	for name, values := range r.options.Headers {
+		req.Header[name] = values
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := r.options.Client.Do(req)
+	if err != nil {
+		// the URL is the same for every request of an entry, only what
+		// went wrong is worth counting
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return resp.StatusCode, "", err
+	}
+
+	var problem struct {
+		Code string `json:"code"`
+	}
+	if resp.StatusCode >= 400 {
+		_ = json.Unmarshal(content, &problem)
+	}
+	if resp.StatusCode == http.StatusOK && into != nil {
+		err = json.Unmarshal(content, into)
+	}
+
+	return resp.StatusCode, problem.Code, err
+}
@@ -0,0 +1,278 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ledger is a server moving money between accounts kept in memory. A leaky
+// ledger credits transfers without debiting them.
+type ledger struct {
+	mu       sync.Mutex
+	balances map[int64]float64
+	leaky    bool
+}
+
+func newLedger(t *testing.T, leaky bool, balances map[int64]float64) *httptest.Server {
+	l := &ledger{balances: balances, leaky: leaky}
+	if l.balances == nil {
+		l.balances = map[int64]float64{}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/accounts", l.create)
+	mux.HandleFunc("GET /v1/accounts/{id}", l.get)
+	mux.HandleFunc("POST /v1/transactions", l.transfer)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func problem(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"status":%d,"code":%q}`, status, code)
+}
+
+func (l *ledger) create(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		AccountId      int64  `json:"account_id"`
+		InitialBalance string `json:"initial_balance"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	balance, _ := strconv.ParseFloat(body.InitialBalance, 64)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, exists := l.balances[body.AccountId]; exists {
+		problem(w, http.StatusConflict, "ACCOUNT_ALREADY_EXISTS")
+		return
+	}
+	l.balances[body.AccountId] = balance
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (l *ledger) get(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	balance, exists := l.balances[id]
+	if !exists {
+		problem(w, http.StatusNotFound, "ACCOUNT_NOT_FOUND")
+		return
+	}
+	fmt.Fprintf(w, `{"account_id":%d,"balance":%g}`, id, balance)
+}
+
+func (l *ledger) transfer(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Source      int64  `json:"source_account_id"`
+		Destination int64  `json:"destination_account_id"`
+		Amount      string `json:"amount"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	amount, _ := strconv.ParseFloat(body.Amount, 64)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, sourceExists := l.balances[body.Source]
+	_, destinationExists := l.balances[body.Destination]
+	switch {
+	case !sourceExists || !destinationExists:
+		problem(w, http.StatusNotFound, "ACCOUNT_NOT_FOUND")
+		return
+	case l.balances[body.Source] < amount:
+		problem(w, http.StatusUnprocessableEntity, "INSUFFICIENT_FUNDS")
+		return
+	}
+	if !l.leaky {
+		l.balances[body.Source] -= amount
+	}
+	l.balances[body.Destination] += amount
+	fmt.Fprint(w, `{"transaction_id":1}`)
+}
+
+// transfers is a log creating accounts 1 and 2 and moving money back and
+// forth between them and account 3, which already exists.
+func transfers(t *testing.T, count int) []Entry {
+	log := `{"name": "create account", "method": "POST", "path": "/v1/accounts", "body": {"account_id": 1, "initial_balance": "100"}, "expect": [204]}
+{"name": "create account", "method": "POST", "path": "/v1/accounts", "body": {"account_id": 2, "initial_balance": "50.5"}, "expect": [204]}
+{"barrier": true}
+`
+	for i := 0; i < count; i++ {
+		log += fmt.Sprintf(`{"name": "transfer", "method": "POST", "path": "/v1/transactions", "body": {"source_account_id": %d, "destination_account_id": %d, "amount": "30"}, "expect": [200, 422]}`+"\n", i%3+1, (i+1)%3+1)
+	}
+
+	entries, err := Read(strings.NewReader(log))
+	require.NoError(t, err)
+
+	return entries
+}
+
+func TestRead(t *testing.T) {
+	entries, err := Read(strings.NewReader(`{"method": "get", "path": "/v1/accounts/1", "headers": {"X-Tenant-ID": "acme"}}
+
+{"barrier": true}
+`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Method: "GET", Path: "/v1/accounts/1", Headers: map[string]string{"X-Tenant-ID": "acme"}},
+		{Barrier: true},
+	}, entries)
+	assert.Equal(t, "GET /v1/accounts/1", entries[0].name())
+	assert.True(t, entries[0].expected(204))
+	assert.False(t, entries[0].expected(409))
+}
+
+func TestRead_Invalid(t *testing.T) {
+	tests := map[string]string{
+		`{"path": "/v1/accounts"}`:                        "line 2: method is required",
+		`{"method": "GET", "path": "v1/accounts"}`:        "line 2: path must start with /",
+		`{"method": "GET", "path": "/", "status": 200}`:   `line 2: json: unknown field "status"`,
+		`{"barrier": true, "method": "GET", "path": "/"}`: "line 2: a barrier is not a request",
+		`{"method": "POST", "path": "/", "body": {"a": 1`: "line 2: unexpected EOF",
+	}
+
+	for line, message := range tests {
+		_, err := Read(strings.NewReader(`{"barrier": true}` + "\n" + line))
+		assert.EqualError(t, err, message, line)
+	}
+}
+
+func TestRun_ConservesMoney(t *testing.T) {
+	server := newLedger(t, false, map[int64]float64{3: 5})
+
+	report, err := Run(context.Background(), transfers(t, 30), Options{BaseURL: server.URL, Concurrency: 4, Conservation: true})
+
+	assert.NoError(t, err)
+	assert.True(t, report.OK())
+	assert.Equal(t, 32, report.Requests)
+	assert.Equal(t, 0, report.Unexpected)
+	assert.Equal(t, []string{"create account", "transfer"}, []string{report.Routes[0].Name, report.Routes[1].Name})
+	assert.Equal(t, map[int]int{204: 2}, report.Routes[0].Statuses)
+	assert.Equal(t, 30, report.Routes[1].Statuses[200]+report.Routes[1].Statuses[422])
+	assert.Equal(t, &Conservation{Accounts: 3, Before: 5, Created: 150.5, After: 155.5, Conserved: true}, report.Conservation)
+}
+
+func TestRun_DetectsMoneyCreated(t *testing.T) {
+	server := newLedger(t, true, map[int64]float64{3: 0})
+
+	report, err := Run(context.Background(), transfers(t, 2), Options{BaseURL: server.URL, Conservation: true})
+
+	assert.NoError(t, err)
+	assert.False(t, report.OK())
+	assert.Equal(t, 0, report.Unexpected)
+	assert.Equal(t, &Conservation{Accounts: 3, Before: 0, Created: 150.5, After: 210.5, Difference: 60, Conserved: false}, report.Conservation)
+}
+
+func TestRun_UnexpectedStatuses(t *testing.T) {
+	server := newLedger(t, false, nil)
+
+	// without the barrier transfers may run before the accounts exist, and
+	// the second run finds them created
+	entries := transfers(t, 1)
+	entries = append(entries[:2], entries[3:]...)
+	report, err := Run(context.Background(), entries, Options{BaseURL: server.URL, Repeat: 2})
+
+	assert.NoError(t, err)
+	assert.False(t, report.OK())
+	assert.Nil(t, report.Conservation)
+	assert.Equal(t, 6, report.Requests)
+	assert.Equal(t, Route{
+		Name:       "create account",
+		Requests:   4,
+		Unexpected: 2,
+		Statuses:   map[int]int{204: 2, 409: 2},
+		Codes:      map[string]int{"ACCOUNT_ALREADY_EXISTS": 2},
+		Errors:     map[string]int{},
+		Latency:    report.Routes[0].Latency,
+	}, report.Routes[0])
+}
+
+func TestRun_UnreachableServer(t *testing.T) {
+	server := newLedger(t, false, nil)
+	server.Close()
+
+	report, err := Run(context.Background(), transfers(t, 1), Options{BaseURL: server.URL})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Unexpected)
+	assert.Len(t, report.Routes[1].Errors, 1)
+
+	_, err = Run(context.Background(), transfers(t, 1), Options{BaseURL: server.URL, Conservation: true})
+
+	assert.ErrorContains(t, err, "reading balances before the replay")
+}
+
+func TestRun_Rate(t *testing.T) {
+	server := newLedger(t, false, nil)
+
+	started := time.Now()
+	report, err := Run(context.Background(), transfers(t, 4), Options{BaseURL: server.URL, Concurrency: 4, Rate: 50})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 6, report.Requests)
+	// six requests at 50 per second are spread over 100ms
+	assert.GreaterOrEqual(t, time.Since(started), 100*time.Millisecond)
+}
+
+func TestRun_Cancelled(t *testing.T) {
+	server := newLedger(t, false, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := Run(ctx, transfers(t, 4), Options{BaseURL: server.URL})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, report.Requests)
+}
+
+func TestPercentiles(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	assert.Equal(t, Latency{P50: 50, P90: 90, P95: 95, P99: 99, Max: 100}, percentiles(latencies))
+	assert.Equal(t, Latency{}, percentiles(nil))
+}
+
+func TestReport_WriteText(t *testing.T) {
+	report := Report{
+		Requests:   3,
+		Unexpected: 1,
+		Seconds:    1.5,
+		Throughput: 2,
+		Routes: []Route{{
+			Name:       "transfer",
+			Requests:   3,
+			Unexpected: 1,
+			Statuses:   map[int]int{200: 2, 422: 1},
+			Codes:      map[string]int{"INSUFFICIENT_FUNDS": 1},
+			Latency:    Latency{P50: 1.25, P99: 3},
+		}},
+		Conservation: &Conservation{Accounts: 2, Before: 10, Created: 5, After: 15, Conserved: true},
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, report.WriteText(&out))
+
+	assert.Contains(t, out.String(), "requests    3 in 1.50s, 2.0/s\n")
+	assert.Contains(t, out.String(), "transfer  3         1           1.2ms  3.0ms  200:2 422:1  INSUFFICIENT_FUNDS:1  -\n")
+	assert.Contains(t, out.String(), "money  conserved across 2 accounts: 10 before + 5 created = 15, 15 after, difference 0\n")
+}
@@ -0,0 +1,212 @@
+package replay
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Latency holds percentiles of response times, in milliseconds.
+type Latency struct {
+	P50 float64 `json:"p50_ms"`
+	P90 float64 `json:"p90_ms"`
+	P95 float64 `json:"p95_ms"`
+	P99 float64 `json:"p99_ms"`
+	Max float64 `json:"max_ms"`
+}
+
+// Route is what became of the requests of one name. Statuses counts the
+// responses by status, Codes the problems by code and Errors the requests
+// without a response, or whose response couldn't be read, by what went
+// wrong.
+type Route struct {
+	Name       string         `json:"name"`
+	Requests   int            `json:"requests"`
+	Unexpected int            `json:"unexpected"`
+	Statuses   map[int]int    `json:"statuses"`
+	Codes      map[string]int `json:"codes,omitempty"`
+	Errors     map[string]int `json:"errors,omitempty"`
+	Latency    Latency        `json:"latency"`
+}
+
+// Report sums up a replay. Unexpected counts the responses with a status
+// the log didn't expect and the requests that got no response. Routes are
+// in the order they first appear in the log.
+type Report struct {
+	Requests     int           `json:"requests"`
+	Unexpected   int           `json:"unexpected"`
+	Seconds      float64       `json:"seconds"`
+	Throughput   float64       `json:"throughput"`
+	Latency      Latency       `json:"latency"`
+	Routes       []Route       `json:"routes"`
+	Conservation *Conservation `json:"conservation,omitempty"`
+}
+
+// OK reports whether every request got an expected response and money was
+// conserved, when checked.
+func (r Report) OK() bool {
+	return r.Unexpected == 0 && (r.Conservation == nil || r.Conservation.Conserved)
+}
+
+// WriteText writes the report as a table, for people.
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "requests\t%d in %.2fs, %.1f/s\n", r.Requests, r.Seconds, r.Throughput)
+	fmt.Fprintf(tw, "unexpected\t%d\n", r.Unexpected)
+	fmt.Fprintf(tw, "latency\t%s\n", r.Latency)
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "route\trequests\tunexpected\tp50\tp99\tstatuses\tcodes\terrors")
+	for _, route := range r.Routes {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1fms\t%.1fms\t%s\t%s\t%s\n", route.Name, route.Requests, route.Unexpected,
+			route.Latency.P50, route.Latency.P99, counts(route.Statuses), counts(route.Codes), counts(route.Errors))
+	}
+
+	if c := r.Conservation; c != nil {
+		outcome := "conserved"
+		if !c.Conserved {
+			outcome = "NOT CONSERVED"
+		}
+		fmt.Fprintln(tw)
+		fmt.Fprintf(tw, "money\t%s across %d accounts: %g before + %g created = %g, %g after, difference %g\n",
+			outcome, c.Accounts, c.Before, c.Created, c.Before+c.Created, c.After, c.Difference)
+	}
+
+	return tw.Flush()
+}
+
+func (l Latency) String() string {
+	return fmt.Sprintf("p50 %.1fms  p90 %.1fms  p95 %.1fms  p99 %.1fms  max %.1fms", l.P50, l.P90, l.P95, l.P99, l.Max)
+}
+
+// counts formats a breakdown as key:count pairs, sorted by key.
+func counts[K int | string](breakdown map[K]int) string {
+	keys := make([]K, 0, len(breakdown))
+	for key := range breakdown {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = fmt.Sprintf("%v:%d", key, breakdown[key])
+	}
+	if len(pairs) == 0 {
+		return "-"
+	}
+	return strings.Join(pairs, " ")
+}
+
+// result is the outcome of one request. created is the initial balance of
+// the account it created, if any.
+type result struct {
+	name     string
+	status   int
+	code     string
+	err      error
+	expected bool
+	latency  time.Duration
+	created  float64
+}
+
+type routeResults struct {
+	route     Route
+	latencies []time.Duration
+}
+
+// recorder collects results from every worker.
+type recorder struct {
+	mu      sync.Mutex
+	routes  map[string]*routeResults
+	order   []string
+	created float64
+}
+
+func newRecorder(entries []Entry) *recorder {
+	r := &recorder{routes: map[string]*routeResults{}}
+	for _, entry := range entries {
+		name := entry.name()
+		if _, seen := r.routes[name]; entry.Barrier || seen {
+			continue
+		}
+		r.routes[name] = &routeResults{route: Route{Name: name, Statuses: map[int]int{}, Codes: map[string]int{}, Errors: map[string]int{}}}
+		r.order = append(r.order, name)
+	}
+
+	return r
+}
+
+func (r *recorder) record(res result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rr := r.routes[res.name]
+	rr.route.Requests++
+	rr.latencies = append(rr.latencies, res.latency)
+	if !res.expected {
+		rr.route.Unexpected++
+	}
+	if res.err != nil {
+		rr.route.Errors[res.err.Error()]++
+	}
+	if res.status != 0 {
+		rr.route.Statuses[res.status]++
+	}
+	if res.code != "" {
+		rr.route.Codes[res.code]++
+	}
+	r.created += res.created
+}
+
+// report sums up the results of a replay that took elapsed. Routes no
+// request was sent to are left out.
+func (r *recorder) report(elapsed time.Duration) Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := Report{Seconds: elapsed.Seconds(), Routes: []Route{}}
+	var all []time.Duration
+	for _, name := range r.order {
+		rr := r.routes[name]
+		if rr.route.Requests == 0 {
+			continue
+		}
+		rr.route.Latency = percentiles(rr.latencies)
+		report.Routes = append(report.Routes, rr.route)
+		report.Requests += rr.route.Requests
+		report.Unexpected += rr.route.Unexpected
+		all = append(all, rr.latencies...)
+	}
+	report.Latency = percentiles(all)
+	if elapsed > 0 {
+		report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	}
+
+	return report
+}
+
+// percentiles uses the nearest rank, so every percentile is a latency that
+// was measured.
+func percentiles(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return float64(sorted[i]) / float64(time.Millisecond)
+	}
+
+	return Latency{P50: rank(0.50), P90: rank(0.90), P95: rank(0.95), P99: rank(0.99), Max: rank(1)}
+}
@@ -0,0 +1,74 @@
+// Package secretbox encrypts the secrets the service has to keep in the
+// database but be able to read back, such as the signing secrets of API
+// keys, with a key only the servers hold. Reading the database alone doesn't
+// reveal them.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the size of the key, in bytes, for AES-256.
+const KeySize = 32
+
+var ErrInvalidKey = fmt.Errorf("the key must be %d bytes, base64 encoded", KeySize)
+
+var errMalformed = errors.New("sealed value is malformed")
+
+// Box seals values with AES-256-GCM, each under a random nonce that is kept
+// in front of the ciphertext.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// ParseKey decodes a base64 encoded key.
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+func New(key []byte) (*Box, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts plaintext. additionalData, which may be nil, isn't stored but
+// must be given again to open the value, binding it to, say, the row it is
+// stored in.
+func (b *Box) Seal(plaintext, additionalData []byte) []byte {
+	nonce := make([]byte, b.aead.NonceSize(), b.aead.NonceSize()+len(plaintext)+b.aead.Overhead())
+	_, _ = rand.Read(nonce)
+
+	return b.aead.Seal(nonce, nonce, plaintext, additionalData)
+}
+
+// Open decrypts a value sealed with the same key and additionalData.
+func (b *Box) Open(sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < b.aead.NonceSize() {
+		return nil, errMalformed
+	}
+	nonce, ciphertext := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+
+	return b.aead.Open(nil, nonce, ciphertext, additionalData)
+}
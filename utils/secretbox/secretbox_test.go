@@ -0,0 +1,46 @@
+package secretbox
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBox_SealAndOpen(t *testing.T) {
+	box, err := New(bytes.Repeat([]byte{1}, KeySize))
+	require.NoError(t, err)
+
+	sealed := box.Seal([]byte("tks_secret"), []byte("key-1"))
+	assert.NotContains(t, string(sealed), "tks_secret")
+	assert.NotEqual(t, sealed, box.Seal([]byte("tks_secret"), []byte("key-1")))
+
+	opened, err := box.Open(sealed, []byte("key-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "tks_secret", string(opened))
+
+	// sealed for another row
+	_, err = box.Open(sealed, []byte("key-2"))
+	assert.Error(t, err)
+
+	other, _ := New(bytes.Repeat([]byte{2}, KeySize))
+	_, err = other.Open(sealed, []byte("key-1"))
+	assert.Error(t, err)
+
+	_, err = box.Open([]byte{1, 2}, nil)
+	assert.Error(t, err)
+}
+
+func TestParseKey(t *testing.T) {
+	key, err := ParseKey(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, KeySize)))
+	assert.NoError(t, err)
+	assert.Len(t, key, KeySize)
+
+	_, err = ParseKey(base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = ParseKey("not base64!")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
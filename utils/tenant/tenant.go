@@ -0,0 +1,87 @@
+// Package tenant carries the tenant a request acts on. DAOs filter every
+// query by it and the database enforces the same boundary with row level
+// security, see ConfigurePool.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Default is the tenant used when neither the caller's credentials nor the
+// request name one.
+const Default = "default"
+
+// setting is the Postgres setting the row level security policies read.
+const setting = "app.tenant_ids"
+
+// ErrMissing is returned by DAOs when called without a tenant on the context.
+var ErrMissing = errors.New("no tenant on context")
+
+// ids are lowercase so they can't collide by case, and never contain commas
+// since the visible tenants are passed to Postgres as a comma separated list.
+var validId = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+func Valid(id string) bool {
+	return validId.MatchString(id)
+}
+
+type scope struct {
+	active  string
+	visible []string
+}
+
+type contextKey struct{}
+
+// NewContext makes id the active tenant, the one queries are filtered by.
+// Rows of the other tenants given are also visible to the database session,
+// which is how a transfer between tenants updates both sides in one
+// transaction.
+func NewContext(ctx context.Context, id string, others ...string) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope{
+		active:  id,
+		visible: append([]string{id}, others...),
+	})
+}
+
+func FromContext(ctx context.Context) (string, bool) {
+	s, ok := ctx.Value(contextKey{}).(scope)
+	return s.active, ok
+}
+
+// Require returns the active tenant, or ErrMissing.
+func Require(ctx context.Context) (string, error) {
+	id, ok := FromContext(ctx)
+	if !ok || id == "" {
+		return "", ErrMissing
+	}
+
+	return id, nil
+}
+
+// ConfigurePool makes every connection handed out by the pool see only the
+// rows of the tenants on the acquiring context. Connections acquired without
+// a tenant see none.
+func ConfigurePool(poolConfig *pgxpool.Config) {
+	beforeAcquire := poolConfig.BeforeAcquire
+	poolConfig.BeforeAcquire = func(ctx context.Context, conn *pgx.Conn) bool {
+		if beforeAcquire != nil && !beforeAcquire(ctx, conn) {
+			return false
+		}
+
+		var visible string
+		if s, ok := ctx.Value(contextKey{}).(scope); ok {
+			visible = strings.Join(s.visible, ",")
+		}
+
+		// A connection that can't be scoped is destroyed rather than handed
+		// out with the previous tenant still set.
+		_, err := conn.Exec(ctx, "select set_config($1, $2, false)", setting, visible)
+		return err == nil
+	}
+}
@@ -0,0 +1,27 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid("default"))
+	assert.True(t, Valid("retail-eu_1"))
+	assert.False(t, Valid(""))
+	assert.False(t, Valid("Retail"))
+	assert.False(t, Valid("retail,corporate"))
+	assert.False(t, Valid("-retail"))
+}
+
+func TestRequire(t *testing.T) {
+	_, err := Require(context.Background())
+	assert.ErrorIs(t, err, ErrMissing)
+
+	ctx := NewContext(context.Background(), "retail", "corporate")
+	id, err := Require(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "retail", id)
+}
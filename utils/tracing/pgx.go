@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/ashwin-m/transactions/utils/pgxiface"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryTracer creates a client span for every query. Install it on
+// pgxpool.Config.ConnConfig.Tracer.
+type QueryTracer struct{}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = otel.Tracer(instrumentationName).Start(ctx, "db.query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBQueryText(data.SQL),
+		),
+	)
+
+	return ctx
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+
+	End(span, data.Err)
+}
+
+type beginner struct {
+	db pgxiface.PgxIface
+}
+
+// Beginner wraps db so that beginning, committing and rolling back a
+// transaction are recorded as spans.
+func Beginner(db pgxiface.PgxIface) pgxiface.PgxIface {
+	return &beginner{db: db}
+}
+
+func (b *beginner) Begin(ctx context.Context) (pgx.Tx, error) {
+	ctx, span := startTxSpan(ctx, "db.begin")
+	tx, err := b.db.Begin(ctx)
+	End(span, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracedTx{Tx: tx}, nil
+}
+
+type tracedTx struct {
+	pgx.Tx
+}
+
+// Unwrap returns the transaction being traced.
+func (t *tracedTx) Unwrap() pgx.Tx {
+	return t.Tx
+}
+
+func (t *tracedTx) Commit(ctx context.Context) error {
+	ctx, span := startTxSpan(ctx, "db.commit")
+	err := t.Tx.Commit(ctx)
+	End(span, err)
+
+	return err
+}
+
+func (t *tracedTx) Rollback(ctx context.Context) error {
+	ctx, span := startTxSpan(ctx, "db.rollback")
+	err := t.Tx.Rollback(ctx)
+	End(span, err)
+
+	return err
+}
+
+func startTxSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL),
+	)
+}